@@ -0,0 +1,47 @@
+// Command policy-test runs the policy test files of a policy directory.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/Atmosfr/user-service/internal/policy"
+)
+
+func main() {
+	dir := flag.String("dir", "policies", "directory with policy and policy test files")
+	verbose := flag.Bool("v", false, "print passing tests")
+	flag.Parse()
+
+	policies, err := policy.LoadDir(*dir)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+	cases, err := policy.LoadTests(*dir)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+
+	failed := 0
+	for _, result := range policy.RunTests(policies, cases) {
+		if !result.Passed {
+			failed++
+			fmt.Printf("FAIL %s: %s: %s\n", result.Case.Source, result.Case.Name, result.Message)
+			continue
+		}
+		if *verbose {
+			fmt.Printf("ok   %s: %s\n", result.Case.Source, result.Case.Name)
+		}
+		if result.Message != "" {
+			fmt.Printf("warn %s: %s: %s\n", result.Case.Source, result.Case.Name, result.Message)
+		}
+	}
+
+	fmt.Printf("%d policies, %d tests, %d failed\n", len(policies), len(cases), failed)
+	if failed > 0 {
+		os.Exit(1)
+	}
+}
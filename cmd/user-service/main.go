@@ -5,20 +5,30 @@ import (
 	"database/sql"
 	"encoding/json"
 	"log/slog"
+	"net"
 	"net/http"
 	"os"
 	"os/signal"
+	"strconv"
 	"syscall"
 	"time"
 
 	"github.com/Atmosfr/user-service/internal/auth"
+	"github.com/Atmosfr/user-service/internal/blob"
+	"github.com/Atmosfr/user-service/internal/events"
 	"github.com/Atmosfr/user-service/internal/handlers"
+	"github.com/Atmosfr/user-service/internal/ldapauth"
+	"github.com/Atmosfr/user-service/internal/mail"
 	"github.com/Atmosfr/user-service/internal/middleware"
+	"github.com/Atmosfr/user-service/internal/policy"
 	"github.com/Atmosfr/user-service/internal/repository"
 	"github.com/Atmosfr/user-service/internal/service"
 	"github.com/pressly/goose/v3"
 )
 
+// authzDecisionMaxAge is how long callers may cache authorization decisions.
+const authzDecisionMaxAge = 30 * time.Second
+
 func runMigrations(db *sql.DB) error {
 	goose.SetDialect("postgres")
 	return goose.Up(db, "migrations")
@@ -29,23 +39,6 @@ func healthHandler(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(map[string]string{"status": "ok"})
 }
 
-func meHandler(w http.ResponseWriter, r *http.Request) {
-	user, ok := middleware.GetUserFromContext(r.Context())
-	if !ok {
-		http.Error(w, "user not found in context", http.StatusInternalServerError)
-		return
-	}
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(map[string]interface{}{
-		"id":         user.ID,
-		"email":      user.Email,
-		"username":   user.Username,
-		"role":       user.Role,
-		"created_at": user.CreatedAt,
-		"is_active":  user.IsActive,
-	})
-}
-
 func main() {
 	secret := os.Getenv("JWT_SECRET")
 	if err := auth.InitJWT(secret); err != nil {
@@ -73,9 +66,146 @@ func main() {
 
 	// router
 	mux := http.NewServeMux()
+	// organization queries can additionally be isolated by postgres row-level security
+	var tenantOpts []repository.TenantOption
+	if v := os.Getenv("DB_ROW_LEVEL_SECURITY"); v != "" {
+		enabled, err := strconv.ParseBool(v)
+		if err != nil {
+			slog.Error("invalid DB_ROW_LEVEL_SECURITY", "error", err)
+			os.Exit(1)
+		}
+		if enabled {
+			tenantOpts = append(tenantOpts, repository.WithRowLevelSecurity())
+			slog.Info("row-level security enabled for organization queries")
+		}
+	}
+
 	repo := repository.NewUserRepository(db)
-	authMiddleware := middleware.NewAuthMiddleware(repo)
-	svc := service.NewUserService(repo)
+	tokenSvc := service.NewTokenService(repository.NewTokenRepository(db))
+	rbacSvc := service.NewRBACService(repository.NewRoleRepository(db))
+	orgRepo := repository.NewOrganizationRepository(db, tenantOpts...)
+	// metadata namespaces can be copied into access tokens
+	metadataSvc := service.NewMetadataService(repository.NewMetadataRepository(db), repo)
+	// hash-chained log of sign-ins, registrations and administrative changes
+	auditSvc := service.NewAuditService(repository.NewAuditRepository(db))
+	orgSvc := service.NewOrganizationService(orgRepo, repo,
+		service.WithOrganizationMetadataClaims(metadataSvc),
+		service.WithOrganizationAuditLog(auditSvc),
+	)
+	groupSvc := service.NewGroupService(repository.NewGroupRepository(db, tenantOpts...), orgRepo)
+	consentSvc := service.NewConsentService(repository.NewConsentRepository(db))
+	authOpts := []middleware.AuthOption{
+		middleware.WithPersonalAccessTokens(tokenSvc),
+		middleware.WithPermissions(rbacSvc),
+		middleware.WithOrganizations(orgSvc),
+	}
+	// users with new mandatory legal documents to accept only reach the routes behind ungatedAuth
+	authMiddleware := middleware.NewAuthMiddleware(repo, append(authOpts, middleware.WithConsentGate(consentSvc))...)
+	ungatedAuth := middleware.NewAuthMiddleware(repo, authOpts...)
+
+	// adminOnly guards admin routes. Personal access tokens additionally need the admin scope.
+	// Changes made through them are audited.
+	auditAdmin := middleware.AuditAdminRequests(auditSvc)
+	adminOnly := func(permission string, next http.Handler) http.Handler {
+		return authMiddleware(middleware.RequireScope(service.ScopeAdmin)(middleware.RequirePermission(permission)(auditAdmin(next))))
+	}
+
+	var mailer mail.Mailer = mail.LogMailer{}
+	smtpCfg, err := mail.ConfigFromEnv()
+	if err != nil {
+		slog.Error("invalid smtp configuration", "error", err)
+		os.Exit(1)
+	}
+	if smtpCfg != nil {
+		mailer = mail.NewSMTPMailer(smtpCfg)
+	}
+
+	// verified organization domains, proved through DNS TXT records
+	domainSvc := service.NewDomainService(repository.NewDomainRepository(db, tenantOpts...), orgRepo, net.DefaultResolver)
+	verificationSecret := os.Getenv("EMAIL_VERIFICATION_SECRET")
+	if verificationSecret == "" {
+		verificationSecret = secret
+	}
+	var verificationOpts []service.EmailVerificationOption
+	if u := os.Getenv("EMAIL_VERIFICATION_URL"); u != "" {
+		verificationOpts = append(verificationOpts, service.WithEmailVerificationURL(u))
+	}
+	verificationSvc := service.NewEmailVerificationService(repo, domainSvc, mailer, []byte(verificationSecret), verificationOpts...)
+
+	// password logins are refused for domains that enforce single sign-on
+	var authenticator service.Authenticator = service.NewSSOEnforcingAuthenticator(service.NewLocalAuthenticator(repo), domainSvc)
+	ldapCfg, err := ldapauth.ConfigFromEnv()
+	if err != nil {
+		slog.Error("invalid ldap configuration", "error", err)
+		os.Exit(1)
+	}
+	if ldapCfg != nil {
+		ldapAuthn := ldapauth.NewAuthenticator(ldapCfg, repo)
+		domains := make(map[string]service.Authenticator, len(ldapCfg.Domains))
+		for _, domain := range ldapCfg.Domains {
+			domains[domain] = ldapAuthn
+		}
+		authenticator = service.NewDomainAuthenticator(authenticator, domains)
+		slog.Info("ldap authentication enabled", "domains", ldapCfg.Domains)
+	}
+
+	// uploaded files such as avatars live on disk unless an S3 bucket is configured
+	blobDir := os.Getenv("BLOB_DIR")
+	if blobDir == "" {
+		blobDir = "data/blobs"
+	}
+	var blobStore blob.Store = blob.NewFilesystemStore(blobDir)
+	s3Cfg, err := blob.S3ConfigFromEnv()
+	if err != nil {
+		slog.Error("invalid s3 configuration", "error", err)
+		os.Exit(1)
+	}
+	if s3Cfg != nil {
+		blobStore = blob.NewS3Store(s3Cfg)
+	}
+	// AVATAR_BASE_URL points at a CDN or public bucket, by default avatars are served by GET /avatars
+	avatarBaseURL := os.Getenv("AVATAR_BASE_URL")
+	if avatarBaseURL == "" {
+		avatarBaseURL = "http://localhost:8080"
+	}
+	avatarSvc := service.NewAvatarService(repo, blobStore, avatarBaseURL)
+
+	svc := service.NewUserService(repo,
+		service.WithAuthenticator(authenticator),
+		service.WithDomainPolicy(domainSvc),
+		service.WithEmailVerification(verificationSvc),
+		service.WithMetadataClaims(metadataSvc),
+		service.WithConsents(consentSvc),
+		service.WithAuditLog(auditSvc),
+		service.WithAvatars(avatarSvc),
+	)
+
+	// email changes are confirmed by the new address and can be undone from the old one
+	emailChangeOpts := []service.EmailChangeOption{}
+	if confirmURL, revertURL := os.Getenv("EMAIL_CHANGE_CONFIRM_URL"), os.Getenv("EMAIL_CHANGE_REVERT_URL"); confirmURL != "" && revertURL != "" {
+		emailChangeOpts = append(emailChangeOpts, service.WithEmailChangeURLs(confirmURL, revertURL))
+	}
+	emailChangeSvc := service.NewEmailChangeService(repository.NewEmailChangeRepository(db), repo, authenticator, domainSvc, mailer,
+		[]byte(verificationSecret), emailChangeOpts...)
+
+	// attribute-based policies, reloaded when files in the policy directory change
+	policyDir := os.Getenv("POLICY_DIR")
+	if policyDir == "" {
+		policyDir = "policies"
+	}
+	policyLocation := time.UTC
+	if tz := os.Getenv("POLICY_TIMEZONE"); tz != "" {
+		if policyLocation, err = time.LoadLocation(tz); err != nil {
+			slog.Error("invalid policy timezone", "error", err)
+			os.Exit(1)
+		}
+	}
+	policyEngine, err := policy.NewEngine(policyDir, policy.WithLocation(policyLocation))
+	if err != nil {
+		slog.Error("failed to load policies", "error", err)
+		os.Exit(1)
+	}
+	go policyEngine.Watch(ctx, 5*time.Second)
 
 	loginHandler := http.HandlerFunc(handlers.LoginHandler(svc))
 	registerHandler := http.HandlerFunc(handlers.RegisterHandler(svc))
@@ -86,12 +216,205 @@ func main() {
 	mux.Handle("POST /register", middleware.RateLimitMiddleware(rateLimit)(registerHandler))
 
 	mux.Handle("GET /health", http.HandlerFunc(healthHandler))
-	mux.Handle("GET /me", authMiddleware(http.HandlerFunc(meHandler)))
+	mux.Handle("GET /me", authMiddleware(middleware.RequireScope(service.ScopeUserRead)(handlers.MeHandler())))
+	mux.Handle("PATCH /me", authMiddleware(middleware.RequireScope(service.ScopeUserWrite)(handlers.UpdateMeHandler(svc))))
+	mux.Handle("PUT /me/avatar", authMiddleware(middleware.RequireScope(service.ScopeUserWrite)(handlers.UploadAvatarHandler(avatarSvc))))
+	mux.Handle("GET /avatars/{path...}", handlers.AvatarFileHandler(blobStore))
+
+	// deleted accounts can be restored for 30 days, then a background job purges them
+	deletionSvc := service.NewAccountDeletionService(repository.NewAccountDeletionRepository(db), repository.NewAccountStateRepository(db), authenticator, avatarSvc, auditSvc)
+	go deletionSvc.Run(ctx, time.Hour)
+	mux.Handle("DELETE /me", middleware.RateLimitMiddleware(rateLimit)(ungatedAuth(middleware.RequireSession(handlers.DeleteAccountHandler(deletionSvc)))))
+	mux.Handle("POST /account/restore", middleware.RateLimitMiddleware(rateLimit)(handlers.RestoreAccountHandler(deletionSvc)))
+
+	settingsSvc := service.NewSettingsService(repository.NewSettingsRepository(db), service.DefaultSettings)
+	mux.Handle("GET /me/settings", authMiddleware(middleware.RequireScope(service.ScopeUserRead)(handlers.GetSettingsHandler(settingsSvc))))
+	mux.Handle("PUT /me/settings", authMiddleware(middleware.RequireScope(service.ScopeUserWrite)(handlers.UpdateSettingsHandler(settingsSvc))))
+
+	// data exports are built in the background and the download link is emailed
+	exportOpts := []service.DataExportOption{}
+	if downloadURL := os.Getenv("DATA_EXPORT_DOWNLOAD_URL"); downloadURL != "" {
+		exportOpts = append(exportOpts, service.WithDataExportURL(downloadURL))
+	}
+	exportSvc := service.NewDataExportService(repository.NewDataExportRepository(db), repo, repository.NewTokenRepository(db),
+		repository.NewAccountStateRepository(db), repository.NewAuditRepository(db), settingsSvc, blobStore, mailer, []byte(verificationSecret), exportOpts...)
+	go exportSvc.Run(ctx, 10*time.Second)
+
+	// user lifecycle events are queued by the database and relayed to EVENTS_SINK
+	var eventSink events.Sink = events.LogSink{}
+	eventsCfg, err := events.ConfigFromEnv()
+	if err != nil {
+		slog.Error("invalid events configuration", "error", err)
+		os.Exit(1)
+	}
+	if eventsCfg != nil {
+		if eventSink, err = events.NewSink(eventsCfg); err != nil {
+			slog.Error("invalid events configuration", "error", err)
+			os.Exit(1)
+		}
+	}
+	outboxRelay := service.NewOutboxRelay(repository.NewOutboxRepository(db), eventSink)
+	go outboxRelay.Run(ctx, time.Second)
+	mux.Handle("POST /me/export", middleware.RateLimitMiddleware(rateLimit)(ungatedAuth(middleware.RequireSession(handlers.RequestDataExportHandler(exportSvc)))))
+	mux.Handle("GET /me/export", ungatedAuth(middleware.RequireSession(handlers.LatestDataExportHandler(exportSvc))))
+	mux.Handle("GET /exports/download", middleware.RateLimitMiddleware(rateLimit)(handlers.DownloadDataExportHandler(exportSvc)))
+
+	mux.Handle("GET /me/permissions", authMiddleware(middleware.RequireScope(service.ScopeUserRead)(handlers.MyPermissionsHandler())))
+	mux.Handle("GET /admin/roles", adminOnly(service.PermissionRolesRead, handlers.ListRolesHandler(rbacSvc)))
+
+	// versioned terms of service and privacy policy, accepted at registration and again for mandatory versions
+	mux.Handle("GET /legal/documents", handlers.CurrentLegalDocumentsHandler(consentSvc))
+	mux.Handle("GET /me/consents", ungatedAuth(handlers.ConsentStatusHandler(consentSvc)))
+	mux.Handle("POST /me/consents", ungatedAuth(middleware.RequireSession(handlers.AcceptDocumentsHandler(consentSvc))))
+	mux.Handle("PUT /me/consents/marketing", ungatedAuth(middleware.RequireSession(handlers.MarketingConsentHandler(consentSvc))))
+	mux.Handle("GET /admin/legal/documents", adminOnly(service.PermissionLegalManage, handlers.ListLegalDocumentsHandler(consentSvc)))
+	mux.Handle("POST /admin/legal/documents", adminOnly(service.PermissionLegalManage, handlers.PublishLegalDocumentHandler(consentSvc)))
+
+	// user administration
+	adminUserSvc := service.NewAdminUserService(repo, auditSvc)
+	// suspensions with an expiry are lifted by a background job
+	accountStateSvc := service.NewAccountStateService(repository.NewAccountStateRepository(db), repo, auditSvc)
+	go accountStateSvc.Run(ctx, time.Minute)
+	mux.Handle("GET /admin/users", adminOnly(service.PermissionUsersRead, handlers.ListUsersHandler(adminUserSvc)))
+	mux.Handle("GET /admin/users/search", adminOnly(service.PermissionUsersRead, handlers.SearchUsersHandler(adminUserSvc)))
+	mux.Handle("GET /admin/users/{id}", adminOnly(service.PermissionUsersRead, handlers.GetUserHandler(adminUserSvc)))
+	mux.Handle("PATCH /admin/users/{id}", adminOnly(service.PermissionUsersWrite, handlers.UpdateUserHandler(adminUserSvc)))
+	mux.Handle("DELETE /admin/users/{id}", adminOnly(service.PermissionUsersDelete, handlers.DeleteUserHandler(adminUserSvc)))
+	mux.Handle("POST /admin/users/{id}/state", adminOnly(service.PermissionUsersWrite, handlers.TransitionUserStateHandler(accountStateSvc)))
+	mux.Handle("GET /admin/users/{id}/state/history", adminOnly(service.PermissionUsersRead, handlers.UserStateHistoryHandler(accountStateSvc)))
+
+	// audit log
+	mux.Handle("GET /admin/audit", adminOnly(service.PermissionAuditRead, handlers.ListAuditEventsHandler(auditSvc)))
+	mux.Handle("GET /admin/audit/verify", adminOnly(service.PermissionAuditRead, handlers.VerifyAuditLogHandler(auditSvc)))
+	mux.Handle("GET /me/activity", authMiddleware(middleware.RequireScope(service.ScopeUserRead)(handlers.MyActivityHandler(auditSvc))))
+
+	// user metadata
+	mux.Handle("GET /admin/metadata/namespaces", adminOnly(service.PermissionMetadataManage, handlers.ListMetadataNamespacesHandler(metadataSvc)))
+	mux.Handle("PUT /admin/metadata/namespaces/{name}", adminOnly(service.PermissionMetadataManage, handlers.PutMetadataNamespaceHandler(metadataSvc)))
+	mux.Handle("DELETE /admin/metadata/namespaces/{name}", adminOnly(service.PermissionMetadataManage, handlers.DeleteMetadataNamespaceHandler(metadataSvc)))
+	mux.Handle("GET /admin/users/{id}/metadata", adminOnly(service.PermissionUsersRead, handlers.GetUserMetadataHandler(metadataSvc)))
+	mux.Handle("PUT /admin/users/{id}/metadata/{visibility}/{namespace}", adminOnly(service.PermissionMetadataManage, handlers.SetUserMetadataHandler(metadataSvc)))
+	mux.Handle("DELETE /admin/users/{id}/metadata/{visibility}/{namespace}", adminOnly(service.PermissionMetadataManage, handlers.DeleteUserMetadataHandler(metadataSvc)))
+
+	// personal access tokens
+	mux.Handle("GET /me/tokens", authMiddleware(middleware.RequireScope(service.ScopeUserRead)(handlers.ListTokensHandler(tokenSvc))))
+	mux.Handle("POST /me/tokens", authMiddleware(middleware.RequireSession(handlers.CreateTokenHandler(tokenSvc))))
+	mux.Handle("DELETE /me/tokens/{id}", authMiddleware(middleware.RequireSession(handlers.DeleteTokenHandler(tokenSvc))))
+
+	// organizations
+	mux.Handle("GET /orgs", authMiddleware(middleware.RequireScope(service.ScopeUserRead)(handlers.ListOrganizationsHandler(orgSvc))))
+	mux.Handle("POST /orgs", authMiddleware(middleware.RequireScope(service.ScopeUserWrite)(handlers.CreateOrganizationHandler(orgSvc))))
+	mux.Handle("GET /orgs/current", authMiddleware(middleware.RequireScope(service.ScopeUserRead)(handlers.CurrentOrganizationHandler(orgSvc))))
+	mux.Handle("POST /orgs/switch", authMiddleware(middleware.RequireSession(handlers.SwitchOrganizationHandler(orgSvc))))
+	mux.Handle("GET /orgs/{id}", authMiddleware(middleware.RequireScope(service.ScopeUserRead)(handlers.GetOrganizationHandler(orgSvc))))
+	mux.Handle("GET /orgs/{id}/members", authMiddleware(middleware.RequireScope(service.ScopeUserRead)(handlers.ListOrganizationMembersHandler(orgSvc))))
+	mux.Handle("POST /orgs/{id}/members", authMiddleware(middleware.RequireScope(service.ScopeUserWrite)(handlers.AddOrganizationMemberHandler(orgSvc))))
+	mux.Handle("PATCH /orgs/{id}/members/{userID}", authMiddleware(middleware.RequireScope(service.ScopeUserWrite)(handlers.UpdateOrganizationMemberHandler(orgSvc))))
+	mux.Handle("DELETE /orgs/{id}/members/{userID}", authMiddleware(middleware.RequireScope(service.ScopeUserWrite)(handlers.RemoveOrganizationMemberHandler(orgSvc))))
+
+	// organization groups
+	mux.Handle("GET /orgs/{id}/groups", authMiddleware(middleware.RequireScope(service.ScopeUserRead)(handlers.ListGroupsHandler(groupSvc))))
+	mux.Handle("POST /orgs/{id}/groups", authMiddleware(middleware.RequireScope(service.ScopeUserWrite)(handlers.CreateGroupHandler(groupSvc))))
+	mux.Handle("GET /orgs/{id}/groups/{groupID}", authMiddleware(middleware.RequireScope(service.ScopeUserRead)(handlers.GetGroupHandler(groupSvc))))
+	mux.Handle("PATCH /orgs/{id}/groups/{groupID}", authMiddleware(middleware.RequireScope(service.ScopeUserWrite)(handlers.UpdateGroupHandler(groupSvc))))
+	mux.Handle("DELETE /orgs/{id}/groups/{groupID}", authMiddleware(middleware.RequireScope(service.ScopeUserWrite)(handlers.DeleteGroupHandler(groupSvc))))
+	mux.Handle("GET /orgs/{id}/groups/{groupID}/members", authMiddleware(middleware.RequireScope(service.ScopeUserRead)(handlers.ListGroupMembersHandler(groupSvc))))
+	mux.Handle("PUT /orgs/{id}/groups/{groupID}/members/{userID}", authMiddleware(middleware.RequireScope(service.ScopeUserWrite)(handlers.AddGroupMemberHandler(groupSvc))))
+	mux.Handle("DELETE /orgs/{id}/groups/{groupID}/members/{userID}", authMiddleware(middleware.RequireScope(service.ScopeUserWrite)(handlers.RemoveGroupMemberHandler(groupSvc))))
+
+	// organization invitations
+	invitationSecret := os.Getenv("INVITATION_SECRET")
+	if invitationSecret == "" {
+		invitationSecret = secret
+	}
+	invitationOpts := []service.InvitationOption{
+		service.WithInvitationDomains(domainSvc),
+		service.WithInvitationConsents(consentSvc),
+		service.WithInvitationAuditLog(auditSvc),
+	}
+	if u := os.Getenv("INVITATION_URL"); u != "" {
+		invitationOpts = append(invitationOpts, service.WithInvitationURL(u))
+	}
+	invitationSvc := service.NewInvitationService(repository.NewInvitationRepository(db, tenantOpts...), orgRepo, repo, mailer, []byte(invitationSecret), invitationOpts...)
+	mux.Handle("GET /orgs/{id}/invitations", authMiddleware(middleware.RequireScope(service.ScopeUserRead)(handlers.ListInvitationsHandler(invitationSvc))))
+	mux.Handle("POST /orgs/{id}/invitations", authMiddleware(middleware.RequireScope(service.ScopeUserWrite)(handlers.CreateInvitationHandler(invitationSvc))))
+	mux.Handle("DELETE /orgs/{id}/invitations/{invitationID}", authMiddleware(middleware.RequireScope(service.ScopeUserWrite)(handlers.RevokeInvitationHandler(invitationSvc))))
+	mux.Handle("POST /orgs/{id}/invitations/{invitationID}/resend", authMiddleware(middleware.RequireScope(service.ScopeUserWrite)(handlers.ResendInvitationHandler(invitationSvc))))
+	mux.Handle("GET /invitations/{token}", middleware.RateLimitMiddleware(rateLimit)(handlers.PreviewInvitationHandler(invitationSvc)))
+	mux.Handle("POST /invitations/accept", authMiddleware(middleware.RequireSession(handlers.AcceptInvitationHandler(invitationSvc))))
+	mux.Handle("POST /invitations/register", middleware.RateLimitMiddleware(rateLimit)(handlers.RegisterInvitationHandler(invitationSvc)))
+
+	// organization domains, email verification and email changes
+	mux.Handle("GET /orgs/{id}/domains", authMiddleware(middleware.RequireScope(service.ScopeUserRead)(handlers.ListDomainsHandler(domainSvc))))
+	mux.Handle("POST /orgs/{id}/domains", authMiddleware(middleware.RequireScope(service.ScopeUserWrite)(handlers.ClaimDomainHandler(domainSvc))))
+	mux.Handle("POST /orgs/{id}/domains/{domainID}/verify", authMiddleware(middleware.RequireScope(service.ScopeUserWrite)(handlers.VerifyDomainHandler(domainSvc))))
+	mux.Handle("PATCH /orgs/{id}/domains/{domainID}", authMiddleware(middleware.RequireScope(service.ScopeUserWrite)(handlers.UpdateDomainHandler(domainSvc))))
+	mux.Handle("DELETE /orgs/{id}/domains/{domainID}", authMiddleware(middleware.RequireScope(service.ScopeUserWrite)(handlers.DeleteDomainHandler(domainSvc))))
+	mux.Handle("POST /me/email/verification", authMiddleware(middleware.RequireSession(handlers.SendEmailVerificationHandler(verificationSvc))))
+	mux.Handle("POST /email/verify", middleware.RateLimitMiddleware(rateLimit)(handlers.VerifyEmailHandler(verificationSvc)))
+	mux.Handle("POST /me/email", middleware.RateLimitMiddleware(rateLimit)(authMiddleware(middleware.RequireSession(handlers.RequestEmailChangeHandler(emailChangeSvc)))))
+	mux.Handle("POST /email/change/confirm", middleware.RateLimitMiddleware(rateLimit)(handlers.ConfirmEmailChangeHandler(emailChangeSvc)))
+	mux.Handle("POST /email/change/revert", middleware.RateLimitMiddleware(rateLimit)(handlers.RevertEmailChangeHandler(emailChangeSvc)))
+
+	// scim provisioning
+	scimSvc := service.NewSCIMService(repository.NewSCIMRepository(db), auditSvc)
+	scimAuth := middleware.NewSCIMAuthMiddleware(scimSvc)
+	mux.Handle("POST /admin/scim/tenants", adminOnly(service.PermissionSCIMManage, handlers.CreateSCIMTenantHandler(scimSvc)))
+
+	mux.Handle("GET /scim/v2/ServiceProviderConfig", handlers.SCIMServiceProviderConfigHandler())
+	mux.Handle("GET /scim/v2/ResourceTypes", handlers.SCIMResourceTypesHandler())
+	mux.Handle("GET /scim/v2/ResourceTypes/{id}", handlers.SCIMResourceTypesHandler())
+	mux.Handle("GET /scim/v2/Schemas", handlers.SCIMSchemasHandler())
+	mux.Handle("GET /scim/v2/Schemas/{id}", handlers.SCIMSchemasHandler())
+
+	mux.Handle("GET /scim/v2/Users", scimAuth(handlers.SCIMListUsersHandler(scimSvc)))
+	mux.Handle("POST /scim/v2/Users", scimAuth(handlers.SCIMCreateUserHandler(scimSvc)))
+	mux.Handle("GET /scim/v2/Users/{id}", scimAuth(handlers.SCIMGetUserHandler(scimSvc)))
+	mux.Handle("PUT /scim/v2/Users/{id}", scimAuth(handlers.SCIMReplaceUserHandler(scimSvc)))
+	mux.Handle("PATCH /scim/v2/Users/{id}", scimAuth(handlers.SCIMPatchUserHandler(scimSvc)))
+	mux.Handle("DELETE /scim/v2/Users/{id}", scimAuth(handlers.SCIMDeleteUserHandler(scimSvc)))
+
+	mux.Handle("GET /scim/v2/Groups", scimAuth(handlers.SCIMListGroupsHandler(scimSvc)))
+	mux.Handle("POST /scim/v2/Groups", scimAuth(handlers.SCIMCreateGroupHandler(scimSvc)))
+	mux.Handle("GET /scim/v2/Groups/{id}", scimAuth(handlers.SCIMGetGroupHandler(scimSvc)))
+	mux.Handle("PUT /scim/v2/Groups/{id}", scimAuth(handlers.SCIMReplaceGroupHandler(scimSvc)))
+	mux.Handle("PATCH /scim/v2/Groups/{id}", scimAuth(handlers.SCIMPatchGroupHandler(scimSvc)))
+	mux.Handle("DELETE /scim/v2/Groups/{id}", scimAuth(handlers.SCIMDeleteGroupHandler(scimSvc)))
+
+	// service accounts
+	serviceAccountSvc := service.NewServiceAccountService(repository.NewServiceAccountRepository(db))
+	principalAuth := middleware.NewAuthMiddleware(repo,
+		middleware.WithPersonalAccessTokens(tokenSvc),
+		middleware.WithServiceAccounts(serviceAccountSvc),
+		middleware.WithPermissions(rbacSvc),
+		middleware.WithOrganizations(orgSvc),
+	)
+
+	mux.Handle("POST /oauth/token", middleware.RateLimitMiddleware(rateLimit)(handlers.ServiceAccountTokenHandler(serviceAccountSvc, os.Getenv("OAUTH_TOKEN_AUDIENCE"))))
+	mux.Handle("GET /service-accounts/me", principalAuth(handlers.ServiceAccountMeHandler()))
+
+	mux.Handle("GET /admin/service-accounts", adminOnly(service.PermissionServiceAccountsRead, handlers.ListServiceAccountsHandler(serviceAccountSvc)))
+	mux.Handle("POST /admin/service-accounts", adminOnly(service.PermissionServiceAccountsWrite, handlers.CreateServiceAccountHandler(serviceAccountSvc)))
+	mux.Handle("GET /admin/service-accounts/{id}", adminOnly(service.PermissionServiceAccountsRead, handlers.GetServiceAccountHandler(serviceAccountSvc)))
+	mux.Handle("DELETE /admin/service-accounts/{id}", adminOnly(service.PermissionServiceAccountsWrite, handlers.DeleteServiceAccountHandler(serviceAccountSvc)))
+	mux.Handle("GET /admin/service-accounts/{id}/keys", adminOnly(service.PermissionServiceAccountsRead, handlers.ListServiceAccountKeysHandler(serviceAccountSvc)))
+	mux.Handle("POST /admin/service-accounts/{id}/keys", adminOnly(service.PermissionServiceAccountsWrite, handlers.CreateServiceAccountKeyHandler(serviceAccountSvc)))
+	mux.Handle("DELETE /admin/service-accounts/{id}/keys/{keyID}", adminOnly(service.PermissionServiceAccountsWrite, handlers.DeleteServiceAccountKeyHandler(serviceAccountSvc)))
+
+	// authorization decisions for other services
+	authzSvc := service.NewAuthzService(repo, rbacSvc, policyEngine, tokenSvc, serviceAccountSvc,
+		service.WithOrganizationContext(orgSvc, groupSvc),
+	)
+	authzGuard := func(next http.Handler) http.Handler {
+		return principalAuth(middleware.RequireScope(service.ScopeAdmin)(middleware.RequirePermissionOrServiceAccount(service.PermissionAuthzCheck)(next)))
+	}
+	mux.Handle("POST /authz/check", authzGuard(handlers.CheckAuthzHandler(authzSvc, authzDecisionMaxAge)))
+	mux.Handle("POST /authz/check-many", authzGuard(handlers.CheckManyAuthzHandler(authzSvc, authzDecisionMaxAge)))
 
 	// server
 	srv := &http.Server{
 		Addr:    ":8080",
-		Handler: mux,
+		Handler: middleware.RequestInfo(mux),
 	}
 
 	// graceful shutdown
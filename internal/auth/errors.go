@@ -9,4 +9,7 @@ var (
 	ErrInvalidUserId  = errors.New("invalid user ID")
 	ErrUserIsNil      = errors.New("user is nil")
 	ErrInvalidToken   = errors.New("invalid token")
+
+	ErrInvalidServiceAccountID = errors.New("invalid service account ID")
+	ErrUnknownPrincipalType    = errors.New("unknown principal type")
 )
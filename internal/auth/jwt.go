@@ -1,6 +1,7 @@
 package auth
 
 import (
+	"encoding/json"
 	"fmt"
 	"time"
 
@@ -8,12 +9,43 @@ import (
 	"github.com/golang-jwt/jwt/v5"
 )
 
+// Principal types carried in the principal_type claim so downstream services
+// can tell people and machines apart.
+const (
+	PrincipalUser           = "user"
+	PrincipalServiceAccount = "service_account"
+)
+
 type Claims struct {
-	UserID int64  `json:"user_id"`
-	Role   string `json:"user_role"`
+	UserID           int64  `json:"user_id,omitempty"`
+	ServiceAccountID int64  `json:"service_account_id,omitempty"`
+	Role             string `json:"user_role"`
+	PrincipalType    string `json:"principal_type"`
+	// OrgID is the user's active organization, OrgRole their role in it at issue time.
+	OrgID   int64  `json:"org_id,omitempty"`
+	OrgRole string `json:"org_role,omitempty"`
+	// Metadata holds the public metadata namespaces flagged for token claims.
+	Metadata map[string]json.RawMessage `json:"metadata,omitempty"`
 	jwt.RegisteredClaims
 }
 
+type TokenOption func(*Claims)
+
+// WithOrganization sets the active organization claims.
+func WithOrganization(orgID int64, role string) TokenOption {
+	return func(c *Claims) {
+		c.OrgID = orgID
+		c.OrgRole = role
+	}
+}
+
+// WithMetadata sets the metadata claim.
+func WithMetadata(metadata map[string]json.RawMessage) TokenOption {
+	return func(c *Claims) {
+		c.Metadata = metadata
+	}
+}
+
 var JwtSecret []byte
 
 func InitJWT(secret string) error {
@@ -24,7 +56,7 @@ func InitJWT(secret string) error {
 	return nil
 }
 
-func GenerateToken(user *models.User, duration time.Duration, jwtSecret []byte) (string, error) {
+func GenerateToken(user *models.User, duration time.Duration, jwtSecret []byte, opts ...TokenOption) (string, error) {
 	if len(jwtSecret) == 0 {
 		return "", ErrEmptyJwtSecret
 	}
@@ -38,13 +70,17 @@ func GenerateToken(user *models.User, duration time.Duration, jwtSecret []byte)
 	}
 
 	claims := Claims{
-		UserID: user.ID,
-		Role:   user.Role,
+		UserID:        user.ID,
+		Role:          user.Role,
+		PrincipalType: PrincipalUser,
 		RegisteredClaims: jwt.RegisteredClaims{
 			ExpiresAt: jwt.NewNumericDate(time.Now().Add(duration)),
 			IssuedAt:  jwt.NewNumericDate(time.Now()),
 		},
 	}
+	for _, opt := range opts {
+		opt(&claims)
+	}
 
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
 
@@ -52,13 +88,36 @@ func GenerateToken(user *models.User, duration time.Duration, jwtSecret []byte)
 	return tokenString, err
 }
 
-func ValidateToken(tokenString string, jwtSecret []byte) (*models.User, error) {
+func GenerateServiceAccountToken(sa *models.ServiceAccount, duration time.Duration, jwtSecret []byte) (string, error) {
+	if len(jwtSecret) == 0 {
+		return "", ErrEmptyJwtSecret
+	}
+	if sa == nil || sa.ID < 1 {
+		return "", ErrInvalidServiceAccountID
+	}
+
+	claims := Claims{
+		ServiceAccountID: sa.ID,
+		PrincipalType:    PrincipalServiceAccount,
+		RegisteredClaims: jwt.RegisteredClaims{
+			Subject:   sa.Name,
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(duration)),
+			IssuedAt:  jwt.NewNumericDate(time.Now()),
+		},
+	}
+
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	return token.SignedString(jwtSecret)
+}
+
+// ParseToken verifies the signature and time claims and returns the claims of
+// any principal type.
+func ParseToken(tokenString string, jwtSecret []byte) (*Claims, error) {
 	if len(jwtSecret) == 0 {
 		return nil, ErrEmptyJwtSecret
 	}
 
 	claims := &Claims{}
-
 	token, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
 		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
 			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
@@ -73,14 +132,42 @@ func ValidateToken(tokenString string, jwtSecret []byte) (*models.User, error) {
 		return nil, ErrInvalidToken
 	}
 
-	if claims.UserID == 0 {
-		return nil, ErrInvalidUserId
-	}
-
 	if claims.IssuedAt != nil && claims.IssuedAt.Unix() > time.Now().Unix() {
 		return nil, jwt.ErrTokenNotValidYet
 	}
 
+	// tokens issued before principal_type existed were always for users
+	if claims.PrincipalType == "" {
+		claims.PrincipalType = PrincipalUser
+	}
+
+	switch claims.PrincipalType {
+	case PrincipalUser:
+		if claims.UserID == 0 {
+			return nil, ErrInvalidUserId
+		}
+	case PrincipalServiceAccount:
+		if claims.ServiceAccountID == 0 {
+			return nil, ErrInvalidServiceAccountID
+		}
+	default:
+		return nil, ErrUnknownPrincipalType
+	}
+
+	return claims, nil
+}
+
+// ValidateToken only accepts user tokens, service account tokens are rejected.
+func ValidateToken(tokenString string, jwtSecret []byte) (*models.User, error) {
+	claims, err := ParseToken(tokenString, jwtSecret)
+	if err != nil {
+		return nil, err
+	}
+
+	if claims.PrincipalType != PrincipalUser {
+		return nil, ErrUnknownPrincipalType
+	}
+
 	user := &models.User{
 		ID:   claims.UserID,
 		Role: claims.Role,
@@ -1,6 +1,7 @@
 package auth
 
 import (
+	"encoding/json"
 	"testing"
 	"time"
 
@@ -214,3 +215,104 @@ func generateTokenWithoutUserID(t *testing.T, user *models.User) string {
 	}
 	return s
 }
+
+func TestServiceAccountToken(t *testing.T) {
+	secret := []byte("secret")
+	sa := &models.ServiceAccount{ID: 5, Name: "ci-deployer"}
+
+	token, err := GenerateServiceAccountToken(sa, time.Hour, secret)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	claims, err := ParseToken(token, secret)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if claims.PrincipalType != PrincipalServiceAccount {
+		t.Errorf("expected principal_type %q, got %q", PrincipalServiceAccount, claims.PrincipalType)
+	}
+	if claims.ServiceAccountID != sa.ID || claims.UserID != 0 {
+		t.Errorf("unexpected principal ids: service account %d, user %d", claims.ServiceAccountID, claims.UserID)
+	}
+	if claims.Subject != sa.Name {
+		t.Errorf("expected subject %q, got %q", sa.Name, claims.Subject)
+	}
+
+	if _, err := ValidateToken(token, secret); err == nil {
+		t.Errorf("expected user validation to reject a service account token")
+	}
+
+	if _, err := GenerateServiceAccountToken(&models.ServiceAccount{}, time.Hour, secret); err != ErrInvalidServiceAccountID {
+		t.Errorf("expected ErrInvalidServiceAccountID, got %v", err)
+	}
+}
+
+func TestParseToken_PrincipalType(t *testing.T) {
+	JwtSecret = []byte("secret")
+	user := &models.User{ID: 1, Role: "user"}
+
+	userToken, _ := GenerateToken(user, time.Hour, JwtSecret)
+	claims, err := ParseToken(userToken, JwtSecret)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if claims.PrincipalType != PrincipalUser {
+		t.Errorf("expected principal_type %q, got %q", PrincipalUser, claims.PrincipalType)
+	}
+
+	legacy := generateTamperedToken(t, user, func(c *Claims) { c.PrincipalType = "" }, jwt.SigningMethodHS256)
+	if got, err := ValidateToken(legacy, JwtSecret); err != nil || got.ID != user.ID {
+		t.Errorf("expected token without principal_type to be accepted as a user, got %v, %v", got, err)
+	}
+
+	unknown := generateTamperedToken(t, user, func(c *Claims) { c.PrincipalType = "robot" }, jwt.SigningMethodHS256)
+	if _, err := ParseToken(unknown, JwtSecret); err != ErrUnknownPrincipalType {
+		t.Errorf("expected ErrUnknownPrincipalType, got %v", err)
+	}
+
+	forged := generateTamperedToken(t, user, func(c *Claims) { c.PrincipalType = PrincipalServiceAccount }, jwt.SigningMethodHS256)
+	if _, err := ParseToken(forged, JwtSecret); err != ErrInvalidServiceAccountID {
+		t.Errorf("expected ErrInvalidServiceAccountID, got %v", err)
+	}
+}
+
+func TestGenerateToken_WithOrganization(t *testing.T) {
+	JwtSecret = []byte("secret")
+	user := &models.User{ID: 1, Role: "user"}
+
+	token, err := GenerateToken(user, time.Hour, JwtSecret, WithOrganization(42, "admin"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	claims, err := ParseToken(token, JwtSecret)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if claims.OrgID != 42 || claims.OrgRole != "admin" {
+		t.Errorf("expected org 42 with role admin, got %d %q", claims.OrgID, claims.OrgRole)
+	}
+
+	token, _ = GenerateToken(user, time.Hour, JwtSecret)
+	claims, _ = ParseToken(token, JwtSecret)
+	if claims.OrgID != 0 || claims.OrgRole != "" {
+		t.Errorf("expected no organization claims, got %d %q", claims.OrgID, claims.OrgRole)
+	}
+}
+
+func TestGenerateToken_WithMetadata(t *testing.T) {
+	JwtSecret = []byte("secret")
+	user := &models.User{ID: 1, Role: "user"}
+
+	token, err := GenerateToken(user, time.Hour, JwtSecret, WithMetadata(map[string]json.RawMessage{"billing": json.RawMessage(`{"plan":"pro"}`)}))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	claims, err := ParseToken(token, JwtSecret)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if string(claims.Metadata["billing"]) != `{"plan":"pro"}` {
+		t.Errorf("expected billing metadata claim, got %s", claims.Metadata["billing"])
+	}
+}
@@ -0,0 +1,42 @@
+// Package blob stores binary objects such as avatars outside the database.
+package blob
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strings"
+)
+
+var (
+	ErrNotFound   = errors.New("blob not found")
+	ErrInvalidKey = errors.New("invalid blob key")
+)
+
+// Info describes a stored object.
+type Info struct {
+	ContentType string
+	Size        int64
+}
+
+// Store keeps objects under slash-separated keys like "avatars/7/1a2b/64.jpg".
+type Store interface {
+	Put(ctx context.Context, key string, body io.Reader, size int64, contentType string) error
+	// Get returns ErrNotFound for unknown keys. Callers close the reader.
+	Get(ctx context.Context, key string) (io.ReadCloser, *Info, error)
+	// Delete succeeds for keys that do not exist.
+	Delete(ctx context.Context, key string) error
+}
+
+// checkKey rejects keys that could escape the store's root.
+func checkKey(key string) error {
+	if key == "" || strings.HasPrefix(key, "/") || strings.HasSuffix(key, "/") || strings.Contains(key, "\\") {
+		return ErrInvalidKey
+	}
+	for _, part := range strings.Split(key, "/") {
+		if part == "" || part == "." || part == ".." {
+			return ErrInvalidKey
+		}
+	}
+	return nil
+}
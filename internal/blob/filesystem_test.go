@@ -0,0 +1,35 @@
+package blob
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestFilesystemStore(t *testing.T) {
+	ctx := context.Background()
+	store := NewFilesystemStore(t.TempDir())
+
+	require.NoError(t, store.Put(ctx, "avatars/7/v1/64.jpg", bytes.NewReader([]byte("jpeg")), 4, "image/jpeg"))
+	body, info, err := store.Get(ctx, "avatars/7/v1/64.jpg")
+	require.NoError(t, err)
+	data, _ := io.ReadAll(body)
+	body.Close()
+	require.Equal(t, "jpeg", string(data))
+	require.Equal(t, &Info{ContentType: "image/jpeg", Size: 4}, info)
+
+	_, _, err = store.Get(ctx, "avatars/7")
+	require.ErrorIs(t, err, ErrNotFound)
+
+	require.NoError(t, store.Delete(ctx, "avatars/7/v1/64.jpg"))
+	_, _, err = store.Get(ctx, "avatars/7/v1/64.jpg")
+	require.ErrorIs(t, err, ErrNotFound)
+	require.NoError(t, store.Delete(ctx, "avatars/7/v1/64.jpg"))
+
+	for _, key := range []string{"", "/etc/passwd", "avatars/../../etc/passwd", "avatars//64.jpg", "avatars/", `avatars\64.jpg`} {
+		require.ErrorIs(t, store.Put(ctx, key, bytes.NewReader(nil), 0, "image/jpeg"), ErrInvalidKey, key)
+	}
+}
@@ -0,0 +1,194 @@
+// Package events publishes user lifecycle events to other services.
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/url"
+	"os"
+	"strconv"
+	"time"
+)
+
+// Event types.
+const (
+	UserRegistered = "user.registered"
+	UserUpdated    = "user.updated"
+	// UserDeleted follows when a deleted account is purged or an account is
+	// removed. Deleting an account is a UserUpdated to the deleted state
+	// first, it can be restored until it is purged.
+	UserDeleted = "user.deleted"
+)
+
+// Source names this service in events.
+const Source = "user-service"
+
+var (
+	ErrUnknownSink = errors.New("EVENTS_SINK must be one of redis, nats or http")
+	ErrNoSinkAddr  = errors.New("EVENTS_NATS_URL or EVENTS_HTTP_URL is required for the chosen sink")
+)
+
+// Event is the envelope every sink delivers. Delivery is at least once:
+// consumers skip IDs they have seen and check Version before decoding Data.
+type Event struct {
+	ID         string          `json:"id"`
+	Type       string          `json:"type"`
+	Version    int             `json:"version"`
+	Source     string          `json:"source"`
+	OccurredAt time.Time       `json:"occurred_at"`
+	Data       json.RawMessage `json:"data"`
+}
+
+// UserData is version 1 of the data of user.registered and user.updated.
+// user.deleted only carries the id.
+type UserData struct {
+	ID            int64     `json:"id"`
+	Email         string    `json:"email,omitempty"`
+	Username      string    `json:"username,omitempty"`
+	DisplayName   string    `json:"display_name,omitempty"`
+	Locale        string    `json:"locale,omitempty"`
+	Timezone      string    `json:"timezone,omitempty"`
+	AvatarURL     string    `json:"avatar_url,omitempty"`
+	Role          string    `json:"role,omitempty"`
+	State         string    `json:"state,omitempty"`
+	EmailVerified bool      `json:"email_verified,omitempty"`
+	CreatedAt     time.Time `json:"created_at,omitzero"`
+	UpdatedAt     time.Time `json:"updated_at,omitzero"`
+}
+
+// Sink delivers events to other services. Publish returns nil only once the
+// event has been accepted, anything else is retried.
+type Sink interface {
+	Publish(ctx context.Context, event *Event) error
+}
+
+// LogSink writes events to the log instead of publishing them. It is used
+// when no sink is configured.
+type LogSink struct {
+	Logger *slog.Logger
+}
+
+func (s LogSink) Publish(ctx context.Context, event *Event) error {
+	logger := s.Logger
+	if logger == nil {
+		logger = slog.Default()
+	}
+	logger.InfoContext(ctx, "event not published, no sink configured", "event_id", event.ID, "type", event.Type)
+	return nil
+}
+
+// Config selects the sink and how to reach it.
+type Config struct {
+	// Sink is redis, nats or http.
+	Sink string
+
+	RedisAddr     string
+	RedisPassword string
+	RedisDB       int
+	// RedisStream is the stream events are added to.
+	RedisStream string
+
+	// NATSURL lists the servers separated by commas, tls:// urls require TLS.
+	NATSURL      string
+	NATSUser     string
+	NATSPassword string
+	NATSToken    string
+	// NATSCredsFile holds the user JWT and nkey seed of decentralized auth,
+	// NATSNKeySeedFile the seed of a plain nkey user.
+	NATSCredsFile    string
+	NATSNKeySeedFile string
+	// NATSCAFile verifies the server certificate in place of the system roots.
+	NATSCAFile string
+	// NATSJetStream waits for the stream to acknowledge each event.
+	NATSJetStream bool
+	// NATSSubjectPrefix is prepended to the event type, e.g. events.user.registered.
+	NATSSubjectPrefix string
+
+	HTTPURL string
+	// HTTPSecret signs request bodies, see HTTPSink.
+	HTTPSecret string
+}
+
+func envOr(name, fallback string) string {
+	if v := os.Getenv(name); v != "" {
+		return v
+	}
+	return fallback
+}
+
+// ConfigFromEnv reads the EVENTS_* variables. It returns nil when EVENTS_SINK
+// is unset. The redis sink falls back to the REDIS_* variables.
+func ConfigFromEnv() (*Config, error) {
+	sink := os.Getenv("EVENTS_SINK")
+	if sink == "" {
+		return nil, nil
+	}
+	cfg := &Config{
+		Sink:              sink,
+		RedisAddr:         envOr("EVENTS_REDIS_ADDR", envOr("REDIS_ADDR", "redis:6379")),
+		RedisPassword:     envOr("EVENTS_REDIS_PASSWORD", os.Getenv("REDIS_PASSWORD")),
+		RedisStream:       envOr("EVENTS_REDIS_STREAM", "user-events"),
+		NATSURL:           os.Getenv("EVENTS_NATS_URL"),
+		NATSUser:          os.Getenv("EVENTS_NATS_USER"),
+		NATSPassword:      os.Getenv("EVENTS_NATS_PASSWORD"),
+		NATSToken:         os.Getenv("EVENTS_NATS_TOKEN"),
+		NATSCredsFile:     os.Getenv("EVENTS_NATS_CREDS_FILE"),
+		NATSNKeySeedFile:  os.Getenv("EVENTS_NATS_NKEY_SEED_FILE"),
+		NATSCAFile:        os.Getenv("EVENTS_NATS_CA_FILE"),
+		NATSSubjectPrefix: envOr("EVENTS_NATS_SUBJECT_PREFIX", "events"),
+		HTTPURL:           os.Getenv("EVENTS_HTTP_URL"),
+		HTTPSecret:        os.Getenv("EVENTS_HTTP_SECRET"),
+	}
+	if db := envOr("EVENTS_REDIS_DB", os.Getenv("REDIS_DB")); db != "" {
+		n, err := strconv.Atoi(db)
+		if err != nil {
+			return nil, fmt.Errorf("invalid EVENTS_REDIS_DB: %w", err)
+		}
+		cfg.RedisDB = n
+	}
+	if v := os.Getenv("EVENTS_NATS_JETSTREAM"); v != "" {
+		enabled, err := strconv.ParseBool(v)
+		if err != nil {
+			return nil, fmt.Errorf("invalid EVENTS_NATS_JETSTREAM: %w", err)
+		}
+		cfg.NATSJetStream = enabled
+	}
+
+	switch sink {
+	case "redis":
+	case "nats":
+		if cfg.NATSURL == "" {
+			return nil, ErrNoSinkAddr
+		}
+	case "http":
+		if cfg.HTTPURL == "" {
+			return nil, ErrNoSinkAddr
+		}
+		if _, err := url.ParseRequestURI(cfg.HTTPURL); err != nil {
+			return nil, fmt.Errorf("invalid EVENTS_HTTP_URL: %w", err)
+		}
+	default:
+		return nil, ErrUnknownSink
+	}
+	return cfg, nil
+}
+
+// NewSink returns the sink cfg selects.
+func NewSink(cfg *Config) (Sink, error) {
+	switch cfg.Sink {
+	case "redis":
+		return NewRedisStreamSink(cfg.RedisAddr, cfg.RedisPassword, cfg.RedisDB, cfg.RedisStream), nil
+	case "nats":
+		opts, err := natsOptions(cfg)
+		if err != nil {
+			return nil, err
+		}
+		return NewNATSSink(cfg.NATSURL, cfg.NATSSubjectPrefix, cfg.NATSJetStream, opts...)
+	case "http":
+		return NewHTTPSink(cfg.HTTPURL, []byte(cfg.HTTPSecret)), nil
+	}
+	return nil, ErrUnknownSink
+}
@@ -0,0 +1,62 @@
+package events
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+)
+
+// HTTPSink POSTs each event as JSON to a webhook. With a secret the body is
+// signed with HMAC-SHA256 in the X-Signature header as sha256=<hex>. Any
+// status outside 2xx counts as a failed delivery.
+type HTTPSink struct {
+	url    string
+	secret []byte
+	client *http.Client
+}
+
+func NewHTTPSink(url string, secret []byte) *HTTPSink {
+	return &HTTPSink{url: url, secret: secret, client: &http.Client{Timeout: 10 * time.Second}}
+}
+
+// Signature returns the X-Signature value of body.
+func Signature(secret, body []byte) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+func (s *HTTPSink) Publish(ctx context.Context, event *Event) error {
+	body, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Event-ID", event.ID)
+	req.Header.Set("X-Event-Type", event.Type)
+	if len(s.secret) > 0 {
+		req.Header.Set("X-Signature", Signature(s.secret, body))
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("webhook answered %s", resp.Status)
+	}
+	return nil
+}
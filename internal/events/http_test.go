@@ -0,0 +1,81 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func testEvent() *Event {
+	return &Event{
+		ID:         "0b9d2c1e-5f2a-4c1b-9a57-3f1e2d4c5b6a",
+		Type:       UserRegistered,
+		Version:    1,
+		Source:     Source,
+		OccurredAt: time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC),
+		Data:       json.RawMessage(`{"id":7,"email":"ann@example.com"}`),
+	}
+}
+
+func TestHTTPSink_Publish(t *testing.T) {
+	secret := []byte("webhook-secret")
+	status := http.StatusAccepted
+	var received *Event
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		if r.Header.Get("X-Signature") != Signature(secret, body) {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		require.Equal(t, "user.registered", r.Header.Get("X-Event-Type"))
+		received = &Event{}
+		require.NoError(t, json.Unmarshal(body, received))
+		w.WriteHeader(status)
+	}))
+	defer srv.Close()
+
+	require.NoError(t, NewHTTPSink(srv.URL, secret).Publish(context.Background(), testEvent()))
+	require.Equal(t, testEvent(), received)
+
+	require.Error(t, NewHTTPSink(srv.URL, []byte("wrong")).Publish(context.Background(), testEvent()))
+	status = http.StatusInternalServerError
+	require.Error(t, NewHTTPSink(srv.URL, secret).Publish(context.Background(), testEvent()))
+}
+
+func TestConfigFromEnv(t *testing.T) {
+	t.Setenv("EVENTS_SINK", "")
+	cfg, err := ConfigFromEnv()
+	require.NoError(t, err)
+	require.Nil(t, cfg)
+
+	t.Setenv("EVENTS_SINK", "redis")
+	t.Setenv("REDIS_ADDR", "cache:6379")
+	cfg, err = ConfigFromEnv()
+	require.NoError(t, err)
+	require.Equal(t, "cache:6379", cfg.RedisAddr)
+	require.Equal(t, "user-events", cfg.RedisStream)
+
+	t.Setenv("EVENTS_SINK", "nats")
+	_, err = ConfigFromEnv()
+	require.ErrorIs(t, err, ErrNoSinkAddr)
+	t.Setenv("EVENTS_NATS_URL", "tls://nats-1:4222,tls://nats-2:4222")
+	t.Setenv("EVENTS_NATS_JETSTREAM", "true")
+	cfg, err = ConfigFromEnv()
+	require.NoError(t, err)
+	require.True(t, cfg.NATSJetStream)
+	require.Equal(t, "events", cfg.NATSSubjectPrefix)
+	t.Setenv("EVENTS_NATS_JETSTREAM", "sometimes")
+	_, err = ConfigFromEnv()
+	require.Error(t, err)
+	t.Setenv("EVENTS_NATS_JETSTREAM", "")
+
+	t.Setenv("EVENTS_SINK", "kafka")
+	_, err = ConfigFromEnv()
+	require.ErrorIs(t, err, ErrUnknownSink)
+}
@@ -0,0 +1,95 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
+)
+
+const natsTimeout = 10 * time.Second
+
+// NATSSink publishes each event to <prefix>.<type>. The event id goes along
+// as Nats-Msg-Id, which JetStream streams use to drop redelivered events.
+// With JetStream a publish counts once the stream acknowledged it, otherwise
+// once the server has received it.
+type NATSSink struct {
+	conn   *nats.Conn
+	js     jetstream.JetStream
+	prefix string
+}
+
+// NewNATSSink connects to the comma separated server urls. The connection is
+// retried and reestablished in the background, publishing fails while it is
+// down.
+func NewNATSSink(urls, prefix string, useJetStream bool, opts ...nats.Option) (*NATSSink, error) {
+	opts = append([]nats.Option{
+		nats.Name(Source),
+		nats.RetryOnFailedConnect(true),
+		nats.MaxReconnects(-1),
+	}, opts...)
+	conn, err := nats.Connect(urls, opts...)
+	if err != nil {
+		return nil, err
+	}
+	s := &NATSSink{conn: conn, prefix: prefix}
+	if useJetStream {
+		if s.js, err = jetstream.New(conn); err != nil {
+			conn.Close()
+			return nil, err
+		}
+	}
+	return s, nil
+}
+
+// natsOptions returns the authentication and TLS options cfg asks for.
+func natsOptions(cfg *Config) ([]nats.Option, error) {
+	var opts []nats.Option
+	if cfg.NATSUser != "" {
+		opts = append(opts, nats.UserInfo(cfg.NATSUser, cfg.NATSPassword))
+	}
+	if cfg.NATSToken != "" {
+		opts = append(opts, nats.Token(cfg.NATSToken))
+	}
+	if cfg.NATSCredsFile != "" {
+		opts = append(opts, nats.UserCredentials(cfg.NATSCredsFile))
+	}
+	if cfg.NATSNKeySeedFile != "" {
+		opt, err := nats.NkeyOptionFromSeed(cfg.NATSNKeySeedFile)
+		if err != nil {
+			return nil, err
+		}
+		opts = append(opts, opt)
+	}
+	if cfg.NATSCAFile != "" {
+		opts = append(opts, nats.RootCAs(cfg.NATSCAFile))
+	}
+	return opts, nil
+}
+
+func (s *NATSSink) Publish(ctx context.Context, event *Event) error {
+	body, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(ctx, natsTimeout)
+	defer cancel()
+
+	msg := nats.NewMsg(s.prefix + "." + event.Type)
+	msg.Data = body
+	if s.js != nil {
+		_, err := s.js.PublishMsg(ctx, msg, jetstream.WithMsgID(event.ID))
+		return err
+	}
+	msg.Header.Set(nats.MsgIdHdr, event.ID)
+	if err := s.conn.PublishMsg(msg); err != nil {
+		return err
+	}
+	return s.conn.FlushWithContext(ctx)
+}
+
+func (s *NATSSink) Close() {
+	s.conn.Close()
+}
@@ -0,0 +1,108 @@
+package events
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// fakeNATS speaks enough of the NATS protocol for one client. It records the
+// messages published to it and answers the PING of the handshake. Later PINGs
+// are answered with pong, so an empty pong leaves flushes unacknowledged.
+// Messages with a reply subject get a JetStream publish ack.
+func fakeNATS(t *testing.T, pong string) (string, chan string) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { ln.Close() })
+
+	messages := make(chan string, 10)
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		fmt.Fprintf(conn, "INFO {\"server_id\":\"test\",\"version\":\"2.10.0\",\"proto\":1,\"headers\":true,\"max_payload\":1048576}\r\n")
+		r := bufio.NewReader(conn)
+		handshake := true
+		sid := ""
+		for {
+			line, err := r.ReadString('\n')
+			if err != nil {
+				return
+			}
+			fields := strings.Fields(line)
+			if len(fields) == 0 {
+				continue
+			}
+			switch fields[0] {
+			case "SUB":
+				sid = fields[len(fields)-1]
+			case "PUB", "HPUB":
+				size, _ := strconv.Atoi(fields[len(fields)-1])
+				payload := make([]byte, size+2)
+				if _, err := io.ReadFull(r, payload); err != nil {
+					return
+				}
+				messages <- line + string(payload[:size])
+				// HPUB <subject> <reply> <header size> <total size>
+				if fields[0] == "HPUB" && len(fields) == 5 {
+					ack := `{"stream":"EVENTS","seq":1}`
+					fmt.Fprintf(conn, "MSG %s %s %d\r\n%s\r\n", fields[2], sid, len(ack), ack)
+				}
+			case "PING":
+				if handshake {
+					conn.Write([]byte("PONG\r\n"))
+					handshake = false
+				} else {
+					conn.Write([]byte(pong))
+				}
+			}
+		}
+	}()
+	return "nats://" + ln.Addr().String(), messages
+}
+
+func TestNATSSink_Publish(t *testing.T) {
+	url, messages := fakeNATS(t, "PONG\r\n")
+	sink, err := NewNATSSink(url, "events", false)
+	require.NoError(t, err)
+	defer sink.Close()
+
+	require.NoError(t, sink.Publish(context.Background(), testEvent()))
+	msg := <-messages
+	require.True(t, strings.HasPrefix(msg, "HPUB events.user.registered "), msg)
+	require.Contains(t, msg, "Nats-Msg-Id: "+testEvent().ID+"\r\n")
+	require.Contains(t, msg, `"type":"user.registered"`)
+}
+
+func TestNATSSink_PublishUnacknowledged(t *testing.T) {
+	url, _ := fakeNATS(t, "")
+	sink, err := NewNATSSink(url, "events", false)
+	require.NoError(t, err)
+	defer sink.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+	require.Error(t, sink.Publish(ctx, testEvent()))
+}
+
+func TestNATSSink_PublishJetStream(t *testing.T) {
+	url, messages := fakeNATS(t, "PONG\r\n")
+	sink, err := NewNATSSink(url, "events", true)
+	require.NoError(t, err)
+	defer sink.Close()
+
+	require.NoError(t, sink.Publish(context.Background(), testEvent()))
+	msg := <-messages
+	require.True(t, strings.HasPrefix(msg, "HPUB events.user.registered _INBOX."), msg)
+	require.Contains(t, msg, "Nats-Msg-Id: "+testEvent().ID+"\r\n")
+}
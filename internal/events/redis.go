@@ -0,0 +1,37 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// RedisStreamSink adds each event to a Redis stream. Entries carry the event
+// id and type next to the JSON envelope, so consumer groups can filter
+// without decoding it.
+type RedisStreamSink struct {
+	client *redis.Client
+	stream string
+}
+
+func NewRedisStreamSink(addr, password string, db int, stream string) *RedisStreamSink {
+	client := redis.NewClient(&redis.Options{Addr: addr, Password: password, DB: db})
+	return &RedisStreamSink{client: client, stream: stream}
+}
+
+func (s *RedisStreamSink) Publish(ctx context.Context, event *Event) error {
+	body, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	return s.client.XAdd(ctx, &redis.XAddArgs{
+		Stream: s.stream,
+		Values: map[string]any{
+			"id":      event.ID,
+			"type":    event.Type,
+			"version": event.Version,
+			"event":   body,
+		},
+	}).Err()
+}
@@ -0,0 +1,105 @@
+package events
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// readRESPCommand reads one command, an array of bulk strings.
+func readRESPCommand(r *bufio.Reader) ([]string, error) {
+	line, err := r.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	n, err := strconv.Atoi(strings.TrimSpace(strings.TrimPrefix(line, "*")))
+	if err != nil {
+		return nil, err
+	}
+	args := make([]string, n)
+	for i := range args {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return nil, err
+		}
+		size, err := strconv.Atoi(strings.TrimSpace(strings.TrimPrefix(line, "$")))
+		if err != nil {
+			return nil, err
+		}
+		arg := make([]byte, size+2)
+		if _, err := io.ReadFull(r, arg); err != nil {
+			return nil, err
+		}
+		args[i] = string(arg[:size])
+	}
+	return args, nil
+}
+
+// fakeRedis records the XADD commands it gets and answers them with reply.
+// HELLO is refused like an old server does, so clients fall back to RESP2.
+func fakeRedis(t *testing.T, reply string) (string, chan []string) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { ln.Close() })
+
+	commands := make(chan []string, 10)
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				r := bufio.NewReader(conn)
+				for {
+					args, err := readRESPCommand(r)
+					if err != nil {
+						return
+					}
+					switch strings.ToUpper(args[0]) {
+					case "XADD":
+						commands <- args
+						conn.Write([]byte(reply))
+					case "PING":
+						conn.Write([]byte("+PONG\r\n"))
+					default:
+						fmt.Fprintf(conn, "-ERR unknown command '%s'\r\n", args[0])
+					}
+				}
+			}()
+		}
+	}()
+	return ln.Addr().String(), commands
+}
+
+func TestRedisStreamSink_Publish(t *testing.T) {
+	addr, commands := fakeRedis(t, "$15\r\n1760875200000-0\r\n")
+	sink := NewRedisStreamSink(addr, "", 0, "user-events")
+
+	require.NoError(t, sink.Publish(context.Background(), testEvent()))
+	args := <-commands
+	require.Equal(t, []string{"xadd", "user-events", "*"}, args[:3])
+	fields := map[string]string{}
+	for i := 3; i+1 < len(args); i += 2 {
+		fields[args[i]] = args[i+1]
+	}
+	require.Equal(t, testEvent().ID, fields["id"])
+	require.Equal(t, "user.registered", fields["type"])
+	require.Equal(t, "1", fields["version"])
+	require.JSONEq(t, `{"id":"0b9d2c1e-5f2a-4c1b-9a57-3f1e2d4c5b6a","type":"user.registered","version":1,"source":"user-service",
+		"occurred_at":"2026-10-19T12:00:00Z","data":{"id":7,"email":"ann@example.com"}}`, fields["event"])
+}
+
+func TestRedisStreamSink_PublishError(t *testing.T) {
+	addr, _ := fakeRedis(t, "-OOM command not allowed when used memory > 'maxmemory'\r\n")
+	err := NewRedisStreamSink(addr, "", 0, "user-events").Publish(context.Background(), testEvent())
+	require.ErrorContains(t, err, "OOM")
+}
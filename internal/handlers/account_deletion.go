@@ -0,0 +1,97 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/Atmosfr/user-service/internal/repository"
+	"github.com/Atmosfr/user-service/internal/service"
+)
+
+type DeleteAccountRequest struct {
+	Password string `json:"password"`
+}
+
+type DeleteAccountResponse struct {
+	RestorableUntil time.Time `json:"restorable_until"`
+}
+
+type RestoreAccountRequest struct {
+	Email    string `json:"email"`
+	Password string `json:"password"`
+}
+
+func accountDeletionErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, repository.ErrInvalidPassword),
+		errors.Is(err, repository.ErrInvalidCredentials):
+		return http.StatusForbidden
+	case errors.Is(err, repository.ErrUserOwnsServiceAccounts),
+		errors.Is(err, repository.ErrLastOrganizationOwner),
+		errors.Is(err, repository.ErrUserStateChanged):
+		return http.StatusConflict
+	case errors.Is(err, service.ErrRestorePeriodOver):
+		return http.StatusGone
+	}
+	return http.StatusInternalServerError
+}
+
+func writeAccountDeletionError(w http.ResponseWriter, err error) {
+	w.WriteHeader(accountDeletionErrorStatus(err))
+	json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+}
+
+// DeleteAccountHandler deletes the signed-in user's account. The user confirms
+// with their password.
+func DeleteAccountHandler(svc service.AccountDeletionService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		defer r.Body.Close()
+		w.Header().Set("Content-Type", "application/json")
+
+		user, ok := requireUser(w, r)
+		if !ok {
+			return
+		}
+
+		var req DeleteAccountRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"error": "Invalid request payload"})
+			return
+		}
+
+		restorableUntil, err := svc.Delete(r.Context(), user, req.Password)
+		if err != nil {
+			writeAccountDeletionError(w, err)
+			return
+		}
+
+		json.NewEncoder(w).Encode(DeleteAccountResponse{RestorableUntil: restorableUntil})
+	}
+}
+
+// RestoreAccountHandler reactivates a deleted account within the grace period.
+// It needs no authentication, deleted users cannot sign in.
+func RestoreAccountHandler(svc service.AccountDeletionService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		defer r.Body.Close()
+		w.Header().Set("Content-Type", "application/json")
+
+		var req RestoreAccountRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"error": "Invalid request payload"})
+			return
+		}
+
+		user, err := svc.Restore(r.Context(), req.Email, req.Password)
+		if err != nil {
+			writeAccountDeletionError(w, err)
+			return
+		}
+
+		json.NewEncoder(w).Encode(user)
+	}
+}
@@ -0,0 +1,91 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/Atmosfr/user-service/internal/models"
+	"github.com/Atmosfr/user-service/internal/repository"
+	"github.com/Atmosfr/user-service/internal/service"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+type mockAccountDeletionService struct {
+	mock.Mock
+}
+
+func (m *mockAccountDeletionService) Delete(ctx context.Context, user *models.User, password string) (time.Time, error) {
+	args := m.Called(ctx, user, password)
+	until, _ := args.Get(0).(time.Time)
+	return until, args.Error(1)
+}
+
+func (m *mockAccountDeletionService) Restore(ctx context.Context, email, password string) (*models.User, error) {
+	args := m.Called(ctx, email, password)
+	user, _ := args.Get(0).(*models.User)
+	return user, args.Error(1)
+}
+
+func (m *mockAccountDeletionService) Purge(ctx context.Context) (int, error) {
+	args := m.Called(ctx)
+	return args.Int(0), args.Error(1)
+}
+
+func (m *mockAccountDeletionService) Run(ctx context.Context, interval time.Duration) {
+	m.Called(ctx, interval)
+}
+
+func TestDeleteAccountHandler(t *testing.T) {
+	user := &models.User{ID: 7, Email: "bob@example.com"}
+	until := time.Date(2026, 11, 18, 12, 0, 0, 0, time.UTC)
+	svc := new(mockAccountDeletionService)
+	svc.On("Delete", mock.Anything, mock.Anything, "StrongPass!12").Return(until, nil)
+	svc.On("Delete", mock.Anything, mock.Anything, "owner").Return(time.Time{}, repository.ErrLastOrganizationOwner)
+	svc.On("Delete", mock.Anything, mock.Anything, mock.Anything).Return(time.Time{}, repository.ErrInvalidPassword)
+
+	rr := serveAsUser(t, user, DeleteAccountHandler(svc), httptest.NewRequest(http.MethodDelete, "/me", bytes.NewBufferString(`{"password":"StrongPass!12"}`)))
+	require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+	var resp DeleteAccountResponse
+	require.NoError(t, json.NewDecoder(rr.Body).Decode(&resp))
+	require.Equal(t, until, resp.RestorableUntil)
+
+	for body, status := range map[string]int{
+		`{"password":"wrong"}`: http.StatusForbidden,
+		`{"password":"owner"}`: http.StatusConflict,
+		`{"password":`:         http.StatusBadRequest,
+	} {
+		rr := serveAsUser(t, user, DeleteAccountHandler(svc), httptest.NewRequest(http.MethodDelete, "/me", bytes.NewBufferString(body)))
+		require.Equal(t, status, rr.Code, body)
+	}
+}
+
+func TestRestoreAccountHandler(t *testing.T) {
+	svc := new(mockAccountDeletionService)
+	svc.On("Restore", mock.Anything, "bob@example.com", "StrongPass!12").Return(&models.User{ID: 7, State: models.AccountActive}, nil)
+	svc.On("Restore", mock.Anything, "old@example.com", mock.Anything).Return(nil, service.ErrRestorePeriodOver)
+	svc.On("Restore", mock.Anything, mock.Anything, mock.Anything).Return(nil, repository.ErrInvalidPassword)
+
+	tests := []struct {
+		name           string
+		body           string
+		expectedStatus int
+	}{
+		{name: "restore", body: `{"email":"bob@example.com","password":"StrongPass!12"}`, expectedStatus: http.StatusOK},
+		{name: "wrong password", body: `{"email":"bob@example.com","password":"nope"}`, expectedStatus: http.StatusForbidden},
+		{name: "grace period over", body: `{"email":"old@example.com","password":"StrongPass!12"}`, expectedStatus: http.StatusGone},
+		{name: "bad payload", body: `[]`, expectedStatus: http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rr := httptest.NewRecorder()
+			RestoreAccountHandler(svc).ServeHTTP(rr, httptest.NewRequest(http.MethodPost, "/account/restore", bytes.NewBufferString(tt.body)))
+			require.Equal(t, tt.expectedStatus, rr.Code, rr.Body.String())
+		})
+	}
+}
@@ -0,0 +1,116 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/Atmosfr/user-service/internal/models"
+	"github.com/Atmosfr/user-service/internal/repository"
+	"github.com/Atmosfr/user-service/internal/service"
+)
+
+type TransitionUserStateRequest struct {
+	State     string     `json:"state"`
+	Reason    string     `json:"reason"`
+	Note      string     `json:"note"`
+	ExpiresAt *time.Time `json:"expires_at"`
+}
+
+type UserStateHistoryResponse struct {
+	History []*models.AccountStateChange `json:"history"`
+}
+
+// isAccountStateError reports whether err refuses a sign-in because of the
+// account state.
+func isAccountStateError(err error) bool {
+	return errors.Is(err, service.ErrAccountSuspended) ||
+		errors.Is(err, service.ErrAccountBanned) ||
+		errors.Is(err, service.ErrAccountPendingVerification) ||
+		errors.Is(err, service.ErrAccountDeleted)
+}
+
+func accountStateErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, service.ErrInvalidAccountState),
+		errors.Is(err, service.ErrInvalidStateReason),
+		errors.Is(err, service.ErrInvalidSuspensionExpiry),
+		errors.Is(err, service.ErrStateNoteTooLong):
+		return http.StatusBadRequest
+	case errors.Is(err, service.ErrCannotChangeSelf):
+		return http.StatusForbidden
+	case errors.Is(err, service.ErrInvalidStateTransition),
+		errors.Is(err, repository.ErrUserStateChanged):
+		return http.StatusConflict
+	case errors.Is(err, repository.ErrUserNotFound):
+		return http.StatusNotFound
+	}
+	return http.StatusInternalServerError
+}
+
+func writeAccountStateError(w http.ResponseWriter, err error) {
+	w.WriteHeader(accountStateErrorStatus(err))
+	json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+}
+
+// TransitionUserStateHandler suspends, bans or reactivates a user, or asks
+// them to verify their address again. Suspensions end at expires_at if set.
+func TransitionUserStateHandler(svc service.AccountStateService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		defer r.Body.Close()
+		w.Header().Set("Content-Type", "application/json")
+
+		actor, ok := requireUser(w, r)
+		if !ok {
+			return
+		}
+		id, ok := pathID(r, "id")
+		if !ok {
+			writeAccountStateError(w, repository.ErrUserNotFound)
+			return
+		}
+
+		var req TransitionUserStateRequest
+		dec := json.NewDecoder(r.Body)
+		dec.DisallowUnknownFields()
+		if err := dec.Decode(&req); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"error": "Invalid request payload"})
+			return
+		}
+
+		user, err := svc.Transition(r.Context(), actor, id, service.AccountStateTransition{
+			State:     req.State,
+			Reason:    req.Reason,
+			Note:      req.Note,
+			ExpiresAt: req.ExpiresAt,
+		})
+		if err != nil {
+			writeAccountStateError(w, err)
+			return
+		}
+
+		json.NewEncoder(w).Encode(adminUser(user))
+	}
+}
+
+func UserStateHistoryHandler(svc service.AccountStateService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+
+		id, ok := pathID(r, "id")
+		if !ok {
+			writeAccountStateError(w, repository.ErrUserNotFound)
+			return
+		}
+
+		history, err := svc.History(r.Context(), id)
+		if err != nil {
+			writeAccountStateError(w, err)
+			return
+		}
+
+		json.NewEncoder(w).Encode(UserStateHistoryResponse{History: history})
+	}
+}
@@ -0,0 +1,112 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/Atmosfr/user-service/internal/models"
+	"github.com/Atmosfr/user-service/internal/repository"
+	"github.com/Atmosfr/user-service/internal/service"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+type mockAccountStateService struct {
+	mock.Mock
+}
+
+func (m *mockAccountStateService) Transition(ctx context.Context, actor *models.User, userID int64, t service.AccountStateTransition) (*models.User, error) {
+	args := m.Called(ctx, actor, userID, t)
+	user, _ := args.Get(0).(*models.User)
+	return user, args.Error(1)
+}
+
+func (m *mockAccountStateService) History(ctx context.Context, userID int64) ([]*models.AccountStateChange, error) {
+	args := m.Called(ctx, userID)
+	history, _ := args.Get(0).([]*models.AccountStateChange)
+	return history, args.Error(1)
+}
+
+func (m *mockAccountStateService) ExpireSuspensions(ctx context.Context) (int, error) {
+	args := m.Called(ctx)
+	return args.Int(0), args.Error(1)
+}
+
+func (m *mockAccountStateService) Run(ctx context.Context, interval time.Duration) {
+	m.Called(ctx, interval)
+}
+
+func TestTransitionUserStateHandler(t *testing.T) {
+	admin := &models.User{ID: 1, Role: "admin"}
+	until := time.Date(2026, 11, 1, 0, 0, 0, 0, time.UTC)
+	svc := new(mockAccountStateService)
+	svc.On("Transition", mock.Anything, mock.Anything, int64(7), service.AccountStateTransition{State: "suspended", Reason: "spam", Note: "link farm", ExpiresAt: &until}).
+		Return(&models.User{ID: 7, State: "suspended", StateReason: "spam", StateExpiresAt: &until}, nil)
+	svc.On("Transition", mock.Anything, mock.Anything, int64(8), mock.Anything).Return(nil, service.ErrInvalidStateTransition)
+	svc.On("Transition", mock.Anything, mock.Anything, int64(9), mock.Anything).Return(nil, service.ErrInvalidStateReason)
+
+	tests := []struct {
+		name           string
+		id             string
+		body           string
+		expectedStatus int
+	}{
+		{name: "suspend", id: "7", body: `{"state":"suspended","reason":"spam","note":"link farm","expires_at":"2026-11-01T00:00:00Z"}`, expectedStatus: http.StatusOK},
+		{name: "not allowed", id: "8", body: `{"state":"banned","reason":"spam"}`, expectedStatus: http.StatusConflict},
+		{name: "unknown reason", id: "9", body: `{"state":"banned","reason":"boredom"}`, expectedStatus: http.StatusBadRequest},
+		{name: "unknown field", id: "7", body: `{"state":"banned","until":"tomorrow"}`, expectedStatus: http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/", bytes.NewBufferString(tt.body))
+			req.SetPathValue("id", tt.id)
+			rr := serveAsUser(t, admin, TransitionUserStateHandler(svc), req)
+			require.Equal(t, tt.expectedStatus, rr.Code, rr.Body.String())
+		})
+	}
+}
+
+func TestUserStateHistoryHandler(t *testing.T) {
+	svc := new(mockAccountStateService)
+	svc.On("History", mock.Anything, int64(7)).Return([]*models.AccountStateChange{{ID: 1, UserID: 7, From: "active", To: "banned", Reason: "fraud"}}, nil)
+	svc.On("History", mock.Anything, int64(8)).Return(nil, repository.ErrUserNotFound)
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.SetPathValue("id", "7")
+	rr := httptest.NewRecorder()
+	UserStateHistoryHandler(svc).ServeHTTP(rr, req)
+	require.Equal(t, http.StatusOK, rr.Code)
+	require.JSONEq(t, `{"history":[{"id":1,"user_id":7,"from":"active","to":"banned","reason":"fraud","created_at":"0001-01-01T00:00:00Z"}]}`, rr.Body.String())
+
+	req = httptest.NewRequest(http.MethodGet, "/", nil)
+	req.SetPathValue("id", "8")
+	rr = httptest.NewRecorder()
+	UserStateHistoryHandler(svc).ServeHTTP(rr, req)
+	require.Equal(t, http.StatusNotFound, rr.Code)
+}
+
+func TestAuthMiddlewareRejectsInactiveAccounts(t *testing.T) {
+	until := time.Now().Add(time.Hour)
+	expired := time.Now().Add(-time.Hour)
+	tests := []struct {
+		name           string
+		user           *models.User
+		expectedStatus int
+	}{
+		{name: "active", user: &models.User{ID: 7, State: models.AccountActive}, expectedStatus: http.StatusOK},
+		{name: "suspended", user: &models.User{ID: 7, State: models.AccountSuspended, StateExpiresAt: &until}, expectedStatus: http.StatusForbidden},
+		{name: "suspension over", user: &models.User{ID: 7, State: models.AccountSuspended, StateExpiresAt: &expired}, expectedStatus: http.StatusOK},
+		{name: "banned", user: &models.User{ID: 7, State: models.AccountBanned}, expectedStatus: http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+			rr := serveAsUser(t, tt.user, ok, httptest.NewRequest(http.MethodGet, "/me", nil))
+			require.Equal(t, tt.expectedStatus, rr.Code, rr.Body.String())
+		})
+	}
+}
@@ -0,0 +1,276 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/Atmosfr/user-service/internal/models"
+	"github.com/Atmosfr/user-service/internal/repository"
+	"github.com/Atmosfr/user-service/internal/service"
+)
+
+// AdminUser is a user as administrators see it, including private metadata.
+type AdminUser struct {
+	*models.User
+	PrivateMetadata json.RawMessage `json:"private_metadata,omitempty"`
+}
+
+type ListUsersResponse struct {
+	Users      []AdminUser `json:"users"`
+	NextCursor string      `json:"next_cursor,omitempty"`
+}
+
+type UserSearchResult struct {
+	AdminUser
+	Score      float64                        `json:"score"`
+	Highlights map[string][]service.TextRange `json:"highlights"`
+}
+
+type SearchUsersResponse struct {
+	Users []UserSearchResult `json:"users"`
+}
+
+type UpdateUserRequest struct {
+	Role *string `json:"role"`
+}
+
+func adminUser(user *models.User) AdminUser {
+	return AdminUser{User: user, PrivateMetadata: user.PrivateMetadata}
+}
+
+func adminUserErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, service.ErrInvalidCursor),
+		errors.Is(err, service.ErrInvalidCreatedRange),
+		errors.Is(err, service.ErrInvalidMetadataFilter),
+		errors.Is(err, service.ErrSearchQueryTooShort),
+		errors.Is(err, repository.ErrRoleNotFound):
+		return http.StatusBadRequest
+	case errors.Is(err, service.ErrCannotChangeSelf):
+		return http.StatusForbidden
+	case errors.Is(err, repository.ErrUserOwnsServiceAccounts),
+		errors.Is(err, repository.ErrLastOrganizationOwner):
+		return http.StatusConflict
+	case errors.Is(err, repository.ErrUserNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, repository.ErrSearchTimeout):
+		return http.StatusServiceUnavailable
+	}
+	return http.StatusInternalServerError
+}
+
+func writeAdminUserError(w http.ResponseWriter, err error) {
+	w.WriteHeader(adminUserErrorStatus(err))
+	json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+}
+
+// parseDateParam accepts RFC 3339 timestamps and plain dates, which mean
+// midnight UTC.
+func parseDateParam(query url.Values, name string) (*time.Time, error) {
+	v := query.Get(name)
+	if v == "" {
+		return nil, nil
+	}
+	for _, layout := range []string{time.RFC3339, time.DateOnly} {
+		if t, err := time.Parse(layout, v); err == nil {
+			return &t, nil
+		}
+	}
+	return nil, fmt.Errorf("%s must be a date or an RFC 3339 timestamp", name)
+}
+
+// parseUserFilter reads the filters of GET /admin/users.
+func parseUserFilter(query url.Values) (repository.UserFilter, error) {
+	filter := repository.UserFilter{
+		Role:        query.Get("role"),
+		State:       query.Get("state"),
+		EmailDomain: query.Get("email_domain"),
+		Query:       query.Get("q"),
+	}
+	if v := query.Get("is_active"); v != "" {
+		active, err := strconv.ParseBool(v)
+		if err != nil {
+			return filter, errors.New("is_active must be true or false")
+		}
+		filter.IsActive = &active
+	}
+	var err error
+	if filter.CreatedAfter, err = parseDateParam(query, "created_after"); err != nil {
+		return filter, err
+	}
+	if filter.CreatedBefore, err = parseDateParam(query, "created_before"); err != nil {
+		return filter, err
+	}
+	for _, param := range query["metadata"] {
+		f, err := parseMetadataFilter(param)
+		if err != nil {
+			return filter, err
+		}
+		filter.Metadata = append(filter.Metadata, f)
+	}
+	return filter, nil
+}
+
+// ListUsersHandler pages through users, newest first. Filters are the query
+// parameters role, is_active, state, created_after, created_before,
+// email_domain, q and metadata, e.g. ?metadata=public.billing.plan:pro. Pass next_cursor as
+// cursor to get the following page.
+func ListUsersHandler(svc service.AdminUserService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+
+		query := r.URL.Query()
+		filter, err := parseUserFilter(query)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+			return
+		}
+		limit, err := parseLimit(query)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+			return
+		}
+
+		page, err := svc.List(r.Context(), filter, query.Get("cursor"), limit)
+		if err != nil {
+			writeAdminUserError(w, err)
+			return
+		}
+
+		resp := ListUsersResponse{Users: make([]AdminUser, 0, len(page.Users)), NextCursor: page.NextCursor}
+		for _, user := range page.Users {
+			resp.Users = append(resp.Users, adminUser(user))
+		}
+		json.NewEncoder(w).Encode(resp)
+	}
+}
+
+// parseLimit reads the optional limit parameter, 0 when it is not set.
+func parseLimit(query url.Values) (int, error) {
+	v := query.Get("limit")
+	if v == "" {
+		return 0, nil
+	}
+	limit, err := strconv.Atoi(v)
+	if err != nil || limit < 1 {
+		return 0, errors.New("limit must be a positive integer")
+	}
+	return limit, nil
+}
+
+// SearchUsersHandler finds users by a fuzzy match of q on their email and
+// username. Highlights point at the matched characters of both.
+func SearchUsersHandler(svc service.AdminUserService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+
+		query := r.URL.Query()
+		limit, err := parseLimit(query)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+			return
+		}
+
+		results, err := svc.Search(r.Context(), query.Get("q"), limit)
+		if err != nil {
+			writeAdminUserError(w, err)
+			return
+		}
+
+		resp := SearchUsersResponse{Users: make([]UserSearchResult, 0, len(results))}
+		for _, result := range results {
+			resp.Users = append(resp.Users, UserSearchResult{
+				AdminUser:  adminUser(result.User),
+				Score:      result.Score,
+				Highlights: result.Highlights,
+			})
+		}
+		json.NewEncoder(w).Encode(resp)
+	}
+}
+
+func GetUserHandler(svc service.AdminUserService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+
+		id, ok := pathID(r, "id")
+		if !ok {
+			writeAdminUserError(w, repository.ErrUserNotFound)
+			return
+		}
+
+		user, err := svc.Get(r.Context(), id)
+		if err != nil {
+			writeAdminUserError(w, err)
+			return
+		}
+
+		json.NewEncoder(w).Encode(adminUser(user))
+	}
+}
+
+// UpdateUserHandler changes the role of a user.
+func UpdateUserHandler(svc service.AdminUserService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		defer r.Body.Close()
+		w.Header().Set("Content-Type", "application/json")
+
+		actor, ok := requireUser(w, r)
+		if !ok {
+			return
+		}
+		id, ok := pathID(r, "id")
+		if !ok {
+			writeAdminUserError(w, repository.ErrUserNotFound)
+			return
+		}
+
+		var req UpdateUserRequest
+		dec := json.NewDecoder(r.Body)
+		dec.DisallowUnknownFields()
+		if err := dec.Decode(&req); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"error": "Invalid request payload"})
+			return
+		}
+
+		user, err := svc.Update(r.Context(), actor, id, service.AdminUserPatch{Role: req.Role})
+		if err != nil {
+			writeAdminUserError(w, err)
+			return
+		}
+
+		json.NewEncoder(w).Encode(adminUser(user))
+	}
+}
+
+func DeleteUserHandler(svc service.AdminUserService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+
+		actor, ok := requireUser(w, r)
+		if !ok {
+			return
+		}
+		id, ok := pathID(r, "id")
+		if !ok {
+			writeAdminUserError(w, repository.ErrUserNotFound)
+			return
+		}
+
+		if err := svc.Delete(r.Context(), actor, id); err != nil {
+			writeAdminUserError(w, err)
+			return
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
@@ -0,0 +1,167 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/Atmosfr/user-service/internal/models"
+	"github.com/Atmosfr/user-service/internal/repository"
+	"github.com/Atmosfr/user-service/internal/service"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+type mockAdminUserService struct {
+	mock.Mock
+}
+
+func (m *mockAdminUserService) List(ctx context.Context, filter repository.UserFilter, cursor string, limit int) (*service.UserPage, error) {
+	args := m.Called(ctx, filter, cursor, limit)
+	page, _ := args.Get(0).(*service.UserPage)
+	return page, args.Error(1)
+}
+
+func (m *mockAdminUserService) Get(ctx context.Context, id int64) (*models.User, error) {
+	args := m.Called(ctx, id)
+	user, _ := args.Get(0).(*models.User)
+	return user, args.Error(1)
+}
+
+func (m *mockAdminUserService) Update(ctx context.Context, actor *models.User, id int64, patch service.AdminUserPatch) (*models.User, error) {
+	args := m.Called(ctx, actor, id, patch)
+	user, _ := args.Get(0).(*models.User)
+	return user, args.Error(1)
+}
+
+func (m *mockAdminUserService) Delete(ctx context.Context, actor *models.User, id int64) error {
+	return m.Called(ctx, actor, id).Error(0)
+}
+
+func (m *mockAdminUserService) Search(ctx context.Context, query string, limit int) ([]*service.UserSearchResult, error) {
+	args := m.Called(ctx, query, limit)
+	results, _ := args.Get(0).([]*service.UserSearchResult)
+	return results, args.Error(1)
+}
+
+func TestParseUserFilter(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/admin/users?role=admin&is_active=false&created_after=2026-01-01&created_before=2026-02-01T10:00:00Z&email_domain=example.com&q=bob&metadata=public.billing.plan:pro", nil)
+	filter, err := parseUserFilter(req.URL.Query())
+	require.NoError(t, err)
+	require.Equal(t, "admin", filter.Role)
+	require.False(t, *filter.IsActive)
+	require.Equal(t, time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC), *filter.CreatedAfter)
+	require.Equal(t, time.Date(2026, 2, 1, 10, 0, 0, 0, time.UTC), *filter.CreatedBefore)
+	require.Equal(t, "example.com", filter.EmailDomain)
+	require.Equal(t, "bob", filter.Query)
+	require.Len(t, filter.Metadata, 1)
+
+	for _, query := range []string{"is_active=maybe", "created_after=yesterday", "metadata=billing"} {
+		req := httptest.NewRequest(http.MethodGet, "/admin/users?"+query, nil)
+		_, err := parseUserFilter(req.URL.Query())
+		require.Error(t, err, query)
+	}
+}
+
+func TestListUsersHandler(t *testing.T) {
+	svc := new(mockAdminUserService)
+	svc.On("List", mock.Anything, repository.UserFilter{Role: "user"}, "abc", 10).Return(&service.UserPage{
+		Users:      []*models.User{{ID: 7, Email: "bob@example.com", PrivateMetadata: json.RawMessage(`{"crm":{"id":"A1"}}`)}},
+		NextCursor: "def",
+	}, nil)
+	svc.On("List", mock.Anything, repository.UserFilter{}, "bad", 0).Return(nil, service.ErrInvalidCursor)
+
+	rr := httptest.NewRecorder()
+	ListUsersHandler(svc).ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/admin/users?role=user&cursor=abc&limit=10", nil))
+	require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+	var resp struct {
+		Users      []map[string]json.RawMessage `json:"users"`
+		NextCursor string                       `json:"next_cursor"`
+	}
+	require.NoError(t, json.NewDecoder(rr.Body).Decode(&resp))
+	require.Len(t, resp.Users, 1)
+	require.JSONEq(t, `{"crm":{"id":"A1"}}`, string(resp.Users[0]["private_metadata"]))
+	require.Equal(t, "def", resp.NextCursor)
+
+	rr = httptest.NewRecorder()
+	ListUsersHandler(svc).ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/admin/users?cursor=bad", nil))
+	require.Equal(t, http.StatusBadRequest, rr.Code)
+}
+
+func TestManageUserHandlers(t *testing.T) {
+	admin := &models.User{ID: 1, Role: "admin"}
+	isAdmin := mock.MatchedBy(func(u *models.User) bool { return u.ID == 1 })
+	role := "support"
+	svc := new(mockAdminUserService)
+	svc.On("Get", mock.Anything, int64(7)).Return(&models.User{ID: 7}, nil)
+	svc.On("Get", mock.Anything, int64(8)).Return(nil, repository.ErrUserNotFound)
+	svc.On("Update", mock.Anything, isAdmin, int64(7), service.AdminUserPatch{Role: &role}).Return(&models.User{ID: 7}, nil)
+	svc.On("Update", mock.Anything, isAdmin, int64(1), service.AdminUserPatch{Role: &role}).Return(nil, service.ErrCannotChangeSelf)
+	svc.On("Delete", mock.Anything, isAdmin, int64(7)).Return(nil)
+	svc.On("Delete", mock.Anything, isAdmin, int64(9)).Return(repository.ErrUserOwnsServiceAccounts)
+	svc.On("Delete", mock.Anything, isAdmin, int64(10)).Return(repository.ErrLastOrganizationOwner)
+
+	tests := []struct {
+		name           string
+		method         string
+		id             string
+		body           string
+		handler        http.HandlerFunc
+		expectedStatus int
+	}{
+		{name: "get", method: http.MethodGet, id: "7", handler: GetUserHandler(svc), expectedStatus: http.StatusOK},
+		{name: "get missing", method: http.MethodGet, id: "8", handler: GetUserHandler(svc), expectedStatus: http.StatusNotFound},
+		{name: "change role", method: http.MethodPatch, id: "7", body: `{"role":"support"}`, handler: UpdateUserHandler(svc), expectedStatus: http.StatusOK},
+		{name: "change own role", method: http.MethodPatch, id: "1", body: `{"role":"support"}`, handler: UpdateUserHandler(svc), expectedStatus: http.StatusForbidden},
+		{name: "state is not patched", method: http.MethodPatch, id: "7", body: `{"is_active":false}`, handler: UpdateUserHandler(svc), expectedStatus: http.StatusBadRequest},
+		{name: "unknown field", method: http.MethodPatch, id: "7", body: `{"password":"x"}`, handler: UpdateUserHandler(svc), expectedStatus: http.StatusBadRequest},
+		{name: "delete", method: http.MethodDelete, id: "7", handler: DeleteUserHandler(svc), expectedStatus: http.StatusNoContent},
+		{name: "delete owner of service accounts", method: http.MethodDelete, id: "9", handler: DeleteUserHandler(svc), expectedStatus: http.StatusConflict},
+		{name: "delete last organization owner", method: http.MethodDelete, id: "10", handler: DeleteUserHandler(svc), expectedStatus: http.StatusConflict},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, "/", bytes.NewBufferString(tt.body))
+			req.SetPathValue("id", tt.id)
+			rr := serveAsUser(t, admin, tt.handler, req)
+			require.Equal(t, tt.expectedStatus, rr.Code, rr.Body.String())
+		})
+	}
+}
+
+func TestSearchUsersHandler(t *testing.T) {
+	svc := new(mockAdminUserService)
+	svc.On("Search", mock.Anything, "jon smth", 5).Return([]*service.UserSearchResult{{
+		User:       &models.User{ID: 7, Email: "jon.smith@example.com", Username: "jonsmith"},
+		Score:      0.8,
+		Highlights: map[string][]service.TextRange{"email": {{Start: 0, End: 3}}, "username": {}},
+	}}, nil)
+	svc.On("Search", mock.Anything, "jo", 0).Return(nil, service.ErrSearchQueryTooShort)
+	svc.On("Search", mock.Anything, "slow", 0).Return(nil, repository.ErrSearchTimeout)
+
+	rr := httptest.NewRecorder()
+	SearchUsersHandler(svc).ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/admin/users/search?q=jon+smth&limit=5", nil))
+	require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+	var resp struct {
+		Users []struct {
+			ID         int64                          `json:"id"`
+			Score      float64                        `json:"score"`
+			Highlights map[string][]service.TextRange `json:"highlights"`
+		} `json:"users"`
+	}
+	require.NoError(t, json.NewDecoder(rr.Body).Decode(&resp))
+	require.Len(t, resp.Users, 1)
+	require.Equal(t, int64(7), resp.Users[0].ID)
+	require.Equal(t, 0.8, resp.Users[0].Score)
+	require.Equal(t, []service.TextRange{{Start: 0, End: 3}}, resp.Users[0].Highlights["email"])
+
+	for query, status := range map[string]int{"jo": http.StatusBadRequest, "slow": http.StatusServiceUnavailable} {
+		rr := httptest.NewRecorder()
+		SearchUsersHandler(svc).ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/admin/users/search?q="+query, nil))
+		require.Equal(t, status, rr.Code, query)
+	}
+}
@@ -0,0 +1,158 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"github.com/Atmosfr/user-service/internal/models"
+	"github.com/Atmosfr/user-service/internal/repository"
+	"github.com/Atmosfr/user-service/internal/service"
+)
+
+type AuditEventsResponse struct {
+	Events     []*models.AuditEvent `json:"events"`
+	NextCursor string               `json:"next_cursor,omitempty"`
+}
+
+type ActivityResponse struct {
+	Events     []service.ActivityEvent `json:"events"`
+	NextCursor string                  `json:"next_cursor,omitempty"`
+}
+
+func auditErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, service.ErrInvalidCursor),
+		errors.Is(err, service.ErrInvalidAuditRange):
+		return http.StatusBadRequest
+	}
+	return http.StatusInternalServerError
+}
+
+func writeAuditError(w http.ResponseWriter, err error) {
+	w.WriteHeader(auditErrorStatus(err))
+	json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+}
+
+func parseIDParam(query url.Values, name string) (*int64, error) {
+	v := query.Get(name)
+	if v == "" {
+		return nil, nil
+	}
+	id, err := strconv.ParseInt(v, 10, 64)
+	if err != nil || id < 1 {
+		return nil, fmt.Errorf("%s must be a positive integer", name)
+	}
+	return &id, nil
+}
+
+// parseAuditFilter reads the filters of GET /admin/audit.
+func parseAuditFilter(query url.Values) (repository.AuditFilter, error) {
+	filter := repository.AuditFilter{
+		Action:    query.Get("action"),
+		IP:        query.Get("ip"),
+		RequestID: query.Get("request_id"),
+	}
+	var err error
+	if filter.ActorID, err = parseIDParam(query, "actor_id"); err != nil {
+		return filter, err
+	}
+	if filter.TargetID, err = parseIDParam(query, "target_id"); err != nil {
+		return filter, err
+	}
+	if filter.UserID, err = parseIDParam(query, "user_id"); err != nil {
+		return filter, err
+	}
+	if filter.From, err = parseDateParam(query, "from"); err != nil {
+		return filter, err
+	}
+	if filter.To, err = parseDateParam(query, "to"); err != nil {
+		return filter, err
+	}
+	return filter, nil
+}
+
+func writeAuditPage(w http.ResponseWriter, page *service.AuditPage) {
+	json.NewEncoder(w).Encode(AuditEventsResponse{Events: page.Events, NextCursor: page.NextCursor})
+}
+
+// ListAuditEventsHandler pages through the audit log, newest first. Filters
+// are the query parameters action, actor_id, target_id, user_id (actor or
+// target), ip, request_id, from and to. Pass next_cursor as cursor to get the
+// following page.
+func ListAuditEventsHandler(svc service.AuditService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+
+		query := r.URL.Query()
+		filter, err := parseAuditFilter(query)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+			return
+		}
+		limit, err := parseLimit(query)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+			return
+		}
+
+		page, err := svc.List(r.Context(), filter, query.Get("cursor"), limit)
+		if err != nil {
+			writeAuditError(w, err)
+			return
+		}
+		writeAuditPage(w, page)
+	}
+}
+
+// VerifyAuditLogHandler recomputes the hash chain and reports the first
+// event that does not match.
+func VerifyAuditLogHandler(svc service.AuditService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+
+		result, err := svc.Verify(r.Context())
+		if err != nil {
+			writeAuditError(w, err)
+			return
+		}
+		json.NewEncoder(w).Encode(result)
+	}
+}
+
+// MyActivityHandler pages through the events the user did or was the target
+// of, such as sign-ins and changes administrators made to the account. Events
+// are returned as service.ActivityEvent.
+func MyActivityHandler(svc service.AuditService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+
+		user, ok := requireUser(w, r)
+		if !ok {
+			return
+		}
+		query := r.URL.Query()
+		limit, err := parseLimit(query)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+			return
+		}
+
+		page, err := svc.Activity(r.Context(), user.ID, query.Get("cursor"), limit)
+		if err != nil {
+			writeAuditError(w, err)
+			return
+		}
+		resp := ActivityResponse{Events: make([]service.ActivityEvent, len(page.Events)), NextCursor: page.NextCursor}
+		for i, event := range page.Events {
+			resp.Events[i] = service.UserActivity(user.ID, event)
+		}
+		json.NewEncoder(w).Encode(resp)
+	}
+}
@@ -0,0 +1,141 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/Atmosfr/user-service/internal/middleware"
+	"github.com/Atmosfr/user-service/internal/models"
+	"github.com/Atmosfr/user-service/internal/repository"
+	"github.com/Atmosfr/user-service/internal/service"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+type mockAuditService struct {
+	mock.Mock
+}
+
+func (m *mockAuditService) Record(ctx context.Context, event *models.AuditEvent) {
+	m.Called(ctx, event)
+}
+
+func (m *mockAuditService) List(ctx context.Context, filter repository.AuditFilter, cursor string, limit int) (*service.AuditPage, error) {
+	args := m.Called(ctx, filter, cursor, limit)
+	page, _ := args.Get(0).(*service.AuditPage)
+	return page, args.Error(1)
+}
+
+func (m *mockAuditService) Activity(ctx context.Context, userID int64, cursor string, limit int) (*service.AuditPage, error) {
+	args := m.Called(ctx, userID, cursor, limit)
+	page, _ := args.Get(0).(*service.AuditPage)
+	return page, args.Error(1)
+}
+
+func (m *mockAuditService) Verify(ctx context.Context) (*service.AuditVerification, error) {
+	args := m.Called(ctx)
+	result, _ := args.Get(0).(*service.AuditVerification)
+	return result, args.Error(1)
+}
+
+func TestListAuditEventsHandler(t *testing.T) {
+	actorID := int64(7)
+	from := time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC)
+	svc := new(mockAuditService)
+	svc.On("List", mock.Anything, repository.AuditFilter{Action: models.AuditLoginFailed, ActorID: &actorID, From: &from}, "", 20).
+		Return(&service.AuditPage{Events: []*models.AuditEvent{{ID: 3, Action: models.AuditLoginFailed}}, NextCursor: "Mw"}, nil)
+	svc.On("List", mock.Anything, repository.AuditFilter{}, "bogus", 0).Return(nil, service.ErrInvalidCursor)
+
+	tests := []struct {
+		name           string
+		query          string
+		expectedStatus int
+	}{
+		{name: "filtered", query: "?action=login.failed&actor_id=7&from=2026-10-01&limit=20", expectedStatus: http.StatusOK},
+		{name: "invalid actor", query: "?actor_id=bob", expectedStatus: http.StatusBadRequest},
+		{name: "invalid date", query: "?to=yesterday", expectedStatus: http.StatusBadRequest},
+		{name: "invalid cursor", query: "?cursor=bogus", expectedStatus: http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rr := httptest.NewRecorder()
+			ListAuditEventsHandler(svc).ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/admin/audit"+tt.query, nil))
+			require.Equal(t, tt.expectedStatus, rr.Code, rr.Body.String())
+		})
+	}
+
+	rr := httptest.NewRecorder()
+	ListAuditEventsHandler(svc).ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/admin/audit?action=login.failed&actor_id=7&from=2026-10-01&limit=20", nil))
+	var resp AuditEventsResponse
+	require.NoError(t, json.NewDecoder(rr.Body).Decode(&resp))
+	require.Len(t, resp.Events, 1)
+	require.Equal(t, "Mw", resp.NextCursor)
+}
+
+func TestMyActivityHandler(t *testing.T) {
+	user := &models.User{ID: 4, Role: "user"}
+	adminID := int64(1)
+	svc := new(mockAuditService)
+	svc.On("Activity", mock.Anything, int64(4), "", 0).Return(&service.AuditPage{Events: []*models.AuditEvent{
+		{ID: 3, Action: models.AuditRoleChanged, ActorID: &adminID, TargetID: &user.ID, IP: "198.51.100.9",
+			UserAgent: "admin-browser", RequestID: "req-admin", Details: map[string]string{"from": "user", "to": "admin"}},
+		{ID: 2, Action: models.AuditLoginFailed, TargetID: &user.ID, IP: "203.0.113.66", Details: map[string]string{"reason": "invalid_password"}},
+		{ID: 1, Action: models.AuditLoginSucceeded, ActorID: &user.ID, TargetID: &user.ID, IP: "192.0.2.1", UserAgent: "my-browser"},
+	}}, nil)
+
+	rr := serveAsUser(t, user, MyActivityHandler(svc), httptest.NewRequest(http.MethodGet, "/me/activity", nil))
+	require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+	body := rr.Body.String()
+	// nothing about the requests of others reaches the user
+	for _, leaked := range []string{"198.51.100.9", "admin-browser", "req-admin", "actor_id", "203.0.113.66", "invalid_password"} {
+		require.NotContains(t, body, leaked)
+	}
+
+	var resp ActivityResponse
+	require.NoError(t, json.NewDecoder(rr.Body).Decode(&resp))
+	require.Equal(t, []service.ActivityEvent{
+		{ID: 3, Action: models.AuditRoleChanged, Role: "target", Details: map[string]string{"from": "user", "to": "admin"}},
+		{ID: 2, Action: models.AuditLoginFailed, Role: "target"},
+		{ID: 1, Action: models.AuditLoginSucceeded, Role: "actor", IP: "192.0.2.1", UserAgent: "my-browser"},
+	}, resp.Events)
+	svc.AssertExpectations(t)
+}
+
+func TestAuditAdminRequests(t *testing.T) {
+	admin := &models.User{ID: 1, Role: "admin"}
+	svc := new(mockAuditService)
+	svc.On("Record", mock.Anything, mock.Anything)
+
+	mux := http.NewServeMux()
+	mux.Handle("DELETE /admin/users/{id}", middleware.AuditAdminRequests(svc)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	})))
+	mux.Handle("GET /admin/users/{id}", middleware.AuditAdminRequests(svc)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})))
+
+	req := httptest.NewRequest(http.MethodGet, "/admin/users/7", nil)
+	serveAsUser(t, admin, middleware.RequestInfo(mux), req)
+	svc.AssertNotCalled(t, "Record", mock.Anything, mock.Anything)
+
+	req = httptest.NewRequest(http.MethodDelete, "/admin/users/7", nil)
+	req.Header.Set(middleware.RequestIDHeader, "req-42")
+	req.Header.Set("User-Agent", "curl/8.0")
+	rr := serveAsUser(t, admin, middleware.RequestInfo(mux), req)
+	require.Equal(t, http.StatusNoContent, rr.Code)
+	require.Equal(t, "req-42", rr.Header().Get(middleware.RequestIDHeader))
+
+	ctx := svc.Calls[0].Arguments.Get(0).(context.Context)
+	require.Equal(t, service.RequestInfo{ID: "req-42", IP: "192.0.2.1", UserAgent: "curl/8.0"}, service.RequestInfoFromContext(ctx))
+	event := svc.Calls[0].Arguments.Get(1).(*models.AuditEvent)
+	require.Equal(t, models.AuditAdminRequest, event.Action)
+	require.Equal(t, int64(1), *event.ActorID)
+	require.Equal(t, map[string]string{
+		"method": http.MethodDelete,
+		"route":  "DELETE /admin/users/{id}",
+		"path":   "/admin/users/7",
+		"status": "204",
+	}, event.Details)
+}
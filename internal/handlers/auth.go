@@ -2,6 +2,7 @@ package handlers
 
 import (
 	"encoding/json"
+	"errors"
 	"log/slog"
 	"net/http"
 
@@ -14,6 +15,9 @@ type RegisterRequest struct {
 	Email    string `json:"email"`
 	Password string `json:"password"`
 	Username string `json:"username"`
+	// Accept maps document kinds to the versions the user accepted.
+	Accept    map[string]string `json:"accept,omitempty"`
+	Marketing bool              `json:"marketing,omitempty"`
 }
 
 type LoginRequest struct {
@@ -59,7 +63,21 @@ func RegisterHandler(svc service.UserService) http.HandlerFunc {
 			return
 		}
 
-		loginResp, err := svc.Register(r.Context(), req.Email, req.Password, req.Username)
+		loginResp, err := svc.Register(r.Context(), req.Email, req.Password, req.Username, service.RegistrationConsent{
+			Accepted:  req.Accept,
+			Marketing: req.Marketing,
+			IP:        clientIP(r),
+		})
+		if errors.Is(err, service.ErrSSORequired) {
+			w.WriteHeader(http.StatusForbidden)
+			json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+			return
+		}
+		if errors.Is(err, service.ErrConsentRequired) {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+			return
+		}
 		if err != nil {
 			w.WriteHeader(http.StatusInternalServerError)
 			json.NewEncoder(w).Encode(map[string]string{
@@ -68,7 +86,7 @@ func RegisterHandler(svc service.UserService) http.HandlerFunc {
 			return
 		}
 
-		slog.Info("registration successful", "email", req.Email, "user_id", loginResp.User.ID)
+		slog.Info("registration successful", "user_id", loginResp.User.ID)
 		w.WriteHeader(http.StatusCreated)
 		json.NewEncoder(w).Encode(loginResp)
 	}
@@ -109,13 +127,13 @@ func LoginHandler(svc service.UserService) http.HandlerFunc {
 			json.NewEncoder(w).Encode(map[string]string{
 				"error": err.Error(),
 			})
-			slog.Warn("login validation failed", "email", req.Email, "err", err)
+			slog.Warn("login validation failed", "err", err)
 			return
 		}
 
 		loginResp, err := svc.Login(r.Context(), req.Email, req.Password)
 		if err != nil {
-			slog.Warn("login failed", "email", req.Email, "err", err)
+			slog.Warn("login failed", "err", err)
 
 			if err == repository.ErrInvalidCredentials ||
 				err == repository.ErrInvalidPassword ||
@@ -124,6 +142,11 @@ func LoginHandler(svc service.UserService) http.HandlerFunc {
 				json.NewEncoder(w).Encode(map[string]string{"error": "invalid email or password"})
 				return
 			}
+			if errors.Is(err, service.ErrSSORequired) || isAccountStateError(err) {
+				w.WriteHeader(http.StatusForbidden)
+				json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+				return
+			}
 
 			w.WriteHeader(http.StatusInternalServerError)
 			json.NewEncoder(w).Encode(map[string]string{
@@ -132,7 +155,7 @@ func LoginHandler(svc service.UserService) http.HandlerFunc {
 			return
 		}
 
-		slog.Info("login successful", "email", req.Email)
+		slog.Info("login successful", "user_id", loginResp.User.ID)
 		w.Header().Set("Content-Type", "application/json")
 		w.WriteHeader(http.StatusOK)
 		json.NewEncoder(w).Encode(loginResp)
@@ -21,8 +21,8 @@ type mockUserService struct {
 	mock.Mock
 }
 
-func (m *mockUserService) Register(ctx context.Context, email, password, username string) (*service.LoginResponse, error) {
-	args := m.Called(ctx, email, password, username)
+func (m *mockUserService) Register(ctx context.Context, email, password, username string, consent service.RegistrationConsent) (*service.LoginResponse, error) {
+	args := m.Called(ctx, email, password, username, consent)
 	return args.Get(0).(*service.LoginResponse), args.Error(1)
 }
 
@@ -31,6 +31,12 @@ func (m *mockUserService) Login(ctx context.Context, email, password string) (*s
 	return args.Get(0).(*service.LoginResponse), args.Error(1)
 }
 
+func (m *mockUserService) UpdateProfile(ctx context.Context, userID int64, patch service.ProfilePatch, ifUnmodified *time.Time) (*models.User, error) {
+	args := m.Called(ctx, userID, patch, ifUnmodified)
+	user, _ := args.Get(0).(*models.User)
+	return user, args.Error(1)
+}
+
 func TestRegisterHandler(t *testing.T) {
 	tests := []struct {
 		name           string
@@ -51,7 +57,7 @@ func TestRegisterHandler(t *testing.T) {
 			},
 			requestBody: `{"email": "LhV4X@example.com", "password": "StrongP@ssw0rd!", "username": "testuser"}`,
 			setupMock: func(svc *mockUserService) {
-				svc.On("Register", mock.Anything, "LhV4X@example.com", "StrongP@ssw0rd!", "testuser").Return(&service.LoginResponse{
+				svc.On("Register", mock.Anything, "LhV4X@example.com", "StrongP@ssw0rd!", "testuser", mock.Anything).Return(&service.LoginResponse{
 					User: &models.User{
 						ID:        1,
 						Email:     "LhV4X@example.com",
@@ -155,13 +161,27 @@ func TestRegisterHandler(t *testing.T) {
 			expectedStatus: http.StatusBadRequest,
 			wantErr:        "invalid credentials",
 		},
+		{
+			name:        "outdated terms",
+			requestBody: `{"email": "LhV4X@example.com", "password": "StrongP@ssw0rd!", "username": "testuser", "accept": {"terms_of_service": "2026-01"}, "marketing": true}`,
+			method:      http.MethodPost,
+			contentType: "application/json",
+			setupMock: func(svc *mockUserService) {
+				svc.On("Register", mock.Anything, "LhV4X@example.com", "StrongP@ssw0rd!", "testuser", service.RegistrationConsent{
+					Accepted:  map[string]string{"terms_of_service": "2026-01"},
+					Marketing: true,
+				}).Return(&service.LoginResponse{}, service.ErrConsentRequired)
+			},
+			expectedStatus: http.StatusBadRequest,
+			wantErr:        "must be accepted",
+		},
 		{
 			name:        "service returns error",
 			requestBody: `{"email": "LhV4X@example.com", "password": "StrongP@ssw0rd!", "username": "testuser"}`,
 			method:      http.MethodPost,
 			contentType: "application/json",
 			setupMock: func(svc *mockUserService) {
-				svc.On("Register", mock.Anything, "LhV4X@example.com", "StrongP@ssw0rd!", "testuser").Return(&service.LoginResponse{}, errors.New("service error"))
+				svc.On("Register", mock.Anything, "LhV4X@example.com", "StrongP@ssw0rd!", "testuser", mock.Anything).Return(&service.LoginResponse{}, errors.New("service error"))
 			},
 			expectedStatus: http.StatusInternalServerError,
 			wantErr:        "service error",
@@ -312,6 +332,28 @@ func TestLoginHandler(t *testing.T) {
 			expectedStatus: http.StatusUnauthorized,
 			wantErr:        "invalid email or password",
 		},
+		{
+			name:        "sso enforced domain",
+			requestBody: `{"email": "bob@acme.com", "password": "StrongP@ssw0rd!"}`,
+			method:      http.MethodPost,
+			contentType: "application/json",
+			setupMock: func(svc *mockUserService) {
+				svc.On("Login", mock.Anything, "bob@acme.com", "StrongP@ssw0rd!").Return((*service.LoginResponse)(nil), service.ErrSSORequired)
+			},
+			expectedStatus: http.StatusForbidden,
+			wantErr:        service.ErrSSORequired.Error(),
+		},
+		{
+			name:        "banned account",
+			requestBody: `{"email": "LhV4X@example.com", "password": "StrongP@ssw0rd!"}`,
+			method:      http.MethodPost,
+			contentType: "application/json",
+			setupMock: func(svc *mockUserService) {
+				svc.On("Login", mock.Anything, "LhV4X@example.com", "StrongP@ssw0rd!").Return((*service.LoginResponse)(nil), service.ErrAccountBanned)
+			},
+			expectedStatus: http.StatusForbidden,
+			wantErr:        service.ErrAccountBanned.Error(),
+		},
 		{
 			name:        "service returns error",
 			requestBody: `{"email": "LhV4X@example.com", "password": "StrongP@ssw0rd!"}`,
@@ -0,0 +1,95 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/Atmosfr/user-service/internal/service"
+)
+
+type CheckManyRequest struct {
+	Checks []service.AuthzCheck `json:"checks"`
+}
+
+type CheckManyResponse struct {
+	Decisions []*service.AuthzDecision `json:"decisions"`
+}
+
+func authzErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, service.ErrInvalidAuthzSubject),
+		errors.Is(err, service.ErrMissingAuthzAction):
+		return http.StatusBadRequest
+	case errors.Is(err, service.ErrAuthzBatchTooLarge):
+		return http.StatusRequestEntityTooLarge
+	}
+	return http.StatusInternalServerError
+}
+
+func writeAuthzError(w http.ResponseWriter, err error) {
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(authzErrorStatus(err))
+	json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+}
+
+// setDecisionCacheHeaders lets the caller reuse a decision for maxAge. Decisions
+// depend on the caller's credentials, so shared caches must not store them.
+func setDecisionCacheHeaders(w http.ResponseWriter, maxAge time.Duration) {
+	if maxAge <= 0 {
+		w.Header().Set("Cache-Control", "no-store")
+		return
+	}
+	w.Header().Set("Cache-Control", fmt.Sprintf("private, max-age=%d", int(maxAge.Seconds())))
+	w.Header().Set("Vary", "Authorization")
+}
+
+func CheckAuthzHandler(svc service.AuthzService, maxAge time.Duration) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		defer r.Body.Close()
+		w.Header().Set("Content-Type", "application/json")
+
+		var req service.AuthzCheck
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			w.Header().Set("Cache-Control", "no-store")
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"error": "Invalid request payload"})
+			return
+		}
+
+		decision, err := svc.Check(r.Context(), req)
+		if err != nil {
+			writeAuthzError(w, err)
+			return
+		}
+
+		setDecisionCacheHeaders(w, maxAge)
+		json.NewEncoder(w).Encode(decision)
+	}
+}
+
+func CheckManyAuthzHandler(svc service.AuthzService, maxAge time.Duration) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		defer r.Body.Close()
+		w.Header().Set("Content-Type", "application/json")
+
+		var req CheckManyRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			w.Header().Set("Cache-Control", "no-store")
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"error": "Invalid request payload"})
+			return
+		}
+
+		decisions, err := svc.CheckMany(r.Context(), req.Checks)
+		if err != nil {
+			writeAuthzError(w, err)
+			return
+		}
+
+		setDecisionCacheHeaders(w, maxAge)
+		json.NewEncoder(w).Encode(CheckManyResponse{Decisions: decisions})
+	}
+}
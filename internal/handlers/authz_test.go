@@ -0,0 +1,176 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/Atmosfr/user-service/internal/auth"
+	"github.com/Atmosfr/user-service/internal/middleware"
+	"github.com/Atmosfr/user-service/internal/models"
+	"github.com/Atmosfr/user-service/internal/service"
+	"github.com/Atmosfr/user-service/internal/testutil"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+type mockAuthzService struct {
+	mock.Mock
+}
+
+func (m *mockAuthzService) Check(ctx context.Context, check service.AuthzCheck) (*service.AuthzDecision, error) {
+	args := m.Called(ctx, check)
+	decision, _ := args.Get(0).(*service.AuthzDecision)
+	return decision, args.Error(1)
+}
+
+func (m *mockAuthzService) CheckMany(ctx context.Context, checks []service.AuthzCheck) ([]*service.AuthzDecision, error) {
+	args := m.Called(ctx, checks)
+	decisions, _ := args.Get(0).([]*service.AuthzDecision)
+	return decisions, args.Error(1)
+}
+
+func TestCheckAuthzHandler(t *testing.T) {
+	check := service.AuthzCheck{Subject: service.AuthzSubject{UserID: 1}, Action: "users:read"}
+
+	tests := []struct {
+		name          string
+		body          string
+		setupMock     func(svc *mockAuthzService)
+		expectedCode  int
+		expectedCache string
+		expectedBody  string
+	}{
+		{
+			name: "decision",
+			body: `{"subject":{"user_id":1},"action":"users:read"}`,
+			setupMock: func(svc *mockAuthzService) {
+				svc.On("Check", mock.Anything, check).Return(&service.AuthzDecision{Allowed: true, Reason: "granted by role admin"}, nil)
+			},
+			expectedCode:  http.StatusOK,
+			expectedCache: "private, max-age=30",
+			expectedBody:  `{"allowed":true,"reason":"granted by role admin"}`,
+		},
+		{
+			name: "validation error",
+			body: `{"subject":{},"action":"users:read"}`,
+			setupMock: func(svc *mockAuthzService) {
+				svc.On("Check", mock.Anything, mock.Anything).Return(nil, service.ErrInvalidAuthzSubject)
+			},
+			expectedCode:  http.StatusBadRequest,
+			expectedCache: "no-store",
+			expectedBody:  `{"error":"subject needs exactly one of user_id or token"}`,
+		},
+		{
+			name: "internal error",
+			body: `{"subject":{"user_id":1},"action":"users:read"}`,
+			setupMock: func(svc *mockAuthzService) {
+				svc.On("Check", mock.Anything, check).Return(nil, errors.New("db down"))
+			},
+			expectedCode:  http.StatusInternalServerError,
+			expectedCache: "no-store",
+			expectedBody:  `{"error":"db down"}`,
+		},
+		{
+			name:          "invalid payload",
+			body:          `{`,
+			setupMock:     func(svc *mockAuthzService) {},
+			expectedCode:  http.StatusBadRequest,
+			expectedCache: "no-store",
+			expectedBody:  `{"error":"Invalid request payload"}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc := new(mockAuthzService)
+			tt.setupMock(svc)
+
+			req := httptest.NewRequest(http.MethodPost, "/authz/check", bytes.NewBufferString(tt.body))
+			rr := httptest.NewRecorder()
+			CheckAuthzHandler(svc, 30*time.Second).ServeHTTP(rr, req)
+
+			require.Equal(t, tt.expectedCode, rr.Code)
+			require.Equal(t, tt.expectedCache, rr.Header().Get("Cache-Control"))
+			require.JSONEq(t, tt.expectedBody, rr.Body.String())
+			svc.AssertExpectations(t)
+		})
+	}
+}
+
+func TestCheckManyAuthzHandler(t *testing.T) {
+	svc := new(mockAuthzService)
+	svc.On("CheckMany", mock.Anything, mock.Anything).Return([]*service.AuthzDecision{
+		{Allowed: true, Reason: "allowed by policy", Policy: "self-read"},
+		{Allowed: false, Reason: "no role or policy grants this action"},
+	}, nil)
+
+	body := `{"checks":[{"subject":{"user_id":1},"action":"users:read","resource":{"type":"user","id":1}},{"subject":{"token":"t"},"action":"users:delete"}]}`
+	req := httptest.NewRequest(http.MethodPost, "/authz/check-many", bytes.NewBufferString(body))
+	rr := httptest.NewRecorder()
+	CheckManyAuthzHandler(svc, 0).ServeHTTP(rr, req)
+
+	require.Equal(t, http.StatusOK, rr.Code)
+	require.Equal(t, "no-store", rr.Header().Get("Cache-Control"))
+
+	var resp CheckManyResponse
+	require.NoError(t, json.NewDecoder(rr.Body).Decode(&resp))
+	require.Len(t, resp.Decisions, 2)
+	require.Equal(t, "self-read", resp.Decisions[0].Policy)
+
+	checks := svc.Calls[0].Arguments.Get(1).([]service.AuthzCheck)
+	require.Equal(t, "t", checks[1].Subject.Token)
+	require.Equal(t, map[string]any{"type": "user", "id": float64(1)}, checks[0].Resource)
+
+	svc = new(mockAuthzService)
+	svc.On("CheckMany", mock.Anything, mock.Anything).Return(nil, service.ErrAuthzBatchTooLarge)
+	rr = httptest.NewRecorder()
+	CheckManyAuthzHandler(svc, 0).ServeHTTP(rr, httptest.NewRequest(http.MethodPost, "/authz/check-many", bytes.NewBufferString(`{"checks":[]}`)))
+	require.Equal(t, http.StatusRequestEntityTooLarge, rr.Code)
+}
+
+func TestRequirePermissionOrServiceAccount(t *testing.T) {
+	auth.JwtSecret = []byte("secret")
+	user := &models.User{ID: 7, Role: "user"}
+	userToken, err := auth.GenerateToken(user, time.Hour, auth.JwtSecret)
+	require.NoError(t, err)
+	apiKey := service.APIKeyPrefix + "key"
+
+	tests := []struct {
+		name           string
+		token          string
+		permissions    models.PermissionSet
+		expectedStatus int
+	}{
+		{name: "service account", token: apiKey, expectedStatus: http.StatusOK},
+		{name: "user with permission", token: userToken, permissions: models.PermissionSet{service.PermissionAuthzCheck}, expectedStatus: http.StatusOK},
+		{name: "user without permission", token: userToken, permissions: models.PermissionSet{"profile:read"}, expectedStatus: http.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := new(testutil.MockUserRepo)
+			repo.On("FindByID", mock.Anything, int64(7)).Return(user, nil)
+			rbac := new(mockRBACService)
+			rbac.On("Permissions", mock.Anything, "user").Return(tt.permissions, nil)
+			sas := new(mockServiceAccountService)
+			sas.On("AuthenticateAPIKey", mock.Anything, apiKey).Return(&models.ServiceAccount{ID: 3}, nil)
+
+			authn := middleware.NewAuthMiddleware(repo, middleware.WithServiceAccounts(sas), middleware.WithPermissions(rbac))
+			handler := authn(middleware.RequirePermissionOrServiceAccount(service.PermissionAuthzCheck)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusOK)
+			})))
+
+			req := httptest.NewRequest(http.MethodPost, "/authz/check", nil)
+			req.Header.Set("Authorization", "Bearer "+tt.token)
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, req)
+			require.Equal(t, tt.expectedStatus, rr.Code)
+		})
+	}
+}
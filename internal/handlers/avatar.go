@@ -0,0 +1,115 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+
+	"github.com/Atmosfr/user-service/internal/blob"
+	"github.com/Atmosfr/user-service/internal/imaging"
+	"github.com/Atmosfr/user-service/internal/service"
+)
+
+// avatarFormField is the multipart field PUT /me/avatar reads the picture from.
+const avatarFormField = "avatar"
+
+func avatarErrorStatus(err error) int {
+	var tooLarge *http.MaxBytesError
+	switch {
+	case errors.Is(err, service.ErrAvatarTooLarge), errors.As(err, &tooLarge):
+		return http.StatusRequestEntityTooLarge
+	case errors.Is(err, imaging.ErrUnsupportedFormat):
+		return http.StatusUnsupportedMediaType
+	case errors.Is(err, imaging.ErrInvalidImage),
+		errors.Is(err, imaging.ErrTooManyPixels):
+		return http.StatusUnprocessableEntity
+	}
+	return profileErrorStatus(err)
+}
+
+func writeAvatarError(w http.ResponseWriter, err error) {
+	status := avatarErrorStatus(err)
+	if status == http.StatusRequestEntityTooLarge {
+		err = service.ErrAvatarTooLarge
+	}
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+}
+
+// UploadAvatarHandler replaces the avatar of the signed-in user with the
+// picture in the "avatar" field of a multipart/form-data body. The declared
+// content type of the part is ignored, the content is sniffed.
+func UploadAvatarHandler(svc service.AvatarService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		defer r.Body.Close()
+		w.Header().Set("Content-Type", "application/json")
+
+		user, ok := requireUser(w, r)
+		if !ok {
+			return
+		}
+
+		// leave room for the multipart headers and boundaries
+		r.Body = http.MaxBytesReader(w, r.Body, service.MaxAvatarBytes+64<<10)
+		reader, err := r.MultipartReader()
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"error": "Content-Type must be multipart/form-data"})
+			return
+		}
+		for {
+			part, err := reader.NextPart()
+			if errors.Is(err, io.EOF) {
+				w.WriteHeader(http.StatusBadRequest)
+				json.NewEncoder(w).Encode(map[string]string{"error": "missing " + avatarFormField + " field"})
+				return
+			}
+			if err != nil {
+				writeAvatarError(w, err)
+				return
+			}
+			if part.FormName() != avatarFormField {
+				part.Close()
+				continue
+			}
+
+			updated, err := svc.Upload(r.Context(), user.ID, part)
+			part.Close()
+			if err != nil {
+				writeAvatarError(w, err)
+				return
+			}
+
+			w.Header().Set("ETag", userETag(updated))
+			json.NewEncoder(w).Encode(profileResponse(updated))
+			return
+		}
+	}
+}
+
+// AvatarFileHandler serves avatars from store under GET /avatars/{path...}.
+// Keys are never reused, so responses may be cached indefinitely.
+func AvatarFileHandler(store blob.Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		body, info, err := store.Get(r.Context(), service.AvatarKeyPrefix+r.PathValue("path"))
+		if errors.Is(err, blob.ErrNotFound) || errors.Is(err, blob.ErrInvalidKey) {
+			http.NotFound(w, r)
+			return
+		}
+		if err != nil {
+			http.Error(w, "avatar unavailable", http.StatusBadGateway)
+			return
+		}
+		defer body.Close()
+
+		w.Header().Set("Content-Type", info.ContentType)
+		if info.Size >= 0 {
+			w.Header().Set("Content-Length", strconv.FormatInt(info.Size, 10))
+		}
+		w.Header().Set("Cache-Control", "public, max-age=31536000, immutable")
+		w.Header().Set("X-Content-Type-Options", "nosniff")
+		io.Copy(w, body)
+	}
+}
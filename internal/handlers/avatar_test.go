@@ -0,0 +1,106 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/Atmosfr/user-service/internal/blob"
+	"github.com/Atmosfr/user-service/internal/imaging"
+	"github.com/Atmosfr/user-service/internal/models"
+	"github.com/Atmosfr/user-service/internal/service"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+type mockAvatarService struct {
+	mock.Mock
+}
+
+func (m *mockAvatarService) Upload(ctx context.Context, userID int64, image io.Reader) (*models.User, error) {
+	data, _ := io.ReadAll(image)
+	args := m.Called(ctx, userID, string(data))
+	user, _ := args.Get(0).(*models.User)
+	return user, args.Error(1)
+}
+
+func (m *mockAvatarService) DeleteFiles(ctx context.Context, key string) {
+	m.Called(ctx, key)
+}
+
+func multipartBody(t *testing.T, field, content string) (*bytes.Buffer, string) {
+	t.Helper()
+	var buf bytes.Buffer
+	mw := multipart.NewWriter(&buf)
+	require.NoError(t, mw.WriteField("note", "ignored"))
+	fw, err := mw.CreateFormFile(field, "me.png")
+	require.NoError(t, err)
+	fw.Write([]byte(content))
+	require.NoError(t, mw.Close())
+	return &buf, mw.FormDataContentType()
+}
+
+func TestUploadAvatarHandler(t *testing.T) {
+	user := &models.User{ID: 7, Role: "user"}
+	svc := new(mockAvatarService)
+	svc.On("Upload", mock.Anything, int64(7), "png-bytes").
+		Return(&models.User{ID: 7, AvatarURL: "https://cdn.example.com/avatars/7/a/512.jpg", AvatarURLs: map[string]string{"512": "https://cdn.example.com/avatars/7/a/512.jpg"}}, nil)
+	svc.On("Upload", mock.Anything, int64(7), "<svg/>").Return(nil, imaging.ErrUnsupportedFormat)
+	svc.On("Upload", mock.Anything, int64(7), "huge").Return(nil, service.ErrAvatarTooLarge)
+
+	tests := []struct {
+		name           string
+		field          string
+		content        string
+		expectedStatus int
+	}{
+		{name: "upload", field: "avatar", content: "png-bytes", expectedStatus: http.StatusOK},
+		{name: "sniffed type refused", field: "avatar", content: "<svg/>", expectedStatus: http.StatusUnsupportedMediaType},
+		{name: "too large", field: "avatar", content: "huge", expectedStatus: http.StatusRequestEntityTooLarge},
+		{name: "missing field", field: "picture", content: "png-bytes", expectedStatus: http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			body, contentType := multipartBody(t, tt.field, tt.content)
+			req := httptest.NewRequest(http.MethodPut, "/me/avatar", body)
+			req.Header.Set("Content-Type", contentType)
+
+			rr := serveAsUser(t, user, UploadAvatarHandler(svc), req)
+			require.Equal(t, tt.expectedStatus, rr.Code, rr.Body.String())
+			if tt.expectedStatus == http.StatusOK {
+				require.Contains(t, rr.Body.String(), `"avatar_urls":{"512":`)
+			}
+		})
+	}
+
+	req := httptest.NewRequest(http.MethodPut, "/me/avatar", strings.NewReader("png-bytes"))
+	req.Header.Set("Content-Type", "image/png")
+	rr := serveAsUser(t, user, UploadAvatarHandler(svc), req)
+	require.Equal(t, http.StatusBadRequest, rr.Code)
+}
+
+func TestAvatarFileHandler(t *testing.T) {
+	store := blob.NewFilesystemStore(t.TempDir())
+	require.NoError(t, store.Put(context.Background(), "avatars/7/a/64.jpg", strings.NewReader("jpeg"), 4, "image/jpeg"))
+	require.NoError(t, store.Put(context.Background(), "exports/7/data.zip", strings.NewReader("zip"), 3, "application/zip"))
+
+	mux := http.NewServeMux()
+	mux.Handle("GET /avatars/{path...}", AvatarFileHandler(store))
+
+	rr := httptest.NewRecorder()
+	mux.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/avatars/7/a/64.jpg", nil))
+	require.Equal(t, http.StatusOK, rr.Code)
+	require.Equal(t, "image/jpeg", rr.Header().Get("Content-Type"))
+	require.Equal(t, "jpeg", rr.Body.String())
+
+	for _, path := range []string{"/avatars/7/a/128.jpg", "/avatars/../exports/7/data.zip", "/avatars/%2e%2e/exports/7/data.zip"} {
+		rr = httptest.NewRecorder()
+		mux.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, path, nil))
+		require.NotEqual(t, http.StatusOK, rr.Code, path)
+	}
+}
@@ -0,0 +1,197 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/Atmosfr/user-service/internal/models"
+	"github.com/Atmosfr/user-service/internal/repository"
+	"github.com/Atmosfr/user-service/internal/service"
+)
+
+type LegalDocumentsResponse struct {
+	Documents []*models.LegalDocument `json:"documents"`
+}
+
+type PublishLegalDocumentRequest struct {
+	Kind      string `json:"kind"`
+	Version   string `json:"version"`
+	URL       string `json:"url"`
+	Mandatory bool   `json:"mandatory"`
+	// PublishedAt schedules the version, it takes effect right away when unset.
+	PublishedAt *time.Time `json:"published_at,omitempty"`
+}
+
+type AcceptDocumentsRequest struct {
+	// Accept maps document kinds to the versions the user accepted.
+	Accept map[string]string `json:"accept"`
+}
+
+type MarketingConsentRequest struct {
+	Granted *bool `json:"granted"`
+}
+
+// clientIP returns the address of the peer the request came from.
+func clientIP(r *http.Request) string {
+	if host, _, err := net.SplitHostPort(r.RemoteAddr); err == nil {
+		return host
+	}
+	return r.RemoteAddr
+}
+
+func consentErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, service.ErrInvalidLegalDocument),
+		errors.Is(err, service.ErrDocumentNotCurrent):
+		return http.StatusBadRequest
+	case errors.Is(err, repository.ErrDocumentVersionExists):
+		return http.StatusConflict
+	}
+	return http.StatusInternalServerError
+}
+
+func writeConsentError(w http.ResponseWriter, err error) {
+	w.WriteHeader(consentErrorStatus(err))
+	json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+}
+
+// CurrentLegalDocumentsHandler lists the versions in force, which is what
+// registration must accept.
+func CurrentLegalDocumentsHandler(svc service.ConsentService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+
+		docs, err := svc.Current(r.Context())
+		if err != nil {
+			writeConsentError(w, err)
+			return
+		}
+		json.NewEncoder(w).Encode(LegalDocumentsResponse{Documents: docs})
+	}
+}
+
+func ListLegalDocumentsHandler(svc service.ConsentService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+
+		docs, err := svc.ListDocuments(r.Context())
+		if err != nil {
+			writeConsentError(w, err)
+			return
+		}
+		json.NewEncoder(w).Encode(LegalDocumentsResponse{Documents: docs})
+	}
+}
+
+// PublishLegalDocumentHandler adds a document version. Users have to accept
+// mandatory versions before they can use the API again.
+func PublishLegalDocumentHandler(svc service.ConsentService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		defer r.Body.Close()
+		w.Header().Set("Content-Type", "application/json")
+
+		var req PublishLegalDocumentRequest
+		dec := json.NewDecoder(r.Body)
+		dec.DisallowUnknownFields()
+		if err := dec.Decode(&req); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"error": "Invalid request payload"})
+			return
+		}
+
+		doc := &models.LegalDocument{Kind: req.Kind, Version: req.Version, URL: req.URL, Mandatory: req.Mandatory}
+		if req.PublishedAt != nil {
+			doc.PublishedAt = *req.PublishedAt
+		}
+		if err := svc.Publish(r.Context(), doc); err != nil {
+			writeConsentError(w, err)
+			return
+		}
+
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(doc)
+	}
+}
+
+// ConsentStatusHandler shows what the signed-in user agreed to and which
+// versions they still have to accept.
+func ConsentStatusHandler(svc service.ConsentService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+
+		user, ok := requireUser(w, r)
+		if !ok {
+			return
+		}
+
+		status, err := svc.Status(r.Context(), user.ID)
+		if err != nil {
+			writeConsentError(w, err)
+			return
+		}
+		json.NewEncoder(w).Encode(status)
+	}
+}
+
+func AcceptDocumentsHandler(svc service.ConsentService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		defer r.Body.Close()
+		w.Header().Set("Content-Type", "application/json")
+
+		user, ok := requireUser(w, r)
+		if !ok {
+			return
+		}
+
+		var req AcceptDocumentsRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil || len(req.Accept) == 0 {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"error": "Invalid request payload"})
+			return
+		}
+
+		if err := svc.Accept(r.Context(), user.ID, req.Accept, clientIP(r)); err != nil {
+			writeConsentError(w, err)
+			return
+		}
+		status, err := svc.Status(r.Context(), user.ID)
+		if err != nil {
+			writeConsentError(w, err)
+			return
+		}
+		json.NewEncoder(w).Encode(status)
+	}
+}
+
+func MarketingConsentHandler(svc service.ConsentService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		defer r.Body.Close()
+		w.Header().Set("Content-Type", "application/json")
+
+		user, ok := requireUser(w, r)
+		if !ok {
+			return
+		}
+
+		var req MarketingConsentRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Granted == nil {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"error": "Invalid request payload"})
+			return
+		}
+
+		if err := svc.SetMarketing(r.Context(), user.ID, *req.Granted, clientIP(r)); err != nil {
+			writeConsentError(w, err)
+			return
+		}
+		status, err := svc.Status(r.Context(), user.ID)
+		if err != nil {
+			writeConsentError(w, err)
+			return
+		}
+		json.NewEncoder(w).Encode(status)
+	}
+}
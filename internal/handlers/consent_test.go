@@ -0,0 +1,135 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/Atmosfr/user-service/internal/auth"
+	"github.com/Atmosfr/user-service/internal/middleware"
+	"github.com/Atmosfr/user-service/internal/models"
+	"github.com/Atmosfr/user-service/internal/repository"
+	"github.com/Atmosfr/user-service/internal/service"
+	"github.com/Atmosfr/user-service/internal/testutil"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+type mockConsentService struct {
+	mock.Mock
+}
+
+func (m *mockConsentService) Publish(ctx context.Context, doc *models.LegalDocument) error {
+	return m.Called(ctx, doc).Error(0)
+}
+
+func (m *mockConsentService) ListDocuments(ctx context.Context) ([]*models.LegalDocument, error) {
+	args := m.Called(ctx)
+	docs, _ := args.Get(0).([]*models.LegalDocument)
+	return docs, args.Error(1)
+}
+
+func (m *mockConsentService) Current(ctx context.Context) ([]*models.LegalDocument, error) {
+	args := m.Called(ctx)
+	docs, _ := args.Get(0).([]*models.LegalDocument)
+	return docs, args.Error(1)
+}
+
+func (m *mockConsentService) Pending(ctx context.Context, userID int64) ([]*models.LegalDocument, error) {
+	args := m.Called(ctx, userID)
+	docs, _ := args.Get(0).([]*models.LegalDocument)
+	return docs, args.Error(1)
+}
+
+func (m *mockConsentService) CheckAccepted(ctx context.Context, versions map[string]string) error {
+	return m.Called(ctx, versions).Error(0)
+}
+
+func (m *mockConsentService) Accept(ctx context.Context, userID int64, versions map[string]string, ip string) error {
+	return m.Called(ctx, userID, versions, ip).Error(0)
+}
+
+func (m *mockConsentService) SetMarketing(ctx context.Context, userID int64, granted bool, ip string) error {
+	return m.Called(ctx, userID, granted, ip).Error(0)
+}
+
+func (m *mockConsentService) Status(ctx context.Context, userID int64) (*service.ConsentStatus, error) {
+	args := m.Called(ctx, userID)
+	status, _ := args.Get(0).(*service.ConsentStatus)
+	return status, args.Error(1)
+}
+
+func TestConsentHandlers(t *testing.T) {
+	user := &models.User{ID: 7}
+	svc := new(mockConsentService)
+	svc.On("Status", mock.Anything, int64(7)).Return(&service.ConsentStatus{Pending: []*models.LegalDocument{}, Marketing: true}, nil)
+	// httptest requests come from 192.0.2.1
+	svc.On("Accept", mock.Anything, int64(7), map[string]string{"terms_of_service": "2026-10"}, "192.0.2.1").Return(nil)
+	svc.On("Accept", mock.Anything, int64(7), map[string]string{"terms_of_service": "2026-01"}, mock.Anything).Return(service.ErrDocumentNotCurrent)
+	svc.On("SetMarketing", mock.Anything, int64(7), true, "192.0.2.1").Return(nil)
+	svc.On("Publish", mock.Anything, mock.MatchedBy(func(d *models.LegalDocument) bool { return d.Version == "2026-11" })).Return(nil)
+	svc.On("Publish", mock.Anything, mock.MatchedBy(func(d *models.LegalDocument) bool { return d.Version == "2026-10" })).Return(repository.ErrDocumentVersionExists)
+
+	tests := []struct {
+		name           string
+		method         string
+		body           string
+		handler        http.HandlerFunc
+		expectedStatus int
+	}{
+		{name: "status", method: http.MethodGet, handler: ConsentStatusHandler(svc), expectedStatus: http.StatusOK},
+		{name: "accept", method: http.MethodPost, body: `{"accept":{"terms_of_service":"2026-10"}}`, handler: AcceptDocumentsHandler(svc), expectedStatus: http.StatusOK},
+		{name: "accept outdated", method: http.MethodPost, body: `{"accept":{"terms_of_service":"2026-01"}}`, handler: AcceptDocumentsHandler(svc), expectedStatus: http.StatusBadRequest},
+		{name: "accept nothing", method: http.MethodPost, body: `{}`, handler: AcceptDocumentsHandler(svc), expectedStatus: http.StatusBadRequest},
+		{name: "marketing", method: http.MethodPut, body: `{"granted":true}`, handler: MarketingConsentHandler(svc), expectedStatus: http.StatusOK},
+		{name: "marketing without choice", method: http.MethodPut, body: `{}`, handler: MarketingConsentHandler(svc), expectedStatus: http.StatusBadRequest},
+		{name: "publish", method: http.MethodPost, body: `{"kind":"terms_of_service","version":"2026-11","url":"https://example.com/terms","mandatory":true}`, handler: PublishLegalDocumentHandler(svc), expectedStatus: http.StatusCreated},
+		{name: "publish existing version", method: http.MethodPost, body: `{"kind":"terms_of_service","version":"2026-10","url":"https://example.com/terms"}`, handler: PublishLegalDocumentHandler(svc), expectedStatus: http.StatusConflict},
+		{name: "publish unknown field", method: http.MethodPost, body: `{"kind":"terms_of_service","text":"..."}`, handler: PublishLegalDocumentHandler(svc), expectedStatus: http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rr := serveAsUser(t, user, tt.handler, httptest.NewRequest(tt.method, "/", bytes.NewBufferString(tt.body)))
+			require.Equal(t, tt.expectedStatus, rr.Code, rr.Body.String())
+		})
+	}
+}
+
+func TestAuthMiddlewareConsentGate(t *testing.T) {
+	auth.JwtSecret = []byte("secret")
+	repo := new(testutil.MockUserRepo)
+	repo.On("FindByID", mock.Anything, int64(7)).Return(&models.User{ID: 7}, nil)
+	repo.On("FindByID", mock.Anything, int64(8)).Return(&models.User{ID: 8}, nil)
+	consents := new(mockConsentService)
+	consents.On("Pending", mock.Anything, int64(7)).Return([]*models.LegalDocument{}, nil)
+	consents.On("Pending", mock.Anything, int64(8)).Return([]*models.LegalDocument{{ID: 5, Kind: models.DocumentTermsOfService, Version: "2026-11", Mandatory: true}}, nil)
+	gate := middleware.NewAuthMiddleware(repo, middleware.WithConsentGate(consents))
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+
+	serve := func(userID int64) *httptest.ResponseRecorder {
+		token, err := auth.GenerateToken(&models.User{ID: userID}, time.Hour, auth.JwtSecret)
+		require.NoError(t, err)
+		req := httptest.NewRequest(http.MethodGet, "/me", nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		rr := httptest.NewRecorder()
+		gate(ok).ServeHTTP(rr, req)
+		return rr
+	}
+
+	require.Equal(t, http.StatusOK, serve(7).Code)
+
+	rr := serve(8)
+	require.Equal(t, http.StatusForbidden, rr.Code)
+	var body struct {
+		Error   string                  `json:"error"`
+		Pending []*models.LegalDocument `json:"pending"`
+	}
+	require.NoError(t, json.NewDecoder(rr.Body).Decode(&body))
+	require.Equal(t, service.ErrConsentRequired.Error(), body.Error)
+	require.Len(t, body.Pending, 1)
+	require.Equal(t, "2026-11", body.Pending[0].Version)
+}
@@ -0,0 +1,90 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+
+	"github.com/Atmosfr/user-service/internal/repository"
+	"github.com/Atmosfr/user-service/internal/service"
+)
+
+func dataExportErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, repository.ErrExportNotFound),
+		errors.Is(err, service.ErrInvalidExportLink):
+		return http.StatusNotFound
+	case errors.Is(err, repository.ErrExportInProgress):
+		return http.StatusConflict
+	}
+	return http.StatusInternalServerError
+}
+
+func writeDataExportError(w http.ResponseWriter, err error) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(dataExportErrorStatus(err))
+	json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+}
+
+// RequestDataExportHandler queues an export of the signed-in user's data. The
+// download link is emailed once it is ready.
+func RequestDataExportHandler(svc service.DataExportService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+
+		user, ok := requireUser(w, r)
+		if !ok {
+			return
+		}
+
+		export, err := svc.Request(r.Context(), user)
+		if err != nil {
+			writeDataExportError(w, err)
+			return
+		}
+
+		w.WriteHeader(http.StatusAccepted)
+		json.NewEncoder(w).Encode(export)
+	}
+}
+
+// LatestDataExportHandler shows the status of the signed-in user's last export.
+func LatestDataExportHandler(svc service.DataExportService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+
+		user, ok := requireUser(w, r)
+		if !ok {
+			return
+		}
+
+		export, err := svc.Latest(r.Context(), user.ID)
+		if err != nil {
+			writeDataExportError(w, err)
+			return
+		}
+
+		json.NewEncoder(w).Encode(export)
+	}
+}
+
+// DownloadDataExportHandler serves the archive of an emailed download link. It
+// needs no authentication, the token is the credential.
+func DownloadDataExportHandler(svc service.DataExportService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		body, export, err := svc.Open(r.Context(), r.URL.Query().Get("token"))
+		if err != nil {
+			writeDataExportError(w, err)
+			return
+		}
+		defer body.Close()
+
+		w.Header().Set("Content-Type", "application/zip")
+		w.Header().Set("Content-Length", strconv.FormatInt(export.Size, 10))
+		w.Header().Set("Content-Disposition", `attachment; filename="data-export-`+strconv.FormatInt(export.ID, 10)+`.zip"`)
+		w.Header().Set("Cache-Control", "private, no-store")
+		io.Copy(w, body)
+	}
+}
@@ -0,0 +1,97 @@
+package handlers
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Atmosfr/user-service/internal/models"
+	"github.com/Atmosfr/user-service/internal/repository"
+	"github.com/Atmosfr/user-service/internal/service"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+type mockDataExportService struct {
+	mock.Mock
+}
+
+func (m *mockDataExportService) Request(ctx context.Context, user *models.User) (*models.DataExport, error) {
+	args := m.Called(ctx, user)
+	export, _ := args.Get(0).(*models.DataExport)
+	return export, args.Error(1)
+}
+
+func (m *mockDataExportService) Latest(ctx context.Context, userID int64) (*models.DataExport, error) {
+	args := m.Called(ctx, userID)
+	export, _ := args.Get(0).(*models.DataExport)
+	return export, args.Error(1)
+}
+
+func (m *mockDataExportService) Open(ctx context.Context, token string) (io.ReadCloser, *models.DataExport, error) {
+	args := m.Called(ctx, token)
+	body, _ := args.Get(0).(io.ReadCloser)
+	export, _ := args.Get(1).(*models.DataExport)
+	return body, export, args.Error(2)
+}
+
+func (m *mockDataExportService) Process(ctx context.Context) (bool, error) {
+	args := m.Called(ctx)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *mockDataExportService) Run(ctx context.Context, interval time.Duration) {
+	m.Called(ctx, interval)
+}
+
+func TestDataExportHandlers(t *testing.T) {
+	bob := &models.User{ID: 7}
+	alice := &models.User{ID: 8}
+	svc := new(mockDataExportService)
+	svc.On("Request", mock.Anything, mock.MatchedBy(func(u *models.User) bool { return u.ID == 7 })).
+		Return(&models.DataExport{ID: 5, UserID: 7, Status: models.DataExportPending}, nil)
+	svc.On("Request", mock.Anything, mock.MatchedBy(func(u *models.User) bool { return u.ID == 8 })).
+		Return(nil, repository.ErrExportInProgress)
+	svc.On("Latest", mock.Anything, int64(7)).Return(&models.DataExport{ID: 5, UserID: 7, Status: models.DataExportReady}, nil)
+	svc.On("Latest", mock.Anything, int64(8)).Return(nil, repository.ErrExportNotFound)
+
+	tests := []struct {
+		name           string
+		user           *models.User
+		method         string
+		handler        http.HandlerFunc
+		expectedStatus int
+	}{
+		{name: "request", user: bob, method: http.MethodPost, handler: RequestDataExportHandler(svc), expectedStatus: http.StatusAccepted},
+		{name: "one at a time", user: alice, method: http.MethodPost, handler: RequestDataExportHandler(svc), expectedStatus: http.StatusConflict},
+		{name: "latest", user: bob, method: http.MethodGet, handler: LatestDataExportHandler(svc), expectedStatus: http.StatusOK},
+		{name: "none yet", user: alice, method: http.MethodGet, handler: LatestDataExportHandler(svc), expectedStatus: http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rr := serveAsUser(t, tt.user, tt.handler, httptest.NewRequest(tt.method, "/me/export", nil))
+			require.Equal(t, tt.expectedStatus, rr.Code, rr.Body.String())
+		})
+	}
+}
+
+func TestDownloadDataExportHandler(t *testing.T) {
+	svc := new(mockDataExportService)
+	svc.On("Open", mock.Anything, "good").Return(io.NopCloser(strings.NewReader("PK")), &models.DataExport{ID: 5, Size: 2}, nil)
+	svc.On("Open", mock.Anything, mock.Anything).Return(nil, nil, service.ErrInvalidExportLink)
+
+	rr := httptest.NewRecorder()
+	DownloadDataExportHandler(svc).ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/exports/download?token=good", nil))
+	require.Equal(t, http.StatusOK, rr.Code)
+	require.Equal(t, "application/zip", rr.Header().Get("Content-Type"))
+	require.Equal(t, `attachment; filename="data-export-5.zip"`, rr.Header().Get("Content-Disposition"))
+	require.Equal(t, "PK", rr.Body.String())
+
+	rr = httptest.NewRecorder()
+	DownloadDataExportHandler(svc).ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/exports/download?token=bad", nil))
+	require.Equal(t, http.StatusNotFound, rr.Code)
+}
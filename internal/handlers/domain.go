@@ -0,0 +1,196 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/Atmosfr/user-service/internal/models"
+	"github.com/Atmosfr/user-service/internal/repository"
+	"github.com/Atmosfr/user-service/internal/service"
+	"github.com/Atmosfr/user-service/internal/validation"
+)
+
+type ClaimDomainRequest struct {
+	Domain string `json:"domain"`
+}
+
+type UpdateDomainRequest struct {
+	AutoJoin    *bool `json:"auto_join"`
+	SSOEnforced *bool `json:"sso_enforced"`
+}
+
+type ListDomainsResponse struct {
+	Domains []*models.OrganizationDomain `json:"domains"`
+}
+
+func domainErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, validation.ErrInvalidDomain):
+		return http.StatusBadRequest
+	case errors.Is(err, repository.ErrDomainNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, repository.ErrDomainAlreadyClaimed),
+		errors.Is(err, repository.ErrDomainVerifiedElsewhere):
+		return http.StatusConflict
+	case errors.Is(err, service.ErrDomainVerificationFailed):
+		return http.StatusUnprocessableEntity
+	}
+	return organizationErrorStatus(err)
+}
+
+func writeDomainError(w http.ResponseWriter, err error) {
+	w.WriteHeader(domainErrorStatus(err))
+	json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+}
+
+// domainPath returns the organization and domain IDs of the request path.
+func domainPath(w http.ResponseWriter, r *http.Request) (orgID, domainID int64, ok bool) {
+	if orgID, ok = pathID(r, "id"); !ok {
+		writeDomainError(w, repository.ErrOrganizationNotFound)
+		return 0, 0, false
+	}
+	if domainID, ok = pathID(r, "domainID"); !ok {
+		writeDomainError(w, repository.ErrDomainNotFound)
+		return 0, 0, false
+	}
+	return orgID, domainID, true
+}
+
+func ClaimDomainHandler(svc service.DomainService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		defer r.Body.Close()
+		w.Header().Set("Content-Type", "application/json")
+
+		user, ok := requireUser(w, r)
+		if !ok {
+			return
+		}
+		orgID, ok := pathID(r, "id")
+		if !ok {
+			writeDomainError(w, repository.ErrOrganizationNotFound)
+			return
+		}
+
+		var req ClaimDomainRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"error": "Invalid request payload"})
+			return
+		}
+
+		domain, err := svc.Claim(r.Context(), user.ID, orgID, req.Domain)
+		if err != nil {
+			writeDomainError(w, err)
+			return
+		}
+
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(domain)
+	}
+}
+
+func ListDomainsHandler(svc service.DomainService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+
+		user, ok := requireUser(w, r)
+		if !ok {
+			return
+		}
+		orgID, ok := pathID(r, "id")
+		if !ok {
+			writeDomainError(w, repository.ErrOrganizationNotFound)
+			return
+		}
+
+		domains, err := svc.List(r.Context(), user.ID, orgID)
+		if err != nil {
+			writeDomainError(w, err)
+			return
+		}
+
+		json.NewEncoder(w).Encode(ListDomainsResponse{Domains: domains})
+	}
+}
+
+// VerifyDomainHandler checks the domain's DNS TXT record and marks it verified
+// when the record is published.
+func VerifyDomainHandler(svc service.DomainService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+
+		user, ok := requireUser(w, r)
+		if !ok {
+			return
+		}
+		orgID, domainID, ok := domainPath(w, r)
+		if !ok {
+			return
+		}
+
+		domain, err := svc.Verify(r.Context(), user.ID, orgID, domainID)
+		if err != nil {
+			writeDomainError(w, err)
+			return
+		}
+
+		json.NewEncoder(w).Encode(domain)
+	}
+}
+
+func UpdateDomainHandler(svc service.DomainService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		defer r.Body.Close()
+		w.Header().Set("Content-Type", "application/json")
+
+		user, ok := requireUser(w, r)
+		if !ok {
+			return
+		}
+		orgID, domainID, ok := domainPath(w, r)
+		if !ok {
+			return
+		}
+
+		var req UpdateDomainRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"error": "Invalid request payload"})
+			return
+		}
+
+		domain, err := svc.Update(r.Context(), user.ID, orgID, domainID, service.DomainUpdate{
+			AutoJoin:    req.AutoJoin,
+			SSOEnforced: req.SSOEnforced,
+		})
+		if err != nil {
+			writeDomainError(w, err)
+			return
+		}
+
+		json.NewEncoder(w).Encode(domain)
+	}
+}
+
+func DeleteDomainHandler(svc service.DomainService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+
+		user, ok := requireUser(w, r)
+		if !ok {
+			return
+		}
+		orgID, domainID, ok := domainPath(w, r)
+		if !ok {
+			return
+		}
+
+		if err := svc.Delete(r.Context(), user.ID, orgID, domainID); err != nil {
+			writeDomainError(w, err)
+			return
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
@@ -0,0 +1,148 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/Atmosfr/user-service/internal/models"
+	"github.com/Atmosfr/user-service/internal/repository"
+	"github.com/Atmosfr/user-service/internal/service"
+	"github.com/Atmosfr/user-service/internal/validation"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+type mockDomainService struct {
+	mock.Mock
+}
+
+func (m *mockDomainService) SSORequired(ctx context.Context, email string) (bool, error) {
+	args := m.Called(ctx, email)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *mockDomainService) Claim(ctx context.Context, actorID, orgID int64, domain string) (*models.OrganizationDomain, error) {
+	args := m.Called(ctx, actorID, orgID, domain)
+	d, _ := args.Get(0).(*models.OrganizationDomain)
+	return d, args.Error(1)
+}
+
+func (m *mockDomainService) List(ctx context.Context, actorID, orgID int64) ([]*models.OrganizationDomain, error) {
+	args := m.Called(ctx, actorID, orgID)
+	domains, _ := args.Get(0).([]*models.OrganizationDomain)
+	return domains, args.Error(1)
+}
+
+func (m *mockDomainService) Verify(ctx context.Context, actorID, orgID, id int64) (*models.OrganizationDomain, error) {
+	args := m.Called(ctx, actorID, orgID, id)
+	d, _ := args.Get(0).(*models.OrganizationDomain)
+	return d, args.Error(1)
+}
+
+func (m *mockDomainService) Update(ctx context.Context, actorID, orgID, id int64, update service.DomainUpdate) (*models.OrganizationDomain, error) {
+	args := m.Called(ctx, actorID, orgID, id, update)
+	d, _ := args.Get(0).(*models.OrganizationDomain)
+	return d, args.Error(1)
+}
+
+func (m *mockDomainService) Delete(ctx context.Context, actorID, orgID, id int64) error {
+	return m.Called(ctx, actorID, orgID, id).Error(0)
+}
+
+func (m *mockDomainService) JoinByDomain(ctx context.Context, user *models.User) error {
+	return m.Called(ctx, user).Error(0)
+}
+
+func TestDomainHandlers(t *testing.T) {
+	user := &models.User{ID: 7, Role: "user"}
+	enabled := true
+
+	tests := []struct {
+		name           string
+		method         string
+		pattern        string
+		path           string
+		body           string
+		handler        func(svc service.DomainService) http.HandlerFunc
+		setupMock      func(svc *mockDomainService)
+		expectedStatus int
+	}{
+		{
+			name: "claim", method: http.MethodPost, pattern: "POST /orgs/{id}/domains", path: "/orgs/1/domains",
+			body:    `{"domain":"acme.com"}`,
+			handler: ClaimDomainHandler,
+			setupMock: func(svc *mockDomainService) {
+				svc.On("Claim", mock.Anything, int64(7), int64(1), "acme.com").Return(&models.OrganizationDomain{ID: 4}, nil)
+			},
+			expectedStatus: http.StatusCreated,
+		},
+		{
+			name: "claim invalid domain", method: http.MethodPost, pattern: "POST /orgs/{id}/domains", path: "/orgs/1/domains",
+			body:    `{"domain":"acme"}`,
+			handler: ClaimDomainHandler,
+			setupMock: func(svc *mockDomainService) {
+				svc.On("Claim", mock.Anything, int64(7), int64(1), "acme").Return(nil, validation.ErrInvalidDomain)
+			},
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name: "list as member", method: http.MethodGet, pattern: "GET /orgs/{id}/domains", path: "/orgs/1/domains",
+			handler: ListDomainsHandler,
+			setupMock: func(svc *mockDomainService) {
+				svc.On("List", mock.Anything, int64(7), int64(1)).Return(nil, service.ErrOrganizationAdminOnly)
+			},
+			expectedStatus: http.StatusForbidden,
+		},
+		{
+			name: "verify without record", method: http.MethodPost, pattern: "POST /orgs/{id}/domains/{domainID}/verify", path: "/orgs/1/domains/4/verify",
+			handler: VerifyDomainHandler,
+			setupMock: func(svc *mockDomainService) {
+				svc.On("Verify", mock.Anything, int64(7), int64(1), int64(4)).Return(nil, service.ErrDomainVerificationFailed)
+			},
+			expectedStatus: http.StatusUnprocessableEntity,
+		},
+		{
+			name: "verify taken domain", method: http.MethodPost, pattern: "POST /orgs/{id}/domains/{domainID}/verify", path: "/orgs/1/domains/4/verify",
+			handler: VerifyDomainHandler,
+			setupMock: func(svc *mockDomainService) {
+				svc.On("Verify", mock.Anything, int64(7), int64(1), int64(4)).Return(nil, repository.ErrDomainVerifiedElsewhere)
+			},
+			expectedStatus: http.StatusConflict,
+		},
+		{
+			name: "enforce sso", method: http.MethodPatch, pattern: "PATCH /orgs/{id}/domains/{domainID}", path: "/orgs/1/domains/4",
+			body:    `{"sso_enforced":true}`,
+			handler: UpdateDomainHandler,
+			setupMock: func(svc *mockDomainService) {
+				svc.On("Update", mock.Anything, int64(7), int64(1), int64(4), service.DomainUpdate{SSOEnforced: &enabled}).
+					Return(&models.OrganizationDomain{ID: 4, SSOEnforced: true}, nil)
+			},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name: "delete unknown", method: http.MethodDelete, pattern: "DELETE /orgs/{id}/domains/{domainID}", path: "/orgs/1/domains/9",
+			handler: DeleteDomainHandler,
+			setupMock: func(svc *mockDomainService) {
+				svc.On("Delete", mock.Anything, int64(7), int64(1), int64(9)).Return(repository.ErrDomainNotFound)
+			},
+			expectedStatus: http.StatusNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc := new(mockDomainService)
+			tt.setupMock(svc)
+
+			mux := http.NewServeMux()
+			mux.Handle(tt.pattern, tt.handler(svc))
+			rr := serveAsUser(t, user, mux, httptest.NewRequest(tt.method, tt.path, bytes.NewBufferString(tt.body)))
+
+			require.Equal(t, tt.expectedStatus, rr.Code, rr.Body.String())
+			svc.AssertExpectations(t)
+		})
+	}
+}
@@ -0,0 +1,122 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/Atmosfr/user-service/internal/repository"
+	"github.com/Atmosfr/user-service/internal/service"
+	"github.com/Atmosfr/user-service/internal/validation"
+)
+
+type RequestEmailChangeRequest struct {
+	NewEmail string `json:"new_email"`
+	Password string `json:"password"`
+}
+
+type EmailChangeTokenRequest struct {
+	Token string `json:"token"`
+}
+
+func emailChangeErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, validation.ErrInvalidEmail),
+		errors.Is(err, service.ErrEmailUnchanged):
+		return http.StatusBadRequest
+	case errors.Is(err, repository.ErrInvalidPassword),
+		errors.Is(err, repository.ErrInvalidCredentials),
+		errors.Is(err, service.ErrSSORequired):
+		return http.StatusForbidden
+	case errors.Is(err, service.ErrInvalidEmailChangeLink):
+		return http.StatusNotFound
+	case errors.Is(err, repository.ErrEmailAlreadyExists):
+		return http.StatusConflict
+	case errors.Is(err, service.ErrEmailChangeNotSent):
+		return http.StatusBadGateway
+	}
+	return http.StatusInternalServerError
+}
+
+func writeEmailChangeError(w http.ResponseWriter, err error) {
+	w.WriteHeader(emailChangeErrorStatus(err))
+	json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+}
+
+// RequestEmailChangeHandler starts moving the signed-in user to a new address.
+// The user confirms with their current password.
+func RequestEmailChangeHandler(svc service.EmailChangeService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		defer r.Body.Close()
+		w.Header().Set("Content-Type", "application/json")
+
+		user, ok := requireUser(w, r)
+		if !ok {
+			return
+		}
+
+		var req RequestEmailChangeRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"error": "Invalid request payload"})
+			return
+		}
+
+		change, err := svc.Request(r.Context(), user, req.NewEmail, req.Password)
+		if err != nil {
+			writeEmailChangeError(w, err)
+			return
+		}
+
+		w.WriteHeader(http.StatusAccepted)
+		json.NewEncoder(w).Encode(change)
+	}
+}
+
+// ConfirmEmailChangeHandler completes a change from the link sent to the new
+// address. It needs no authentication, the token is the credential.
+func ConfirmEmailChangeHandler(svc service.EmailChangeService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		defer r.Body.Close()
+		w.Header().Set("Content-Type", "application/json")
+
+		var req EmailChangeTokenRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"error": "Invalid request payload"})
+			return
+		}
+
+		user, err := svc.Confirm(r.Context(), req.Token)
+		if err != nil {
+			writeEmailChangeError(w, err)
+			return
+		}
+
+		json.NewEncoder(w).Encode(user)
+	}
+}
+
+// RevertEmailChangeHandler cancels or reverts a change from the link sent to
+// the old address. It needs no authentication, the token is the credential.
+func RevertEmailChangeHandler(svc service.EmailChangeService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		defer r.Body.Close()
+		w.Header().Set("Content-Type", "application/json")
+
+		var req EmailChangeTokenRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"error": "Invalid request payload"})
+			return
+		}
+
+		change, err := svc.Revert(r.Context(), req.Token)
+		if err != nil {
+			writeEmailChangeError(w, err)
+			return
+		}
+
+		json.NewEncoder(w).Encode(change)
+	}
+}
@@ -0,0 +1,81 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/Atmosfr/user-service/internal/models"
+	"github.com/Atmosfr/user-service/internal/repository"
+	"github.com/Atmosfr/user-service/internal/service"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+type mockEmailChangeService struct {
+	mock.Mock
+}
+
+func (m *mockEmailChangeService) Request(ctx context.Context, user *models.User, newEmail, password string) (*models.EmailChange, error) {
+	args := m.Called(ctx, user, newEmail, password)
+	change, _ := args.Get(0).(*models.EmailChange)
+	return change, args.Error(1)
+}
+
+func (m *mockEmailChangeService) Confirm(ctx context.Context, token string) (*models.User, error) {
+	args := m.Called(ctx, token)
+	user, _ := args.Get(0).(*models.User)
+	return user, args.Error(1)
+}
+
+func (m *mockEmailChangeService) Revert(ctx context.Context, token string) (*models.EmailChange, error) {
+	args := m.Called(ctx, token)
+	change, _ := args.Get(0).(*models.EmailChange)
+	return change, args.Error(1)
+}
+
+func TestEmailChangeHandlers(t *testing.T) {
+	user := &models.User{ID: 7, Role: "user", Email: "bob@example.com"}
+	isBob := mock.MatchedBy(func(u *models.User) bool { return u.ID == 7 })
+	svc := new(mockEmailChangeService)
+	svc.On("Request", mock.Anything, isBob, "bob@new.example", "StrongPass!12").
+		Return(&models.EmailChange{ID: 5, UserID: 7, Status: models.EmailChangePending}, nil)
+	svc.On("Request", mock.Anything, isBob, "bob@new.example", "wrong").Return(nil, repository.ErrInvalidPassword)
+	svc.On("Request", mock.Anything, isBob, "alice@example.com", "StrongPass!12").Return(nil, repository.ErrEmailAlreadyExists)
+	svc.On("Confirm", mock.Anything, "5.123.sig").Return(&models.User{ID: 7, Email: "bob@new.example"}, nil)
+	svc.On("Confirm", mock.Anything, "expired").Return(nil, service.ErrInvalidEmailChangeLink)
+	svc.On("Confirm", mock.Anything, "taken").Return(nil, repository.ErrEmailAlreadyExists)
+	svc.On("Revert", mock.Anything, "5.456.sig").Return(&models.EmailChange{ID: 5, Status: models.EmailChangeReverted}, nil)
+
+	tests := []struct {
+		name           string
+		body           string
+		handler        http.HandlerFunc
+		asUser         bool
+		expectedStatus int
+	}{
+		{name: "request", body: `{"new_email":"bob@new.example","password":"StrongPass!12"}`, handler: RequestEmailChangeHandler(svc), asUser: true, expectedStatus: http.StatusAccepted},
+		{name: "wrong password", body: `{"new_email":"bob@new.example","password":"wrong"}`, handler: RequestEmailChangeHandler(svc), asUser: true, expectedStatus: http.StatusForbidden},
+		{name: "address taken", body: `{"new_email":"alice@example.com","password":"StrongPass!12"}`, handler: RequestEmailChangeHandler(svc), asUser: true, expectedStatus: http.StatusConflict},
+		{name: "request payload", body: `{`, handler: RequestEmailChangeHandler(svc), asUser: true, expectedStatus: http.StatusBadRequest},
+		{name: "confirm", body: `{"token":"5.123.sig"}`, handler: ConfirmEmailChangeHandler(svc), expectedStatus: http.StatusOK},
+		{name: "confirm expired", body: `{"token":"expired"}`, handler: ConfirmEmailChangeHandler(svc), expectedStatus: http.StatusNotFound},
+		{name: "confirm taken meanwhile", body: `{"token":"taken"}`, handler: ConfirmEmailChangeHandler(svc), expectedStatus: http.StatusConflict},
+		{name: "revert", body: `{"token":"5.456.sig"}`, handler: RevertEmailChangeHandler(svc), expectedStatus: http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/", bytes.NewBufferString(tt.body))
+			var rr *httptest.ResponseRecorder
+			if tt.asUser {
+				rr = serveAsUser(t, user, tt.handler, req)
+			} else {
+				rr = httptest.NewRecorder()
+				tt.handler.ServeHTTP(rr, req)
+			}
+			require.Equal(t, tt.expectedStatus, rr.Code, rr.Body.String())
+		})
+	}
+}
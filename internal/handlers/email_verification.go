@@ -0,0 +1,74 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/Atmosfr/user-service/internal/service"
+)
+
+type VerifyEmailRequest struct {
+	Token string `json:"token"`
+}
+
+func emailVerificationErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, service.ErrInvalidVerificationLink):
+		return http.StatusNotFound
+	case errors.Is(err, service.ErrEmailAlreadyVerified):
+		return http.StatusConflict
+	case errors.Is(err, service.ErrVerificationNotSent):
+		return http.StatusBadGateway
+	}
+	return http.StatusInternalServerError
+}
+
+func writeEmailVerificationError(w http.ResponseWriter, err error) {
+	w.WriteHeader(emailVerificationErrorStatus(err))
+	json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+}
+
+// SendEmailVerificationHandler emails the signed-in user a new verification link.
+func SendEmailVerificationHandler(svc service.EmailVerificationService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+
+		user, ok := requireUser(w, r)
+		if !ok {
+			return
+		}
+
+		if err := svc.Send(r.Context(), user); err != nil {
+			writeEmailVerificationError(w, err)
+			return
+		}
+
+		w.WriteHeader(http.StatusAccepted)
+		json.NewEncoder(w).Encode(map[string]string{"status": "sent"})
+	}
+}
+
+// VerifyEmailHandler confirms an address from an emailed link. It needs no
+// authentication, the token is the credential.
+func VerifyEmailHandler(svc service.EmailVerificationService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		defer r.Body.Close()
+		w.Header().Set("Content-Type", "application/json")
+
+		var req VerifyEmailRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"error": "Invalid request payload"})
+			return
+		}
+
+		user, err := svc.Confirm(r.Context(), req.Token)
+		if err != nil {
+			writeEmailVerificationError(w, err)
+			return
+		}
+
+		json.NewEncoder(w).Encode(user)
+	}
+}
@@ -0,0 +1,47 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/Atmosfr/user-service/internal/models"
+	"github.com/Atmosfr/user-service/internal/service"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+type mockEmailVerificationService struct {
+	mock.Mock
+}
+
+func (m *mockEmailVerificationService) Send(ctx context.Context, user *models.User) error {
+	return m.Called(ctx, user).Error(0)
+}
+
+func (m *mockEmailVerificationService) Confirm(ctx context.Context, token string) (*models.User, error) {
+	args := m.Called(ctx, token)
+	user, _ := args.Get(0).(*models.User)
+	return user, args.Error(1)
+}
+
+func TestEmailVerificationHandlers(t *testing.T) {
+	svc := new(mockEmailVerificationService)
+	svc.On("Send", mock.Anything, mock.MatchedBy(func(u *models.User) bool { return u.ID == 7 })).Return(nil)
+	svc.On("Confirm", mock.Anything, "10.123.sig").Return(&models.User{ID: 10}, nil)
+	svc.On("Confirm", mock.Anything, "expired").Return(nil, service.ErrInvalidVerificationLink)
+
+	rr := serveAsUser(t, &models.User{ID: 7, Role: "user"}, SendEmailVerificationHandler(svc),
+		httptest.NewRequest(http.MethodPost, "/me/email/verification", nil))
+	require.Equal(t, http.StatusAccepted, rr.Code, rr.Body.String())
+
+	rr = httptest.NewRecorder()
+	VerifyEmailHandler(svc).ServeHTTP(rr, httptest.NewRequest(http.MethodPost, "/email/verify", bytes.NewBufferString(`{"token":"10.123.sig"}`)))
+	require.Equal(t, http.StatusOK, rr.Code)
+
+	rr = httptest.NewRecorder()
+	VerifyEmailHandler(svc).ServeHTTP(rr, httptest.NewRequest(http.MethodPost, "/email/verify", bytes.NewBufferString(`{"token":"expired"}`)))
+	require.Equal(t, http.StatusNotFound, rr.Code)
+}
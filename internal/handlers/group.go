@@ -0,0 +1,303 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/Atmosfr/user-service/internal/models"
+	"github.com/Atmosfr/user-service/internal/repository"
+	"github.com/Atmosfr/user-service/internal/service"
+	"github.com/Atmosfr/user-service/internal/validation"
+)
+
+type CreateGroupRequest struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	ParentID    *int64 `json:"parent_id"`
+}
+
+type UpdateGroupRequest struct {
+	Name        *string `json:"name"`
+	Description *string `json:"description"`
+	// ParentID moves the group when present, null moves it to the top level.
+	ParentID json.RawMessage `json:"parent_id"`
+}
+
+type ListGroupsResponse struct {
+	Groups []*models.Group `json:"groups"`
+}
+
+type ListGroupMembersResponse struct {
+	Members []*models.GroupMember `json:"members"`
+}
+
+func groupErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, validation.ErrInvalidGroupName),
+		errors.Is(err, validation.ErrInvalidGroupDescription):
+		return http.StatusBadRequest
+	case errors.Is(err, service.ErrGroupAdminOnly):
+		return http.StatusForbidden
+	case errors.Is(err, repository.ErrGroupAlreadyExists),
+		errors.Is(err, repository.ErrGroupCycle),
+		errors.Is(err, repository.ErrGroupHasSubgroups):
+		return http.StatusConflict
+	case errors.Is(err, repository.ErrParentGroupNotFound):
+		return http.StatusUnprocessableEntity
+	case errors.Is(err, repository.ErrGroupNotFound):
+		return http.StatusNotFound
+	}
+	return organizationErrorStatus(err)
+}
+
+func writeGroupError(w http.ResponseWriter, err error) {
+	w.WriteHeader(groupErrorStatus(err))
+	json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+}
+
+// groupPath returns the organization and group IDs of the request path.
+func groupPath(w http.ResponseWriter, r *http.Request) (orgID, groupID int64, ok bool) {
+	if orgID, ok = pathID(r, "id"); !ok {
+		writeGroupError(w, repository.ErrOrganizationNotFound)
+		return 0, 0, false
+	}
+	if groupID, ok = pathID(r, "groupID"); !ok {
+		writeGroupError(w, repository.ErrGroupNotFound)
+		return 0, 0, false
+	}
+	return orgID, groupID, true
+}
+
+func CreateGroupHandler(svc service.GroupService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		defer r.Body.Close()
+		w.Header().Set("Content-Type", "application/json")
+
+		user, ok := requireUser(w, r)
+		if !ok {
+			return
+		}
+		orgID, ok := pathID(r, "id")
+		if !ok {
+			writeGroupError(w, repository.ErrOrganizationNotFound)
+			return
+		}
+
+		var req CreateGroupRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"error": "Invalid request payload"})
+			return
+		}
+
+		group, err := svc.Create(r.Context(), user.ID, orgID, req.Name, req.Description, req.ParentID)
+		if err != nil {
+			writeGroupError(w, err)
+			return
+		}
+
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(group)
+	}
+}
+
+func ListGroupsHandler(svc service.GroupService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+
+		user, ok := requireUser(w, r)
+		if !ok {
+			return
+		}
+		orgID, ok := pathID(r, "id")
+		if !ok {
+			writeGroupError(w, repository.ErrOrganizationNotFound)
+			return
+		}
+
+		groups, err := svc.List(r.Context(), user.ID, orgID)
+		if err != nil {
+			writeGroupError(w, err)
+			return
+		}
+
+		json.NewEncoder(w).Encode(ListGroupsResponse{Groups: groups})
+	}
+}
+
+func GetGroupHandler(svc service.GroupService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+
+		user, ok := requireUser(w, r)
+		if !ok {
+			return
+		}
+		orgID, groupID, ok := groupPath(w, r)
+		if !ok {
+			return
+		}
+
+		group, err := svc.Get(r.Context(), user.ID, orgID, groupID)
+		if err != nil {
+			writeGroupError(w, err)
+			return
+		}
+
+		json.NewEncoder(w).Encode(group)
+	}
+}
+
+func UpdateGroupHandler(svc service.GroupService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		defer r.Body.Close()
+		w.Header().Set("Content-Type", "application/json")
+
+		user, ok := requireUser(w, r)
+		if !ok {
+			return
+		}
+		orgID, groupID, ok := groupPath(w, r)
+		if !ok {
+			return
+		}
+
+		var req UpdateGroupRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"error": "Invalid request payload"})
+			return
+		}
+
+		update := service.GroupUpdate{Name: req.Name, Description: req.Description}
+		if len(req.ParentID) > 0 {
+			update.Move = true
+			if err := json.Unmarshal(req.ParentID, &update.ParentID); err != nil {
+				w.WriteHeader(http.StatusBadRequest)
+				json.NewEncoder(w).Encode(map[string]string{"error": "parent_id must be a group id or null"})
+				return
+			}
+		}
+
+		group, err := svc.Update(r.Context(), user.ID, orgID, groupID, update)
+		if err != nil {
+			writeGroupError(w, err)
+			return
+		}
+
+		json.NewEncoder(w).Encode(group)
+	}
+}
+
+func DeleteGroupHandler(svc service.GroupService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+
+		user, ok := requireUser(w, r)
+		if !ok {
+			return
+		}
+		orgID, groupID, ok := groupPath(w, r)
+		if !ok {
+			return
+		}
+
+		if err := svc.Delete(r.Context(), user.ID, orgID, groupID); err != nil {
+			writeGroupError(w, err)
+			return
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// ListGroupMembersHandler lists direct members, or with ?effective=true also
+// the members inherited from subgroups.
+func ListGroupMembersHandler(svc service.GroupService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+
+		user, ok := requireUser(w, r)
+		if !ok {
+			return
+		}
+		orgID, groupID, ok := groupPath(w, r)
+		if !ok {
+			return
+		}
+
+		effective := false
+		if v := r.URL.Query().Get("effective"); v != "" {
+			var err error
+			if effective, err = strconv.ParseBool(v); err != nil {
+				w.WriteHeader(http.StatusBadRequest)
+				json.NewEncoder(w).Encode(map[string]string{"error": "effective must be true or false"})
+				return
+			}
+		}
+
+		members, err := svc.ListMembers(r.Context(), user.ID, orgID, groupID, effective)
+		if err != nil {
+			writeGroupError(w, err)
+			return
+		}
+
+		json.NewEncoder(w).Encode(ListGroupMembersResponse{Members: members})
+	}
+}
+
+func AddGroupMemberHandler(svc service.GroupService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+
+		user, ok := requireUser(w, r)
+		if !ok {
+			return
+		}
+		orgID, groupID, ok := groupPath(w, r)
+		if !ok {
+			return
+		}
+		memberID, ok := pathID(r, "userID")
+		if !ok {
+			writeGroupError(w, repository.ErrMemberNotFound)
+			return
+		}
+
+		if err := svc.AddMember(r.Context(), user.ID, orgID, groupID, memberID); err != nil {
+			writeGroupError(w, err)
+			return
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+func RemoveGroupMemberHandler(svc service.GroupService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+
+		user, ok := requireUser(w, r)
+		if !ok {
+			return
+		}
+		orgID, groupID, ok := groupPath(w, r)
+		if !ok {
+			return
+		}
+		memberID, ok := pathID(r, "userID")
+		if !ok {
+			writeGroupError(w, repository.ErrMemberNotFound)
+			return
+		}
+
+		if err := svc.RemoveMember(r.Context(), user.ID, orgID, groupID, memberID); err != nil {
+			writeGroupError(w, err)
+			return
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
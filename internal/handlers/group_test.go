@@ -0,0 +1,192 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/Atmosfr/user-service/internal/models"
+	"github.com/Atmosfr/user-service/internal/repository"
+	"github.com/Atmosfr/user-service/internal/service"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+type mockGroupService struct {
+	mock.Mock
+}
+
+func (m *mockGroupService) Create(ctx context.Context, actorID, orgID int64, name, description string, parentID *int64) (*models.Group, error) {
+	args := m.Called(ctx, actorID, orgID, name, description, parentID)
+	group, _ := args.Get(0).(*models.Group)
+	return group, args.Error(1)
+}
+
+func (m *mockGroupService) List(ctx context.Context, userID, orgID int64) ([]*models.Group, error) {
+	args := m.Called(ctx, userID, orgID)
+	groups, _ := args.Get(0).([]*models.Group)
+	return groups, args.Error(1)
+}
+
+func (m *mockGroupService) Get(ctx context.Context, userID, orgID, id int64) (*models.Group, error) {
+	args := m.Called(ctx, userID, orgID, id)
+	group, _ := args.Get(0).(*models.Group)
+	return group, args.Error(1)
+}
+
+func (m *mockGroupService) Update(ctx context.Context, actorID, orgID, id int64, update service.GroupUpdate) (*models.Group, error) {
+	args := m.Called(ctx, actorID, orgID, id, update)
+	group, _ := args.Get(0).(*models.Group)
+	return group, args.Error(1)
+}
+
+func (m *mockGroupService) Delete(ctx context.Context, actorID, orgID, id int64) error {
+	return m.Called(ctx, actorID, orgID, id).Error(0)
+}
+
+func (m *mockGroupService) ListMembers(ctx context.Context, userID, orgID, groupID int64, effective bool) ([]*models.GroupMember, error) {
+	args := m.Called(ctx, userID, orgID, groupID, effective)
+	members, _ := args.Get(0).([]*models.GroupMember)
+	return members, args.Error(1)
+}
+
+func (m *mockGroupService) AddMember(ctx context.Context, actorID, orgID, groupID, userID int64) error {
+	return m.Called(ctx, actorID, orgID, groupID, userID).Error(0)
+}
+
+func (m *mockGroupService) RemoveMember(ctx context.Context, actorID, orgID, groupID, userID int64) error {
+	return m.Called(ctx, actorID, orgID, groupID, userID).Error(0)
+}
+
+func (m *mockGroupService) EffectiveGroups(ctx context.Context, orgID, userID int64) ([]*models.Group, error) {
+	args := m.Called(ctx, orgID, userID)
+	groups, _ := args.Get(0).([]*models.Group)
+	return groups, args.Error(1)
+}
+
+func TestGroupHandlers(t *testing.T) {
+	user := &models.User{ID: 7, Role: "user"}
+	parentID := int64(2)
+
+	tests := []struct {
+		name           string
+		method         string
+		pattern        string
+		path           string
+		body           string
+		handler        func(svc service.GroupService) http.HandlerFunc
+		setupMock      func(svc *mockGroupService)
+		expectedStatus int
+	}{
+		{
+			name: "create subgroup", method: http.MethodPost, pattern: "POST /orgs/{id}/groups", path: "/orgs/1/groups",
+			body:    `{"name":"SRE","parent_id":2}`,
+			handler: CreateGroupHandler,
+			setupMock: func(svc *mockGroupService) {
+				svc.On("Create", mock.Anything, int64(7), int64(1), "SRE", "", &parentID).Return(&models.Group{ID: 3, Name: "SRE", ParentID: &parentID}, nil)
+			},
+			expectedStatus: http.StatusCreated,
+		},
+		{
+			name: "create with unknown parent", method: http.MethodPost, pattern: "POST /orgs/{id}/groups", path: "/orgs/1/groups",
+			body:    `{"name":"SRE","parent_id":2}`,
+			handler: CreateGroupHandler,
+			setupMock: func(svc *mockGroupService) {
+				svc.On("Create", mock.Anything, int64(7), int64(1), "SRE", "", &parentID).Return(nil, repository.ErrParentGroupNotFound)
+			},
+			expectedStatus: http.StatusUnprocessableEntity,
+		},
+		{
+			name: "list as non-member", method: http.MethodGet, pattern: "GET /orgs/{id}/groups", path: "/orgs/1/groups",
+			handler: ListGroupsHandler,
+			setupMock: func(svc *mockGroupService) {
+				svc.On("List", mock.Anything, int64(7), int64(1)).Return(nil, repository.ErrOrganizationNotFound)
+			},
+			expectedStatus: http.StatusNotFound,
+		},
+		{
+			name: "rename without moving", method: http.MethodPatch, pattern: "PATCH /orgs/{id}/groups/{groupID}", path: "/orgs/1/groups/3",
+			body:    `{"name":"Site Reliability"}`,
+			handler: UpdateGroupHandler,
+			setupMock: func(svc *mockGroupService) {
+				svc.On("Update", mock.Anything, int64(7), int64(1), int64(3), mock.MatchedBy(func(u service.GroupUpdate) bool {
+					return !u.Move && *u.Name == "Site Reliability"
+				})).Return(&models.Group{ID: 3}, nil)
+			},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name: "move to top level", method: http.MethodPatch, pattern: "PATCH /orgs/{id}/groups/{groupID}", path: "/orgs/1/groups/3",
+			body:    `{"parent_id":null}`,
+			handler: UpdateGroupHandler,
+			setupMock: func(svc *mockGroupService) {
+				svc.On("Update", mock.Anything, int64(7), int64(1), int64(3), service.GroupUpdate{Move: true}).Return(&models.Group{ID: 3}, nil)
+			},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name: "move below own subgroup", method: http.MethodPatch, pattern: "PATCH /orgs/{id}/groups/{groupID}", path: "/orgs/1/groups/2",
+			body:    `{"parent_id":3}`,
+			handler: UpdateGroupHandler,
+			setupMock: func(svc *mockGroupService) {
+				svc.On("Update", mock.Anything, int64(7), int64(1), int64(2), mock.Anything).Return(nil, repository.ErrGroupCycle)
+			},
+			expectedStatus: http.StatusConflict,
+		},
+		{
+			name: "invalid parent id", method: http.MethodPatch, pattern: "PATCH /orgs/{id}/groups/{groupID}", path: "/orgs/1/groups/2",
+			body:           `{"parent_id":"platform"}`,
+			handler:        UpdateGroupHandler,
+			setupMock:      func(svc *mockGroupService) {},
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name: "delete group with subgroups", method: http.MethodDelete, pattern: "DELETE /orgs/{id}/groups/{groupID}", path: "/orgs/1/groups/2",
+			handler: DeleteGroupHandler,
+			setupMock: func(svc *mockGroupService) {
+				svc.On("Delete", mock.Anything, int64(7), int64(1), int64(2)).Return(repository.ErrGroupHasSubgroups)
+			},
+			expectedStatus: http.StatusConflict,
+		},
+		{
+			name: "effective members", method: http.MethodGet, pattern: "GET /orgs/{id}/groups/{groupID}/members", path: "/orgs/1/groups/2/members?effective=true",
+			handler: ListGroupMembersHandler,
+			setupMock: func(svc *mockGroupService) {
+				svc.On("ListMembers", mock.Anything, int64(7), int64(1), int64(2), true).Return([]*models.GroupMember{{GroupID: 3, UserID: 10}}, nil)
+			},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name: "add member as non-admin", method: http.MethodPut, pattern: "PUT /orgs/{id}/groups/{groupID}/members/{userID}", path: "/orgs/1/groups/2/members/10",
+			handler: AddGroupMemberHandler,
+			setupMock: func(svc *mockGroupService) {
+				svc.On("AddMember", mock.Anything, int64(7), int64(1), int64(2), int64(10)).Return(service.ErrGroupAdminOnly)
+			},
+			expectedStatus: http.StatusForbidden,
+		},
+		{
+			name: "remove member", method: http.MethodDelete, pattern: "DELETE /orgs/{id}/groups/{groupID}/members/{userID}", path: "/orgs/1/groups/2/members/10",
+			handler: RemoveGroupMemberHandler,
+			setupMock: func(svc *mockGroupService) {
+				svc.On("RemoveMember", mock.Anything, int64(7), int64(1), int64(2), int64(10)).Return(nil)
+			},
+			expectedStatus: http.StatusNoContent,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc := new(mockGroupService)
+			tt.setupMock(svc)
+
+			mux := http.NewServeMux()
+			mux.Handle(tt.pattern, tt.handler(svc))
+			rr := serveAsUser(t, user, mux, httptest.NewRequest(tt.method, tt.path, bytes.NewBufferString(tt.body)))
+
+			require.Equal(t, tt.expectedStatus, rr.Code, rr.Body.String())
+			svc.AssertExpectations(t)
+		})
+	}
+}
@@ -0,0 +1,257 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/Atmosfr/user-service/internal/models"
+	"github.com/Atmosfr/user-service/internal/repository"
+	"github.com/Atmosfr/user-service/internal/service"
+	"github.com/Atmosfr/user-service/internal/validation"
+)
+
+type CreateInvitationRequest struct {
+	Email string `json:"email"`
+	Role  string `json:"role"`
+}
+
+type ListInvitationsResponse struct {
+	Invitations []*models.Invitation `json:"invitations"`
+}
+
+type AcceptInvitationRequest struct {
+	Token string `json:"token"`
+}
+
+// RegisterInvitationRequest has no email, the account gets the invited address.
+type RegisterInvitationRequest struct {
+	Token    string `json:"token"`
+	Password string `json:"password"`
+	Username string `json:"username"`
+	// Accept maps document kinds to the versions the user accepted.
+	Accept    map[string]string `json:"accept,omitempty"`
+	Marketing bool              `json:"marketing,omitempty"`
+}
+
+func invitationErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, validation.ErrInvalidEmail),
+		errors.Is(err, validation.ErrPasswordTooShort),
+		errors.Is(err, validation.ErrInvalidUsername),
+		errors.Is(err, validation.ErrInvalidCredentials),
+		errors.Is(err, service.ErrConsentRequired):
+		return http.StatusBadRequest
+	case errors.Is(err, service.ErrInvalidInvitation),
+		errors.Is(err, repository.ErrInvitationNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, service.ErrInvitationEmailMismatch),
+		errors.Is(err, service.ErrSSORequired):
+		return http.StatusForbidden
+	case errors.Is(err, service.ErrInvitationNeedsLogin),
+		errors.Is(err, repository.ErrInvitationExists),
+		errors.Is(err, repository.ErrEmailAlreadyExists),
+		errors.Is(err, repository.ErrUsernameAlreadyExists):
+		return http.StatusConflict
+	case errors.Is(err, service.ErrInvitationNotSent):
+		return http.StatusBadGateway
+	}
+	return organizationErrorStatus(err)
+}
+
+func writeInvitationError(w http.ResponseWriter, err error) {
+	w.WriteHeader(invitationErrorStatus(err))
+	json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+}
+
+func CreateInvitationHandler(svc service.InvitationService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		defer r.Body.Close()
+		w.Header().Set("Content-Type", "application/json")
+
+		user, ok := requireUser(w, r)
+		if !ok {
+			return
+		}
+		orgID, ok := pathID(r, "id")
+		if !ok {
+			writeInvitationError(w, repository.ErrOrganizationNotFound)
+			return
+		}
+
+		var req CreateInvitationRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"error": "Invalid request payload"})
+			return
+		}
+		if req.Role == "" {
+			req.Role = models.OrgRoleMember
+		}
+
+		inv, err := svc.Invite(r.Context(), user.ID, orgID, req.Email, req.Role)
+		if err != nil {
+			writeInvitationError(w, err)
+			return
+		}
+
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(inv)
+	}
+}
+
+func ListInvitationsHandler(svc service.InvitationService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+
+		user, ok := requireUser(w, r)
+		if !ok {
+			return
+		}
+		orgID, ok := pathID(r, "id")
+		if !ok {
+			writeInvitationError(w, repository.ErrOrganizationNotFound)
+			return
+		}
+
+		invitations, err := svc.ListPending(r.Context(), user.ID, orgID)
+		if err != nil {
+			writeInvitationError(w, err)
+			return
+		}
+
+		json.NewEncoder(w).Encode(ListInvitationsResponse{Invitations: invitations})
+	}
+}
+
+// invitationPath returns the organization and invitation IDs of the request path.
+func invitationPath(w http.ResponseWriter, r *http.Request) (orgID, invitationID int64, ok bool) {
+	if orgID, ok = pathID(r, "id"); !ok {
+		writeInvitationError(w, repository.ErrOrganizationNotFound)
+		return 0, 0, false
+	}
+	if invitationID, ok = pathID(r, "invitationID"); !ok {
+		writeInvitationError(w, repository.ErrInvitationNotFound)
+		return 0, 0, false
+	}
+	return orgID, invitationID, true
+}
+
+func RevokeInvitationHandler(svc service.InvitationService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+
+		user, ok := requireUser(w, r)
+		if !ok {
+			return
+		}
+		orgID, invitationID, ok := invitationPath(w, r)
+		if !ok {
+			return
+		}
+
+		if err := svc.Revoke(r.Context(), user.ID, orgID, invitationID); err != nil {
+			writeInvitationError(w, err)
+			return
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+func ResendInvitationHandler(svc service.InvitationService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+
+		user, ok := requireUser(w, r)
+		if !ok {
+			return
+		}
+		orgID, invitationID, ok := invitationPath(w, r)
+		if !ok {
+			return
+		}
+
+		inv, err := svc.Resend(r.Context(), user.ID, orgID, invitationID)
+		if err != nil {
+			writeInvitationError(w, err)
+			return
+		}
+
+		json.NewEncoder(w).Encode(inv)
+	}
+}
+
+// PreviewInvitationHandler shows the invitation behind a link so clients can
+// pre-fill registration. It needs no authentication, the token is the credential.
+func PreviewInvitationHandler(svc service.InvitationService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Cache-Control", "no-store")
+
+		preview, err := svc.Preview(r.Context(), r.PathValue("token"))
+		if err != nil {
+			writeInvitationError(w, err)
+			return
+		}
+
+		json.NewEncoder(w).Encode(preview)
+	}
+}
+
+// AcceptInvitationHandler joins the signed-in user to the invitation's organization.
+func AcceptInvitationHandler(svc service.InvitationService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		defer r.Body.Close()
+		w.Header().Set("Content-Type", "application/json")
+
+		user, ok := requireUser(w, r)
+		if !ok {
+			return
+		}
+
+		var req AcceptInvitationRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"error": "Invalid request payload"})
+			return
+		}
+
+		inv, err := svc.Accept(r.Context(), user, req.Token)
+		if err != nil {
+			writeInvitationError(w, err)
+			return
+		}
+
+		json.NewEncoder(w).Encode(inv)
+	}
+}
+
+// RegisterInvitationHandler creates an account for the invited address and
+// accepts the invitation.
+func RegisterInvitationHandler(svc service.InvitationService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		defer r.Body.Close()
+		w.Header().Set("Content-Type", "application/json")
+
+		var req RegisterInvitationRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"error": "Invalid request payload"})
+			return
+		}
+
+		resp, err := svc.Register(r.Context(), req.Token, req.Password, req.Username, service.RegistrationConsent{
+			Accepted:  req.Accept,
+			Marketing: req.Marketing,
+			IP:        clientIP(r),
+		})
+		if err != nil {
+			writeInvitationError(w, err)
+			return
+		}
+
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(resp)
+	}
+}
@@ -0,0 +1,195 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/Atmosfr/user-service/internal/models"
+	"github.com/Atmosfr/user-service/internal/repository"
+	"github.com/Atmosfr/user-service/internal/service"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+type mockInvitationService struct {
+	mock.Mock
+}
+
+func (m *mockInvitationService) Invite(ctx context.Context, actorID, orgID int64, email, role string) (*models.Invitation, error) {
+	args := m.Called(ctx, actorID, orgID, email, role)
+	inv, _ := args.Get(0).(*models.Invitation)
+	return inv, args.Error(1)
+}
+
+func (m *mockInvitationService) ListPending(ctx context.Context, actorID, orgID int64) ([]*models.Invitation, error) {
+	args := m.Called(ctx, actorID, orgID)
+	invitations, _ := args.Get(0).([]*models.Invitation)
+	return invitations, args.Error(1)
+}
+
+func (m *mockInvitationService) Revoke(ctx context.Context, actorID, orgID, id int64) error {
+	return m.Called(ctx, actorID, orgID, id).Error(0)
+}
+
+func (m *mockInvitationService) Resend(ctx context.Context, actorID, orgID, id int64) (*models.Invitation, error) {
+	args := m.Called(ctx, actorID, orgID, id)
+	inv, _ := args.Get(0).(*models.Invitation)
+	return inv, args.Error(1)
+}
+
+func (m *mockInvitationService) Preview(ctx context.Context, token string) (*service.InvitationPreview, error) {
+	args := m.Called(ctx, token)
+	preview, _ := args.Get(0).(*service.InvitationPreview)
+	return preview, args.Error(1)
+}
+
+func (m *mockInvitationService) Accept(ctx context.Context, user *models.User, token string) (*models.Invitation, error) {
+	args := m.Called(ctx, user, token)
+	inv, _ := args.Get(0).(*models.Invitation)
+	return inv, args.Error(1)
+}
+
+func (m *mockInvitationService) Register(ctx context.Context, token, password, username string, consent service.RegistrationConsent) (*service.LoginResponse, error) {
+	args := m.Called(ctx, token, password, username, consent)
+	resp, _ := args.Get(0).(*service.LoginResponse)
+	return resp, args.Error(1)
+}
+
+func TestInvitationHandlers(t *testing.T) {
+	user := &models.User{ID: 7, Role: "user"}
+
+	tests := []struct {
+		name           string
+		method         string
+		pattern        string
+		path           string
+		body           string
+		handler        func(svc service.InvitationService) http.HandlerFunc
+		setupMock      func(svc *mockInvitationService)
+		expectedStatus int
+	}{
+		{
+			name: "invite defaults to member role", method: http.MethodPost, pattern: "POST /orgs/{id}/invitations", path: "/orgs/1/invitations",
+			body:    `{"email":"bob@example.com"}`,
+			handler: CreateInvitationHandler,
+			setupMock: func(svc *mockInvitationService) {
+				svc.On("Invite", mock.Anything, int64(7), int64(1), "bob@example.com", models.OrgRoleMember).Return(&models.Invitation{ID: 5}, nil)
+			},
+			expectedStatus: http.StatusCreated,
+		},
+		{
+			name: "invite already pending", method: http.MethodPost, pattern: "POST /orgs/{id}/invitations", path: "/orgs/1/invitations",
+			body:    `{"email":"bob@example.com","role":"admin"}`,
+			handler: CreateInvitationHandler,
+			setupMock: func(svc *mockInvitationService) {
+				svc.On("Invite", mock.Anything, int64(7), int64(1), "bob@example.com", models.OrgRoleAdmin).Return(nil, repository.ErrInvitationExists)
+			},
+			expectedStatus: http.StatusConflict,
+		},
+		{
+			name: "invite email failed", method: http.MethodPost, pattern: "POST /orgs/{id}/invitations", path: "/orgs/1/invitations",
+			body:    `{"email":"bob@example.com"}`,
+			handler: CreateInvitationHandler,
+			setupMock: func(svc *mockInvitationService) {
+				svc.On("Invite", mock.Anything, int64(7), int64(1), "bob@example.com", models.OrgRoleMember).Return(&models.Invitation{ID: 5}, service.ErrInvitationNotSent)
+			},
+			expectedStatus: http.StatusBadGateway,
+		},
+		{
+			name: "list as member", method: http.MethodGet, pattern: "GET /orgs/{id}/invitations", path: "/orgs/1/invitations",
+			handler: ListInvitationsHandler,
+			setupMock: func(svc *mockInvitationService) {
+				svc.On("ListPending", mock.Anything, int64(7), int64(1)).Return(nil, service.ErrOrganizationAdminOnly)
+			},
+			expectedStatus: http.StatusForbidden,
+		},
+		{
+			name: "revoke", method: http.MethodDelete, pattern: "DELETE /orgs/{id}/invitations/{invitationID}", path: "/orgs/1/invitations/5",
+			handler: RevokeInvitationHandler,
+			setupMock: func(svc *mockInvitationService) {
+				svc.On("Revoke", mock.Anything, int64(7), int64(1), int64(5)).Return(nil)
+			},
+			expectedStatus: http.StatusNoContent,
+		},
+		{
+			name: "resend closed invitation", method: http.MethodPost, pattern: "POST /orgs/{id}/invitations/{invitationID}/resend", path: "/orgs/1/invitations/5/resend",
+			handler: ResendInvitationHandler,
+			setupMock: func(svc *mockInvitationService) {
+				svc.On("Resend", mock.Anything, int64(7), int64(1), int64(5)).Return(nil, repository.ErrInvitationNotFound)
+			},
+			expectedStatus: http.StatusNotFound,
+		},
+		{
+			name: "accept for another address", method: http.MethodPost, pattern: "POST /invitations/accept", path: "/invitations/accept",
+			body:    `{"token":"5.123.sig"}`,
+			handler: AcceptInvitationHandler,
+			setupMock: func(svc *mockInvitationService) {
+				svc.On("Accept", mock.Anything, mock.Anything, "5.123.sig").Return(nil, service.ErrInvitationEmailMismatch)
+			},
+			expectedStatus: http.StatusForbidden,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc := new(mockInvitationService)
+			tt.setupMock(svc)
+
+			mux := http.NewServeMux()
+			mux.Handle(tt.pattern, tt.handler(svc))
+			rr := serveAsUser(t, user, mux, httptest.NewRequest(tt.method, tt.path, bytes.NewBufferString(tt.body)))
+
+			require.Equal(t, tt.expectedStatus, rr.Code, rr.Body.String())
+			svc.AssertExpectations(t)
+		})
+	}
+}
+
+func TestPublicInvitationHandlers(t *testing.T) {
+	svc := new(mockInvitationService)
+	svc.On("Preview", mock.Anything, "5.123.sig").Return(&service.InvitationPreview{
+		Invitation:    &models.Invitation{ID: 5, Email: "bob@example.com", OrganizationName: "Acme"},
+		AccountExists: false,
+	}, nil)
+	svc.On("Preview", mock.Anything, "expired").Return(nil, service.ErrInvalidInvitation)
+	svc.On("Register", mock.Anything, "5.123.sig", "password123", "bob", service.RegistrationConsent{
+		Accepted: map[string]string{"terms_of_service": "2026-10"}, Marketing: true, IP: "192.0.2.1",
+	}).Return(&service.LoginResponse{User: &models.User{ID: 20, Email: "bob@example.com"}, Token: "jwt"}, nil)
+	svc.On("Register", mock.Anything, "5.123.sig", "password123", "bob", mock.Anything).Return(nil, service.ErrConsentRequired)
+	svc.On("Register", mock.Anything, "5.123.sig", "password123", "alice", mock.Anything).Return(nil, service.ErrInvitationNeedsLogin)
+
+	mux := http.NewServeMux()
+	mux.Handle("GET /invitations/{token}", PreviewInvitationHandler(svc))
+	mux.Handle("POST /invitations/register", RegisterInvitationHandler(svc))
+
+	rr := httptest.NewRecorder()
+	mux.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/invitations/5.123.sig", nil))
+	require.Equal(t, http.StatusOK, rr.Code)
+	require.Equal(t, "no-store", rr.Header().Get("Cache-Control"))
+	var preview map[string]any
+	require.NoError(t, json.NewDecoder(rr.Body).Decode(&preview))
+	require.Equal(t, "bob@example.com", preview["email"])
+	require.Equal(t, "Acme", preview["organization_name"])
+	require.Equal(t, false, preview["account_exists"])
+
+	rr = httptest.NewRecorder()
+	mux.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/invitations/expired", nil))
+	require.Equal(t, http.StatusNotFound, rr.Code)
+
+	rr = httptest.NewRecorder()
+	mux.ServeHTTP(rr, httptest.NewRequest(http.MethodPost, "/invitations/register", bytes.NewBufferString(`{"token":"5.123.sig","password":"password123","username":"bob",
+		"accept":{"terms_of_service":"2026-10"},"marketing":true}`)))
+	require.Equal(t, http.StatusCreated, rr.Code)
+
+	rr = httptest.NewRecorder()
+	mux.ServeHTTP(rr, httptest.NewRequest(http.MethodPost, "/invitations/register", bytes.NewBufferString(`{"token":"5.123.sig","password":"password123","username":"bob"}`)))
+	require.Equal(t, http.StatusBadRequest, rr.Code)
+
+	rr = httptest.NewRecorder()
+	mux.ServeHTTP(rr, httptest.NewRequest(http.MethodPost, "/invitations/register", bytes.NewBufferString(`{"token":"5.123.sig","password":"password123","username":"alice"}`)))
+	require.Equal(t, http.StatusConflict, rr.Code)
+}
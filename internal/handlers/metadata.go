@@ -0,0 +1,218 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/Atmosfr/user-service/internal/jsonschema"
+	"github.com/Atmosfr/user-service/internal/models"
+	"github.com/Atmosfr/user-service/internal/repository"
+	"github.com/Atmosfr/user-service/internal/service"
+)
+
+// maxMetadataBytes bounds the value written to a single namespace.
+const maxMetadataBytes = 64 << 10
+
+type ListMetadataNamespacesResponse struct {
+	Namespaces []*models.MetadataNamespace `json:"namespaces"`
+}
+
+type PutMetadataNamespaceRequest struct {
+	Visibility    string          `json:"visibility"`
+	Schema        json.RawMessage `json:"schema"`
+	InTokenClaims bool            `json:"in_token_claims"`
+}
+
+type UserMetadataResponse struct {
+	UserID          int64           `json:"user_id"`
+	PublicMetadata  json.RawMessage `json:"public_metadata"`
+	PrivateMetadata json.RawMessage `json:"private_metadata"`
+}
+
+func userMetadataResponse(user *models.User) UserMetadataResponse {
+	return UserMetadataResponse{
+		UserID:          user.ID,
+		PublicMetadata:  user.PublicMetadata,
+		PrivateMetadata: user.PrivateMetadata,
+	}
+}
+
+func metadataErrorStatus(err error) int {
+	var invalid *jsonschema.ValidationError
+	switch {
+	case errors.As(err, &invalid):
+		return http.StatusUnprocessableEntity
+	case errors.Is(err, service.ErrInvalidNamespaceName),
+		errors.Is(err, service.ErrInvalidVisibility),
+		errors.Is(err, service.ErrPrivateTokenClaims),
+		errors.Is(err, service.ErrInvalidMetadataFilter),
+		errors.Is(err, jsonschema.ErrInvalidSchema):
+		return http.StatusBadRequest
+	case errors.Is(err, service.ErrNamespaceVisibility):
+		return http.StatusConflict
+	case errors.Is(err, repository.ErrNamespaceNotFound),
+		errors.Is(err, repository.ErrUserNotFound):
+		return http.StatusNotFound
+	}
+	return http.StatusInternalServerError
+}
+
+func writeMetadataError(w http.ResponseWriter, err error) {
+	w.WriteHeader(metadataErrorStatus(err))
+	json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+}
+
+// parseMetadataFilter reads "public.ns.key:value" as a match on the value and
+// "public.ns.key" as a check for the key. A value that is not JSON is taken as
+// a string, so plan:pro and plan:"pro" are the same filter.
+func parseMetadataFilter(param string) (repository.MetadataFilter, error) {
+	var f repository.MetadataFilter
+	path, value, hasValue := strings.Cut(param, ":")
+	parts := strings.Split(path, ".")
+	if len(parts) < 2 || strings.Contains(path, "..") || strings.HasSuffix(path, ".") {
+		return f, service.ErrInvalidMetadataFilter
+	}
+	f.Visibility, f.Path = parts[0], parts[1:]
+	if hasValue {
+		if json.Valid([]byte(value)) {
+			f.Value = json.RawMessage(value)
+		} else {
+			f.Value, _ = json.Marshal(value)
+		}
+	}
+	return f, nil
+}
+
+func ListMetadataNamespacesHandler(svc service.MetadataService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+
+		namespaces, err := svc.ListNamespaces(r.Context())
+		if err != nil {
+			writeMetadataError(w, err)
+			return
+		}
+
+		json.NewEncoder(w).Encode(ListMetadataNamespacesResponse{Namespaces: namespaces})
+	}
+}
+
+// PutMetadataNamespaceHandler registers the namespace in the path or replaces
+// its schema and token claim setting.
+func PutMetadataNamespaceHandler(svc service.MetadataService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		defer r.Body.Close()
+		w.Header().Set("Content-Type", "application/json")
+
+		var req PutMetadataNamespaceRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"error": "Invalid request payload"})
+			return
+		}
+
+		ns := &models.MetadataNamespace{
+			Name:          r.PathValue("name"),
+			Visibility:    req.Visibility,
+			Schema:        req.Schema,
+			InTokenClaims: req.InTokenClaims,
+		}
+		if err := svc.RegisterNamespace(r.Context(), ns); err != nil {
+			writeMetadataError(w, err)
+			return
+		}
+
+		json.NewEncoder(w).Encode(ns)
+	}
+}
+
+func DeleteMetadataNamespaceHandler(svc service.MetadataService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+
+		if err := svc.DeleteNamespace(r.Context(), r.PathValue("name")); err != nil {
+			writeMetadataError(w, err)
+			return
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+func GetUserMetadataHandler(svc service.MetadataService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+
+		id, ok := pathID(r, "id")
+		if !ok {
+			writeMetadataError(w, repository.ErrUserNotFound)
+			return
+		}
+
+		user, err := svc.UserMetadata(r.Context(), id)
+		if err != nil {
+			writeMetadataError(w, err)
+			return
+		}
+
+		json.NewEncoder(w).Encode(userMetadataResponse(user))
+	}
+}
+
+// SetUserMetadataHandler replaces a namespace of a user's metadata with the
+// JSON document in the request body.
+func SetUserMetadataHandler(svc service.MetadataService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		defer r.Body.Close()
+		w.Header().Set("Content-Type", "application/json")
+
+		id, ok := pathID(r, "id")
+		if !ok {
+			writeMetadataError(w, repository.ErrUserNotFound)
+			return
+		}
+
+		value, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxMetadataBytes))
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			w.WriteHeader(http.StatusRequestEntityTooLarge)
+			json.NewEncoder(w).Encode(map[string]string{"error": "metadata must be at most 64 KB"})
+			return
+		}
+		if err != nil || !json.Valid(value) {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"error": "Invalid request payload"})
+			return
+		}
+
+		user, err := svc.SetUserMetadata(r.Context(), id, r.PathValue("visibility"), r.PathValue("namespace"), value)
+		if err != nil {
+			writeMetadataError(w, err)
+			return
+		}
+
+		json.NewEncoder(w).Encode(userMetadataResponse(user))
+	}
+}
+
+func DeleteUserMetadataHandler(svc service.MetadataService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+
+		id, ok := pathID(r, "id")
+		if !ok {
+			writeMetadataError(w, repository.ErrUserNotFound)
+			return
+		}
+
+		if _, err := svc.SetUserMetadata(r.Context(), id, r.PathValue("visibility"), r.PathValue("namespace"), nil); err != nil {
+			writeMetadataError(w, err)
+			return
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
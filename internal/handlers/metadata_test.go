@@ -0,0 +1,114 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/Atmosfr/user-service/internal/jsonschema"
+	"github.com/Atmosfr/user-service/internal/models"
+	"github.com/Atmosfr/user-service/internal/repository"
+	"github.com/Atmosfr/user-service/internal/service"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+type mockMetadataService struct {
+	mock.Mock
+}
+
+func (m *mockMetadataService) RegisterNamespace(ctx context.Context, ns *models.MetadataNamespace) error {
+	return m.Called(ctx, ns).Error(0)
+}
+
+func (m *mockMetadataService) ListNamespaces(ctx context.Context) ([]*models.MetadataNamespace, error) {
+	args := m.Called(ctx)
+	namespaces, _ := args.Get(0).([]*models.MetadataNamespace)
+	return namespaces, args.Error(1)
+}
+
+func (m *mockMetadataService) DeleteNamespace(ctx context.Context, name string) error {
+	return m.Called(ctx, name).Error(0)
+}
+
+func (m *mockMetadataService) UserMetadata(ctx context.Context, userID int64) (*models.User, error) {
+	args := m.Called(ctx, userID)
+	user, _ := args.Get(0).(*models.User)
+	return user, args.Error(1)
+}
+
+func (m *mockMetadataService) SetUserMetadata(ctx context.Context, userID int64, visibility, namespace string, value json.RawMessage) (*models.User, error) {
+	args := m.Called(ctx, userID, visibility, namespace, value)
+	user, _ := args.Get(0).(*models.User)
+	return user, args.Error(1)
+}
+
+func (m *mockMetadataService) TokenClaims(ctx context.Context, user *models.User) (map[string]json.RawMessage, error) {
+	args := m.Called(ctx, user)
+	claims, _ := args.Get(0).(map[string]json.RawMessage)
+	return claims, args.Error(1)
+}
+
+func TestParseMetadataFilter(t *testing.T) {
+	tests := []struct {
+		param   string
+		want    repository.MetadataFilter
+		wantErr bool
+	}{
+		{param: "public.billing.plan:pro", want: repository.MetadataFilter{Visibility: "public", Path: []string{"billing", "plan"}, Value: json.RawMessage(`"pro"`)}},
+		{param: `public.billing.plan:"pro"`, want: repository.MetadataFilter{Visibility: "public", Path: []string{"billing", "plan"}, Value: json.RawMessage(`"pro"`)}},
+		{param: "private.crm.seats:10", want: repository.MetadataFilter{Visibility: "private", Path: []string{"crm", "seats"}, Value: json.RawMessage(`10`)}},
+		{param: "private.crm", want: repository.MetadataFilter{Visibility: "private", Path: []string{"crm"}}},
+		{param: "public", wantErr: true},
+		{param: "public..plan", wantErr: true},
+		{param: "public.billing.", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.param, func(t *testing.T) {
+			got, err := parseMetadataFilter(tt.param)
+			if tt.wantErr {
+				require.ErrorIs(t, err, service.ErrInvalidMetadataFilter)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tt.want.Visibility, got.Visibility)
+			require.Equal(t, tt.want.Path, got.Path)
+			require.Equal(t, string(tt.want.Value), string(got.Value))
+		})
+	}
+}
+
+func TestSetUserMetadataHandler(t *testing.T) {
+	svc := new(mockMetadataService)
+	svc.On("SetUserMetadata", mock.Anything, int64(7), "public", "billing", json.RawMessage(`{"plan":"pro"}`)).
+		Return(&models.User{ID: 7, PublicMetadata: json.RawMessage(`{"billing":{"plan":"pro"}}`), PrivateMetadata: json.RawMessage(`{}`)}, nil)
+	svc.On("SetUserMetadata", mock.Anything, int64(7), "public", "billing", json.RawMessage(`{"plan":"gold"}`)).
+		Return(nil, &jsonschema.ValidationError{Path: "/plan", Message: "value is not one of the allowed values"})
+	svc.On("SetUserMetadata", mock.Anything, int64(7), "public", "crm", mock.Anything).Return(nil, repository.ErrNamespaceNotFound)
+
+	tests := []struct {
+		name           string
+		namespace      string
+		body           string
+		expectedStatus int
+	}{
+		{name: "valid", namespace: "billing", body: `{"plan":"pro"}`, expectedStatus: http.StatusOK},
+		{name: "schema violation", namespace: "billing", body: `{"plan":"gold"}`, expectedStatus: http.StatusUnprocessableEntity},
+		{name: "unregistered namespace", namespace: "crm", body: `{}`, expectedStatus: http.StatusNotFound},
+		{name: "not json", namespace: "billing", body: `{plan}`, expectedStatus: http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPut, "/", bytes.NewBufferString(tt.body))
+			req.SetPathValue("id", "7")
+			req.SetPathValue("visibility", "public")
+			req.SetPathValue("namespace", tt.namespace)
+			rr := httptest.NewRecorder()
+			SetUserMetadataHandler(svc).ServeHTTP(rr, req)
+			require.Equal(t, tt.expectedStatus, rr.Code, rr.Body.String())
+		})
+	}
+}
@@ -0,0 +1,327 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/Atmosfr/user-service/internal/middleware"
+	"github.com/Atmosfr/user-service/internal/models"
+	"github.com/Atmosfr/user-service/internal/repository"
+	"github.com/Atmosfr/user-service/internal/service"
+	"github.com/Atmosfr/user-service/internal/validation"
+)
+
+type CreateOrganizationRequest struct {
+	Name string `json:"name"`
+	Slug string `json:"slug"`
+}
+
+type ListOrganizationsResponse struct {
+	Organizations []*models.Organization `json:"organizations"`
+}
+
+type AddOrganizationMemberRequest struct {
+	Email string `json:"email"`
+	Role  string `json:"role"`
+}
+
+type UpdateOrganizationMemberRequest struct {
+	Role string `json:"role"`
+}
+
+type ListOrganizationMembersResponse struct {
+	Members []*models.OrganizationMember `json:"members"`
+}
+
+type SwitchOrganizationRequest struct {
+	// OrganizationID 0 switches to no active organization.
+	OrganizationID int64 `json:"organization_id"`
+}
+
+func organizationErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, validation.ErrInvalidOrganizationName),
+		errors.Is(err, validation.ErrInvalidOrganizationSlug),
+		errors.Is(err, service.ErrInvalidOrganizationRole):
+		return http.StatusBadRequest
+	case errors.Is(err, service.ErrOrganizationAdminOnly),
+		errors.Is(err, service.ErrOrganizationOwnerOnly),
+		errors.Is(err, service.ErrCannotRemoveHigherMember):
+		return http.StatusForbidden
+	case errors.Is(err, repository.ErrOrganizationSlugExists),
+		errors.Is(err, repository.ErrMemberAlreadyExists),
+		errors.Is(err, repository.ErrLastOrganizationOwner):
+		return http.StatusConflict
+	case errors.Is(err, repository.ErrOrganizationNotFound),
+		errors.Is(err, repository.ErrMemberNotFound),
+		errors.Is(err, repository.ErrUserNotFound):
+		return http.StatusNotFound
+	}
+	return http.StatusInternalServerError
+}
+
+func writeOrganizationError(w http.ResponseWriter, err error) {
+	w.WriteHeader(organizationErrorStatus(err))
+	json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+}
+
+// requireUser writes a 401 and reports false when the request has no user.
+func requireUser(w http.ResponseWriter, r *http.Request) (*models.User, bool) {
+	user, ok := middleware.GetUserFromContext(r.Context())
+	if !ok {
+		w.WriteHeader(http.StatusUnauthorized)
+		json.NewEncoder(w).Encode(map[string]string{"error": "unauthorized"})
+	}
+	return user, ok
+}
+
+func CreateOrganizationHandler(svc service.OrganizationService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		defer r.Body.Close()
+		w.Header().Set("Content-Type", "application/json")
+
+		user, ok := requireUser(w, r)
+		if !ok {
+			return
+		}
+
+		var req CreateOrganizationRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"error": "Invalid request payload"})
+			return
+		}
+
+		org, err := svc.Create(r.Context(), user.ID, req.Name, req.Slug)
+		if err != nil {
+			writeOrganizationError(w, err)
+			return
+		}
+
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(org)
+	}
+}
+
+func ListOrganizationsHandler(svc service.OrganizationService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+
+		user, ok := requireUser(w, r)
+		if !ok {
+			return
+		}
+
+		orgs, err := svc.ListForUser(r.Context(), user.ID)
+		if err != nil {
+			writeOrganizationError(w, err)
+			return
+		}
+
+		json.NewEncoder(w).Encode(ListOrganizationsResponse{Organizations: orgs})
+	}
+}
+
+func GetOrganizationHandler(svc service.OrganizationService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+
+		user, ok := requireUser(w, r)
+		if !ok {
+			return
+		}
+		orgID, ok := pathID(r, "id")
+		if !ok {
+			writeOrganizationError(w, repository.ErrOrganizationNotFound)
+			return
+		}
+
+		org, err := svc.Get(r.Context(), user.ID, orgID)
+		if err != nil {
+			writeOrganizationError(w, err)
+			return
+		}
+
+		json.NewEncoder(w).Encode(org)
+	}
+}
+
+// CurrentOrganizationHandler returns the token's active organization.
+func CurrentOrganizationHandler(svc service.OrganizationService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+
+		user, ok := requireUser(w, r)
+		if !ok {
+			return
+		}
+		member, ok := middleware.GetOrganizationFromContext(r.Context())
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			json.NewEncoder(w).Encode(map[string]string{"error": "no active organization"})
+			return
+		}
+
+		org, err := svc.Get(r.Context(), user.ID, member.OrganizationID)
+		if err != nil {
+			writeOrganizationError(w, err)
+			return
+		}
+
+		json.NewEncoder(w).Encode(org)
+	}
+}
+
+func ListOrganizationMembersHandler(svc service.OrganizationService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+
+		user, ok := requireUser(w, r)
+		if !ok {
+			return
+		}
+		orgID, ok := pathID(r, "id")
+		if !ok {
+			writeOrganizationError(w, repository.ErrOrganizationNotFound)
+			return
+		}
+
+		members, err := svc.ListMembers(r.Context(), user.ID, orgID)
+		if err != nil {
+			writeOrganizationError(w, err)
+			return
+		}
+
+		json.NewEncoder(w).Encode(ListOrganizationMembersResponse{Members: members})
+	}
+}
+
+func AddOrganizationMemberHandler(svc service.OrganizationService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		defer r.Body.Close()
+		w.Header().Set("Content-Type", "application/json")
+
+		user, ok := requireUser(w, r)
+		if !ok {
+			return
+		}
+		orgID, ok := pathID(r, "id")
+		if !ok {
+			writeOrganizationError(w, repository.ErrOrganizationNotFound)
+			return
+		}
+
+		var req AddOrganizationMemberRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"error": "Invalid request payload"})
+			return
+		}
+		if req.Role == "" {
+			req.Role = models.OrgRoleMember
+		}
+
+		member, err := svc.AddMember(r.Context(), user.ID, orgID, req.Email, req.Role)
+		if err != nil {
+			writeOrganizationError(w, err)
+			return
+		}
+
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(member)
+	}
+}
+
+func UpdateOrganizationMemberHandler(svc service.OrganizationService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		defer r.Body.Close()
+		w.Header().Set("Content-Type", "application/json")
+
+		user, ok := requireUser(w, r)
+		if !ok {
+			return
+		}
+		orgID, ok := pathID(r, "id")
+		if !ok {
+			writeOrganizationError(w, repository.ErrOrganizationNotFound)
+			return
+		}
+		memberID, ok := pathID(r, "userID")
+		if !ok {
+			writeOrganizationError(w, repository.ErrMemberNotFound)
+			return
+		}
+
+		var req UpdateOrganizationMemberRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"error": "Invalid request payload"})
+			return
+		}
+
+		if err := svc.UpdateMemberRole(r.Context(), user.ID, orgID, memberID, req.Role); err != nil {
+			writeOrganizationError(w, err)
+			return
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+func RemoveOrganizationMemberHandler(svc service.OrganizationService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+
+		user, ok := requireUser(w, r)
+		if !ok {
+			return
+		}
+		orgID, ok := pathID(r, "id")
+		if !ok {
+			writeOrganizationError(w, repository.ErrOrganizationNotFound)
+			return
+		}
+		memberID, ok := pathID(r, "userID")
+		if !ok {
+			writeOrganizationError(w, repository.ErrMemberNotFound)
+			return
+		}
+
+		if err := svc.RemoveMember(r.Context(), user.ID, orgID, memberID); err != nil {
+			writeOrganizationError(w, err)
+			return
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// SwitchOrganizationHandler reissues the caller's access token with a different
+// active organization.
+func SwitchOrganizationHandler(svc service.OrganizationService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		defer r.Body.Close()
+		w.Header().Set("Content-Type", "application/json")
+
+		user, ok := requireUser(w, r)
+		if !ok {
+			return
+		}
+
+		var req SwitchOrganizationRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"error": "Invalid request payload"})
+			return
+		}
+
+		resp, err := svc.Switch(r.Context(), user, req.OrganizationID)
+		if err != nil {
+			writeOrganizationError(w, err)
+			return
+		}
+
+		json.NewEncoder(w).Encode(resp)
+	}
+}
@@ -0,0 +1,245 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/Atmosfr/user-service/internal/auth"
+	"github.com/Atmosfr/user-service/internal/middleware"
+	"github.com/Atmosfr/user-service/internal/models"
+	"github.com/Atmosfr/user-service/internal/repository"
+	"github.com/Atmosfr/user-service/internal/service"
+	"github.com/Atmosfr/user-service/internal/testutil"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+type mockOrganizationService struct {
+	mock.Mock
+}
+
+func (m *mockOrganizationService) Create(ctx context.Context, userID int64, name, slug string) (*models.Organization, error) {
+	args := m.Called(ctx, userID, name, slug)
+	org, _ := args.Get(0).(*models.Organization)
+	return org, args.Error(1)
+}
+
+func (m *mockOrganizationService) ListForUser(ctx context.Context, userID int64) ([]*models.Organization, error) {
+	args := m.Called(ctx, userID)
+	return args.Get(0).([]*models.Organization), args.Error(1)
+}
+
+func (m *mockOrganizationService) Get(ctx context.Context, userID, orgID int64) (*models.Organization, error) {
+	args := m.Called(ctx, userID, orgID)
+	org, _ := args.Get(0).(*models.Organization)
+	return org, args.Error(1)
+}
+
+func (m *mockOrganizationService) Membership(ctx context.Context, orgID, userID int64) (*models.OrganizationMember, error) {
+	args := m.Called(ctx, orgID, userID)
+	member, _ := args.Get(0).(*models.OrganizationMember)
+	return member, args.Error(1)
+}
+
+func (m *mockOrganizationService) ListMembers(ctx context.Context, userID, orgID int64) ([]*models.OrganizationMember, error) {
+	args := m.Called(ctx, userID, orgID)
+	members, _ := args.Get(0).([]*models.OrganizationMember)
+	return members, args.Error(1)
+}
+
+func (m *mockOrganizationService) AddMember(ctx context.Context, actorID, orgID int64, email, role string) (*models.OrganizationMember, error) {
+	args := m.Called(ctx, actorID, orgID, email, role)
+	member, _ := args.Get(0).(*models.OrganizationMember)
+	return member, args.Error(1)
+}
+
+func (m *mockOrganizationService) UpdateMemberRole(ctx context.Context, actorID, orgID, userID int64, role string) error {
+	return m.Called(ctx, actorID, orgID, userID, role).Error(0)
+}
+
+func (m *mockOrganizationService) RemoveMember(ctx context.Context, actorID, orgID, userID int64) error {
+	return m.Called(ctx, actorID, orgID, userID).Error(0)
+}
+
+func (m *mockOrganizationService) Switch(ctx context.Context, user *models.User, orgID int64) (*service.LoginResponse, error) {
+	args := m.Called(ctx, user, orgID)
+	resp, _ := args.Get(0).(*service.LoginResponse)
+	return resp, args.Error(1)
+}
+
+// serveAsUser runs handler behind the auth middleware with a session token for user.
+func serveAsUser(t *testing.T, user *models.User, handler http.Handler, req *http.Request) *httptest.ResponseRecorder {
+	t.Helper()
+	auth.JwtSecret = []byte("secret")
+	token, err := auth.GenerateToken(user, time.Hour, auth.JwtSecret)
+	require.NoError(t, err)
+	repo := new(testutil.MockUserRepo)
+	repo.On("FindByID", mock.Anything, user.ID).Return(user, nil)
+
+	req.Header.Set("Authorization", "Bearer "+token)
+	rr := httptest.NewRecorder()
+	middleware.NewAuthMiddleware(repo)(handler).ServeHTTP(rr, req)
+	return rr
+}
+
+func TestOrganizationHandlers(t *testing.T) {
+	user := &models.User{ID: 7, Role: "user"}
+
+	tests := []struct {
+		name           string
+		method         string
+		pattern        string
+		path           string
+		body           string
+		handler        func(svc service.OrganizationService) http.HandlerFunc
+		setupMock      func(svc *mockOrganizationService)
+		expectedStatus int
+	}{
+		{
+			name: "create", method: http.MethodPost, pattern: "POST /orgs", path: "/orgs",
+			body:    `{"name":"Acme","slug":"acme"}`,
+			handler: CreateOrganizationHandler,
+			setupMock: func(svc *mockOrganizationService) {
+				svc.On("Create", mock.Anything, int64(7), "Acme", "acme").Return(&models.Organization{ID: 1, Name: "Acme", Slug: "acme"}, nil)
+			},
+			expectedStatus: http.StatusCreated,
+		},
+		{
+			name: "create with taken slug", method: http.MethodPost, pattern: "POST /orgs", path: "/orgs",
+			body:    `{"name":"Acme","slug":"acme"}`,
+			handler: CreateOrganizationHandler,
+			setupMock: func(svc *mockOrganizationService) {
+				svc.On("Create", mock.Anything, int64(7), "Acme", "acme").Return(nil, repository.ErrOrganizationSlugExists)
+			},
+			expectedStatus: http.StatusConflict,
+		},
+		{
+			name: "get as non-member", method: http.MethodGet, pattern: "GET /orgs/{id}", path: "/orgs/3",
+			handler: GetOrganizationHandler,
+			setupMock: func(svc *mockOrganizationService) {
+				svc.On("Get", mock.Anything, int64(7), int64(3)).Return(nil, repository.ErrOrganizationNotFound)
+			},
+			expectedStatus: http.StatusNotFound,
+		},
+		{
+			name: "add member defaults to member role", method: http.MethodPost, pattern: "POST /orgs/{id}/members", path: "/orgs/1/members",
+			body:    `{"email":"new@example.com"}`,
+			handler: AddOrganizationMemberHandler,
+			setupMock: func(svc *mockOrganizationService) {
+				svc.On("AddMember", mock.Anything, int64(7), int64(1), "new@example.com", models.OrgRoleMember).
+					Return(&models.OrganizationMember{OrganizationID: 1, UserID: 10, Role: models.OrgRoleMember}, nil)
+			},
+			expectedStatus: http.StatusCreated,
+		},
+		{
+			name: "add member without rights", method: http.MethodPost, pattern: "POST /orgs/{id}/members", path: "/orgs/1/members",
+			body:    `{"email":"new@example.com","role":"admin"}`,
+			handler: AddOrganizationMemberHandler,
+			setupMock: func(svc *mockOrganizationService) {
+				svc.On("AddMember", mock.Anything, int64(7), int64(1), "new@example.com", models.OrgRoleAdmin).Return(nil, service.ErrOrganizationAdminOnly)
+			},
+			expectedStatus: http.StatusForbidden,
+		},
+		{
+			name: "update role", method: http.MethodPatch, pattern: "PATCH /orgs/{id}/members/{userID}", path: "/orgs/1/members/10",
+			body:    `{"role":"admin"}`,
+			handler: UpdateOrganizationMemberHandler,
+			setupMock: func(svc *mockOrganizationService) {
+				svc.On("UpdateMemberRole", mock.Anything, int64(7), int64(1), int64(10), models.OrgRoleAdmin).Return(nil)
+			},
+			expectedStatus: http.StatusNoContent,
+		},
+		{
+			name: "last owner cannot leave", method: http.MethodDelete, pattern: "DELETE /orgs/{id}/members/{userID}", path: "/orgs/1/members/7",
+			handler: RemoveOrganizationMemberHandler,
+			setupMock: func(svc *mockOrganizationService) {
+				svc.On("RemoveMember", mock.Anything, int64(7), int64(1), int64(7)).Return(repository.ErrLastOrganizationOwner)
+			},
+			expectedStatus: http.StatusConflict,
+		},
+		{
+			name: "invalid member id", method: http.MethodDelete, pattern: "DELETE /orgs/{id}/members/{userID}", path: "/orgs/1/members/abc",
+			handler:        RemoveOrganizationMemberHandler,
+			setupMock:      func(svc *mockOrganizationService) {},
+			expectedStatus: http.StatusNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc := new(mockOrganizationService)
+			tt.setupMock(svc)
+
+			mux := http.NewServeMux()
+			mux.Handle(tt.pattern, tt.handler(svc))
+			rr := serveAsUser(t, user, mux, httptest.NewRequest(tt.method, tt.path, bytes.NewBufferString(tt.body)))
+
+			require.Equal(t, tt.expectedStatus, rr.Code, rr.Body.String())
+			svc.AssertExpectations(t)
+		})
+	}
+}
+
+func TestActiveOrganization(t *testing.T) {
+	auth.JwtSecret = []byte("secret")
+	user := &models.User{ID: 7, Role: "user"}
+	member := &models.OrganizationMember{OrganizationID: 1, UserID: 7, Role: models.OrgRoleAdmin}
+
+	orgToken, err := auth.GenerateToken(user, time.Hour, auth.JwtSecret, auth.WithOrganization(1, models.OrgRoleAdmin))
+	require.NoError(t, err)
+	staleToken, err := auth.GenerateToken(user, time.Hour, auth.JwtSecret, auth.WithOrganization(2, models.OrgRoleOwner))
+	require.NoError(t, err)
+	plainToken, err := auth.GenerateToken(user, time.Hour, auth.JwtSecret)
+	require.NoError(t, err)
+
+	tests := []struct {
+		name           string
+		token          string
+		expectedStatus int
+	}{
+		{name: "active organization", token: orgToken, expectedStatus: http.StatusOK},
+		{name: "membership revoked since issue", token: staleToken, expectedStatus: http.StatusUnauthorized},
+		{name: "no active organization", token: plainToken, expectedStatus: http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := new(testutil.MockUserRepo)
+			repo.On("FindByID", mock.Anything, int64(7)).Return(user, nil)
+			svc := new(mockOrganizationService)
+			svc.On("Membership", mock.Anything, int64(1), int64(7)).Return(member, nil)
+			svc.On("Membership", mock.Anything, int64(2), int64(7)).Return(nil, repository.ErrMemberNotFound)
+			svc.On("Get", mock.Anything, int64(7), int64(1)).Return(&models.Organization{ID: 1, Name: "Acme", Role: models.OrgRoleAdmin}, nil)
+
+			handler := middleware.NewAuthMiddleware(repo, middleware.WithOrganizations(svc))(CurrentOrganizationHandler(svc))
+			req := httptest.NewRequest(http.MethodGet, "/orgs/current", nil)
+			req.Header.Set("Authorization", "Bearer "+tt.token)
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, req)
+
+			require.Equal(t, tt.expectedStatus, rr.Code, rr.Body.String())
+			if tt.expectedStatus == http.StatusOK {
+				var org models.Organization
+				require.NoError(t, json.NewDecoder(rr.Body).Decode(&org))
+				require.Equal(t, "Acme", org.Name)
+			}
+		})
+	}
+}
+
+func TestSwitchOrganizationHandler(t *testing.T) {
+	user := &models.User{ID: 7, Role: "user"}
+	svc := new(mockOrganizationService)
+	svc.On("Switch", mock.Anything, user, int64(1)).Return(&service.LoginResponse{User: user, Token: "new-token"}, nil)
+	svc.On("Switch", mock.Anything, user, int64(9)).Return(nil, repository.ErrOrganizationNotFound)
+
+	for body, expected := range map[string]int{`{"organization_id":1}`: http.StatusOK, `{"organization_id":9}`: http.StatusNotFound} {
+		rr := serveAsUser(t, user, SwitchOrganizationHandler(svc), httptest.NewRequest(http.MethodPost, "/orgs/switch", bytes.NewBufferString(body)))
+		require.Equal(t, expected, rr.Code)
+	}
+}
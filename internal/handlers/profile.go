@@ -0,0 +1,206 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Atmosfr/user-service/internal/models"
+	"github.com/Atmosfr/user-service/internal/repository"
+	"github.com/Atmosfr/user-service/internal/service"
+	"github.com/Atmosfr/user-service/internal/validation"
+)
+
+// errPreconditionFailed is returned for If-Match values that cannot match any version.
+var errPreconditionFailed = errors.New("If-Match does not match the current version")
+
+type ProfileResponse struct {
+	ID              int64             `json:"id"`
+	Email           string            `json:"email"`
+	Username        string            `json:"username"`
+	DisplayName     string            `json:"display_name"`
+	Locale          string            `json:"locale"`
+	Timezone        string            `json:"timezone"`
+	AvatarURL       string            `json:"avatar_url"`
+	AvatarURLs      map[string]string `json:"avatar_urls,omitempty"`
+	Role            string            `json:"role"`
+	CreatedAt       time.Time         `json:"created_at"`
+	UpdatedAt       time.Time         `json:"updated_at"`
+	IsActive        bool              `json:"is_active"`
+	EmailVerifiedAt *time.Time        `json:"email_verified_at"`
+	PublicMetadata  json.RawMessage   `json:"public_metadata,omitempty"`
+}
+
+func profileResponse(user *models.User) ProfileResponse {
+	return ProfileResponse{
+		ID:              user.ID,
+		Email:           user.Email,
+		Username:        user.Username,
+		DisplayName:     user.DisplayName,
+		Locale:          user.Locale,
+		Timezone:        user.Timezone,
+		AvatarURL:       user.AvatarURL,
+		AvatarURLs:      user.AvatarURLs,
+		Role:            user.Role,
+		CreatedAt:       user.CreatedAt,
+		UpdatedAt:       user.UpdatedAt,
+		IsActive:        user.IsActive,
+		EmailVerifiedAt: user.EmailVerifiedAt,
+		PublicMetadata:  user.PublicMetadata,
+	}
+}
+
+// userETag identifies the version of a user by its updated_at, which Postgres
+// stores with microsecond precision.
+func userETag(user *models.User) string {
+	return fmt.Sprintf(`"%d"`, user.UpdatedAt.UnixMicro())
+}
+
+// parseIfMatch returns the version a request is conditional on, nil for a
+// missing header or "*". Only a single strong entity tag can match a user.
+func parseIfMatch(header string) (*time.Time, error) {
+	header = strings.TrimSpace(header)
+	if header == "" || header == "*" {
+		return nil, nil
+	}
+	tag, ok := strings.CutPrefix(header, `"`)
+	if !ok {
+		return nil, errPreconditionFailed
+	}
+	tag, ok = strings.CutSuffix(tag, `"`)
+	if !ok {
+		return nil, errPreconditionFailed
+	}
+	micros, err := strconv.ParseInt(tag, 10, 64)
+	if err != nil {
+		return nil, errPreconditionFailed
+	}
+	version := time.UnixMicro(micros)
+	return &version, nil
+}
+
+func profileErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, validation.ErrInvalidUsername),
+		errors.Is(err, validation.ErrInvalidDisplayName),
+		errors.Is(err, validation.ErrInvalidLocale),
+		errors.Is(err, validation.ErrInvalidTimezone),
+		errors.Is(err, validation.ErrInvalidAvatarURL):
+		return http.StatusBadRequest
+	case errors.Is(err, repository.ErrUsernameAlreadyExists):
+		return http.StatusConflict
+	case errors.Is(err, repository.ErrUserModified),
+		errors.Is(err, errPreconditionFailed):
+		return http.StatusPreconditionFailed
+	case errors.Is(err, repository.ErrUserNotFound):
+		return http.StatusNotFound
+	}
+	return http.StatusInternalServerError
+}
+
+func writeProfileError(w http.ResponseWriter, err error) {
+	w.WriteHeader(profileErrorStatus(err))
+	json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+}
+
+// decodeProfilePatch reads a JSON Merge Patch (RFC 7396) of the editable
+// profile fields. A null member clears the field.
+func decodeProfilePatch(r *http.Request) (service.ProfilePatch, error) {
+	var patch service.ProfilePatch
+	var members map[string]json.RawMessage
+	if err := json.NewDecoder(r.Body).Decode(&members); err != nil {
+		return patch, err
+	}
+	if members == nil {
+		return patch, errors.New("merge patch must be a JSON object")
+	}
+
+	fields := map[string]**string{
+		"username":     &patch.Username,
+		"display_name": &patch.DisplayName,
+		"locale":       &patch.Locale,
+		"timezone":     &patch.Timezone,
+		"avatar_url":   &patch.AvatarURL,
+	}
+	for name, raw := range members {
+		field, ok := fields[name]
+		if !ok {
+			return patch, fmt.Errorf("%s cannot be changed", name)
+		}
+		value := ""
+		if !bytes.Equal(raw, []byte("null")) {
+			if err := json.Unmarshal(raw, &value); err != nil {
+				return patch, fmt.Errorf("%s must be a string", name)
+			}
+		}
+		*field = &value
+	}
+	return patch, nil
+}
+
+// MeHandler returns the profile of the authenticated user.
+func MeHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+
+		user, ok := requireUser(w, r)
+		if !ok {
+			return
+		}
+
+		w.Header().Set("ETag", userETag(user))
+		json.NewEncoder(w).Encode(profileResponse(user))
+	}
+}
+
+// UpdateMeHandler applies a JSON Merge Patch to the profile of the
+// authenticated user. An If-Match header with the ETag of GET /me makes the
+// update fail with 412 if the profile changed in between.
+func UpdateMeHandler(svc service.UserService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		defer r.Body.Close()
+		w.Header().Set("Content-Type", "application/json")
+
+		user, ok := requireUser(w, r)
+		if !ok {
+			return
+		}
+
+		mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+		if mediaType != "application/merge-patch+json" && mediaType != "application/json" {
+			w.WriteHeader(http.StatusUnsupportedMediaType)
+			json.NewEncoder(w).Encode(map[string]string{
+				"error": "Content-Type must be application/merge-patch+json",
+			})
+			return
+		}
+
+		ifMatch, err := parseIfMatch(r.Header.Get("If-Match"))
+		if err != nil {
+			writeProfileError(w, err)
+			return
+		}
+
+		patch, err := decodeProfilePatch(r)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+			return
+		}
+
+		updated, err := svc.UpdateProfile(r.Context(), user.ID, patch, ifMatch)
+		if err != nil {
+			writeProfileError(w, err)
+			return
+		}
+
+		w.Header().Set("ETag", userETag(updated))
+		json.NewEncoder(w).Encode(profileResponse(updated))
+	}
+}
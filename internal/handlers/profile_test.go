@@ -0,0 +1,152 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/Atmosfr/user-service/internal/models"
+	"github.com/Atmosfr/user-service/internal/repository"
+	"github.com/Atmosfr/user-service/internal/service"
+	"github.com/Atmosfr/user-service/internal/validation"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMeHandler(t *testing.T) {
+	user := &models.User{ID: 7, Role: "user", Username: "alice", Timezone: "Europe/London", UpdatedAt: time.UnixMicro(1700000000123456)}
+
+	rr := serveAsUser(t, user, MeHandler(), httptest.NewRequest(http.MethodGet, "/me", nil))
+	require.Equal(t, http.StatusOK, rr.Code)
+	require.Equal(t, `"1700000000123456"`, rr.Header().Get("ETag"))
+
+	var resp ProfileResponse
+	require.NoError(t, json.NewDecoder(rr.Body).Decode(&resp))
+	require.Equal(t, "alice", resp.Username)
+	require.Equal(t, "Europe/London", resp.Timezone)
+}
+
+func TestUpdateMeHandler(t *testing.T) {
+	version := time.UnixMicro(1700000000123456)
+	user := &models.User{ID: 7, Role: "user", Username: "alice", UpdatedAt: version}
+	str := func(s string) *string { return &s }
+
+	tests := []struct {
+		name           string
+		contentType    string
+		ifMatch        string
+		body           string
+		setupMock      func(svc *mockUserService)
+		expectedStatus int
+	}{
+		{
+			name:        "merge patch",
+			contentType: "application/merge-patch+json",
+			ifMatch:     `"1700000000123456"`,
+			body:        `{"display_name":"Alice","avatar_url":null}`,
+			setupMock: func(svc *mockUserService) {
+				svc.On("UpdateProfile", mock.Anything, int64(7), service.ProfilePatch{DisplayName: str("Alice"), AvatarURL: str("")}, &version).
+					Return(&models.User{ID: 7, Username: "alice", DisplayName: "Alice", UpdatedAt: version.Add(time.Second)}, nil)
+			},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:        "unconditional",
+			contentType: "application/json; charset=utf-8",
+			ifMatch:     "*",
+			body:        `{"locale":"de-DE"}`,
+			setupMock: func(svc *mockUserService) {
+				svc.On("UpdateProfile", mock.Anything, int64(7), service.ProfilePatch{Locale: str("de-DE")}, (*time.Time)(nil)).
+					Return(&models.User{ID: 7, Username: "alice", Locale: "de-DE"}, nil)
+			},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "unsupported content type",
+			contentType:    "text/plain",
+			body:           `{}`,
+			setupMock:      func(svc *mockUserService) {},
+			expectedStatus: http.StatusUnsupportedMediaType,
+		},
+		{
+			name:           "weak etag",
+			contentType:    "application/merge-patch+json",
+			ifMatch:        `W/"1700000000123456"`,
+			body:           `{"locale":"de-DE"}`,
+			setupMock:      func(svc *mockUserService) {},
+			expectedStatus: http.StatusPreconditionFailed,
+		},
+		{
+			name:           "read-only field",
+			contentType:    "application/merge-patch+json",
+			body:           `{"role":"admin"}`,
+			setupMock:      func(svc *mockUserService) {},
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "not an object",
+			contentType:    "application/merge-patch+json",
+			body:           `["locale"]`,
+			setupMock:      func(svc *mockUserService) {},
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "wrong type",
+			contentType:    "application/merge-patch+json",
+			body:           `{"timezone":1}`,
+			setupMock:      func(svc *mockUserService) {},
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:        "invalid value",
+			contentType: "application/merge-patch+json",
+			body:        `{"timezone":"Europe/Atlantis"}`,
+			setupMock: func(svc *mockUserService) {
+				svc.On("UpdateProfile", mock.Anything, int64(7), mock.Anything, (*time.Time)(nil)).Return(nil, validation.ErrInvalidTimezone)
+			},
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:        "username taken",
+			contentType: "application/merge-patch+json",
+			body:        `{"username":"bob"}`,
+			setupMock: func(svc *mockUserService) {
+				svc.On("UpdateProfile", mock.Anything, int64(7), mock.Anything, (*time.Time)(nil)).Return(nil, repository.ErrUsernameAlreadyExists)
+			},
+			expectedStatus: http.StatusConflict,
+		},
+		{
+			name:        "stale version",
+			contentType: "application/merge-patch+json",
+			ifMatch:     `"1700000000000000"`,
+			body:        `{"locale":"de-DE"}`,
+			setupMock: func(svc *mockUserService) {
+				svc.On("UpdateProfile", mock.Anything, int64(7), mock.Anything, mock.Anything).Return(nil, repository.ErrUserModified)
+			},
+			expectedStatus: http.StatusPreconditionFailed,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc := new(mockUserService)
+			tt.setupMock(svc)
+
+			req := httptest.NewRequest(http.MethodPatch, "/me", bytes.NewBufferString(tt.body))
+			req.Header.Set("Content-Type", tt.contentType)
+			if tt.ifMatch != "" {
+				req.Header.Set("If-Match", tt.ifMatch)
+			}
+			rr := serveAsUser(t, user, UpdateMeHandler(svc), req)
+
+			require.Equal(t, tt.expectedStatus, rr.Code, rr.Body.String())
+			if tt.expectedStatus == http.StatusOK {
+				require.NotEmpty(t, rr.Header().Get("ETag"))
+			}
+			svc.AssertExpectations(t)
+		})
+	}
+}
@@ -0,0 +1,59 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/Atmosfr/user-service/internal/middleware"
+	"github.com/Atmosfr/user-service/internal/models"
+	"github.com/Atmosfr/user-service/internal/service"
+)
+
+type ListRolesResponse struct {
+	Roles []*models.Role `json:"roles"`
+}
+
+type PermissionsResponse struct {
+	Role        string               `json:"role"`
+	Permissions models.PermissionSet `json:"permissions"`
+}
+
+func ListRolesHandler(svc service.RBACService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+
+		roles, err := svc.ListRoles(r.Context())
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+			return
+		}
+
+		json.NewEncoder(w).Encode(ListRolesResponse{Roles: roles})
+	}
+}
+
+func MyPermissionsHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+
+		user, ok := middleware.GetUserFromContext(r.Context())
+		if !ok {
+			w.WriteHeader(http.StatusUnauthorized)
+			json.NewEncoder(w).Encode(map[string]string{"error": "unauthorized"})
+			return
+		}
+
+		permissions, _, err := middleware.GetPermissionsFromContext(r.Context())
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+			return
+		}
+		if permissions == nil {
+			permissions = models.PermissionSet{}
+		}
+
+		json.NewEncoder(w).Encode(PermissionsResponse{Role: user.Role, Permissions: permissions})
+	}
+}
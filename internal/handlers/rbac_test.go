@@ -0,0 +1,118 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/Atmosfr/user-service/internal/auth"
+	"github.com/Atmosfr/user-service/internal/middleware"
+	"github.com/Atmosfr/user-service/internal/models"
+	"github.com/Atmosfr/user-service/internal/testutil"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+type mockRBACService struct {
+	mock.Mock
+}
+
+func (m *mockRBACService) Permissions(ctx context.Context, role string) (models.PermissionSet, error) {
+	args := m.Called(ctx, role)
+	return args.Get(0).(models.PermissionSet), args.Error(1)
+}
+
+func (m *mockRBACService) ListRoles(ctx context.Context) ([]*models.Role, error) {
+	args := m.Called(ctx)
+	return args.Get(0).([]*models.Role), args.Error(1)
+}
+
+func TestRequirePermission(t *testing.T) {
+	auth.JwtSecret = []byte("secret")
+	user := &models.User{ID: 7, Role: "support"}
+	token, err := auth.GenerateToken(user, time.Hour, auth.JwtSecret)
+	require.NoError(t, err)
+
+	tests := []struct {
+		name           string
+		required       []string
+		permissions    models.PermissionSet
+		resolveErr     error
+		expectedStatus int
+	}{
+		{name: "granted", required: []string{"users:read"}, permissions: models.PermissionSet{"users:read", "profile:read"}, expectedStatus: http.StatusOK},
+		{name: "all permissions are required", required: []string{"users:read", "users:write"}, permissions: models.PermissionSet{"users:read"}, expectedStatus: http.StatusForbidden},
+		{name: "wildcard", required: []string{"users:write"}, permissions: models.PermissionSet{"*"}, expectedStatus: http.StatusOK},
+		{name: "resolution failure", required: []string{"users:read"}, permissions: models.PermissionSet{}, resolveErr: errors.New("db down"), expectedStatus: http.StatusInternalServerError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := new(testutil.MockUserRepo)
+			repo.On("FindByID", mock.Anything, int64(7)).Return(user, nil)
+			rbac := new(mockRBACService)
+			rbac.On("Permissions", mock.Anything, "support").Return(tt.permissions, tt.resolveErr)
+
+			authn := middleware.NewAuthMiddleware(repo, middleware.WithPermissions(rbac))
+			// two stacked guards and a handler-level check share one resolution
+			handler := authn(middleware.RequirePermission(tt.required...)(middleware.RequirePermission(tt.required[0])(
+				http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+					require.True(t, middleware.HasPermission(r.Context(), tt.required[0]))
+					w.WriteHeader(http.StatusOK)
+				}),
+			)))
+
+			req := httptest.NewRequest(http.MethodGet, "/admin/users", nil)
+			req.Header.Set("Authorization", "Bearer "+token)
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, req)
+
+			require.Equal(t, tt.expectedStatus, w.Code, w.Body.String())
+			rbac.AssertNumberOfCalls(t, "Permissions", 1)
+		})
+	}
+}
+
+func TestRequirePermission_WithoutPermissionsOption(t *testing.T) {
+	auth.JwtSecret = []byte("secret")
+	user := &models.User{ID: 7, Role: "admin"}
+	token, err := auth.GenerateToken(user, time.Hour, auth.JwtSecret)
+	require.NoError(t, err)
+
+	repo := new(testutil.MockUserRepo)
+	repo.On("FindByID", mock.Anything, int64(7)).Return(user, nil)
+
+	req := httptest.NewRequest(http.MethodGet, "/admin/roles", nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	w := httptest.NewRecorder()
+	middleware.NewAuthMiddleware(repo)(middleware.RequirePermission("roles:read")(ListRolesHandler(new(mockRBACService)))).ServeHTTP(w, req)
+
+	require.Equal(t, http.StatusForbidden, w.Code)
+}
+
+func TestMyPermissionsHandler(t *testing.T) {
+	auth.JwtSecret = []byte("secret")
+	user := &models.User{ID: 7, Role: "user"}
+	token, err := auth.GenerateToken(user, time.Hour, auth.JwtSecret)
+	require.NoError(t, err)
+
+	repo := new(testutil.MockUserRepo)
+	repo.On("FindByID", mock.Anything, int64(7)).Return(user, nil)
+	rbac := new(mockRBACService)
+	rbac.On("Permissions", mock.Anything, "user").Return(models.PermissionSet{"profile:read", "profile:write"}, nil)
+
+	req := httptest.NewRequest(http.MethodGet, "/me/permissions", nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	w := httptest.NewRecorder()
+	middleware.NewAuthMiddleware(repo, middleware.WithPermissions(rbac))(MyPermissionsHandler()).ServeHTTP(w, req)
+
+	require.Equal(t, http.StatusOK, w.Code)
+	var resp PermissionsResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	require.Equal(t, "user", resp.Role)
+	require.Equal(t, models.PermissionSet{"profile:read", "profile:write"}, resp.Permissions)
+}
@@ -0,0 +1,447 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"mime"
+	"net/http"
+	"strconv"
+
+	"github.com/Atmosfr/user-service/internal/middleware"
+	"github.com/Atmosfr/user-service/internal/repository"
+	"github.com/Atmosfr/user-service/internal/scim"
+	"github.com/Atmosfr/user-service/internal/service"
+)
+
+const scimBasePath = "/scim/v2"
+
+type CreateSCIMTenantRequest struct {
+	Name string `json:"name"`
+}
+
+type CreateSCIMTenantResponse struct {
+	ID    int64  `json:"id"`
+	Name  string `json:"name"`
+	Token string `json:"token"`
+}
+
+func writeSCIM(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", scim.ContentType)
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+func writeSCIMError(w http.ResponseWriter, err error) {
+	var scimErr *scim.Error
+	switch {
+	case errors.As(err, &scimErr):
+	case scim.ScimType(err) != "":
+		scimErr = scim.NewError(http.StatusBadRequest, scim.ScimType(err), err.Error())
+	case errors.Is(err, repository.ErrUserNotFound),
+		errors.Is(err, repository.ErrGroupNotFound):
+		scimErr = scim.NewError(http.StatusNotFound, "", err.Error())
+	case errors.Is(err, repository.ErrEmailAlreadyExists),
+		errors.Is(err, repository.ErrUsernameAlreadyExists),
+		errors.Is(err, repository.ErrExternalIDAlreadyExists),
+		errors.Is(err, repository.ErrGroupAlreadyExists):
+		scimErr = scim.NewError(http.StatusConflict, "uniqueness", err.Error())
+	case errors.Is(err, repository.ErrUserOwnsServiceAccounts),
+		errors.Is(err, repository.ErrLastOrganizationOwner):
+		scimErr = scim.NewError(http.StatusConflict, "", err.Error())
+	case errors.Is(err, repository.ErrInvalidGroupMember):
+		scimErr = scim.NewError(http.StatusBadRequest, "invalidValue", err.Error())
+	default:
+		slog.Error("scim request failed", "err", err)
+		scimErr = scim.NewError(http.StatusInternalServerError, "", "internal server error")
+	}
+	writeSCIM(w, scimErr.StatusCode(), scimErr)
+}
+
+func decodeSCIM(r *http.Request, v any) error {
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if mediaType != scim.ContentType && mediaType != "application/json" {
+		return scim.NewError(http.StatusUnsupportedMediaType, "", "Content-Type must be "+scim.ContentType)
+	}
+	if err := json.NewDecoder(r.Body).Decode(v); err != nil {
+		return scim.NewError(http.StatusBadRequest, "invalidSyntax", err.Error())
+	}
+	return nil
+}
+
+func scimBaseURL(r *http.Request) string {
+	scheme := "http"
+	if r.TLS != nil {
+		scheme = "https"
+	}
+	if proto := r.Header.Get("X-Forwarded-Proto"); proto != "" {
+		scheme = proto
+	}
+	return scheme + "://" + r.Host + scimBasePath
+}
+
+func scimTenantID(w http.ResponseWriter, r *http.Request) (int64, bool) {
+	tenant, ok := middleware.GetSCIMTenantFromContext(r.Context())
+	if !ok {
+		writeSCIM(w, http.StatusUnauthorized, scim.NewError(http.StatusUnauthorized, "", "authorization failure"))
+		return 0, false
+	}
+	return tenant.ID, true
+}
+
+func scimPaging(r *http.Request) (startIndex, count int) {
+	startIndex, count = 1, scim.DefaultCount
+	if v, err := strconv.Atoi(r.URL.Query().Get("startIndex")); err == nil {
+		startIndex = max(v, 1)
+	}
+	if v, err := strconv.Atoi(r.URL.Query().Get("count")); err == nil {
+		count = min(max(v, 0), scim.MaxCount)
+	}
+	return startIndex, count
+}
+
+func withUserLocation(r *http.Request, u *scim.User) *scim.User {
+	if u.Meta != nil {
+		u.Meta.Location = scimBaseURL(r) + "/Users/" + u.ID
+	}
+	return u
+}
+
+func withGroupLocation(r *http.Request, g *scim.Group) *scim.Group {
+	base := scimBaseURL(r)
+	if g.Meta != nil {
+		g.Meta.Location = base + "/Groups/" + g.ID
+	}
+	for i := range g.Members {
+		g.Members[i].Ref = base + "/Users/" + g.Members[i].Value
+	}
+	return g
+}
+
+func CreateSCIMTenantHandler(svc service.SCIMService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		defer r.Body.Close()
+		w.Header().Set("Content-Type", "application/json")
+
+		var req CreateSCIMTenantRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"error": "Invalid request payload"})
+			return
+		}
+
+		tenant, token, err := svc.CreateTenant(r.Context(), req.Name)
+		if err != nil {
+			status := http.StatusInternalServerError
+			if errors.Is(err, scim.ErrInvalidValue) {
+				status = http.StatusBadRequest
+			}
+			w.WriteHeader(status)
+			json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+			return
+		}
+
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(CreateSCIMTenantResponse{ID: tenant.ID, Name: tenant.Name, Token: token})
+	}
+}
+
+func SCIMServiceProviderConfigHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		writeSCIM(w, http.StatusOK, scim.ServiceProviderConfig(scimBaseURL(r)))
+	}
+}
+
+func SCIMResourceTypesHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		types := scim.ResourceTypes(scimBaseURL(r))
+		if id := r.PathValue("id"); id != "" {
+			for _, t := range types {
+				if t["id"] == id {
+					writeSCIM(w, http.StatusOK, t)
+					return
+				}
+			}
+			writeSCIM(w, http.StatusNotFound, scim.NewError(http.StatusNotFound, "", "resource type not found"))
+			return
+		}
+
+		resources := make([]any, len(types))
+		for i, t := range types {
+			resources[i] = t
+		}
+		writeSCIM(w, http.StatusOK, scim.NewListResponse(resources, len(resources), 1))
+	}
+}
+
+func SCIMSchemasHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		schemas := scim.Schemas(scimBaseURL(r))
+		if id := r.PathValue("id"); id != "" {
+			for _, s := range schemas {
+				if s["id"] == id {
+					writeSCIM(w, http.StatusOK, s)
+					return
+				}
+			}
+			writeSCIM(w, http.StatusNotFound, scim.NewError(http.StatusNotFound, "", "schema not found"))
+			return
+		}
+
+		resources := make([]any, len(schemas))
+		for i, s := range schemas {
+			resources[i] = s
+		}
+		writeSCIM(w, http.StatusOK, scim.NewListResponse(resources, len(resources), 1))
+	}
+}
+
+func SCIMListUsersHandler(svc service.SCIMService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		tenantID, ok := scimTenantID(w, r)
+		if !ok {
+			return
+		}
+
+		startIndex, count := scimPaging(r)
+		users, total, err := svc.ListUsers(r.Context(), tenantID, r.URL.Query().Get("filter"), startIndex, count)
+		if err != nil {
+			writeSCIMError(w, err)
+			return
+		}
+
+		resources := make([]any, len(users))
+		for i, u := range users {
+			resources[i] = withUserLocation(r, u)
+		}
+		writeSCIM(w, http.StatusOK, scim.NewListResponse(resources, total, startIndex))
+	}
+}
+
+func SCIMGetUserHandler(svc service.SCIMService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		tenantID, ok := scimTenantID(w, r)
+		if !ok {
+			return
+		}
+
+		user, err := svc.GetUser(r.Context(), tenantID, r.PathValue("id"))
+		if err != nil {
+			writeSCIMError(w, err)
+			return
+		}
+		writeSCIM(w, http.StatusOK, withUserLocation(r, user))
+	}
+}
+
+func SCIMCreateUserHandler(svc service.SCIMService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		defer r.Body.Close()
+		tenantID, ok := scimTenantID(w, r)
+		if !ok {
+			return
+		}
+
+		var req scim.User
+		if err := decodeSCIM(r, &req); err != nil {
+			writeSCIMError(w, err)
+			return
+		}
+
+		user, err := svc.CreateUser(r.Context(), tenantID, &req)
+		if err != nil {
+			writeSCIMError(w, err)
+			return
+		}
+
+		user = withUserLocation(r, user)
+		w.Header().Set("Location", user.Meta.Location)
+		writeSCIM(w, http.StatusCreated, user)
+	}
+}
+
+func SCIMReplaceUserHandler(svc service.SCIMService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		defer r.Body.Close()
+		tenantID, ok := scimTenantID(w, r)
+		if !ok {
+			return
+		}
+
+		var req scim.User
+		if err := decodeSCIM(r, &req); err != nil {
+			writeSCIMError(w, err)
+			return
+		}
+
+		user, err := svc.ReplaceUser(r.Context(), tenantID, r.PathValue("id"), &req)
+		if err != nil {
+			writeSCIMError(w, err)
+			return
+		}
+		writeSCIM(w, http.StatusOK, withUserLocation(r, user))
+	}
+}
+
+func SCIMPatchUserHandler(svc service.SCIMService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		defer r.Body.Close()
+		tenantID, ok := scimTenantID(w, r)
+		if !ok {
+			return
+		}
+
+		var req scim.PatchRequest
+		if err := decodeSCIM(r, &req); err != nil {
+			writeSCIMError(w, err)
+			return
+		}
+
+		user, err := svc.PatchUser(r.Context(), tenantID, r.PathValue("id"), req.Operations)
+		if err != nil {
+			writeSCIMError(w, err)
+			return
+		}
+		writeSCIM(w, http.StatusOK, withUserLocation(r, user))
+	}
+}
+
+func SCIMDeleteUserHandler(svc service.SCIMService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		tenantID, ok := scimTenantID(w, r)
+		if !ok {
+			return
+		}
+
+		if err := svc.DeleteUser(r.Context(), tenantID, r.PathValue("id")); err != nil {
+			writeSCIMError(w, err)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+func SCIMListGroupsHandler(svc service.SCIMService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		tenantID, ok := scimTenantID(w, r)
+		if !ok {
+			return
+		}
+
+		startIndex, count := scimPaging(r)
+		groups, total, err := svc.ListGroups(r.Context(), tenantID, r.URL.Query().Get("filter"), startIndex, count)
+		if err != nil {
+			writeSCIMError(w, err)
+			return
+		}
+
+		resources := make([]any, len(groups))
+		for i, g := range groups {
+			resources[i] = withGroupLocation(r, g)
+		}
+		writeSCIM(w, http.StatusOK, scim.NewListResponse(resources, total, startIndex))
+	}
+}
+
+func SCIMGetGroupHandler(svc service.SCIMService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		tenantID, ok := scimTenantID(w, r)
+		if !ok {
+			return
+		}
+
+		group, err := svc.GetGroup(r.Context(), tenantID, r.PathValue("id"))
+		if err != nil {
+			writeSCIMError(w, err)
+			return
+		}
+		writeSCIM(w, http.StatusOK, withGroupLocation(r, group))
+	}
+}
+
+func SCIMCreateGroupHandler(svc service.SCIMService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		defer r.Body.Close()
+		tenantID, ok := scimTenantID(w, r)
+		if !ok {
+			return
+		}
+
+		var req scim.Group
+		if err := decodeSCIM(r, &req); err != nil {
+			writeSCIMError(w, err)
+			return
+		}
+
+		group, err := svc.CreateGroup(r.Context(), tenantID, &req)
+		if err != nil {
+			writeSCIMError(w, err)
+			return
+		}
+
+		group = withGroupLocation(r, group)
+		w.Header().Set("Location", group.Meta.Location)
+		writeSCIM(w, http.StatusCreated, group)
+	}
+}
+
+func SCIMReplaceGroupHandler(svc service.SCIMService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		defer r.Body.Close()
+		tenantID, ok := scimTenantID(w, r)
+		if !ok {
+			return
+		}
+
+		var req scim.Group
+		if err := decodeSCIM(r, &req); err != nil {
+			writeSCIMError(w, err)
+			return
+		}
+
+		group, err := svc.ReplaceGroup(r.Context(), tenantID, r.PathValue("id"), &req)
+		if err != nil {
+			writeSCIMError(w, err)
+			return
+		}
+		writeSCIM(w, http.StatusOK, withGroupLocation(r, group))
+	}
+}
+
+func SCIMPatchGroupHandler(svc service.SCIMService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		defer r.Body.Close()
+		tenantID, ok := scimTenantID(w, r)
+		if !ok {
+			return
+		}
+
+		var req scim.PatchRequest
+		if err := decodeSCIM(r, &req); err != nil {
+			writeSCIMError(w, err)
+			return
+		}
+
+		group, err := svc.PatchGroup(r.Context(), tenantID, r.PathValue("id"), req.Operations)
+		if err != nil {
+			writeSCIMError(w, err)
+			return
+		}
+		writeSCIM(w, http.StatusOK, withGroupLocation(r, group))
+	}
+}
+
+func SCIMDeleteGroupHandler(svc service.SCIMService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		tenantID, ok := scimTenantID(w, r)
+		if !ok {
+			return
+		}
+
+		if err := svc.DeleteGroup(r.Context(), tenantID, r.PathValue("id")); err != nil {
+			writeSCIMError(w, err)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
@@ -0,0 +1,264 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/Atmosfr/user-service/internal/middleware"
+	"github.com/Atmosfr/user-service/internal/models"
+	"github.com/Atmosfr/user-service/internal/repository"
+	"github.com/Atmosfr/user-service/internal/scim"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+type mockSCIMService struct {
+	mock.Mock
+}
+
+func (m *mockSCIMService) CreateTenant(ctx context.Context, name string) (*models.SCIMTenant, string, error) {
+	args := m.Called(ctx, name)
+	return args.Get(0).(*models.SCIMTenant), args.String(1), args.Error(2)
+}
+
+func (m *mockSCIMService) Authenticate(ctx context.Context, token string) (*models.SCIMTenant, error) {
+	args := m.Called(ctx, token)
+	return args.Get(0).(*models.SCIMTenant), args.Error(1)
+}
+
+func (m *mockSCIMService) ListUsers(ctx context.Context, tenantID int64, filter string, startIndex, count int) ([]*scim.User, int, error) {
+	args := m.Called(ctx, tenantID, filter, startIndex, count)
+	return args.Get(0).([]*scim.User), args.Int(1), args.Error(2)
+}
+
+func (m *mockSCIMService) GetUser(ctx context.Context, tenantID int64, id string) (*scim.User, error) {
+	args := m.Called(ctx, tenantID, id)
+	return args.Get(0).(*scim.User), args.Error(1)
+}
+
+func (m *mockSCIMService) CreateUser(ctx context.Context, tenantID int64, user *scim.User) (*scim.User, error) {
+	args := m.Called(ctx, tenantID, user)
+	return args.Get(0).(*scim.User), args.Error(1)
+}
+
+func (m *mockSCIMService) ReplaceUser(ctx context.Context, tenantID int64, id string, user *scim.User) (*scim.User, error) {
+	args := m.Called(ctx, tenantID, id, user)
+	return args.Get(0).(*scim.User), args.Error(1)
+}
+
+func (m *mockSCIMService) PatchUser(ctx context.Context, tenantID int64, id string, ops []scim.PatchOperation) (*scim.User, error) {
+	args := m.Called(ctx, tenantID, id, ops)
+	return args.Get(0).(*scim.User), args.Error(1)
+}
+
+func (m *mockSCIMService) DeleteUser(ctx context.Context, tenantID int64, id string) error {
+	return m.Called(ctx, tenantID, id).Error(0)
+}
+
+func (m *mockSCIMService) ListGroups(ctx context.Context, tenantID int64, filter string, startIndex, count int) ([]*scim.Group, int, error) {
+	args := m.Called(ctx, tenantID, filter, startIndex, count)
+	return args.Get(0).([]*scim.Group), args.Int(1), args.Error(2)
+}
+
+func (m *mockSCIMService) GetGroup(ctx context.Context, tenantID int64, id string) (*scim.Group, error) {
+	args := m.Called(ctx, tenantID, id)
+	return args.Get(0).(*scim.Group), args.Error(1)
+}
+
+func (m *mockSCIMService) CreateGroup(ctx context.Context, tenantID int64, group *scim.Group) (*scim.Group, error) {
+	args := m.Called(ctx, tenantID, group)
+	return args.Get(0).(*scim.Group), args.Error(1)
+}
+
+func (m *mockSCIMService) ReplaceGroup(ctx context.Context, tenantID int64, id string, group *scim.Group) (*scim.Group, error) {
+	args := m.Called(ctx, tenantID, id, group)
+	return args.Get(0).(*scim.Group), args.Error(1)
+}
+
+func (m *mockSCIMService) PatchGroup(ctx context.Context, tenantID int64, id string, ops []scim.PatchOperation) (*scim.Group, error) {
+	args := m.Called(ctx, tenantID, id, ops)
+	return args.Get(0).(*scim.Group), args.Error(1)
+}
+
+func (m *mockSCIMService) DeleteGroup(ctx context.Context, tenantID int64, id string) error {
+	return m.Called(ctx, tenantID, id).Error(0)
+}
+
+func newSCIMMux(svc *mockSCIMService) http.Handler {
+	auth := middleware.NewSCIMAuthMiddleware(svc)
+	mux := http.NewServeMux()
+	mux.Handle("GET /scim/v2/Users", auth(SCIMListUsersHandler(svc)))
+	mux.Handle("POST /scim/v2/Users", auth(SCIMCreateUserHandler(svc)))
+	mux.Handle("GET /scim/v2/Users/{id}", auth(SCIMGetUserHandler(svc)))
+	mux.Handle("PATCH /scim/v2/Users/{id}", auth(SCIMPatchUserHandler(svc)))
+	mux.Handle("GET /scim/v2/ServiceProviderConfig", SCIMServiceProviderConfigHandler())
+	return mux
+}
+
+func TestSCIMHandlers(t *testing.T) {
+	tenant := &models.SCIMTenant{ID: 3, Name: "acme"}
+	active := false
+
+	tests := []struct {
+		name           string
+		method         string
+		target         string
+		token          string
+		contentType    string
+		body           string
+		setupMock      func(svc *mockSCIMService)
+		expectedStatus int
+		wantScimType   string
+		check          func(t *testing.T, body map[string]any)
+	}{
+		{
+			name:   "missing token",
+			method: http.MethodGet,
+			target: "/scim/v2/Users",
+			setupMock: func(svc *mockSCIMService) {
+			},
+			expectedStatus: http.StatusUnauthorized,
+		},
+		{
+			name:   "unknown token",
+			method: http.MethodGet,
+			target: "/scim/v2/Users",
+			token:  "scim_unknown",
+			setupMock: func(svc *mockSCIMService) {
+				svc.On("Authenticate", mock.Anything, "scim_unknown").Return((*models.SCIMTenant)(nil), repository.ErrTenantNotFound)
+			},
+			expectedStatus: http.StatusUnauthorized,
+		},
+		{
+			name:   "list users with filter and paging",
+			method: http.MethodGet,
+			target: "/scim/v2/Users?filter=" + strings.ReplaceAll(`userName eq "bjensen"`, " ", "%20") + "&startIndex=11&count=500",
+			token:  "scim_valid",
+			setupMock: func(svc *mockSCIMService) {
+				svc.On("ListUsers", mock.Anything, int64(3), `userName eq "bjensen"`, 11, scim.MaxCount).
+					Return([]*scim.User{{ID: "42", UserName: "bjensen", Meta: &scim.Meta{ResourceType: "User"}}}, 11, nil)
+			},
+			expectedStatus: http.StatusOK,
+			check: func(t *testing.T, body map[string]any) {
+				require.EqualValues(t, 11, body["totalResults"])
+				require.EqualValues(t, 11, body["startIndex"])
+				require.EqualValues(t, 1, body["itemsPerPage"])
+				resource := body["Resources"].([]any)[0].(map[string]any)
+				require.Equal(t, "http://example.com/scim/v2/Users/42", resource["meta"].(map[string]any)["location"])
+			},
+		},
+		{
+			name:   "invalid filter",
+			method: http.MethodGet,
+			target: "/scim/v2/Users?filter=bogus",
+			token:  "scim_valid",
+			setupMock: func(svc *mockSCIMService) {
+				svc.On("ListUsers", mock.Anything, int64(3), "bogus", 1, scim.DefaultCount).
+					Return([]*scim.User(nil), 0, fmt.Errorf("%w: expected operator", scim.ErrInvalidFilter))
+			},
+			expectedStatus: http.StatusBadRequest,
+			wantScimType:   "invalidFilter",
+		},
+		{
+			name:   "user not found",
+			method: http.MethodGet,
+			target: "/scim/v2/Users/9",
+			token:  "scim_valid",
+			setupMock: func(svc *mockSCIMService) {
+				svc.On("GetUser", mock.Anything, int64(3), "9").Return((*scim.User)(nil), repository.ErrUserNotFound)
+			},
+			expectedStatus: http.StatusNotFound,
+		},
+		{
+			name:        "create duplicate user",
+			method:      http.MethodPost,
+			target:      "/scim/v2/Users",
+			token:       "scim_valid",
+			contentType: scim.ContentType,
+			body:        `{"schemas": ["urn:ietf:params:scim:schemas:core:2.0:User"], "userName": "bjensen@example.com"}`,
+			setupMock: func(svc *mockSCIMService) {
+				svc.On("CreateUser", mock.Anything, int64(3), mock.AnythingOfType("*scim.User")).Return((*scim.User)(nil), repository.ErrUsernameAlreadyExists)
+			},
+			expectedStatus: http.StatusConflict,
+			wantScimType:   "uniqueness",
+		},
+		{
+			name:           "create with wrong content type",
+			method:         http.MethodPost,
+			target:         "/scim/v2/Users",
+			token:          "scim_valid",
+			contentType:    "text/plain",
+			body:           `{}`,
+			setupMock:      func(svc *mockSCIMService) {},
+			expectedStatus: http.StatusUnsupportedMediaType,
+		},
+		{
+			name:        "patch deactivates user",
+			method:      http.MethodPatch,
+			target:      "/scim/v2/Users/42",
+			token:       "scim_valid",
+			contentType: "application/scim+json; charset=utf-8",
+			body:        `{"schemas": ["urn:ietf:params:scim:api:messages:2.0:PatchOp"], "Operations": [{"op": "replace", "path": "active", "value": false}]}`,
+			setupMock: func(svc *mockSCIMService) {
+				svc.On("PatchUser", mock.Anything, int64(3), "42", mock.Anything).
+					Return(&scim.User{ID: "42", UserName: "bjensen", Active: &active, Meta: &scim.Meta{ResourceType: "User"}}, nil)
+			},
+			expectedStatus: http.StatusOK,
+			check: func(t *testing.T, body map[string]any) {
+				require.Equal(t, false, body["active"])
+			},
+		},
+		{
+			name:           "discovery does not require a token",
+			method:         http.MethodGet,
+			target:         "/scim/v2/ServiceProviderConfig",
+			setupMock:      func(svc *mockSCIMService) {},
+			expectedStatus: http.StatusOK,
+			check: func(t *testing.T, body map[string]any) {
+				require.Equal(t, true, body["patch"].(map[string]any)["supported"])
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc := &mockSCIMService{}
+			svc.On("Authenticate", mock.Anything, "scim_valid").Return(tenant, nil).Maybe()
+			tt.setupMock(svc)
+
+			req := httptest.NewRequest(tt.method, "http://example.com"+tt.target, strings.NewReader(tt.body))
+			if tt.token != "" {
+				req.Header.Set("Authorization", "Bearer "+tt.token)
+			}
+			if tt.contentType != "" {
+				req.Header.Set("Content-Type", tt.contentType)
+			}
+
+			rr := httptest.NewRecorder()
+			newSCIMMux(svc).ServeHTTP(rr, req)
+
+			require.Equal(t, tt.expectedStatus, rr.Code)
+			require.Equal(t, scim.ContentType, rr.Header().Get("Content-Type"))
+
+			var body map[string]any
+			require.NoError(t, json.NewDecoder(rr.Body).Decode(&body))
+			if rr.Code >= 400 {
+				require.Equal(t, []any{scim.SchemaError}, body["schemas"])
+				require.Equal(t, fmt.Sprint(tt.expectedStatus), body["status"])
+				if tt.wantScimType != "" {
+					require.Equal(t, tt.wantScimType, body["scimType"])
+				}
+			}
+			if tt.check != nil {
+				tt.check(t, body)
+			}
+
+			svc.AssertExpectations(t)
+		})
+	}
+}
@@ -0,0 +1,309 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/Atmosfr/user-service/internal/middleware"
+	"github.com/Atmosfr/user-service/internal/models"
+	"github.com/Atmosfr/user-service/internal/repository"
+	"github.com/Atmosfr/user-service/internal/service"
+	"github.com/Atmosfr/user-service/internal/validation"
+)
+
+type CreateServiceAccountRequest struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	// OwnerID defaults to the calling admin.
+	OwnerID int64 `json:"owner_id"`
+}
+
+type ListServiceAccountsResponse struct {
+	ServiceAccounts []*models.ServiceAccount `json:"service_accounts"`
+}
+
+type CreateServiceAccountKeyRequest struct {
+	Type      string     `json:"type"`
+	Name      string     `json:"name"`
+	PublicKey string     `json:"public_key"`
+	ExpiresAt *time.Time `json:"expires_at"`
+}
+
+type CreateServiceAccountKeyResponse struct {
+	*models.ServiceAccountKey
+	Key string `json:"key,omitempty"`
+}
+
+type ListServiceAccountKeysResponse struct {
+	Keys []*models.ServiceAccountKey `json:"keys"`
+}
+
+func pathID(r *http.Request, name string) (int64, bool) {
+	id, err := strconv.ParseInt(r.PathValue(name), 10, 64)
+	return id, err == nil && id > 0
+}
+
+func serviceAccountErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, validation.ErrInvalidServiceAccountName),
+		errors.Is(err, validation.ErrInvalidServiceAccountDescription),
+		errors.Is(err, service.ErrInvalidKeyName),
+		errors.Is(err, service.ErrInvalidPublicKey),
+		errors.Is(err, service.ErrInvalidTokenExpiry),
+		errors.Is(err, repository.ErrUserNotFound):
+		return http.StatusBadRequest
+	case errors.Is(err, repository.ErrServiceAccountExists),
+		errors.Is(err, repository.ErrKeyNameAlreadyExists):
+		return http.StatusConflict
+	case errors.Is(err, repository.ErrServiceAccountNotFound),
+		errors.Is(err, repository.ErrKeyNotFound):
+		return http.StatusNotFound
+	}
+	return http.StatusInternalServerError
+}
+
+func writeServiceAccountError(w http.ResponseWriter, err error) {
+	w.WriteHeader(serviceAccountErrorStatus(err))
+	json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+}
+
+func ListServiceAccountsHandler(svc service.ServiceAccountService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+
+		accounts, err := svc.List(r.Context())
+		if err != nil {
+			writeServiceAccountError(w, err)
+			return
+		}
+
+		json.NewEncoder(w).Encode(ListServiceAccountsResponse{ServiceAccounts: accounts})
+	}
+}
+
+func CreateServiceAccountHandler(svc service.ServiceAccountService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		defer r.Body.Close()
+		w.Header().Set("Content-Type", "application/json")
+
+		user, ok := middleware.GetUserFromContext(r.Context())
+		if !ok {
+			w.WriteHeader(http.StatusUnauthorized)
+			json.NewEncoder(w).Encode(map[string]string{"error": "unauthorized"})
+			return
+		}
+
+		var req CreateServiceAccountRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"error": "Invalid request payload"})
+			return
+		}
+		if req.OwnerID == 0 {
+			req.OwnerID = user.ID
+		}
+
+		sa, err := svc.Create(r.Context(), req.Name, req.Description, req.OwnerID)
+		if err != nil {
+			writeServiceAccountError(w, err)
+			return
+		}
+
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(sa)
+	}
+}
+
+func GetServiceAccountHandler(svc service.ServiceAccountService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+
+		id, ok := pathID(r, "id")
+		if !ok {
+			writeServiceAccountError(w, repository.ErrServiceAccountNotFound)
+			return
+		}
+
+		sa, err := svc.Get(r.Context(), id)
+		if err != nil {
+			writeServiceAccountError(w, err)
+			return
+		}
+
+		json.NewEncoder(w).Encode(sa)
+	}
+}
+
+func DeleteServiceAccountHandler(svc service.ServiceAccountService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+
+		id, ok := pathID(r, "id")
+		if !ok {
+			writeServiceAccountError(w, repository.ErrServiceAccountNotFound)
+			return
+		}
+
+		if err := svc.Delete(r.Context(), id); err != nil {
+			writeServiceAccountError(w, err)
+			return
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+func ListServiceAccountKeysHandler(svc service.ServiceAccountService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+
+		id, ok := pathID(r, "id")
+		if !ok {
+			writeServiceAccountError(w, repository.ErrServiceAccountNotFound)
+			return
+		}
+
+		keys, err := svc.ListKeys(r.Context(), id)
+		if err != nil {
+			writeServiceAccountError(w, err)
+			return
+		}
+
+		json.NewEncoder(w).Encode(ListServiceAccountKeysResponse{Keys: keys})
+	}
+}
+
+func CreateServiceAccountKeyHandler(svc service.ServiceAccountService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		defer r.Body.Close()
+		w.Header().Set("Content-Type", "application/json")
+
+		id, ok := pathID(r, "id")
+		if !ok {
+			writeServiceAccountError(w, repository.ErrServiceAccountNotFound)
+			return
+		}
+
+		var req CreateServiceAccountKeyRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"error": "Invalid request payload"})
+			return
+		}
+
+		var resp CreateServiceAccountKeyResponse
+		var err error
+		switch req.Type {
+		case models.ServiceAccountKeyTypeAPIKey:
+			resp.ServiceAccountKey, resp.Key, err = svc.CreateAPIKey(r.Context(), id, req.Name, req.ExpiresAt)
+		case models.ServiceAccountKeyTypePublicKey:
+			resp.ServiceAccountKey, err = svc.AddPublicKey(r.Context(), id, req.Name, req.PublicKey, req.ExpiresAt)
+		default:
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"error": "type must be api_key or public_key"})
+			return
+		}
+		if err != nil {
+			writeServiceAccountError(w, err)
+			return
+		}
+
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(resp)
+	}
+}
+
+func DeleteServiceAccountKeyHandler(svc service.ServiceAccountService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+
+		id, ok := pathID(r, "id")
+		if !ok {
+			writeServiceAccountError(w, repository.ErrServiceAccountNotFound)
+			return
+		}
+		keyID, ok := pathID(r, "keyID")
+		if !ok {
+			writeServiceAccountError(w, repository.ErrKeyNotFound)
+			return
+		}
+
+		if err := svc.RevokeKey(r.Context(), id, keyID); err != nil {
+			writeServiceAccountError(w, err)
+			return
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// ServiceAccountMeHandler lets a service account check which principal its credentials resolve to.
+func ServiceAccountMeHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+
+		sa, ok := middleware.GetServiceAccountFromContext(r.Context())
+		if !ok {
+			w.WriteHeader(http.StatusForbidden)
+			json.NewEncoder(w).Encode(map[string]string{"error": "endpoint is only available to service accounts"})
+			return
+		}
+
+		json.NewEncoder(w).Encode(sa)
+	}
+}
+
+func writeOAuthError(w http.ResponseWriter, status int, code, description string) {
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]string{"error": code, "error_description": description})
+}
+
+// ServiceAccountTokenHandler implements the client_credentials grant with a JWT client
+// assertion (RFC 7523). When audience is empty the token endpoint URL is expected.
+func ServiceAccountTokenHandler(svc service.ServiceAccountService, audience string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		defer r.Body.Close()
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Cache-Control", "no-store")
+
+		if err := r.ParseForm(); err != nil {
+			writeOAuthError(w, http.StatusBadRequest, "invalid_request", "malformed form body")
+			return
+		}
+		if r.PostForm.Get("grant_type") != "client_credentials" {
+			writeOAuthError(w, http.StatusBadRequest, "unsupported_grant_type", "only client_credentials is supported")
+			return
+		}
+		if r.PostForm.Get("client_assertion_type") != service.ClientAssertionType || r.PostForm.Get("client_assertion") == "" {
+			writeOAuthError(w, http.StatusBadRequest, "invalid_request", "a jwt-bearer client_assertion is required")
+			return
+		}
+
+		aud := audience
+		if aud == "" {
+			scheme := "http"
+			if r.TLS != nil {
+				scheme = "https"
+			}
+			if proto := r.Header.Get("X-Forwarded-Proto"); proto != "" {
+				scheme = proto
+			}
+			aud = scheme + "://" + r.Host + r.URL.Path
+		}
+
+		resp, err := svc.ExchangeAssertion(r.Context(), r.PostForm.Get("client_assertion"), aud)
+		if err != nil {
+			if errors.Is(err, service.ErrInvalidAssertion) || errors.Is(err, repository.ErrServiceAccountNotFound) {
+				writeOAuthError(w, http.StatusUnauthorized, "invalid_client", "client assertion rejected")
+				return
+			}
+			writeOAuthError(w, http.StatusInternalServerError, "server_error", err.Error())
+			return
+		}
+
+		json.NewEncoder(w).Encode(resp)
+	}
+}
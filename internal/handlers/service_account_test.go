@@ -0,0 +1,232 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Atmosfr/user-service/internal/auth"
+	"github.com/Atmosfr/user-service/internal/middleware"
+	"github.com/Atmosfr/user-service/internal/models"
+	"github.com/Atmosfr/user-service/internal/repository"
+	"github.com/Atmosfr/user-service/internal/service"
+	"github.com/Atmosfr/user-service/internal/testutil"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+type mockServiceAccountService struct {
+	mock.Mock
+}
+
+func (m *mockServiceAccountService) Create(ctx context.Context, name, description string, ownerID int64) (*models.ServiceAccount, error) {
+	args := m.Called(ctx, name, description, ownerID)
+	return args.Get(0).(*models.ServiceAccount), args.Error(1)
+}
+
+func (m *mockServiceAccountService) List(ctx context.Context) ([]*models.ServiceAccount, error) {
+	args := m.Called(ctx)
+	return args.Get(0).([]*models.ServiceAccount), args.Error(1)
+}
+
+func (m *mockServiceAccountService) Get(ctx context.Context, id int64) (*models.ServiceAccount, error) {
+	args := m.Called(ctx, id)
+	return args.Get(0).(*models.ServiceAccount), args.Error(1)
+}
+
+func (m *mockServiceAccountService) Delete(ctx context.Context, id int64) error {
+	return m.Called(ctx, id).Error(0)
+}
+
+func (m *mockServiceAccountService) CreateAPIKey(ctx context.Context, serviceAccountID int64, name string, expiresAt *time.Time) (*models.ServiceAccountKey, string, error) {
+	args := m.Called(ctx, serviceAccountID, name, expiresAt)
+	return args.Get(0).(*models.ServiceAccountKey), args.String(1), args.Error(2)
+}
+
+func (m *mockServiceAccountService) AddPublicKey(ctx context.Context, serviceAccountID int64, name, publicKeyPEM string, expiresAt *time.Time) (*models.ServiceAccountKey, error) {
+	args := m.Called(ctx, serviceAccountID, name, publicKeyPEM, expiresAt)
+	return args.Get(0).(*models.ServiceAccountKey), args.Error(1)
+}
+
+func (m *mockServiceAccountService) ListKeys(ctx context.Context, serviceAccountID int64) ([]*models.ServiceAccountKey, error) {
+	args := m.Called(ctx, serviceAccountID)
+	return args.Get(0).([]*models.ServiceAccountKey), args.Error(1)
+}
+
+func (m *mockServiceAccountService) RevokeKey(ctx context.Context, serviceAccountID, keyID int64) error {
+	return m.Called(ctx, serviceAccountID, keyID).Error(0)
+}
+
+func (m *mockServiceAccountService) AuthenticateAPIKey(ctx context.Context, key string) (*models.ServiceAccount, error) {
+	args := m.Called(ctx, key)
+	return args.Get(0).(*models.ServiceAccount), args.Error(1)
+}
+
+func (m *mockServiceAccountService) ExchangeAssertion(ctx context.Context, assertion, audience string) (*service.ServiceAccountTokenResponse, error) {
+	args := m.Called(ctx, assertion, audience)
+	return args.Get(0).(*service.ServiceAccountTokenResponse), args.Error(1)
+}
+
+func TestServiceAccountTokenHandler(t *testing.T) {
+	tests := []struct {
+		name           string
+		form           url.Values
+		setupMock      func(svc *mockServiceAccountService)
+		expectedStatus int
+		wantError      string
+	}{
+		{
+			name: "valid assertion",
+			form: url.Values{
+				"grant_type":            {"client_credentials"},
+				"client_assertion_type": {service.ClientAssertionType},
+				"client_assertion":      {"signed.jwt"},
+			},
+			setupMock: func(svc *mockServiceAccountService) {
+				svc.On("ExchangeAssertion", mock.Anything, "signed.jwt", "http://users.example.com/oauth/token").
+					Return(&service.ServiceAccountTokenResponse{AccessToken: "access", TokenType: "Bearer", ExpiresIn: 3600}, nil)
+			},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "unsupported grant",
+			form:           url.Values{"grant_type": {"password"}},
+			setupMock:      func(svc *mockServiceAccountService) {},
+			expectedStatus: http.StatusBadRequest,
+			wantError:      "unsupported_grant_type",
+		},
+		{
+			name:           "missing assertion",
+			form:           url.Values{"grant_type": {"client_credentials"}, "client_assertion_type": {service.ClientAssertionType}},
+			setupMock:      func(svc *mockServiceAccountService) {},
+			expectedStatus: http.StatusBadRequest,
+			wantError:      "invalid_request",
+		},
+		{
+			name: "rejected assertion",
+			form: url.Values{
+				"grant_type":            {"client_credentials"},
+				"client_assertion_type": {service.ClientAssertionType},
+				"client_assertion":      {"forged.jwt"},
+			},
+			setupMock: func(svc *mockServiceAccountService) {
+				svc.On("ExchangeAssertion", mock.Anything, "forged.jwt", mock.Anything).
+					Return((*service.ServiceAccountTokenResponse)(nil), service.ErrInvalidAssertion)
+			},
+			expectedStatus: http.StatusUnauthorized,
+			wantError:      "invalid_client",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc := new(mockServiceAccountService)
+			tt.setupMock(svc)
+
+			req := httptest.NewRequest(http.MethodPost, "http://users.example.com/oauth/token", strings.NewReader(tt.form.Encode()))
+			req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+			w := httptest.NewRecorder()
+			ServiceAccountTokenHandler(svc, "").ServeHTTP(w, req)
+
+			require.Equal(t, tt.expectedStatus, w.Code, w.Body.String())
+			require.Equal(t, "no-store", w.Header().Get("Cache-Control"))
+			var body map[string]any
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+			if tt.wantError != "" {
+				require.Equal(t, tt.wantError, body["error"])
+			}
+			svc.AssertExpectations(t)
+		})
+	}
+}
+
+func TestServiceAccountAuthentication(t *testing.T) {
+	auth.JwtSecret = []byte("secret")
+	sa := &models.ServiceAccount{ID: 9, Name: "nightly-cron"}
+	saToken, err := auth.GenerateServiceAccountToken(sa, time.Hour, auth.JwtSecret)
+	require.NoError(t, err)
+	userToken, err := auth.GenerateToken(&models.User{ID: 7, Role: "user"}, time.Hour, auth.JwtSecret)
+	require.NoError(t, err)
+	const apiKey = service.APIKeyPrefix + "cron"
+
+	tests := []struct {
+		name           string
+		bearer         string
+		allowMachines  bool
+		setupMock      func(svc *mockServiceAccountService)
+		expectedStatus int
+	}{
+		{
+			name:          "service account access token",
+			bearer:        saToken,
+			allowMachines: true,
+			setupMock: func(svc *mockServiceAccountService) {
+				svc.On("Get", mock.Anything, int64(9)).Return(sa, nil)
+			},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:          "service account api key",
+			bearer:        apiKey,
+			allowMachines: true,
+			setupMock: func(svc *mockServiceAccountService) {
+				svc.On("AuthenticateAPIKey", mock.Anything, apiKey).Return(sa, nil)
+			},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:          "deleted service account",
+			bearer:        saToken,
+			allowMachines: true,
+			setupMock: func(svc *mockServiceAccountService) {
+				svc.On("Get", mock.Anything, int64(9)).Return((*models.ServiceAccount)(nil), repository.ErrServiceAccountNotFound)
+			},
+			expectedStatus: http.StatusUnauthorized,
+		},
+		{
+			name:           "service account token on a user route",
+			bearer:         saToken,
+			setupMock:      func(svc *mockServiceAccountService) {},
+			expectedStatus: http.StatusUnauthorized,
+		},
+		{
+			name:           "user token on a service account route",
+			bearer:         userToken,
+			allowMachines:  true,
+			setupMock:      func(svc *mockServiceAccountService) {},
+			expectedStatus: http.StatusForbidden,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := new(testutil.MockUserRepo)
+			repo.On("FindByID", mock.Anything, int64(7)).Return(&models.User{ID: 7, Role: "user"}, nil)
+			svc := new(mockServiceAccountService)
+			tt.setupMock(svc)
+
+			var opts []middleware.AuthOption
+			if tt.allowMachines {
+				opts = append(opts, middleware.WithServiceAccounts(svc))
+			}
+
+			req := httptest.NewRequest(http.MethodGet, "/service-accounts/me", nil)
+			req.Header.Set("Authorization", "Bearer "+tt.bearer)
+			w := httptest.NewRecorder()
+			middleware.NewAuthMiddleware(repo, opts...)(ServiceAccountMeHandler()).ServeHTTP(w, req)
+
+			require.Equal(t, tt.expectedStatus, w.Code, w.Body.String())
+			if w.Code == http.StatusOK {
+				var got models.ServiceAccount
+				require.NoError(t, json.Unmarshal(w.Body.Bytes(), &got))
+				require.Equal(t, sa.Name, got.Name)
+			}
+			svc.AssertExpectations(t)
+		})
+	}
+}
@@ -0,0 +1,77 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/Atmosfr/user-service/internal/repository"
+	"github.com/Atmosfr/user-service/internal/service"
+	"github.com/Atmosfr/user-service/internal/settings"
+)
+
+func settingsErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, settings.ErrUnknownSetting),
+		errors.Is(err, settings.ErrInvalidValue):
+		return http.StatusBadRequest
+	case errors.Is(err, repository.ErrUserNotFound):
+		return http.StatusNotFound
+	}
+	return http.StatusInternalServerError
+}
+
+func writeSettingsError(w http.ResponseWriter, err error) {
+	w.WriteHeader(settingsErrorStatus(err))
+	json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+}
+
+// GetSettingsHandler returns every setting of the authenticated user, defaults
+// included.
+func GetSettingsHandler(svc service.SettingsService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+
+		user, ok := requireUser(w, r)
+		if !ok {
+			return
+		}
+
+		values, err := svc.Get(r.Context(), user.ID)
+		if err != nil {
+			writeSettingsError(w, err)
+			return
+		}
+
+		json.NewEncoder(w).Encode(values)
+	}
+}
+
+// UpdateSettingsHandler changes the settings present in the request body, null
+// resets a setting to its default. It responds with every setting.
+func UpdateSettingsHandler(svc service.SettingsService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		defer r.Body.Close()
+		w.Header().Set("Content-Type", "application/json")
+
+		user, ok := requireUser(w, r)
+		if !ok {
+			return
+		}
+
+		var patch map[string]json.RawMessage
+		if err := json.NewDecoder(r.Body).Decode(&patch); err != nil || patch == nil {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"error": "Invalid request payload"})
+			return
+		}
+
+		values, err := svc.Update(r.Context(), user.ID, patch)
+		if err != nil {
+			writeSettingsError(w, err)
+			return
+		}
+
+		json.NewEncoder(w).Encode(values)
+	}
+}
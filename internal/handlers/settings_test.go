@@ -0,0 +1,74 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/Atmosfr/user-service/internal/models"
+	"github.com/Atmosfr/user-service/internal/settings"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+type mockSettingsService struct {
+	mock.Mock
+}
+
+func (m *mockSettingsService) Get(ctx context.Context, userID int64) (settings.Values, error) {
+	args := m.Called(ctx, userID)
+	values, _ := args.Get(0).(settings.Values)
+	return values, args.Error(1)
+}
+
+func (m *mockSettingsService) Update(ctx context.Context, userID int64, patch map[string]json.RawMessage) (settings.Values, error) {
+	args := m.Called(ctx, userID, patch)
+	values, _ := args.Get(0).(settings.Values)
+	return values, args.Error(1)
+}
+
+func (m *mockSettingsService) Resolve(ctx context.Context, userIDs []int64) (map[int64]settings.Values, error) {
+	args := m.Called(ctx, userIDs)
+	resolved, _ := args.Get(0).(map[int64]settings.Values)
+	return resolved, args.Error(1)
+}
+
+func TestSettingsHandlers(t *testing.T) {
+	user := &models.User{ID: 7, Role: "user"}
+	svc := new(mockSettingsService)
+	svc.On("Get", mock.Anything, int64(7)).Return(settings.Values{"theme": "system", "language": "en"}, nil)
+	svc.On("Update", mock.Anything, int64(7), map[string]json.RawMessage{"theme": json.RawMessage(`"dark"`)}).
+		Return(settings.Values{"theme": "dark", "language": "en"}, nil)
+	svc.On("Update", mock.Anything, int64(7), map[string]json.RawMessage{"theme": json.RawMessage(`"neon"`)}).
+		Return(nil, fmt.Errorf("%w: theme", settings.ErrInvalidValue))
+
+	tests := []struct {
+		name           string
+		method         string
+		body           string
+		handler        http.HandlerFunc
+		expectedStatus int
+		expectedTheme  string
+	}{
+		{name: "get", method: http.MethodGet, handler: GetSettingsHandler(svc), expectedStatus: http.StatusOK, expectedTheme: "system"},
+		{name: "partial update", method: http.MethodPut, body: `{"theme":"dark"}`, handler: UpdateSettingsHandler(svc), expectedStatus: http.StatusOK, expectedTheme: "dark"},
+		{name: "invalid value", method: http.MethodPut, body: `{"theme":"neon"}`, handler: UpdateSettingsHandler(svc), expectedStatus: http.StatusBadRequest},
+		{name: "not an object", method: http.MethodPut, body: `["theme"]`, handler: UpdateSettingsHandler(svc), expectedStatus: http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, "/me/settings", bytes.NewBufferString(tt.body))
+			rr := serveAsUser(t, user, tt.handler, req)
+			require.Equal(t, tt.expectedStatus, rr.Code, rr.Body.String())
+			if tt.expectedTheme != "" {
+				var values map[string]any
+				require.NoError(t, json.NewDecoder(rr.Body).Decode(&values))
+				require.Equal(t, tt.expectedTheme, values["theme"])
+			}
+		})
+	}
+}
@@ -0,0 +1,124 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/Atmosfr/user-service/internal/middleware"
+	"github.com/Atmosfr/user-service/internal/models"
+	"github.com/Atmosfr/user-service/internal/repository"
+	"github.com/Atmosfr/user-service/internal/service"
+)
+
+type CreateTokenRequest struct {
+	Name      string     `json:"name"`
+	Scopes    []string   `json:"scopes"`
+	ExpiresAt *time.Time `json:"expires_at"`
+}
+
+type CreateTokenResponse struct {
+	*models.PersonalAccessToken
+	Token string `json:"token"`
+}
+
+type ListTokensResponse struct {
+	Tokens []*models.PersonalAccessToken `json:"tokens"`
+}
+
+func tokenErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, service.ErrInvalidTokenName),
+		errors.Is(err, service.ErrInvalidTokenScope),
+		errors.Is(err, service.ErrNoTokenScopes),
+		errors.Is(err, service.ErrInvalidTokenExpiry):
+		return http.StatusBadRequest
+	case errors.Is(err, repository.ErrTokenNameAlreadyExists):
+		return http.StatusConflict
+	case errors.Is(err, repository.ErrTokenNotFound):
+		return http.StatusNotFound
+	}
+	return http.StatusInternalServerError
+}
+
+func ListTokensHandler(svc service.TokenService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+
+		user, ok := middleware.GetUserFromContext(r.Context())
+		if !ok {
+			w.WriteHeader(http.StatusUnauthorized)
+			json.NewEncoder(w).Encode(map[string]string{"error": "unauthorized"})
+			return
+		}
+
+		tokens, err := svc.ListTokens(r.Context(), user.ID)
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+			return
+		}
+
+		json.NewEncoder(w).Encode(ListTokensResponse{Tokens: tokens})
+	}
+}
+
+func CreateTokenHandler(svc service.TokenService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		defer r.Body.Close()
+		w.Header().Set("Content-Type", "application/json")
+
+		user, ok := middleware.GetUserFromContext(r.Context())
+		if !ok {
+			w.WriteHeader(http.StatusUnauthorized)
+			json.NewEncoder(w).Encode(map[string]string{"error": "unauthorized"})
+			return
+		}
+
+		var req CreateTokenRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"error": "Invalid request payload"})
+			return
+		}
+
+		token, secret, err := svc.CreateToken(r.Context(), user.ID, req.Name, req.Scopes, req.ExpiresAt)
+		if err != nil {
+			w.WriteHeader(tokenErrorStatus(err))
+			json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+			return
+		}
+
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(CreateTokenResponse{PersonalAccessToken: token, Token: secret})
+	}
+}
+
+func DeleteTokenHandler(svc service.TokenService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+
+		user, ok := middleware.GetUserFromContext(r.Context())
+		if !ok {
+			w.WriteHeader(http.StatusUnauthorized)
+			json.NewEncoder(w).Encode(map[string]string{"error": "unauthorized"})
+			return
+		}
+
+		id, ok := pathID(r, "id")
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			json.NewEncoder(w).Encode(map[string]string{"error": repository.ErrTokenNotFound.Error()})
+			return
+		}
+
+		if err := svc.RevokeToken(r.Context(), user.ID, id); err != nil {
+			w.WriteHeader(tokenErrorStatus(err))
+			json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+			return
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
@@ -0,0 +1,214 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Atmosfr/user-service/internal/auth"
+	"github.com/Atmosfr/user-service/internal/middleware"
+	"github.com/Atmosfr/user-service/internal/models"
+	"github.com/Atmosfr/user-service/internal/repository"
+	"github.com/Atmosfr/user-service/internal/service"
+	"github.com/Atmosfr/user-service/internal/testutil"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+type mockTokenService struct {
+	mock.Mock
+}
+
+func (m *mockTokenService) CreateToken(ctx context.Context, userID int64, name string, scopes []string, expiresAt *time.Time) (*models.PersonalAccessToken, string, error) {
+	args := m.Called(ctx, userID, name, scopes, expiresAt)
+	return args.Get(0).(*models.PersonalAccessToken), args.String(1), args.Error(2)
+}
+
+func (m *mockTokenService) ListTokens(ctx context.Context, userID int64) ([]*models.PersonalAccessToken, error) {
+	args := m.Called(ctx, userID)
+	return args.Get(0).([]*models.PersonalAccessToken), args.Error(1)
+}
+
+func (m *mockTokenService) RevokeToken(ctx context.Context, userID, tokenID int64) error {
+	return m.Called(ctx, userID, tokenID).Error(0)
+}
+
+func (m *mockTokenService) Authenticate(ctx context.Context, token string) (*models.PersonalAccessToken, error) {
+	args := m.Called(ctx, token)
+	return args.Get(0).(*models.PersonalAccessToken), args.Error(1)
+}
+
+func newTokenMux(repo *testutil.MockUserRepo, svc *mockTokenService) http.Handler {
+	authn := middleware.NewAuthMiddleware(repo, middleware.WithPersonalAccessTokens(svc))
+	readScope := middleware.RequireScope(service.ScopeUserRead)
+	mux := http.NewServeMux()
+	mux.Handle("GET /me/tokens", authn(readScope(ListTokensHandler(svc))))
+	mux.Handle("POST /me/tokens", authn(middleware.RequireSession(CreateTokenHandler(svc))))
+	mux.Handle("DELETE /me/tokens/{id}", authn(middleware.RequireSession(DeleteTokenHandler(svc))))
+	return mux
+}
+
+func TestTokenHandlers(t *testing.T) {
+	auth.JwtSecret = []byte("secret")
+	user := &models.User{ID: 7, Email: "dev@example.com", Role: "user"}
+	jwtToken, err := auth.GenerateToken(user, time.Hour, auth.JwtSecret)
+	require.NoError(t, err)
+
+	const pat = service.PersonalAccessTokenPrefix + "scripted"
+	created := time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name           string
+		method         string
+		target         string
+		bearer         string
+		body           string
+		setupMock      func(svc *mockTokenService)
+		expectedStatus int
+		check          func(t *testing.T, body map[string]any)
+	}{
+		{
+			name:   "create token with session",
+			method: http.MethodPost,
+			target: "/me/tokens",
+			bearer: jwtToken,
+			body:   `{"name":"ci","scopes":["user:read"],"expires_at":"2027-01-01T00:00:00Z"}`,
+			setupMock: func(svc *mockTokenService) {
+				expires := time.Date(2027, 1, 1, 0, 0, 0, 0, time.UTC)
+				svc.On("CreateToken", mock.Anything, int64(7), "ci", []string{"user:read"}, &expires).
+					Return(&models.PersonalAccessToken{ID: 3, Name: "ci", Prefix: "usp_abcdefgh", Scopes: []string{"user:read"}, ExpiresAt: &expires, CreatedAt: created}, "usp_secret", nil)
+			},
+			expectedStatus: http.StatusCreated,
+			check: func(t *testing.T, body map[string]any) {
+				require.Equal(t, "usp_secret", body["token"])
+				require.Equal(t, "usp_abcdefgh", body["prefix"])
+				require.Equal(t, float64(3), body["id"])
+			},
+		},
+		{
+			name:   "invalid scope is a bad request",
+			method: http.MethodPost,
+			target: "/me/tokens",
+			bearer: jwtToken,
+			body:   `{"name":"ci","scopes":["everything"]}`,
+			setupMock: func(svc *mockTokenService) {
+				svc.On("CreateToken", mock.Anything, int64(7), "ci", []string{"everything"}, (*time.Time)(nil)).
+					Return((*models.PersonalAccessToken)(nil), "", service.ErrInvalidTokenScope)
+			},
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:   "duplicate name conflicts",
+			method: http.MethodPost,
+			target: "/me/tokens",
+			bearer: jwtToken,
+			body:   `{"name":"ci","scopes":["user:read"]}`,
+			setupMock: func(svc *mockTokenService) {
+				svc.On("CreateToken", mock.Anything, int64(7), "ci", []string{"user:read"}, (*time.Time)(nil)).
+					Return((*models.PersonalAccessToken)(nil), "", repository.ErrTokenNameAlreadyExists)
+			},
+			expectedStatus: http.StatusConflict,
+		},
+		{
+			name:   "personal access token cannot mint tokens",
+			method: http.MethodPost,
+			target: "/me/tokens",
+			bearer: pat,
+			body:   `{"name":"ci","scopes":["user:read"]}`,
+			setupMock: func(svc *mockTokenService) {
+				svc.On("Authenticate", mock.Anything, pat).Return(&models.PersonalAccessToken{ID: 1, UserID: 7, Scopes: []string{"user:write"}}, nil)
+			},
+			expectedStatus: http.StatusForbidden,
+		},
+		{
+			name:   "list tokens with a scoped personal access token",
+			method: http.MethodGet,
+			target: "/me/tokens",
+			bearer: pat,
+			setupMock: func(svc *mockTokenService) {
+				svc.On("Authenticate", mock.Anything, pat).Return(&models.PersonalAccessToken{ID: 1, UserID: 7, Scopes: []string{"user:read"}}, nil)
+				svc.On("ListTokens", mock.Anything, int64(7)).Return([]*models.PersonalAccessToken{{ID: 1, Name: "ci", Prefix: "usp_abcdefgh", Scopes: []string{"user:read"}}}, nil)
+			},
+			expectedStatus: http.StatusOK,
+			check: func(t *testing.T, body map[string]any) {
+				tokens := body["tokens"].([]any)
+				require.Len(t, tokens, 1)
+				require.NotContains(t, tokens[0], "token")
+			},
+		},
+		{
+			name:   "personal access token without the scope is forbidden",
+			method: http.MethodGet,
+			target: "/me/tokens",
+			bearer: pat,
+			setupMock: func(svc *mockTokenService) {
+				svc.On("Authenticate", mock.Anything, pat).Return(&models.PersonalAccessToken{ID: 1, UserID: 7, Scopes: []string{"admin"}}, nil)
+			},
+			expectedStatus: http.StatusForbidden,
+		},
+		{
+			name:   "expired personal access token",
+			method: http.MethodGet,
+			target: "/me/tokens",
+			bearer: pat,
+			setupMock: func(svc *mockTokenService) {
+				svc.On("Authenticate", mock.Anything, pat).Return((*models.PersonalAccessToken)(nil), service.ErrTokenExpired)
+			},
+			expectedStatus: http.StatusUnauthorized,
+		},
+		{
+			name:   "revoke token",
+			method: http.MethodDelete,
+			target: "/me/tokens/3",
+			bearer: jwtToken,
+			setupMock: func(svc *mockTokenService) {
+				svc.On("RevokeToken", mock.Anything, int64(7), int64(3)).Return(nil)
+			},
+			expectedStatus: http.StatusNoContent,
+		},
+		{
+			name:   "revoke someone else's token",
+			method: http.MethodDelete,
+			target: "/me/tokens/4",
+			bearer: jwtToken,
+			setupMock: func(svc *mockTokenService) {
+				svc.On("RevokeToken", mock.Anything, int64(7), int64(4)).Return(repository.ErrTokenNotFound)
+			},
+			expectedStatus: http.StatusNotFound,
+		},
+		{
+			name:           "revoke with a malformed id",
+			method:         http.MethodDelete,
+			target:         "/me/tokens/abc",
+			bearer:         jwtToken,
+			setupMock:      func(svc *mockTokenService) {},
+			expectedStatus: http.StatusNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := new(testutil.MockUserRepo)
+			repo.On("FindByID", mock.Anything, int64(7)).Return(user, nil)
+			svc := new(mockTokenService)
+			tt.setupMock(svc)
+
+			req := httptest.NewRequest(tt.method, tt.target, strings.NewReader(tt.body))
+			req.Header.Set("Authorization", "Bearer "+tt.bearer)
+			w := httptest.NewRecorder()
+			newTokenMux(repo, svc).ServeHTTP(w, req)
+
+			require.Equal(t, tt.expectedStatus, w.Code, w.Body.String())
+			svc.AssertExpectations(t)
+			if tt.check != nil {
+				var body map[string]any
+				require.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+				tt.check(t, body)
+			}
+		})
+	}
+}
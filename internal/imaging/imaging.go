@@ -0,0 +1,99 @@
+// Package imaging decodes uploaded pictures and renders square thumbnails of
+// them using only the standard library codecs.
+package imaging
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"image/draw"
+	_ "image/gif" // registers the GIF decoder, only the first frame is used
+	"image/jpeg"
+	_ "image/png" // registers the PNG decoder
+	"io"
+	"slices"
+
+	"github.com/gabriel-vasile/mimetype"
+)
+
+var (
+	ErrUnsupportedFormat = errors.New("image must be a JPEG, PNG or GIF")
+	ErrTooManyPixels     = errors.New("image dimensions are too large")
+	ErrInvalidImage      = errors.New("image could not be decoded")
+)
+
+// formats are the sniffed MIME types Decode accepts.
+var formats = []string{"image/jpeg", "image/png", "image/gif"}
+
+// Decode sniffs the content of data, ignoring any name or declared type, and
+// decodes it. Dimensions are checked before the pixels are allocated.
+func Decode(data []byte, maxPixels int) (image.Image, error) {
+	if !slices.ContainsFunc(formats, mimetype.Detect(data).Is) {
+		return nil, ErrUnsupportedFormat
+	}
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, ErrInvalidImage
+	}
+	if cfg.Width <= 0 || cfg.Height <= 0 {
+		return nil, ErrInvalidImage
+	}
+	if cfg.Width > maxPixels/cfg.Height {
+		return nil, ErrTooManyPixels
+	}
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, ErrInvalidImage
+	}
+	return img, nil
+}
+
+// Square crops src to its centred square and scales that to size×size. Each
+// target pixel averages the source pixels it covers.
+func Square(src image.Image, size int) *image.RGBA {
+	b := src.Bounds()
+	side := min(b.Dx(), b.Dy())
+	crop := image.NewRGBA(image.Rect(0, 0, side, side))
+	draw.Draw(crop, crop.Bounds(), src, image.Pt(b.Min.X+(b.Dx()-side)/2, b.Min.Y+(b.Dy()-side)/2), draw.Src)
+
+	dst := image.NewRGBA(image.Rect(0, 0, size, size))
+	for y := 0; y < size; y++ {
+		sy0 := y * side / size
+		sy1 := max(sy0+1, (y+1)*side/size)
+		for x := 0; x < size; x++ {
+			sx0 := x * side / size
+			sx1 := max(sx0+1, (x+1)*side/size)
+
+			var sum [4]int
+			for sy := sy0; sy < sy1; sy++ {
+				row := crop.Pix[crop.PixOffset(sx0, sy):crop.PixOffset(sx1, sy)]
+				for i := 0; i < len(row); i += 4 {
+					sum[0] += int(row[i])
+					sum[1] += int(row[i+1])
+					sum[2] += int(row[i+2])
+					sum[3] += int(row[i+3])
+				}
+			}
+			n := (sx1 - sx0) * (sy1 - sy0)
+			i := dst.PixOffset(x, y)
+			for c := range sum {
+				dst.Pix[i+c] = uint8((sum[c] + n/2) / n)
+			}
+		}
+	}
+	return dst
+}
+
+// EncodeJPEG writes img as a JPEG, composing transparent areas over white.
+func EncodeJPEG(w io.Writer, img *image.RGBA, quality int) error {
+	flat := image.NewRGBA(img.Bounds())
+	for i := 0; i < len(img.Pix); i += 4 {
+		// the pixels are alpha-premultiplied, so white shows through by 255-alpha
+		background := 255 - img.Pix[i+3]
+		flat.Pix[i] = img.Pix[i] + background
+		flat.Pix[i+1] = img.Pix[i+1] + background
+		flat.Pix[i+2] = img.Pix[i+2] + background
+		flat.Pix[i+3] = 255
+	}
+	return jpeg.Encode(w, flat, &jpeg.Options{Quality: quality})
+}
@@ -0,0 +1,78 @@
+package imaging
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func encodePNG(t *testing.T, img image.Image) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	require.NoError(t, png.Encode(&buf, img))
+	return buf.Bytes()
+}
+
+func TestDecode(t *testing.T) {
+	img := image.NewNRGBA(image.Rect(0, 0, 40, 30))
+	data := encodePNG(t, img)
+
+	decoded, err := Decode(data, 40*30)
+	require.NoError(t, err)
+	require.Equal(t, image.Rect(0, 0, 40, 30), decoded.Bounds())
+
+	_, err = Decode(data, 40*30-1)
+	require.ErrorIs(t, err, ErrTooManyPixels)
+
+	_, err = Decode([]byte("<svg xmlns=\"http://www.w3.org/2000/svg\"></svg>"), 1000)
+	require.ErrorIs(t, err, ErrUnsupportedFormat)
+
+	_, err = Decode(data[:60], 40*30)
+	require.ErrorIs(t, err, ErrInvalidImage)
+}
+
+func TestSquare(t *testing.T) {
+	// a 6x4 picture: blue 1px bands left and right are cropped away
+	src := image.NewNRGBA(image.Rect(0, 0, 6, 4))
+	for y := 0; y < 4; y++ {
+		for x := 0; x < 6; x++ {
+			c := color.NRGBA{R: 255, A: 255}
+			if x == 0 || x == 5 {
+				c = color.NRGBA{B: 255, A: 255}
+			} else if y >= 2 {
+				c = color.NRGBA{G: 255, A: 255}
+			}
+			src.Set(x, y, c)
+		}
+	}
+
+	down := Square(src, 2)
+	require.Equal(t, color.RGBA{R: 255, A: 255}, down.RGBAAt(0, 0))
+	require.Equal(t, color.RGBA{G: 255, A: 255}, down.RGBAAt(1, 1))
+
+	up := Square(src, 8)
+	require.Equal(t, image.Rect(0, 0, 8, 8), up.Bounds())
+	require.Equal(t, color.RGBA{R: 255, A: 255}, up.RGBAAt(7, 0))
+	require.Equal(t, color.RGBA{G: 255, A: 255}, up.RGBAAt(0, 7))
+
+	half := Square(src, 1)
+	require.Equal(t, color.RGBA{R: 128, G: 128, A: 255}, half.RGBAAt(0, 0))
+}
+
+func TestEncodeJPEG(t *testing.T) {
+	img := image.NewRGBA(image.Rect(0, 0, 8, 8))
+
+	var buf bytes.Buffer
+	require.NoError(t, EncodeJPEG(&buf, img, 90))
+	decoded, err := jpeg.Decode(&buf)
+	require.NoError(t, err)
+	r, g, b, _ := decoded.At(4, 4).RGBA()
+	require.Greater(t, r>>8, uint32(250), "transparent pixels become white")
+	require.Greater(t, g>>8, uint32(250))
+	require.Greater(t, b>>8, uint32(250))
+}
@@ -0,0 +1,445 @@
+// Package jsonschema validates JSON documents against a subset of JSON Schema
+// (draft 2020-12). Compile rejects schemas using keywords it does not
+// implement, so a schema never silently accepts more than its author meant.
+//
+// Supported are type, enum, const, properties, required,
+// additionalProperties, minProperties, maxProperties, items, minItems,
+// maxItems, uniqueItems, minLength, maxLength, pattern, minimum, maximum,
+// exclusiveMinimum, exclusiveMaximum and multipleOf. Annotations such as
+// title, description, default, examples, $schema, $id, $comment and format
+// are accepted and ignored.
+package jsonschema
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+var ErrInvalidSchema = errors.New("invalid json schema")
+
+// ValidationError reports the first violation found, at a JSON Pointer into
+// the document.
+type ValidationError struct {
+	Path    string
+	Message string
+}
+
+func (e *ValidationError) Error() string {
+	if e.Path == "" {
+		return e.Message
+	}
+	return e.Path + ": " + e.Message
+}
+
+type Schema struct {
+	// always is set for the boolean schemas true and false.
+	always *bool
+
+	types []string
+	enum  []any
+	konst *any
+
+	properties           map[string]*Schema
+	required             []string
+	additionalProperties *Schema
+	minProperties        *int
+	maxProperties        *int
+
+	items       *Schema
+	minItems    *int
+	maxItems    *int
+	uniqueItems bool
+
+	minLength *int
+	maxLength *int
+	pattern   *regexp.Regexp
+
+	minimum          *float64
+	maximum          *float64
+	exclusiveMinimum *float64
+	exclusiveMaximum *float64
+	multipleOf       *float64
+}
+
+var ignoredKeywords = []string{"title", "description", "default", "examples", "$schema", "$id", "$comment", "format", "readOnly", "writeOnly", "deprecated"}
+
+var knownTypes = []string{"null", "boolean", "object", "array", "number", "integer", "string"}
+
+// Compile parses a schema document.
+func Compile(raw []byte) (*Schema, error) {
+	var doc any
+	if err := decode(raw, &doc); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidSchema, err)
+	}
+	s, err := compile(doc, "")
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidSchema, err)
+	}
+	return s, nil
+}
+
+func decode(raw []byte, v any) error {
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	dec.UseNumber()
+	if err := dec.Decode(v); err != nil {
+		return err
+	}
+	if dec.More() {
+		return errors.New("trailing data after JSON value")
+	}
+	return nil
+}
+
+func compile(doc any, path string) (*Schema, error) {
+	if b, ok := doc.(bool); ok {
+		return &Schema{always: &b}, nil
+	}
+	obj, ok := doc.(map[string]any)
+	if !ok {
+		return nil, fmt.Errorf("%s: schema must be an object or boolean", pointer(path))
+	}
+
+	s := &Schema{}
+	for key, value := range obj {
+		at := path + "/" + escape(key)
+		var err error
+		switch key {
+		case "type":
+			err = s.compileType(value, at)
+		case "enum":
+			values, ok := value.([]any)
+			if !ok {
+				return nil, fmt.Errorf("%s: must be an array", at)
+			}
+			s.enum = values
+		case "const":
+			s.konst = &value
+		case "properties":
+			props, ok := value.(map[string]any)
+			if !ok {
+				return nil, fmt.Errorf("%s: must be an object", at)
+			}
+			s.properties = make(map[string]*Schema, len(props))
+			for name, sub := range props {
+				if s.properties[name], err = compile(sub, at+"/"+escape(name)); err != nil {
+					return nil, err
+				}
+			}
+		case "required":
+			names, ok := value.([]any)
+			if !ok {
+				return nil, fmt.Errorf("%s: must be an array of strings", at)
+			}
+			for _, name := range names {
+				str, ok := name.(string)
+				if !ok {
+					return nil, fmt.Errorf("%s: must be an array of strings", at)
+				}
+				s.required = append(s.required, str)
+			}
+		case "additionalProperties":
+			s.additionalProperties, err = compile(value, at)
+		case "items":
+			s.items, err = compile(value, at)
+		case "uniqueItems":
+			unique, ok := value.(bool)
+			if !ok {
+				return nil, fmt.Errorf("%s: must be a boolean", at)
+			}
+			s.uniqueItems = unique
+		case "minProperties":
+			s.minProperties, err = count(value, at)
+		case "maxProperties":
+			s.maxProperties, err = count(value, at)
+		case "minItems":
+			s.minItems, err = count(value, at)
+		case "maxItems":
+			s.maxItems, err = count(value, at)
+		case "minLength":
+			s.minLength, err = count(value, at)
+		case "maxLength":
+			s.maxLength, err = count(value, at)
+		case "pattern":
+			str, ok := value.(string)
+			if !ok {
+				return nil, fmt.Errorf("%s: must be a string", at)
+			}
+			if s.pattern, err = regexp.Compile(str); err != nil {
+				return nil, fmt.Errorf("%s: %v", at, err)
+			}
+		case "minimum":
+			s.minimum, err = number(value, at)
+		case "maximum":
+			s.maximum, err = number(value, at)
+		case "exclusiveMinimum":
+			s.exclusiveMinimum, err = number(value, at)
+		case "exclusiveMaximum":
+			s.exclusiveMaximum, err = number(value, at)
+		case "multipleOf":
+			if s.multipleOf, err = number(value, at); err == nil && *s.multipleOf <= 0 {
+				err = fmt.Errorf("%s: must be greater than 0", at)
+			}
+		default:
+			if !slices.Contains(ignoredKeywords, key) {
+				return nil, fmt.Errorf("%s: unsupported keyword", at)
+			}
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	return s, nil
+}
+
+func (s *Schema) compileType(value any, at string) error {
+	switch v := value.(type) {
+	case string:
+		s.types = []string{v}
+	case []any:
+		for _, t := range v {
+			str, ok := t.(string)
+			if !ok {
+				return fmt.Errorf("%s: must be a string or an array of strings", at)
+			}
+			s.types = append(s.types, str)
+		}
+	default:
+		return fmt.Errorf("%s: must be a string or an array of strings", at)
+	}
+	for _, t := range s.types {
+		if !slices.Contains(knownTypes, t) {
+			return fmt.Errorf("%s: unknown type %q", at, t)
+		}
+	}
+	return nil
+}
+
+func count(value any, at string) (*int, error) {
+	n, ok := value.(json.Number)
+	if !ok {
+		return nil, fmt.Errorf("%s: must be a non-negative integer", at)
+	}
+	i, err := strconv.Atoi(n.String())
+	if err != nil || i < 0 {
+		return nil, fmt.Errorf("%s: must be a non-negative integer", at)
+	}
+	return &i, nil
+}
+
+func number(value any, at string) (*float64, error) {
+	n, ok := value.(json.Number)
+	if !ok {
+		return nil, fmt.Errorf("%s: must be a number", at)
+	}
+	f, err := n.Float64()
+	if err != nil {
+		return nil, fmt.Errorf("%s: must be a number", at)
+	}
+	return &f, nil
+}
+
+func escape(key string) string {
+	return strings.ReplaceAll(strings.ReplaceAll(key, "~", "~0"), "/", "~1")
+}
+
+func pointer(path string) string {
+	if path == "" {
+		return "/"
+	}
+	return path
+}
+
+// Validate checks a JSON document against the schema.
+func (s *Schema) Validate(raw []byte) error {
+	var doc any
+	if err := decode(raw, &doc); err != nil {
+		return &ValidationError{Message: "invalid JSON: " + err.Error()}
+	}
+	return s.validate(doc, "")
+}
+
+func fail(path, format string, args ...any) error {
+	return &ValidationError{Path: path, Message: fmt.Sprintf(format, args...)}
+}
+
+func typeOf(v any) string {
+	switch v := v.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case map[string]any:
+		return "object"
+	case []any:
+		return "array"
+	case string:
+		return "string"
+	case json.Number:
+		if f, err := v.Float64(); err == nil && f == math.Trunc(f) && !math.IsInf(f, 0) {
+			return "integer"
+		}
+		return "number"
+	}
+	return "unknown"
+}
+
+func (s *Schema) validate(v any, path string) error {
+	if s.always != nil {
+		if !*s.always {
+			return fail(path, "no value is allowed here")
+		}
+		return nil
+	}
+
+	if len(s.types) > 0 {
+		t := typeOf(v)
+		if !slices.Contains(s.types, t) && !(t == "integer" && slices.Contains(s.types, "number")) {
+			return fail(path, "must be of type %s", strings.Join(s.types, " or "))
+		}
+	}
+	if s.enum != nil && !slices.ContainsFunc(s.enum, func(e any) bool { return equal(e, v) }) {
+		return fail(path, "must be one of the allowed values")
+	}
+	if s.konst != nil && !equal(*s.konst, v) {
+		return fail(path, "must be the constant value")
+	}
+
+	switch v := v.(type) {
+	case map[string]any:
+		return s.validateObject(v, path)
+	case []any:
+		return s.validateArray(v, path)
+	case string:
+		n := utf8.RuneCountInString(v)
+		if s.minLength != nil && n < *s.minLength {
+			return fail(path, "must be at least %d characters", *s.minLength)
+		}
+		if s.maxLength != nil && n > *s.maxLength {
+			return fail(path, "must be at most %d characters", *s.maxLength)
+		}
+		if s.pattern != nil && !s.pattern.MatchString(v) {
+			return fail(path, "must match %s", s.pattern)
+		}
+	case json.Number:
+		f, _ := v.Float64()
+		switch {
+		case s.minimum != nil && f < *s.minimum:
+			return fail(path, "must be at least %v", *s.minimum)
+		case s.maximum != nil && f > *s.maximum:
+			return fail(path, "must be at most %v", *s.maximum)
+		case s.exclusiveMinimum != nil && f <= *s.exclusiveMinimum:
+			return fail(path, "must be greater than %v", *s.exclusiveMinimum)
+		case s.exclusiveMaximum != nil && f >= *s.exclusiveMaximum:
+			return fail(path, "must be less than %v", *s.exclusiveMaximum)
+		case s.multipleOf != nil && math.Abs(math.Remainder(f, *s.multipleOf)) > 1e-9:
+			return fail(path, "must be a multiple of %v", *s.multipleOf)
+		}
+	}
+	return nil
+}
+
+func (s *Schema) validateObject(obj map[string]any, path string) error {
+	for _, name := range s.required {
+		if _, ok := obj[name]; !ok {
+			return fail(pointer(path), "missing required property %q", name)
+		}
+	}
+	if s.minProperties != nil && len(obj) < *s.minProperties {
+		return fail(pointer(path), "must have at least %d properties", *s.minProperties)
+	}
+	if s.maxProperties != nil && len(obj) > *s.maxProperties {
+		return fail(pointer(path), "must have at most %d properties", *s.maxProperties)
+	}
+
+	// sorted, so the reported violation does not depend on map order
+	names := make([]string, 0, len(obj))
+	for name := range obj {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		sub, ok := s.properties[name]
+		if !ok {
+			sub = s.additionalProperties
+		}
+		if sub == nil {
+			continue
+		}
+		if err := sub.validate(obj[name], path+"/"+escape(name)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *Schema) validateArray(arr []any, path string) error {
+	if s.minItems != nil && len(arr) < *s.minItems {
+		return fail(pointer(path), "must have at least %d items", *s.minItems)
+	}
+	if s.maxItems != nil && len(arr) > *s.maxItems {
+		return fail(pointer(path), "must have at most %d items", *s.maxItems)
+	}
+	if s.uniqueItems {
+		for i := range arr {
+			for j := i + 1; j < len(arr); j++ {
+				if equal(arr[i], arr[j]) {
+					return fail(pointer(path), "items must be unique")
+				}
+			}
+		}
+	}
+	if s.items != nil {
+		for i, item := range arr {
+			if err := s.items.validate(item, path+"/"+strconv.Itoa(i)); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// equal compares decoded JSON values, numbers by value so 1 equals 1.0.
+func equal(a, b any) bool {
+	switch a := a.(type) {
+	case json.Number:
+		bn, ok := b.(json.Number)
+		if !ok {
+			return false
+		}
+		af, _ := a.Float64()
+		bf, _ := bn.Float64()
+		return af == bf
+	case map[string]any:
+		bm, ok := b.(map[string]any)
+		if !ok || len(a) != len(bm) {
+			return false
+		}
+		for k, v := range a {
+			if bv, ok := bm[k]; !ok || !equal(v, bv) {
+				return false
+			}
+		}
+		return true
+	case []any:
+		ba, ok := b.([]any)
+		if !ok || len(a) != len(ba) {
+			return false
+		}
+		for i := range a {
+			if !equal(a[i], ba[i]) {
+				return false
+			}
+		}
+		return true
+	}
+	return a == b
+}
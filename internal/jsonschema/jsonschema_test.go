@@ -0,0 +1,102 @@
+package jsonschema
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+const planSchema = `{
+	"$schema": "https://json-schema.org/draft/2020-12/schema",
+	"type": "object",
+	"properties": {
+		"plan": {"enum": ["free", "pro", "enterprise"]},
+		"seats": {"type": "integer", "minimum": 1, "maximum": 1000},
+		"discount": {"type": "number", "exclusiveMaximum": 1, "multipleOf": 0.05},
+		"coupon": {"type": ["string", "null"], "pattern": "^[A-Z0-9]{4,12}$"},
+		"flags": {"type": "array", "items": {"type": "string", "minLength": 1}, "uniqueItems": true, "maxItems": 3}
+	},
+	"required": ["plan"],
+	"additionalProperties": false
+}`
+
+func TestValidate(t *testing.T) {
+	schema, err := Compile([]byte(planSchema))
+	require.NoError(t, err)
+
+	tests := []struct {
+		name     string
+		doc      string
+		wantErr  bool
+		wantPath string
+	}{
+		{name: "valid", doc: `{"plan":"pro","seats":5,"discount":0.15,"coupon":"SPRING24","flags":["beta"]}`},
+		{name: "null coupon", doc: `{"plan":"free","coupon":null}`},
+		{name: "integral float is an integer", doc: `{"plan":"pro","seats":5.0}`},
+		{name: "missing required", doc: `{"seats":5}`, wantErr: true, wantPath: "/"},
+		{name: "not in enum", doc: `{"plan":"gold"}`, wantErr: true, wantPath: "/plan"},
+		{name: "fractional integer", doc: `{"plan":"pro","seats":1.5}`, wantErr: true, wantPath: "/seats"},
+		{name: "below minimum", doc: `{"plan":"pro","seats":0}`, wantErr: true, wantPath: "/seats"},
+		{name: "exclusive maximum", doc: `{"plan":"pro","discount":1}`, wantErr: true, wantPath: "/discount"},
+		{name: "not a multiple", doc: `{"plan":"pro","discount":0.12}`, wantErr: true, wantPath: "/discount"},
+		{name: "pattern", doc: `{"plan":"pro","coupon":"spring"}`, wantErr: true, wantPath: "/coupon"},
+		{name: "duplicate items", doc: `{"plan":"pro","flags":["a","a"]}`, wantErr: true, wantPath: "/flags"},
+		{name: "invalid item", doc: `{"plan":"pro","flags":[""]}`, wantErr: true, wantPath: "/flags/0"},
+		{name: "additional property", doc: `{"plan":"pro","tier":1}`, wantErr: true, wantPath: "/tier"},
+		{name: "wrong type", doc: `["pro"]`, wantErr: true, wantPath: ""},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			err := schema.Validate([]byte(tt.doc))
+			if !tt.wantErr {
+				require.NoError(t, err)
+				return
+			}
+			var verr *ValidationError
+			require.True(t, errors.As(err, &verr), "got %v", err)
+			require.Equal(t, tt.wantPath, verr.Path, verr.Error())
+		})
+	}
+}
+
+func TestCompile(t *testing.T) {
+	tests := []struct {
+		name    string
+		schema  string
+		wantErr bool
+	}{
+		{name: "boolean schema", schema: `true`},
+		{name: "annotations", schema: `{"title":"Onboarding","description":"…","format":"uuid","type":"string"}`},
+		{name: "unsupported keyword", schema: `{"oneOf":[{"type":"string"}]}`, wantErr: true},
+		{name: "nested unsupported keyword", schema: `{"properties":{"a":{"$ref":"#/defs/a"}}}`, wantErr: true},
+		{name: "unknown type", schema: `{"type":"date"}`, wantErr: true},
+		{name: "invalid pattern", schema: `{"pattern":"("}`, wantErr: true},
+		{name: "negative length", schema: `{"maxLength":-1}`, wantErr: true},
+		{name: "not a schema", schema: `"string"`, wantErr: true},
+		{name: "trailing data", schema: `{} {}`, wantErr: true},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			_, err := Compile([]byte(tt.schema))
+			if tt.wantErr {
+				require.ErrorIs(t, err, ErrInvalidSchema)
+				return
+			}
+			require.NoError(t, err)
+		})
+	}
+}
+
+func TestFalseSchema(t *testing.T) {
+	schema, err := Compile([]byte(`{"properties":{"legacy":false}}`))
+	require.NoError(t, err)
+	require.NoError(t, schema.Validate([]byte(`{"other":1}`)))
+	require.Error(t, schema.Validate([]byte(`{"legacy":1}`)))
+}
@@ -0,0 +1,183 @@
+package ldapauth
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"github.com/Atmosfr/user-service/internal/models"
+	"github.com/Atmosfr/user-service/internal/repository"
+	"github.com/Atmosfr/user-service/internal/service"
+	"github.com/go-ldap/ldap/v3"
+)
+
+var invalidUsernameChars = regexp.MustCompile(`[^a-zA-Z0-9_-]+`)
+
+type Authenticator struct {
+	cfg  *Config
+	repo repository.UserRepository
+}
+
+type directoryEntry struct {
+	dn       string
+	username string
+	groups   []string
+}
+
+func (a *Authenticator) connect() (*ldap.Conn, error) {
+	conn, err := ldap.DialURL(a.cfg.URL, ldap.DialWithDialer(&net.Dialer{Timeout: a.cfg.Timeout}))
+	if err != nil {
+		return nil, err
+	}
+	conn.SetTimeout(a.cfg.Timeout)
+
+	if a.cfg.StartTLS {
+		tlsConfig := a.cfg.TLSConfig
+		if tlsConfig == nil {
+			tlsConfig = &tls.Config{InsecureSkipVerify: a.cfg.InsecureSkipVerify}
+		} else {
+			tlsConfig = tlsConfig.Clone()
+		}
+		if tlsConfig.ServerName == "" {
+			if u, err := url.Parse(a.cfg.URL); err == nil {
+				tlsConfig.ServerName = u.Hostname()
+			}
+		}
+		if err := conn.StartTLS(tlsConfig); err != nil {
+			conn.Close()
+			return nil, fmt.Errorf("ldap starttls: %w", err)
+		}
+	}
+
+	return conn, nil
+}
+
+func (a *Authenticator) lookup(conn *ldap.Conn, email string) (*directoryEntry, error) {
+	if a.cfg.BindDN != "" {
+		if err := conn.Bind(a.cfg.BindDN, a.cfg.BindPassword); err != nil {
+			return nil, fmt.Errorf("ldap service bind: %w", err)
+		}
+	}
+
+	req := ldap.NewSearchRequest(
+		a.cfg.BaseDN,
+		ldap.ScopeWholeSubtree, ldap.NeverDerefAliases,
+		2, int(a.cfg.Timeout.Seconds()), false,
+		fmt.Sprintf(a.cfg.UserFilter, ldap.EscapeFilter(email)),
+		[]string{a.cfg.UsernameAttribute, a.cfg.GroupAttribute},
+		nil,
+	)
+	res, err := conn.Search(req)
+	if err != nil && !ldap.IsErrorWithCode(err, ldap.LDAPResultSizeLimitExceeded) {
+		return nil, fmt.Errorf("ldap search: %w", err)
+	}
+
+	switch {
+	case res == nil || len(res.Entries) == 0:
+		return nil, repository.ErrUserNotFound
+	case len(res.Entries) > 1:
+		return nil, ErrAmbiguousUser
+	}
+
+	entry := res.Entries[0]
+	return &directoryEntry{
+		dn:       entry.DN,
+		username: entry.GetAttributeValue(a.cfg.UsernameAttribute),
+		groups:   entry.GetAttributeValues(a.cfg.GroupAttribute),
+	}, nil
+}
+
+func (a *Authenticator) role(groups []string) string {
+	for _, mapping := range a.cfg.GroupRoles {
+		for _, group := range groups {
+			if strings.EqualFold(group, mapping.GroupDN) {
+				return mapping.Role
+			}
+		}
+	}
+	return a.cfg.DefaultRole
+}
+
+func usernameFor(entry *directoryEntry, email string) string {
+	name := entry.username
+	if name == "" {
+		name, _, _ = strings.Cut(email, "@")
+	}
+	name = invalidUsernameChars.ReplaceAllString(name, "_")
+	if len(name) > 30 {
+		name = name[:30]
+	}
+	return name
+}
+
+// Authenticate binds as the directory entry matching the email and provisions
+// the user locally on first login.
+func (a *Authenticator) Authenticate(ctx context.Context, email, password string) (*models.User, error) {
+	// an empty password would turn the bind into an unauthenticated bind, which succeeds
+	if password == "" {
+		return nil, repository.ErrInvalidPassword
+	}
+
+	conn, err := a.connect()
+	if err != nil {
+		slog.Error("ldap connection failed", "url", a.cfg.URL, "err", err)
+		return nil, err
+	}
+	defer conn.Close()
+
+	entry, err := a.lookup(conn, email)
+	if err != nil {
+		slog.Warn("ldap user lookup failed", "domain", service.EmailDomain(email), "err", err)
+		return nil, err
+	}
+
+	if err := conn.Bind(entry.dn, password); err != nil {
+		if ldap.IsErrorWithCode(err, ldap.LDAPResultInvalidCredentials) {
+			slog.Warn("wrong ldap password for user", "domain", service.EmailDomain(email))
+			return nil, repository.ErrInvalidPassword
+		}
+		return nil, fmt.Errorf("ldap user bind: %w", err)
+	}
+
+	role := a.role(entry.groups)
+
+	user, err := a.repo.FindByEmail(ctx, email)
+	if errors.Is(err, repository.ErrUserNotFound) {
+		user = &models.User{
+			Email:    email,
+			Username: usernameFor(entry, email),
+			Role:     role,
+			IsActive: true,
+			State:    models.AccountActive,
+		}
+		if err := a.repo.Create(ctx, user); err != nil {
+			return nil, err
+		}
+		slog.Info("ldap user provisioned", "user_id", user.ID, "role", role)
+		return a.repo.FindByID(ctx, user.ID)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if len(a.cfg.GroupRoles) > 0 && user.Role != role {
+		if err := a.repo.UpdateRole(ctx, user.ID, role); err != nil {
+			return nil, err
+		}
+		slog.Info("ldap role synchronized", "user_id", user.ID, "old_role", user.Role, "role", role)
+		user.Role = role
+	}
+
+	return user, nil
+}
+
+func NewAuthenticator(cfg *Config, repo repository.UserRepository) *Authenticator {
+	cfg.setDefaults()
+	return &Authenticator{cfg: cfg, repo: repo}
+}
@@ -0,0 +1,353 @@
+package ldapauth
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"math/big"
+	"net"
+	"regexp"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Atmosfr/user-service/internal/models"
+	"github.com/Atmosfr/user-service/internal/repository"
+	"github.com/Atmosfr/user-service/internal/testutil"
+	ber "github.com/go-asn1-ber/asn1-ber"
+	"github.com/go-ldap/ldap/v3"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+type stubEntry struct {
+	dn       string
+	password string
+	mail     string
+	attrs    map[string][]string
+}
+
+// stubDirectory is a minimal in-process LDAP server that understands simple
+// binds, equality searches on mail and the StartTLS extended operation.
+type stubDirectory struct {
+	listener  net.Listener
+	tlsConfig *tls.Config
+	entries   []stubEntry
+
+	mu           sync.Mutex
+	startTLSUsed bool
+}
+
+const (
+	serviceDN       = "cn=svc,dc=corp,dc=example"
+	servicePassword = "svc-secret"
+	adminsGroup     = "CN=Admins,OU=Groups,DC=corp,DC=example"
+)
+
+var mailFilter = regexp.MustCompile(`\(mail=([^)]*)\)`)
+
+func newStubDirectory(t *testing.T, tlsConfig *tls.Config, entries ...stubEntry) *stubDirectory {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	d := &stubDirectory{listener: listener, tlsConfig: tlsConfig, entries: entries}
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go d.serve(conn)
+		}
+	}()
+	t.Cleanup(func() { listener.Close() })
+	return d
+}
+
+func (d *stubDirectory) url() string {
+	return "ldap://" + d.listener.Addr().String()
+}
+
+func (d *stubDirectory) usedStartTLS() bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.startTLSUsed
+}
+
+func ldapMessage(id int64, op *ber.Packet) *ber.Packet {
+	p := ber.Encode(ber.ClassUniversal, ber.TypeConstructed, ber.TagSequence, nil, "LDAP Response")
+	p.AppendChild(ber.NewInteger(ber.ClassUniversal, ber.TypePrimitive, ber.TagInteger, id, "MessageID"))
+	p.AppendChild(op)
+	return p
+}
+
+func ldapResult(app int, code int) *ber.Packet {
+	op := ber.Encode(ber.ClassApplication, ber.TypeConstructed, ber.Tag(app), nil, "Response")
+	op.AppendChild(ber.NewInteger(ber.ClassUniversal, ber.TypePrimitive, ber.TagEnumerated, code, "resultCode"))
+	op.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, "", "matchedDN"))
+	op.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, "", "diagnosticMessage"))
+	return op
+}
+
+func searchEntry(e stubEntry) *ber.Packet {
+	op := ber.Encode(ber.ClassApplication, ber.TypeConstructed, ldap.ApplicationSearchResultEntry, nil, "Search Result Entry")
+	op.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, e.dn, "objectName"))
+	attrs := ber.Encode(ber.ClassUniversal, ber.TypeConstructed, ber.TagSequence, nil, "attributes")
+	for name, values := range e.attrs {
+		attr := ber.Encode(ber.ClassUniversal, ber.TypeConstructed, ber.TagSequence, nil, "attribute")
+		attr.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, name, "type"))
+		set := ber.Encode(ber.ClassUniversal, ber.TypeConstructed, ber.TagSet, nil, "vals")
+		for _, v := range values {
+			set.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, v, "value"))
+		}
+		attr.AppendChild(set)
+		attrs.AppendChild(attr)
+	}
+	op.AppendChild(attrs)
+	return op
+}
+
+func (d *stubDirectory) bind(dn, password string) int {
+	if dn == serviceDN && password == servicePassword {
+		return ldap.LDAPResultSuccess
+	}
+	for _, e := range d.entries {
+		if strings.EqualFold(e.dn, dn) && e.password == password {
+			return ldap.LDAPResultSuccess
+		}
+	}
+	return ldap.LDAPResultInvalidCredentials
+}
+
+func (d *stubDirectory) serve(conn net.Conn) {
+	defer func() { conn.Close() }()
+	for {
+		packet, err := ber.ReadPacket(conn)
+		if err != nil || len(packet.Children) < 2 {
+			return
+		}
+		id, _ := packet.Children[0].Value.(int64)
+		op := packet.Children[1]
+
+		switch op.Tag {
+		case ldap.ApplicationBindRequest:
+			dn, _ := op.Children[1].Value.(string)
+			code := d.bind(dn, op.Children[2].Data.String())
+			conn.Write(ldapMessage(id, ldapResult(ldap.ApplicationBindResponse, code)).Bytes())
+		case ldap.ApplicationSearchRequest:
+			filter, _ := ldap.DecompileFilter(op.Children[6])
+			if m := mailFilter.FindStringSubmatch(filter); m != nil {
+				for _, e := range d.entries {
+					if strings.EqualFold(e.mail, m[1]) {
+						conn.Write(ldapMessage(id, searchEntry(e)).Bytes())
+					}
+				}
+			}
+			conn.Write(ldapMessage(id, ldapResult(ldap.ApplicationSearchResultDone, ldap.LDAPResultSuccess)).Bytes())
+		case ldap.ApplicationExtendedRequest:
+			conn.Write(ldapMessage(id, ldapResult(ldap.ApplicationExtendedResponse, ldap.LDAPResultSuccess)).Bytes())
+			tlsConn := tls.Server(conn, d.tlsConfig)
+			if err := tlsConn.Handshake(); err != nil {
+				return
+			}
+			d.mu.Lock()
+			d.startTLSUsed = true
+			d.mu.Unlock()
+			conn = tlsConn
+		case ldap.ApplicationUnbindRequest:
+			return
+		}
+	}
+}
+
+func selfSignedTLS(t *testing.T) (server, client *tls.Config) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "ldap stub"},
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	require.NoError(t, err)
+	cert, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+
+	pool := x509.NewCertPool()
+	pool.AddCert(cert)
+	server = &tls.Config{Certificates: []tls.Certificate{{Certificate: [][]byte{der}, PrivateKey: key}}}
+	client = &tls.Config{RootCAs: pool}
+	return server, client
+}
+
+var (
+	alice = stubEntry{
+		dn:       "CN=Alice,OU=People,DC=corp,DC=example",
+		password: "alice-password",
+		mail:     "alice@corp.example",
+		attrs: map[string][]string{
+			"sAMAccountName": {"alice.smith"},
+			"memberOf":       {"CN=Staff,OU=Groups,DC=corp,DC=example", strings.ToLower(adminsGroup)},
+		},
+	}
+	bob = stubEntry{
+		dn:       "CN=Bob,OU=People,DC=corp,DC=example",
+		password: "bob-password",
+		mail:     "bob@corp.example",
+		attrs: map[string][]string{
+			"sAMAccountName": {"bob"},
+		},
+	}
+)
+
+func testConfig(d *stubDirectory) *Config {
+	return &Config{
+		URL:          d.url(),
+		BindDN:       serviceDN,
+		BindPassword: servicePassword,
+		BaseDN:       "DC=corp,DC=example",
+		GroupRoles:   []GroupRole{{GroupDN: adminsGroup, Role: "admin"}},
+		Domains:      []string{"corp.example"},
+		Timeout:      2 * time.Second,
+	}
+}
+
+func TestAuthenticator_Authenticate(t *testing.T) {
+	ctx := context.Background()
+	serverTLS, clientTLS := selfSignedTLS(t)
+
+	tests := []struct {
+		name      string
+		email     string
+		password  string
+		startTLS  bool
+		setupMock func(repo *testutil.MockUserRepo)
+		wantErr   error
+		wantRole  string
+	}{
+		{
+			name:     "first login provisions the user with a mapped role",
+			email:    "alice@corp.example",
+			password: "alice-password",
+			setupMock: func(repo *testutil.MockUserRepo) {
+				repo.On("FindByEmail", mock.Anything, "alice@corp.example").Return((*models.User)(nil), repository.ErrUserNotFound)
+				repo.On("Create", mock.Anything, mock.MatchedBy(func(u *models.User) bool {
+					return u.Username == "alice_smith" && u.Role == "admin" && u.PasswordHash == ""
+				})).Return(nil).Run(func(args mock.Arguments) {
+					args.Get(1).(*models.User).ID = 10
+				})
+				repo.On("FindByID", mock.Anything, int64(10)).Return(&models.User{ID: 10, Email: "alice@corp.example", Role: "admin"}, nil)
+			},
+			wantRole: "admin",
+		},
+		{
+			name:     "existing user is demoted when no longer in a mapped group",
+			email:    "bob@corp.example",
+			password: "bob-password",
+			startTLS: true,
+			setupMock: func(repo *testutil.MockUserRepo) {
+				repo.On("FindByEmail", mock.Anything, "bob@corp.example").Return(&models.User{ID: 11, Email: "bob@corp.example", Role: "admin"}, nil)
+				repo.On("UpdateRole", mock.Anything, int64(11), "user").Return(nil)
+			},
+			wantRole: "user",
+		},
+		{
+			name:      "wrong password",
+			email:     "alice@corp.example",
+			password:  "wrong-password",
+			setupMock: func(repo *testutil.MockUserRepo) {},
+			wantErr:   repository.ErrInvalidPassword,
+		},
+		{
+			name:      "empty password never reaches the directory",
+			email:     "alice@corp.example",
+			password:  "",
+			setupMock: func(repo *testutil.MockUserRepo) {},
+			wantErr:   repository.ErrInvalidPassword,
+		},
+		{
+			name:      "unknown user",
+			email:     "carol@corp.example",
+			password:  "carol-password",
+			setupMock: func(repo *testutil.MockUserRepo) {},
+			wantErr:   repository.ErrUserNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			directory := newStubDirectory(t, serverTLS, alice, bob)
+			cfg := testConfig(directory)
+			if tt.startTLS {
+				cfg.StartTLS = true
+				cfg.TLSConfig = clientTLS
+			}
+
+			repo := new(testutil.MockUserRepo)
+			tt.setupMock(repo)
+
+			user, err := NewAuthenticator(cfg, repo).Authenticate(ctx, tt.email, tt.password)
+			if tt.wantErr != nil {
+				require.ErrorIs(t, err, tt.wantErr)
+				repo.AssertNotCalled(t, "Create", mock.Anything, mock.Anything)
+				return
+			}
+
+			require.NoError(t, err)
+			require.Equal(t, tt.wantRole, user.Role)
+			require.Equal(t, tt.startTLS, directory.usedStartTLS())
+			repo.AssertExpectations(t)
+		})
+	}
+}
+
+func TestAuthenticator_StartTLSRejectsUntrustedCertificate(t *testing.T) {
+	serverTLS, _ := selfSignedTLS(t)
+	directory := newStubDirectory(t, serverTLS, alice)
+
+	cfg := testConfig(directory)
+	cfg.StartTLS = true
+
+	_, err := NewAuthenticator(cfg, new(testutil.MockUserRepo)).Authenticate(context.Background(), alice.mail, alice.password)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "starttls")
+}
+
+func TestConfigFromEnv(t *testing.T) {
+	t.Setenv("LDAP_URL", "ldaps://dc.corp.example")
+	t.Setenv("LDAP_BASE_DN", "DC=corp,DC=example")
+	t.Setenv("LDAP_DOMAINS", "Corp.Example, subsidiary.example")
+	t.Setenv("LDAP_START_TLS", "true")
+	t.Setenv("LDAP_GROUP_ROLES", adminsGroup+"|admin;CN=Support,OU=Groups,DC=corp,DC=example|support")
+
+	cfg, err := ConfigFromEnv()
+	require.NoError(t, err)
+	require.True(t, cfg.StartTLS)
+	require.Equal(t, []string{"corp.example", "subsidiary.example"}, cfg.Domains)
+	require.Equal(t, []GroupRole{
+		{GroupDN: adminsGroup, Role: "admin"},
+		{GroupDN: "CN=Support,OU=Groups,DC=corp,DC=example", Role: "support"},
+	}, cfg.GroupRoles)
+	require.Equal(t, "(&(objectClass=person)(mail=%s))", cfg.UserFilter)
+
+	t.Setenv("LDAP_GROUP_ROLES", "missing-role")
+	_, err = ConfigFromEnv()
+	require.Error(t, err)
+
+	t.Setenv("LDAP_URL", "")
+	cfg, err = ConfigFromEnv()
+	require.NoError(t, err)
+	require.Nil(t, cfg)
+}
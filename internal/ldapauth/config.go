@@ -0,0 +1,127 @@
+package ldapauth
+
+import (
+	"crypto/tls"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type GroupRole struct {
+	GroupDN string
+	Role    string
+}
+
+type Config struct {
+	URL                string
+	StartTLS           bool
+	InsecureSkipVerify bool
+	TLSConfig          *tls.Config
+
+	BindDN       string
+	BindPassword string
+
+	BaseDN            string
+	UserFilter        string
+	UsernameAttribute string
+	GroupAttribute    string
+
+	// GroupRoles is checked in order, the first group the user belongs to decides the role.
+	GroupRoles  []GroupRole
+	DefaultRole string
+
+	Domains []string
+	Timeout time.Duration
+}
+
+func (c *Config) setDefaults() {
+	if c.UserFilter == "" {
+		c.UserFilter = "(&(objectClass=person)(mail=%s))"
+	}
+	if c.UsernameAttribute == "" {
+		c.UsernameAttribute = "sAMAccountName"
+	}
+	if c.GroupAttribute == "" {
+		c.GroupAttribute = "memberOf"
+	}
+	if c.DefaultRole == "" {
+		c.DefaultRole = "user"
+	}
+	if c.Timeout == 0 {
+		c.Timeout = 10 * time.Second
+	}
+}
+
+// ConfigFromEnv reads the LDAP_* variables. It returns nil when LDAP_URL is unset.
+//
+// LDAP_GROUP_ROLES is a semicolon separated list of "group DN|role" pairs, e.g.
+// "CN=Admins,OU=Groups,DC=corp,DC=example|admin;CN=Support,OU=Groups,DC=corp,DC=example|support".
+func ConfigFromEnv() (*Config, error) {
+	url := os.Getenv("LDAP_URL")
+	if url == "" {
+		return nil, nil
+	}
+
+	cfg := &Config{
+		URL:               url,
+		BindDN:            os.Getenv("LDAP_BIND_DN"),
+		BindPassword:      os.Getenv("LDAP_BIND_PASSWORD"),
+		BaseDN:            os.Getenv("LDAP_BASE_DN"),
+		UserFilter:        os.Getenv("LDAP_USER_FILTER"),
+		UsernameAttribute: os.Getenv("LDAP_USERNAME_ATTRIBUTE"),
+		GroupAttribute:    os.Getenv("LDAP_GROUP_ATTRIBUTE"),
+		DefaultRole:       os.Getenv("LDAP_DEFAULT_ROLE"),
+	}
+
+	for _, name := range []string{"LDAP_START_TLS", "LDAP_INSECURE_SKIP_VERIFY"} {
+		v := os.Getenv(name)
+		if v == "" {
+			continue
+		}
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s: %w", name, err)
+		}
+		if name == "LDAP_START_TLS" {
+			cfg.StartTLS = b
+		} else {
+			cfg.InsecureSkipVerify = b
+		}
+	}
+
+	if v := os.Getenv("LDAP_TIMEOUT"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil {
+			return nil, fmt.Errorf("invalid LDAP_TIMEOUT: %w", err)
+		}
+		cfg.Timeout = d
+	}
+
+	for _, domain := range strings.Split(os.Getenv("LDAP_DOMAINS"), ",") {
+		if domain = strings.TrimSpace(domain); domain != "" {
+			cfg.Domains = append(cfg.Domains, strings.ToLower(domain))
+		}
+	}
+	if len(cfg.Domains) == 0 {
+		return nil, ErrNoDomains
+	}
+	if cfg.BaseDN == "" {
+		return nil, ErrNoBaseDN
+	}
+
+	for _, pair := range strings.Split(os.Getenv("LDAP_GROUP_ROLES"), ";") {
+		if strings.TrimSpace(pair) == "" {
+			continue
+		}
+		dn, role, ok := strings.Cut(pair, "|")
+		if !ok || strings.TrimSpace(dn) == "" || strings.TrimSpace(role) == "" {
+			return nil, fmt.Errorf("invalid LDAP_GROUP_ROLES entry %q", pair)
+		}
+		cfg.GroupRoles = append(cfg.GroupRoles, GroupRole{GroupDN: strings.TrimSpace(dn), Role: strings.TrimSpace(role)})
+	}
+
+	cfg.setDefaults()
+	return cfg, nil
+}
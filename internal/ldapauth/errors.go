@@ -0,0 +1,9 @@
+package ldapauth
+
+import "errors"
+
+var (
+	ErrNoDomains     = errors.New("LDAP_DOMAINS must list at least one email domain")
+	ErrNoBaseDN      = errors.New("LDAP_BASE_DN is required")
+	ErrAmbiguousUser = errors.New("ldap search returned more than one entry")
+)
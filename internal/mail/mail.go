@@ -0,0 +1,33 @@
+// Package mail sends transactional email such as invitations.
+package mail
+
+import (
+	"context"
+	"log/slog"
+)
+
+type Message struct {
+	To      string
+	Subject string
+	// Body is plain text.
+	Body string
+}
+
+type Mailer interface {
+	Send(ctx context.Context, msg Message) error
+}
+
+// LogMailer writes messages to the log instead of sending them. It is used when
+// no SMTP server is configured.
+type LogMailer struct {
+	Logger *slog.Logger
+}
+
+func (m LogMailer) Send(ctx context.Context, msg Message) error {
+	logger := m.Logger
+	if logger == nil {
+		logger = slog.Default()
+	}
+	logger.InfoContext(ctx, "email not sent, no mail server configured", "to", msg.To, "subject", msg.Subject, "body", msg.Body)
+	return nil
+}
@@ -0,0 +1,98 @@
+package mail
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"mime"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"os"
+	"strings"
+	"time"
+)
+
+var ErrNoSender = errors.New("SMTP_FROM is required when SMTP_ADDR is set")
+
+type SMTPConfig struct {
+	// Addr is host:port of the server. STARTTLS is used when the server offers it.
+	Addr     string
+	Username string
+	Password string
+	From     string
+}
+
+// ConfigFromEnv reads the SMTP_* variables. It returns nil when SMTP_ADDR is unset.
+func ConfigFromEnv() (*SMTPConfig, error) {
+	addr := os.Getenv("SMTP_ADDR")
+	if addr == "" {
+		return nil, nil
+	}
+	cfg := &SMTPConfig{
+		Addr:     addr,
+		Username: os.Getenv("SMTP_USERNAME"),
+		Password: os.Getenv("SMTP_PASSWORD"),
+		From:     os.Getenv("SMTP_FROM"),
+	}
+	if cfg.From == "" {
+		return nil, ErrNoSender
+	}
+	if _, _, err := net.SplitHostPort(addr); err != nil {
+		return nil, fmt.Errorf("invalid SMTP_ADDR: %w", err)
+	}
+	return cfg, nil
+}
+
+type SMTPMailer struct {
+	cfg *SMTPConfig
+	// send is smtp.SendMail, replaced in tests.
+	send func(addr string, a smtp.Auth, from string, to []string, msg []byte) error
+}
+
+func NewSMTPMailer(cfg *SMTPConfig) *SMTPMailer {
+	return &SMTPMailer{cfg: cfg, send: smtp.SendMail}
+}
+
+func (m *SMTPMailer) Send(ctx context.Context, msg Message) error {
+	from, err := mail.ParseAddress(m.cfg.From)
+	if err != nil {
+		return fmt.Errorf("invalid sender: %w", err)
+	}
+	to, err := mail.ParseAddress(msg.To)
+	if err != nil {
+		return fmt.Errorf("invalid recipient: %w", err)
+	}
+
+	var auth smtp.Auth
+	if m.cfg.Username != "" {
+		host, _, _ := net.SplitHostPort(m.cfg.Addr)
+		auth = smtp.PlainAuth("", m.cfg.Username, m.cfg.Password, host)
+	}
+
+	// smtp.SendMail takes no context, so a cancelled request only stops us waiting
+	done := make(chan error, 1)
+	go func() {
+		done <- m.send(m.cfg.Addr, auth, from.Address, []string{to.Address}, buildMessage(from, to, msg, time.Now()))
+	}()
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func buildMessage(from, to *mail.Address, msg Message, now time.Time) []byte {
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "From: %s\r\n", from.String())
+	fmt.Fprintf(&b, "To: %s\r\n", to.String())
+	fmt.Fprintf(&b, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", msg.Subject))
+	fmt.Fprintf(&b, "Date: %s\r\n", now.Format(time.RFC1123Z))
+	b.WriteString("MIME-Version: 1.0\r\n")
+	b.WriteString("Content-Type: text/plain; charset=utf-8\r\n")
+	b.WriteString("Content-Transfer-Encoding: 8bit\r\n\r\n")
+	b.WriteString(strings.ReplaceAll(strings.ReplaceAll(msg.Body, "\r\n", "\n"), "\n", "\r\n"))
+	return b.Bytes()
+}
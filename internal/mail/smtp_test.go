@@ -0,0 +1,44 @@
+package mail
+
+import (
+	"context"
+	"net/smtp"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSMTPMailer_Send(t *testing.T) {
+	var gotAddr, gotFrom string
+	var gotTo []string
+	var gotMsg []byte
+	m := NewSMTPMailer(&SMTPConfig{Addr: "smtp.example.com:587", Username: "app", Password: "pw", From: "Acme <noreply@example.com>"})
+	m.send = func(addr string, a smtp.Auth, from string, to []string, msg []byte) error {
+		require.NotNil(t, a)
+		gotAddr, gotFrom, gotTo, gotMsg = addr, from, to, msg
+		return nil
+	}
+
+	err := m.Send(context.Background(), Message{To: "bob@example.com", Subject: "Join Ünicorn", Body: "line one\nline two"})
+	require.NoError(t, err)
+	require.Equal(t, "smtp.example.com:587", gotAddr)
+	require.Equal(t, "noreply@example.com", gotFrom)
+	require.Equal(t, []string{"bob@example.com"}, gotTo)
+
+	msg := string(gotMsg)
+	require.Contains(t, msg, "To: <bob@example.com>\r\n")
+	require.Contains(t, msg, "Subject: =?utf-8?q?Join_=C3=9Cnicorn?=\r\n")
+	require.True(t, strings.HasSuffix(msg, "\r\n\r\nline one\r\nline two"))
+}
+
+func TestSMTPMailer_InvalidRecipient(t *testing.T) {
+	m := NewSMTPMailer(&SMTPConfig{Addr: "smtp.example.com:25", From: "noreply@example.com"})
+	m.send = func(string, smtp.Auth, string, []string, []byte) error {
+		t.Fatal("send must not be called")
+		return nil
+	}
+
+	err := m.Send(context.Background(), Message{To: "bob@example.com\r\nBcc: eve@example.com", Subject: "hi"})
+	require.Error(t, err)
+}
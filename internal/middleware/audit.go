@@ -0,0 +1,62 @@
+package middleware
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/Atmosfr/user-service/internal/models"
+	"github.com/Atmosfr/user-service/internal/service"
+)
+
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (r *statusRecorder) WriteHeader(status int) {
+	if r.status == 0 {
+		r.status = status
+	}
+	r.ResponseWriter.WriteHeader(status)
+}
+
+func (r *statusRecorder) Write(b []byte) (int, error) {
+	if r.status == 0 {
+		r.status = http.StatusOK
+	}
+	return r.ResponseWriter.Write(b)
+}
+
+// AuditAdminRequests records every request that may change something in the
+// audit log, with the route and the response status. It must run after the
+// auth middleware.
+func AuditAdminRequests(audit service.AuditLog) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Method == http.MethodGet || r.Method == http.MethodHead {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			rec := &statusRecorder{ResponseWriter: w}
+			next.ServeHTTP(rec, r)
+			if rec.status == 0 {
+				rec.status = http.StatusOK
+			}
+
+			event := &models.AuditEvent{
+				Action: models.AuditAdminRequest,
+				Details: map[string]string{
+					"method": r.Method,
+					"route":  r.Pattern,
+					"path":   r.URL.Path,
+					"status": strconv.Itoa(rec.status),
+				},
+			}
+			if user, ok := GetUserFromContext(r.Context()); ok {
+				event.ActorID = &user.ID
+			}
+			audit.Record(r.Context(), event)
+		})
+	}
+}
@@ -2,35 +2,120 @@ package middleware
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
 	"net/http"
 	"strings"
+	"time"
 
 	"github.com/Atmosfr/user-service/internal/auth"
 	"github.com/Atmosfr/user-service/internal/models"
 	"github.com/Atmosfr/user-service/internal/repository"
+	"github.com/Atmosfr/user-service/internal/service"
 )
 
 type contextKey string
 
-const userKey contextKey = "user"
+const (
+	userKey           contextKey = "user"
+	accessTokenKey    contextKey = "access_token"
+	serviceAccountKey contextKey = "service_account"
+	organizationKey   contextKey = "organization"
+)
 
 func GetUserFromContext(ctx context.Context) (*models.User, bool) {
 	user, ok := ctx.Value(userKey).(*models.User)
 	return user, ok
 }
 
-func NewAuthMiddleware(repo repository.UserRepository) func(http.Handler) http.Handler {
+// GetAccessTokenFromContext returns the personal access token the request was
+// authenticated with. It is absent for requests carrying a session JWT.
+func GetAccessTokenFromContext(ctx context.Context) (*models.PersonalAccessToken, bool) {
+	token, ok := ctx.Value(accessTokenKey).(*models.PersonalAccessToken)
+	return token, ok
+}
+
+// GetServiceAccountFromContext returns the service account the request was
+// authenticated as. Requests from service accounts carry no user.
+func GetServiceAccountFromContext(ctx context.Context) (*models.ServiceAccount, bool) {
+	sa, ok := ctx.Value(serviceAccountKey).(*models.ServiceAccount)
+	return sa, ok
+}
+
+// GetOrganizationFromContext returns the user's membership in the active
+// organization named by the token's org_id claim.
+func GetOrganizationFromContext(ctx context.Context) (*models.OrganizationMember, bool) {
+	member, ok := ctx.Value(organizationKey).(*models.OrganizationMember)
+	return member, ok
+}
+
+type authConfig struct {
+	tokens          service.TokenService
+	serviceAccounts service.ServiceAccountService
+	rbac            service.RBACService
+	organizations   service.OrganizationService
+	consents        service.ConsentService
+}
+
+type AuthOption func(*authConfig)
+
+// WithPersonalAccessTokens makes the middleware accept personal access tokens
+// in the Bearer header alongside JWTs.
+func WithPersonalAccessTokens(tokens service.TokenService) AuthOption {
+	return func(c *authConfig) {
+		c.tokens = tokens
+	}
+}
+
+// WithServiceAccounts makes the middleware accept service account API keys and
+// access tokens. Only use it on routes whose handlers do not require a user.
+func WithServiceAccounts(serviceAccounts service.ServiceAccountService) AuthOption {
+	return func(c *authConfig) {
+		c.serviceAccounts = serviceAccounts
+	}
+}
+
+// WithPermissions makes the user's role permissions available to RequirePermission.
+// They are resolved lazily, once per request.
+func WithPermissions(rbac service.RBACService) AuthOption {
+	return func(c *authConfig) {
+		c.rbac = rbac
+	}
+}
+
+// WithOrganizations resolves the active organization of session tokens. Tokens
+// whose user has since left the organization are rejected.
+func WithOrganizations(organizations service.OrganizationService) AuthOption {
+	return func(c *authConfig) {
+		c.organizations = organizations
+	}
+}
+
+// WithConsentGate rejects users with service.ErrConsentRequired until they
+// accepted every mandatory document version. The routes to accept them must
+// be served without it.
+func WithConsentGate(consents service.ConsentService) AuthOption {
+	return func(c *authConfig) {
+		c.consents = consents
+	}
+}
+
+func NewAuthMiddleware(repo repository.UserRepository, opts ...AuthOption) func(http.Handler) http.Handler {
+	cfg := &authConfig{}
+	for _, opt := range opts {
+		opt(cfg)
+	}
+
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			authHeader := r.Header.Get("Authorization")
-
 			if authHeader == "" {
 				http.Error(w, `{"error": "missing Authorization header"}`, http.StatusUnauthorized)
 				return
 			}
 
 			parts := strings.Split(authHeader, " ")
-
 			if len(parts) != 2 || parts[0] != "Bearer" {
 				http.Error(w, `{"error": "invalid Authorization header format"}`, http.StatusUnauthorized)
 				return
@@ -42,20 +127,90 @@ func NewAuthMiddleware(repo repository.UserRepository) func(http.Handler) http.H
 				return
 			}
 
-			user, err := auth.ValidateToken(token, auth.JwtSecret)
-			if err != nil {
-				http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			ctx := r.Context()
+			var userID, orgID int64
+			switch {
+			case cfg.tokens != nil && strings.HasPrefix(token, service.PersonalAccessTokenPrefix):
+				pat, err := cfg.tokens.Authenticate(ctx, token)
+				if err != nil {
+					http.Error(w, "Unauthorized", http.StatusUnauthorized)
+					return
+				}
+				userID = pat.UserID
+				ctx = context.WithValue(ctx, accessTokenKey, pat)
+			case cfg.serviceAccounts != nil && strings.HasPrefix(token, service.APIKeyPrefix):
+				sa, err := cfg.serviceAccounts.AuthenticateAPIKey(ctx, token)
+				if err != nil {
+					http.Error(w, "Unauthorized", http.StatusUnauthorized)
+					return
+				}
+				next.ServeHTTP(w, r.WithContext(context.WithValue(ctx, serviceAccountKey, sa)))
 				return
+			default:
+				claims, err := auth.ParseToken(token, auth.JwtSecret)
+				if err != nil {
+					http.Error(w, "Unauthorized", http.StatusUnauthorized)
+					return
+				}
+				if claims.PrincipalType == auth.PrincipalServiceAccount {
+					if cfg.serviceAccounts == nil {
+						http.Error(w, "Unauthorized", http.StatusUnauthorized)
+						return
+					}
+					sa, err := cfg.serviceAccounts.Get(ctx, claims.ServiceAccountID)
+					if err != nil {
+						http.Error(w, "Unauthorized", http.StatusUnauthorized)
+						return
+					}
+					next.ServeHTTP(w, r.WithContext(context.WithValue(ctx, serviceAccountKey, sa)))
+					return
+				}
+				userID = claims.UserID
+				orgID = claims.OrgID
 			}
 
-			fullUser, err := repo.FindByID(r.Context(), user.ID)
+			fullUser, err := repo.FindByID(ctx, userID)
 			if err != nil {
 				http.Error(w, "Unauthorized", http.StatusUnauthorized)
 				return
 			}
+			// tokens issued before a suspension or ban stop working with it
+			if err := service.CheckAccountState(fullUser, time.Now()); err != nil {
+				http.Error(w, fmt.Sprintf(`{"error": %q}`, err.Error()), http.StatusForbidden)
+				return
+			}
+			if cfg.consents != nil {
+				pending, err := cfg.consents.Pending(ctx, fullUser.ID)
+				if err != nil {
+					http.Error(w, `{"error": "internal server error"}`, http.StatusInternalServerError)
+					return
+				}
+				if len(pending) > 0 {
+					body, _ := json.Marshal(map[string]any{"error": service.ErrConsentRequired.Error(), "pending": pending})
+					http.Error(w, string(body), http.StatusForbidden)
+					return
+				}
+			}
 
-			ctx := context.WithValue(r.Context(), userKey, fullUser)
-
+			ctx = context.WithValue(ctx, userKey, fullUser)
+			if orgID != 0 && cfg.organizations != nil {
+				member, err := cfg.organizations.Membership(ctx, orgID, userID)
+				if errors.Is(err, repository.ErrMemberNotFound) {
+					http.Error(w, `{"error": "no longer a member of the active organization"}`, http.StatusUnauthorized)
+					return
+				}
+				if err != nil {
+					http.Error(w, `{"error": "internal server error"}`, http.StatusInternalServerError)
+					return
+				}
+				ctx = context.WithValue(ctx, organizationKey, member)
+			}
+			if cfg.rbac != nil {
+				rbacCtx := ctx
+				ctx = context.WithValue(ctx, permissionsKey, &permissionCache{resolve: func() (models.PermissionSet, error) {
+					return cfg.rbac.Permissions(rbacCtx, fullUser.Role)
+				}})
+			}
 			next.ServeHTTP(w, r.WithContext(ctx))
 		})
 	}
@@ -0,0 +1,88 @@
+package middleware
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"sync"
+
+	"github.com/Atmosfr/user-service/internal/models"
+)
+
+const permissionsKey contextKey = "permissions"
+
+// permissionCache resolves the user's permissions at most once per request, no
+// matter how many guards or handlers ask for them.
+type permissionCache struct {
+	once        sync.Once
+	resolve     func() (models.PermissionSet, error)
+	permissions models.PermissionSet
+	err         error
+}
+
+func (c *permissionCache) get() (models.PermissionSet, error) {
+	c.once.Do(func() {
+		c.permissions, c.err = c.resolve()
+	})
+	return c.permissions, c.err
+}
+
+// GetPermissionsFromContext returns the permissions of the authenticated user.
+// It reports false when the auth middleware was not configured with WithPermissions
+// or the request was not made by a user.
+func GetPermissionsFromContext(ctx context.Context) (models.PermissionSet, bool, error) {
+	cache, ok := ctx.Value(permissionsKey).(*permissionCache)
+	if !ok {
+		return nil, false, nil
+	}
+	permissions, err := cache.get()
+	return permissions, true, err
+}
+
+// HasPermission reports whether the authenticated user holds permission.
+func HasPermission(ctx context.Context, permission string) bool {
+	permissions, ok, err := GetPermissionsFromContext(ctx)
+	return ok && err == nil && permissions.Has(permission)
+}
+
+// RequirePermission must be chained after an auth middleware created with
+// WithPermissions. The user needs every one of permissions.
+func RequirePermission(permissions ...string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			granted, ok, err := GetPermissionsFromContext(r.Context())
+			if err != nil {
+				slog.Error("failed to resolve permissions", "err", err)
+				http.Error(w, `{"error": "internal server error"}`, http.StatusInternalServerError)
+				return
+			}
+			if !ok {
+				http.Error(w, `{"error": "forbidden"}`, http.StatusForbidden)
+				return
+			}
+			for _, permission := range permissions {
+				if !granted.Has(permission) {
+					http.Error(w, `{"error": "forbidden"}`, http.StatusForbidden)
+					return
+				}
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// RequirePermissionOrServiceAccount lets service accounts through and otherwise
+// behaves like RequirePermission. Service accounts have no roles, so routes meant
+// for other services use it to admit them.
+func RequirePermissionOrServiceAccount(permissions ...string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		guarded := RequirePermission(permissions...)(next)
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if _, ok := GetServiceAccountFromContext(r.Context()); ok {
+				next.ServeHTTP(w, r)
+				return
+			}
+			guarded.ServeHTTP(w, r)
+		})
+	}
+}
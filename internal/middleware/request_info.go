@@ -0,0 +1,43 @@
+package middleware
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"net"
+	"net/http"
+	"regexp"
+
+	"github.com/Atmosfr/user-service/internal/service"
+)
+
+const RequestIDHeader = "X-Request-ID"
+
+// requestIDPattern is what an incoming request id must look like to be kept,
+// anything else is replaced so it can safely end up in logs.
+var requestIDPattern = regexp.MustCompile(`^[A-Za-z0-9._-]{1,128}$`)
+
+func newRequestID() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// RequestInfo passes the request id, client address and user agent to the
+// audit log. It keeps a well-formed X-Request-ID from the caller, generates
+// one otherwise and echoes it in the response.
+func RequestInfo(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get(RequestIDHeader)
+		if !requestIDPattern.MatchString(id) {
+			id = newRequestID()
+		}
+		ip := r.RemoteAddr
+		if host, _, err := net.SplitHostPort(r.RemoteAddr); err == nil {
+			ip = host
+		}
+		w.Header().Set(RequestIDHeader, id)
+
+		ctx := service.ContextWithRequestInfo(r.Context(), service.RequestInfo{ID: id, IP: ip, UserAgent: r.UserAgent()})
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
@@ -0,0 +1,47 @@
+package middleware
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/Atmosfr/user-service/internal/models"
+	"github.com/Atmosfr/user-service/internal/scim"
+	"github.com/Atmosfr/user-service/internal/service"
+)
+
+const scimTenantKey contextKey = "scim_tenant"
+
+func GetSCIMTenantFromContext(ctx context.Context) (*models.SCIMTenant, bool) {
+	tenant, ok := ctx.Value(scimTenantKey).(*models.SCIMTenant)
+	return tenant, ok
+}
+
+func NewSCIMAuthMiddleware(svc service.SCIMService) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+			if !ok || token == "" {
+				writeSCIMUnauthorized(w)
+				return
+			}
+
+			tenant, err := svc.Authenticate(r.Context(), token)
+			if err != nil {
+				writeSCIMUnauthorized(w)
+				return
+			}
+
+			ctx := context.WithValue(r.Context(), scimTenantKey, tenant)
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+func writeSCIMUnauthorized(w http.ResponseWriter) {
+	w.Header().Set("Content-Type", scim.ContentType)
+	w.Header().Set("WWW-Authenticate", `Bearer realm="scim"`)
+	w.WriteHeader(http.StatusUnauthorized)
+	json.NewEncoder(w).Encode(scim.NewError(http.StatusUnauthorized, "", "authorization failure"))
+}
@@ -0,0 +1,47 @@
+package models
+
+import "time"
+
+// Account states. Only active accounts can sign in.
+const (
+	AccountActive              = "active"
+	AccountSuspended           = "suspended"
+	AccountBanned              = "banned"
+	AccountPendingVerification = "pending_verification"
+	// AccountDeleted marks accounts deleted by their owner, an administrator
+	// or the identity provider. They are purged after a grace period.
+	AccountDeleted = "deleted"
+)
+
+// Reason codes of state changes. Administrators pick one of the first group,
+// the others are recorded by the service itself.
+const (
+	StateReasonSpam           = "spam"
+	StateReasonAbuse          = "abuse"
+	StateReasonFraud          = "fraud"
+	StateReasonTermsViolation = "terms_violation"
+	StateReasonCompromised    = "compromised"
+	StateReasonUserRequest    = "user_request"
+	StateReasonOther          = "other"
+
+	StateReasonSuspensionExpired = "suspension_expired"
+	StateReasonEmailVerified     = "email_verified"
+	// StateReasonSCIM marks accounts the identity provider deactivated or deleted.
+	StateReasonSCIM = "scim"
+	// StateReasonAdminDeletion marks accounts an administrator deleted.
+	StateReasonAdminDeletion = "admin_deletion"
+)
+
+// AccountStateChange is an entry in a user's state history. ActorID is nil
+// for changes the service made itself.
+type AccountStateChange struct {
+	ID        int64      `json:"id"`
+	UserID    int64      `json:"user_id"`
+	From      string     `json:"from"`
+	To        string     `json:"to"`
+	Reason    string     `json:"reason"`
+	Note      string     `json:"note,omitempty"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+	ActorID   *int64     `json:"actor_id,omitempty"`
+	CreatedAt time.Time  `json:"created_at"`
+}
@@ -0,0 +1,41 @@
+package models
+
+import "time"
+
+// Audit actions.
+const (
+	AuditUserRegistered = "user.registered"
+	AuditLoginSucceeded = "login.succeeded"
+	AuditLoginFailed    = "login.failed"
+	// AuditPasswordChanged is recorded when a SCIM client sets a password, the
+	// only way to change one after registration.
+	AuditPasswordChanged = "password.changed"
+	// AuditRoleChanged covers the global role and organization roles. For the
+	// latter Details name the organization_id, and an empty "to" means the
+	// user left the organization.
+	AuditRoleChanged     = "user.role_changed"
+	AuditUserDeleted     = "user.deleted"
+	AuditStateChanged    = "user.state_changed"
+	AuditAccountDeleted  = "account.deleted"
+	AuditAccountRestored = "account.restored"
+	AuditAccountPurged   = "account.purged"
+	// AuditAdminRequest is any change made through an admin route.
+	AuditAdminRequest = "admin.request"
+)
+
+// AuditEvent is an entry in the audit log. ActorID is the user who acted, nil
+// for anonymous requests and the service itself, TargetID the user acted on.
+// Hash covers the event and PrevHash, the hash of the event before it.
+type AuditEvent struct {
+	ID        int64             `json:"id"`
+	Action    string            `json:"action"`
+	ActorID   *int64            `json:"actor_id,omitempty"`
+	TargetID  *int64            `json:"target_id,omitempty"`
+	IP        string            `json:"ip,omitempty"`
+	UserAgent string            `json:"user_agent,omitempty"`
+	RequestID string            `json:"request_id,omitempty"`
+	Details   map[string]string `json:"details,omitempty"`
+	CreatedAt time.Time         `json:"created_at"`
+	PrevHash  string            `json:"prev_hash"`
+	Hash      string            `json:"hash"`
+}
@@ -0,0 +1,35 @@
+package models
+
+import "time"
+
+const (
+	DocumentTermsOfService = "terms_of_service"
+	DocumentPrivacyPolicy  = "privacy_policy"
+	// ConsentMarketing is the kind of marketing consents, which have no document.
+	ConsentMarketing = "marketing"
+)
+
+// LegalDocument is one version of the terms of service or privacy policy. A
+// mandatory version must be accepted by every user once it is published.
+type LegalDocument struct {
+	ID          int64     `db:"id" json:"id"`
+	Kind        string    `db:"kind" json:"kind"`
+	Version     string    `db:"version" json:"version"`
+	URL         string    `db:"url" json:"url"`
+	Mandatory   bool      `db:"mandatory" json:"mandatory"`
+	PublishedAt time.Time `db:"published_at" json:"published_at"`
+	CreatedAt   time.Time `db:"created_at" json:"created_at"`
+}
+
+// Consent records that a user accepted a document version, or granted or
+// withdrew marketing consent. Version is empty for marketing.
+type Consent struct {
+	ID         int64     `db:"id" json:"id"`
+	UserID     int64     `db:"user_id" json:"user_id"`
+	Kind       string    `db:"kind" json:"kind"`
+	DocumentID *int64    `db:"document_id" json:"document_id,omitempty"`
+	Version    string    `db:"-" json:"version,omitempty"`
+	Granted    bool      `db:"granted" json:"granted"`
+	IPAddress  string    `db:"ip_address" json:"ip_address"`
+	CreatedAt  time.Time `db:"created_at" json:"created_at"`
+}
@@ -0,0 +1,25 @@
+package models
+
+import "time"
+
+const (
+	DataExportPending = "pending"
+	DataExportRunning = "running"
+	DataExportReady   = "ready"
+	DataExportFailed  = "failed"
+	// DataExportExpired is recorded once the archive of a ready export is deleted.
+	DataExportExpired = "expired"
+)
+
+// DataExport is a ZIP archive of everything stored about a user. Size and
+// ExpiresAt are set once it is ready for download.
+type DataExport struct {
+	ID          int64      `db:"id" json:"id"`
+	UserID      int64      `db:"user_id" json:"user_id"`
+	Status      string     `db:"status" json:"status"`
+	BlobKey     string     `db:"blob_key" json:"-"`
+	Size        int64      `db:"size" json:"size,omitempty"`
+	ExpiresAt   *time.Time `db:"expires_at" json:"expires_at,omitempty"`
+	CompletedAt *time.Time `db:"completed_at" json:"completed_at,omitempty"`
+	CreatedAt   time.Time  `db:"created_at" json:"created_at"`
+}
@@ -0,0 +1,26 @@
+package models
+
+import "time"
+
+// OrganizationDomain is an email domain claimed by an organization. Only a
+// verified domain lets users join automatically or enforces single sign-on.
+type OrganizationDomain struct {
+	ID                int64      `db:"id" json:"id"`
+	OrganizationID    int64      `db:"organization_id" json:"organization_id"`
+	Domain            string     `db:"domain" json:"domain"`
+	VerificationToken string     `db:"verification_token" json:"-"`
+	VerifiedAt        *time.Time `db:"verified_at" json:"verified_at"`
+	AutoJoin          bool       `db:"auto_join" json:"auto_join"`
+	SSOEnforced       bool       `db:"sso_enforced" json:"sso_enforced"`
+	CreatedAt         time.Time  `db:"created_at" json:"created_at"`
+	UpdatedAt         time.Time  `db:"updated_at" json:"updated_at"`
+
+	// TXTRecordName and TXTRecordValue describe the DNS record that proves
+	// ownership. They are only set while the domain is unverified.
+	TXTRecordName  string `db:"-" json:"txt_record_name,omitempty"`
+	TXTRecordValue string `db:"-" json:"txt_record_value,omitempty"`
+}
+
+func (d *OrganizationDomain) IsVerified() bool {
+	return d.VerifiedAt != nil
+}
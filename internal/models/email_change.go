@@ -0,0 +1,27 @@
+package models
+
+import "time"
+
+const (
+	EmailChangePending   = "pending"
+	EmailChangeConfirmed = "confirmed"
+	// EmailChangeCancelled is recorded when the old address cancels a pending
+	// change or a new request replaces it.
+	EmailChangeCancelled = "cancelled"
+	EmailChangeReverted  = "reverted"
+)
+
+// EmailChange moves a user from OldEmail to NewEmail once the new address is
+// confirmed. The old address can cancel it, or revert it until RevertibleUntil.
+type EmailChange struct {
+	ID              int64      `db:"id" json:"id"`
+	UserID          int64      `db:"user_id" json:"user_id"`
+	OldEmail        string     `db:"old_email" json:"old_email"`
+	NewEmail        string     `db:"new_email" json:"new_email"`
+	Status          string     `db:"status" json:"status"`
+	ExpiresAt       time.Time  `db:"expires_at" json:"expires_at"`
+	RevertibleUntil time.Time  `db:"revertible_until" json:"revertible_until"`
+	ConfirmedAt     *time.Time `db:"confirmed_at" json:"confirmed_at,omitempty"`
+	CreatedAt       time.Time  `db:"created_at" json:"created_at"`
+	UpdatedAt       time.Time  `db:"updated_at" json:"updated_at"`
+}
@@ -0,0 +1,24 @@
+package models
+
+import "time"
+
+// Group is a team inside an organization. Groups nest: members of a subgroup
+// are effective members of all of its ancestors.
+type Group struct {
+	ID             int64     `db:"id" json:"id"`
+	OrganizationID int64     `db:"organization_id" json:"organization_id"`
+	ParentID       *int64    `db:"parent_id" json:"parent_id"`
+	Name           string    `db:"name" json:"name"`
+	Description    string    `db:"description" json:"description"`
+	CreatedAt      time.Time `db:"created_at" json:"created_at"`
+	UpdatedAt      time.Time `db:"updated_at" json:"updated_at"`
+}
+
+type GroupMember struct {
+	// GroupID is the group the user was added to, a subgroup for inherited memberships.
+	GroupID   int64     `db:"group_id" json:"group_id"`
+	UserID    int64     `db:"user_id" json:"user_id"`
+	Email     string    `db:"email" json:"email"`
+	Username  string    `db:"username" json:"username"`
+	CreatedAt time.Time `db:"created_at" json:"created_at"`
+}
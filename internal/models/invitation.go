@@ -0,0 +1,32 @@
+package models
+
+import "time"
+
+const (
+	InvitationPending  = "pending"
+	InvitationAccepted = "accepted"
+	InvitationRevoked  = "revoked"
+	// InvitationExpired is recorded when a new invitation replaces one that ran out.
+	InvitationExpired = "expired"
+)
+
+// Invitation asks the owner of Email to join an organization with Role.
+type Invitation struct {
+	ID               int64      `db:"id" json:"id"`
+	OrganizationID   int64      `db:"organization_id" json:"organization_id"`
+	OrganizationName string     `db:"organization_name" json:"organization_name"`
+	Email            string     `db:"email" json:"email"`
+	Role             string     `db:"role" json:"role"`
+	InvitedBy        *int64     `db:"invited_by" json:"invited_by"`
+	Status           string     `db:"status" json:"status"`
+	ExpiresAt        time.Time  `db:"expires_at" json:"expires_at"`
+	AcceptedBy       *int64     `db:"accepted_by" json:"accepted_by,omitempty"`
+	AcceptedAt       *time.Time `db:"accepted_at" json:"accepted_at,omitempty"`
+	CreatedAt        time.Time  `db:"created_at" json:"created_at"`
+	UpdatedAt        time.Time  `db:"updated_at" json:"updated_at"`
+}
+
+// IsOpen reports whether the invitation can still be accepted at now.
+func (i *Invitation) IsOpen(now time.Time) bool {
+	return i.Status == InvitationPending && now.Before(i.ExpiresAt)
+}
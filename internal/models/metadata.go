@@ -0,0 +1,25 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+const (
+	// MetadataPublic is readable by the user it belongs to, MetadataPrivate only
+	// by administrators.
+	MetadataPublic  = "public"
+	MetadataPrivate = "private"
+)
+
+// MetadataNamespace registers a top-level key of a user's public or private
+// metadata. Values written to it must match Schema.
+type MetadataNamespace struct {
+	Name       string          `db:"name" json:"name"`
+	Visibility string          `db:"visibility" json:"visibility"`
+	Schema     json.RawMessage `db:"schema" json:"schema"`
+	// InTokenClaims copies the namespace into the metadata claim of access tokens.
+	InTokenClaims bool      `db:"in_token_claims" json:"in_token_claims"`
+	CreatedAt     time.Time `db:"created_at" json:"created_at"`
+	UpdatedAt     time.Time `db:"updated_at" json:"updated_at"`
+}
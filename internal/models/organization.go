@@ -0,0 +1,40 @@
+package models
+
+import "time"
+
+// Roles a user can hold inside an organization. They are independent of the
+// global role in users.role.
+const (
+	OrgRoleOwner  = "owner"
+	OrgRoleAdmin  = "admin"
+	OrgRoleMember = "member"
+)
+
+func IsValidOrgRole(role string) bool {
+	return role == OrgRoleOwner || role == OrgRoleAdmin || role == OrgRoleMember
+}
+
+type Organization struct {
+	ID        int64     `db:"id" json:"id"`
+	Name      string    `db:"name" json:"name"`
+	Slug      string    `db:"slug" json:"slug"`
+	CreatedBy *int64    `db:"created_by" json:"created_by"`
+	CreatedAt time.Time `db:"created_at" json:"created_at"`
+	UpdatedAt time.Time `db:"updated_at" json:"updated_at"`
+	// Role is the requesting user's role in the organization.
+	Role string `db:"role" json:"role,omitempty"`
+}
+
+type OrganizationMember struct {
+	OrganizationID int64     `db:"organization_id" json:"organization_id"`
+	UserID         int64     `db:"user_id" json:"user_id"`
+	Email          string    `db:"email" json:"email"`
+	Username       string    `db:"username" json:"username"`
+	Role           string    `db:"role" json:"role"`
+	CreatedAt      time.Time `db:"created_at" json:"created_at"`
+}
+
+// CanManageMembers reports whether the member may add, remove and change members.
+func (m *OrganizationMember) CanManageMembers() bool {
+	return m.Role == OrgRoleOwner || m.Role == OrgRoleAdmin
+}
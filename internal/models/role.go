@@ -0,0 +1,27 @@
+package models
+
+import "strings"
+
+type Role struct {
+	ID          int64  `db:"id" json:"id"`
+	Name        string `db:"name" json:"name"`
+	Description string `db:"description" json:"description"`
+	// Parent is the role this one inherits permissions from.
+	Parent string `db:"parent" json:"parent,omitempty"`
+	// Permissions are the role's effective permissions, including inherited ones.
+	Permissions []string `json:"permissions"`
+}
+
+// PermissionSet holds permission strings such as "users:read". A "*" entry grants
+// everything and "users:*" grants every permission in the users namespace.
+type PermissionSet []string
+
+func (s PermissionSet) Has(permission string) bool {
+	namespace, _, _ := strings.Cut(permission, ":")
+	for _, p := range s {
+		if p == permission || p == "*" || p == namespace+":*" {
+			return true
+		}
+	}
+	return false
+}
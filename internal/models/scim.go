@@ -0,0 +1,30 @@
+package models
+
+import "time"
+
+type SCIMTenant struct {
+	ID        int64     `db:"id" json:"id"`
+	Name      string    `db:"name" json:"name"`
+	CreatedAt time.Time `db:"created_at" json:"created_at"`
+}
+
+type SCIMUser struct {
+	User
+	TenantID   int64  `db:"scim_tenant_id" json:"-"`
+	ExternalID string `db:"external_id" json:"external_id,omitempty"`
+}
+
+type SCIMGroupMember struct {
+	UserID   int64  `db:"user_id" json:"user_id"`
+	Username string `db:"username" json:"username"`
+}
+
+type SCIMGroup struct {
+	ID          int64             `db:"id" json:"id"`
+	TenantID    int64             `db:"tenant_id" json:"-"`
+	DisplayName string            `db:"display_name" json:"display_name"`
+	ExternalID  string            `db:"external_id" json:"external_id,omitempty"`
+	Members     []SCIMGroupMember `json:"members"`
+	CreatedAt   time.Time         `db:"created_at" json:"created_at"`
+	UpdatedAt   time.Time         `db:"updated_at" json:"updated_at"`
+}
@@ -0,0 +1,36 @@
+package models
+
+import "time"
+
+const (
+	ServiceAccountKeyTypeAPIKey    = "api_key"
+	ServiceAccountKeyTypePublicKey = "public_key"
+)
+
+// ServiceAccount is a non-human principal. It has no password and authenticates
+// with API keys or JWT client assertions signed by one of its public keys.
+type ServiceAccount struct {
+	ID            int64     `db:"id" json:"id"`
+	Name          string    `db:"name" json:"name"`
+	Description   string    `db:"description" json:"description"`
+	OwnerID       int64     `db:"owner_id" json:"owner_id"`
+	OwnerUsername string    `db:"owner_username" json:"owner_username,omitempty"`
+	CreatedAt     time.Time `db:"created_at" json:"created_at"`
+	UpdatedAt     time.Time `db:"updated_at" json:"updated_at"`
+}
+
+type ServiceAccountKey struct {
+	ID               int64      `db:"id" json:"id"`
+	ServiceAccountID int64      `db:"service_account_id" json:"service_account_id"`
+	Type             string     `db:"type" json:"type"`
+	Name             string     `db:"name" json:"name"`
+	Prefix           string     `db:"key_prefix" json:"prefix,omitempty"`
+	PublicKey        string     `db:"public_key" json:"public_key,omitempty"`
+	ExpiresAt        *time.Time `db:"expires_at" json:"expires_at"`
+	LastUsedAt       *time.Time `db:"last_used_at" json:"last_used_at"`
+	CreatedAt        time.Time  `db:"created_at" json:"created_at"`
+}
+
+func (k *ServiceAccountKey) Expired(now time.Time) bool {
+	return k.ExpiresAt != nil && !now.Before(*k.ExpiresAt)
+}
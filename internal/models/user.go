@@ -1,6 +1,9 @@
 package models
 
-import "time"
+import (
+	"encoding/json"
+	"time"
+)
 
 type User struct {
 	ID           int64     `db:"id" json:"id"`
@@ -11,4 +14,32 @@ type User struct {
 	UpdatedAt    time.Time `db:"updated_at" json:"updated_at"`
 	IsActive     bool      `db:"is_active" json:"is_active"`
 	Role         string    `db:"role" json:"role"`
+	// EmailVerifiedAt is set once the user proved they own Email.
+	EmailVerifiedAt *time.Time `db:"email_verified_at" json:"email_verified_at,omitempty"`
+
+	// State is one of the Account* constants and IsActive mirrors State ==
+	// AccountActive. StateReason is the reason code of the last change and
+	// StateExpiresAt ends a suspension.
+	State          string     `db:"state" json:"state"`
+	StateReason    string     `db:"state_reason" json:"state_reason,omitempty"`
+	StateExpiresAt *time.Time `db:"state_expires_at" json:"state_expires_at,omitempty"`
+	// DeletedAt is set while State is AccountDeleted.
+	DeletedAt *time.Time `db:"deleted_at" json:"deleted_at,omitempty"`
+
+	// Profile fields, empty when not set. Locale is a BCP 47 tag and Timezone an
+	// IANA name such as "Europe/Berlin".
+	DisplayName string `db:"display_name" json:"display_name"`
+	Locale      string `db:"locale" json:"locale"`
+	Timezone    string `db:"timezone" json:"timezone"`
+	AvatarURL   string `db:"avatar_url" json:"avatar_url"`
+
+	// AvatarURLs maps the sizes of an uploaded avatar, e.g. "64", to their URLs.
+	// AvatarKey is the blob key prefix they are stored under.
+	AvatarURLs map[string]string `db:"avatar_urls" json:"avatar_urls,omitempty"`
+	AvatarKey  string            `db:"avatar_key" json:"-"`
+
+	// Metadata objects keyed by namespace. Private metadata is only shown to
+	// administrators.
+	PublicMetadata  json.RawMessage `db:"public_metadata" json:"public_metadata,omitempty"`
+	PrivateMetadata json.RawMessage `db:"private_metadata" json:"-"`
 }
@@ -0,0 +1,40 @@
+package policy
+
+import (
+	"net"
+	"net/http"
+
+	"github.com/Atmosfr/user-service/internal/models"
+)
+
+// SubjectFromUser builds subject attributes from a user. Callers may add more,
+// e.g. permissions or region, before evaluating.
+func SubjectFromUser(user *models.User) Attributes {
+	return Attributes{
+		"type":       "user",
+		"id":         user.ID,
+		"email":      user.Email,
+		"username":   user.Username,
+		"role":       user.Role,
+		"is_active":  user.IsActive,
+		"created_at": user.CreatedAt,
+	}
+}
+
+// ResourceFromUser builds resource attributes for a user being acted upon.
+func ResourceFromUser(user *models.User) Attributes {
+	return SubjectFromUser(user)
+}
+
+// ContextFromRequest builds request context attributes. Time attributes are added by the engine.
+func ContextFromRequest(r *http.Request) Attributes {
+	ip := r.RemoteAddr
+	if host, _, err := net.SplitHostPort(r.RemoteAddr); err == nil {
+		ip = host
+	}
+	return Attributes{
+		"ip":     ip,
+		"method": r.Method,
+		"path":   r.URL.Path,
+	}
+}
@@ -0,0 +1,308 @@
+package policy
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// FileExtension is the extension of policy files loaded from a policy directory.
+const FileExtension = ".policy"
+
+type Effect string
+
+const (
+	Allow Effect = "allow"
+	Deny  Effect = "deny"
+)
+
+type Policy struct {
+	Name        string
+	Description string
+	Effect      Effect
+	// Actions may end in "*" to match a whole namespace, e.g. "users:*".
+	Actions []string
+	// Resource is matched against the resource's "type" attribute, "*" matches any.
+	Resource string
+	// When is optional, a policy without a condition applies to every matching request.
+	When   Expr
+	Source string
+}
+
+type Attributes map[string]any
+
+type Input struct {
+	Subject  Attributes `json:"subject"`
+	Action   string     `json:"action"`
+	Resource Attributes `json:"resource"`
+	Context  Attributes `json:"context"`
+}
+
+type Decision struct {
+	Allowed bool   `json:"allowed"`
+	Policy  string `json:"policy,omitempty"`
+	Reason  string `json:"reason"`
+	// Errors lists conditions that failed to evaluate.
+	Errors []string `json:"errors,omitempty"`
+}
+
+func matchPattern(pattern, value string) bool {
+	if prefix, ok := strings.CutSuffix(pattern, "*"); ok {
+		return strings.HasPrefix(value, prefix)
+	}
+	return pattern == value
+}
+
+func (p *Policy) applies(in *Input) bool {
+	if p.Resource != "*" {
+		if t, _ := in.Resource["type"].(string); t != p.Resource {
+			return false
+		}
+	}
+	for _, action := range p.Actions {
+		if matchPattern(action, in.Action) {
+			return true
+		}
+	}
+	return false
+}
+
+// Evaluate combines policies with deny-overrides: a matching deny wins over any
+// allow, and a request no policy allows is denied. A deny policy whose condition
+// fails to evaluate counts as matching so that errors fail closed.
+func Evaluate(policies []*Policy, in Input) Decision {
+	var allowedBy *Policy
+	var errs []string
+
+	for _, p := range policies {
+		if !p.applies(&in) {
+			continue
+		}
+
+		matched := true
+		if p.When != nil {
+			v, err := p.When.Eval(&in)
+			if err != nil {
+				errs = append(errs, fmt.Sprintf("%s: %v", p.Name, err))
+				if p.Effect == Deny {
+					return Decision{Allowed: false, Policy: p.Name, Reason: "deny condition failed to evaluate", Errors: errs}
+				}
+				continue
+			}
+			matched = v == true
+		}
+		if !matched {
+			continue
+		}
+
+		if p.Effect == Deny {
+			return Decision{Allowed: false, Policy: p.Name, Reason: "denied by policy", Errors: errs}
+		}
+		if allowedBy == nil {
+			allowedBy = p
+		}
+	}
+
+	if allowedBy != nil {
+		return Decision{Allowed: true, Policy: allowedBy.Name, Reason: "allowed by policy", Errors: errs}
+	}
+	return Decision{Allowed: false, Reason: "no policy allows this request", Errors: errs}
+}
+
+// LoadDir parses every policy file in dir, in file name order.
+func LoadDir(dir string) ([]*Policy, error) {
+	paths, err := filepath.Glob(filepath.Join(dir, "*"+FileExtension))
+	if err != nil {
+		return nil, err
+	}
+	if _, err := os.Stat(dir); err != nil {
+		return nil, err
+	}
+	sort.Strings(paths)
+
+	var policies []*Policy
+	names := map[string]string{}
+	for _, path := range paths {
+		src, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		parsed, err := ParseFile(filepath.Base(path), string(src))
+		if err != nil {
+			return nil, err
+		}
+		for _, p := range parsed {
+			if other, ok := names[p.Name]; ok {
+				return nil, fmt.Errorf("%s: policy %q is already defined in %s", p.Source, p.Name, other)
+			}
+			names[p.Name] = p.Source
+		}
+		policies = append(policies, parsed...)
+	}
+	return policies, nil
+}
+
+// fingerprint changes whenever a policy file is added, removed or modified.
+func fingerprint(dir string) (string, error) {
+	paths, err := filepath.Glob(filepath.Join(dir, "*"+FileExtension))
+	if err != nil {
+		return "", err
+	}
+	sort.Strings(paths)
+
+	var sb strings.Builder
+	for _, path := range paths {
+		info, err := os.Stat(path)
+		if err != nil {
+			return "", err
+		}
+		fmt.Fprintf(&sb, "%s:%d:%d;", path, info.Size(), info.ModTime().UnixNano())
+	}
+	return sb.String(), nil
+}
+
+// Engine evaluates the policies of a directory and keeps them up to date.
+type Engine struct {
+	dir      string
+	logger   *slog.Logger
+	location *time.Location
+	now      func() time.Time
+
+	mu          sync.RWMutex
+	policies    []*Policy
+	fingerprint string
+}
+
+type Option func(*Engine)
+
+// WithLogger sets the logger decisions and reloads are written to.
+func WithLogger(logger *slog.Logger) Option {
+	return func(e *Engine) {
+		e.logger = logger
+	}
+}
+
+// WithLocation sets the time zone of the time attributes added to the request context.
+func WithLocation(location *time.Location) Option {
+	return func(e *Engine) {
+		e.location = location
+	}
+}
+
+func NewEngine(dir string, opts ...Option) (*Engine, error) {
+	e := &Engine{
+		dir:      dir,
+		logger:   slog.Default().With("component", "policy"),
+		location: time.UTC,
+		now:      time.Now,
+	}
+	for _, opt := range opts {
+		opt(e)
+	}
+
+	if err := e.Reload(); err != nil {
+		return nil, err
+	}
+	return e, nil
+}
+
+// Reload re-reads the policy directory. On error the previous policies stay active.
+func (e *Engine) Reload() error {
+	fp, err := fingerprint(e.dir)
+	if err != nil {
+		return err
+	}
+	policies, err := LoadDir(e.dir)
+	if err != nil {
+		return err
+	}
+
+	e.mu.Lock()
+	e.policies = policies
+	e.fingerprint = fp
+	e.mu.Unlock()
+
+	e.logger.Info("policies loaded", "dir", e.dir, "count", len(policies))
+	return nil
+}
+
+// Watch polls the policy directory and reloads it when a file changes, until ctx is done.
+func (e *Engine) Watch(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			fp, err := fingerprint(e.dir)
+			if err != nil {
+				e.logger.Error("failed to scan policy directory", "dir", e.dir, "err", err)
+				continue
+			}
+			e.mu.RLock()
+			changed := fp != e.fingerprint
+			e.mu.RUnlock()
+			if !changed {
+				continue
+			}
+			if err := e.Reload(); err != nil {
+				e.logger.Error("policy reload failed, keeping previous policies", "dir", e.dir, "err", err)
+				e.mu.Lock()
+				// remember the broken state so the error is logged once per change
+				e.fingerprint = fp
+				e.mu.Unlock()
+			}
+		}
+	}
+}
+
+func (e *Engine) Policies() []*Policy {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	return e.policies
+}
+
+// Decide evaluates in against the loaded policies and logs the decision. Time
+// attributes (time, hour, minute, weekday, date) are added to the context unless
+// the caller already set them.
+func (e *Engine) Decide(ctx context.Context, in Input) Decision {
+	start := time.Now()
+
+	in.Context = e.withTimeAttributes(in.Context)
+	decision := Evaluate(e.Policies(), in)
+
+	e.logger.LogAttrs(ctx, slog.LevelInfo, "policy decision",
+		slog.Bool("allowed", decision.Allowed),
+		slog.String("action", in.Action),
+		slog.Any("subject_id", in.Subject["id"]),
+		slog.Any("resource_type", in.Resource["type"]),
+		slog.Any("resource_id", in.Resource["id"]),
+		slog.String("policy", decision.Policy),
+		slog.String("reason", decision.Reason),
+		slog.Any("errors", decision.Errors),
+		slog.Duration("duration", time.Since(start)),
+	)
+	return decision
+}
+
+func (e *Engine) withTimeAttributes(attrs Attributes) Attributes {
+	out := make(Attributes, len(attrs)+5)
+	now := e.now().In(e.location)
+	out["time"] = now.Format(time.RFC3339)
+	out["hour"] = now.Hour()
+	out["minute"] = now.Minute()
+	out["weekday"] = strings.ToLower(now.Weekday().String())
+	out["date"] = now.Format(time.DateOnly)
+	for k, v := range attrs {
+		out[k] = v
+	}
+	return out
+}
@@ -0,0 +1,192 @@
+package policy
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func mustParse(t *testing.T, src string) []*Policy {
+	t.Helper()
+	policies, err := ParseFile("test.policy", src)
+	require.NoError(t, err)
+	return policies
+}
+
+func TestEvaluate(t *testing.T) {
+	policies := mustParse(t, `
+policy "readers" {
+    effect   = allow
+    actions  = ["docs:read"]
+    resource = "document"
+    when     = subject.role in ["reader", "editor"]
+}
+policy "editors" {
+    effect   = allow
+    actions  = ["docs:*"]
+    resource = "document"
+    when     = subject.role == "editor"
+}
+policy "no-secret-docs" {
+    effect  = deny
+    actions = ["docs:*"]
+    when    = resource.classification == "secret"
+}
+policy "broken-deny" {
+    effect  = deny
+    actions = ["docs:archive"]
+    when    = resource.classification > 1
+}
+`)
+
+	tests := []struct {
+		name       string
+		in         Input
+		wantAllow  bool
+		wantPolicy string
+		wantErrors bool
+	}{
+		{
+			name:       "allowed by first matching policy",
+			in:         Input{Subject: Attributes{"role": "editor"}, Action: "docs:read", Resource: Attributes{"type": "document"}},
+			wantAllow:  true,
+			wantPolicy: "readers",
+		},
+		{
+			name:       "action wildcard",
+			in:         Input{Subject: Attributes{"role": "editor"}, Action: "docs:delete", Resource: Attributes{"type": "document"}},
+			wantAllow:  true,
+			wantPolicy: "editors",
+		},
+		{
+			name: "default deny",
+			in:   Input{Subject: Attributes{"role": "reader"}, Action: "docs:delete", Resource: Attributes{"type": "document"}},
+		},
+		{
+			name: "resource type must match",
+			in:   Input{Subject: Attributes{"role": "reader"}, Action: "docs:read", Resource: Attributes{"type": "folder"}},
+		},
+		{
+			name:       "deny overrides allow",
+			in:         Input{Subject: Attributes{"role": "editor"}, Action: "docs:read", Resource: Attributes{"type": "document", "classification": "secret"}},
+			wantPolicy: "no-secret-docs",
+		},
+		{
+			name:       "failing deny condition fails closed",
+			in:         Input{Subject: Attributes{"role": "editor"}, Action: "docs:archive", Resource: Attributes{"type": "document", "classification": "internal"}},
+			wantPolicy: "broken-deny",
+			wantErrors: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := Evaluate(policies, tt.in)
+			require.Equal(t, tt.wantAllow, d.Allowed)
+			require.Equal(t, tt.wantPolicy, d.Policy)
+			require.Equal(t, tt.wantErrors, len(d.Errors) > 0)
+		})
+	}
+}
+
+func TestEvaluate_FailingAllowConditionIsSkipped(t *testing.T) {
+	policies := mustParse(t, `
+policy "broken" {
+    effect  = allow
+    actions = ["a"]
+    when    = subject.name > 3
+}
+policy "fallback" {
+    effect  = allow
+    actions = ["a"]
+}
+`)
+	d := Evaluate(policies, Input{Subject: Attributes{"name": "x"}, Action: "a"})
+	require.True(t, d.Allowed)
+	require.Equal(t, "fallback", d.Policy)
+	require.Len(t, d.Errors, 1)
+}
+
+func writePolicy(t *testing.T, path, src string) {
+	t.Helper()
+	require.NoError(t, os.WriteFile(path, []byte(src), 0o644))
+}
+
+func TestEngine_ReloadAndTimeAttributes(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "hours.policy")
+	writePolicy(t, path, `policy "mornings" { effect = allow actions = ["a"] when = context.hour < 12 and context.weekday == "monday" }`)
+	// ignored: wrong extension
+	writePolicy(t, filepath.Join(dir, "notes.txt"), "not a policy")
+
+	loc := time.FixedZone("UTC+2", 2*60*60)
+	e, err := NewEngine(dir, WithLogger(slog.New(slog.NewTextHandler(io.Discard, nil))), WithLocation(loc))
+	require.NoError(t, err)
+	e.now = func() time.Time { return time.Date(2026, 10, 19, 8, 30, 0, 0, time.UTC) } // monday 10:30 in loc
+	require.Len(t, e.Policies(), 1)
+
+	require.True(t, e.Decide(context.Background(), Input{Action: "a"}).Allowed)
+	// caller-supplied context wins over the clock
+	require.False(t, e.Decide(context.Background(), Input{Action: "a", Context: Attributes{"hour": 15}}).Allowed)
+
+	writePolicy(t, path, `policy "mornings" { effect = deny actions = ["a"] }`)
+	require.NoError(t, e.Reload())
+	require.False(t, e.Decide(context.Background(), Input{Action: "a"}).Allowed)
+
+	writePolicy(t, path, `policy "broken" {`)
+	require.Error(t, e.Reload())
+	require.Equal(t, "mornings", e.Policies()[0].Name, "previous policies stay active")
+}
+
+func TestEngine_Watch(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "a.policy")
+	writePolicy(t, path, `policy "one" { effect = allow actions = ["a"] }`)
+
+	e, err := NewEngine(dir, WithLogger(slog.New(slog.NewTextHandler(io.Discard, nil))))
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go e.Watch(ctx, 10*time.Millisecond)
+
+	writePolicy(t, filepath.Join(dir, "b.policy"), `policy "two" { effect = deny actions = ["a"] }`)
+	require.Eventually(t, func() bool { return len(e.Policies()) == 2 }, 2*time.Second, 10*time.Millisecond)
+}
+
+func TestLoadDir_DuplicateNames(t *testing.T) {
+	dir := t.TempDir()
+	writePolicy(t, filepath.Join(dir, "a.policy"), `policy "same" { effect = allow actions = ["a"] }`)
+	writePolicy(t, filepath.Join(dir, "b.policy"), `policy "same" { effect = deny actions = ["a"] }`)
+
+	_, err := LoadDir(dir)
+	require.ErrorContains(t, err, `policy "same" is already defined in a.policy`)
+}
+
+func TestLoadDir_MissingDirectory(t *testing.T) {
+	_, err := LoadDir(filepath.Join(t.TempDir(), "missing"))
+	require.ErrorIs(t, err, os.ErrNotExist)
+}
+
+// TestShippedPolicies runs the policy tests in the repository's policies directory.
+func TestShippedPolicies(t *testing.T) {
+	dir := filepath.Join("..", "..", "policies")
+	policies, err := LoadDir(dir)
+	require.NoError(t, err)
+	cases, err := LoadTests(dir)
+	require.NoError(t, err)
+	require.NotEmpty(t, cases)
+
+	for _, result := range RunTests(policies, cases) {
+		t.Run(result.Case.Name, func(t *testing.T) {
+			require.True(t, result.Passed, result.Message)
+			require.Empty(t, result.Decision.Errors)
+		})
+	}
+}
@@ -0,0 +1,413 @@
+package policy
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
+	"strings"
+	"time"
+)
+
+var ErrTypeMismatch = errors.New("type mismatch")
+
+// Expr is a node of a policy condition.
+type Expr interface {
+	Eval(in *Input) (any, error)
+	String() string
+}
+
+type Literal struct {
+	Value any
+}
+
+type AttrRef struct {
+	// Path starts with one of subject, resource, context or action.
+	Path []string
+}
+
+type ListExpr struct {
+	Items []Expr
+}
+
+type UnaryExpr struct {
+	Op      string
+	Operand Expr
+}
+
+type BinaryExpr struct {
+	Op          string
+	Left, Right Expr
+}
+
+type CallExpr struct {
+	Func string
+	Args []Expr
+}
+
+func (e *Literal) Eval(*Input) (any, error) { return e.Value, nil }
+
+func (e *Literal) String() string {
+	if s, ok := e.Value.(string); ok {
+		return fmt.Sprintf("%q", s)
+	}
+	if e.Value == nil {
+		return "null"
+	}
+	return fmt.Sprint(e.Value)
+}
+
+func (e *AttrRef) Eval(in *Input) (any, error) {
+	var value any
+	switch e.Path[0] {
+	case "subject":
+		value = map[string]any(in.Subject)
+	case "resource":
+		value = map[string]any(in.Resource)
+	case "context":
+		value = map[string]any(in.Context)
+	case "action":
+		value = in.Action
+	}
+
+	for _, key := range e.Path[1:] {
+		m, ok := normalize(value).(map[string]any)
+		if !ok {
+			return nil, nil
+		}
+		value = m[key]
+	}
+	return normalize(value), nil
+}
+
+func (e *AttrRef) String() string { return strings.Join(e.Path, ".") }
+
+func (e *ListExpr) Eval(in *Input) (any, error) {
+	items := make([]any, len(e.Items))
+	for i, item := range e.Items {
+		v, err := item.Eval(in)
+		if err != nil {
+			return nil, err
+		}
+		items[i] = v
+	}
+	return items, nil
+}
+
+func (e *ListExpr) String() string {
+	parts := make([]string, len(e.Items))
+	for i, item := range e.Items {
+		parts[i] = item.String()
+	}
+	return "[" + strings.Join(parts, ", ") + "]"
+}
+
+func (e *UnaryExpr) Eval(in *Input) (any, error) {
+	v, err := e.Operand.Eval(in)
+	if err != nil {
+		return nil, err
+	}
+	b, ok := v.(bool)
+	if !ok {
+		return nil, fmt.Errorf("%w: ! expects a boolean, got %s", ErrTypeMismatch, typeName(v))
+	}
+	return !b, nil
+}
+
+func (e *UnaryExpr) String() string { return "!" + e.Operand.String() }
+
+func (e *BinaryExpr) Eval(in *Input) (any, error) {
+	left, err := e.Left.Eval(in)
+	if err != nil {
+		return nil, err
+	}
+
+	// && and || short-circuit so guards like `resource.owner != null && ...` work
+	if e.Op == "&&" || e.Op == "||" {
+		lb, ok := left.(bool)
+		if !ok {
+			return nil, fmt.Errorf("%w: %s expects booleans, got %s", ErrTypeMismatch, e.Op, typeName(left))
+		}
+		if (e.Op == "&&" && !lb) || (e.Op == "||" && lb) {
+			return lb, nil
+		}
+		right, err := e.Right.Eval(in)
+		if err != nil {
+			return nil, err
+		}
+		rb, ok := right.(bool)
+		if !ok {
+			return nil, fmt.Errorf("%w: %s expects booleans, got %s", ErrTypeMismatch, e.Op, typeName(right))
+		}
+		return rb, nil
+	}
+
+	right, err := e.Right.Eval(in)
+	if err != nil {
+		return nil, err
+	}
+
+	switch e.Op {
+	case "==":
+		return equal(left, right), nil
+	case "!=":
+		return !equal(left, right), nil
+	case "in":
+		return contains(right, left)
+	case "<", "<=", ">", ">=":
+		return compare(e.Op, left, right)
+	}
+	return nil, fmt.Errorf("unknown operator %s", e.Op)
+}
+
+func (e *BinaryExpr) String() string {
+	return "(" + e.Left.String() + " " + e.Op + " " + e.Right.String() + ")"
+}
+
+func (e *CallExpr) Eval(in *Input) (any, error) {
+	fn, ok := functions[e.Func]
+	if !ok {
+		return nil, fmt.Errorf("unknown function %s", e.Func)
+	}
+	args := make([]any, len(e.Args))
+	for i, arg := range e.Args {
+		v, err := arg.Eval(in)
+		if err != nil {
+			return nil, err
+		}
+		args[i] = v
+	}
+	return fn(args)
+}
+
+func (e *CallExpr) String() string {
+	parts := make([]string, len(e.Args))
+	for i, arg := range e.Args {
+		parts[i] = arg.String()
+	}
+	return e.Func + "(" + strings.Join(parts, ", ") + ")"
+}
+
+var functions = map[string]func(args []any) (any, error){
+	"contains": func(args []any) (any, error) {
+		if len(args) != 2 {
+			return nil, errors.New("contains expects 2 arguments")
+		}
+		return contains(args[0], args[1])
+	},
+	"starts_with": stringPredicate("starts_with", strings.HasPrefix),
+	"ends_with":   stringPredicate("ends_with", strings.HasSuffix),
+	"lower":       stringFunc("lower", strings.ToLower),
+	"upper":       stringFunc("upper", strings.ToUpper),
+	"len": func(args []any) (any, error) {
+		if len(args) != 1 {
+			return nil, errors.New("len expects 1 argument")
+		}
+		switch v := args[0].(type) {
+		case string:
+			return float64(len(v)), nil
+		case []any:
+			return float64(len(v)), nil
+		case map[string]any:
+			return float64(len(v)), nil
+		case nil:
+			return float64(0), nil
+		}
+		return nil, fmt.Errorf("%w: len of %s", ErrTypeMismatch, typeName(args[0]))
+	},
+}
+
+var knownFunctions = func() map[string]bool {
+	names := map[string]bool{}
+	for name := range functions {
+		names[name] = true
+	}
+	return names
+}()
+
+func stringPredicate(name string, fn func(s, affix string) bool) func([]any) (any, error) {
+	return func(args []any) (any, error) {
+		if len(args) != 2 {
+			return nil, fmt.Errorf("%s expects 2 arguments", name)
+		}
+		s, ok1 := args[0].(string)
+		affix, ok2 := args[1].(string)
+		if !ok1 || !ok2 {
+			return false, nil
+		}
+		return fn(s, affix), nil
+	}
+}
+
+func stringFunc(name string, fn func(string) string) func([]any) (any, error) {
+	return func(args []any) (any, error) {
+		if len(args) != 1 {
+			return nil, fmt.Errorf("%s expects 1 argument", name)
+		}
+		s, ok := args[0].(string)
+		if !ok {
+			return nil, fmt.Errorf("%w: %s of %s", ErrTypeMismatch, name, typeName(args[0]))
+		}
+		return fn(s), nil
+	}
+}
+
+// normalize converts attribute values from Go types into the evaluator's value
+// types: nil, bool, float64, string, []any and map[string]any.
+func normalize(v any) any {
+	switch t := v.(type) {
+	case nil, bool, float64, string, []any, map[string]any:
+		return v
+	case Attributes:
+		return map[string]any(t)
+	case time.Time:
+		return t.UTC().Format(time.RFC3339)
+	case fmt.Stringer:
+		return t.String()
+	}
+
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(rv.Int())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return float64(rv.Uint())
+	case reflect.Float32:
+		return rv.Float()
+	case reflect.String:
+		return rv.String()
+	case reflect.Bool:
+		return rv.Bool()
+	case reflect.Slice, reflect.Array:
+		items := make([]any, rv.Len())
+		for i := range items {
+			items[i] = normalize(rv.Index(i).Interface())
+		}
+		return items
+	case reflect.Map:
+		if rv.Type().Key().Kind() != reflect.String {
+			return nil
+		}
+		m := make(map[string]any, rv.Len())
+		for _, key := range rv.MapKeys() {
+			m[key.String()] = normalize(rv.MapIndex(key).Interface())
+		}
+		return m
+	case reflect.Pointer:
+		if rv.IsNil() {
+			return nil
+		}
+		return normalize(rv.Elem().Interface())
+	}
+	return fmt.Sprint(v)
+}
+
+func typeName(v any) string {
+	switch v.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case float64:
+		return "number"
+	case string:
+		return "string"
+	case []any:
+		return "list"
+	case map[string]any:
+		return "object"
+	}
+	return fmt.Sprintf("%T", v)
+}
+
+func equal(a, b any) bool {
+	a, b = normalize(a), normalize(b)
+	switch av := a.(type) {
+	case []any:
+		bv, ok := b.([]any)
+		if !ok || len(av) != len(bv) {
+			return false
+		}
+		for i := range av {
+			if !equal(av[i], bv[i]) {
+				return false
+			}
+		}
+		return true
+	case map[string]any:
+		return reflect.DeepEqual(a, b)
+	}
+	return a == b
+}
+
+func contains(collection, item any) (any, error) {
+	switch c := normalize(collection).(type) {
+	case []any:
+		for _, v := range c {
+			if equal(v, item) {
+				return true, nil
+			}
+		}
+		return false, nil
+	case map[string]any:
+		key, ok := item.(string)
+		if !ok {
+			return false, nil
+		}
+		_, found := c[key]
+		return found, nil
+	case string:
+		s, ok := item.(string)
+		if !ok {
+			return false, nil
+		}
+		return strings.Contains(c, s), nil
+	case nil:
+		return false, nil
+	}
+	return nil, fmt.Errorf("%w: cannot search in %s", ErrTypeMismatch, typeName(collection))
+}
+
+// compare orders numbers and strings. Comparisons involving null are false
+// so that missing attributes never satisfy a range check.
+func compare(op string, a, b any) (any, error) {
+	if a == nil || b == nil {
+		return false, nil
+	}
+
+	var cmp int
+	switch av := a.(type) {
+	case float64:
+		bv, ok := b.(float64)
+		if !ok {
+			return nil, fmt.Errorf("%w: cannot compare number with %s", ErrTypeMismatch, typeName(b))
+		}
+		if math.IsNaN(av) || math.IsNaN(bv) {
+			return false, nil
+		}
+		switch {
+		case av < bv:
+			cmp = -1
+		case av > bv:
+			cmp = 1
+		}
+	case string:
+		bv, ok := b.(string)
+		if !ok {
+			return nil, fmt.Errorf("%w: cannot compare string with %s", ErrTypeMismatch, typeName(b))
+		}
+		cmp = strings.Compare(av, bv)
+	default:
+		return nil, fmt.Errorf("%w: cannot order %s", ErrTypeMismatch, typeName(a))
+	}
+
+	switch op {
+	case "<":
+		return cmp < 0, nil
+	case "<=":
+		return cmp <= 0, nil
+	case ">":
+		return cmp > 0, nil
+	}
+	return cmp >= 0, nil
+}
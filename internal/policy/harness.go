@@ -0,0 +1,95 @@
+package policy
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+// TestFileExtension is the extension of policy test files, which hold a JSON
+// array of test cases.
+const TestFileExtension = ".policytest.json"
+
+type TestCase struct {
+	Name     string     `json:"name"`
+	Subject  Attributes `json:"subject"`
+	Action   string     `json:"action"`
+	Resource Attributes `json:"resource"`
+	Context  Attributes `json:"context"`
+	// Expect is "allow" or "deny".
+	Expect Effect `json:"expect"`
+	// Policy optionally names the policy expected to decide.
+	Policy string `json:"policy,omitempty"`
+	Source string `json:"-"`
+}
+
+type TestResult struct {
+	Case     TestCase
+	Decision Decision
+	Passed   bool
+	Message  string
+}
+
+// LoadTests reads every policy test file in dir, in file name order.
+func LoadTests(dir string) ([]TestCase, error) {
+	paths, err := filepath.Glob(filepath.Join(dir, "*"+TestFileExtension))
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(paths)
+
+	var cases []TestCase
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		var fileCases []TestCase
+		if err := json.Unmarshal(data, &fileCases); err != nil {
+			return nil, fmt.Errorf("%s: %w", filepath.Base(path), err)
+		}
+		for i := range fileCases {
+			fileCases[i].Source = filepath.Base(path)
+			if fileCases[i].Expect != Allow && fileCases[i].Expect != Deny {
+				return nil, fmt.Errorf("%s: test %q: expect must be allow or deny", fileCases[i].Source, fileCases[i].Name)
+			}
+		}
+		cases = append(cases, fileCases...)
+	}
+	return cases, nil
+}
+
+// RunTests evaluates each case against policies. Unlike Engine.Decide no time
+// attributes are added, so cases spell out the context they depend on.
+func RunTests(policies []*Policy, cases []TestCase) []TestResult {
+	results := make([]TestResult, len(cases))
+	for i, tc := range cases {
+		decision := Evaluate(policies, Input{
+			Subject:  tc.Subject,
+			Action:   tc.Action,
+			Resource: tc.Resource,
+			Context:  tc.Context,
+		})
+
+		result := TestResult{Case: tc, Decision: decision, Passed: true}
+		got := Deny
+		if decision.Allowed {
+			got = Allow
+		}
+		switch {
+		case got != tc.Expect:
+			result.Passed = false
+			result.Message = fmt.Sprintf("expected %s, got %s (%s)", tc.Expect, got, decision.Reason)
+		case tc.Policy != "" && decision.Policy != tc.Policy:
+			result.Passed = false
+			result.Message = fmt.Sprintf("expected decision by %q, got %q", tc.Policy, decision.Policy)
+		}
+		if result.Passed && len(decision.Errors) > 0 {
+			result.Message = fmt.Sprintf("evaluation errors: %v", decision.Errors)
+		}
+		results[i] = result
+	}
+	return results
+}
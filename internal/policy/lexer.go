@@ -0,0 +1,148 @@
+package policy
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+type tokenKind int
+
+const (
+	tokEOF tokenKind = iota
+	tokIdent
+	tokString
+	tokNumber
+	tokPunct
+)
+
+type token struct {
+	kind tokenKind
+	text string
+	pos  Position
+}
+
+type Position struct {
+	File   string
+	Line   int
+	Column int
+}
+
+func (p Position) String() string {
+	if p.File == "" {
+		return fmt.Sprintf("%d:%d", p.Line, p.Column)
+	}
+	return fmt.Sprintf("%s:%d:%d", p.File, p.Line, p.Column)
+}
+
+type ParseError struct {
+	Pos Position
+	Msg string
+}
+
+func (e *ParseError) Error() string {
+	return fmt.Sprintf("%s: %s", e.Pos, e.Msg)
+}
+
+// multi-character operators must come before their single-character prefixes
+var punctuation = []string{"==", "!=", "<=", ">=", "&&", "||", "{", "}", "[", "]", "(", ")", ",", ".", "=", "<", ">", "!"}
+
+func tokenize(file, src string) ([]token, error) {
+	var tokens []token
+	runes := []rune(src)
+	line, col := 1, 1
+
+	advance := func(n int) {
+		for i := 0; i < n; i++ {
+			if runes[0] == '\n' {
+				line++
+				col = 1
+			} else {
+				col++
+			}
+			runes = runes[1:]
+		}
+	}
+
+	for len(runes) > 0 {
+		r := runes[0]
+		pos := Position{File: file, Line: line, Column: col}
+
+		switch {
+		case unicode.IsSpace(r):
+			advance(1)
+
+		case r == '#' || (r == '/' && len(runes) > 1 && runes[1] == '/'):
+			for len(runes) > 0 && runes[0] != '\n' {
+				advance(1)
+			}
+
+		case r == '"':
+			var sb strings.Builder
+			advance(1)
+			closed := false
+			for len(runes) > 0 {
+				c := runes[0]
+				if c == '\n' {
+					break
+				}
+				if c == '"' {
+					advance(1)
+					closed = true
+					break
+				}
+				if c == '\\' && len(runes) > 1 {
+					switch runes[1] {
+					case 'n':
+						sb.WriteRune('\n')
+					case 't':
+						sb.WriteRune('\t')
+					default:
+						sb.WriteRune(runes[1])
+					}
+					advance(2)
+					continue
+				}
+				sb.WriteRune(c)
+				advance(1)
+			}
+			if !closed {
+				return nil, &ParseError{Pos: pos, Msg: "unterminated string"}
+			}
+			tokens = append(tokens, token{kind: tokString, text: sb.String(), pos: pos})
+
+		case unicode.IsDigit(r) || (r == '-' && len(runes) > 1 && unicode.IsDigit(runes[1])):
+			n := 1
+			for n < len(runes) && (unicode.IsDigit(runes[n]) || runes[n] == '.') {
+				n++
+			}
+			tokens = append(tokens, token{kind: tokNumber, text: string(runes[:n]), pos: pos})
+			advance(n)
+
+		case unicode.IsLetter(r) || r == '_':
+			n := 1
+			for n < len(runes) && (unicode.IsLetter(runes[n]) || unicode.IsDigit(runes[n]) || runes[n] == '_') {
+				n++
+			}
+			tokens = append(tokens, token{kind: tokIdent, text: string(runes[:n]), pos: pos})
+			advance(n)
+
+		default:
+			matched := false
+			for _, p := range punctuation {
+				if strings.HasPrefix(string(runes[:min(len(runes), len(p))]), p) {
+					tokens = append(tokens, token{kind: tokPunct, text: p, pos: pos})
+					advance(len(p))
+					matched = true
+					break
+				}
+			}
+			if !matched {
+				return nil, &ParseError{Pos: pos, Msg: fmt.Sprintf("unexpected character %q", r)}
+			}
+		}
+	}
+
+	tokens = append(tokens, token{kind: tokEOF, pos: Position{File: file, Line: line, Column: col}})
+	return tokens, nil
+}
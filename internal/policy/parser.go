@@ -0,0 +1,365 @@
+package policy
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Policy files hold one or more blocks like:
+//
+//	policy "support-views-users-in-region" {
+//	    description = "support agents may view users in their region during business hours"
+//	    effect      = allow
+//	    actions     = ["users:read"]
+//	    resource    = "user"
+//	    when        = subject.role == "support"
+//	                  && subject.region == resource.region
+//	                  && context.hour >= 9 && context.hour < 17
+//	}
+//
+// Conditions support ==, !=, <, <=, >, >=, in, &&, ||, !, the keywords and, or,
+// not, list literals and the functions contains, starts_with, ends_with, lower,
+// upper and len.
+
+type parser struct {
+	tokens []token
+	pos    int
+}
+
+func (p *parser) peek() token { return p.tokens[p.pos] }
+
+func (p *parser) next() token {
+	t := p.tokens[p.pos]
+	if t.kind != tokEOF {
+		p.pos++
+	}
+	return t
+}
+
+func (p *parser) errorf(t token, format string, args ...any) error {
+	return &ParseError{Pos: t.pos, Msg: fmt.Sprintf(format, args...)}
+}
+
+func (p *parser) isPunct(text string) bool {
+	t := p.peek()
+	return t.kind == tokPunct && t.text == text
+}
+
+func (p *parser) isKeyword(text string) bool {
+	t := p.peek()
+	return t.kind == tokIdent && t.text == text
+}
+
+func (p *parser) expectPunct(text string) error {
+	t := p.next()
+	if t.kind != tokPunct || t.text != text {
+		return p.errorf(t, "expected %q, found %q", text, t.text)
+	}
+	return nil
+}
+
+func (p *parser) expectString() (string, error) {
+	t := p.next()
+	if t.kind != tokString {
+		return "", p.errorf(t, "expected a string, found %q", t.text)
+	}
+	return t.text, nil
+}
+
+// ParseFile parses the policies in src. name is used in error positions.
+func ParseFile(name, src string) ([]*Policy, error) {
+	tokens, err := tokenize(name, src)
+	if err != nil {
+		return nil, err
+	}
+	p := &parser{tokens: tokens}
+
+	var policies []*Policy
+	for p.peek().kind != tokEOF {
+		policy, err := p.parsePolicy()
+		if err != nil {
+			return nil, err
+		}
+		policy.Source = name
+		policies = append(policies, policy)
+	}
+	return policies, nil
+}
+
+// ParseExpr parses a single condition.
+func ParseExpr(src string) (Expr, error) {
+	tokens, err := tokenize("", src)
+	if err != nil {
+		return nil, err
+	}
+	p := &parser{tokens: tokens}
+	expr, err := p.parseExpr()
+	if err != nil {
+		return nil, err
+	}
+	if t := p.peek(); t.kind != tokEOF {
+		return nil, p.errorf(t, "unexpected %q", t.text)
+	}
+	return expr, nil
+}
+
+func (p *parser) parsePolicy() (*Policy, error) {
+	start := p.next()
+	if start.kind != tokIdent || start.text != "policy" {
+		return nil, p.errorf(start, "expected \"policy\", found %q", start.text)
+	}
+	name, err := p.expectString()
+	if err != nil {
+		return nil, err
+	}
+	if err := p.expectPunct("{"); err != nil {
+		return nil, err
+	}
+
+	policy := &Policy{Name: name, Resource: "*"}
+	seen := map[string]bool{}
+	for !p.isPunct("}") {
+		field := p.next()
+		if field.kind != tokIdent {
+			return nil, p.errorf(field, "expected a field name, found %q", field.text)
+		}
+		if seen[field.text] {
+			return nil, p.errorf(field, "duplicate field %q", field.text)
+		}
+		seen[field.text] = true
+		if err := p.expectPunct("="); err != nil {
+			return nil, err
+		}
+
+		switch field.text {
+		case "description":
+			if policy.Description, err = p.expectString(); err != nil {
+				return nil, err
+			}
+		case "effect":
+			t := p.next()
+			if t.kind != tokIdent || (t.text != string(Allow) && t.text != string(Deny)) {
+				return nil, p.errorf(t, "effect must be allow or deny, found %q", t.text)
+			}
+			policy.Effect = Effect(t.text)
+		case "actions":
+			if policy.Actions, err = p.parseStringList(); err != nil {
+				return nil, err
+			}
+		case "resource":
+			if policy.Resource, err = p.expectString(); err != nil {
+				return nil, err
+			}
+		case "when":
+			if policy.When, err = p.parseExpr(); err != nil {
+				return nil, err
+			}
+		default:
+			return nil, p.errorf(field, "unknown field %q", field.text)
+		}
+	}
+	p.next()
+
+	if policy.Effect == "" {
+		return nil, p.errorf(start, "policy %q has no effect", name)
+	}
+	if len(policy.Actions) == 0 {
+		return nil, p.errorf(start, "policy %q has no actions", name)
+	}
+	return policy, nil
+}
+
+func (p *parser) parseStringList() ([]string, error) {
+	if err := p.expectPunct("["); err != nil {
+		return nil, err
+	}
+	var items []string
+	for !p.isPunct("]") {
+		s, err := p.expectString()
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, s)
+		if !p.isPunct("]") {
+			if err := p.expectPunct(","); err != nil {
+				return nil, err
+			}
+		}
+	}
+	p.next()
+	return items, nil
+}
+
+func (p *parser) parseExpr() (Expr, error) {
+	return p.parseOr()
+}
+
+func (p *parser) parseOr() (Expr, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.isPunct("||") || p.isKeyword("or") {
+		p.next()
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = &BinaryExpr{Op: "||", Left: left, Right: right}
+	}
+	return left, nil
+}
+
+func (p *parser) parseAnd() (Expr, error) {
+	left, err := p.parseComparison()
+	if err != nil {
+		return nil, err
+	}
+	for p.isPunct("&&") || p.isKeyword("and") {
+		p.next()
+		right, err := p.parseComparison()
+		if err != nil {
+			return nil, err
+		}
+		left = &BinaryExpr{Op: "&&", Left: left, Right: right}
+	}
+	return left, nil
+}
+
+var comparisonOps = map[string]bool{"==": true, "!=": true, "<": true, "<=": true, ">": true, ">=": true}
+
+func (p *parser) parseComparison() (Expr, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+
+	t := p.peek()
+	var op string
+	switch {
+	case t.kind == tokPunct && comparisonOps[t.text]:
+		op = t.text
+	case t.kind == tokIdent && t.text == "in":
+		op = "in"
+	default:
+		return left, nil
+	}
+	p.next()
+
+	right, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	return &BinaryExpr{Op: op, Left: left, Right: right}, nil
+}
+
+func (p *parser) parseUnary() (Expr, error) {
+	if p.isPunct("!") || p.isKeyword("not") {
+		p.next()
+		operand, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return &UnaryExpr{Op: "!", Operand: operand}, nil
+	}
+	return p.parsePrimary()
+}
+
+var attributeRoots = map[string]bool{"subject": true, "resource": true, "context": true, "action": true}
+
+func (p *parser) parsePrimary() (Expr, error) {
+	t := p.next()
+	switch t.kind {
+	case tokString:
+		return &Literal{Value: t.text}, nil
+
+	case tokNumber:
+		n, err := strconv.ParseFloat(t.text, 64)
+		if err != nil {
+			return nil, p.errorf(t, "invalid number %q", t.text)
+		}
+		return &Literal{Value: n}, nil
+
+	case tokIdent:
+		switch t.text {
+		case "true":
+			return &Literal{Value: true}, nil
+		case "false":
+			return &Literal{Value: false}, nil
+		case "null":
+			return &Literal{Value: nil}, nil
+		}
+
+		if p.isPunct("(") {
+			if !knownFunctions[t.text] {
+				return nil, p.errorf(t, "unknown function %q", t.text)
+			}
+			p.next()
+			args, err := p.parseExprList(")")
+			if err != nil {
+				return nil, err
+			}
+			return &CallExpr{Func: t.text, Args: args}, nil
+		}
+
+		if !attributeRoots[t.text] {
+			return nil, p.errorf(t, "unknown identifier %q, attributes start with subject, resource, context or action", t.text)
+		}
+		path := []string{t.text}
+		for p.isPunct(".") {
+			p.next()
+			field := p.next()
+			if field.kind != tokIdent {
+				return nil, p.errorf(field, "expected an attribute name, found %q", field.text)
+			}
+			path = append(path, field.text)
+		}
+		if t.text == "action" && len(path) > 1 {
+			return nil, p.errorf(t, "action has no attributes")
+		}
+		return &AttrRef{Path: path}, nil
+
+	case tokPunct:
+		switch t.text {
+		case "(":
+			expr, err := p.parseExpr()
+			if err != nil {
+				return nil, err
+			}
+			if err := p.expectPunct(")"); err != nil {
+				return nil, err
+			}
+			return expr, nil
+		case "[":
+			items, err := p.parseExprList("]")
+			if err != nil {
+				return nil, err
+			}
+			return &ListExpr{Items: items}, nil
+		}
+	}
+
+	if t.kind == tokEOF {
+		return nil, p.errorf(t, "unexpected end of input")
+	}
+	return nil, p.errorf(t, "unexpected %q", strings.TrimSpace(t.text))
+}
+
+func (p *parser) parseExprList(closing string) ([]Expr, error) {
+	var items []Expr
+	for !p.isPunct(closing) {
+		item, err := p.parseExpr()
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+		if !p.isPunct(closing) {
+			if err := p.expectPunct(","); err != nil {
+				return nil, err
+			}
+		}
+	}
+	p.next()
+	return items, nil
+}
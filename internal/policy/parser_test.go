@@ -0,0 +1,125 @@
+package policy
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseFile(t *testing.T) {
+	src := `
+# comment
+policy "owner-reads" {
+    description = "owners read their documents"
+    effect      = allow
+    actions     = ["docs:read", "docs:list"]
+    resource    = "document"
+    when        = subject.id == resource.owner_id and not resource.archived
+}
+
+policy "deny-all-deletes" {
+    effect  = deny
+    actions = ["*:delete"]
+}
+`
+	policies, err := ParseFile("docs.policy", src)
+	require.NoError(t, err)
+	require.Len(t, policies, 2)
+
+	p := policies[0]
+	require.Equal(t, "owner-reads", p.Name)
+	require.Equal(t, "owners read their documents", p.Description)
+	require.Equal(t, Allow, p.Effect)
+	require.Equal(t, []string{"docs:read", "docs:list"}, p.Actions)
+	require.Equal(t, "document", p.Resource)
+	require.Equal(t, "docs.policy", p.Source)
+	require.Equal(t, "((subject.id == resource.owner_id) && !resource.archived)", p.When.String())
+
+	require.Equal(t, Deny, policies[1].Effect)
+	require.Equal(t, "*", policies[1].Resource)
+	require.Nil(t, policies[1].When)
+}
+
+func TestParseFile_Errors(t *testing.T) {
+	tests := []struct {
+		name    string
+		src     string
+		wantErr string
+	}{
+		{name: "missing effect", src: `policy "p" { actions = ["a"] }`, wantErr: "1:1: policy \"p\" has no effect"},
+		{name: "missing actions", src: `policy "p" { effect = allow }`, wantErr: "has no actions"},
+		{name: "bad effect", src: `policy "p" { effect = maybe }`, wantErr: "effect must be allow or deny"},
+		{name: "unknown field", src: `policy "p" { owner = "x" }`, wantErr: "unknown field \"owner\""},
+		{name: "duplicate field", src: `policy "p" { effect = allow effect = deny }`, wantErr: "duplicate field \"effect\""},
+		{name: "unknown identifier", src: `policy "p" { effect = allow actions = ["a"] when = user.id == 1 }`, wantErr: "unknown identifier \"user\""},
+		{name: "unknown function", src: `policy "p" { effect = allow actions = ["a"] when = now() }`, wantErr: "unknown function \"now\""},
+		{name: "unterminated string", src: "policy \"p {\n}", wantErr: "1:8: unterminated string"},
+		{name: "unclosed block", src: `policy "p" { effect = allow`, wantErr: "expected a field name"},
+		{name: "error position", src: "policy \"p\" {\n  effect = allow\n  actions = [\"a\"]\n  when = subject.id ==\n}", wantErr: "5:1: unexpected \"}\""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParseFile("", tt.src)
+			require.Error(t, err)
+			require.Contains(t, err.Error(), tt.wantErr)
+		})
+	}
+}
+
+func TestExprEval(t *testing.T) {
+	in := &Input{
+		Subject: Attributes{
+			"id":     int64(7),
+			"role":   "support",
+			"groups": []string{"eu", "oncall"},
+			"email":  "Agent@Example.com",
+		},
+		Action:   "users:read",
+		Resource: Attributes{"id": 7, "region": "eu", "tags": map[string]any{"vip": true}},
+		Context:  Attributes{"hour": 10},
+	}
+
+	tests := []struct {
+		expr    string
+		want    any
+		wantErr error
+	}{
+		{expr: `subject.id == resource.id`, want: true},
+		{expr: `subject.id != 7`, want: false},
+		{expr: `resource.region in subject.groups`, want: true},
+		{expr: `"admin" in subject.groups`, want: false},
+		{expr: `subject.role in ["support", "admin"]`, want: true},
+		{expr: `context.hour >= 9 && context.hour < 17`, want: true},
+		{expr: `context.missing < 17`, want: false},
+		{expr: `subject.missing == null`, want: true},
+		{expr: `subject.missing.deeper == null`, want: true},
+		{expr: `resource.tags.vip`, want: true},
+		{expr: `"vip" in resource.tags`, want: true},
+		{expr: `action == "users:read"`, want: true},
+		{expr: `ends_with(lower(subject.email), "@example.com")`, want: true},
+		{expr: `starts_with(action, "users:")`, want: true},
+		{expr: `len(subject.groups) == 2`, want: true},
+		{expr: `contains(subject.groups, "oncall")`, want: true},
+		{expr: `!(subject.role == "admin") || subject.missing`, want: true},
+		{expr: `false && subject.missing`, want: false},
+		{expr: `subject.role && true`, wantErr: ErrTypeMismatch},
+		{expr: `subject.role < 3`, wantErr: ErrTypeMismatch},
+		{expr: `!subject.role`, wantErr: ErrTypeMismatch},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			expr, err := ParseExpr(tt.expr)
+			require.NoError(t, err)
+
+			got, err := expr.Eval(in)
+			if tt.wantErr != nil {
+				require.ErrorIs(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tt.want, got)
+		})
+	}
+}
@@ -0,0 +1,173 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/Atmosfr/user-service/internal/models"
+)
+
+// PurgedUser is an account Purge anonymized. AvatarKey names the avatar files
+// left for the caller to delete.
+type PurgedUser struct {
+	ID        int64
+	AvatarKey string
+}
+
+type AccountDeletionRepository interface {
+	// CheckDeletable fails with ErrUserOwnsServiceAccounts or
+	// ErrLastOrganizationOwner while others depend on the user.
+	CheckDeletable(ctx context.Context, userID int64) error
+	// FindDeleted returns the deleted user with email that is not purged yet.
+	FindDeleted(ctx context.Context, email string) (*models.User, error)
+	// Purge anonymizes up to limit users deleted before deletedBefore and
+	// removes their personal data. Their email and username become free.
+	Purge(ctx context.Context, deletedBefore time.Time, limit int) ([]PurgedUser, error)
+}
+
+type accountDeletionRepository struct {
+	db *sql.DB
+}
+
+func (r *accountDeletionRepository) CheckDeletable(ctx context.Context, userID int64) error {
+	return checkDeletable(ctx, r.db, userID)
+}
+
+// checkDeletable fails while service accounts or organizations depend on the
+// user. Every way of deleting a user goes through it.
+func checkDeletable(ctx context.Context, q queryRower, userID int64) error {
+	var ownsServiceAccounts, lastOwner bool
+	err := q.QueryRowContext(ctx, `SELECT
+			EXISTS (SELECT 1 FROM service_accounts WHERE owner_id = $1),
+			EXISTS (SELECT 1 FROM organization_members m
+				WHERE m.user_id = $1 AND m.role = $2
+				AND NOT EXISTS (SELECT 1 FROM organization_members o
+					WHERE o.organization_id = m.organization_id AND o.role = $2 AND o.user_id <> $1))`,
+		userID, models.OrgRoleOwner).Scan(&ownsServiceAccounts, &lastOwner)
+	if err != nil {
+		return err
+	}
+	switch {
+	case ownsServiceAccounts:
+		return ErrUserOwnsServiceAccounts
+	case lastOwner:
+		return ErrLastOrganizationOwner
+	}
+	return nil
+}
+
+func (r *accountDeletionRepository) FindDeleted(ctx context.Context, email string) (*models.User, error) {
+	user, err := scanUser(r.db.QueryRowContext(ctx, `SELECT `+userColumns+` FROM users
+		WHERE email = $1 AND deleted_at IS NOT NULL AND purged_at IS NULL`, email))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrUserNotFound
+	}
+	return user, err
+}
+
+// softDeleteUser moves a user that is not deleted yet to the deleted state for
+// reason and records the change. Purge anonymizes the account after the grace
+// period, as it does with accounts their owners deleted. With scimTenantID set
+// only users of that tenant are deleted.
+func softDeleteUser(ctx context.Context, db *sql.DB, id int64, reason string, scimTenantID *int64) error {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var deletedID int64
+	err = tx.QueryRowContext(ctx, `WITH target AS (
+			SELECT id, state FROM users
+			WHERE id = $1 AND deleted_at IS NULL AND ($3::bigint IS NULL OR scim_tenant_id = $3)
+			FOR UPDATE
+		), deleted AS (
+			UPDATE users u SET state = 'deleted', state_reason = $2, state_expires_at = NULL, deleted_at = NOW(), updated_at = NOW()
+			FROM target WHERE u.id = target.id
+			RETURNING u.id, target.state AS from_state
+		), history AS (
+			INSERT INTO user_state_history (user_id, from_state, to_state, reason)
+			SELECT id, from_state, 'deleted', $2 FROM deleted
+		)
+		SELECT id FROM deleted`, id, reason, scimTenantID).Scan(&deletedID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrUserNotFound
+	}
+	if err != nil {
+		return err
+	}
+	// checked once the user is known to be in scope, failing rolls the deletion back
+	if err := checkDeletable(ctx, tx, id); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// purgeStatements remove what the purged users left in other tables. Service
+// accounts cannot be among it, users owning any cannot be deleted.
+var purgeStatements = []string{
+	`DELETE FROM personal_access_tokens WHERE user_id = ANY($1)`,
+	`DELETE FROM user_settings WHERE user_id = ANY($1)`,
+	`DELETE FROM email_changes WHERE user_id = ANY($1)`,
+	`DELETE FROM scim_group_members WHERE user_id = ANY($1)`,
+	// removes the group memberships with them
+	`DELETE FROM organization_members WHERE user_id = ANY($1)`,
+	`UPDATE user_state_history SET note = '' WHERE user_id = ANY($1)`,
+	// which versions were accepted stays on record, where from does not
+	`UPDATE user_consents SET ip_address = '' WHERE user_id = ANY($1)`,
+	// published events carry copies of the profile, pending ones are still delivered
+	`DELETE FROM outbox_events WHERE aggregate_id = ANY($1) AND published_at IS NOT NULL`,
+	// the placeholders are no valid email or username, so they never collide
+	`UPDATE users SET email = 'purged:' || id, username = 'purged:' || id, password_hash = '',
+		email_verified_at = NULL, display_name = '', locale = '', timezone = '',
+		avatar_url = '', avatar_urls = '{}', avatar_key = '', public_metadata = '{}', private_metadata = '{}',
+		scim_tenant_id = NULL, external_id = NULL, purged_at = NOW(), updated_at = NOW()
+	 WHERE id = ANY($1)`,
+}
+
+func (r *accountDeletionRepository) Purge(ctx context.Context, deletedBefore time.Time, limit int) ([]PurgedUser, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	// SKIP LOCKED lets several instances purge side by side
+	rows, err := tx.QueryContext(ctx, `SELECT id, avatar_key FROM users
+		WHERE deleted_at < $1 AND purged_at IS NULL
+		ORDER BY deleted_at LIMIT $2 FOR UPDATE SKIP LOCKED`, deletedBefore, limit)
+	if err != nil {
+		return nil, err
+	}
+	var purged []PurgedUser
+	var ids []int64
+	for rows.Next() {
+		var u PurgedUser
+		if err := rows.Scan(&u.ID, &u.AvatarKey); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		purged = append(purged, u)
+		ids = append(ids, u.ID)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if len(ids) == 0 {
+		return nil, nil
+	}
+
+	for _, stmt := range purgeStatements {
+		if _, err := tx.ExecContext(ctx, stmt, ids); err != nil {
+			return nil, err
+		}
+	}
+	return purged, tx.Commit()
+}
+
+func NewAccountDeletionRepository(db *sql.DB) AccountDeletionRepository {
+	return &accountDeletionRepository{db: db}
+}
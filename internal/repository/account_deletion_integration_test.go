@@ -0,0 +1,106 @@
+//go:build integration
+
+package repository
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/Atmosfr/user-service/internal/models"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAccountDeletionRepository(t *testing.T) {
+	db := openTestDB(t)
+	ctx := context.Background()
+	users := NewUserRepository(db)
+	states := NewAccountStateRepository(db)
+	deletions := NewAccountDeletionRepository(db)
+	suffix := time.Now().UnixNano()
+
+	email, username := fmt.Sprintf("leaver%d@example.com", suffix), fmt.Sprintf("leaver%d", suffix)
+	user := &models.User{Email: email, Username: username, PasswordHash: "x", DisplayName: "Leaver"}
+	require.NoError(t, users.Create(ctx, user))
+	require.NoError(t, deletions.CheckDeletable(ctx, user.ID))
+
+	deleted, err := states.Transition(ctx, &models.AccountStateChange{
+		UserID: user.ID, From: models.AccountActive, To: models.AccountDeleted,
+		Reason: models.StateReasonUserRequest, ActorID: &user.ID,
+	})
+	require.NoError(t, err)
+	require.NotNil(t, deleted.DeletedAt)
+
+	// deleted users are hidden but keep their address until purged
+	_, err = users.FindByEmail(ctx, email)
+	require.ErrorIs(t, err, ErrUserNotFound)
+	found, err := deletions.FindDeleted(ctx, email)
+	require.NoError(t, err)
+	require.Equal(t, user.ID, found.ID)
+	err = users.Create(ctx, &models.User{Email: email, Username: username + "x", PasswordHash: "x"})
+	require.ErrorIs(t, err, ErrEmailAlreadyExists)
+
+	// deleted accounts cannot be changed while they wait for the purge
+	require.ErrorIs(t, users.UpdateRole(ctx, user.ID, "admin"), ErrUserNotFound)
+	found.DisplayName = "Changed"
+	require.ErrorIs(t, users.Update(ctx, found, &found.UpdatedAt), ErrUserNotFound)
+	_, err = users.UpdateAvatar(ctx, found)
+	require.ErrorIs(t, err, ErrUserNotFound)
+	_, err = users.MarkEmailVerified(ctx, user.ID, email)
+	require.ErrorIs(t, err, ErrUserNotFound)
+
+	// not yet past the cutoff
+	purged, err := deletions.Purge(ctx, deleted.DeletedAt.Add(-time.Second), 1000)
+	require.NoError(t, err)
+	require.NotContains(t, purged, PurgedUser{ID: user.ID})
+
+	purged, err = deletions.Purge(ctx, time.Now().Add(time.Minute), 1000)
+	require.NoError(t, err)
+	require.Contains(t, purged, PurgedUser{ID: user.ID})
+
+	_, err = deletions.FindDeleted(ctx, email)
+	require.ErrorIs(t, err, ErrUserNotFound)
+	// purged accounts release their address and username
+	require.NoError(t, users.Create(ctx, &models.User{Email: email, Username: username, PasswordHash: "x"}))
+}
+
+func TestAccountDeletionRepository_AdminDelete(t *testing.T) {
+	db := openTestDB(t)
+	ctx := context.Background()
+	users := NewUserRepository(db)
+	deletions := NewAccountDeletionRepository(db)
+	suffix := time.Now().UnixNano()
+
+	email := fmt.Sprintf("removed%d@example.com", suffix)
+	user := &models.User{Email: email, Username: fmt.Sprintf("removed%d", suffix), PasswordHash: "x"}
+	require.NoError(t, users.Create(ctx, user))
+
+	// an organization must keep an owner until it gets another one
+	orgs := NewOrganizationRepository(db)
+	org := &models.Organization{Name: "Removed", Slug: fmt.Sprintf("removed-%d", suffix)}
+	require.NoError(t, orgs.Create(ctx, org, user.ID))
+	require.ErrorIs(t, users.Delete(ctx, user.ID), ErrLastOrganizationOwner)
+	_, err := users.FindByID(ctx, user.ID)
+	require.NoError(t, err)
+	other := &models.User{Email: fmt.Sprintf("owner%d@example.com", suffix), Username: fmt.Sprintf("owner%d", suffix), PasswordHash: "x"}
+	require.NoError(t, users.Create(ctx, other))
+	require.NoError(t, orgs.AddMember(ctx, &models.OrganizationMember{OrganizationID: org.ID, UserID: other.ID, Role: models.OrgRoleOwner}))
+
+	require.NoError(t, users.Delete(ctx, user.ID))
+	require.ErrorIs(t, users.Delete(ctx, user.ID), ErrUserNotFound)
+
+	// admin deletes wait for the purge like self-service ones
+	_, err = users.FindByID(ctx, user.ID)
+	require.ErrorIs(t, err, ErrUserNotFound)
+	found, err := deletions.FindDeleted(ctx, email)
+	require.NoError(t, err)
+	require.Equal(t, models.StateReasonAdminDeletion, found.StateReason)
+	history, err := NewAccountStateRepository(db).History(ctx, user.ID)
+	require.NoError(t, err)
+	require.Equal(t, models.AccountDeleted, history[0].To)
+
+	purged, err := deletions.Purge(ctx, time.Now().Add(time.Minute), 1000)
+	require.NoError(t, err)
+	require.Contains(t, purged, PurgedUser{ID: user.ID})
+}
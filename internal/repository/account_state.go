@@ -0,0 +1,116 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/Atmosfr/user-service/internal/models"
+)
+
+type AccountStateRepository interface {
+	// Transition moves the user from change.From to change.To and records the
+	// change, filling in its id and time. It fails with ErrUserStateChanged if
+	// the user is no longer in change.From.
+	Transition(ctx context.Context, change *models.AccountStateChange) (*models.User, error)
+	// History lists the state changes of a user, newest first.
+	History(ctx context.Context, userID int64) ([]*models.AccountStateChange, error)
+	// ExpireSuspensions reactivates the users whose suspension ended at or
+	// before now and returns their ids.
+	ExpireSuspensions(ctx context.Context, now time.Time) ([]int64, error)
+}
+
+type accountStateRepository struct {
+	db *sql.DB
+}
+
+func (r *accountStateRepository) Transition(ctx context.Context, change *models.AccountStateChange) (*models.User, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	user, err := scanUser(tx.QueryRowContext(ctx, `UPDATE users SET state = $3, state_reason = $4, state_expires_at = $5, updated_at = NOW(),
+			deleted_at = CASE WHEN $3 = 'deleted' THEN NOW() END
+		WHERE id = $1 AND state = $2 AND purged_at IS NULL RETURNING `+userColumns,
+		change.UserID, change.From, change.To, change.Reason, change.ExpiresAt))
+	if errors.Is(err, sql.ErrNoRows) {
+		var exists bool
+		if err := tx.QueryRowContext(ctx, `SELECT EXISTS (SELECT 1 FROM users WHERE id = $1)`, change.UserID).Scan(&exists); err != nil {
+			return nil, err
+		}
+		if !exists {
+			return nil, ErrUserNotFound
+		}
+		return nil, ErrUserStateChanged
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	err = tx.QueryRowContext(ctx, `INSERT INTO user_state_history (user_id, from_state, to_state, reason, note, expires_at, actor_id)
+		VALUES ($1, $2, $3, $4, $5, $6, $7) RETURNING id, created_at`,
+		change.UserID, change.From, change.To, change.Reason, change.Note, change.ExpiresAt, change.ActorID).Scan(&change.ID, &change.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return user, tx.Commit()
+}
+
+func (r *accountStateRepository) History(ctx context.Context, userID int64) ([]*models.AccountStateChange, error) {
+	rows, err := r.db.QueryContext(ctx, `SELECT id, user_id, from_state, to_state, reason, note, expires_at, actor_id, created_at
+		FROM user_state_history WHERE user_id = $1 ORDER BY created_at DESC, id DESC`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	history := []*models.AccountStateChange{}
+	for rows.Next() {
+		change := &models.AccountStateChange{}
+		var expiresAt sql.NullTime
+		var actorID sql.NullInt64
+		if err := rows.Scan(&change.ID, &change.UserID, &change.From, &change.To, &change.Reason, &change.Note, &expiresAt, &actorID, &change.CreatedAt); err != nil {
+			return nil, err
+		}
+		if expiresAt.Valid {
+			change.ExpiresAt = &expiresAt.Time
+		}
+		if actorID.Valid {
+			change.ActorID = &actorID.Int64
+		}
+		history = append(history, change)
+	}
+	return history, rows.Err()
+}
+
+func (r *accountStateRepository) ExpireSuspensions(ctx context.Context, now time.Time) ([]int64, error) {
+	rows, err := r.db.QueryContext(ctx, `WITH expired AS (
+			UPDATE users SET state = 'active', state_reason = $2, state_expires_at = NULL, updated_at = NOW()
+			WHERE state = 'suspended' AND state_expires_at <= $1
+			RETURNING id
+		)
+		INSERT INTO user_state_history (user_id, from_state, to_state, reason)
+		SELECT id, 'suspended', 'active', $2 FROM expired
+		RETURNING user_id`, now, models.StateReasonSuspensionExpired)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var ids []int64
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
+
+func NewAccountStateRepository(db *sql.DB) AccountStateRepository {
+	return &accountStateRepository{db: db}
+}
@@ -0,0 +1,83 @@
+//go:build integration
+
+package repository
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/Atmosfr/user-service/internal/models"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAccountStateRepository(t *testing.T) {
+	db := openTestDB(t)
+	ctx := context.Background()
+	users := NewUserRepository(db)
+	states := NewAccountStateRepository(db)
+	suffix := time.Now().UnixNano()
+
+	admin := &models.User{Email: fmt.Sprintf("admin%d@example.com", suffix), Username: fmt.Sprintf("admin%d", suffix), PasswordHash: "x"}
+	require.NoError(t, users.Create(ctx, admin))
+	user := &models.User{Email: fmt.Sprintf("member%d@example.com", suffix), Username: fmt.Sprintf("member%d", suffix), PasswordHash: "x"}
+	require.NoError(t, users.Create(ctx, user))
+
+	until := time.Now().Add(time.Hour)
+	suspended, err := states.Transition(ctx, &models.AccountStateChange{
+		UserID: user.ID, From: models.AccountActive, To: models.AccountSuspended,
+		Reason: models.StateReasonSpam, ExpiresAt: &until, ActorID: &admin.ID,
+	})
+	require.NoError(t, err)
+	require.Equal(t, models.AccountSuspended, suspended.State)
+	require.False(t, suspended.IsActive)
+
+	// the user is no longer active, so the same change conflicts
+	_, err = states.Transition(ctx, &models.AccountStateChange{UserID: user.ID, From: models.AccountActive, To: models.AccountBanned, Reason: models.StateReasonSpam})
+	require.ErrorIs(t, err, ErrUserStateChanged)
+
+	ids, err := states.ExpireSuspensions(ctx, until.Add(time.Second))
+	require.NoError(t, err)
+	require.Contains(t, ids, user.ID)
+
+	found, err := users.FindByID(ctx, user.ID)
+	require.NoError(t, err)
+	require.Equal(t, models.AccountActive, found.State)
+	require.True(t, found.IsActive)
+	require.Nil(t, found.StateExpiresAt)
+
+	history, err := states.History(ctx, user.ID)
+	require.NoError(t, err)
+	require.Len(t, history, 2)
+	require.Equal(t, models.StateReasonSuspensionExpired, history[0].Reason)
+	require.Nil(t, history[0].ActorID)
+	require.Equal(t, admin.ID, *history[1].ActorID)
+}
+
+func TestSCIMUpdateUserKeepsBans(t *testing.T) {
+	db := openTestDB(t)
+	ctx := context.Background()
+	scimRepo := NewSCIMRepository(db)
+	states := NewAccountStateRepository(db)
+	suffix := time.Now().UnixNano()
+
+	tenant := &models.SCIMTenant{Name: fmt.Sprintf("idp%d", suffix)}
+	require.NoError(t, scimRepo.CreateTenant(ctx, tenant, fmt.Sprintf("hash%d", suffix)))
+	user := &models.SCIMUser{TenantID: tenant.ID, User: models.User{
+		Email: fmt.Sprintf("scim%d@example.com", suffix), Username: fmt.Sprintf("scim%d", suffix), PasswordHash: "x", IsActive: true,
+	}}
+	require.NoError(t, scimRepo.CreateUser(ctx, user))
+
+	user.IsActive = false
+	require.NoError(t, scimRepo.UpdateUser(ctx, user))
+	require.False(t, user.IsActive)
+	user.IsActive = true
+	require.NoError(t, scimRepo.UpdateUser(ctx, user))
+	require.True(t, user.IsActive)
+
+	_, err := states.Transition(ctx, &models.AccountStateChange{UserID: user.ID, From: models.AccountActive, To: models.AccountBanned, Reason: models.StateReasonFraud})
+	require.NoError(t, err)
+	require.NoError(t, scimRepo.UpdateUser(ctx, user))
+	require.False(t, user.IsActive)
+}
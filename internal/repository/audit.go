@@ -0,0 +1,208 @@
+package repository
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/Atmosfr/user-service/internal/models"
+)
+
+// auditChainLock is the advisory lock serializing appends, every event needs
+// the hash of the one committed before it.
+const auditChainLock = 0x61756469
+
+// AuditFilter narrows down List, zero fields match everything.
+type AuditFilter struct {
+	Action   string
+	ActorID  *int64
+	TargetID *int64
+	// UserID matches events the user did or was the target of.
+	UserID    *int64
+	IP        string
+	RequestID string
+	From      *time.Time
+	To        *time.Time
+}
+
+type AuditRepository interface {
+	// Append links event to the end of the chain and stores it, filling in
+	// its id, PrevHash and Hash.
+	Append(ctx context.Context, event *models.AuditEvent) error
+	// List returns the events matching filter with an id below beforeID,
+	// newest first. beforeID 0 starts at the latest event.
+	List(ctx context.Context, filter AuditFilter, beforeID int64, limit int) ([]*models.AuditEvent, error)
+	// Chain returns the events with an id above afterID in chain order.
+	Chain(ctx context.Context, afterID int64, limit int) ([]*models.AuditEvent, error)
+}
+
+type auditRepository struct {
+	db *sql.DB
+}
+
+// auditHashInput is what the hash of an event covers, in a fixed order.
+type auditHashInput struct {
+	PrevHash  string            `json:"prev_hash"`
+	Action    string            `json:"action"`
+	ActorID   *int64            `json:"actor_id"`
+	TargetID  *int64            `json:"target_id"`
+	IP        string            `json:"ip"`
+	UserAgent string            `json:"user_agent"`
+	RequestID string            `json:"request_id"`
+	Details   map[string]string `json:"details"`
+	CreatedAt string            `json:"created_at"`
+}
+
+// HashAuditEvent returns the hex SHA-256 of event and its PrevHash. Details
+// are encoded with sorted keys and CreatedAt at the microsecond precision
+// postgres stores, so stored events hash to the same value.
+func HashAuditEvent(event *models.AuditEvent) string {
+	input := auditHashInput{
+		PrevHash:  event.PrevHash,
+		Action:    event.Action,
+		ActorID:   event.ActorID,
+		TargetID:  event.TargetID,
+		IP:        event.IP,
+		UserAgent: event.UserAgent,
+		RequestID: event.RequestID,
+		CreatedAt: event.CreatedAt.UTC().Truncate(time.Microsecond).Format(time.RFC3339Nano),
+	}
+	if len(event.Details) > 0 {
+		input.Details = event.Details
+	}
+	// a struct of strings and a string map always encodes
+	data, _ := json.Marshal(input)
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+func (r *auditRepository) Append(ctx context.Context, event *models.AuditEvent) error {
+	details := event.Details
+	if details == nil {
+		details = map[string]string{}
+	}
+	detailsJSON, err := json.Marshal(details)
+	if err != nil {
+		return err
+	}
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, `SELECT pg_advisory_xact_lock($1)`, auditChainLock); err != nil {
+		return err
+	}
+	event.PrevHash = ""
+	err = tx.QueryRowContext(ctx, `SELECT hash FROM audit_events ORDER BY id DESC LIMIT 1`).Scan(&event.PrevHash)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return err
+	}
+	event.CreatedAt = event.CreatedAt.UTC().Truncate(time.Microsecond)
+	event.Hash = HashAuditEvent(event)
+
+	err = tx.QueryRowContext(ctx, `INSERT INTO audit_events
+			(action, actor_id, target_id, ip_address, user_agent, request_id, details, created_at, prev_hash, hash)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10) RETURNING id`,
+		event.Action, event.ActorID, event.TargetID, event.IP, event.UserAgent, event.RequestID, detailsJSON,
+		event.CreatedAt, event.PrevHash, event.Hash).Scan(&event.ID)
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+const auditEventColumns = `id, action, actor_id, target_id, ip_address, user_agent, request_id, details, created_at, prev_hash, hash`
+
+func scanAuditEvents(rows *sql.Rows) ([]*models.AuditEvent, error) {
+	defer rows.Close()
+	events := []*models.AuditEvent{}
+	for rows.Next() {
+		event := &models.AuditEvent{}
+		var actorID, targetID sql.NullInt64
+		var details []byte
+		if err := rows.Scan(&event.ID, &event.Action, &actorID, &targetID, &event.IP, &event.UserAgent, &event.RequestID,
+			&details, &event.CreatedAt, &event.PrevHash, &event.Hash); err != nil {
+			return nil, err
+		}
+		if actorID.Valid {
+			event.ActorID = &actorID.Int64
+		}
+		if targetID.Valid {
+			event.TargetID = &targetID.Int64
+		}
+		if err := json.Unmarshal(details, &event.Details); err != nil {
+			return nil, err
+		}
+		if len(event.Details) == 0 {
+			event.Details = nil
+		}
+		events = append(events, event)
+	}
+	return events, rows.Err()
+}
+
+func (r *auditRepository) List(ctx context.Context, filter AuditFilter, beforeID int64, limit int) ([]*models.AuditEvent, error) {
+	args := &sqlArgs{}
+	var conditions []string
+	if filter.Action != "" {
+		conditions = append(conditions, "action = "+args.add(filter.Action))
+	}
+	if filter.ActorID != nil {
+		conditions = append(conditions, "actor_id = "+args.add(*filter.ActorID))
+	}
+	if filter.TargetID != nil {
+		conditions = append(conditions, "target_id = "+args.add(*filter.TargetID))
+	}
+	if filter.UserID != nil {
+		id := args.add(*filter.UserID)
+		conditions = append(conditions, "(actor_id = "+id+" OR target_id = "+id+")")
+	}
+	if filter.IP != "" {
+		conditions = append(conditions, "ip_address = "+args.add(filter.IP))
+	}
+	if filter.RequestID != "" {
+		conditions = append(conditions, "request_id = "+args.add(filter.RequestID))
+	}
+	if filter.From != nil {
+		conditions = append(conditions, "created_at >= "+args.add(*filter.From))
+	}
+	if filter.To != nil {
+		conditions = append(conditions, "created_at < "+args.add(*filter.To))
+	}
+	if beforeID > 0 {
+		conditions = append(conditions, "id < "+args.add(beforeID))
+	}
+
+	query := `SELECT ` + auditEventColumns + ` FROM audit_events`
+	if len(conditions) > 0 {
+		query += " WHERE " + strings.Join(conditions, " AND ")
+	}
+	query += " ORDER BY id DESC LIMIT " + args.add(limit)
+
+	rows, err := r.db.QueryContext(ctx, query, args.values...)
+	if err != nil {
+		return nil, err
+	}
+	return scanAuditEvents(rows)
+}
+
+func (r *auditRepository) Chain(ctx context.Context, afterID int64, limit int) ([]*models.AuditEvent, error) {
+	rows, err := r.db.QueryContext(ctx, `SELECT `+auditEventColumns+` FROM audit_events
+		WHERE id > $1 ORDER BY id LIMIT $2`, afterID, limit)
+	if err != nil {
+		return nil, err
+	}
+	return scanAuditEvents(rows)
+}
+
+func NewAuditRepository(db *sql.DB) AuditRepository {
+	return &auditRepository{db: db}
+}
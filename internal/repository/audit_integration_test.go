@@ -0,0 +1,56 @@
+//go:build integration
+
+package repository
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/Atmosfr/user-service/internal/models"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAuditRepositoryAppendOnlyChain(t *testing.T) {
+	db := openTestDB(t)
+	ctx := context.Background()
+	audit := NewAuditRepository(db)
+	// events of other runs stay, the log cannot be cleaned up
+	requestID := fmt.Sprintf("audit-test-%d", time.Now().UnixNano())
+	actorID, targetID := int64(1), int64(2)
+
+	first := &models.AuditEvent{Action: models.AuditLoginFailed, TargetID: &targetID, IP: "192.0.2.1", RequestID: requestID,
+		Details: map[string]string{"reason": "invalid_password"}, CreatedAt: time.Now()}
+	second := &models.AuditEvent{Action: models.AuditRoleChanged, ActorID: &actorID, TargetID: &targetID, RequestID: requestID, CreatedAt: time.Now()}
+	require.NoError(t, audit.Append(ctx, first))
+	require.NoError(t, audit.Append(ctx, second))
+	require.Equal(t, first.Hash, second.PrevHash)
+
+	events, err := audit.List(ctx, AuditFilter{RequestID: requestID}, 0, 10)
+	require.NoError(t, err)
+	require.Len(t, events, 2)
+	require.Equal(t, second.ID, events[0].ID)
+	// stored events hash to what was computed before the insert
+	for _, event := range events {
+		require.Equal(t, event.Hash, HashAuditEvent(event))
+	}
+	require.Equal(t, first.Details, events[1].Details)
+
+	events, err = audit.List(ctx, AuditFilter{RequestID: requestID, ActorID: &actorID}, 0, 10)
+	require.NoError(t, err)
+	require.Len(t, events, 1)
+	events, err = audit.List(ctx, AuditFilter{RequestID: requestID, UserID: &targetID}, second.ID, 10)
+	require.NoError(t, err)
+	require.Len(t, events, 1)
+	require.Equal(t, first.ID, events[0].ID)
+
+	chain, err := audit.Chain(ctx, first.ID-1, 2)
+	require.NoError(t, err)
+	require.Equal(t, first.Hash, chain[0].Hash)
+
+	_, err = db.ExecContext(ctx, `UPDATE audit_events SET action = 'login.succeeded' WHERE id = $1`, first.ID)
+	require.ErrorContains(t, err, "append-only")
+	_, err = db.ExecContext(ctx, `DELETE FROM audit_events WHERE id = $1`, first.ID)
+	require.ErrorContains(t, err, "append-only")
+}
@@ -0,0 +1,25 @@
+package repository
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Atmosfr/user-service/internal/models"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHashAuditEvent(t *testing.T) {
+	created := time.Date(2026, 10, 19, 12, 0, 0, 123456789, time.FixedZone("CEST", 2*60*60))
+	event := &models.AuditEvent{Action: models.AuditLoginSucceeded, CreatedAt: created}
+	hash := HashAuditEvent(event)
+	require.Len(t, hash, 64)
+
+	// what postgres gives back hashes the same
+	stored := &models.AuditEvent{Action: models.AuditLoginSucceeded, Details: map[string]string{}, CreatedAt: created.UTC().Truncate(time.Microsecond)}
+	require.Equal(t, hash, HashAuditEvent(stored))
+
+	stored.PrevHash = hash
+	require.NotEqual(t, hash, HashAuditEvent(stored))
+	userID := int64(1)
+	require.NotEqual(t, hash, HashAuditEvent(&models.AuditEvent{Action: models.AuditLoginSucceeded, ActorID: &userID, CreatedAt: created}))
+}
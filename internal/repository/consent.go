@@ -0,0 +1,126 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/Atmosfr/user-service/internal/models"
+)
+
+type ConsentRepository interface {
+	CreateDocument(ctx context.Context, doc *models.LegalDocument) error
+	// ListDocuments returns all versions, the latest published first.
+	ListDocuments(ctx context.Context) ([]*models.LegalDocument, error)
+	// CurrentDocuments returns the latest version of each kind published by now.
+	CurrentDocuments(ctx context.Context, now time.Time) ([]*models.LegalDocument, error)
+	// PendingDocuments returns, for each kind, the latest mandatory version
+	// published by now unless the user accepted it or a later version.
+	PendingDocuments(ctx context.Context, userID int64, now time.Time) ([]*models.LegalDocument, error)
+	// Record appends consents in one transaction.
+	Record(ctx context.Context, consents []*models.Consent) error
+	// History returns the user's consents, the latest first.
+	History(ctx context.Context, userID int64) ([]*models.Consent, error)
+}
+
+type consentRepository struct {
+	db *sql.DB
+}
+
+const legalDocumentColumns = `id, kind, version, url, mandatory, published_at, created_at`
+
+func scanLegalDocuments(rows *sql.Rows) ([]*models.LegalDocument, error) {
+	defer rows.Close()
+	docs := []*models.LegalDocument{}
+	for rows.Next() {
+		doc := &models.LegalDocument{}
+		if err := rows.Scan(&doc.ID, &doc.Kind, &doc.Version, &doc.URL, &doc.Mandatory, &doc.PublishedAt, &doc.CreatedAt); err != nil {
+			return nil, err
+		}
+		docs = append(docs, doc)
+	}
+	return docs, rows.Err()
+}
+
+func (r *consentRepository) CreateDocument(ctx context.Context, doc *models.LegalDocument) error {
+	err := r.db.QueryRowContext(ctx, `INSERT INTO legal_documents (kind, version, url, mandatory, published_at)
+		VALUES ($1, $2, $3, $4, $5) RETURNING id, created_at`,
+		doc.Kind, doc.Version, doc.URL, doc.Mandatory, doc.PublishedAt).Scan(&doc.ID, &doc.CreatedAt)
+	return mapUniqueViolation(err)
+}
+
+func (r *consentRepository) ListDocuments(ctx context.Context) ([]*models.LegalDocument, error) {
+	rows, err := r.db.QueryContext(ctx, `SELECT `+legalDocumentColumns+` FROM legal_documents ORDER BY published_at DESC, id DESC`)
+	if err != nil {
+		return nil, err
+	}
+	return scanLegalDocuments(rows)
+}
+
+func (r *consentRepository) CurrentDocuments(ctx context.Context, now time.Time) ([]*models.LegalDocument, error) {
+	rows, err := r.db.QueryContext(ctx, `SELECT DISTINCT ON (kind) `+legalDocumentColumns+` FROM legal_documents
+		WHERE published_at <= $1 ORDER BY kind, published_at DESC, id DESC`, now)
+	if err != nil {
+		return nil, err
+	}
+	return scanLegalDocuments(rows)
+}
+
+func (r *consentRepository) PendingDocuments(ctx context.Context, userID int64, now time.Time) ([]*models.LegalDocument, error) {
+	rows, err := r.db.QueryContext(ctx, `SELECT `+legalDocumentColumns+` FROM (
+			SELECT DISTINCT ON (kind) * FROM legal_documents
+			WHERE mandatory AND published_at <= $2 ORDER BY kind, published_at DESC, id DESC
+		) d
+		WHERE NOT EXISTS (SELECT 1 FROM user_consents c JOIN legal_documents a ON a.id = c.document_id
+			WHERE c.user_id = $1 AND a.kind = d.kind AND a.published_at >= d.published_at)
+		ORDER BY kind`, userID, now)
+	if err != nil {
+		return nil, err
+	}
+	return scanLegalDocuments(rows)
+}
+
+func (r *consentRepository) Record(ctx context.Context, consents []*models.Consent) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	for _, c := range consents {
+		err := tx.QueryRowContext(ctx, `INSERT INTO user_consents (user_id, kind, document_id, granted, ip_address)
+			VALUES ($1, $2, $3, $4, $5) RETURNING id, created_at`,
+			c.UserID, c.Kind, c.DocumentID, c.Granted, c.IPAddress).Scan(&c.ID, &c.CreatedAt)
+		if err != nil {
+			if isForeignKeyViolation(err, "user_consents_user_id_fkey") {
+				return ErrUserNotFound
+			}
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+func (r *consentRepository) History(ctx context.Context, userID int64) ([]*models.Consent, error) {
+	rows, err := r.db.QueryContext(ctx, `SELECT c.id, c.user_id, c.kind, c.document_id, COALESCE(d.version, ''), c.granted, c.ip_address, c.created_at
+		FROM user_consents c LEFT JOIN legal_documents d ON d.id = c.document_id
+		WHERE c.user_id = $1 ORDER BY c.created_at DESC, c.id DESC`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	consents := []*models.Consent{}
+	for rows.Next() {
+		c := &models.Consent{}
+		if err := rows.Scan(&c.ID, &c.UserID, &c.Kind, &c.DocumentID, &c.Version, &c.Granted, &c.IPAddress, &c.CreatedAt); err != nil {
+			return nil, err
+		}
+		consents = append(consents, c)
+	}
+	return consents, rows.Err()
+}
+
+func NewConsentRepository(db *sql.DB) ConsentRepository {
+	return &consentRepository{db: db}
+}
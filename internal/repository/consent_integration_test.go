@@ -0,0 +1,76 @@
+//go:build integration
+
+package repository
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/Atmosfr/user-service/internal/models"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConsentRepositoryPendingDocuments(t *testing.T) {
+	db := openTestDB(t)
+	ctx := context.Background()
+	users := NewUserRepository(db)
+	consents := NewConsentRepository(db)
+	suffix := time.Now().UnixNano()
+	// far in the future, so these versions are the latest for the test
+	base := time.Now().AddDate(100, 0, 0).Add(time.Duration(suffix % int64(time.Hour)))
+
+	user := &models.User{Email: fmt.Sprintf("consent%d@example.com", suffix), Username: fmt.Sprintf("consent%d", suffix), PasswordHash: "x"}
+	require.NoError(t, users.Create(ctx, user))
+	// documents of other runs would compete for the latest version
+	t.Cleanup(func() {
+		db.Exec(`DELETE FROM user_consents WHERE user_id = $1`, user.ID)
+		db.Exec(`DELETE FROM legal_documents WHERE version LIKE $1`, fmt.Sprintf("%%-%d", suffix))
+	})
+
+	publish := func(version string, mandatory bool, at time.Time) *models.LegalDocument {
+		doc := &models.LegalDocument{Kind: models.DocumentTermsOfService, Version: fmt.Sprintf("%s-%d", version, suffix), URL: "https://example.com/terms", Mandatory: mandatory, PublishedAt: at}
+		require.NoError(t, consents.CreateDocument(ctx, doc))
+		return doc
+	}
+	v1 := publish("v1", true, base)
+	err := consents.CreateDocument(ctx, &models.LegalDocument{Kind: v1.Kind, Version: v1.Version, URL: v1.URL, PublishedAt: base})
+	require.ErrorIs(t, err, ErrDocumentVersionExists)
+	v2 := publish("v2", false, base.Add(time.Hour))
+
+	pending, err := consents.PendingDocuments(ctx, user.ID, base.Add(2*time.Hour))
+	require.NoError(t, err)
+	require.Contains(t, documentIDs(pending), v1.ID)
+
+	// accepting a later version covers the mandatory one before it
+	require.NoError(t, consents.Record(ctx, []*models.Consent{
+		{UserID: user.ID, Kind: v2.Kind, DocumentID: &v2.ID, Granted: true, IPAddress: "192.0.2.1"},
+		{UserID: user.ID, Kind: models.ConsentMarketing, Granted: true},
+	}))
+	pending, err = consents.PendingDocuments(ctx, user.ID, base.Add(2*time.Hour))
+	require.NoError(t, err)
+	require.NotContains(t, documentIDs(pending), v1.ID)
+
+	// a new mandatory version asks again once it is published
+	v3 := publish("v3", true, base.Add(3*time.Hour))
+	pending, err = consents.PendingDocuments(ctx, user.ID, base.Add(2*time.Hour))
+	require.NoError(t, err)
+	require.NotContains(t, documentIDs(pending), v3.ID)
+	pending, err = consents.PendingDocuments(ctx, user.ID, base.Add(4*time.Hour))
+	require.NoError(t, err)
+	require.Contains(t, documentIDs(pending), v3.ID)
+
+	history, err := consents.History(ctx, user.ID)
+	require.NoError(t, err)
+	require.Len(t, history, 2)
+	require.Equal(t, v2.Version, history[1].Version)
+}
+
+func documentIDs(docs []*models.LegalDocument) []int64 {
+	ids := make([]int64, 0, len(docs))
+	for _, doc := range docs {
+		ids = append(ids, doc.ID)
+	}
+	return ids
+}
@@ -0,0 +1,128 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/Atmosfr/user-service/internal/models"
+)
+
+type DataExportRepository interface {
+	// Create queues a pending export. It fails with ErrExportInProgress while
+	// the user has another pending or running export.
+	Create(ctx context.Context, export *models.DataExport) error
+	Find(ctx context.Context, id int64) (*models.DataExport, error)
+	// Latest returns the user's most recent export.
+	Latest(ctx context.Context, userID int64) (*models.DataExport, error)
+	// Claim marks the oldest pending export running and returns it. Running
+	// exports started before staleBefore are claimed again, their worker is
+	// assumed gone. It fails with ErrExportNotFound when there is nothing to do.
+	Claim(ctx context.Context, staleBefore time.Time) (*models.DataExport, error)
+	// Complete marks a running export ready with its BlobKey, Size and ExpiresAt.
+	Complete(ctx context.Context, export *models.DataExport) error
+	Fail(ctx context.Context, id int64) error
+	// Expire marks ready exports that expired by now and returns their blob keys.
+	Expire(ctx context.Context, now time.Time) ([]string, error)
+}
+
+type dataExportRepository struct {
+	db *sql.DB
+}
+
+const dataExportColumns = `id, user_id, status, blob_key, size, expires_at, completed_at, created_at`
+
+func scanDataExport(row rowScanner) (*models.DataExport, error) {
+	export := &models.DataExport{}
+	var expiresAt, completedAt sql.NullTime
+	err := row.Scan(&export.ID, &export.UserID, &export.Status, &export.BlobKey, &export.Size,
+		&expiresAt, &completedAt, &export.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	if expiresAt.Valid {
+		export.ExpiresAt = &expiresAt.Time
+	}
+	if completedAt.Valid {
+		export.CompletedAt = &completedAt.Time
+	}
+	return export, nil
+}
+
+func findDataExport(row rowScanner) (*models.DataExport, error) {
+	export, err := scanDataExport(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrExportNotFound
+	}
+	return export, err
+}
+
+func (r *dataExportRepository) Create(ctx context.Context, export *models.DataExport) error {
+	err := r.db.QueryRowContext(ctx, `INSERT INTO data_exports (user_id) VALUES ($1) RETURNING id, status, created_at`,
+		export.UserID).Scan(&export.ID, &export.Status, &export.CreatedAt)
+	if err != nil {
+		if isForeignKeyViolation(err, "data_exports_user_id_fkey") {
+			return ErrUserNotFound
+		}
+		return mapUniqueViolation(err)
+	}
+	return nil
+}
+
+func (r *dataExportRepository) Find(ctx context.Context, id int64) (*models.DataExport, error) {
+	return findDataExport(r.db.QueryRowContext(ctx, `SELECT `+dataExportColumns+` FROM data_exports WHERE id = $1`, id))
+}
+
+func (r *dataExportRepository) Latest(ctx context.Context, userID int64) (*models.DataExport, error) {
+	return findDataExport(r.db.QueryRowContext(ctx, `SELECT `+dataExportColumns+` FROM data_exports
+		WHERE user_id = $1 ORDER BY created_at DESC, id DESC LIMIT 1`, userID))
+}
+
+func (r *dataExportRepository) Claim(ctx context.Context, staleBefore time.Time) (*models.DataExport, error) {
+	// SKIP LOCKED lets several instances work through the queue side by side
+	return findDataExport(r.db.QueryRowContext(ctx, `UPDATE data_exports SET status = 'running', started_at = NOW()
+		WHERE id = (SELECT id FROM data_exports
+			WHERE status = 'pending' OR (status = 'running' AND started_at < $1)
+			ORDER BY created_at, id LIMIT 1 FOR UPDATE SKIP LOCKED)
+		RETURNING `+dataExportColumns, staleBefore))
+}
+
+func (r *dataExportRepository) Complete(ctx context.Context, export *models.DataExport) error {
+	err := r.db.QueryRowContext(ctx, `UPDATE data_exports SET status = 'ready', blob_key = $2, size = $3, expires_at = $4, completed_at = NOW()
+		WHERE id = $1 AND status = 'running' RETURNING status, completed_at`,
+		export.ID, export.BlobKey, export.Size, export.ExpiresAt).Scan(&export.Status, &export.CompletedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrExportNotFound
+	}
+	return err
+}
+
+func (r *dataExportRepository) Fail(ctx context.Context, id int64) error {
+	_, err := r.db.ExecContext(ctx, `UPDATE data_exports SET status = 'failed', completed_at = NOW()
+		WHERE id = $1 AND status = 'running'`, id)
+	return err
+}
+
+func (r *dataExportRepository) Expire(ctx context.Context, now time.Time) ([]string, error) {
+	rows, err := r.db.QueryContext(ctx, `UPDATE data_exports SET status = 'expired'
+		WHERE status = 'ready' AND expires_at <= $1 RETURNING blob_key`, now)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var keys []string
+	for rows.Next() {
+		var key string
+		if err := rows.Scan(&key); err != nil {
+			return nil, err
+		}
+		keys = append(keys, key)
+	}
+	return keys, rows.Err()
+}
+
+func NewDataExportRepository(db *sql.DB) DataExportRepository {
+	return &dataExportRepository{db: db}
+}
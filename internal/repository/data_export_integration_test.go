@@ -0,0 +1,59 @@
+//go:build integration
+
+package repository
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/Atmosfr/user-service/internal/models"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDataExportRepository(t *testing.T) {
+	db := openTestDB(t)
+	ctx := context.Background()
+	users := NewUserRepository(db)
+	exports := NewDataExportRepository(db)
+	suffix := time.Now().UnixNano()
+
+	user := &models.User{Email: fmt.Sprintf("export%d@example.com", suffix), Username: fmt.Sprintf("export%d", suffix), PasswordHash: "x"}
+	require.NoError(t, users.Create(ctx, user))
+
+	export := &models.DataExport{UserID: user.ID}
+	require.NoError(t, exports.Create(ctx, export))
+	require.Equal(t, models.DataExportPending, export.Status)
+	require.ErrorIs(t, exports.Create(ctx, &models.DataExport{UserID: user.ID}), ErrExportInProgress)
+
+	// other pending exports may be queued ahead, claim until ours comes up
+	var claimed *models.DataExport
+	for claimed == nil || claimed.ID != export.ID {
+		var err error
+		claimed, err = exports.Claim(ctx, time.Now().Add(-time.Hour))
+		require.NoError(t, err)
+	}
+	require.Equal(t, models.DataExportRunning, claimed.Status)
+	// still in flight while running
+	require.ErrorIs(t, exports.Create(ctx, &models.DataExport{UserID: user.ID}), ErrExportInProgress)
+
+	expiresAt := time.Now().Add(time.Hour)
+	claimed.BlobKey = fmt.Sprintf("exports/%d/abc.zip", user.ID)
+	claimed.Size = 42
+	claimed.ExpiresAt = &expiresAt
+	require.NoError(t, exports.Complete(ctx, claimed))
+	require.Equal(t, models.DataExportReady, claimed.Status)
+
+	latest, err := exports.Latest(ctx, user.ID)
+	require.NoError(t, err)
+	require.Equal(t, export.ID, latest.ID)
+	require.Equal(t, int64(42), latest.Size)
+
+	keys, err := exports.Expire(ctx, expiresAt.Add(time.Second))
+	require.NoError(t, err)
+	require.Contains(t, keys, claimed.BlobKey)
+
+	// a finished export frees the slot
+	require.NoError(t, exports.Create(ctx, &models.DataExport{UserID: user.ID}))
+}
@@ -0,0 +1,152 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/Atmosfr/user-service/internal/models"
+)
+
+type DomainRepository interface {
+	Create(ctx context.Context, d *models.OrganizationDomain) error
+	List(ctx context.Context, orgID int64) ([]*models.OrganizationDomain, error)
+	Find(ctx context.Context, orgID, id int64) (*models.OrganizationDomain, error)
+	// MarkVerified fails with ErrDomainVerifiedElsewhere when another organization
+	// has proved the domain first.
+	MarkVerified(ctx context.Context, d *models.OrganizationDomain) error
+	UpdateSettings(ctx context.Context, d *models.OrganizationDomain) error
+	Delete(ctx context.Context, orgID, id int64) error
+
+	// FindVerified returns the organization's claim that has been verified for
+	// domain. It searches all organizations, so it runs outside any tenant scope.
+	FindVerified(ctx context.Context, domain string) (*models.OrganizationDomain, error)
+}
+
+type domainRepository struct {
+	tenants tenantScope
+}
+
+const domainColumns = `id, organization_id, domain, verification_token, verified_at, auto_join, sso_enforced, created_at, updated_at`
+
+func scanDomain(row rowScanner) (*models.OrganizationDomain, error) {
+	d := &models.OrganizationDomain{}
+	var verifiedAt sql.NullTime
+	err := row.Scan(&d.ID, &d.OrganizationID, &d.Domain, &d.VerificationToken, &verifiedAt,
+		&d.AutoJoin, &d.SSOEnforced, &d.CreatedAt, &d.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	if verifiedAt.Valid {
+		d.VerifiedAt = &verifiedAt.Time
+	}
+	return d, nil
+}
+
+func (r *domainRepository) Create(ctx context.Context, d *models.OrganizationDomain) error {
+	query := `INSERT INTO org_domains (organization_id, domain, verification_token, auto_join, sso_enforced)
+		  VALUES ($1, $2, $3, $4, $5) RETURNING id, created_at, updated_at`
+	err := r.tenants.run(ctx, d.OrganizationID, func(q dbtx) error {
+		return q.QueryRowContext(ctx, query, d.OrganizationID, d.Domain, d.VerificationToken, d.AutoJoin, d.SSOEnforced).
+			Scan(&d.ID, &d.CreatedAt, &d.UpdatedAt)
+	})
+	if err != nil {
+		if isForeignKeyViolation(err, "org_domains_organization_id_fkey") {
+			return ErrOrganizationNotFound
+		}
+		return mapUniqueViolation(err)
+	}
+	return nil
+}
+
+func (r *domainRepository) List(ctx context.Context, orgID int64) ([]*models.OrganizationDomain, error) {
+	domains := []*models.OrganizationDomain{}
+	err := r.tenants.run(ctx, orgID, func(q dbtx) error {
+		rows, err := q.QueryContext(ctx, `SELECT `+domainColumns+` FROM org_domains WHERE organization_id = $1 ORDER BY domain`, orgID)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			d, err := scanDomain(rows)
+			if err != nil {
+				return err
+			}
+			domains = append(domains, d)
+		}
+		return rows.Err()
+	})
+	if err != nil {
+		return nil, err
+	}
+	return domains, nil
+}
+
+func findDomain(ctx context.Context, q dbtx, query string, args ...any) (*models.OrganizationDomain, error) {
+	d, err := scanDomain(q.QueryRowContext(ctx, `SELECT `+domainColumns+` FROM org_domains WHERE `+query, args...))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrDomainNotFound
+		}
+		return nil, err
+	}
+	return d, nil
+}
+
+func (r *domainRepository) Find(ctx context.Context, orgID, id int64) (*models.OrganizationDomain, error) {
+	var d *models.OrganizationDomain
+	err := r.tenants.run(ctx, orgID, func(q dbtx) (err error) {
+		d, err = findDomain(ctx, q, `organization_id = $1 AND id = $2`, orgID, id)
+		return err
+	})
+	return d, err
+}
+
+func (r *domainRepository) FindVerified(ctx context.Context, domain string) (*models.OrganizationDomain, error) {
+	return findDomain(ctx, r.tenants.db, `domain = $1 AND verified_at IS NOT NULL`, domain)
+}
+
+func (r *domainRepository) MarkVerified(ctx context.Context, d *models.OrganizationDomain) error {
+	err := r.tenants.run(ctx, d.OrganizationID, func(q dbtx) error {
+		return q.QueryRowContext(ctx, `UPDATE org_domains SET verified_at = COALESCE(verified_at, NOW()), updated_at = NOW()
+			WHERE organization_id = $1 AND id = $2 RETURNING verified_at, updated_at`, d.OrganizationID, d.ID).
+			Scan(&d.VerifiedAt, &d.UpdatedAt)
+	})
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrDomainNotFound
+		}
+		return mapUniqueViolation(err)
+	}
+	return nil
+}
+
+func (r *domainRepository) UpdateSettings(ctx context.Context, d *models.OrganizationDomain) error {
+	err := r.tenants.run(ctx, d.OrganizationID, func(q dbtx) error {
+		return q.QueryRowContext(ctx, `UPDATE org_domains SET auto_join = $3, sso_enforced = $4, updated_at = NOW()
+			WHERE organization_id = $1 AND id = $2 RETURNING updated_at`, d.OrganizationID, d.ID, d.AutoJoin, d.SSOEnforced).
+			Scan(&d.UpdatedAt)
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrDomainNotFound
+	}
+	return err
+}
+
+func (r *domainRepository) Delete(ctx context.Context, orgID, id int64) error {
+	return r.tenants.run(ctx, orgID, func(q dbtx) error {
+		res, err := q.ExecContext(ctx, `DELETE FROM org_domains WHERE organization_id = $1 AND id = $2`, orgID, id)
+		if err != nil {
+			return err
+		}
+		if n, _ := res.RowsAffected(); n == 0 {
+			return ErrDomainNotFound
+		}
+		return nil
+	})
+}
+
+func NewDomainRepository(db *sql.DB, opts ...TenantOption) DomainRepository {
+	return &domainRepository{tenants: newTenantScope(db, opts)}
+}
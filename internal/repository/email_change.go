@@ -0,0 +1,153 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/Atmosfr/user-service/internal/models"
+)
+
+type EmailChangeRepository interface {
+	// Create cancels any pending change of the user before adding change.
+	Create(ctx context.Context, change *models.EmailChange) error
+	Find(ctx context.Context, id int64) (*models.EmailChange, error)
+	// Confirm moves the user to the new address and marks it verified. It fails
+	// with ErrEmailChangeNotFound unless change is pending and unexpired and the
+	// user still has the old address, and with ErrEmailAlreadyExists if another
+	// user took the new one in the meantime.
+	Confirm(ctx context.Context, change *models.EmailChange) error
+	// Cancel closes a pending change.
+	Cancel(ctx context.Context, id int64) error
+	// Revert moves the user back to the old address of a confirmed change that
+	// is still revertible.
+	Revert(ctx context.Context, change *models.EmailChange) error
+}
+
+type emailChangeRepository struct {
+	db *sql.DB
+}
+
+const emailChangeSelect = `SELECT id, user_id, old_email, new_email, status, expires_at, revertible_until, confirmed_at, created_at, updated_at
+	FROM email_changes`
+
+func scanEmailChange(row rowScanner) (*models.EmailChange, error) {
+	change := &models.EmailChange{}
+	var confirmedAt sql.NullTime
+	err := row.Scan(&change.ID, &change.UserID, &change.OldEmail, &change.NewEmail, &change.Status,
+		&change.ExpiresAt, &change.RevertibleUntil, &confirmedAt, &change.CreatedAt, &change.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	if confirmedAt.Valid {
+		change.ConfirmedAt = &confirmedAt.Time
+	}
+	return change, nil
+}
+
+func (r *emailChangeRepository) Create(ctx context.Context, change *models.EmailChange) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.ExecContext(ctx, `UPDATE email_changes SET status = 'cancelled', updated_at = NOW()
+		WHERE user_id = $1 AND status = 'pending'`, change.UserID)
+	if err != nil {
+		return err
+	}
+
+	query := `INSERT INTO email_changes (user_id, old_email, new_email, expires_at, revertible_until)
+		  VALUES ($1, $2, $3, $4, $5) RETURNING id, status, created_at, updated_at`
+	err = tx.QueryRowContext(ctx, query, change.UserID, change.OldEmail, change.NewEmail, change.ExpiresAt, change.RevertibleUntil).
+		Scan(&change.ID, &change.Status, &change.CreatedAt, &change.UpdatedAt)
+	if err != nil {
+		if isForeignKeyViolation(err, "email_changes_user_id_fkey") {
+			return ErrUserNotFound
+		}
+		return err
+	}
+	return tx.Commit()
+}
+
+func (r *emailChangeRepository) Find(ctx context.Context, id int64) (*models.EmailChange, error) {
+	change, err := scanEmailChange(r.db.QueryRowContext(ctx, emailChangeSelect+` WHERE id = $1`, id))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrEmailChangeNotFound
+		}
+		return nil, err
+	}
+	return change, nil
+}
+
+// moveEmail switches the address of the change's user from one address to
+// another and records the new status of the change.
+func (r *emailChangeRepository) moveEmail(ctx context.Context, change *models.EmailChange, lock, from, to, status string) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var id int64
+	err = tx.QueryRowContext(ctx, `SELECT id FROM email_changes WHERE id = $1 AND `+lock+` FOR UPDATE`, change.ID).Scan(&id)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrEmailChangeNotFound
+		}
+		return err
+	}
+
+	res, err := tx.ExecContext(ctx, `UPDATE users SET email = $3, email_verified_at = NOW(), updated_at = NOW()
+		WHERE id = $1 AND email = $2 AND deleted_at IS NULL`, change.UserID, from, to)
+	if err != nil {
+		return mapUniqueViolation(err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return ErrEmailChangeNotFound
+	}
+
+	var confirmedAt sql.NullTime
+	err = tx.QueryRowContext(ctx, `UPDATE email_changes SET status = $2,
+		confirmed_at = CASE WHEN $2 = 'confirmed' THEN NOW() ELSE confirmed_at END, updated_at = NOW()
+		WHERE id = $1 RETURNING confirmed_at, updated_at`, change.ID, status).Scan(&confirmedAt, &change.UpdatedAt)
+	if err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	change.Status = status
+	if confirmedAt.Valid {
+		change.ConfirmedAt = &confirmedAt.Time
+	}
+	return nil
+}
+
+func (r *emailChangeRepository) Confirm(ctx context.Context, change *models.EmailChange) error {
+	return r.moveEmail(ctx, change, `status = 'pending' AND expires_at > NOW()`,
+		change.OldEmail, change.NewEmail, models.EmailChangeConfirmed)
+}
+
+func (r *emailChangeRepository) Cancel(ctx context.Context, id int64) error {
+	res, err := r.db.ExecContext(ctx, `UPDATE email_changes SET status = 'cancelled', updated_at = NOW()
+		WHERE id = $1 AND status = 'pending'`, id)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return ErrEmailChangeNotFound
+	}
+	return nil
+}
+
+func (r *emailChangeRepository) Revert(ctx context.Context, change *models.EmailChange) error {
+	return r.moveEmail(ctx, change, `status = 'confirmed' AND revertible_until > NOW()`,
+		change.NewEmail, change.OldEmail, models.EmailChangeReverted)
+}
+
+func NewEmailChangeRepository(db *sql.DB) EmailChangeRepository {
+	return &emailChangeRepository{db: db}
+}
@@ -3,8 +3,43 @@ package repository
 import "errors"
 
 var (
-	ErrEmailAlreadyExists = errors.New("email already exists")
-	ErrUserNotFound       = errors.New("user not found")
-	ErrInvalidCredentials = errors.New("invalid credentials")
-	ErrInvalidPassword	= errors.New("invalid password")
+	ErrEmailAlreadyExists      = errors.New("email already exists")
+	ErrUsernameAlreadyExists   = errors.New("username already exists")
+	ErrExternalIDAlreadyExists = errors.New("external id already exists")
+	ErrUserNotFound            = errors.New("user not found")
+	ErrUserModified            = errors.New("user was modified since it was read")
+	ErrUserOwnsServiceAccounts = errors.New("user owns service accounts, transfer or delete them first")
+	ErrUserStateChanged        = errors.New("account state was changed concurrently, reload and try again")
+	ErrInvalidCredentials      = errors.New("invalid credentials")
+	ErrInvalidPassword         = errors.New("invalid password")
+	ErrTenantNotFound          = errors.New("tenant not found")
+	ErrGroupNotFound           = errors.New("group not found")
+	ErrGroupAlreadyExists      = errors.New("group already exists")
+	ErrInvalidGroupMember      = errors.New("group member does not exist")
+	ErrTokenNotFound           = errors.New("token not found")
+	ErrTokenNameAlreadyExists  = errors.New("token name already exists")
+	ErrServiceAccountNotFound  = errors.New("service account not found")
+	ErrServiceAccountExists    = errors.New("service account already exists")
+	ErrKeyNotFound             = errors.New("key not found")
+	ErrKeyNameAlreadyExists    = errors.New("key name already exists")
+	ErrRoleNotFound            = errors.New("role not found")
+	ErrOrganizationNotFound    = errors.New("organization not found")
+	ErrOrganizationSlugExists  = errors.New("organization slug already exists")
+	ErrMemberNotFound          = errors.New("organization member not found")
+	ErrMemberAlreadyExists     = errors.New("user is already a member of the organization")
+	ErrLastOrganizationOwner   = errors.New("an organization must keep at least one owner")
+	ErrParentGroupNotFound     = errors.New("parent group not found")
+	ErrGroupCycle              = errors.New("a group cannot be moved below itself or one of its subgroups")
+	ErrGroupHasSubgroups       = errors.New("group has subgroups")
+	ErrInvitationNotFound      = errors.New("invitation not found")
+	ErrInvitationExists        = errors.New("an invitation for this email is already pending")
+	ErrDomainNotFound          = errors.New("domain not found")
+	ErrDomainAlreadyClaimed    = errors.New("domain is already claimed by this organization")
+	ErrDomainVerifiedElsewhere = errors.New("domain is already verified by another organization")
+	ErrEmailChangeNotFound     = errors.New("email change not found")
+	ErrNamespaceNotFound       = errors.New("metadata namespace not found")
+	ErrSearchTimeout           = errors.New("search took too long, try a more specific query")
+	ErrExportNotFound          = errors.New("export not found")
+	ErrExportInProgress        = errors.New("an export is already being prepared")
+	ErrDocumentVersionExists   = errors.New("this version of the document already exists")
 )
@@ -0,0 +1,300 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/Atmosfr/user-service/internal/models"
+)
+
+// GroupRepository stores organization groups with a closure table, so ancestor
+// and descendant lookups are single indexed joins at any depth. Every query is
+// scoped by organization.
+type GroupRepository interface {
+	Create(ctx context.Context, group *models.Group) error
+	List(ctx context.Context, orgID int64) ([]*models.Group, error)
+	Find(ctx context.Context, orgID, id int64) (*models.Group, error)
+	// Update saves name and description.
+	Update(ctx context.Context, group *models.Group) error
+	// Move re-parents a group with its subtree, nil makes it a root group.
+	Move(ctx context.Context, orgID, id int64, parentID *int64) error
+	Delete(ctx context.Context, orgID, id int64) error
+
+	// ListMembers returns direct members, or with effective set also the members of all subgroups.
+	ListMembers(ctx context.Context, orgID, groupID int64, effective bool) ([]*models.GroupMember, error)
+	// AddMember succeeds when the user already is a direct member.
+	AddMember(ctx context.Context, orgID, groupID, userID int64) error
+	RemoveMember(ctx context.Context, orgID, groupID, userID int64) error
+	// EffectiveGroups returns the groups userID belongs to directly or through a subgroup.
+	EffectiveGroups(ctx context.Context, orgID, userID int64) ([]*models.Group, error)
+}
+
+type groupRepository struct {
+	tenants tenantScope
+}
+
+const groupColumns = `g.id, g.organization_id, g.parent_id, g.name, g.description, g.created_at, g.updated_at`
+
+func scanGroup(row rowScanner) (*models.Group, error) {
+	g := &models.Group{}
+	var parentID sql.NullInt64
+	if err := row.Scan(&g.ID, &g.OrganizationID, &parentID, &g.Name, &g.Description, &g.CreatedAt, &g.UpdatedAt); err != nil {
+		return nil, err
+	}
+	if parentID.Valid {
+		g.ParentID = &parentID.Int64
+	}
+	return g, nil
+}
+
+func scanGroups(rows *sql.Rows) ([]*models.Group, error) {
+	defer rows.Close()
+	groups := []*models.Group{}
+	for rows.Next() {
+		g, err := scanGroup(rows)
+		if err != nil {
+			return nil, err
+		}
+		groups = append(groups, g)
+	}
+	return groups, rows.Err()
+}
+
+func (r *groupRepository) Create(ctx context.Context, group *models.Group) error {
+	return r.tenants.inTx(ctx, group.OrganizationID, func(tx *sql.Tx) error {
+		if err := lockOrganization(ctx, tx, group.OrganizationID); err != nil {
+			return err
+		}
+
+		query := `INSERT INTO org_groups (organization_id, parent_id, name, description) VALUES ($1, $2, $3, $4)
+			  RETURNING id, created_at, updated_at`
+		err := tx.QueryRowContext(ctx, query, group.OrganizationID, group.ParentID, group.Name, group.Description).
+			Scan(&group.ID, &group.CreatedAt, &group.UpdatedAt)
+		if err != nil {
+			if isForeignKeyViolation(err, "org_groups_parent_fkey") {
+				return ErrParentGroupNotFound
+			}
+			return mapUniqueViolation(err)
+		}
+
+		// the group itself plus one row per ancestor of its parent
+		_, err = tx.ExecContext(ctx, `INSERT INTO org_group_closure (ancestor_id, descendant_id, depth)
+			SELECT $1, $1, 0
+			UNION ALL
+			SELECT ancestor_id, $1, depth + 1 FROM org_group_closure WHERE descendant_id = $2`, group.ID, group.ParentID)
+		return err
+	})
+}
+
+func (r *groupRepository) List(ctx context.Context, orgID int64) ([]*models.Group, error) {
+	var groups []*models.Group
+	err := r.tenants.run(ctx, orgID, func(q dbtx) error {
+		rows, err := q.QueryContext(ctx, `SELECT `+groupColumns+` FROM org_groups g WHERE g.organization_id = $1 ORDER BY g.name`, orgID)
+		if err != nil {
+			return err
+		}
+		groups, err = scanGroups(rows)
+		return err
+	})
+	return groups, err
+}
+
+func (r *groupRepository) Find(ctx context.Context, orgID, id int64) (*models.Group, error) {
+	var g *models.Group
+	err := r.tenants.run(ctx, orgID, func(q dbtx) (err error) {
+		g, err = scanGroup(q.QueryRowContext(ctx, `SELECT `+groupColumns+` FROM org_groups g WHERE g.organization_id = $1 AND g.id = $2`, orgID, id))
+		return err
+	})
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrGroupNotFound
+		}
+		return nil, err
+	}
+	return g, nil
+}
+
+func (r *groupRepository) Update(ctx context.Context, group *models.Group) error {
+	query := `UPDATE org_groups SET name = $3, description = $4, updated_at = NOW()
+		  WHERE organization_id = $1 AND id = $2 RETURNING updated_at`
+	err := r.tenants.run(ctx, group.OrganizationID, func(q dbtx) error {
+		return q.QueryRowContext(ctx, query, group.OrganizationID, group.ID, group.Name, group.Description).Scan(&group.UpdatedAt)
+	})
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrGroupNotFound
+		}
+		return mapUniqueViolation(err)
+	}
+	return nil
+}
+
+func (r *groupRepository) Move(ctx context.Context, orgID, id int64, parentID *int64) error {
+	return r.tenants.inTx(ctx, orgID, func(tx *sql.Tx) error {
+		return moveGroup(ctx, tx, orgID, id, parentID)
+	})
+}
+
+func moveGroup(ctx context.Context, tx *sql.Tx, orgID, id int64, parentID *int64) error {
+	// hierarchy changes of one organization run one at a time so two moves
+	// cannot together create a cycle
+	if err := lockOrganization(ctx, tx, orgID); err != nil {
+		return err
+	}
+
+	res, err := tx.ExecContext(ctx, `UPDATE org_groups SET parent_id = $3, updated_at = NOW() WHERE organization_id = $1 AND id = $2`,
+		orgID, id, parentID)
+	if err != nil {
+		if isForeignKeyViolation(err, "org_groups_parent_fkey") {
+			return ErrParentGroupNotFound
+		}
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return ErrGroupNotFound
+	}
+
+	if parentID != nil {
+		var isDescendant bool
+		err := tx.QueryRowContext(ctx, `SELECT EXISTS (SELECT 1 FROM org_group_closure WHERE ancestor_id = $1 AND descendant_id = $2)`,
+			id, *parentID).Scan(&isDescendant)
+		if err != nil {
+			return err
+		}
+		if isDescendant {
+			return ErrGroupCycle
+		}
+	}
+
+	// detach the subtree from its old ancestors, then attach it below the new parent
+	_, err = tx.ExecContext(ctx, `DELETE FROM org_group_closure
+		WHERE descendant_id IN (SELECT descendant_id FROM org_group_closure WHERE ancestor_id = $1)
+		  AND ancestor_id NOT IN (SELECT descendant_id FROM org_group_closure WHERE ancestor_id = $1)`, id)
+	if err != nil {
+		return err
+	}
+	if parentID != nil {
+		_, err = tx.ExecContext(ctx, `INSERT INTO org_group_closure (ancestor_id, descendant_id, depth)
+			SELECT up.ancestor_id, down.descendant_id, up.depth + down.depth + 1
+			FROM org_group_closure up CROSS JOIN org_group_closure down
+			WHERE up.descendant_id = $1 AND down.ancestor_id = $2`, *parentID, id)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *groupRepository) Delete(ctx context.Context, orgID, id int64) error {
+	return r.tenants.run(ctx, orgID, func(q dbtx) error {
+		res, err := q.ExecContext(ctx, `DELETE FROM org_groups WHERE organization_id = $1 AND id = $2`, orgID, id)
+		if err != nil {
+			if isForeignKeyViolation(err, "org_groups_parent_fkey") {
+				return ErrGroupHasSubgroups
+			}
+			return err
+		}
+		if n, _ := res.RowsAffected(); n == 0 {
+			return ErrGroupNotFound
+		}
+		return nil
+	})
+}
+
+func (r *groupRepository) ListMembers(ctx context.Context, orgID, groupID int64, effective bool) ([]*models.GroupMember, error) {
+	if _, err := r.Find(ctx, orgID, groupID); err != nil {
+		return nil, err
+	}
+
+	query := `SELECT gm.group_id, gm.user_id, u.email, u.username, gm.created_at
+		  FROM org_group_members gm JOIN users u ON u.id = gm.user_id
+		  WHERE gm.organization_id = $1 AND gm.group_id = $2
+		  ORDER BY u.username`
+	if effective {
+		// a user in several subgroups is listed once, with the closest one
+		query = `SELECT DISTINCT ON (u.username, gm.user_id) gm.group_id, gm.user_id, u.email, u.username, gm.created_at
+			 FROM org_group_closure c
+			 JOIN org_group_members gm ON gm.group_id = c.descendant_id
+			 JOIN users u ON u.id = gm.user_id
+			 WHERE gm.organization_id = $1 AND c.ancestor_id = $2
+			 ORDER BY u.username, gm.user_id, c.depth`
+	}
+
+	members := []*models.GroupMember{}
+	err := r.tenants.run(ctx, orgID, func(q dbtx) error {
+		rows, err := q.QueryContext(ctx, query, orgID, groupID)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			m := &models.GroupMember{}
+			if err := rows.Scan(&m.GroupID, &m.UserID, &m.Email, &m.Username, &m.CreatedAt); err != nil {
+				return err
+			}
+			members = append(members, m)
+		}
+		return rows.Err()
+	})
+	if err != nil {
+		return nil, err
+	}
+	return members, nil
+}
+
+func (r *groupRepository) AddMember(ctx context.Context, orgID, groupID, userID int64) error {
+	err := r.tenants.run(ctx, orgID, func(q dbtx) error {
+		_, err := q.ExecContext(ctx, `INSERT INTO org_group_members (organization_id, group_id, user_id) VALUES ($1, $2, $3)
+			ON CONFLICT (group_id, user_id) DO NOTHING`, orgID, groupID, userID)
+		return err
+	})
+	if err != nil {
+		switch {
+		case isForeignKeyViolation(err, "org_group_members_group_fkey"):
+			return ErrGroupNotFound
+		case isForeignKeyViolation(err, "org_group_members_member_fkey"):
+			return ErrMemberNotFound
+		}
+		return err
+	}
+	return nil
+}
+
+func (r *groupRepository) RemoveMember(ctx context.Context, orgID, groupID, userID int64) error {
+	return r.tenants.run(ctx, orgID, func(q dbtx) error {
+		res, err := q.ExecContext(ctx, `DELETE FROM org_group_members WHERE organization_id = $1 AND group_id = $2 AND user_id = $3`,
+			orgID, groupID, userID)
+		if err != nil {
+			return err
+		}
+		if n, _ := res.RowsAffected(); n == 0 {
+			return ErrMemberNotFound
+		}
+		return nil
+	})
+}
+
+func (r *groupRepository) EffectiveGroups(ctx context.Context, orgID, userID int64) ([]*models.Group, error) {
+	query := `SELECT ` + groupColumns + ` FROM org_groups g
+		  WHERE g.organization_id = $1 AND g.id IN (
+		      SELECT c.ancestor_id FROM org_group_members gm
+		      JOIN org_group_closure c ON c.descendant_id = gm.group_id
+		      WHERE gm.organization_id = $1 AND gm.user_id = $2)
+		  ORDER BY g.name`
+	var groups []*models.Group
+	err := r.tenants.run(ctx, orgID, func(q dbtx) error {
+		rows, err := q.QueryContext(ctx, query, orgID, userID)
+		if err != nil {
+			return err
+		}
+		groups, err = scanGroups(rows)
+		return err
+	})
+	return groups, err
+}
+
+func NewGroupRepository(db *sql.DB, opts ...TenantOption) GroupRepository {
+	return &groupRepository{tenants: newTenantScope(db, opts)}
+}
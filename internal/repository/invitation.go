@@ -0,0 +1,195 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/Atmosfr/user-service/internal/models"
+)
+
+type InvitationRepository interface {
+	// Create replaces a lapsed pending invitation for the same address, a live one
+	// gives ErrInvitationExists.
+	Create(ctx context.Context, inv *models.Invitation) error
+	// Find looks invitations up by the ID in their links, so it runs outside any
+	// tenant scope. Callers check the organization.
+	Find(ctx context.Context, id int64) (*models.Invitation, error)
+	// ListPending includes lapsed invitations, reported as expired, so they can be resent.
+	ListPending(ctx context.Context, orgID int64) ([]*models.Invitation, error)
+	Revoke(ctx context.Context, orgID, id int64) error
+	// Renew moves the expiry of a pending invitation, which invalidates links signed for the old one.
+	Renew(ctx context.Context, orgID, id int64, expiresAt time.Time) error
+
+	// Accept adds userID to the organization and closes the invitation. It fails
+	// with ErrInvitationNotFound unless inv is still pending with the same expiry.
+	Accept(ctx context.Context, inv *models.Invitation, userID int64) error
+	// AcceptNewUser creates user and accepts inv for them in one transaction.
+	AcceptNewUser(ctx context.Context, inv *models.Invitation, user *models.User) error
+}
+
+type invitationRepository struct {
+	tenants tenantScope
+}
+
+const invitationSelect = `SELECT i.id, i.organization_id, o.name, i.email, i.role, i.invited_by,
+		CASE WHEN i.status = 'pending' AND i.expires_at <= NOW() THEN 'expired' ELSE i.status END,
+		i.expires_at, i.accepted_by, i.accepted_at, i.created_at, i.updated_at
+	FROM org_invitations i JOIN organizations o ON o.id = i.organization_id`
+
+func scanInvitation(row rowScanner) (*models.Invitation, error) {
+	inv := &models.Invitation{}
+	var invitedBy, acceptedBy sql.NullInt64
+	var acceptedAt sql.NullTime
+	err := row.Scan(&inv.ID, &inv.OrganizationID, &inv.OrganizationName, &inv.Email, &inv.Role, &invitedBy,
+		&inv.Status, &inv.ExpiresAt, &acceptedBy, &acceptedAt, &inv.CreatedAt, &inv.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	if invitedBy.Valid {
+		inv.InvitedBy = &invitedBy.Int64
+	}
+	if acceptedBy.Valid {
+		inv.AcceptedBy = &acceptedBy.Int64
+	}
+	if acceptedAt.Valid {
+		inv.AcceptedAt = &acceptedAt.Time
+	}
+	return inv, nil
+}
+
+func (r *invitationRepository) Create(ctx context.Context, inv *models.Invitation) error {
+	return r.tenants.inTx(ctx, inv.OrganizationID, func(tx *sql.Tx) error {
+		_, err := tx.ExecContext(ctx, `UPDATE org_invitations SET status = 'expired', updated_at = NOW()
+			WHERE organization_id = $1 AND lower(email) = lower($2) AND status = 'pending' AND expires_at <= NOW()`,
+			inv.OrganizationID, inv.Email)
+		if err != nil {
+			return err
+		}
+
+		query := `INSERT INTO org_invitations (organization_id, email, role, invited_by, expires_at)
+			  VALUES ($1, $2, $3, $4, $5) RETURNING id, status, created_at, updated_at`
+		err = tx.QueryRowContext(ctx, query, inv.OrganizationID, inv.Email, inv.Role, inv.InvitedBy, inv.ExpiresAt).
+			Scan(&inv.ID, &inv.Status, &inv.CreatedAt, &inv.UpdatedAt)
+		if err != nil {
+			if isForeignKeyViolation(err, "org_invitations_organization_id_fkey") {
+				return ErrOrganizationNotFound
+			}
+			return mapUniqueViolation(err)
+		}
+		return tx.QueryRowContext(ctx, `SELECT name FROM organizations WHERE id = $1`, inv.OrganizationID).Scan(&inv.OrganizationName)
+	})
+}
+
+func (r *invitationRepository) Find(ctx context.Context, id int64) (*models.Invitation, error) {
+	inv, err := scanInvitation(r.tenants.db.QueryRowContext(ctx, invitationSelect+` WHERE i.id = $1`, id))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrInvitationNotFound
+		}
+		return nil, err
+	}
+	return inv, nil
+}
+
+func (r *invitationRepository) ListPending(ctx context.Context, orgID int64) ([]*models.Invitation, error) {
+	invitations := []*models.Invitation{}
+	err := r.tenants.run(ctx, orgID, func(q dbtx) error {
+		rows, err := q.QueryContext(ctx, invitationSelect+` WHERE i.organization_id = $1 AND i.status = 'pending' ORDER BY i.created_at DESC, i.id DESC`, orgID)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			inv, err := scanInvitation(rows)
+			if err != nil {
+				return err
+			}
+			invitations = append(invitations, inv)
+		}
+		return rows.Err()
+	})
+	if err != nil {
+		return nil, err
+	}
+	return invitations, nil
+}
+
+func (r *invitationRepository) Revoke(ctx context.Context, orgID, id int64) error {
+	return r.tenants.run(ctx, orgID, func(q dbtx) error {
+		res, err := q.ExecContext(ctx, `UPDATE org_invitations SET status = 'revoked', updated_at = NOW()
+			WHERE organization_id = $1 AND id = $2 AND status = 'pending'`, orgID, id)
+		if err != nil {
+			return err
+		}
+		if n, _ := res.RowsAffected(); n == 0 {
+			return ErrInvitationNotFound
+		}
+		return nil
+	})
+}
+
+func (r *invitationRepository) Renew(ctx context.Context, orgID, id int64, expiresAt time.Time) error {
+	return r.tenants.run(ctx, orgID, func(q dbtx) error {
+		res, err := q.ExecContext(ctx, `UPDATE org_invitations SET expires_at = $3, updated_at = NOW()
+			WHERE organization_id = $1 AND id = $2 AND status = 'pending'`, orgID, id, expiresAt)
+		if err != nil {
+			return err
+		}
+		if n, _ := res.RowsAffected(); n == 0 {
+			return ErrInvitationNotFound
+		}
+		return nil
+	})
+}
+
+// accept closes the invitation and adds the membership. A user who already is
+// a member keeps their current role.
+func accept(ctx context.Context, tx *sql.Tx, inv *models.Invitation, userID int64) error {
+	var id int64
+	err := tx.QueryRowContext(ctx, `SELECT id FROM org_invitations
+		WHERE id = $1 AND status = 'pending' AND expires_at = $2 AND expires_at > NOW() FOR UPDATE`,
+		inv.ID, inv.ExpiresAt).Scan(&id)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrInvitationNotFound
+		}
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx, `INSERT INTO organization_members (organization_id, user_id, role) VALUES ($1, $2, $3)
+		ON CONFLICT (organization_id, user_id) DO NOTHING`, inv.OrganizationID, userID, inv.Role)
+	if err != nil {
+		return err
+	}
+
+	err = tx.QueryRowContext(ctx, `UPDATE org_invitations SET status = 'accepted', accepted_by = $2, accepted_at = NOW(), updated_at = NOW()
+		WHERE id = $1 RETURNING accepted_at`, inv.ID, userID).Scan(&inv.AcceptedAt)
+	if err != nil {
+		return err
+	}
+	inv.Status = models.InvitationAccepted
+	inv.AcceptedBy = &userID
+	return nil
+}
+
+func (r *invitationRepository) Accept(ctx context.Context, inv *models.Invitation, userID int64) error {
+	return r.tenants.inTx(ctx, inv.OrganizationID, func(tx *sql.Tx) error {
+		return accept(ctx, tx, inv, userID)
+	})
+}
+
+func (r *invitationRepository) AcceptNewUser(ctx context.Context, inv *models.Invitation, user *models.User) error {
+	return r.tenants.inTx(ctx, inv.OrganizationID, func(tx *sql.Tx) error {
+		if err := insertUser(ctx, tx, user); err != nil {
+			return err
+		}
+		return accept(ctx, tx, inv, user.ID)
+	})
+}
+
+func NewInvitationRepository(db *sql.DB, opts ...TenantOption) InvitationRepository {
+	return &invitationRepository{tenants: newTenantScope(db, opts)}
+}
@@ -0,0 +1,192 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"strings"
+
+	"github.com/Atmosfr/user-service/internal/models"
+)
+
+// metadataColumns maps a visibility to its column; it is the only source of
+// column names interpolated into metadata queries.
+var metadataColumns = map[string]string{
+	models.MetadataPublic:  "public_metadata",
+	models.MetadataPrivate: "private_metadata",
+}
+
+var errUnknownVisibility = errors.New("unknown metadata visibility")
+
+// MetadataFilter matches users by a key of their metadata, see UserFilter.
+// Path starts with the namespace. A nil Value only requires the key to exist,
+// otherwise the key must contain Value.
+type MetadataFilter struct {
+	Visibility string
+	Path       []string
+	Value      json.RawMessage
+}
+
+type MetadataRepository interface {
+	UpsertNamespace(ctx context.Context, ns *models.MetadataNamespace) error
+	ListNamespaces(ctx context.Context) ([]*models.MetadataNamespace, error)
+	FindNamespace(ctx context.Context, name string) (*models.MetadataNamespace, error)
+	// DeleteNamespace removes the namespace and its values from all users.
+	DeleteNamespace(ctx context.Context, name string) error
+
+	// SetUserMetadata replaces the namespace's value of the user, a nil value
+	// removes it. It returns the updated user.
+	SetUserMetadata(ctx context.Context, userID int64, visibility, namespace string, value json.RawMessage) (*models.User, error)
+}
+
+type metadataRepository struct {
+	db *sql.DB
+}
+
+const namespaceColumns = `name, visibility, schema, in_token_claims, created_at, updated_at`
+
+func scanNamespace(row rowScanner) (*models.MetadataNamespace, error) {
+	ns := &models.MetadataNamespace{}
+	var schema []byte
+	if err := row.Scan(&ns.Name, &ns.Visibility, &schema, &ns.InTokenClaims, &ns.CreatedAt, &ns.UpdatedAt); err != nil {
+		return nil, err
+	}
+	ns.Schema = schema
+	return ns, nil
+}
+
+func (r *metadataRepository) UpsertNamespace(ctx context.Context, ns *models.MetadataNamespace) error {
+	query := `INSERT INTO metadata_namespaces (name, visibility, schema, in_token_claims) VALUES ($1, $2, $3, $4)
+		ON CONFLICT (name) DO UPDATE SET visibility = EXCLUDED.visibility, schema = EXCLUDED.schema,
+			in_token_claims = EXCLUDED.in_token_claims, updated_at = NOW()
+		RETURNING created_at, updated_at`
+	return r.db.QueryRowContext(ctx, query, ns.Name, ns.Visibility, string(ns.Schema), ns.InTokenClaims).
+		Scan(&ns.CreatedAt, &ns.UpdatedAt)
+}
+
+func (r *metadataRepository) ListNamespaces(ctx context.Context) ([]*models.MetadataNamespace, error) {
+	rows, err := r.db.QueryContext(ctx, `SELECT `+namespaceColumns+` FROM metadata_namespaces ORDER BY name`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	namespaces := []*models.MetadataNamespace{}
+	for rows.Next() {
+		ns, err := scanNamespace(rows)
+		if err != nil {
+			return nil, err
+		}
+		namespaces = append(namespaces, ns)
+	}
+	return namespaces, rows.Err()
+}
+
+func (r *metadataRepository) FindNamespace(ctx context.Context, name string) (*models.MetadataNamespace, error) {
+	ns, err := scanNamespace(r.db.QueryRowContext(ctx, `SELECT `+namespaceColumns+` FROM metadata_namespaces WHERE name = $1`, name))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNamespaceNotFound
+	}
+	return ns, err
+}
+
+func (r *metadataRepository) DeleteNamespace(ctx context.Context, name string) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var visibility string
+	err = tx.QueryRowContext(ctx, `DELETE FROM metadata_namespaces WHERE name = $1 RETURNING visibility`, name).Scan(&visibility)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrNamespaceNotFound
+	}
+	if err != nil {
+		return err
+	}
+
+	column, ok := metadataColumns[visibility]
+	if !ok {
+		return errUnknownVisibility
+	}
+	// updated_at is left alone, the users did not change anything themselves
+	_, err = tx.ExecContext(ctx, `UPDATE users SET `+column+` = `+column+` - $1::text WHERE `+column+` ? $1::text`, name)
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (r *metadataRepository) SetUserMetadata(ctx context.Context, userID int64, visibility, namespace string, value json.RawMessage) (*models.User, error) {
+	column, ok := metadataColumns[visibility]
+	if !ok {
+		return nil, errUnknownVisibility
+	}
+
+	var row *sql.Row
+	if value == nil {
+		row = r.db.QueryRowContext(ctx, `UPDATE users SET `+column+` = `+column+` - $2::text, updated_at = NOW()
+			WHERE id = $1 AND deleted_at IS NULL RETURNING `+userColumns, userID, namespace)
+	} else {
+		row = r.db.QueryRowContext(ctx, `UPDATE users SET `+column+` = `+column+` || jsonb_build_object($2::text, $3::jsonb), updated_at = NOW()
+			WHERE id = $1 AND deleted_at IS NULL RETURNING `+userColumns, userID, namespace, string(value))
+	}
+	user, err := scanUser(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrUserNotFound
+	}
+	return user, err
+}
+
+// metadataConditions turns filters into SQL conditions on the users table.
+// Both operators, @> and @?, are served by the GIN indexes on the metadata
+// columns.
+func metadataConditions(filters []MetadataFilter, args *sqlArgs) ([]string, error) {
+	var conditions []string
+	for _, f := range filters {
+		column, ok := metadataColumns[f.Visibility]
+		if !ok || len(f.Path) == 0 {
+			return nil, errUnknownVisibility
+		}
+		if f.Value == nil {
+			path, err := jsonPath(f.Path)
+			if err != nil {
+				return nil, err
+			}
+			conditions = append(conditions, column+" @? "+args.add(path)+"::jsonpath")
+			continue
+		}
+		// {"ns": {"key": value}} contained in the column matches the key's value
+		document := f.Value
+		for i := len(f.Path) - 1; i >= 0; i-- {
+			wrapped, err := json.Marshal(map[string]json.RawMessage{f.Path[i]: document})
+			if err != nil {
+				return nil, err
+			}
+			document = wrapped
+		}
+		conditions = append(conditions, column+" @> "+args.add(string(document))+"::jsonb")
+	}
+	return conditions, nil
+}
+
+// jsonPath builds a strict accessor such as $."ns"."key" with every key quoted.
+func jsonPath(keys []string) (string, error) {
+	var b strings.Builder
+	b.WriteString("$")
+	for _, key := range keys {
+		quoted, err := json.Marshal(key)
+		if err != nil {
+			return "", err
+		}
+		b.WriteString(".")
+		b.Write(quoted)
+	}
+	return b.String(), nil
+}
+
+func NewMetadataRepository(db *sql.DB) MetadataRepository {
+	return &metadataRepository{db: db}
+}
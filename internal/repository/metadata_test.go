@@ -0,0 +1,29 @@
+package repository
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestMetadataConditions(t *testing.T) {
+	filters := []MetadataFilter{
+		{Visibility: "public", Path: []string{"billing", "plan"}, Value: json.RawMessage(`"pro"`)},
+		{Visibility: "private", Path: []string{"crm", `odd "key"`}},
+	}
+
+	args := &sqlArgs{values: []any{"existing"}}
+	conditions, err := metadataConditions(filters, args)
+	require.NoError(t, err)
+	require.Equal(t, []string{
+		"public_metadata @> $2::jsonb",
+		"private_metadata @? $3::jsonpath",
+	}, conditions)
+	require.Equal(t, []any{"existing", `{"billing":{"plan":"pro"}}`, `$."crm"."odd \"key\""`}, args.values)
+
+	_, err = metadataConditions([]MetadataFilter{{Visibility: "users; DROP TABLE users", Path: []string{"x"}}}, &sqlArgs{})
+	require.ErrorIs(t, err, errUnknownVisibility)
+	_, err = metadataConditions([]MetadataFilter{{Visibility: "public"}}, &sqlArgs{})
+	require.ErrorIs(t, err, errUnknownVisibility)
+}
@@ -0,0 +1,245 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/Atmosfr/user-service/internal/models"
+)
+
+// OrganizationRepository scopes every query by organization. Lookups on behalf of
+// a user additionally require that user to be a member, so non-members cannot
+// tell an organization exists.
+type OrganizationRepository interface {
+	// Create inserts the organization and makes ownerID its first owner.
+	Create(ctx context.Context, org *models.Organization, ownerID int64) error
+	// ListForUser spans organizations, so it runs outside any tenant scope.
+	ListForUser(ctx context.Context, userID int64) ([]*models.Organization, error)
+	FindForMember(ctx context.Context, orgID, userID int64) (*models.Organization, error)
+
+	FindMembership(ctx context.Context, orgID, userID int64) (*models.OrganizationMember, error)
+	ListMembers(ctx context.Context, orgID int64) ([]*models.OrganizationMember, error)
+	AddMember(ctx context.Context, member *models.OrganizationMember) error
+	// UpdateMemberRole and RemoveMember refuse to leave an organization without an owner.
+	UpdateMemberRole(ctx context.Context, orgID, userID int64, role string) error
+	RemoveMember(ctx context.Context, orgID, userID int64) error
+}
+
+type organizationRepository struct {
+	tenants tenantScope
+}
+
+const organizationMemberSelect = `SELECT m.organization_id, m.user_id, u.email, u.username, m.role, m.created_at
+	FROM organization_members m JOIN users u ON u.id = m.user_id`
+
+func scanOrganization(row rowScanner) (*models.Organization, error) {
+	org := &models.Organization{}
+	var createdBy sql.NullInt64
+	if err := row.Scan(&org.ID, &org.Name, &org.Slug, &createdBy, &org.CreatedAt, &org.UpdatedAt, &org.Role); err != nil {
+		return nil, err
+	}
+	if createdBy.Valid {
+		org.CreatedBy = &createdBy.Int64
+	}
+	return org, nil
+}
+
+func scanOrganizationMember(row rowScanner) (*models.OrganizationMember, error) {
+	m := &models.OrganizationMember{}
+	err := row.Scan(&m.OrganizationID, &m.UserID, &m.Email, &m.Username, &m.Role, &m.CreatedAt)
+	return m, err
+}
+
+func (r *organizationRepository) Create(ctx context.Context, org *models.Organization, ownerID int64) error {
+	tx, err := r.tenants.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	query := `INSERT INTO organizations (name, slug, created_by) VALUES ($1, $2, $3) RETURNING id, created_at, updated_at`
+	if err := tx.QueryRowContext(ctx, query, org.Name, org.Slug, ownerID).Scan(&org.ID, &org.CreatedAt, &org.UpdatedAt); err != nil {
+		if isForeignKeyViolation(err, "organizations_created_by_fkey") {
+			return ErrUserNotFound
+		}
+		return mapUniqueViolation(err)
+	}
+	// the new organization is the tenant from here on
+	if err := r.tenants.enterTenant(ctx, tx, org.ID); err != nil {
+		return err
+	}
+	_, err = tx.ExecContext(ctx, `INSERT INTO organization_members (organization_id, user_id, role) VALUES ($1, $2, $3)`,
+		org.ID, ownerID, models.OrgRoleOwner)
+	if err != nil {
+		return err
+	}
+
+	org.CreatedBy = &ownerID
+	org.Role = models.OrgRoleOwner
+	return tx.Commit()
+}
+
+func (r *organizationRepository) ListForUser(ctx context.Context, userID int64) ([]*models.Organization, error) {
+	query := `SELECT o.id, o.name, o.slug, o.created_by, o.created_at, o.updated_at, m.role
+		  FROM organizations o JOIN organization_members m ON m.organization_id = o.id
+		  WHERE m.user_id = $1 ORDER BY o.name, o.id`
+	rows, err := r.tenants.db.QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	orgs := []*models.Organization{}
+	for rows.Next() {
+		org, err := scanOrganization(rows)
+		if err != nil {
+			return nil, err
+		}
+		orgs = append(orgs, org)
+	}
+	return orgs, rows.Err()
+}
+
+func (r *organizationRepository) FindForMember(ctx context.Context, orgID, userID int64) (*models.Organization, error) {
+	query := `SELECT o.id, o.name, o.slug, o.created_by, o.created_at, o.updated_at, m.role
+		  FROM organizations o JOIN organization_members m ON m.organization_id = o.id
+		  WHERE o.id = $1 AND m.user_id = $2`
+	var org *models.Organization
+	err := r.tenants.run(ctx, orgID, func(q dbtx) (err error) {
+		org, err = scanOrganization(q.QueryRowContext(ctx, query, orgID, userID))
+		return err
+	})
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrOrganizationNotFound
+		}
+		return nil, err
+	}
+	return org, nil
+}
+
+func (r *organizationRepository) FindMembership(ctx context.Context, orgID, userID int64) (*models.OrganizationMember, error) {
+	var m *models.OrganizationMember
+	err := r.tenants.run(ctx, orgID, func(q dbtx) (err error) {
+		m, err = scanOrganizationMember(q.QueryRowContext(ctx, organizationMemberSelect+` WHERE m.organization_id = $1 AND m.user_id = $2`, orgID, userID))
+		return err
+	})
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrMemberNotFound
+		}
+		return nil, err
+	}
+	return m, nil
+}
+
+func (r *organizationRepository) ListMembers(ctx context.Context, orgID int64) ([]*models.OrganizationMember, error) {
+	members := []*models.OrganizationMember{}
+	err := r.tenants.run(ctx, orgID, func(q dbtx) error {
+		rows, err := q.QueryContext(ctx, organizationMemberSelect+` WHERE m.organization_id = $1 ORDER BY u.username`, orgID)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			m, err := scanOrganizationMember(rows)
+			if err != nil {
+				return err
+			}
+			members = append(members, m)
+		}
+		return rows.Err()
+	})
+	if err != nil {
+		return nil, err
+	}
+	return members, nil
+}
+
+func (r *organizationRepository) AddMember(ctx context.Context, member *models.OrganizationMember) error {
+	query := `INSERT INTO organization_members (organization_id, user_id, role) VALUES ($1, $2, $3) RETURNING created_at`
+	err := r.tenants.run(ctx, member.OrganizationID, func(q dbtx) error {
+		return q.QueryRowContext(ctx, query, member.OrganizationID, member.UserID, member.Role).Scan(&member.CreatedAt)
+	})
+	if err != nil {
+		switch {
+		case isForeignKeyViolation(err, "organization_members_organization_id_fkey"):
+			return ErrOrganizationNotFound
+		case isForeignKeyViolation(err, "organization_members_user_id_fkey"):
+			return ErrUserNotFound
+		}
+		return mapUniqueViolation(err)
+	}
+	return nil
+}
+
+// lockOrganization serializes membership and group hierarchy changes of one
+// organization so the last-owner and cycle checks cannot race.
+func lockOrganization(ctx context.Context, tx *sql.Tx, orgID int64) error {
+	var id int64
+	err := tx.QueryRowContext(ctx, `SELECT id FROM organizations WHERE id = $1 FOR UPDATE`, orgID).Scan(&id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrOrganizationNotFound
+	}
+	return err
+}
+
+// ensureOtherOwner fails when userID is the organization's only owner.
+func ensureOtherOwner(ctx context.Context, tx *sql.Tx, orgID, userID int64) error {
+	var others int
+	query := `SELECT COUNT(*) FROM organization_members WHERE organization_id = $1 AND role = $2 AND user_id <> $3`
+	if err := tx.QueryRowContext(ctx, query, orgID, models.OrgRoleOwner, userID).Scan(&others); err != nil {
+		return err
+	}
+	if others == 0 {
+		return ErrLastOrganizationOwner
+	}
+	return nil
+}
+
+func (r *organizationRepository) changeMember(ctx context.Context, orgID, userID int64, apply func(tx *sql.Tx, current string) error) error {
+	return r.tenants.inTx(ctx, orgID, func(tx *sql.Tx) error {
+		if err := lockOrganization(ctx, tx, orgID); err != nil {
+			return err
+		}
+		var current string
+		err := tx.QueryRowContext(ctx, `SELECT role FROM organization_members WHERE organization_id = $1 AND user_id = $2`, orgID, userID).Scan(&current)
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return ErrMemberNotFound
+			}
+			return err
+		}
+		return apply(tx, current)
+	})
+}
+
+func (r *organizationRepository) UpdateMemberRole(ctx context.Context, orgID, userID int64, role string) error {
+	return r.changeMember(ctx, orgID, userID, func(tx *sql.Tx, current string) error {
+		if current == models.OrgRoleOwner && role != models.OrgRoleOwner {
+			if err := ensureOtherOwner(ctx, tx, orgID, userID); err != nil {
+				return err
+			}
+		}
+		_, err := tx.ExecContext(ctx, `UPDATE organization_members SET role = $3 WHERE organization_id = $1 AND user_id = $2`, orgID, userID, role)
+		return err
+	})
+}
+
+func (r *organizationRepository) RemoveMember(ctx context.Context, orgID, userID int64) error {
+	return r.changeMember(ctx, orgID, userID, func(tx *sql.Tx, current string) error {
+		if current == models.OrgRoleOwner {
+			if err := ensureOtherOwner(ctx, tx, orgID, userID); err != nil {
+				return err
+			}
+		}
+		_, err := tx.ExecContext(ctx, `DELETE FROM organization_members WHERE organization_id = $1 AND user_id = $2`, orgID, userID)
+		return err
+	})
+}
+
+func NewOrganizationRepository(db *sql.DB, opts ...TenantOption) OrganizationRepository {
+	return &organizationRepository{tenants: newTenantScope(db, opts)}
+}
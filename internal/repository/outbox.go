@@ -0,0 +1,87 @@
+package repository
+
+import (
+	"cmp"
+	"context"
+	"database/sql"
+	"slices"
+	"time"
+
+	"github.com/Atmosfr/user-service/internal/models"
+)
+
+type OutboxRepository interface {
+	// Claim leases up to limit due events until leaseUntil and counts the
+	// attempt. Only the oldest unpublished event of each user is due, so a
+	// user's events are published in order. Events are returned oldest first.
+	Claim(ctx context.Context, now, leaseUntil time.Time, limit int) ([]*models.OutboxEvent, error)
+	MarkPublished(ctx context.Context, id int64) error
+	// Retry gives up the lease and schedules the next attempt at next.
+	Retry(ctx context.Context, id int64, next time.Time, lastError string) error
+	// DeletePublished removes the events published before before.
+	DeletePublished(ctx context.Context, before time.Time) (int64, error)
+}
+
+type outboxRepository struct {
+	db *sql.DB
+}
+
+func (r *outboxRepository) Claim(ctx context.Context, now, leaseUntil time.Time, limit int) ([]*models.OutboxEvent, error) {
+	// SKIP LOCKED lets several relays claim side by side
+	rows, err := r.db.QueryContext(ctx, `UPDATE outbox_events o SET attempts = o.attempts + 1, next_attempt_at = $2
+		FROM (
+			SELECT id FROM outbox_events e
+			WHERE published_at IS NULL AND next_attempt_at <= $1
+				AND NOT EXISTS (SELECT 1 FROM outbox_events p
+					WHERE p.aggregate_id = e.aggregate_id AND p.published_at IS NULL AND p.id < e.id)
+			ORDER BY id LIMIT $3
+			FOR UPDATE SKIP LOCKED
+		) due
+		WHERE o.id = due.id
+		RETURNING o.id, o.event_id, o.event_type, o.schema_version, o.aggregate_id, o.payload, o.attempts, o.created_at`,
+		now, leaseUntil, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	events := []*models.OutboxEvent{}
+	for rows.Next() {
+		event := &models.OutboxEvent{}
+		var payload []byte
+		if err := rows.Scan(&event.ID, &event.EventID, &event.Type, &event.SchemaVersion, &event.AggregateID, &payload,
+			&event.Attempts, &event.CreatedAt); err != nil {
+			return nil, err
+		}
+		event.Payload = payload
+		events = append(events, event)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	slices.SortFunc(events, func(a, b *models.OutboxEvent) int { return cmp.Compare(a.ID, b.ID) })
+	return events, nil
+}
+
+func (r *outboxRepository) MarkPublished(ctx context.Context, id int64) error {
+	_, err := r.db.ExecContext(ctx, `UPDATE outbox_events SET published_at = NOW(), last_error = '' WHERE id = $1`, id)
+	return err
+}
+
+func (r *outboxRepository) Retry(ctx context.Context, id int64, next time.Time, lastError string) error {
+	_, err := r.db.ExecContext(ctx, `UPDATE outbox_events SET next_attempt_at = $2, last_error = $3
+		WHERE id = $1 AND published_at IS NULL`, id, next, lastError)
+	return err
+}
+
+func (r *outboxRepository) DeletePublished(ctx context.Context, before time.Time) (int64, error) {
+	res, err := r.db.ExecContext(ctx, `DELETE FROM outbox_events WHERE published_at < $1`, before)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
+
+func NewOutboxRepository(db *sql.DB) OutboxRepository {
+	return &outboxRepository{db: db}
+}
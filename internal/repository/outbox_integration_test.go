@@ -0,0 +1,86 @@
+//go:build integration
+
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/Atmosfr/user-service/internal/models"
+	"github.com/stretchr/testify/require"
+)
+
+func outboxEventTypes(t *testing.T, ctx context.Context, db *sql.DB, userID int64) []string {
+	t.Helper()
+	rows, err := db.QueryContext(ctx, `SELECT event_type FROM outbox_events WHERE aggregate_id = $1 ORDER BY id`, userID)
+	require.NoError(t, err)
+	defer rows.Close()
+	types := []string{}
+	for rows.Next() {
+		var eventType string
+		require.NoError(t, rows.Scan(&eventType))
+		types = append(types, eventType)
+	}
+	require.NoError(t, rows.Err())
+	return types
+}
+
+func claimFor(t *testing.T, outbox OutboxRepository, userID int64) []*models.OutboxEvent {
+	t.Helper()
+	now := time.Now()
+	claimed, err := outbox.Claim(context.Background(), now, now.Add(time.Minute), 1000)
+	require.NoError(t, err)
+	mine := []*models.OutboxEvent{}
+	for _, event := range claimed {
+		if event.AggregateID == userID {
+			mine = append(mine, event)
+		}
+	}
+	return mine
+}
+
+func TestOutboxRepository(t *testing.T) {
+	db := openTestDB(t)
+	ctx := context.Background()
+	users := NewUserRepository(db)
+	outbox := NewOutboxRepository(db)
+	suffix := time.Now().UnixNano()
+
+	user := &models.User{Email: fmt.Sprintf("outbox%d@example.com", suffix), Username: fmt.Sprintf("outbox%d", suffix), PasswordHash: "x"}
+	require.NoError(t, users.Create(ctx, user))
+	require.NoError(t, users.UpdateRole(ctx, user.ID, "admin"))
+	// the password is not part of the payload
+	_, err := db.ExecContext(ctx, `UPDATE users SET password_hash = 'y' WHERE id = $1`, user.ID)
+	require.NoError(t, err)
+	// deleting is a change of state, the account is only gone once purged
+	require.NoError(t, users.Delete(ctx, user.ID))
+	_, err = NewAccountDeletionRepository(db).Purge(ctx, time.Now().Add(time.Minute), 1000)
+	require.NoError(t, err)
+	require.Equal(t, []string{"user.registered", "user.updated", "user.updated", "user.deleted"}, outboxEventTypes(t, ctx, db, user.ID))
+
+	// only the oldest unpublished event of a user is handed out
+	claimed := claimFor(t, outbox, user.ID)
+	require.Len(t, claimed, 1)
+	require.Equal(t, "user.registered", claimed[0].Type)
+	require.Equal(t, 1, claimed[0].SchemaVersion)
+	require.Equal(t, 1, claimed[0].Attempts)
+	require.Contains(t, string(claimed[0].Payload), user.Email)
+	// leased events are not claimed twice
+	require.Empty(t, claimFor(t, outbox, user.ID))
+
+	require.NoError(t, outbox.Retry(ctx, claimed[0].ID, time.Now().Add(-time.Second), "sink unavailable"))
+	claimed = claimFor(t, outbox, user.ID)
+	require.Len(t, claimed, 1)
+	require.Equal(t, 2, claimed[0].Attempts)
+
+	require.NoError(t, outbox.MarkPublished(ctx, claimed[0].ID))
+	claimed = claimFor(t, outbox, user.ID)
+	require.Len(t, claimed, 1)
+	require.Equal(t, "user.updated", claimed[0].Type)
+
+	_, err = db.ExecContext(ctx, `DELETE FROM outbox_events WHERE aggregate_id = $1`, user.ID)
+	require.NoError(t, err)
+}
@@ -0,0 +1,80 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+
+	"github.com/Atmosfr/user-service/internal/models"
+)
+
+// maxRoleDepth bounds the walk up the role hierarchy in case a cycle slips in.
+const maxRoleDepth = 16
+
+type RoleRepository interface {
+	// PermissionsForRole returns the permissions of role and all of its ancestors.
+	PermissionsForRole(ctx context.Context, role string) ([]string, error)
+	// ListRoles returns every role with its directly assigned permissions.
+	ListRoles(ctx context.Context) ([]*models.Role, error)
+}
+
+type roleRepository struct {
+	db *sql.DB
+}
+
+func (r *roleRepository) PermissionsForRole(ctx context.Context, role string) ([]string, error) {
+	query := `WITH RECURSIVE chain AS (
+			SELECT id, parent_id, 1 AS depth FROM roles WHERE name = $1
+			UNION ALL
+			SELECT r.id, r.parent_id, c.depth + 1 FROM roles r JOIN chain c ON r.id = c.parent_id WHERE c.depth < $2
+		)
+		SELECT DISTINCT p.name FROM chain c
+		JOIN role_permissions rp ON rp.role_id = c.id
+		JOIN permissions p ON p.id = rp.permission_id
+		ORDER BY p.name`
+	rows, err := r.db.QueryContext(ctx, query, role, maxRoleDepth)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	permissions := []string{}
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, err
+		}
+		permissions = append(permissions, name)
+	}
+	return permissions, rows.Err()
+}
+
+func (r *roleRepository) ListRoles(ctx context.Context) ([]*models.Role, error) {
+	query := `SELECT r.id, r.name, r.description, COALESCE(parent.name, ''),
+			COALESCE(array_to_string(ARRAY(
+				SELECT p.name FROM role_permissions rp JOIN permissions p ON p.id = rp.permission_id
+				WHERE rp.role_id = r.id ORDER BY p.name), ' '), '')
+		FROM roles r LEFT JOIN roles parent ON parent.id = r.parent_id
+		ORDER BY r.name`
+	rows, err := r.db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	roles := []*models.Role{}
+	for rows.Next() {
+		role := &models.Role{}
+		var permissions string
+		if err := rows.Scan(&role.ID, &role.Name, &role.Description, &role.Parent, &permissions); err != nil {
+			return nil, err
+		}
+		role.Permissions = strings.Fields(permissions)
+		roles = append(roles, role)
+	}
+	return roles, rows.Err()
+}
+
+func NewRoleRepository(db *sql.DB) RoleRepository {
+	return &roleRepository{db: db}
+}
@@ -0,0 +1,354 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/Atmosfr/user-service/internal/models"
+	"github.com/Atmosfr/user-service/internal/scim"
+)
+
+type SCIMRepository interface {
+	CreateTenant(ctx context.Context, tenant *models.SCIMTenant, tokenHash string) error
+	FindTenantByTokenHash(ctx context.Context, tokenHash string) (*models.SCIMTenant, error)
+
+	ListUsers(ctx context.Context, tenantID int64, filter scim.Expr, offset, limit int) ([]*models.SCIMUser, int, error)
+	FindUser(ctx context.Context, tenantID, id int64) (*models.SCIMUser, error)
+	CreateUser(ctx context.Context, user *models.SCIMUser) error
+	UpdateUser(ctx context.Context, user *models.SCIMUser) error
+	DeleteUser(ctx context.Context, tenantID, id int64) error
+
+	ListGroups(ctx context.Context, tenantID int64, filter scim.Expr, offset, limit int) ([]*models.SCIMGroup, int, error)
+	FindGroup(ctx context.Context, tenantID, id int64) (*models.SCIMGroup, error)
+	CreateGroup(ctx context.Context, group *models.SCIMGroup) error
+	UpdateGroup(ctx context.Context, group *models.SCIMGroup) error
+	DeleteGroup(ctx context.Context, tenantID, id int64) error
+}
+
+type scimRepository struct {
+	db *sql.DB
+}
+
+const scimUserColumnList = `id, email, password_hash, username, created_at, updated_at, is_active, role, scim_tenant_id, COALESCE(external_id, '')`
+
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+func scanSCIMUser(row rowScanner) (*models.SCIMUser, error) {
+	user := &models.SCIMUser{}
+	err := row.Scan(&user.ID, &user.Email, &user.PasswordHash, &user.Username, &user.CreatedAt, &user.UpdatedAt, &user.IsActive, &user.Role, &user.TenantID, &user.ExternalID)
+	return user, err
+}
+
+func (r *scimRepository) CreateTenant(ctx context.Context, tenant *models.SCIMTenant, tokenHash string) error {
+	query := `INSERT INTO scim_tenants (name, token_hash) VALUES ($1, $2) RETURNING id, created_at`
+	return r.db.QueryRowContext(ctx, query, tenant.Name, tokenHash).Scan(&tenant.ID, &tenant.CreatedAt)
+}
+
+func (r *scimRepository) FindTenantByTokenHash(ctx context.Context, tokenHash string) (*models.SCIMTenant, error) {
+	query := `SELECT id, name, created_at FROM scim_tenants WHERE token_hash = $1`
+	tenant := &models.SCIMTenant{}
+	err := r.db.QueryRowContext(ctx, query, tokenHash).Scan(&tenant.ID, &tenant.Name, &tenant.CreatedAt)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrTenantNotFound
+		}
+		return nil, err
+	}
+	return tenant, nil
+}
+
+func (r *scimRepository) ListUsers(ctx context.Context, tenantID int64, filter scim.Expr, offset, limit int) ([]*models.SCIMUser, int, error) {
+	args := &sqlArgs{}
+	where := "scim_tenant_id = " + args.add(tenantID) + " AND deleted_at IS NULL"
+	if filter != nil {
+		clause, err := scimFilterSQL(filter, scimUserColumns, args)
+		if err != nil {
+			return nil, 0, err
+		}
+		where += " AND " + clause
+	}
+
+	var total int
+	if err := r.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM users WHERE `+where, args.values...).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+
+	query := `SELECT ` + scimUserColumnList + ` FROM users WHERE ` + where +
+		` ORDER BY id LIMIT ` + args.add(limit) + ` OFFSET ` + args.add(offset)
+	rows, err := r.db.QueryContext(ctx, query, args.values...)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	var users []*models.SCIMUser
+	for rows.Next() {
+		user, err := scanSCIMUser(rows)
+		if err != nil {
+			return nil, 0, err
+		}
+		users = append(users, user)
+	}
+	return users, total, rows.Err()
+}
+
+func (r *scimRepository) FindUser(ctx context.Context, tenantID, id int64) (*models.SCIMUser, error) {
+	query := `SELECT ` + scimUserColumnList + ` FROM users WHERE id = $1 AND scim_tenant_id = $2 AND deleted_at IS NULL`
+	user, err := scanSCIMUser(r.db.QueryRowContext(ctx, query, id, tenantID))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrUserNotFound
+		}
+		return nil, err
+	}
+	return user, nil
+}
+
+func (r *scimRepository) CreateUser(ctx context.Context, user *models.SCIMUser) error {
+	// users provisioned inactive are suspended for the identity provider to reactivate
+	query := `INSERT INTO users (email, password_hash, username, state, state_reason, scim_tenant_id, external_id)
+		  VALUES ($1, $2, $3, CASE WHEN $4 THEN 'active' ELSE 'suspended' END, CASE WHEN $4 THEN '' ELSE $7 END, $5, NULLIF($6, ''))
+		  RETURNING id, created_at, updated_at, role`
+	err := r.db.QueryRowContext(ctx, query, user.Email, user.PasswordHash, user.Username, user.IsActive, user.TenantID, user.ExternalID, models.StateReasonSCIM).
+		Scan(&user.ID, &user.CreatedAt, &user.UpdatedAt, &user.Role)
+	if err != nil {
+		return mapUniqueViolation(err)
+	}
+	return nil
+}
+
+// UpdateUser maps active onto the account state: deactivating suspends an
+// active user and activating only lifts suspensions made through SCIM, so the
+// identity provider cannot undo a ban.
+func (r *scimRepository) UpdateUser(ctx context.Context, user *models.SCIMUser) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	query := `UPDATE users u
+		  SET email = $1, username = $2, external_id = NULLIF($4, ''),
+		      password_hash = COALESCE(NULLIF($5, ''), u.password_hash), updated_at = NOW(),
+		      state = CASE
+		          WHEN $3 AND u.state = 'suspended' AND u.state_reason = $8 THEN 'active'
+		          WHEN NOT $3 AND u.state = 'active' THEN 'suspended'
+		          ELSE u.state END,
+		      state_reason = CASE
+		          WHEN $3 AND u.state = 'suspended' AND u.state_reason = $8 THEN ''
+		          WHEN NOT $3 AND u.state = 'active' THEN $8
+		          ELSE u.state_reason END
+		  FROM users old
+		  WHERE u.id = old.id AND u.id = $6 AND u.scim_tenant_id = $7 AND u.deleted_at IS NULL
+		  RETURNING u.updated_at, old.state, u.state`
+	var from, to string
+	err = tx.QueryRowContext(ctx, query, user.Email, user.Username, user.IsActive, user.ExternalID, user.PasswordHash, user.ID, user.TenantID, models.StateReasonSCIM).
+		Scan(&user.UpdatedAt, &from, &to)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrUserNotFound
+		}
+		return mapUniqueViolation(err)
+	}
+	user.IsActive = to == models.AccountActive
+
+	if from != to {
+		_, err := tx.ExecContext(ctx, `INSERT INTO user_state_history (user_id, from_state, to_state, reason) VALUES ($1, $2, $3, $4)`,
+			user.ID, from, to, models.StateReasonSCIM)
+		if err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+func (r *scimRepository) DeleteUser(ctx context.Context, tenantID, id int64) error {
+	return softDeleteUser(ctx, r.db, id, models.StateReasonSCIM, &tenantID)
+}
+
+func (r *scimRepository) ListGroups(ctx context.Context, tenantID int64, filter scim.Expr, offset, limit int) ([]*models.SCIMGroup, int, error) {
+	args := &sqlArgs{}
+	where := "tenant_id = " + args.add(tenantID)
+	if filter != nil {
+		clause, err := scimFilterSQL(filter, scimGroupColumns, args)
+		if err != nil {
+			return nil, 0, err
+		}
+		where += " AND " + clause
+	}
+
+	var total int
+	if err := r.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM scim_groups WHERE `+where, args.values...).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+
+	query := `SELECT id, tenant_id, display_name, COALESCE(external_id, ''), created_at, updated_at
+		  FROM scim_groups WHERE ` + where + ` ORDER BY id LIMIT ` + args.add(limit) + ` OFFSET ` + args.add(offset)
+	rows, err := r.db.QueryContext(ctx, query, args.values...)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	var groups []*models.SCIMGroup
+	byID := map[int64]*models.SCIMGroup{}
+	var ids []int64
+	for rows.Next() {
+		g := &models.SCIMGroup{}
+		if err := rows.Scan(&g.ID, &g.TenantID, &g.DisplayName, &g.ExternalID, &g.CreatedAt, &g.UpdatedAt); err != nil {
+			return nil, 0, err
+		}
+		groups = append(groups, g)
+		byID[g.ID] = g
+		ids = append(ids, g.ID)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, 0, err
+	}
+	if len(ids) == 0 {
+		return groups, total, nil
+	}
+
+	memberRows, err := r.db.QueryContext(ctx, `SELECT m.group_id, u.id, u.username
+		  FROM scim_group_members m JOIN users u ON u.id = m.user_id
+		  WHERE m.group_id = ANY($1) ORDER BY u.id`, ids)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer memberRows.Close()
+
+	for memberRows.Next() {
+		var groupID int64
+		var m models.SCIMGroupMember
+		if err := memberRows.Scan(&groupID, &m.UserID, &m.Username); err != nil {
+			return nil, 0, err
+		}
+		byID[groupID].Members = append(byID[groupID].Members, m)
+	}
+	return groups, total, memberRows.Err()
+}
+
+func (r *scimRepository) FindGroup(ctx context.Context, tenantID, id int64) (*models.SCIMGroup, error) {
+	query := `SELECT id, tenant_id, display_name, COALESCE(external_id, ''), created_at, updated_at
+		  FROM scim_groups WHERE id = $1 AND tenant_id = $2`
+	g := &models.SCIMGroup{}
+	err := r.db.QueryRowContext(ctx, query, id, tenantID).Scan(&g.ID, &g.TenantID, &g.DisplayName, &g.ExternalID, &g.CreatedAt, &g.UpdatedAt)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrGroupNotFound
+		}
+		return nil, err
+	}
+
+	rows, err := r.db.QueryContext(ctx, `SELECT u.id, u.username
+		  FROM scim_group_members m JOIN users u ON u.id = m.user_id
+		  WHERE m.group_id = $1 ORDER BY u.id`, id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var m models.SCIMGroupMember
+		if err := rows.Scan(&m.UserID, &m.Username); err != nil {
+			return nil, err
+		}
+		g.Members = append(g.Members, m)
+	}
+	return g, rows.Err()
+}
+
+func (r *scimRepository) CreateGroup(ctx context.Context, group *models.SCIMGroup) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	query := `INSERT INTO scim_groups (tenant_id, display_name, external_id)
+		  VALUES ($1, $2, NULLIF($3, '')) RETURNING id, created_at, updated_at`
+	err = tx.QueryRowContext(ctx, query, group.TenantID, group.DisplayName, group.ExternalID).
+		Scan(&group.ID, &group.CreatedAt, &group.UpdatedAt)
+	if err != nil {
+		return mapUniqueViolation(err)
+	}
+
+	if err := setGroupMembers(ctx, tx, group); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (r *scimRepository) UpdateGroup(ctx context.Context, group *models.SCIMGroup) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	query := `UPDATE scim_groups SET display_name = $1, external_id = NULLIF($2, ''), updated_at = NOW()
+		  WHERE id = $3 AND tenant_id = $4 RETURNING updated_at`
+	err = tx.QueryRowContext(ctx, query, group.DisplayName, group.ExternalID, group.ID, group.TenantID).Scan(&group.UpdatedAt)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrGroupNotFound
+		}
+		return mapUniqueViolation(err)
+	}
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM scim_group_members WHERE group_id = $1`, group.ID); err != nil {
+		return err
+	}
+	if err := setGroupMembers(ctx, tx, group); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// setGroupMembers inserts the group's members, rejecting users that belong to another tenant.
+func setGroupMembers(ctx context.Context, tx *sql.Tx, group *models.SCIMGroup) error {
+	if len(group.Members) == 0 {
+		return nil
+	}
+	ids := make([]int64, len(group.Members))
+	for i, m := range group.Members {
+		ids[i] = m.UserID
+	}
+
+	res, err := tx.ExecContext(ctx, `INSERT INTO scim_group_members (group_id, user_id)
+		  SELECT $1::integer, id FROM users WHERE id = ANY($2) AND scim_tenant_id = $3 AND deleted_at IS NULL
+		  ON CONFLICT DO NOTHING`, group.ID, ids, group.TenantID)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); int(n) != len(uniqueIDs(ids)) {
+		return ErrInvalidGroupMember
+	}
+	return nil
+}
+
+func uniqueIDs(ids []int64) map[int64]struct{} {
+	set := make(map[int64]struct{}, len(ids))
+	for _, id := range ids {
+		set[id] = struct{}{}
+	}
+	return set
+}
+
+func (r *scimRepository) DeleteGroup(ctx context.Context, tenantID, id int64) error {
+	res, err := r.db.ExecContext(ctx, `DELETE FROM scim_groups WHERE id = $1 AND tenant_id = $2`, id, tenantID)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return ErrGroupNotFound
+	}
+	return nil
+}
+
+func NewSCIMRepository(db *sql.DB) SCIMRepository {
+	return &scimRepository{db: db}
+}
@@ -0,0 +1,203 @@
+package repository
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Atmosfr/user-service/internal/scim"
+)
+
+type scimColumnKind int
+
+const (
+	scimString scimColumnKind = iota
+	scimBool
+	scimInt
+	scimTime
+	scimGroupMember
+)
+
+type scimColumn struct {
+	expr string
+	kind scimColumnKind
+}
+
+var scimUserColumns = map[string]scimColumn{
+	"id":                {expr: "id", kind: scimInt},
+	"username":          {expr: "username", kind: scimString},
+	"emails":            {expr: "email", kind: scimString},
+	"emails.value":      {expr: "email", kind: scimString},
+	"externalid":        {expr: "external_id", kind: scimString},
+	"active":            {expr: "is_active", kind: scimBool},
+	"meta.created":      {expr: "created_at", kind: scimTime},
+	"meta.lastmodified": {expr: "updated_at", kind: scimTime},
+}
+
+var scimGroupColumns = map[string]scimColumn{
+	"id":                {expr: "id", kind: scimInt},
+	"displayname":       {expr: "display_name", kind: scimString},
+	"externalid":        {expr: "external_id", kind: scimString},
+	"members":           {expr: "id", kind: scimGroupMember},
+	"members.value":     {expr: "id", kind: scimGroupMember},
+	"meta.created":      {expr: "created_at", kind: scimTime},
+	"meta.lastmodified": {expr: "updated_at", kind: scimTime},
+}
+
+type sqlArgs struct {
+	values []any
+}
+
+func (a *sqlArgs) add(v any) string {
+	a.values = append(a.values, v)
+	return "$" + strconv.Itoa(len(a.values))
+}
+
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
+}
+
+// scimFilterSQL translates a parsed SCIM filter into a WHERE clause fragment
+// over the given attribute-to-column mapping.
+func scimFilterSQL(e scim.Expr, columns map[string]scimColumn, args *sqlArgs) (string, error) {
+	switch e := e.(type) {
+	case *scim.LogicalExpr:
+		left, err := scimFilterSQL(e.Left, columns, args)
+		if err != nil {
+			return "", err
+		}
+		right, err := scimFilterSQL(e.Right, columns, args)
+		if err != nil {
+			return "", err
+		}
+		return "(" + left + " " + strings.ToUpper(e.Op) + " " + right + ")", nil
+	case *scim.NotExpr:
+		inner, err := scimFilterSQL(e.Expr, columns, args)
+		if err != nil {
+			return "", err
+		}
+		return "NOT (" + inner + ")", nil
+	case *scim.ValuePathExpr:
+		return scimFilterSQL(prefixPaths(e.Filter, e.Attr), columns, args)
+	case *scim.AttrExpr:
+		col, ok := columns[e.Path]
+		if !ok {
+			return "", fmt.Errorf("%w: unsupported attribute %q", scim.ErrInvalidFilter, e.Path)
+		}
+		return scimCompareSQL(col, e, args)
+	}
+	return "", scim.ErrInvalidFilter
+}
+
+func prefixPaths(e scim.Expr, attr string) scim.Expr {
+	switch e := e.(type) {
+	case *scim.LogicalExpr:
+		return &scim.LogicalExpr{Op: e.Op, Left: prefixPaths(e.Left, attr), Right: prefixPaths(e.Right, attr)}
+	case *scim.NotExpr:
+		return &scim.NotExpr{Expr: prefixPaths(e.Expr, attr)}
+	case *scim.AttrExpr:
+		return &scim.AttrExpr{Path: attr + "." + e.Path, Op: e.Op, Value: e.Value}
+	}
+	return e
+}
+
+func scimCompareSQL(col scimColumn, e *scim.AttrExpr, args *sqlArgs) (string, error) {
+	if e.Op == "pr" {
+		if col.kind == scimString {
+			return "(" + col.expr + " IS NOT NULL AND " + col.expr + " <> '')", nil
+		}
+		return col.expr + " IS NOT NULL", nil
+	}
+
+	if e.Value == nil {
+		switch e.Op {
+		case "eq":
+			return col.expr + " IS NULL", nil
+		case "ne":
+			return col.expr + " IS NOT NULL", nil
+		}
+		return "", fmt.Errorf("%w: null only supports eq and ne", scim.ErrInvalidFilter)
+	}
+
+	switch col.kind {
+	case scimString:
+		s, ok := e.Value.(string)
+		if !ok {
+			return "", fmt.Errorf("%w: %s expects a string", scim.ErrInvalidFilter, e.Path)
+		}
+		switch e.Op {
+		case "eq":
+			return "LOWER(" + col.expr + ") = LOWER(" + args.add(s) + ")", nil
+		case "ne":
+			return "LOWER(" + col.expr + ") <> LOWER(" + args.add(s) + ")", nil
+		case "co":
+			return col.expr + " ILIKE " + args.add("%"+escapeLike(s)+"%"), nil
+		case "sw":
+			return col.expr + " ILIKE " + args.add(escapeLike(s)+"%"), nil
+		case "ew":
+			return col.expr + " ILIKE " + args.add("%"+escapeLike(s)), nil
+		}
+		return "LOWER(" + col.expr + ") " + sqlOperator(e.Op) + " LOWER(" + args.add(s) + ")", nil
+	case scimBool:
+		b, ok := e.Value.(bool)
+		if !ok || (e.Op != "eq" && e.Op != "ne") {
+			return "", fmt.Errorf("%w: %s only supports eq and ne with a boolean", scim.ErrInvalidFilter, e.Path)
+		}
+		return col.expr + " " + sqlOperator(e.Op) + " " + args.add(b), nil
+	case scimInt, scimGroupMember:
+		id, ok := scimIDValue(e.Value)
+		if !ok {
+			// ids are opaque strings to SCIM clients, a non-numeric id never matches
+			return "FALSE", nil
+		}
+		if col.kind == scimGroupMember {
+			if e.Op != "eq" {
+				return "", fmt.Errorf("%w: %s only supports eq", scim.ErrInvalidFilter, e.Path)
+			}
+			return col.expr + " IN (SELECT group_id FROM scim_group_members WHERE user_id = " + args.add(id) + ")", nil
+		}
+		if op := sqlOperator(e.Op); op != "" {
+			return col.expr + " " + op + " " + args.add(id), nil
+		}
+	case scimTime:
+		s, _ := e.Value.(string)
+		t, err := time.Parse(time.RFC3339Nano, s)
+		if err != nil {
+			return "", fmt.Errorf("%w: %s expects an RFC 3339 timestamp", scim.ErrInvalidFilter, e.Path)
+		}
+		if op := sqlOperator(e.Op); op != "" {
+			return col.expr + " " + op + " " + args.add(t), nil
+		}
+	}
+	return "", fmt.Errorf("%w: operator %s not supported for %s", scim.ErrInvalidFilter, e.Op, e.Path)
+}
+
+func scimIDValue(v any) (int64, bool) {
+	switch v := v.(type) {
+	case string:
+		id, err := strconv.ParseInt(v, 10, 64)
+		return id, err == nil
+	case float64:
+		return int64(v), v == float64(int64(v))
+	}
+	return 0, false
+}
+
+func sqlOperator(op string) string {
+	switch op {
+	case "eq":
+		return "="
+	case "ne":
+		return "<>"
+	case "gt":
+		return ">"
+	case "ge":
+		return ">="
+	case "lt":
+		return "<"
+	case "le":
+		return "<="
+	}
+	return ""
+}
@@ -0,0 +1,91 @@
+package repository
+
+import (
+	"testing"
+
+	"github.com/Atmosfr/user-service/internal/scim"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSCIMFilterSQL(t *testing.T) {
+	tests := []struct {
+		name     string
+		filter   string
+		columns  map[string]scimColumn
+		wantSQL  string
+		wantArgs []any
+		wantErr  bool
+	}{
+		{
+			name:     "userName eq is case-insensitive",
+			filter:   `userName eq "BJensen"`,
+			columns:  scimUserColumns,
+			wantSQL:  "LOWER(username) = LOWER($1)",
+			wantArgs: []any{"BJensen"},
+		},
+		{
+			name:     "emails.value co escapes like wildcards",
+			filter:   `emails.value co "50%_off"`,
+			columns:  scimUserColumns,
+			wantSQL:  "email ILIKE $1",
+			wantArgs: []any{`%50\%\_off%`},
+		},
+		{
+			name:     "logical operators and booleans",
+			filter:   `active eq false or not (externalId pr)`,
+			columns:  scimUserColumns,
+			wantSQL:  "(is_active = $1 OR NOT ((external_id IS NOT NULL AND external_id <> '')))",
+			wantArgs: []any{false},
+		},
+		{
+			name:     "value path over emails",
+			filter:   `emails[value sw "admin"]`,
+			columns:  scimUserColumns,
+			wantSQL:  "email ILIKE $1",
+			wantArgs: []any{"admin%"},
+		},
+		{
+			name:     "non numeric id never matches",
+			filter:   `id eq "abc"`,
+			columns:  scimUserColumns,
+			wantSQL:  "FALSE",
+			wantArgs: nil,
+		},
+		{
+			name:     "group membership",
+			filter:   `members.value eq "12"`,
+			columns:  scimGroupColumns,
+			wantSQL:  "id IN (SELECT group_id FROM scim_group_members WHERE user_id = $1)",
+			wantArgs: []any{int64(12)},
+		},
+		{
+			name:    "unknown attribute",
+			filter:  `password eq "x"`,
+			columns: scimUserColumns,
+			wantErr: true,
+		},
+		{
+			name:    "boolean with co",
+			filter:  `active co "t"`,
+			columns: scimUserColumns,
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			expr, err := scim.ParseFilter(tt.filter)
+			require.NoError(t, err)
+
+			args := &sqlArgs{}
+			sql, err := scimFilterSQL(expr, tt.columns, args)
+			if tt.wantErr {
+				require.ErrorIs(t, err, scim.ErrInvalidFilter)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tt.wantSQL, sql)
+			require.Equal(t, tt.wantArgs, args.values)
+		})
+	}
+}
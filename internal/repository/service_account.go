@@ -0,0 +1,184 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/Atmosfr/user-service/internal/models"
+	"github.com/jackc/pgerrcode"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+type ServiceAccountRepository interface {
+	Create(ctx context.Context, sa *models.ServiceAccount) error
+	List(ctx context.Context) ([]*models.ServiceAccount, error)
+	FindByID(ctx context.Context, id int64) (*models.ServiceAccount, error)
+	Delete(ctx context.Context, id int64) error
+
+	CreateKey(ctx context.Context, key *models.ServiceAccountKey, keyHash string) error
+	ListKeys(ctx context.Context, serviceAccountID int64) ([]*models.ServiceAccountKey, error)
+	FindKey(ctx context.Context, serviceAccountID, keyID int64) (*models.ServiceAccountKey, error)
+	FindKeyByHash(ctx context.Context, keyHash string) (*models.ServiceAccountKey, error)
+	DeleteKey(ctx context.Context, serviceAccountID, keyID int64) error
+	TouchKeyLastUsed(ctx context.Context, keyID int64) error
+}
+
+type serviceAccountRepository struct {
+	db *sql.DB
+}
+
+const serviceAccountSelect = `SELECT sa.id, sa.name, sa.description, sa.owner_id, u.username, sa.created_at, sa.updated_at
+	FROM service_accounts sa JOIN users u ON u.id = sa.owner_id`
+
+const serviceAccountKeyColumns = `id, service_account_id, type, name, COALESCE(key_prefix, ''), COALESCE(public_key, ''),
+	expires_at, last_used_at, created_at`
+
+func scanServiceAccount(row rowScanner) (*models.ServiceAccount, error) {
+	sa := &models.ServiceAccount{}
+	err := row.Scan(&sa.ID, &sa.Name, &sa.Description, &sa.OwnerID, &sa.OwnerUsername, &sa.CreatedAt, &sa.UpdatedAt)
+	return sa, err
+}
+
+func scanServiceAccountKey(row rowScanner) (*models.ServiceAccountKey, error) {
+	k := &models.ServiceAccountKey{}
+	var expiresAt, lastUsedAt sql.NullTime
+	err := row.Scan(&k.ID, &k.ServiceAccountID, &k.Type, &k.Name, &k.Prefix, &k.PublicKey, &expiresAt, &lastUsedAt, &k.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	if expiresAt.Valid {
+		k.ExpiresAt = &expiresAt.Time
+	}
+	if lastUsedAt.Valid {
+		k.LastUsedAt = &lastUsedAt.Time
+	}
+	return k, nil
+}
+
+func (r *serviceAccountRepository) Create(ctx context.Context, sa *models.ServiceAccount) error {
+	query := `INSERT INTO service_accounts (name, description, owner_id) VALUES ($1, $2, $3) RETURNING id, created_at, updated_at`
+	err := r.db.QueryRowContext(ctx, query, sa.Name, sa.Description, sa.OwnerID).Scan(&sa.ID, &sa.CreatedAt, &sa.UpdatedAt)
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == pgerrcode.ForeignKeyViolation {
+			return ErrUserNotFound
+		}
+		return mapUniqueViolation(err)
+	}
+	return nil
+}
+
+func (r *serviceAccountRepository) List(ctx context.Context) ([]*models.ServiceAccount, error) {
+	rows, err := r.db.QueryContext(ctx, serviceAccountSelect+` ORDER BY sa.name`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	accounts := []*models.ServiceAccount{}
+	for rows.Next() {
+		sa, err := scanServiceAccount(rows)
+		if err != nil {
+			return nil, err
+		}
+		accounts = append(accounts, sa)
+	}
+	return accounts, rows.Err()
+}
+
+func (r *serviceAccountRepository) FindByID(ctx context.Context, id int64) (*models.ServiceAccount, error) {
+	sa, err := scanServiceAccount(r.db.QueryRowContext(ctx, serviceAccountSelect+` WHERE sa.id = $1`, id))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrServiceAccountNotFound
+		}
+		return nil, err
+	}
+	return sa, nil
+}
+
+func (r *serviceAccountRepository) Delete(ctx context.Context, id int64) error {
+	res, err := r.db.ExecContext(ctx, `DELETE FROM service_accounts WHERE id = $1`, id)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return ErrServiceAccountNotFound
+	}
+	return nil
+}
+
+func (r *serviceAccountRepository) CreateKey(ctx context.Context, key *models.ServiceAccountKey, keyHash string) error {
+	query := `INSERT INTO service_account_keys (service_account_id, type, name, key_prefix, key_hash, public_key, expires_at)
+		  VALUES ($1, $2, $3, NULLIF($4, ''), NULLIF($5, ''), NULLIF($6, ''), $7) RETURNING id, created_at`
+	err := r.db.QueryRowContext(ctx, query, key.ServiceAccountID, key.Type, key.Name, key.Prefix, keyHash, key.PublicKey, key.ExpiresAt).
+		Scan(&key.ID, &key.CreatedAt)
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == pgerrcode.ForeignKeyViolation {
+			return ErrServiceAccountNotFound
+		}
+		return mapUniqueViolation(err)
+	}
+	return nil
+}
+
+func (r *serviceAccountRepository) ListKeys(ctx context.Context, serviceAccountID int64) ([]*models.ServiceAccountKey, error) {
+	query := `SELECT ` + serviceAccountKeyColumns + ` FROM service_account_keys WHERE service_account_id = $1 ORDER BY created_at DESC, id DESC`
+	rows, err := r.db.QueryContext(ctx, query, serviceAccountID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	keys := []*models.ServiceAccountKey{}
+	for rows.Next() {
+		k, err := scanServiceAccountKey(rows)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, k)
+	}
+	return keys, rows.Err()
+}
+
+func (r *serviceAccountRepository) findKey(ctx context.Context, where string, args ...any) (*models.ServiceAccountKey, error) {
+	k, err := scanServiceAccountKey(r.db.QueryRowContext(ctx, `SELECT `+serviceAccountKeyColumns+` FROM service_account_keys WHERE `+where, args...))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrKeyNotFound
+		}
+		return nil, err
+	}
+	return k, nil
+}
+
+func (r *serviceAccountRepository) FindKey(ctx context.Context, serviceAccountID, keyID int64) (*models.ServiceAccountKey, error) {
+	return r.findKey(ctx, `id = $1 AND service_account_id = $2`, keyID, serviceAccountID)
+}
+
+func (r *serviceAccountRepository) FindKeyByHash(ctx context.Context, keyHash string) (*models.ServiceAccountKey, error) {
+	return r.findKey(ctx, `key_hash = $1`, keyHash)
+}
+
+func (r *serviceAccountRepository) DeleteKey(ctx context.Context, serviceAccountID, keyID int64) error {
+	res, err := r.db.ExecContext(ctx, `DELETE FROM service_account_keys WHERE id = $1 AND service_account_id = $2`, keyID, serviceAccountID)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return ErrKeyNotFound
+	}
+	return nil
+}
+
+func (r *serviceAccountRepository) TouchKeyLastUsed(ctx context.Context, keyID int64) error {
+	_, err := r.db.ExecContext(ctx, `UPDATE service_account_keys SET last_used_at = NOW()
+		WHERE id = $1 AND (last_used_at IS NULL OR last_used_at < NOW() - INTERVAL '1 minute')`, keyID)
+	return err
+}
+
+func NewServiceAccountRepository(db *sql.DB) ServiceAccountRepository {
+	return &serviceAccountRepository{db: db}
+}
@@ -0,0 +1,72 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+)
+
+type SettingsRepository interface {
+	// List returns the stored settings of each user. Users without any are
+	// missing from the map.
+	List(ctx context.Context, userIDs ...int64) (map[int64]map[string]json.RawMessage, error)
+	// Save stores set and deletes the reset keys of the user in one transaction.
+	Save(ctx context.Context, userID int64, set map[string]json.RawMessage, reset []string) error
+}
+
+type settingsRepository struct {
+	db *sql.DB
+}
+
+func (r *settingsRepository) List(ctx context.Context, userIDs ...int64) (map[int64]map[string]json.RawMessage, error) {
+	rows, err := r.db.QueryContext(ctx, `SELECT user_id, key, value FROM user_settings WHERE user_id = ANY($1)`, userIDs)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	settings := make(map[int64]map[string]json.RawMessage)
+	for rows.Next() {
+		var userID int64
+		var key string
+		var value []byte
+		if err := rows.Scan(&userID, &key, &value); err != nil {
+			return nil, err
+		}
+		if settings[userID] == nil {
+			settings[userID] = make(map[string]json.RawMessage)
+		}
+		settings[userID][key] = value
+	}
+	return settings, rows.Err()
+}
+
+func (r *settingsRepository) Save(ctx context.Context, userID int64, set map[string]json.RawMessage, reset []string) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if len(reset) > 0 {
+		_, err := tx.ExecContext(ctx, `DELETE FROM user_settings WHERE user_id = $1 AND key = ANY($2)`, userID, reset)
+		if err != nil {
+			return err
+		}
+	}
+	for key, value := range set {
+		_, err := tx.ExecContext(ctx, `INSERT INTO user_settings (user_id, key, value) VALUES ($1, $2, $3)
+			ON CONFLICT (user_id, key) DO UPDATE SET value = EXCLUDED.value, updated_at = NOW()`, userID, key, string(value))
+		if err != nil {
+			if isForeignKeyViolation(err, "user_settings_user_id_fkey") {
+				return ErrUserNotFound
+			}
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+func NewSettingsRepository(db *sql.DB) SettingsRepository {
+	return &settingsRepository{db: db}
+}
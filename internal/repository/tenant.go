@@ -0,0 +1,84 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"strconv"
+)
+
+// tenantRole is the database role tenant-scoped transactions assume when row-level
+// security is enabled. Its policies only show rows of the organization in
+// app.current_tenant; table owners, which the service connects as, bypass them.
+const tenantRole = "user_service_tenant"
+
+// dbtx is satisfied by both *sql.DB and *sql.Tx.
+type dbtx interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+type TenantOption func(*tenantScope)
+
+// WithRowLevelSecurity runs each organization's queries in a transaction that sets
+// app.current_tenant, so the database hides other organizations' rows even from a
+// query that forgets to filter by organization.
+func WithRowLevelSecurity() TenantOption {
+	return func(s *tenantScope) {
+		s.rls = true
+	}
+}
+
+// tenantScope runs the queries of organization-scoped repositories.
+type tenantScope struct {
+	db  *sql.DB
+	rls bool
+}
+
+func newTenantScope(db *sql.DB, opts []TenantOption) tenantScope {
+	s := tenantScope{db: db}
+	for _, opt := range opts {
+		opt(&s)
+	}
+	return s
+}
+
+// enterTenant limits the rest of tx to orgID's rows. Without row-level security it does nothing.
+func (s tenantScope) enterTenant(ctx context.Context, tx *sql.Tx, orgID int64) error {
+	if !s.rls {
+		return nil
+	}
+	if _, err := tx.ExecContext(ctx, `SET LOCAL ROLE `+tenantRole); err != nil {
+		return err
+	}
+	_, err := tx.ExecContext(ctx, `SELECT set_config('app.current_tenant', $1, true)`, strconv.FormatInt(orgID, 10))
+	return err
+}
+
+// inTx runs fn in a transaction scoped to orgID and commits it when fn succeeds.
+func (s tenantScope) inTx(ctx context.Context, orgID int64, fn func(tx *sql.Tx) error) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := s.enterTenant(ctx, tx, orgID); err != nil {
+		return err
+	}
+	if err := fn(tx); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// run runs fn for orgID, in a scoped transaction only when row-level security
+// needs one. fn must be done with any rows before it returns.
+func (s tenantScope) run(ctx context.Context, orgID int64, fn func(q dbtx) error) error {
+	if !s.rls {
+		return fn(s.db)
+	}
+	return s.inTx(ctx, orgID, func(tx *sql.Tx) error {
+		return fn(tx)
+	})
+}
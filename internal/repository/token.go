@@ -0,0 +1,105 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+
+	"github.com/Atmosfr/user-service/internal/models"
+)
+
+type TokenRepository interface {
+	Create(ctx context.Context, token *models.PersonalAccessToken, tokenHash string) error
+	ListByUser(ctx context.Context, userID int64) ([]*models.PersonalAccessToken, error)
+	FindByHash(ctx context.Context, tokenHash string) (*models.PersonalAccessToken, error)
+	Delete(ctx context.Context, userID, id int64) error
+	TouchLastUsed(ctx context.Context, id int64) error
+}
+
+type tokenRepository struct {
+	db *sql.DB
+}
+
+const tokenColumns = `id, user_id, name, token_prefix, array_to_string(scopes, ' '), expires_at, last_used_at, created_at`
+
+func scanToken(row rowScanner) (*models.PersonalAccessToken, error) {
+	t := &models.PersonalAccessToken{}
+	var scopes string
+	var expiresAt, lastUsedAt sql.NullTime
+	if err := row.Scan(&t.ID, &t.UserID, &t.Name, &t.Prefix, &scopes, &expiresAt, &lastUsedAt, &t.CreatedAt); err != nil {
+		return nil, err
+	}
+	t.Scopes = strings.Fields(scopes)
+	if expiresAt.Valid {
+		t.ExpiresAt = &expiresAt.Time
+	}
+	if lastUsedAt.Valid {
+		t.LastUsedAt = &lastUsedAt.Time
+	}
+	return t, nil
+}
+
+func (r *tokenRepository) Create(ctx context.Context, token *models.PersonalAccessToken, tokenHash string) error {
+	query := `INSERT INTO personal_access_tokens (user_id, name, token_prefix, token_hash, scopes, expires_at)
+		  VALUES ($1, $2, $3, $4, $5, $6) RETURNING id, created_at`
+	err := r.db.QueryRowContext(ctx, query, token.UserID, token.Name, token.Prefix, tokenHash, token.Scopes, token.ExpiresAt).
+		Scan(&token.ID, &token.CreatedAt)
+	if err != nil {
+		return mapUniqueViolation(err)
+	}
+	return nil
+}
+
+func (r *tokenRepository) ListByUser(ctx context.Context, userID int64) ([]*models.PersonalAccessToken, error) {
+	query := `SELECT ` + tokenColumns + ` FROM personal_access_tokens WHERE user_id = $1 ORDER BY created_at DESC, id DESC`
+	rows, err := r.db.QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	tokens := []*models.PersonalAccessToken{}
+	for rows.Next() {
+		t, err := scanToken(rows)
+		if err != nil {
+			return nil, err
+		}
+		tokens = append(tokens, t)
+	}
+	return tokens, rows.Err()
+}
+
+func (r *tokenRepository) FindByHash(ctx context.Context, tokenHash string) (*models.PersonalAccessToken, error) {
+	query := `SELECT ` + tokenColumns + ` FROM personal_access_tokens WHERE token_hash = $1`
+	t, err := scanToken(r.db.QueryRowContext(ctx, query, tokenHash))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrTokenNotFound
+		}
+		return nil, err
+	}
+	return t, nil
+}
+
+func (r *tokenRepository) Delete(ctx context.Context, userID, id int64) error {
+	res, err := r.db.ExecContext(ctx, `DELETE FROM personal_access_tokens WHERE id = $1 AND user_id = $2`, id, userID)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return ErrTokenNotFound
+	}
+	return nil
+}
+
+// TouchLastUsed records token usage at most once a minute to keep writes off the hot path.
+func (r *tokenRepository) TouchLastUsed(ctx context.Context, id int64) error {
+	_, err := r.db.ExecContext(ctx, `UPDATE personal_access_tokens SET last_used_at = NOW()
+		WHERE id = $1 AND (last_used_at IS NULL OR last_used_at < NOW() - INTERVAL '1 minute')`, id)
+	return err
+}
+
+func NewTokenRepository(db *sql.DB) TokenRepository {
+	return &tokenRepository{db: db}
+}
@@ -3,7 +3,11 @@ package repository
 import (
 	"context"
 	"database/sql"
+	"encoding/json"
 	"errors"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/Atmosfr/user-service/internal/models"
 	"github.com/jackc/pgerrcode"
@@ -12,33 +16,181 @@ import (
 
 type UserRepository interface {
 	Create(ctx context.Context, user *models.User) error
+	// FindByEmail and FindByID do not find deleted users.
 	FindByEmail(ctx context.Context, email string) (*models.User, error)
 	FindByID(ctx context.Context, id int64) (*models.User, error)
+	UpdateRole(ctx context.Context, id int64, role string) error
+	// Update saves the username and profile fields and bumps updated_at, which is
+	// written back to user. With ifUnmodified set it fails with ErrUserModified
+	// unless updated_at still has that value.
+	Update(ctx context.Context, user *models.User, ifUnmodified *time.Time) error
+	// UpdateAvatar saves the avatar fields of user and returns the avatar key it
+	// replaced.
+	UpdateAvatar(ctx context.Context, user *models.User) (previousKey string, err error)
+	// MarkEmailVerified verifies the user's address if it still is email and
+	// returns when it was verified. Other addresses give ErrUserNotFound.
+	// Accounts pending verification become active.
+	MarkEmailVerified(ctx context.Context, id int64, email string) (time.Time, error)
+
+	// List returns up to limit users matching filter, newest first. With after
+	// set it continues behind that position.
+	List(ctx context.Context, filter UserFilter, after *UserCursor, limit int) ([]*models.User, error)
+	// Delete moves the user to the deleted state, the purge job removes the
+	// account after the grace period. It fails with ErrUserOwnsServiceAccounts
+	// while the user owns any and with ErrLastOrganizationOwner while an
+	// organization has no other owner.
+	Delete(ctx context.Context, id int64) error
+	// Search finds up to limit users whose email or username resembles query,
+	// best match first. It gives up with ErrSearchTimeout after SearchTimeout.
+	Search(ctx context.Context, query string, limit int) ([]*UserMatch, error)
+}
+
+// SearchTimeout bounds a fuzzy search, both while waiting for a connection
+// and on the server, so slow searches cannot hold on to the pool.
+const SearchTimeout = 2 * time.Second
+
+// UserMatch is a search result with its trigram word similarity, 0 to 1.
+type UserMatch struct {
+	User  *models.User
+	Score float64
+}
+
+// UserFilter narrows List, zero fields match every user.
+type UserFilter struct {
+	Role     string
+	IsActive *bool
+	// State matches one account state. Deleted users are only listed when
+	// it is AccountDeleted.
+	State string
+	// CreatedAfter is inclusive, CreatedBefore exclusive.
+	CreatedAfter  *time.Time
+	CreatedBefore *time.Time
+	// EmailDomain matches the part after the @, ignoring case.
+	EmailDomain string
+	// Query is searched for in the email, username and display name.
+	Query    string
+	Metadata []MetadataFilter
+}
+
+// UserCursor is the position of a user in List's order.
+type UserCursor struct {
+	CreatedAt time.Time
+	ID        int64
 }
 
 type userRepository struct {
 	db *sql.DB
 }
 
-func (r *userRepository) Create(ctx context.Context, user *models.User) error {
-	query := `INSERT INTO users (email, password_hash, username) VALUES ($1, $2, $3) RETURNING id`
-	err := r.db.QueryRowContext(ctx, query, user.Email, user.PasswordHash, user.Username).Scan(&user.ID)
+// mapUniqueViolation translates unique constraint violations into repository errors.
+func mapUniqueViolation(err error) error {
+	var pgErr *pgconn.PgError
+	if !errors.As(err, &pgErr) || pgErr.Code != pgerrcode.UniqueViolation {
+		return err
+	}
+	switch pgErr.ConstraintName {
+	case "users_email_key":
+		return ErrEmailAlreadyExists
+	case "users_username_key":
+		return ErrUsernameAlreadyExists
+	case "users_scim_external_id_key":
+		return ErrExternalIDAlreadyExists
+	case "scim_groups_tenant_id_display_name_key":
+		return ErrGroupAlreadyExists
+	case "personal_access_tokens_user_id_name_key":
+		return ErrTokenNameAlreadyExists
+	case "service_accounts_name_key":
+		return ErrServiceAccountExists
+	case "service_account_keys_service_account_id_name_key":
+		return ErrKeyNameAlreadyExists
+	case "organizations_slug_key":
+		return ErrOrganizationSlugExists
+	case "organization_members_pkey":
+		return ErrMemberAlreadyExists
+	case "org_groups_organization_id_name_key":
+		return ErrGroupAlreadyExists
+	case "org_invitations_pending_key":
+		return ErrInvitationExists
+	case "org_domains_organization_id_domain_key":
+		return ErrDomainAlreadyClaimed
+	case "org_domains_verified_key":
+		return ErrDomainVerifiedElsewhere
+	case "data_exports_in_flight_key":
+		return ErrExportInProgress
+	case "legal_documents_kind_version_key":
+		return ErrDocumentVersionExists
+	}
+	return ErrEmailAlreadyExists
+}
+
+func isForeignKeyViolation(err error, constraint string) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == pgerrcode.ForeignKeyViolation && pgErr.ConstraintName == constraint
+}
+
+// isQueryCanceled reports a query stopped by statement_timeout.
+func isQueryCanceled(err error) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == pgerrcode.QueryCanceled
+}
+
+const userColumns = `id, email, password_hash, username, created_at, updated_at, is_active, role, email_verified_at,
+	display_name, locale, timezone, avatar_url, avatar_urls, avatar_key, public_metadata, private_metadata,
+	state, state_reason, state_expires_at, deleted_at`
+
+func scanUser(row rowScanner) (*models.User, error) {
+	user := &models.User{}
+	var verifiedAt, stateExpiresAt, deletedAt sql.NullTime
+	var avatarURLs, publicMetadata, privateMetadata []byte
+	err := row.Scan(&user.ID, &user.Email, &user.PasswordHash, &user.Username, &user.CreatedAt, &user.UpdatedAt, &user.IsActive, &user.Role, &verifiedAt,
+		&user.DisplayName, &user.Locale, &user.Timezone, &user.AvatarURL, &avatarURLs, &user.AvatarKey, &publicMetadata, &privateMetadata,
+		&user.State, &user.StateReason, &stateExpiresAt, &deletedAt)
+	if err != nil {
+		return nil, err
+	}
+	if stateExpiresAt.Valid {
+		user.StateExpiresAt = &stateExpiresAt.Time
+	}
+	if deletedAt.Valid {
+		user.DeletedAt = &deletedAt.Time
+	}
+	user.PublicMetadata = publicMetadata
+	user.PrivateMetadata = privateMetadata
+	if err := json.Unmarshal(avatarURLs, &user.AvatarURLs); err != nil {
+		return nil, err
+	}
+	if verifiedAt.Valid {
+		user.EmailVerifiedAt = &verifiedAt.Time
+	}
+	return user, nil
+}
+
+// queryRower is satisfied by both *sql.DB and *sql.Tx.
+type queryRower interface {
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+// insertUser is shared by Create and flows that create users inside their own transaction.
+func insertUser(ctx context.Context, q queryRower, user *models.User) error {
+	query := `INSERT INTO users (email, password_hash, username, role, email_verified_at)
+		  VALUES ($1, $2, $3, COALESCE(NULLIF($4, ''), 'user'), $5) RETURNING id`
+	err := q.QueryRowContext(ctx, query, user.Email, user.PasswordHash, user.Username, user.Role, user.EmailVerifiedAt).Scan(&user.ID)
 	if err != nil {
-		var pgErr *pgconn.PgError
-		if errors.As(err, &pgErr) && pgErr.Code == pgerrcode.UniqueViolation {
-			return ErrEmailAlreadyExists
+		if isForeignKeyViolation(err, "users_role_fkey") {
+			return ErrRoleNotFound
 		}
-		return err
+		return mapUniqueViolation(err)
 	}
 
 	return nil
 }
 
+func (r *userRepository) Create(ctx context.Context, user *models.User) error {
+	return insertUser(ctx, r.db, user)
+}
+
 func (r *userRepository) FindByEmail(ctx context.Context, email string) (*models.User, error) {
-	query := `SELECT id, email, password_hash, username, created_at, updated_at, is_active, role
-          FROM users WHERE email = $1`
-	user := &models.User{}
-	err := r.db.QueryRowContext(ctx, query, email).Scan(&user.ID, &user.Email, &user.PasswordHash, &user.Username, &user.CreatedAt, &user.UpdatedAt, &user.IsActive, &user.Role)
+	user, err := scanUser(r.db.QueryRowContext(ctx, `SELECT `+userColumns+` FROM users WHERE email = $1 AND deleted_at IS NULL`, email))
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return nil, ErrUserNotFound
@@ -49,10 +201,7 @@ func (r *userRepository) FindByEmail(ctx context.Context, email string) (*models
 }
 
 func (r *userRepository) FindByID(ctx context.Context, id int64) (*models.User, error) {
-	query := `SELECT id, email, password_hash, username, created_at, updated_at, is_active, role
-		  FROM users WHERE id = $1`
-	user := &models.User{}
-	err := r.db.QueryRowContext(ctx, query, id).Scan(&user.ID, &user.Email, &user.PasswordHash, &user.Username, &user.CreatedAt, &user.UpdatedAt, &user.IsActive, &user.Role)
+	user, err := scanUser(r.db.QueryRowContext(ctx, `SELECT `+userColumns+` FROM users WHERE id = $1 AND deleted_at IS NULL`, id))
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return nil, ErrUserNotFound
@@ -62,6 +211,215 @@ func (r *userRepository) FindByID(ctx context.Context, id int64) (*models.User,
 	return user, nil
 }
 
+func (r *userRepository) UpdateRole(ctx context.Context, id int64, role string) error {
+	res, err := r.db.ExecContext(ctx, `UPDATE users SET role = $1, updated_at = NOW() WHERE id = $2 AND deleted_at IS NULL`, role, id)
+	if err != nil {
+		if isForeignKeyViolation(err, "users_role_fkey") {
+			return ErrRoleNotFound
+		}
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return ErrUserNotFound
+	}
+	return nil
+}
+
+func (r *userRepository) Update(ctx context.Context, user *models.User, ifUnmodified *time.Time) error {
+	avatarURLs, err := marshalAvatarURLs(user)
+	if err != nil {
+		return err
+	}
+	query := `UPDATE users SET username = $2, display_name = $3, locale = $4, timezone = $5, avatar_url = $6,
+			avatar_urls = $7, avatar_key = $8, updated_at = NOW()
+		  WHERE id = $1 AND deleted_at IS NULL AND ($9::timestamptz IS NULL OR updated_at = $9) RETURNING updated_at`
+	err = r.db.QueryRowContext(ctx, query, user.ID, user.Username, user.DisplayName, user.Locale, user.Timezone, user.AvatarURL,
+		avatarURLs, user.AvatarKey, ifUnmodified).Scan(&user.UpdatedAt)
+	if err == nil {
+		return nil
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		return mapUniqueViolation(err)
+	}
+	if ifUnmodified != nil {
+		var exists bool
+		if err := r.db.QueryRowContext(ctx, `SELECT EXISTS (SELECT 1 FROM users WHERE id = $1 AND deleted_at IS NULL)`, user.ID).Scan(&exists); err != nil {
+			return err
+		}
+		if exists {
+			return ErrUserModified
+		}
+	}
+	return ErrUserNotFound
+}
+
+func marshalAvatarURLs(user *models.User) (string, error) {
+	if user.AvatarURLs == nil {
+		return "{}", nil
+	}
+	b, err := json.Marshal(user.AvatarURLs)
+	return string(b), err
+}
+
+func (r *userRepository) UpdateAvatar(ctx context.Context, user *models.User) (string, error) {
+	avatarURLs, err := marshalAvatarURLs(user)
+	if err != nil {
+		return "", err
+	}
+	var previousKey string
+	err = r.db.QueryRowContext(ctx, `UPDATE users u SET avatar_url = $2, avatar_urls = $3, avatar_key = $4, updated_at = NOW()
+		FROM (SELECT id, avatar_key FROM users WHERE id = $1 AND deleted_at IS NULL FOR UPDATE) old
+		WHERE u.id = old.id RETURNING old.avatar_key, u.updated_at`, user.ID, user.AvatarURL, avatarURLs, user.AvatarKey).
+		Scan(&previousKey, &user.UpdatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", ErrUserNotFound
+	}
+	return previousKey, err
+}
+
+func (r *userRepository) MarkEmailVerified(ctx context.Context, id int64, email string) (time.Time, error) {
+	var verifiedAt time.Time
+	err := r.db.QueryRowContext(ctx, `WITH verified AS (
+			UPDATE users u SET email_verified_at = COALESCE(u.email_verified_at, NOW()), updated_at = NOW(),
+				state = CASE WHEN u.state = 'pending_verification' THEN 'active' ELSE u.state END,
+				state_reason = CASE WHEN u.state = 'pending_verification' THEN $3 ELSE u.state_reason END
+			FROM users old
+			WHERE u.id = old.id AND u.id = $1 AND u.email = $2 AND u.deleted_at IS NULL
+			RETURNING u.id, u.email_verified_at, old.state AS from_state, u.state AS to_state
+		), history AS (
+			INSERT INTO user_state_history (user_id, from_state, to_state, reason)
+			SELECT id, from_state, to_state, $3 FROM verified WHERE from_state <> to_state
+		)
+		SELECT email_verified_at FROM verified`, id, email, models.StateReasonEmailVerified).Scan(&verifiedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return time.Time{}, ErrUserNotFound
+	}
+	return verifiedAt, err
+}
+
+func (r *userRepository) List(ctx context.Context, filter UserFilter, after *UserCursor, limit int) ([]*models.User, error) {
+	args := &sqlArgs{}
+	var conditions []string
+	if filter.Role != "" {
+		conditions = append(conditions, "role = "+args.add(filter.Role))
+	}
+	if filter.IsActive != nil {
+		conditions = append(conditions, "is_active = "+args.add(*filter.IsActive))
+	}
+	if filter.State != "" {
+		conditions = append(conditions, "state = "+args.add(filter.State))
+	}
+	if filter.State != models.AccountDeleted {
+		conditions = append(conditions, "deleted_at IS NULL")
+	}
+	if filter.CreatedAfter != nil {
+		conditions = append(conditions, "created_at >= "+args.add(*filter.CreatedAfter))
+	}
+	if filter.CreatedBefore != nil {
+		conditions = append(conditions, "created_at < "+args.add(*filter.CreatedBefore))
+	}
+	if filter.EmailDomain != "" {
+		conditions = append(conditions, "LOWER(SPLIT_PART(email, '@', 2)) = LOWER("+args.add(filter.EmailDomain)+")")
+	}
+	if filter.Query != "" {
+		pattern := args.add("%" + escapeLike(filter.Query) + "%")
+		conditions = append(conditions, "(email ILIKE "+pattern+" OR username ILIKE "+pattern+" OR display_name ILIKE "+pattern+")")
+	}
+	metadata, err := metadataConditions(filter.Metadata, args)
+	if err != nil {
+		return nil, err
+	}
+	conditions = append(conditions, metadata...)
+	if after != nil {
+		conditions = append(conditions, "(created_at, id) < ("+args.add(after.CreatedAt)+", "+args.add(after.ID)+")")
+	}
+
+	query := `SELECT ` + userColumns + ` FROM users`
+	if len(conditions) > 0 {
+		query += " WHERE " + strings.Join(conditions, " AND ")
+	}
+	query += " ORDER BY created_at DESC, id DESC LIMIT " + args.add(limit)
+
+	rows, err := r.db.QueryContext(ctx, query, args.values...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	users := []*models.User{}
+	for rows.Next() {
+		user, err := scanUser(rows)
+		if err != nil {
+			return nil, err
+		}
+		users = append(users, user)
+	}
+	return users, rows.Err()
+}
+
+func (r *userRepository) Delete(ctx context.Context, id int64) error {
+	return softDeleteUser(ctx, r.db, id, models.StateReasonAdminDeletion, nil)
+}
+
+func (r *userRepository) Search(ctx context.Context, query string, limit int) ([]*UserMatch, error) {
+	ctx, cancel := context.WithTimeout(ctx, SearchTimeout)
+	defer cancel()
+
+	matches, err := r.search(ctx, query, limit)
+	if errors.Is(err, context.DeadlineExceeded) || isQueryCanceled(err) {
+		return nil, ErrSearchTimeout
+	}
+	return matches, err
+}
+
+func (r *userRepository) search(ctx context.Context, query string, limit int) ([]*UserMatch, error) {
+	tx, err := r.db.BeginTx(ctx, &sql.TxOptions{ReadOnly: true})
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	// statement_timeout stops the query on the server even if the
+	// cancellation from the context does not get through
+	if _, err := tx.ExecContext(ctx, `SELECT set_config('statement_timeout', $1, true)`,
+		strconv.FormatInt(SearchTimeout.Milliseconds(), 10)); err != nil {
+		return nil, err
+	}
+
+	// <% is served by the trigram indexes, word_similarity ranks "jon smth"
+	// close to jon.smith@example.com although the address is much longer
+	rows, err := tx.QueryContext(ctx, `SELECT `+userColumns+`,
+		GREATEST(word_similarity($1, LOWER(email)), word_similarity($1, LOWER(username))) AS score
+		FROM users
+		WHERE ($1 <% LOWER(email) OR $1 <% LOWER(username)) AND deleted_at IS NULL
+		ORDER BY score DESC, id
+		LIMIT $2`, strings.ToLower(query), limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	matches := []*UserMatch{}
+	for rows.Next() {
+		match := &UserMatch{}
+		if match.User, err = scanUser(scoreScanner{rows, &match.Score}); err != nil {
+			return nil, err
+		}
+		matches = append(matches, match)
+	}
+	return matches, rows.Err()
+}
+
+// scoreScanner scans the score column that follows the user columns.
+type scoreScanner struct {
+	row   rowScanner
+	score *float64
+}
+
+func (s scoreScanner) Scan(dest ...any) error {
+	return s.row.Scan(append(dest, s.score)...)
+}
+
 func NewUserRepository(db *sql.DB) UserRepository {
 	return &userRepository{db: db}
 }
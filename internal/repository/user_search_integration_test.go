@@ -0,0 +1,33 @@
+//go:build integration
+
+package repository
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/Atmosfr/user-service/internal/models"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUserRepository_Search(t *testing.T) {
+	db := openTestDB(t)
+	ctx := context.Background()
+	users := NewUserRepository(db)
+	suffix := time.Now().UnixNano()
+
+	jon := &models.User{Email: fmt.Sprintf("jon.smith%d@example.com", suffix), Username: fmt.Sprintf("jsmith%d", suffix), PasswordHash: "x"}
+	require.NoError(t, users.Create(ctx, jon))
+	other := &models.User{Email: fmt.Sprintf("mary.jones%d@example.com", suffix), Username: fmt.Sprintf("mjones%d", suffix), PasswordHash: "x"}
+	require.NoError(t, users.Create(ctx, other))
+
+	matches, err := users.Search(ctx, "jon smth", 10)
+	require.NoError(t, err)
+	require.NotEmpty(t, matches)
+	require.Equal(t, jon.ID, matches[0].User.ID)
+	for i := 1; i < len(matches); i++ {
+		require.LessOrEqual(t, matches[i].Score, matches[i-1].Score)
+	}
+}
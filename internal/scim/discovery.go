@@ -0,0 +1,114 @@
+package scim
+
+func ServiceProviderConfig(baseURL string) map[string]any {
+	return map[string]any{
+		"schemas":          []string{SchemaServiceProviderConfig},
+		"documentationUri": "https://datatracker.ietf.org/doc/html/rfc7644",
+		"patch":            map[string]any{"supported": true},
+		"bulk":             map[string]any{"supported": false, "maxOperations": 0, "maxPayloadSize": 0},
+		"filter":           map[string]any{"supported": true, "maxResults": MaxCount},
+		"changePassword":   map[string]any{"supported": true},
+		"sort":             map[string]any{"supported": false},
+		"etag":             map[string]any{"supported": false},
+		"authenticationSchemes": []map[string]any{{
+			"type":        "oauthbearertoken",
+			"name":        "OAuth Bearer Token",
+			"description": "Authentication with a per-tenant SCIM bearer token",
+			"primary":     true,
+		}},
+		"meta": map[string]any{
+			"resourceType": "ServiceProviderConfig",
+			"location":     baseURL + "/ServiceProviderConfig",
+		},
+	}
+}
+
+func ResourceTypes(baseURL string) []map[string]any {
+	return []map[string]any{
+		{
+			"schemas":     []string{SchemaResourceType},
+			"id":          "User",
+			"name":        "User",
+			"endpoint":    "/Users",
+			"description": "User Account",
+			"schema":      SchemaUser,
+			"meta":        map[string]any{"resourceType": "ResourceType", "location": baseURL + "/ResourceTypes/User"},
+		},
+		{
+			"schemas":     []string{SchemaResourceType},
+			"id":          "Group",
+			"name":        "Group",
+			"endpoint":    "/Groups",
+			"description": "Group",
+			"schema":      SchemaGroup,
+			"meta":        map[string]any{"resourceType": "ResourceType", "location": baseURL + "/ResourceTypes/Group"},
+		},
+	}
+}
+
+func attribute(name, typ string, required, caseExact bool, mutability, uniqueness string) map[string]any {
+	return map[string]any{
+		"name":        name,
+		"type":        typ,
+		"multiValued": false,
+		"required":    required,
+		"caseExact":   caseExact,
+		"mutability":  mutability,
+		"returned":    "default",
+		"uniqueness":  uniqueness,
+	}
+}
+
+func multiValued(name string, subAttributes ...map[string]any) map[string]any {
+	return map[string]any{
+		"name":          name,
+		"type":          "complex",
+		"multiValued":   true,
+		"required":      false,
+		"mutability":    "readWrite",
+		"returned":      "default",
+		"subAttributes": subAttributes,
+	}
+}
+
+func Schemas(baseURL string) []map[string]any {
+	password := attribute("password", "string", false, false, "writeOnly", "none")
+	password["returned"] = "never"
+
+	return []map[string]any{
+		{
+			"schemas":     []string{SchemaSchema},
+			"id":          SchemaUser,
+			"name":        "User",
+			"description": "User Account",
+			"attributes": []map[string]any{
+				attribute("userName", "string", true, false, "readWrite", "server"),
+				attribute("externalId", "string", false, true, "readWrite", "none"),
+				attribute("active", "boolean", false, false, "readWrite", "none"),
+				password,
+				multiValued("emails",
+					attribute("value", "string", true, false, "readWrite", "server"),
+					attribute("type", "string", false, false, "readWrite", "none"),
+					attribute("primary", "boolean", false, false, "readWrite", "none"),
+				),
+			},
+			"meta": map[string]any{"resourceType": "Schema", "location": baseURL + "/Schemas/" + SchemaUser},
+		},
+		{
+			"schemas":     []string{SchemaSchema},
+			"id":          SchemaGroup,
+			"name":        "Group",
+			"description": "Group",
+			"attributes": []map[string]any{
+				attribute("displayName", "string", true, false, "readWrite", "server"),
+				attribute("externalId", "string", false, true, "readWrite", "none"),
+				multiValued("members",
+					attribute("value", "string", true, false, "immutable", "none"),
+					attribute("display", "string", false, false, "readOnly", "none"),
+					attribute("$ref", "reference", false, false, "immutable", "none"),
+				),
+			},
+			"meta": map[string]any{"resourceType": "Schema", "location": baseURL + "/Schemas/" + SchemaGroup},
+		},
+	}
+}
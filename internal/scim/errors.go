@@ -0,0 +1,63 @@
+package scim
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+)
+
+type Error struct {
+	Schemas  []string `json:"schemas"`
+	Status   string   `json:"status"`
+	ScimType string   `json:"scimType,omitempty"`
+	Detail   string   `json:"detail,omitempty"`
+}
+
+func (e *Error) Error() string {
+	return e.Detail
+}
+
+func (e *Error) StatusCode() int {
+	code, err := strconv.Atoi(e.Status)
+	if err != nil {
+		return http.StatusInternalServerError
+	}
+	return code
+}
+
+func NewError(status int, scimType, detail string) *Error {
+	return &Error{
+		Schemas:  []string{SchemaError},
+		Status:   strconv.Itoa(status),
+		ScimType: scimType,
+		Detail:   detail,
+	}
+}
+
+var (
+	ErrInvalidFilter = errors.New("invalid filter")
+	ErrInvalidPath   = errors.New("invalid path")
+	ErrNoTarget      = errors.New("no target")
+	ErrInvalidValue  = errors.New("invalid value")
+	ErrInvalidSyntax = errors.New("invalid syntax")
+	ErrMutability    = errors.New("attribute is immutable")
+)
+
+// ScimType maps a protocol error to the scimType keyword from RFC 7644 section 3.12.
+func ScimType(err error) string {
+	switch {
+	case errors.Is(err, ErrInvalidFilter):
+		return "invalidFilter"
+	case errors.Is(err, ErrInvalidPath):
+		return "invalidPath"
+	case errors.Is(err, ErrNoTarget):
+		return "noTarget"
+	case errors.Is(err, ErrInvalidValue):
+		return "invalidValue"
+	case errors.Is(err, ErrInvalidSyntax):
+		return "invalidSyntax"
+	case errors.Is(err, ErrMutability):
+		return "mutability"
+	}
+	return ""
+}
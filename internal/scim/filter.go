@@ -0,0 +1,428 @@
+package scim
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Expr is a node of a parsed SCIM filter (RFC 7644 section 3.4.2.2).
+type Expr interface {
+	expr()
+}
+
+type AttrExpr struct {
+	Path  string
+	Op    string
+	Value any
+}
+
+type LogicalExpr struct {
+	Op    string
+	Left  Expr
+	Right Expr
+}
+
+type NotExpr struct {
+	Expr Expr
+}
+
+type ValuePathExpr struct {
+	Attr   string
+	Filter Expr
+}
+
+func (*AttrExpr) expr()      {}
+func (*LogicalExpr) expr()   {}
+func (*NotExpr) expr()       {}
+func (*ValuePathExpr) expr() {}
+
+var compareOps = map[string]bool{
+	"eq": true, "ne": true, "co": true, "sw": true, "ew": true,
+	"gt": true, "ge": true, "lt": true, "le": true,
+}
+
+type tokenKind int
+
+const (
+	tokWord tokenKind = iota
+	tokString
+	tokLParen
+	tokRParen
+	tokLBracket
+	tokRBracket
+	tokEOF
+)
+
+type token struct {
+	kind tokenKind
+	text string
+}
+
+func tokenize(s string) ([]token, error) {
+	var tokens []token
+	i := 0
+	for i < len(s) {
+		c := s[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			i++
+		case c == '(':
+			tokens = append(tokens, token{kind: tokLParen, text: "("})
+			i++
+		case c == ')':
+			tokens = append(tokens, token{kind: tokRParen, text: ")"})
+			i++
+		case c == '[':
+			tokens = append(tokens, token{kind: tokLBracket, text: "["})
+			i++
+		case c == ']':
+			tokens = append(tokens, token{kind: tokRBracket, text: "]"})
+			i++
+		case c == '"':
+			j := i + 1
+			for j < len(s) && s[j] != '"' {
+				if s[j] == '\\' {
+					j++
+				}
+				j++
+			}
+			if j >= len(s) {
+				return nil, fmt.Errorf("%w: unterminated string", ErrInvalidFilter)
+			}
+			var str string
+			if err := json.Unmarshal([]byte(s[i:j+1]), &str); err != nil {
+				return nil, fmt.Errorf("%w: %v", ErrInvalidFilter, err)
+			}
+			tokens = append(tokens, token{kind: tokString, text: str})
+			i = j + 1
+		default:
+			j := i
+			for j < len(s) && !strings.ContainsRune(" \t\n\r()[]\"", rune(s[j])) {
+				j++
+			}
+			tokens = append(tokens, token{kind: tokWord, text: s[i:j]})
+			i = j
+		}
+	}
+	return append(tokens, token{kind: tokEOF}), nil
+}
+
+type parser struct {
+	tokens []token
+	pos    int
+}
+
+func (p *parser) peek() token {
+	return p.tokens[p.pos]
+}
+
+func (p *parser) next() token {
+	t := p.tokens[p.pos]
+	if t.kind != tokEOF {
+		p.pos++
+	}
+	return t
+}
+
+func (p *parser) keyword(word string) bool {
+	t := p.peek()
+	return t.kind == tokWord && strings.EqualFold(t.text, word)
+}
+
+// ParseFilter parses a filter expression such as `userName eq "bjensen"`.
+func ParseFilter(s string) (Expr, error) {
+	tokens, err := tokenize(s)
+	if err != nil {
+		return nil, err
+	}
+	p := &parser{tokens: tokens}
+	e, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if p.peek().kind != tokEOF {
+		return nil, fmt.Errorf("%w: unexpected %q", ErrInvalidFilter, p.peek().text)
+	}
+	return e, nil
+}
+
+func (p *parser) parseOr() (Expr, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.keyword("or") {
+		p.next()
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = &LogicalExpr{Op: "or", Left: left, Right: right}
+	}
+	return left, nil
+}
+
+func (p *parser) parseAnd() (Expr, error) {
+	left, err := p.parseNot()
+	if err != nil {
+		return nil, err
+	}
+	for p.keyword("and") {
+		p.next()
+		right, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		left = &LogicalExpr{Op: "and", Left: left, Right: right}
+	}
+	return left, nil
+}
+
+func (p *parser) parseNot() (Expr, error) {
+	if p.keyword("not") {
+		p.next()
+		if p.peek().kind != tokLParen {
+			return nil, fmt.Errorf("%w: expected ( after not", ErrInvalidFilter)
+		}
+		e, err := p.parseAtom()
+		if err != nil {
+			return nil, err
+		}
+		return &NotExpr{Expr: e}, nil
+	}
+	return p.parseAtom()
+}
+
+func (p *parser) parseAtom() (Expr, error) {
+	t := p.next()
+	switch t.kind {
+	case tokLParen:
+		e, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if p.next().kind != tokRParen {
+			return nil, fmt.Errorf("%w: expected )", ErrInvalidFilter)
+		}
+		return e, nil
+	case tokWord:
+	default:
+		return nil, fmt.Errorf("%w: unexpected %q", ErrInvalidFilter, t.text)
+	}
+
+	path := normalizePath(t.text)
+	if p.peek().kind == tokLBracket {
+		p.next()
+		inner, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if p.next().kind != tokRBracket {
+			return nil, fmt.Errorf("%w: expected ]", ErrInvalidFilter)
+		}
+		return &ValuePathExpr{Attr: path, Filter: inner}, nil
+	}
+
+	opTok := p.next()
+	op := strings.ToLower(opTok.text)
+	if opTok.kind != tokWord {
+		return nil, fmt.Errorf("%w: expected operator after %q", ErrInvalidFilter, t.text)
+	}
+	if op == "pr" {
+		return &AttrExpr{Path: path, Op: op}, nil
+	}
+	if !compareOps[op] {
+		return nil, fmt.Errorf("%w: unknown operator %q", ErrInvalidFilter, opTok.text)
+	}
+
+	valTok := p.next()
+	switch valTok.kind {
+	case tokString:
+		return &AttrExpr{Path: path, Op: op, Value: valTok.text}, nil
+	case tokWord:
+		switch strings.ToLower(valTok.text) {
+		case "true":
+			return &AttrExpr{Path: path, Op: op, Value: true}, nil
+		case "false":
+			return &AttrExpr{Path: path, Op: op, Value: false}, nil
+		case "null":
+			return &AttrExpr{Path: path, Op: op, Value: nil}, nil
+		}
+		n, err := strconv.ParseFloat(valTok.text, 64)
+		if err != nil {
+			return nil, fmt.Errorf("%w: invalid value %q", ErrInvalidFilter, valTok.text)
+		}
+		return &AttrExpr{Path: path, Op: op, Value: n}, nil
+	}
+	return nil, fmt.Errorf("%w: expected value after %q", ErrInvalidFilter, opTok.text)
+}
+
+// normalizePath strips a schema URN prefix and lowercases the attribute path,
+// since attribute names are case-insensitive.
+func normalizePath(path string) string {
+	if strings.HasPrefix(strings.ToLower(path), "urn:") {
+		if i := strings.LastIndex(path, ":"); i != -1 {
+			path = path[i+1:]
+		}
+	}
+	return strings.ToLower(path)
+}
+
+// Match evaluates the filter against a decoded JSON resource.
+func Match(e Expr, resource map[string]any) bool {
+	switch e := e.(type) {
+	case *LogicalExpr:
+		if e.Op == "and" {
+			return Match(e.Left, resource) && Match(e.Right, resource)
+		}
+		return Match(e.Left, resource) || Match(e.Right, resource)
+	case *NotExpr:
+		return !Match(e.Expr, resource)
+	case *ValuePathExpr:
+		v, _ := getField(resource, e.Attr)
+		items, ok := v.([]any)
+		if !ok {
+			items = []any{v}
+		}
+		for _, item := range items {
+			if m, ok := item.(map[string]any); ok && Match(e.Filter, m) {
+				return true
+			}
+		}
+		return false
+	case *AttrExpr:
+		values := lookupValues(resource, e.Path)
+		if e.Op == "pr" {
+			for _, v := range values {
+				if v != nil && v != "" {
+					return true
+				}
+			}
+			return false
+		}
+		for _, v := range values {
+			if compare(v, e.Op, e.Value) {
+				return true
+			}
+		}
+		return e.Op == "ne" && len(values) == 0 && e.Value != nil
+	}
+	return false
+}
+
+// lookupValues resolves a dotted path, flattening multi-valued attributes.
+// A multi-valued attribute of complex values addressed without a sub-attribute
+// resolves to the elements' "value" sub-attribute.
+func lookupValues(resource map[string]any, path string) []any {
+	head, rest, _ := strings.Cut(path, ".")
+	v, ok := getField(resource, head)
+	if !ok {
+		return nil
+	}
+	items, isList := v.([]any)
+	if !isList {
+		items = []any{v}
+	}
+	var out []any
+	for _, item := range items {
+		m, isMap := item.(map[string]any)
+		switch {
+		case rest != "" && isMap:
+			out = append(out, lookupValues(m, rest)...)
+		case rest != "":
+		case isMap && isList:
+			if val, ok := getField(m, "value"); ok {
+				out = append(out, val)
+			}
+		default:
+			out = append(out, item)
+		}
+	}
+	return out
+}
+
+func getField(m map[string]any, name string) (any, bool) {
+	if v, ok := m[name]; ok {
+		return v, true
+	}
+	for k, v := range m {
+		if strings.EqualFold(k, name) {
+			return v, true
+		}
+	}
+	return nil, false
+}
+
+func compare(actual any, op string, expected any) bool {
+	switch a := actual.(type) {
+	case string:
+		e, ok := expected.(string)
+		if !ok {
+			return op == "ne"
+		}
+		if at, err := time.Parse(time.RFC3339Nano, a); err == nil {
+			if et, err := time.Parse(time.RFC3339Nano, e); err == nil {
+				return compareOrdered(at.Compare(et), op)
+			}
+		}
+		la, le := strings.ToLower(a), strings.ToLower(e)
+		switch op {
+		case "co":
+			return strings.Contains(la, le)
+		case "sw":
+			return strings.HasPrefix(la, le)
+		case "ew":
+			return strings.HasSuffix(la, le)
+		}
+		return compareOrdered(strings.Compare(la, le), op)
+	case bool:
+		e, ok := expected.(bool)
+		switch op {
+		case "eq":
+			return ok && a == e
+		case "ne":
+			return !ok || a != e
+		}
+	case float64:
+		e, ok := expected.(float64)
+		if !ok {
+			return op == "ne"
+		}
+		switch {
+		case a < e:
+			return compareOrdered(-1, op)
+		case a > e:
+			return compareOrdered(1, op)
+		}
+		return compareOrdered(0, op)
+	case nil:
+		switch op {
+		case "eq":
+			return expected == nil
+		case "ne":
+			return expected != nil
+		}
+	}
+	return false
+}
+
+func compareOrdered(c int, op string) bool {
+	switch op {
+	case "eq":
+		return c == 0
+	case "ne":
+		return c != 0
+	case "gt":
+		return c > 0
+	case "ge":
+		return c >= 0
+	case "lt":
+		return c < 0
+	case "le":
+		return c <= 0
+	}
+	return false
+}
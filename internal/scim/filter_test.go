@@ -0,0 +1,127 @@
+package scim
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseFilter(t *testing.T) {
+	tests := []struct {
+		name    string
+		filter  string
+		want    Expr
+		wantErr bool
+	}{
+		{
+			name:   "userName eq",
+			filter: `userName eq "bjensen"`,
+			want:   &AttrExpr{Path: "username", Op: "eq", Value: "bjensen"},
+		},
+		{
+			name:   "emails.value co",
+			filter: `emails.value co "@example.com"`,
+			want:   &AttrExpr{Path: "emails.value", Op: "co", Value: "@example.com"},
+		},
+		{
+			name:   "schema qualified attribute",
+			filter: `urn:ietf:params:scim:schemas:core:2.0:User:userName sw "J"`,
+			want:   &AttrExpr{Path: "username", Op: "sw", Value: "J"},
+		},
+		{
+			name:   "boolean and presence",
+			filter: `active eq true and externalId pr`,
+			want: &LogicalExpr{
+				Op:    "and",
+				Left:  &AttrExpr{Path: "active", Op: "eq", Value: true},
+				Right: &AttrExpr{Path: "externalid", Op: "pr"},
+			},
+		},
+		{
+			name:   "and binds tighter than or",
+			filter: `userName eq "a" or userName eq "b" and active eq false`,
+			want: &LogicalExpr{
+				Op:   "or",
+				Left: &AttrExpr{Path: "username", Op: "eq", Value: "a"},
+				Right: &LogicalExpr{
+					Op:    "and",
+					Left:  &AttrExpr{Path: "username", Op: "eq", Value: "b"},
+					Right: &AttrExpr{Path: "active", Op: "eq", Value: false},
+				},
+			},
+		},
+		{
+			name:   "value path with not",
+			filter: `not (emails[type eq "work" and value ew ".org"])`,
+			want: &NotExpr{Expr: &ValuePathExpr{
+				Attr: "emails",
+				Filter: &LogicalExpr{
+					Op:    "and",
+					Left:  &AttrExpr{Path: "type", Op: "eq", Value: "work"},
+					Right: &AttrExpr{Path: "value", Op: "ew", Value: ".org"},
+				},
+			}},
+		},
+		{
+			name:   "escaped quote in string",
+			filter: `displayName eq "say \"hi\""`,
+			want:   &AttrExpr{Path: "displayname", Op: "eq", Value: `say "hi"`},
+		},
+		{name: "unknown operator", filter: `userName like "x"`, wantErr: true},
+		{name: "missing value", filter: `userName eq`, wantErr: true},
+		{name: "unterminated string", filter: `userName eq "x`, wantErr: true},
+		{name: "unbalanced parenthesis", filter: `(userName eq "x"`, wantErr: true},
+		{name: "trailing tokens", filter: `userName eq "x" "y"`, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseFilter(tt.filter)
+			if tt.wantErr {
+				require.ErrorIs(t, err, ErrInvalidFilter)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestMatch(t *testing.T) {
+	var user map[string]any
+	require.NoError(t, json.Unmarshal([]byte(`{
+		"userName": "bjensen",
+		"active": true,
+		"emails": [
+			{"value": "bjensen@example.com", "type": "work"},
+			{"value": "babs@jensen.org", "type": "home"}
+		],
+		"meta": {"created": "2024-01-02T03:04:05Z"}
+	}`), &user))
+
+	tests := []struct {
+		filter string
+		want   bool
+	}{
+		{`userName eq "BJENSEN"`, true},
+		{`userName ne "bjensen"`, false},
+		{`emails co "jensen.org"`, true},
+		{`emails.value co "@example.com"`, true},
+		{`emails[type eq "home" and value sw "babs"]`, true},
+		{`emails[type eq "work" and value sw "babs"]`, false},
+		{`active eq false`, false},
+		{`not (active eq false)`, true},
+		{`externalId pr`, false},
+		{`meta.created gt "2023-12-31T00:00:00Z"`, true},
+		{`meta.created lt "2023-12-31T00:00:00Z"`, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.filter, func(t *testing.T) {
+			expr, err := ParseFilter(tt.filter)
+			require.NoError(t, err)
+			require.Equal(t, tt.want, Match(expr, user))
+		})
+	}
+}
@@ -0,0 +1,250 @@
+package scim
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+type PatchRequest struct {
+	Schemas    []string         `json:"schemas"`
+	Operations []PatchOperation `json:"Operations"`
+}
+
+type PatchOperation struct {
+	Op    string          `json:"op"`
+	Path  string          `json:"path,omitempty"`
+	Value json.RawMessage `json:"value,omitempty"`
+}
+
+type patchPath struct {
+	attr   string
+	filter Expr
+	sub    string
+}
+
+func parsePatchPath(path string) (*patchPath, error) {
+	head, rest, hasFilter := strings.Cut(path, "[")
+	head = normalizePath(strings.TrimSpace(head))
+	if head == "" {
+		return nil, fmt.Errorf("%w: %q", ErrInvalidPath, path)
+	}
+
+	if !hasFilter {
+		attr, sub, _ := strings.Cut(head, ".")
+		return &patchPath{attr: attr, sub: sub}, nil
+	}
+
+	end := strings.LastIndex(rest, "]")
+	if end == -1 {
+		return nil, fmt.Errorf("%w: %q", ErrInvalidPath, path)
+	}
+	filter, err := ParseFilter(rest[:end])
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidPath, err)
+	}
+	p := &patchPath{attr: head, filter: filter}
+	if tail := rest[end+1:]; tail != "" {
+		if !strings.HasPrefix(tail, ".") {
+			return nil, fmt.Errorf("%w: %q", ErrInvalidPath, path)
+		}
+		p.sub = strings.ToLower(tail[1:])
+	}
+	return p, nil
+}
+
+// ApplyPatch applies PATCH operations (RFC 7644 section 3.5.2) to a decoded JSON resource in place.
+func ApplyPatch(resource map[string]any, ops []PatchOperation) error {
+	for _, op := range ops {
+		var value any
+		if len(op.Value) > 0 {
+			if err := json.Unmarshal(op.Value, &value); err != nil {
+				return fmt.Errorf("%w: %v", ErrInvalidSyntax, err)
+			}
+		}
+
+		kind := strings.ToLower(op.Op)
+		if kind != "add" && kind != "replace" && kind != "remove" {
+			return fmt.Errorf("%w: unknown op %q", ErrInvalidSyntax, op.Op)
+		}
+
+		if op.Path == "" {
+			if kind == "remove" {
+				return fmt.Errorf("%w: remove requires a path", ErrNoTarget)
+			}
+			values, ok := value.(map[string]any)
+			if !ok {
+				return fmt.Errorf("%w: %s without path requires an object value", ErrInvalidValue, op.Op)
+			}
+			for k, v := range values {
+				if strings.EqualFold(k, "schemas") {
+					continue
+				}
+				p, err := parsePatchPath(k)
+				if err != nil {
+					return err
+				}
+				if err := applyOp(resource, kind, p, v); err != nil {
+					return err
+				}
+			}
+			continue
+		}
+
+		p, err := parsePatchPath(op.Path)
+		if err != nil {
+			return err
+		}
+		if err := applyOp(resource, kind, p, value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func applyOp(resource map[string]any, kind string, p *patchPath, value any) error {
+	if p.attr == "id" || p.attr == "meta" || p.attr == "schemas" {
+		return fmt.Errorf("%w: %s", ErrMutability, p.attr)
+	}
+	key := fieldKey(resource, p.attr)
+
+	if p.filter != nil {
+		items, _ := resource[key].([]any)
+		matched := false
+		kept := items[:0:0]
+		for _, item := range items {
+			m, ok := item.(map[string]any)
+			if !ok || !Match(p.filter, m) {
+				kept = append(kept, item)
+				continue
+			}
+			matched = true
+			switch {
+			case kind == "remove" && p.sub == "":
+				continue
+			case kind == "remove":
+				delete(m, fieldKey(m, p.sub))
+			case p.sub != "":
+				m[fieldKey(m, p.sub)] = value
+			default:
+				replacement, ok := value.(map[string]any)
+				if !ok {
+					return fmt.Errorf("%w: expected object for %s", ErrInvalidValue, p.attr)
+				}
+				for k, v := range replacement {
+					m[fieldKey(m, k)] = v
+				}
+			}
+			kept = append(kept, m)
+		}
+		if !matched {
+			if kind == "remove" {
+				return nil
+			}
+			return fmt.Errorf("%w: no values of %s match filter", ErrNoTarget, p.attr)
+		}
+		resource[key] = kept
+		return nil
+	}
+
+	if p.sub != "" {
+		m, ok := resource[key].(map[string]any)
+		if !ok {
+			if kind == "remove" {
+				return nil
+			}
+			m = map[string]any{}
+			resource[key] = m
+		}
+		if kind == "remove" {
+			delete(m, fieldKey(m, p.sub))
+		} else {
+			m[fieldKey(m, p.sub)] = value
+		}
+		return nil
+	}
+
+	current, exists := resource[key]
+	switch kind {
+	case "remove":
+		items, isList := current.([]any)
+		if value == nil || !isList {
+			delete(resource, key)
+			return nil
+		}
+		resource[key] = removeValues(items, asList(value))
+	case "add":
+		if items, isList := current.([]any); isList && exists {
+			resource[key] = appendValues(items, asList(value))
+			return nil
+		}
+		if m, isMap := current.(map[string]any); isMap {
+			if add, ok := value.(map[string]any); ok {
+				for k, v := range add {
+					m[fieldKey(m, k)] = v
+				}
+				return nil
+			}
+		}
+		resource[key] = value
+	case "replace":
+		resource[key] = value
+	}
+	return nil
+}
+
+func asList(v any) []any {
+	if items, ok := v.([]any); ok {
+		return items
+	}
+	return []any{v}
+}
+
+func appendValues(items, add []any) []any {
+	for _, v := range add {
+		if !containsValue(items, v) {
+			items = append(items, v)
+		}
+	}
+	return items
+}
+
+func removeValues(items, remove []any) []any {
+	kept := items[:0:0]
+	for _, item := range items {
+		if !containsValue(remove, item) {
+			kept = append(kept, item)
+		}
+	}
+	return kept
+}
+
+// containsValue compares complex values by their "value" sub-attribute, which
+// is how members and emails are identified.
+func containsValue(items []any, v any) bool {
+	for _, item := range items {
+		a, aok := item.(map[string]any)
+		b, bok := v.(map[string]any)
+		if aok && bok {
+			av, _ := getField(a, "value")
+			bv, _ := getField(b, "value")
+			if av != nil && reflect.DeepEqual(av, bv) {
+				return true
+			}
+		}
+		if reflect.DeepEqual(item, v) {
+			return true
+		}
+	}
+	return false
+}
+
+func fieldKey(m map[string]any, name string) string {
+	for k := range m {
+		if strings.EqualFold(k, name) {
+			return k
+		}
+	}
+	return name
+}
@@ -0,0 +1,115 @@
+package scim
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestApplyPatch(t *testing.T) {
+	const group = `{
+		"displayName": "Engineering",
+		"members": [{"value": "1"}, {"value": "2"}]
+	}`
+	const user = `{
+		"userName": "bjensen",
+		"active": true,
+		"emails": [{"value": "bjensen@example.com", "type": "work", "primary": true}]
+	}`
+
+	tests := []struct {
+		name     string
+		resource string
+		ops      string
+		want     string
+		wantErr  error
+	}{
+		{
+			name:     "replace active by path",
+			resource: user,
+			ops:      `[{"op": "replace", "path": "active", "value": false}]`,
+			want:     `{"userName": "bjensen", "active": false, "emails": [{"value": "bjensen@example.com", "type": "work", "primary": true}]}`,
+		},
+		{
+			name:     "replace without path",
+			resource: user,
+			ops:      `[{"op": "Replace", "value": {"active": false, "userName": "babs"}}]`,
+			want:     `{"userName": "babs", "active": false, "emails": [{"value": "bjensen@example.com", "type": "work", "primary": true}]}`,
+		},
+		{
+			name:     "replace sub-attribute selected by filter",
+			resource: user,
+			ops:      `[{"op": "replace", "path": "emails[type eq \"work\"].value", "value": "babs@example.com"}]`,
+			want:     `{"userName": "bjensen", "active": true, "emails": [{"value": "babs@example.com", "type": "work", "primary": true}]}`,
+		},
+		{
+			name:     "add members skips duplicates",
+			resource: group,
+			ops:      `[{"op": "add", "path": "members", "value": [{"value": "2"}, {"value": "3"}]}]`,
+			want:     `{"displayName": "Engineering", "members": [{"value": "1"}, {"value": "2"}, {"value": "3"}]}`,
+		},
+		{
+			name:     "remove member by filter",
+			resource: group,
+			ops:      `[{"op": "remove", "path": "members[value eq \"1\"]"}]`,
+			want:     `{"displayName": "Engineering", "members": [{"value": "2"}]}`,
+		},
+		{
+			name:     "remove member by value",
+			resource: group,
+			ops:      `[{"op": "remove", "path": "members", "value": [{"value": "2"}]}]`,
+			want:     `{"displayName": "Engineering", "members": [{"value": "1"}]}`,
+		},
+		{
+			name:     "remove whole attribute",
+			resource: group,
+			ops:      `[{"op": "remove", "path": "members"}]`,
+			want:     `{"displayName": "Engineering"}`,
+		},
+		{
+			name:     "remove without path",
+			resource: group,
+			ops:      `[{"op": "remove"}]`,
+			wantErr:  ErrNoTarget,
+		},
+		{
+			name:     "replace with unmatched filter",
+			resource: group,
+			ops:      `[{"op": "replace", "path": "members[value eq \"9\"]", "value": {"value": "10"}}]`,
+			wantErr:  ErrNoTarget,
+		},
+		{
+			name:     "id is immutable",
+			resource: user,
+			ops:      `[{"op": "replace", "path": "id", "value": "5"}]`,
+			wantErr:  ErrMutability,
+		},
+		{
+			name:     "unknown op",
+			resource: user,
+			ops:      `[{"op": "move", "path": "userName", "value": "x"}]`,
+			wantErr:  ErrInvalidSyntax,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var resource map[string]any
+			require.NoError(t, json.Unmarshal([]byte(tt.resource), &resource))
+			var ops []PatchOperation
+			require.NoError(t, json.Unmarshal([]byte(tt.ops), &ops))
+
+			err := ApplyPatch(resource, ops)
+			if tt.wantErr != nil {
+				require.ErrorIs(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+
+			got, err := json.Marshal(resource)
+			require.NoError(t, err)
+			require.JSONEq(t, tt.want, string(got))
+		})
+	}
+}
@@ -0,0 +1,153 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"time"
+
+	"github.com/Atmosfr/user-service/internal/models"
+	"github.com/Atmosfr/user-service/internal/repository"
+	"golang.org/x/crypto/bcrypt"
+)
+
+const (
+	// DeletionGracePeriod is how long a deleted account can be restored
+	// before its data is purged.
+	DeletionGracePeriod = 30 * 24 * time.Hour
+	// purgeBatchSize bounds the accounts purged in one transaction.
+	purgeBatchSize = 100
+)
+
+var ErrRestorePeriodOver = errors.New("the account can no longer be restored")
+
+// AccountDeletionService lets users delete their own account. Deleted accounts
+// cannot sign in and are purged once the grace period is over, until then the
+// user can restore them.
+type AccountDeletionService interface {
+	// Delete checks the user's password and deletes the account. It returns
+	// until when the account can be restored.
+	Delete(ctx context.Context, user *models.User, password string) (time.Time, error)
+	// Restore reactivates the account of email if its owner deleted it.
+	Restore(ctx context.Context, email, password string) (*models.User, error)
+	// Purge irreversibly anonymizes the accounts whose grace period is over.
+	Purge(ctx context.Context) (int, error)
+	// Run purges accounts every interval until ctx is done.
+	Run(ctx context.Context, interval time.Duration)
+}
+
+type accountDeletionService struct {
+	repo          repository.AccountDeletionRepository
+	states        repository.AccountStateRepository
+	authenticator Authenticator
+	avatars       AvatarService
+	audit         AuditLog
+	now           func() time.Time
+}
+
+func (s *accountDeletionService) Delete(ctx context.Context, user *models.User, password string) (time.Time, error) {
+	authenticated, err := s.authenticator.Authenticate(ctx, user.Email, password)
+	if err != nil {
+		if errors.Is(err, repository.ErrUserNotFound) {
+			return time.Time{}, repository.ErrInvalidPassword
+		}
+		return time.Time{}, err
+	}
+	if authenticated.ID != user.ID {
+		return time.Time{}, repository.ErrInvalidPassword
+	}
+	if err := s.repo.CheckDeletable(ctx, user.ID); err != nil {
+		return time.Time{}, err
+	}
+
+	deleted, err := s.states.Transition(ctx, &models.AccountStateChange{
+		UserID:  user.ID,
+		From:    authenticated.State,
+		To:      models.AccountDeleted,
+		Reason:  models.StateReasonUserRequest,
+		ActorID: &user.ID,
+	})
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	s.audit.Record(ctx, &models.AuditEvent{Action: models.AuditAccountDeleted, ActorID: &user.ID, TargetID: &user.ID})
+	slog.Info("account deleted", "user_id", user.ID)
+	return deleted.DeletedAt.Add(DeletionGracePeriod), nil
+}
+
+func (s *accountDeletionService) Restore(ctx context.Context, email, password string) (*models.User, error) {
+	user, err := s.repo.FindDeleted(ctx, email)
+	if err != nil {
+		if errors.Is(err, repository.ErrUserNotFound) {
+			return nil, repository.ErrInvalidPassword
+		}
+		return nil, err
+	}
+	// directory and SSO accounts have no hash and cannot be restored this way
+	if user.PasswordHash == "" || bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(password)) != nil {
+		return nil, repository.ErrInvalidPassword
+	}
+	// accounts deleted by an administrator or the identity provider stay deleted
+	if user.StateReason != models.StateReasonUserRequest || !s.now().Before(user.DeletedAt.Add(DeletionGracePeriod)) {
+		return nil, ErrRestorePeriodOver
+	}
+
+	restored, err := s.states.Transition(ctx, &models.AccountStateChange{
+		UserID:  user.ID,
+		From:    models.AccountDeleted,
+		To:      models.AccountActive,
+		Reason:  models.StateReasonUserRequest,
+		ActorID: &user.ID,
+	})
+	if err != nil {
+		return nil, err
+	}
+	restored.PasswordHash = ""
+
+	s.audit.Record(ctx, &models.AuditEvent{Action: models.AuditAccountRestored, ActorID: &user.ID, TargetID: &user.ID})
+	slog.Info("account restored", "user_id", user.ID)
+	return restored, nil
+}
+
+func (s *accountDeletionService) Purge(ctx context.Context) (int, error) {
+	deletedBefore := s.now().Add(-DeletionGracePeriod)
+	total := 0
+	for {
+		purged, err := s.repo.Purge(ctx, deletedBefore, purgeBatchSize)
+		if err != nil {
+			return total, err
+		}
+		for _, u := range purged {
+			if u.AvatarKey != "" {
+				s.avatars.DeleteFiles(ctx, u.AvatarKey)
+			}
+			s.audit.Record(ctx, &models.AuditEvent{Action: models.AuditAccountPurged, TargetID: &u.ID})
+			slog.Info("account purged", "user_id", u.ID)
+		}
+		total += len(purged)
+		if len(purged) < purgeBatchSize {
+			return total, nil
+		}
+	}
+}
+
+func (s *accountDeletionService) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if _, err := s.Purge(ctx); err != nil {
+				slog.Error("failed to purge deleted accounts", "err", err)
+			}
+		}
+	}
+}
+
+func NewAccountDeletionService(repo repository.AccountDeletionRepository, states repository.AccountStateRepository, authn Authenticator, avatars AvatarService, audit AuditLog) AccountDeletionService {
+	return &accountDeletionService{repo: repo, states: states, authenticator: authn, avatars: avatars, audit: audit, now: time.Now}
+}
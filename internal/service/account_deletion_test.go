@@ -0,0 +1,224 @@
+package service
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Atmosfr/user-service/internal/blob"
+	"github.com/Atmosfr/user-service/internal/models"
+	"github.com/Atmosfr/user-service/internal/repository"
+	"github.com/Atmosfr/user-service/internal/testutil"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/bcrypt"
+)
+
+type mockAccountDeletionRepo struct {
+	mock.Mock
+}
+
+func (m *mockAccountDeletionRepo) CheckDeletable(ctx context.Context, userID int64) error {
+	return m.Called(ctx, userID).Error(0)
+}
+
+func (m *mockAccountDeletionRepo) FindDeleted(ctx context.Context, email string) (*models.User, error) {
+	args := m.Called(ctx, email)
+	user, _ := args.Get(0).(*models.User)
+	return user, args.Error(1)
+}
+
+func (m *mockAccountDeletionRepo) Purge(ctx context.Context, deletedBefore time.Time, limit int) ([]repository.PurgedUser, error) {
+	args := m.Called(ctx, deletedBefore, limit)
+	purged, _ := args.Get(0).([]repository.PurgedUser)
+	return purged, args.Error(1)
+}
+
+var accountDeletionNow = time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC)
+
+func TestAccountDeletionService_Delete(t *testing.T) {
+	hash, err := bcrypt.GenerateFromPassword([]byte("StrongPass!12"), bcrypt.MinCost)
+	require.NoError(t, err)
+	bob := &models.User{ID: 10, Email: "bob@example.com", State: models.AccountActive}
+
+	tests := []struct {
+		name      string
+		password  string
+		setupMock func(repo *mockAccountDeletionRepo, states *mockAccountStateRepo)
+		wantErr   error
+	}{
+		{
+			name:     "success",
+			password: "StrongPass!12",
+			setupMock: func(repo *mockAccountDeletionRepo, states *mockAccountStateRepo) {
+				repo.On("CheckDeletable", mock.Anything, int64(10)).Return(nil)
+				deletedAt := accountDeletionNow
+				states.On("Transition", mock.Anything, mock.MatchedBy(func(c *models.AccountStateChange) bool {
+					return c.UserID == 10 && c.From == models.AccountActive && c.To == models.AccountDeleted &&
+						c.Reason == models.StateReasonUserRequest && *c.ActorID == 10
+				})).Return(&models.User{ID: 10, State: models.AccountDeleted, DeletedAt: &deletedAt}, nil)
+			},
+		},
+		{
+			name:      "wrong password",
+			password:  "WrongPass!12",
+			setupMock: func(repo *mockAccountDeletionRepo, states *mockAccountStateRepo) {},
+			wantErr:   repository.ErrInvalidPassword,
+		},
+		{
+			name:     "owns service accounts",
+			password: "StrongPass!12",
+			setupMock: func(repo *mockAccountDeletionRepo, states *mockAccountStateRepo) {
+				repo.On("CheckDeletable", mock.Anything, int64(10)).Return(repository.ErrUserOwnsServiceAccounts)
+			},
+			wantErr: repository.ErrUserOwnsServiceAccounts,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			users := new(testutil.MockUserRepo)
+			users.On("FindByEmail", mock.Anything, "bob@example.com").
+				Return(&models.User{ID: 10, Email: "bob@example.com", State: models.AccountActive, PasswordHash: string(hash)}, nil)
+			repo := new(mockAccountDeletionRepo)
+			states := new(mockAccountStateRepo)
+			audit := new(recordingAuditLog)
+			avatars := NewAvatarService(users, blob.NewFilesystemStore(t.TempDir()), "https://cdn.example.com")
+			svc := NewAccountDeletionService(repo, states, NewLocalAuthenticator(users), avatars, audit).(*accountDeletionService)
+			svc.now = func() time.Time { return accountDeletionNow }
+			tt.setupMock(repo, states)
+
+			restorableUntil, err := svc.Delete(context.Background(), bob, tt.password)
+			require.ErrorIs(t, err, tt.wantErr)
+			repo.AssertExpectations(t)
+			states.AssertExpectations(t)
+			if tt.wantErr != nil {
+				states.AssertNotCalled(t, "Transition", mock.Anything, mock.Anything)
+				require.Empty(t, audit.events)
+				return
+			}
+			require.Equal(t, accountDeletionNow.Add(DeletionGracePeriod), restorableUntil)
+			require.Equal(t, []string{models.AuditAccountDeleted}, audit.actions())
+		})
+	}
+}
+
+func TestAccountDeletionService_Restore(t *testing.T) {
+	hash, err := bcrypt.GenerateFromPassword([]byte("StrongPass!12"), bcrypt.MinCost)
+	require.NoError(t, err)
+	recent, old := accountDeletionNow.Add(-24*time.Hour), accountDeletionNow.Add(-DeletionGracePeriod)
+
+	tests := []struct {
+		name      string
+		email     string
+		password  string
+		setupMock func(states *mockAccountStateRepo)
+		wantErr   error
+	}{
+		{
+			name:     "success",
+			email:    "bob@example.com",
+			password: "StrongPass!12",
+			setupMock: func(states *mockAccountStateRepo) {
+				states.On("Transition", mock.Anything, mock.MatchedBy(func(c *models.AccountStateChange) bool {
+					return c.UserID == 10 && c.From == models.AccountDeleted && c.To == models.AccountActive
+				})).Return(&models.User{ID: 10, State: models.AccountActive, PasswordHash: string(hash)}, nil)
+			},
+		},
+		{
+			name:      "wrong password",
+			email:     "bob@example.com",
+			password:  "WrongPass!12",
+			setupMock: func(states *mockAccountStateRepo) {},
+			wantErr:   repository.ErrInvalidPassword,
+		},
+		{
+			// unknown addresses look like a wrong password
+			name:      "unknown address",
+			email:     "nobody@example.com",
+			password:  "StrongPass!12",
+			setupMock: func(states *mockAccountStateRepo) {},
+			wantErr:   repository.ErrInvalidPassword,
+		},
+		{
+			name:      "grace period over",
+			email:     "old@example.com",
+			password:  "StrongPass!12",
+			setupMock: func(states *mockAccountStateRepo) {},
+			wantErr:   ErrRestorePeriodOver,
+		},
+		{
+			// users cannot undo a deletion by an administrator
+			name:      "deleted by an administrator",
+			email:     "removed@example.com",
+			password:  "StrongPass!12",
+			setupMock: func(states *mockAccountStateRepo) {},
+			wantErr:   ErrRestorePeriodOver,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := new(mockAccountDeletionRepo)
+			repo.On("FindDeleted", mock.Anything, "bob@example.com").
+				Return(&models.User{ID: 10, State: models.AccountDeleted, StateReason: models.StateReasonUserRequest, PasswordHash: string(hash), DeletedAt: &recent}, nil)
+			repo.On("FindDeleted", mock.Anything, "old@example.com").
+				Return(&models.User{ID: 11, State: models.AccountDeleted, StateReason: models.StateReasonUserRequest, PasswordHash: string(hash), DeletedAt: &old}, nil)
+			repo.On("FindDeleted", mock.Anything, "removed@example.com").
+				Return(&models.User{ID: 12, State: models.AccountDeleted, StateReason: models.StateReasonAdminDeletion, PasswordHash: string(hash), DeletedAt: &recent}, nil)
+			repo.On("FindDeleted", mock.Anything, mock.Anything).Return(nil, repository.ErrUserNotFound)
+			states := new(mockAccountStateRepo)
+			audit := new(recordingAuditLog)
+			users := new(testutil.MockUserRepo)
+			avatars := NewAvatarService(users, blob.NewFilesystemStore(t.TempDir()), "https://cdn.example.com")
+			svc := NewAccountDeletionService(repo, states, NewLocalAuthenticator(users), avatars, audit).(*accountDeletionService)
+			svc.now = func() time.Time { return accountDeletionNow }
+			tt.setupMock(states)
+
+			user, err := svc.Restore(context.Background(), tt.email, tt.password)
+			require.ErrorIs(t, err, tt.wantErr)
+			states.AssertExpectations(t)
+			if tt.wantErr != nil {
+				states.AssertNotCalled(t, "Transition", mock.Anything, mock.Anything)
+				require.Empty(t, audit.events)
+				return
+			}
+			require.Equal(t, models.AccountActive, user.State)
+			require.Empty(t, user.PasswordHash)
+			require.Equal(t, []string{models.AuditAccountRestored}, audit.actions())
+		})
+	}
+}
+
+func TestAccountDeletionService_Purge(t *testing.T) {
+	ctx := context.Background()
+	repo := new(mockAccountDeletionRepo)
+	users := new(testutil.MockUserRepo)
+	store := blob.NewFilesystemStore(t.TempDir())
+	audit := new(recordingAuditLog)
+	avatars := NewAvatarService(users, store, "https://cdn.example.com", WithAvatarSizes(64))
+	svc := NewAccountDeletionService(repo, new(mockAccountStateRepo), NewLocalAuthenticator(users), avatars, audit).(*accountDeletionService)
+	svc.now = func() time.Time { return accountDeletionNow }
+	deletedBefore := accountDeletionNow.Add(-DeletionGracePeriod)
+
+	full := make([]repository.PurgedUser, purgeBatchSize)
+	for i := range full {
+		full[i] = repository.PurgedUser{ID: int64(i + 100)}
+	}
+	full[0].AvatarKey = "avatars/100/abc"
+	require.NoError(t, store.Put(ctx, "avatars/100/abc/64.jpg", strings.NewReader("jpeg"), 4, "image/jpeg"))
+
+	// a full batch means there may be more to purge
+	repo.On("Purge", mock.Anything, deletedBefore, purgeBatchSize).Return(full, nil).Once()
+	repo.On("Purge", mock.Anything, deletedBefore, purgeBatchSize).Return([]repository.PurgedUser{{ID: 7}}, nil).Once()
+
+	n, err := svc.Purge(ctx)
+	require.NoError(t, err)
+	require.Equal(t, purgeBatchSize+1, n)
+	require.Len(t, audit.events, purgeBatchSize+1)
+	require.Nil(t, audit.events[0].ActorID)
+	_, _, err = store.Get(ctx, "avatars/100/abc/64.jpg")
+	require.ErrorIs(t, err, blob.ErrNotFound)
+	repo.AssertExpectations(t)
+}
@@ -0,0 +1,201 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+	"unicode/utf8"
+
+	"github.com/Atmosfr/user-service/internal/models"
+	"github.com/Atmosfr/user-service/internal/repository"
+)
+
+// MaxStateNoteLength bounds the free-text note of a state change.
+const MaxStateNoteLength = 1000
+
+var (
+	ErrAccountSuspended           = errors.New("account is suspended")
+	ErrAccountBanned              = errors.New("account is banned")
+	ErrAccountPendingVerification = errors.New("account is pending email verification, follow the link sent to your address")
+	ErrAccountDeleted             = errors.New("account is deleted")
+
+	ErrInvalidAccountState     = errors.New("state must be one of active, suspended, banned or pending_verification")
+	ErrInvalidStateReason      = errors.New("reason must be one of spam, abuse, fraud, terms_violation, compromised, user_request or other")
+	ErrInvalidStateTransition  = errors.New("the account cannot be moved to this state")
+	ErrInvalidSuspensionExpiry = errors.New("expires_at must be in the future and is only allowed for suspensions")
+	ErrStateNoteTooLong        = errors.New("note must be at most 1000 characters")
+)
+
+// adminStates are the states administrators can move accounts to.
+var adminStates = map[string]bool{
+	models.AccountActive:              true,
+	models.AccountSuspended:           true,
+	models.AccountBanned:              true,
+	models.AccountPendingVerification: true,
+}
+
+var adminStateReasons = map[string]bool{
+	models.StateReasonSpam:           true,
+	models.StateReasonAbuse:          true,
+	models.StateReasonFraud:          true,
+	models.StateReasonTermsViolation: true,
+	models.StateReasonCompromised:    true,
+	models.StateReasonUserRequest:    true,
+	models.StateReasonOther:          true,
+}
+
+// CheckAccountState returns why user may not sign in at now, or nil. A
+// suspension that has run out no longer counts, even before it is lifted.
+func CheckAccountState(user *models.User, now time.Time) error {
+	switch user.State {
+	case models.AccountActive, "":
+		// users built in memory have no state until they are stored
+		return nil
+	case models.AccountSuspended:
+		if user.StateExpiresAt == nil {
+			return ErrAccountSuspended
+		}
+		if now.Before(*user.StateExpiresAt) {
+			return fmt.Errorf("%w until %s", ErrAccountSuspended, user.StateExpiresAt.UTC().Format(time.RFC3339))
+		}
+		return nil
+	case models.AccountBanned:
+		return ErrAccountBanned
+	case models.AccountPendingVerification:
+		return ErrAccountPendingVerification
+	case models.AccountDeleted:
+		return ErrAccountDeleted
+	}
+	return fmt.Errorf("unknown account state %q", user.State)
+}
+
+// AccountStateTransition is a state change requested by an administrator.
+// ExpiresAt ends a suspension, nil suspends indefinitely.
+type AccountStateTransition struct {
+	State     string
+	Reason    string
+	Note      string
+	ExpiresAt *time.Time
+}
+
+// AccountStateService moves accounts between states and lifts suspensions
+// once they expire.
+type AccountStateService interface {
+	Transition(ctx context.Context, actor *models.User, userID int64, t AccountStateTransition) (*models.User, error)
+	History(ctx context.Context, userID int64) ([]*models.AccountStateChange, error)
+	// ExpireSuspensions reactivates users whose suspension has run out.
+	ExpireSuspensions(ctx context.Context) (int, error)
+	// Run expires suspensions every interval until ctx is done.
+	Run(ctx context.Context, interval time.Duration)
+}
+
+type accountStateService struct {
+	repo  repository.AccountStateRepository
+	users repository.UserRepository
+	audit AuditLog
+	now   func() time.Time
+}
+
+func (s *accountStateService) validate(user *models.User, t AccountStateTransition) error {
+	if !adminStates[t.State] {
+		return ErrInvalidAccountState
+	}
+	if !adminStateReasons[t.Reason] {
+		return ErrInvalidStateReason
+	}
+	if utf8.RuneCountInString(t.Note) > MaxStateNoteLength {
+		return ErrStateNoteTooLong
+	}
+	if t.ExpiresAt != nil && (t.State != models.AccountSuspended || !t.ExpiresAt.After(s.now())) {
+		return ErrInvalidSuspensionExpiry
+	}
+
+	switch {
+	case user.State == models.AccountDeleted:
+		// deleted accounts are only restored by their owner
+		return ErrInvalidStateTransition
+	case user.State == t.State && t.State != models.AccountSuspended:
+		// suspending again changes the expiry, anything else would be a no-op
+		return ErrInvalidStateTransition
+	case t.State == models.AccountPendingVerification && user.EmailVerifiedAt != nil:
+		// a verified address cannot be verified again to leave the state
+		return ErrInvalidStateTransition
+	}
+	return nil
+}
+
+func (s *accountStateService) Transition(ctx context.Context, actor *models.User, userID int64, t AccountStateTransition) (*models.User, error) {
+	if actor.ID == userID {
+		return nil, ErrCannotChangeSelf
+	}
+	user, err := s.users.FindByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.validate(user, t); err != nil {
+		return nil, err
+	}
+
+	change := &models.AccountStateChange{
+		UserID:    userID,
+		From:      user.State,
+		To:        t.State,
+		Reason:    t.Reason,
+		Note:      t.Note,
+		ExpiresAt: t.ExpiresAt,
+		ActorID:   &actor.ID,
+	}
+	user, err = s.repo.Transition(ctx, change)
+	if err != nil {
+		return nil, err
+	}
+	user.PasswordHash = ""
+
+	details := map[string]string{"from": change.From, "to": change.To, "reason": change.Reason}
+	if change.ExpiresAt != nil {
+		details["expires_at"] = change.ExpiresAt.UTC().Format(time.RFC3339)
+	}
+	s.audit.Record(ctx, &models.AuditEvent{Action: models.AuditStateChanged, ActorID: &actor.ID, TargetID: &userID, Details: details})
+	slog.Info("account state changed", "user_id", userID, "from", change.From, "to", change.To, "reason", change.Reason, "actor_id", actor.ID)
+	return user, nil
+}
+
+func (s *accountStateService) History(ctx context.Context, userID int64) ([]*models.AccountStateChange, error) {
+	if _, err := s.users.FindByID(ctx, userID); err != nil {
+		return nil, err
+	}
+	return s.repo.History(ctx, userID)
+}
+
+func (s *accountStateService) ExpireSuspensions(ctx context.Context) (int, error) {
+	ids, err := s.repo.ExpireSuspensions(ctx, s.now())
+	if err != nil {
+		return 0, err
+	}
+	for _, id := range ids {
+		slog.Info("suspension expired", "user_id", id)
+	}
+	return len(ids), nil
+}
+
+func (s *accountStateService) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if _, err := s.ExpireSuspensions(ctx); err != nil {
+				slog.Error("failed to expire suspensions", "err", err)
+			}
+		}
+	}
+}
+
+func NewAccountStateService(repo repository.AccountStateRepository, users repository.UserRepository, audit AuditLog) AccountStateService {
+	return &accountStateService{repo: repo, users: users, audit: audit, now: time.Now}
+}
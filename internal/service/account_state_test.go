@@ -0,0 +1,152 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/Atmosfr/user-service/internal/models"
+	"github.com/Atmosfr/user-service/internal/testutil"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/bcrypt"
+)
+
+type mockAccountStateRepo struct {
+	mock.Mock
+}
+
+func (m *mockAccountStateRepo) Transition(ctx context.Context, change *models.AccountStateChange) (*models.User, error) {
+	args := m.Called(ctx, change)
+	user, _ := args.Get(0).(*models.User)
+	return user, args.Error(1)
+}
+
+func (m *mockAccountStateRepo) History(ctx context.Context, userID int64) ([]*models.AccountStateChange, error) {
+	args := m.Called(ctx, userID)
+	history, _ := args.Get(0).([]*models.AccountStateChange)
+	return history, args.Error(1)
+}
+
+func (m *mockAccountStateRepo) ExpireSuspensions(ctx context.Context, now time.Time) ([]int64, error) {
+	args := m.Called(ctx, now)
+	ids, _ := args.Get(0).([]int64)
+	return ids, args.Error(1)
+}
+
+func TestCheckAccountState(t *testing.T) {
+	now := time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC)
+	later, earlier := now.Add(time.Hour), now.Add(-time.Hour)
+
+	tests := []struct {
+		name    string
+		user    models.User
+		wantErr error
+	}{
+		{"active", models.User{State: models.AccountActive}, nil},
+		{"indefinite suspension", models.User{State: models.AccountSuspended}, ErrAccountSuspended},
+		{"running suspension", models.User{State: models.AccountSuspended, StateExpiresAt: &later}, ErrAccountSuspended},
+		{"expired suspension", models.User{State: models.AccountSuspended, StateExpiresAt: &earlier}, nil},
+		{"banned", models.User{State: models.AccountBanned}, ErrAccountBanned},
+		{"pending verification", models.User{State: models.AccountPendingVerification}, ErrAccountPendingVerification},
+		{"deleted", models.User{State: models.AccountDeleted}, ErrAccountDeleted},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := CheckAccountState(&tt.user, now)
+			if tt.wantErr == nil {
+				require.NoError(t, err)
+				return
+			}
+			require.ErrorIs(t, err, tt.wantErr)
+		})
+	}
+	require.ErrorContains(t, CheckAccountState(&models.User{State: models.AccountSuspended, StateExpiresAt: &later}, now), "until 2026-10-18T13:00:00Z")
+}
+
+func TestAccountStateService_Transition(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC)
+	until := now.Add(72 * time.Hour)
+	verifiedAt := now.Add(-24 * time.Hour)
+	admin := &models.User{ID: 1, Role: "admin", State: models.AccountActive}
+
+	users := new(testutil.MockUserRepo)
+	users.On("FindByID", mock.Anything, int64(7)).Return(&models.User{ID: 7, State: models.AccountActive, EmailVerifiedAt: &verifiedAt}, nil)
+	users.On("FindByID", mock.Anything, int64(8)).Return(&models.User{ID: 8, State: models.AccountBanned}, nil)
+	users.On("FindByID", mock.Anything, int64(9)).Return(&models.User{ID: 9, State: models.AccountDeleted}, nil)
+	repo := new(mockAccountStateRepo)
+	repo.On("Transition", mock.Anything, mock.MatchedBy(func(c *models.AccountStateChange) bool {
+		return c.UserID == 7 && c.From == models.AccountActive && c.To == models.AccountSuspended &&
+			c.Reason == models.StateReasonSpam && c.ExpiresAt.Equal(until) && *c.ActorID == 1
+	})).Return(&models.User{ID: 7, State: models.AccountSuspended, StateExpiresAt: &until, PasswordHash: "hash"}, nil).Once()
+
+	audit := new(recordingAuditLog)
+	svc := &accountStateService{repo: repo, users: users, audit: audit, now: func() time.Time { return now }}
+	user, err := svc.Transition(ctx, admin, 7, AccountStateTransition{State: models.AccountSuspended, Reason: models.StateReasonSpam, ExpiresAt: &until})
+	require.NoError(t, err)
+	require.Equal(t, models.AccountSuspended, user.State)
+	require.Empty(t, user.PasswordHash)
+	require.Len(t, audit.events, 1)
+	require.Equal(t, models.AuditStateChanged, audit.events[0].Action)
+	require.Equal(t, int64(1), *audit.events[0].ActorID)
+	require.Equal(t, int64(7), *audit.events[0].TargetID)
+	require.Equal(t, models.AccountSuspended, audit.events[0].Details["to"])
+
+	past := now.Add(-time.Minute)
+	tests := []struct {
+		name    string
+		userID  int64
+		t       AccountStateTransition
+		wantErr error
+	}{
+		{"self", 1, AccountStateTransition{State: models.AccountBanned, Reason: models.StateReasonSpam}, ErrCannotChangeSelf},
+		{"deleted is not an admin state", 7, AccountStateTransition{State: models.AccountDeleted, Reason: models.StateReasonSpam}, ErrInvalidAccountState},
+		{"system reason", 7, AccountStateTransition{State: models.AccountActive, Reason: models.StateReasonSuspensionExpired}, ErrInvalidStateReason},
+		{"expiry in the past", 7, AccountStateTransition{State: models.AccountSuspended, Reason: models.StateReasonSpam, ExpiresAt: &past}, ErrInvalidSuspensionExpiry},
+		{"expiry on a ban", 7, AccountStateTransition{State: models.AccountBanned, Reason: models.StateReasonSpam, ExpiresAt: &until}, ErrInvalidSuspensionExpiry},
+		{"ban twice", 8, AccountStateTransition{State: models.AccountBanned, Reason: models.StateReasonFraud}, ErrInvalidStateTransition},
+		{"verified address", 7, AccountStateTransition{State: models.AccountPendingVerification, Reason: models.StateReasonCompromised}, ErrInvalidStateTransition},
+		{"restore deleted", 9, AccountStateTransition{State: models.AccountActive, Reason: models.StateReasonOther}, ErrInvalidStateTransition},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := svc.Transition(ctx, admin, tt.userID, tt.t)
+			require.ErrorIs(t, err, tt.wantErr)
+		})
+	}
+	repo.AssertExpectations(t)
+}
+
+func TestAccountStateService_ExpireSuspensions(t *testing.T) {
+	now := time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC)
+	repo := new(mockAccountStateRepo)
+	repo.On("ExpireSuspensions", mock.Anything, now).Return([]int64{7, 8}, nil)
+	svc := &accountStateService{repo: repo, users: new(testutil.MockUserRepo), audit: new(recordingAuditLog), now: func() time.Time { return now }}
+
+	n, err := svc.ExpireSuspensions(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, 2, n)
+}
+
+func TestUserService_LoginEnforcesAccountState(t *testing.T) {
+	ctx := context.Background()
+	hash, err := bcrypt.GenerateFromPassword([]byte("StrongPass!12"), bcrypt.MinCost)
+	require.NoError(t, err)
+
+	repo := new(testutil.MockUserRepo)
+	repo.On("FindByEmail", mock.Anything, "banned@example.com").Return(&models.User{ID: 1, Email: "banned@example.com", PasswordHash: string(hash), State: models.AccountBanned}, nil)
+	repo.On("FindByEmail", mock.Anything, "pending@example.com").Return(&models.User{ID: 2, Email: "pending@example.com", PasswordHash: string(hash), State: models.AccountPendingVerification}, nil)
+	mailer := &recordingMailer{}
+	svc := NewUserService(repo, WithEmailVerification(NewEmailVerificationService(repo, nil, mailer, []byte("verify-secret"))))
+
+	_, err = svc.Login(ctx, "banned@example.com", "StrongPass!12")
+	require.ErrorIs(t, err, ErrAccountBanned)
+	require.Empty(t, mailer.sent)
+
+	// a fresh link is the way out of pending verification
+	_, err = svc.Login(ctx, "pending@example.com", "StrongPass!12")
+	require.ErrorIs(t, err, ErrAccountPendingVerification)
+	require.Len(t, mailer.sent, 1)
+	require.Equal(t, "pending@example.com", mailer.sent[0].To)
+}
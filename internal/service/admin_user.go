@@ -0,0 +1,179 @@
+package service
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"log/slog"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Atmosfr/user-service/internal/models"
+	"github.com/Atmosfr/user-service/internal/repository"
+)
+
+const (
+	DefaultUserPageSize = 50
+	MaxUserPageSize     = 200
+)
+
+var (
+	ErrInvalidCursor       = errors.New("cursor is invalid")
+	ErrInvalidCreatedRange = errors.New("created_before must be after created_after")
+	ErrCannotChangeSelf    = errors.New("administrators cannot deactivate, demote or delete themselves")
+)
+
+// UserPage is one page of a user listing. NextCursor is empty on the last page.
+type UserPage struct {
+	Users      []*models.User
+	NextCursor string
+}
+
+// AdminUserPatch holds the fields an administrator changes, nil fields are
+// left as they are. Account states change through AccountStateService.
+type AdminUserPatch struct {
+	Role *string
+}
+
+// AdminUserService lets administrators find and manage any user.
+type AdminUserService interface {
+	// List pages through the users matching filter, newest first. cursor is
+	// empty for the first page and NextCursor of the previous page after that.
+	List(ctx context.Context, filter repository.UserFilter, cursor string, limit int) (*UserPage, error)
+	Get(ctx context.Context, id int64) (*models.User, error)
+	Update(ctx context.Context, actor *models.User, id int64, patch AdminUserPatch) (*models.User, error)
+	Delete(ctx context.Context, actor *models.User, id int64) error
+	// Search finds users whose email or username resembles query, tolerating
+	// typos and left out characters, best match first.
+	Search(ctx context.Context, query string, limit int) ([]*UserSearchResult, error)
+}
+
+type adminUserService struct {
+	users repository.UserRepository
+	audit AuditLog
+}
+
+// encodeUserCursor makes an opaque cursor of the position of user.
+func encodeUserCursor(user *models.User) string {
+	raw := strconv.FormatInt(user.CreatedAt.UnixMicro(), 10) + ":" + strconv.FormatInt(user.ID, 10)
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+func decodeUserCursor(cursor string) (*repository.UserCursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	micros, id, ok := strings.Cut(string(raw), ":")
+	if !ok {
+		return nil, ErrInvalidCursor
+	}
+	createdAt, err := strconv.ParseInt(micros, 10, 64)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	userID, err := strconv.ParseInt(id, 10, 64)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	return &repository.UserCursor{CreatedAt: time.UnixMicro(createdAt).UTC(), ID: userID}, nil
+}
+
+func (s *adminUserService) List(ctx context.Context, filter repository.UserFilter, cursor string, limit int) (*UserPage, error) {
+	for _, f := range filter.Metadata {
+		if !validVisibility(f.Visibility) || len(f.Path) == 0 {
+			return nil, ErrInvalidMetadataFilter
+		}
+	}
+	if filter.CreatedAfter != nil && filter.CreatedBefore != nil && !filter.CreatedBefore.After(*filter.CreatedAfter) {
+		return nil, ErrInvalidCreatedRange
+	}
+	filter.EmailDomain = strings.TrimPrefix(strings.TrimSpace(filter.EmailDomain), "@")
+	filter.Query = strings.TrimSpace(filter.Query)
+
+	var after *repository.UserCursor
+	if cursor != "" {
+		var err error
+		if after, err = decodeUserCursor(cursor); err != nil {
+			return nil, err
+		}
+	}
+	if limit <= 0 {
+		limit = DefaultUserPageSize
+	}
+	limit = min(limit, MaxUserPageSize)
+
+	// one extra row tells whether another page follows
+	users, err := s.users.List(ctx, filter, after, limit+1)
+	if err != nil {
+		return nil, err
+	}
+	page := &UserPage{Users: users}
+	if len(users) > limit {
+		page.Users = users[:limit]
+		page.NextCursor = encodeUserCursor(page.Users[limit-1])
+	}
+	for _, user := range page.Users {
+		user.PasswordHash = ""
+	}
+	return page, nil
+}
+
+func (s *adminUserService) Get(ctx context.Context, id int64) (*models.User, error) {
+	user, err := s.users.FindByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	user.PasswordHash = ""
+	return user, nil
+}
+
+func (s *adminUserService) Update(ctx context.Context, actor *models.User, id int64, patch AdminUserPatch) (*models.User, error) {
+	user, err := s.users.FindByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if patch.Role == nil || *patch.Role == user.Role {
+		user.PasswordHash = ""
+		return user, nil
+	}
+	if actor.ID == user.ID {
+		// a typo must not lock the last administrator out
+		return nil, ErrCannotChangeSelf
+	}
+
+	from := user.Role
+	if err := s.users.UpdateRole(ctx, id, *patch.Role); err != nil {
+		return nil, err
+	}
+	s.audit.Record(ctx, &models.AuditEvent{
+		Action:   models.AuditRoleChanged,
+		ActorID:  &actor.ID,
+		TargetID: &id,
+		Details:  map[string]string{"from": from, "to": *patch.Role},
+	})
+	if user, err = s.users.FindByID(ctx, id); err != nil {
+		return nil, err
+	}
+	user.PasswordHash = ""
+
+	slog.Info("user updated by admin", "user_id", user.ID, "actor_id", actor.ID, "role", user.Role)
+	return user, nil
+}
+
+func (s *adminUserService) Delete(ctx context.Context, actor *models.User, id int64) error {
+	if actor.ID == id {
+		return ErrCannotChangeSelf
+	}
+	if err := s.users.Delete(ctx, id); err != nil {
+		return err
+	}
+	s.audit.Record(ctx, &models.AuditEvent{Action: models.AuditUserDeleted, ActorID: &actor.ID, TargetID: &id})
+	slog.Info("user deleted by admin", "user_id", id, "actor_id", actor.ID)
+	return nil
+}
+
+func NewAdminUserService(users repository.UserRepository, audit AuditLog) AdminUserService {
+	return &adminUserService{users: users, audit: audit}
+}
@@ -0,0 +1,131 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/Atmosfr/user-service/internal/models"
+	"github.com/Atmosfr/user-service/internal/repository"
+	"github.com/Atmosfr/user-service/internal/testutil"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAdminUserService_ListPages(t *testing.T) {
+	ctx := context.Background()
+	repo := new(testutil.MockUserRepo)
+	svc := NewAdminUserService(repo, new(recordingAuditLog))
+
+	created := time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC)
+	users := []*models.User{
+		{ID: 3, CreatedAt: created, PasswordHash: "hash"},
+		{ID: 2, CreatedAt: created},
+		{ID: 1, CreatedAt: created.Add(-time.Hour)},
+	}
+	active := true
+	filter := repository.UserFilter{IsActive: &active, EmailDomain: "example.com", Query: "bob"}
+	repo.On("List", mock.Anything, filter, (*repository.UserCursor)(nil), 3).Return(users, nil).Once()
+
+	page, err := svc.List(ctx, repository.UserFilter{IsActive: &active, EmailDomain: " @example.com", Query: " bob "}, "", 2)
+	require.NoError(t, err)
+	require.Len(t, page.Users, 2)
+	require.Empty(t, page.Users[0].PasswordHash)
+	require.NotEmpty(t, page.NextCursor)
+
+	// the cursor continues behind the last user of the page, ties broken by id
+	repo.On("List", mock.Anything, filter, &repository.UserCursor{CreatedAt: created, ID: 2}, 3).Return(users[2:], nil).Once()
+	page, err = svc.List(ctx, filter, page.NextCursor, 2)
+	require.NoError(t, err)
+	require.Len(t, page.Users, 1)
+	require.Empty(t, page.NextCursor)
+	repo.AssertExpectations(t)
+}
+
+func TestAdminUserService_ListRejectsFilters(t *testing.T) {
+	ctx := context.Background()
+	svc := NewAdminUserService(new(testutil.MockUserRepo), new(recordingAuditLog))
+	now := time.Now()
+
+	_, err := svc.List(ctx, repository.UserFilter{}, "not a cursor", 0)
+	require.ErrorIs(t, err, ErrInvalidCursor)
+	_, err = svc.List(ctx, repository.UserFilter{CreatedAfter: &now, CreatedBefore: &now}, "", 0)
+	require.ErrorIs(t, err, ErrInvalidCreatedRange)
+	_, err = svc.List(ctx, repository.UserFilter{Metadata: []repository.MetadataFilter{{Visibility: "secret", Path: []string{"x"}, Value: json.RawMessage(`1`)}}}, "", 0)
+	require.ErrorIs(t, err, ErrInvalidMetadataFilter)
+}
+
+func TestAdminUserService_Update(t *testing.T) {
+	ctx := context.Background()
+	repo := new(testutil.MockUserRepo)
+	audit := new(recordingAuditLog)
+	svc := NewAdminUserService(repo, audit)
+	admin := &models.User{ID: 1, Role: "admin", IsActive: true}
+	repo.On("FindByID", mock.Anything, int64(1)).Return(&models.User{ID: 1, Role: "admin", IsActive: true}, nil)
+	repo.On("FindByID", mock.Anything, int64(7)).Return(&models.User{ID: 7, Role: "user", PasswordHash: "hash"}, nil).Once()
+	repo.On("UpdateRole", mock.Anything, int64(7), "support").Return(nil).Once()
+	repo.On("FindByID", mock.Anything, int64(7)).Return(&models.User{ID: 7, Role: "support", PasswordHash: "hash"}, nil).Once()
+
+	role := "support"
+	user, err := svc.Update(ctx, admin, 7, AdminUserPatch{Role: &role})
+	require.NoError(t, err)
+	require.Equal(t, "support", user.Role)
+	require.Empty(t, user.PasswordHash)
+	require.Equal(t, []string{models.AuditRoleChanged}, audit.actions())
+	require.Equal(t, map[string]string{"from": "user", "to": "support"}, audit.events[0].Details)
+
+	_, err = svc.Update(ctx, admin, 1, AdminUserPatch{Role: &role})
+	require.ErrorIs(t, err, ErrCannotChangeSelf)
+	// keeping the own role is fine
+	same := "admin"
+	_, err = svc.Update(ctx, admin, 1, AdminUserPatch{Role: &same})
+	require.NoError(t, err)
+	require.ErrorIs(t, svc.Delete(ctx, admin, 1), ErrCannotChangeSelf)
+
+	repo.On("Delete", mock.Anything, int64(7)).Return(repository.ErrUserOwnsServiceAccounts).Once()
+	require.ErrorIs(t, svc.Delete(ctx, admin, 7), repository.ErrUserOwnsServiceAccounts)
+	repo.On("Delete", mock.Anything, int64(7)).Return(nil).Once()
+	require.NoError(t, svc.Delete(ctx, admin, 7))
+	require.Equal(t, []string{models.AuditRoleChanged, models.AuditUserDeleted}, audit.actions())
+	repo.AssertExpectations(t)
+}
+
+func TestAdminUserService_Search(t *testing.T) {
+	ctx := context.Background()
+	repo := new(testutil.MockUserRepo)
+	svc := NewAdminUserService(repo, new(recordingAuditLog))
+	repo.On("Search", mock.Anything, "jon smth", MaxSearchLimit).Return([]*repository.UserMatch{
+		{User: &models.User{ID: 7, Email: "jon.smith@example.com", Username: "jsmith", PasswordHash: "hash"}, Score: 0.75},
+	}, nil)
+
+	results, err := svc.Search(ctx, "  jon smth ", 1000)
+	require.NoError(t, err)
+	require.Len(t, results, 1)
+	require.Empty(t, results[0].User.PasswordHash)
+	require.Equal(t, 0.75, results[0].Score)
+	// "jon" as a whole, then "sm" and "th" of smith through the trigrams of smth
+	require.Equal(t, []TextRange{{0, 3}, {4, 6}, {7, 9}}, results[0].Highlights["email"])
+	require.Equal(t, []TextRange{{0, 1}, {4, 6}}, results[0].Highlights["username"])
+
+	_, err = svc.Search(ctx, " jo ", 0)
+	require.ErrorIs(t, err, ErrSearchQueryTooShort)
+}
+
+func TestHighlight(t *testing.T) {
+	tests := []struct {
+		query string
+		s     string
+		want  []TextRange
+	}{
+		{"smith", "John.Smith@example.com", []TextRange{{5, 10}}},
+		{"müller", "Jörg-Müller", []TextRange{{5, 11}}},
+		{"xyz", "jon", []TextRange{}},
+		{"jon", "", []TextRange{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.query+"/"+tt.s, func(t *testing.T) {
+			require.Equal(t, tt.want, highlight(tt.s, queryTrigrams(tt.query)))
+		})
+	}
+}
@@ -0,0 +1,217 @@
+package service
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"log/slog"
+	"strconv"
+	"time"
+
+	"github.com/Atmosfr/user-service/internal/models"
+	"github.com/Atmosfr/user-service/internal/repository"
+)
+
+const (
+	DefaultAuditPageSize = 50
+	MaxAuditPageSize     = 200
+
+	// the sizes of the audit_events columns
+	maxAuditIPLength        = 45
+	maxAuditUserAgentLength = 512
+
+	auditVerifyBatchSize = 1000
+)
+
+var ErrInvalidAuditRange = errors.New("to must be after from")
+
+// RequestInfo identifies the HTTP request an action was taken in.
+type RequestInfo struct {
+	ID        string
+	IP        string
+	UserAgent string
+}
+
+type requestInfoKey struct{}
+
+// ContextWithRequestInfo returns a copy of ctx carrying info for the audit log.
+func ContextWithRequestInfo(ctx context.Context, info RequestInfo) context.Context {
+	return context.WithValue(ctx, requestInfoKey{}, info)
+}
+
+// RequestInfoFromContext returns the request info of ctx, empty outside of requests.
+func RequestInfoFromContext(ctx context.Context) RequestInfo {
+	info, _ := ctx.Value(requestInfoKey{}).(RequestInfo)
+	return info
+}
+
+// AuditLog records security relevant events.
+type AuditLog interface {
+	// Record appends event with the request info of ctx. Failures are
+	// logged, the action the event is about has already happened.
+	Record(ctx context.Context, event *models.AuditEvent)
+}
+
+// AuditPage is one page of audit events. NextCursor is empty on the last page.
+type AuditPage struct {
+	Events     []*models.AuditEvent
+	NextCursor string
+}
+
+// AuditVerification is the result of checking the hash chain. BrokenAt is
+// the first event whose hash or link to its predecessor does not match.
+type AuditVerification struct {
+	Checked  int64  `json:"checked"`
+	Valid    bool   `json:"valid"`
+	BrokenAt *int64 `json:"broken_at,omitempty"`
+}
+
+// ActivityEvent is an audit event as shown to the user it concerns. Role is
+// actor when the user did it and target when it was done to them, by an
+// administrator or someone trying to sign in. The request details of events
+// the user did not do are left out.
+type ActivityEvent struct {
+	ID        int64             `json:"id"`
+	Action    string            `json:"action"`
+	At        time.Time         `json:"at"`
+	Role      string            `json:"role"`
+	IP        string            `json:"ip,omitempty"`
+	UserAgent string            `json:"user_agent,omitempty"`
+	Details   map[string]string `json:"details,omitempty"`
+}
+
+// targetDetails are the actions whose details describe the change made to
+// the target rather than the request of the actor.
+var targetDetails = map[string]bool{
+	models.AuditRoleChanged:  true,
+	models.AuditStateChanged: true,
+}
+
+// UserActivity returns event as shown to the user with userID.
+func UserActivity(userID int64, event *models.AuditEvent) ActivityEvent {
+	activity := ActivityEvent{ID: event.ID, Action: event.Action, At: event.CreatedAt, Role: "target"}
+	if event.ActorID != nil && *event.ActorID == userID {
+		activity.Role = "actor"
+		activity.IP = event.IP
+		activity.UserAgent = event.UserAgent
+		activity.Details = event.Details
+	} else if targetDetails[event.Action] {
+		activity.Details = event.Details
+	}
+	return activity
+}
+
+type AuditService interface {
+	AuditLog
+	// List pages through the events matching filter, newest first.
+	List(ctx context.Context, filter repository.AuditFilter, cursor string, limit int) (*AuditPage, error)
+	// Activity pages through the events the user did or was the target of.
+	Activity(ctx context.Context, userID int64, cursor string, limit int) (*AuditPage, error)
+	// Verify recomputes the hash chain from the first event.
+	Verify(ctx context.Context) (*AuditVerification, error)
+}
+
+type auditService struct {
+	repo repository.AuditRepository
+	now  func() time.Time
+}
+
+func truncateRunes(s string, n int) string {
+	runes := []rune(s)
+	if len(runes) <= n {
+		return s
+	}
+	return string(runes[:n])
+}
+
+func (s *auditService) Record(ctx context.Context, event *models.AuditEvent) {
+	info := RequestInfoFromContext(ctx)
+	event.IP = truncateRunes(info.IP, maxAuditIPLength)
+	event.UserAgent = truncateRunes(info.UserAgent, maxAuditUserAgentLength)
+	event.RequestID = info.ID
+	event.CreatedAt = s.now()
+
+	// a client hanging up must not lose the event
+	if err := s.repo.Append(context.WithoutCancel(ctx), event); err != nil {
+		slog.Error("failed to record audit event", "action", event.Action, "request_id", event.RequestID, "err", err)
+	}
+}
+
+func encodeAuditCursor(id int64) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(strconv.FormatInt(id, 10)))
+}
+
+func decodeAuditCursor(cursor string) (int64, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return 0, ErrInvalidCursor
+	}
+	id, err := strconv.ParseInt(string(raw), 10, 64)
+	if err != nil || id <= 0 {
+		return 0, ErrInvalidCursor
+	}
+	return id, nil
+}
+
+func (s *auditService) List(ctx context.Context, filter repository.AuditFilter, cursor string, limit int) (*AuditPage, error) {
+	if filter.From != nil && filter.To != nil && !filter.To.After(*filter.From) {
+		return nil, ErrInvalidAuditRange
+	}
+	var beforeID int64
+	if cursor != "" {
+		var err error
+		if beforeID, err = decodeAuditCursor(cursor); err != nil {
+			return nil, err
+		}
+	}
+	if limit <= 0 {
+		limit = DefaultAuditPageSize
+	}
+	limit = min(limit, MaxAuditPageSize)
+
+	// one extra row tells whether another page follows
+	events, err := s.repo.List(ctx, filter, beforeID, limit+1)
+	if err != nil {
+		return nil, err
+	}
+	page := &AuditPage{Events: events}
+	if len(events) > limit {
+		page.Events = events[:limit]
+		page.NextCursor = encodeAuditCursor(page.Events[limit-1].ID)
+	}
+	return page, nil
+}
+
+func (s *auditService) Activity(ctx context.Context, userID int64, cursor string, limit int) (*AuditPage, error) {
+	return s.List(ctx, repository.AuditFilter{UserID: &userID}, cursor, limit)
+}
+
+func (s *auditService) Verify(ctx context.Context) (*AuditVerification, error) {
+	result := &AuditVerification{Valid: true}
+	var afterID int64
+	prevHash := ""
+	for {
+		events, err := s.repo.Chain(ctx, afterID, auditVerifyBatchSize)
+		if err != nil {
+			return nil, err
+		}
+		for _, event := range events {
+			if event.PrevHash != prevHash || repository.HashAuditEvent(event) != event.Hash {
+				slog.Error("audit log hash chain is broken", "event_id", event.ID)
+				result.Valid = false
+				result.BrokenAt = &event.ID
+				return result, nil
+			}
+			result.Checked++
+			prevHash = event.Hash
+			afterID = event.ID
+		}
+		if len(events) < auditVerifyBatchSize {
+			return result, nil
+		}
+	}
+}
+
+func NewAuditService(repo repository.AuditRepository) AuditService {
+	return &auditService{repo: repo, now: time.Now}
+}
@@ -0,0 +1,153 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Atmosfr/user-service/internal/models"
+	"github.com/Atmosfr/user-service/internal/repository"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// recordingAuditLog keeps the events services record.
+type recordingAuditLog struct {
+	events []*models.AuditEvent
+}
+
+func (l *recordingAuditLog) Record(ctx context.Context, event *models.AuditEvent) {
+	l.events = append(l.events, event)
+}
+
+func (l *recordingAuditLog) actions() []string {
+	actions := []string{}
+	for _, event := range l.events {
+		actions = append(actions, event.Action)
+	}
+	return actions
+}
+
+type mockAuditRepo struct {
+	mock.Mock
+}
+
+func (m *mockAuditRepo) Append(ctx context.Context, event *models.AuditEvent) error {
+	return m.Called(ctx, event).Error(0)
+}
+
+func (m *mockAuditRepo) List(ctx context.Context, filter repository.AuditFilter, beforeID int64, limit int) ([]*models.AuditEvent, error) {
+	args := m.Called(ctx, filter, beforeID, limit)
+	events, _ := args.Get(0).([]*models.AuditEvent)
+	return events, args.Error(1)
+}
+
+func (m *mockAuditRepo) Chain(ctx context.Context, afterID int64, limit int) ([]*models.AuditEvent, error) {
+	args := m.Called(ctx, afterID, limit)
+	events, _ := args.Get(0).([]*models.AuditEvent)
+	return events, args.Error(1)
+}
+
+func TestAuditService_RecordAddsRequestInfo(t *testing.T) {
+	now := time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC)
+	repo := new(mockAuditRepo)
+	svc := &auditService{repo: repo, now: func() time.Time { return now }}
+	repo.On("Append", mock.Anything, mock.AnythingOfType("*models.AuditEvent")).Return(errors.New("db down"))
+
+	ctx, cancel := context.WithCancel(ContextWithRequestInfo(context.Background(), RequestInfo{
+		ID:        "req-1",
+		IP:        "203.0.113.7",
+		UserAgent: strings.Repeat("a", 600),
+	}))
+	cancel()
+	// failures are only logged
+	svc.Record(ctx, &models.AuditEvent{Action: models.AuditLoginSucceeded})
+
+	event := repo.Calls[0].Arguments.Get(1).(*models.AuditEvent)
+	require.Equal(t, "req-1", event.RequestID)
+	require.Equal(t, "203.0.113.7", event.IP)
+	require.Len(t, event.UserAgent, maxAuditUserAgentLength)
+	require.Equal(t, now, event.CreatedAt)
+	// the event is stored even if the request is gone
+	require.NoError(t, repo.Calls[0].Arguments.Get(0).(context.Context).Err())
+}
+
+func TestAuditService_ListPages(t *testing.T) {
+	ctx := context.Background()
+	repo := new(mockAuditRepo)
+	svc := NewAuditService(repo)
+	userID := int64(7)
+	filter := repository.AuditFilter{UserID: &userID}
+	repo.On("List", mock.Anything, filter, int64(0), 3).Return([]*models.AuditEvent{{ID: 9}, {ID: 8}, {ID: 5}}, nil).Once()
+	repo.On("List", mock.Anything, filter, int64(8), 3).Return([]*models.AuditEvent{{ID: 5}}, nil).Once()
+
+	page, err := svc.Activity(ctx, userID, "", 2)
+	require.NoError(t, err)
+	require.Len(t, page.Events, 2)
+	require.NotEmpty(t, page.NextCursor)
+
+	page, err = svc.Activity(ctx, userID, page.NextCursor, 2)
+	require.NoError(t, err)
+	require.Len(t, page.Events, 1)
+	require.Empty(t, page.NextCursor)
+	repo.AssertExpectations(t)
+
+	_, err = svc.List(ctx, repository.AuditFilter{}, "not a cursor", 0)
+	require.ErrorIs(t, err, ErrInvalidCursor)
+	from := time.Now()
+	_, err = svc.List(ctx, repository.AuditFilter{From: &from, To: &from}, "", 0)
+	require.ErrorIs(t, err, ErrInvalidAuditRange)
+}
+
+func auditChain(n int) []*models.AuditEvent {
+	created := time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC)
+	events := make([]*models.AuditEvent, n)
+	prev := ""
+	for i := range events {
+		userID := int64(i + 1)
+		event := &models.AuditEvent{
+			ID:        int64(i + 1),
+			Action:    models.AuditLoginSucceeded,
+			ActorID:   &userID,
+			TargetID:  &userID,
+			Details:   map[string]string{"n": strings.Repeat("x", i)},
+			CreatedAt: created.Add(time.Duration(i) * time.Second),
+			PrevHash:  prev,
+		}
+		event.Hash = repository.HashAuditEvent(event)
+		prev = event.Hash
+		events[i] = event
+	}
+	return events
+}
+
+func TestAuditService_Verify(t *testing.T) {
+	ctx := context.Background()
+
+	repo := new(mockAuditRepo)
+	repo.On("Chain", mock.Anything, int64(0), auditVerifyBatchSize).Return(auditChain(3), nil)
+	result, err := NewAuditService(repo).Verify(ctx)
+	require.NoError(t, err)
+	require.Equal(t, &AuditVerification{Checked: 3, Valid: true}, result)
+
+	// an edited event no longer matches its hash
+	events := auditChain(3)
+	events[1].Details["n"] = "edited"
+	repo = new(mockAuditRepo)
+	repo.On("Chain", mock.Anything, int64(0), auditVerifyBatchSize).Return(events, nil)
+	result, err = NewAuditService(repo).Verify(ctx)
+	require.NoError(t, err)
+	require.False(t, result.Valid)
+	require.Equal(t, int64(2), *result.BrokenAt)
+	require.Equal(t, int64(1), result.Checked)
+
+	// so does a chain with an event taken out
+	events = auditChain(3)
+	repo = new(mockAuditRepo)
+	repo.On("Chain", mock.Anything, int64(0), auditVerifyBatchSize).Return([]*models.AuditEvent{events[0], events[2]}, nil)
+	result, err = NewAuditService(repo).Verify(ctx)
+	require.NoError(t, err)
+	require.Equal(t, int64(3), *result.BrokenAt)
+}
@@ -0,0 +1,71 @@
+package service
+
+import (
+	"context"
+	"log/slog"
+	"strings"
+
+	"github.com/Atmosfr/user-service/internal/models"
+	"github.com/Atmosfr/user-service/internal/repository"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// Authenticator verifies a user's credentials and returns the matching user.
+// Implementations return repository.ErrUserNotFound or repository.ErrInvalidPassword
+// for rejected credentials.
+type Authenticator interface {
+	Authenticate(ctx context.Context, email, password string) (*models.User, error)
+}
+
+type localAuthenticator struct {
+	repo repository.UserRepository
+}
+
+func (a *localAuthenticator) Authenticate(ctx context.Context, email, password string) (*models.User, error) {
+	user, err := a.repo.FindByEmail(ctx, email)
+	if err != nil {
+		slog.Warn("user not found")
+		return nil, err
+	}
+
+	if err := bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(password)); err != nil {
+		slog.Warn("wrong password for user", "user_id", user.ID)
+		return nil, repository.ErrInvalidPassword
+	}
+
+	return user, nil
+}
+
+func NewLocalAuthenticator(repo repository.UserRepository) Authenticator {
+	return &localAuthenticator{repo: repo}
+}
+
+type domainAuthenticator struct {
+	fallback Authenticator
+	domains  map[string]Authenticator
+}
+
+func (a *domainAuthenticator) Authenticate(ctx context.Context, email, password string) (*models.User, error) {
+	if authn, ok := a.domains[EmailDomain(email)]; ok {
+		return authn.Authenticate(ctx, email, password)
+	}
+	return a.fallback.Authenticate(ctx, email, password)
+}
+
+// NewDomainAuthenticator routes each login to the authenticator registered for
+// the email's domain, using fallback for all other domains.
+func NewDomainAuthenticator(fallback Authenticator, domains map[string]Authenticator) Authenticator {
+	normalized := make(map[string]Authenticator, len(domains))
+	for domain, authn := range domains {
+		normalized[strings.ToLower(domain)] = authn
+	}
+	return &domainAuthenticator{fallback: fallback, domains: normalized}
+}
+
+func EmailDomain(email string) string {
+	at := strings.LastIndex(email, "@")
+	if at == -1 {
+		return ""
+	}
+	return strings.ToLower(email[at+1:])
+}
@@ -0,0 +1,64 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"github.com/Atmosfr/user-service/internal/models"
+	"github.com/Atmosfr/user-service/internal/repository"
+	"github.com/stretchr/testify/require"
+)
+
+type stubAuthenticator struct {
+	name  string
+	calls int
+}
+
+func (s *stubAuthenticator) Authenticate(ctx context.Context, email, password string) (*models.User, error) {
+	s.calls++
+	if password != "secret" {
+		return nil, repository.ErrInvalidPassword
+	}
+	return &models.User{Email: email, Username: s.name}, nil
+}
+
+func TestDomainAuthenticator(t *testing.T) {
+	tests := []struct {
+		name     string
+		email    string
+		password string
+		wantBy   string
+		wantErr  error
+	}{
+		{name: "registered domain", email: "alice@corp.example", password: "secret", wantBy: "ldap"},
+		{name: "domain match is case insensitive", email: "alice@CORP.Example", password: "secret", wantBy: "ldap"},
+		{name: "other domain falls back", email: "bob@gmail.com", password: "secret", wantBy: "local"},
+		{name: "subdomain is not matched", email: "eve@evil.corp.example", password: "secret", wantBy: "local"},
+		{name: "missing at sign falls back", email: "nobody", password: "secret", wantBy: "local"},
+		{name: "errors are passed through", email: "alice@corp.example", password: "wrong", wantBy: "ldap", wantErr: repository.ErrInvalidPassword},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			local := &stubAuthenticator{name: "local"}
+			ldap := &stubAuthenticator{name: "ldap"}
+			authn := NewDomainAuthenticator(local, map[string]Authenticator{"Corp.Example": ldap})
+
+			user, err := authn.Authenticate(context.Background(), tt.email, tt.password)
+			if tt.wantBy == "ldap" {
+				require.Equal(t, 1, ldap.calls)
+				require.Zero(t, local.calls)
+			} else {
+				require.Equal(t, 1, local.calls)
+				require.Zero(t, ldap.calls)
+			}
+
+			if tt.wantErr != nil {
+				require.ErrorIs(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tt.wantBy, user.Username)
+		})
+	}
+}
@@ -0,0 +1,347 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/Atmosfr/user-service/internal/auth"
+	"github.com/Atmosfr/user-service/internal/models"
+	"github.com/Atmosfr/user-service/internal/policy"
+	"github.com/Atmosfr/user-service/internal/repository"
+)
+
+const (
+	PermissionAuthzCheck = "authz:check"
+
+	MaxAuthzBatchSize = 100
+)
+
+var (
+	ErrInvalidAuthzSubject = errors.New("subject needs exactly one of user_id or token")
+	ErrMissingAuthzAction  = errors.New("action is required")
+	ErrAuthzBatchTooLarge  = fmt.Errorf("at most %d checks are allowed per batch", MaxAuthzBatchSize)
+)
+
+type AuthzSubject struct {
+	UserID int64  `json:"user_id,omitempty"`
+	Token  string `json:"token,omitempty"`
+	// Attributes are extra subject attributes for policies. Stored attributes take precedence.
+	Attributes map[string]any `json:"attributes,omitempty"`
+}
+
+type AuthzCheck struct {
+	Subject AuthzSubject `json:"subject"`
+	Action  string       `json:"action"`
+	// Resource holds the resource attributes, "type" and "id" by convention.
+	Resource map[string]any `json:"resource,omitempty"`
+	Context  map[string]any `json:"context,omitempty"`
+}
+
+type AuthzDecision struct {
+	Allowed bool   `json:"allowed"`
+	Reason  string `json:"reason"`
+	// Policy names the ABAC policy that decided, if any.
+	Policy string `json:"policy,omitempty"`
+}
+
+// PolicyDecider evaluates attribute-based policies, see policy.Engine.
+type PolicyDecider interface {
+	Decide(ctx context.Context, in policy.Input) policy.Decision
+}
+
+type AuthzService interface {
+	Check(ctx context.Context, check AuthzCheck) (*AuthzDecision, error)
+	// CheckMany returns one decision per check in order. A check failing validation
+	// fails the whole batch.
+	CheckMany(ctx context.Context, checks []AuthzCheck) ([]*AuthzDecision, error)
+}
+
+type authzService struct {
+	users           repository.UserRepository
+	rbac            RBACService
+	policies        PolicyDecider
+	tokens          TokenService
+	serviceAccounts ServiceAccountService
+	orgs            OrganizationService
+	groups          GroupService
+}
+
+type AuthzOption func(*authzService)
+
+// WithOrganizationContext adds the user's role and effective groups in the
+// check's organization to the subject as organization_id, org_role and groups.
+// The organization is the token's active one, otherwise the resource's
+// organization_id.
+func WithOrganizationContext(orgs OrganizationService, groups GroupService) AuthzOption {
+	return func(s *authzService) {
+		s.orgs = orgs
+		s.groups = groups
+	}
+}
+
+// organizationAttributes are only ever set from stored memberships.
+var organizationAttributes = []string{"organization_id", "org_role", "groups"}
+
+func validateAuthzCheck(check AuthzCheck) error {
+	if (check.Subject.UserID == 0) == (check.Subject.Token == "") {
+		return ErrInvalidAuthzSubject
+	}
+	if strings.TrimSpace(check.Action) == "" {
+		return ErrMissingAuthzAction
+	}
+	return nil
+}
+
+func (s *authzService) Check(ctx context.Context, check AuthzCheck) (*AuthzDecision, error) {
+	if err := validateAuthzCheck(check); err != nil {
+		return nil, err
+	}
+	return s.decide(ctx, check)
+}
+
+func (s *authzService) CheckMany(ctx context.Context, checks []AuthzCheck) ([]*AuthzDecision, error) {
+	if len(checks) > MaxAuthzBatchSize {
+		return nil, ErrAuthzBatchTooLarge
+	}
+	for i, check := range checks {
+		if err := validateAuthzCheck(check); err != nil {
+			return nil, fmt.Errorf("check %d: %w", i, err)
+		}
+	}
+
+	decisions := make([]*AuthzDecision, len(checks))
+	for i, check := range checks {
+		decision, err := s.decide(ctx, check)
+		if err != nil {
+			return nil, err
+		}
+		decisions[i] = decision
+	}
+	return decisions, nil
+}
+
+func denied(reason string) *AuthzDecision {
+	return &AuthzDecision{Allowed: false, Reason: reason}
+}
+
+// decide grants an action when the subject's role holds it as a permission or a
+// policy allows it. A matching deny policy overrides both.
+func (s *authzService) decide(ctx context.Context, check AuthzCheck) (*AuthzDecision, error) {
+	subject, user, err := s.resolveSubject(ctx, check.Subject)
+	if err != nil {
+		if errors.Is(err, errUnknownSubject) {
+			return denied("unknown or invalid subject"), nil
+		}
+		return nil, err
+	}
+	if user != nil && CheckAccountState(user, time.Now()) != nil {
+		return denied("subject is inactive"), nil
+	}
+
+	var permissions models.PermissionSet
+	if user != nil {
+		if permissions, err = s.rbac.Permissions(ctx, user.Role); err != nil {
+			return nil, err
+		}
+		subject["permissions"] = []string(permissions)
+
+		if err := s.addOrganization(ctx, subject, user, check.Resource); err != nil {
+			return nil, err
+		}
+	}
+
+	resource, err := s.resourceAttributes(ctx, check.Resource)
+	if err != nil {
+		return nil, err
+	}
+
+	var decision policy.Decision
+	if s.policies != nil {
+		decision = s.policies.Decide(ctx, policy.Input{
+			Subject:  subject,
+			Action:   check.Action,
+			Resource: resource,
+			Context:  check.Context,
+		})
+		// a named policy on a denial means an explicit deny rather than no match
+		if !decision.Allowed && decision.Policy != "" {
+			return &AuthzDecision{Allowed: false, Reason: decision.Reason, Policy: decision.Policy}, nil
+		}
+	}
+
+	if permissions.Has(check.Action) {
+		return &AuthzDecision{Allowed: true, Reason: fmt.Sprintf("granted by role %s", user.Role)}, nil
+	}
+	if decision.Allowed {
+		return &AuthzDecision{Allowed: true, Reason: decision.Reason, Policy: decision.Policy}, nil
+	}
+	return denied("no role or policy grants this action"), nil
+}
+
+var errUnknownSubject = errors.New("unknown subject")
+
+// resolveSubject returns the subject's attributes and, for people, the user.
+func (s *authzService) resolveSubject(ctx context.Context, subject AuthzSubject) (policy.Attributes, *models.User, error) {
+	attrs := policy.Attributes{}
+	for k, v := range subject.Attributes {
+		attrs[k] = v
+	}
+	for _, k := range organizationAttributes {
+		delete(attrs, k)
+	}
+
+	userID := subject.UserID
+	if subject.Token != "" {
+		switch {
+		case s.tokens != nil && strings.HasPrefix(subject.Token, PersonalAccessTokenPrefix):
+			pat, err := s.tokens.Authenticate(ctx, subject.Token)
+			if err != nil {
+				return nil, nil, errUnknownSubject
+			}
+			userID = pat.UserID
+		case s.serviceAccounts != nil && strings.HasPrefix(subject.Token, APIKeyPrefix):
+			sa, err := s.serviceAccounts.AuthenticateAPIKey(ctx, subject.Token)
+			if err != nil {
+				return nil, nil, errUnknownSubject
+			}
+			return mergeServiceAccount(attrs, sa), nil, nil
+		default:
+			claims, err := auth.ParseToken(subject.Token, auth.JwtSecret)
+			if err != nil {
+				return nil, nil, errUnknownSubject
+			}
+			if claims.PrincipalType == auth.PrincipalServiceAccount {
+				if s.serviceAccounts == nil {
+					return nil, nil, errUnknownSubject
+				}
+				sa, err := s.serviceAccounts.Get(ctx, claims.ServiceAccountID)
+				if errors.Is(err, repository.ErrServiceAccountNotFound) {
+					return nil, nil, errUnknownSubject
+				}
+				if err != nil {
+					return nil, nil, err
+				}
+				return mergeServiceAccount(attrs, sa), nil, nil
+			}
+			userID = claims.UserID
+			if claims.OrgID != 0 {
+				attrs["organization_id"] = claims.OrgID
+			}
+		}
+	}
+
+	user, err := s.users.FindByID(ctx, userID)
+	if errors.Is(err, repository.ErrUserNotFound) {
+		return nil, nil, errUnknownSubject
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	for k, v := range policy.SubjectFromUser(user) {
+		attrs[k] = v
+	}
+	return attrs, user, nil
+}
+
+// addOrganization expands the subject with its membership in the check's
+// organization. The organization attributes are dropped for non-members.
+func (s *authzService) addOrganization(ctx context.Context, subject policy.Attributes, user *models.User, resource map[string]any) error {
+	orgID, ok := subject["organization_id"].(int64)
+	if !ok {
+		orgID, ok = resourceUserID(resource["organization_id"])
+	}
+	delete(subject, "organization_id")
+	if !ok || s.orgs == nil {
+		return nil
+	}
+
+	member, err := s.orgs.Membership(ctx, orgID, user.ID)
+	if errors.Is(err, repository.ErrMemberNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	subject["organization_id"] = orgID
+	subject["org_role"] = member.Role
+
+	names := []string{}
+	if s.groups != nil {
+		groups, err := s.groups.EffectiveGroups(ctx, orgID, user.ID)
+		if err != nil {
+			return err
+		}
+		for _, g := range groups {
+			names = append(names, g.Name)
+		}
+	}
+	subject["groups"] = names
+	return nil
+}
+
+func mergeServiceAccount(attrs policy.Attributes, sa *models.ServiceAccount) policy.Attributes {
+	attrs["type"] = auth.PrincipalServiceAccount
+	attrs["id"] = sa.ID
+	attrs["name"] = sa.Name
+	attrs["owner_id"] = sa.OwnerID
+	return attrs
+}
+
+// resourceAttributes adds the stored attributes of user resources to the caller's.
+func (s *authzService) resourceAttributes(ctx context.Context, given map[string]any) (policy.Attributes, error) {
+	attrs := policy.Attributes{}
+	for k, v := range given {
+		attrs[k] = v
+	}
+	if attrs["type"] != "user" {
+		return attrs, nil
+	}
+
+	id, ok := resourceUserID(attrs["id"])
+	if !ok {
+		return attrs, nil
+	}
+	user, err := s.users.FindByID(ctx, id)
+	if errors.Is(err, repository.ErrUserNotFound) {
+		return attrs, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	for k, v := range policy.ResourceFromUser(user) {
+		attrs[k] = v
+	}
+	return attrs, nil
+}
+
+// resourceUserID parses a positive ID given as a JSON number or string.
+func resourceUserID(v any) (int64, bool) {
+	switch id := v.(type) {
+	case float64:
+		return int64(id), id > 0 && id == float64(int64(id))
+	case int64:
+		return id, id > 0
+	case int:
+		return int64(id), id > 0
+	case string:
+		return parseResourceID(id)
+	}
+	return 0, false
+}
+
+func NewAuthzService(users repository.UserRepository, rbac RBACService, policies PolicyDecider, tokens TokenService, serviceAccounts ServiceAccountService, opts ...AuthzOption) AuthzService {
+	s := &authzService{
+		users:           users,
+		rbac:            rbac,
+		policies:        policies,
+		tokens:          tokens,
+		serviceAccounts: serviceAccounts,
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
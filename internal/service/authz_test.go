@@ -0,0 +1,240 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/Atmosfr/user-service/internal/auth"
+	"github.com/Atmosfr/user-service/internal/models"
+	"github.com/Atmosfr/user-service/internal/policy"
+	"github.com/Atmosfr/user-service/internal/repository"
+	"github.com/Atmosfr/user-service/internal/testutil"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+type staticPolicies []*policy.Policy
+
+func (p staticPolicies) Decide(ctx context.Context, in policy.Input) policy.Decision {
+	return policy.Evaluate(p, in)
+}
+
+const authzTestPolicies = `
+policy "self-read" {
+    effect   = allow
+    actions  = ["users:read"]
+    resource = "user"
+    when     = subject.id == resource.id
+}
+policy "frozen-accounts" {
+    effect   = deny
+    actions  = ["users:*"]
+    resource = "user"
+    when     = resource.frozen == true
+}
+policy "bots-read-reports" {
+    effect  = allow
+    actions = ["reports:read"]
+    when    = subject.type == "service_account"
+}
+`
+
+func newTestAuthzService(t *testing.T, users *testutil.MockUserRepo) AuthzService {
+	t.Helper()
+	policies, err := policy.ParseFile("test.policy", authzTestPolicies)
+	require.NoError(t, err)
+
+	roles := new(mockRoleRepo)
+	roles.On("PermissionsForRole", mock.Anything, "user").Return([]string{"profile:read"}, nil)
+	roles.On("PermissionsForRole", mock.Anything, "support").Return([]string{"profile:read", "users:read"}, nil)
+
+	serviceAccounts := new(mockServiceAccountRepo)
+	serviceAccounts.On("FindByID", mock.Anything, int64(3)).Return(&models.ServiceAccount{ID: 3, Name: "reporting"}, nil)
+
+	return NewAuthzService(users, NewRBACService(roles), staticPolicies(policies), nil, NewServiceAccountService(serviceAccounts))
+}
+
+func TestAuthzService_Check(t *testing.T) {
+	auth.JwtSecret = []byte("secret")
+	alice := &models.User{ID: 1, Role: "user", IsActive: true}
+	agent := &models.User{ID: 2, Role: "support", IsActive: true}
+	inactive := &models.User{ID: 4, Role: "support", State: models.AccountSuspended}
+
+	aliceToken, err := auth.GenerateToken(alice, time.Hour, auth.JwtSecret)
+	require.NoError(t, err)
+	botToken, err := auth.GenerateServiceAccountToken(&models.ServiceAccount{ID: 3, Name: "reporting"}, time.Hour, auth.JwtSecret)
+	require.NoError(t, err)
+
+	tests := []struct {
+		name        string
+		check       AuthzCheck
+		wantAllowed bool
+		wantPolicy  string
+		wantReason  string
+	}{
+		{
+			name:        "granted by role",
+			check:       AuthzCheck{Subject: AuthzSubject{UserID: 2}, Action: "users:read", Resource: map[string]any{"type": "user", "id": float64(1)}},
+			wantAllowed: true,
+			wantReason:  "granted by role support",
+		},
+		{
+			name:        "allowed by policy with stored resource attributes",
+			check:       AuthzCheck{Subject: AuthzSubject{UserID: 1}, Action: "users:read", Resource: map[string]any{"type": "user", "id": "1"}},
+			wantAllowed: true,
+			wantPolicy:  "self-read",
+		},
+		{
+			name:       "deny policy overrides role",
+			check:      AuthzCheck{Subject: AuthzSubject{UserID: 2}, Action: "users:read", Resource: map[string]any{"type": "user", "id": float64(99), "frozen": true}},
+			wantPolicy: "frozen-accounts",
+		},
+		{
+			name:       "default deny",
+			check:      AuthzCheck{Subject: AuthzSubject{UserID: 1}, Action: "users:read", Resource: map[string]any{"type": "user", "id": float64(2)}},
+			wantReason: "no role or policy grants this action",
+		},
+		{
+			name:       "inactive subject",
+			check:      AuthzCheck{Subject: AuthzSubject{UserID: 4}, Action: "users:read"},
+			wantReason: "subject is inactive",
+		},
+		{
+			name:       "unknown user",
+			check:      AuthzCheck{Subject: AuthzSubject{UserID: 404}, Action: "profile:read"},
+			wantReason: "unknown or invalid subject",
+		},
+		{
+			name:        "user token",
+			check:       AuthzCheck{Subject: AuthzSubject{Token: aliceToken}, Action: "profile:read"},
+			wantAllowed: true,
+			wantReason:  "granted by role user",
+		},
+		{
+			name:       "invalid token",
+			check:      AuthzCheck{Subject: AuthzSubject{Token: "garbage"}, Action: "profile:read"},
+			wantReason: "unknown or invalid subject",
+		},
+		{
+			name:        "service account token",
+			check:       AuthzCheck{Subject: AuthzSubject{Token: botToken}, Action: "reports:read"},
+			wantAllowed: true,
+			wantPolicy:  "bots-read-reports",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			users := new(testutil.MockUserRepo)
+			users.On("FindByID", mock.Anything, int64(1)).Return(alice, nil)
+			users.On("FindByID", mock.Anything, int64(2)).Return(agent, nil)
+			users.On("FindByID", mock.Anything, int64(4)).Return(inactive, nil)
+			users.On("FindByID", mock.Anything, mock.Anything).Return((*models.User)(nil), repository.ErrUserNotFound)
+
+			decision, err := newTestAuthzService(t, users).Check(context.Background(), tt.check)
+			require.NoError(t, err)
+			require.Equal(t, tt.wantAllowed, decision.Allowed)
+			require.Equal(t, tt.wantPolicy, decision.Policy)
+			if tt.wantReason != "" {
+				require.Equal(t, tt.wantReason, decision.Reason)
+			}
+		})
+	}
+}
+
+func TestAuthzService_Validation(t *testing.T) {
+	svc := newTestAuthzService(t, new(testutil.MockUserRepo))
+	ctx := context.Background()
+
+	_, err := svc.Check(ctx, AuthzCheck{Action: "users:read"})
+	require.ErrorIs(t, err, ErrInvalidAuthzSubject)
+
+	_, err = svc.Check(ctx, AuthzCheck{Subject: AuthzSubject{UserID: 1, Token: "t"}, Action: "users:read"})
+	require.ErrorIs(t, err, ErrInvalidAuthzSubject)
+
+	_, err = svc.Check(ctx, AuthzCheck{Subject: AuthzSubject{UserID: 1}})
+	require.ErrorIs(t, err, ErrMissingAuthzAction)
+
+	_, err = svc.CheckMany(ctx, []AuthzCheck{{Subject: AuthzSubject{UserID: 1}, Action: "a"}, {Subject: AuthzSubject{UserID: 1}}})
+	require.ErrorIs(t, err, ErrMissingAuthzAction)
+	require.ErrorContains(t, err, "check 1")
+
+	_, err = svc.CheckMany(ctx, make([]AuthzCheck, MaxAuthzBatchSize+1))
+	require.ErrorIs(t, err, ErrAuthzBatchTooLarge)
+}
+
+func TestAuthzService_CheckMany(t *testing.T) {
+	users := new(testutil.MockUserRepo)
+	users.On("FindByID", mock.Anything, int64(1)).Return(&models.User{ID: 1, Role: "user", IsActive: true}, nil)
+
+	decisions, err := newTestAuthzService(t, users).CheckMany(context.Background(), []AuthzCheck{
+		{Subject: AuthzSubject{UserID: 1}, Action: "profile:read"},
+		{Subject: AuthzSubject{UserID: 1}, Action: "users:delete"},
+	})
+	require.NoError(t, err)
+	require.Len(t, decisions, 2)
+	require.True(t, decisions[0].Allowed)
+	require.False(t, decisions[1].Allowed)
+}
+
+func TestAuthzService_OrganizationGroups(t *testing.T) {
+	auth.JwtSecret = []byte("secret")
+	policies, err := policy.ParseFile("groups.policy", `
+policy "platform-deploys" {
+    effect   = allow
+    actions  = ["deploys:create"]
+    resource = "service"
+    when     = "Platform" in subject.groups && subject.organization_id == resource.organization_id
+}
+`)
+	require.NoError(t, err)
+
+	alice := &models.User{ID: 1, Role: "user", IsActive: true}
+	users := new(testutil.MockUserRepo)
+	users.On("FindByID", mock.Anything, int64(1)).Return(alice, nil)
+	roles := new(mockRoleRepo)
+	roles.On("PermissionsForRole", mock.Anything, "user").Return([]string{}, nil)
+	orgs := new(mockOrganizationRepo)
+	memberships(orgs, map[int64]string{1: models.OrgRoleMember})
+	groups := new(mockGroupRepo)
+	// alice is in SRE, a subgroup of Platform
+	groups.On("EffectiveGroups", mock.Anything, int64(1), int64(1)).Return([]*models.Group{{ID: 2, Name: "Platform"}, {ID: 3, Name: "SRE"}}, nil)
+
+	svc := NewAuthzService(users, NewRBACService(roles), staticPolicies(policies), nil, nil,
+		WithOrganizationContext(NewOrganizationService(orgs, users), NewGroupService(groups, orgs)))
+
+	orgToken, err := auth.GenerateToken(alice, time.Hour, auth.JwtSecret, auth.WithOrganization(1, models.OrgRoleMember))
+	require.NoError(t, err)
+	otherOrgToken, err := auth.GenerateToken(alice, time.Hour, auth.JwtSecret, auth.WithOrganization(2, models.OrgRoleMember))
+	require.NoError(t, err)
+
+	tests := []struct {
+		name        string
+		subject     AuthzSubject
+		orgID       any
+		wantAllowed bool
+	}{
+		{name: "inherited group by resource organization", subject: AuthzSubject{UserID: 1}, orgID: float64(1), wantAllowed: true},
+		{name: "inherited group by token organization", subject: AuthzSubject{Token: orgToken}, orgID: float64(1), wantAllowed: true},
+		{name: "token scoped to another organization", subject: AuthzSubject{Token: otherOrgToken}, orgID: float64(1)},
+		{name: "not a member", subject: AuthzSubject{UserID: 1}, orgID: float64(2)},
+		{
+			name:    "caller cannot claim groups",
+			subject: AuthzSubject{UserID: 1, Attributes: map[string]any{"groups": []any{"Platform"}, "organization_id": float64(2)}},
+			orgID:   float64(2),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			decision, err := svc.Check(context.Background(), AuthzCheck{
+				Subject:  tt.subject,
+				Action:   "deploys:create",
+				Resource: map[string]any{"type": "service", "organization_id": tt.orgID},
+			})
+			require.NoError(t, err)
+			require.Equal(t, tt.wantAllowed, decision.Allowed)
+		})
+	}
+}
@@ -0,0 +1,142 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/Atmosfr/user-service/internal/blob"
+	"github.com/Atmosfr/user-service/internal/imaging"
+	"github.com/Atmosfr/user-service/internal/models"
+	"github.com/Atmosfr/user-service/internal/repository"
+)
+
+const (
+	MaxAvatarBytes = 5 << 20
+	// MaxAvatarPixels bounds the decoded size of an upload, about 64 MB of RGBA.
+	MaxAvatarPixels = 4096 * 4096
+	// AvatarKeyPrefix starts the blob keys of all avatars.
+	AvatarKeyPrefix = "avatars/"
+)
+
+// DefaultAvatarSizes are the square edge lengths avatars are rendered in.
+var DefaultAvatarSizes = []int{64, 128, 256, 512}
+
+var ErrAvatarTooLarge = fmt.Errorf("avatar must be at most %d MB", MaxAvatarBytes>>20)
+
+// AvatarService stores uploaded pictures as square JPEG avatars in several sizes.
+type AvatarService interface {
+	// Upload replaces the user's avatar. The largest size becomes the user's
+	// avatar URL.
+	Upload(ctx context.Context, userID int64, image io.Reader) (*models.User, error)
+	// DeleteFiles removes the stored files of the avatar with key.
+	DeleteFiles(ctx context.Context, key string)
+}
+
+type avatarService struct {
+	users   repository.UserRepository
+	store   blob.Store
+	baseURL string
+	sizes   []int
+}
+
+type AvatarOption func(*avatarService)
+
+// WithAvatarSizes replaces DefaultAvatarSizes.
+func WithAvatarSizes(sizes ...int) AvatarOption {
+	return func(s *avatarService) {
+		if len(sizes) > 0 {
+			s.sizes = slices.Sorted(slices.Values(sizes))
+		}
+	}
+}
+
+func (s *avatarService) Upload(ctx context.Context, userID int64, image io.Reader) (*models.User, error) {
+	data, err := io.ReadAll(io.LimitReader(image, MaxAvatarBytes+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(data) > MaxAvatarBytes {
+		return nil, ErrAvatarTooLarge
+	}
+	img, err := imaging.Decode(data, MaxAvatarPixels)
+	if err != nil {
+		return nil, err
+	}
+
+	user, err := s.users.FindByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	// every upload gets a fresh key, so the files can be cached forever
+	version := make([]byte, 8)
+	rand.Read(version)
+	key := AvatarKeyPrefix + strconv.FormatInt(userID, 10) + "/" + hex.EncodeToString(version)
+
+	urls := make(map[string]string, len(s.sizes))
+	for _, size := range s.sizes {
+		var buf bytes.Buffer
+		if err := imaging.EncodeJPEG(&buf, imaging.Square(img, size), 85); err != nil {
+			return nil, err
+		}
+		name := key + "/" + strconv.Itoa(size) + ".jpg"
+		if err := s.store.Put(ctx, name, &buf, int64(buf.Len()), "image/jpeg"); err != nil {
+			s.deleteVariants(ctx, key)
+			return nil, err
+		}
+		urls[strconv.Itoa(size)] = s.baseURL + "/" + name
+	}
+
+	user.AvatarURLs = urls
+	user.AvatarURL = urls[strconv.Itoa(s.sizes[len(s.sizes)-1])]
+	user.AvatarKey = key
+	previousKey, err := s.users.UpdateAvatar(ctx, user)
+	if err != nil {
+		s.deleteVariants(ctx, key)
+		return nil, err
+	}
+	if previousKey != "" {
+		s.deleteVariants(ctx, previousKey)
+	}
+
+	user.PasswordHash = ""
+	slog.Info("avatar uploaded", "user_id", userID, "key", key)
+	return user, nil
+}
+
+func (s *avatarService) DeleteFiles(ctx context.Context, key string) {
+	s.deleteVariants(ctx, key)
+}
+
+// deleteVariants removes the files of an avatar, failures only leave garbage behind.
+func (s *avatarService) deleteVariants(ctx context.Context, key string) {
+	for _, size := range s.sizes {
+		if err := s.store.Delete(ctx, key+"/"+strconv.Itoa(size)+".jpg"); err != nil && !errors.Is(err, blob.ErrNotFound) {
+			slog.Warn("failed to delete avatar", "key", key, "size", size, "err", err)
+		}
+	}
+}
+
+// NewAvatarService stores avatars in store. baseURL is where the store's keys
+// are served from, e.g. "https://cdn.example.com" for "avatars/7/…".
+func NewAvatarService(users repository.UserRepository, store blob.Store, baseURL string, opts ...AvatarOption) AvatarService {
+	s := &avatarService{
+		users:   users,
+		store:   store,
+		baseURL: strings.TrimSuffix(baseURL, "/"),
+		sizes:   DefaultAvatarSizes,
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
@@ -0,0 +1,86 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/Atmosfr/user-service/internal/blob"
+	"github.com/Atmosfr/user-service/internal/imaging"
+	"github.com/Atmosfr/user-service/internal/models"
+	"github.com/Atmosfr/user-service/internal/testutil"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func testPNG(t *testing.T, width, height int) []byte {
+	t.Helper()
+	img := image.NewNRGBA(image.Rect(0, 0, width, height))
+	for i := range img.Pix {
+		img.Pix[i] = 200
+	}
+	var buf bytes.Buffer
+	require.NoError(t, png.Encode(&buf, img))
+	return buf.Bytes()
+}
+
+func TestAvatarService_Upload(t *testing.T) {
+	ctx := context.Background()
+	store := blob.NewFilesystemStore(t.TempDir())
+	repo := new(testutil.MockUserRepo)
+	repo.On("FindByID", mock.Anything, int64(7)).Return(&models.User{ID: 7, PasswordHash: "hash"}, nil)
+	repo.On("UpdateAvatar", mock.Anything, mock.AnythingOfType("*models.User")).Return("", nil).Once()
+	svc := NewAvatarService(repo, store, "https://cdn.example.com/", WithAvatarSizes(128, 32))
+
+	user, err := svc.Upload(ctx, 7, bytes.NewReader(testPNG(t, 300, 200)))
+	require.NoError(t, err)
+	require.Empty(t, user.PasswordHash)
+	require.Len(t, user.AvatarURLs, 2)
+	require.True(t, strings.HasPrefix(user.AvatarKey, "avatars/7/"))
+	require.Equal(t, "https://cdn.example.com/"+user.AvatarKey+"/128.jpg", user.AvatarURL)
+	require.Equal(t, "https://cdn.example.com/"+user.AvatarKey+"/32.jpg", user.AvatarURLs["32"])
+
+	body, info, err := store.Get(ctx, user.AvatarKey+"/32.jpg")
+	require.NoError(t, err)
+	require.Equal(t, "image/jpeg", info.ContentType)
+	thumb, err := jpeg.Decode(body)
+	body.Close()
+	require.NoError(t, err)
+	require.Equal(t, image.Rect(0, 0, 32, 32), thumb.Bounds())
+
+	// a new upload removes the files of the one it replaces
+	first := user.AvatarKey
+	repo.On("UpdateAvatar", mock.Anything, mock.AnythingOfType("*models.User")).Return(first, nil).Once()
+	user, err = svc.Upload(ctx, 7, bytes.NewReader(testPNG(t, 64, 64)))
+	require.NoError(t, err)
+	require.NotEqual(t, first, user.AvatarKey)
+	_, _, err = store.Get(ctx, first+"/32.jpg")
+	require.ErrorIs(t, err, blob.ErrNotFound)
+}
+
+func TestAvatarService_RejectsUploads(t *testing.T) {
+	ctx := context.Background()
+	repo := new(testutil.MockUserRepo)
+	svc := NewAvatarService(repo, blob.NewFilesystemStore(t.TempDir()), "https://cdn.example.com")
+
+	_, err := svc.Upload(ctx, 7, strings.NewReader(`<svg xmlns="http://www.w3.org/2000/svg"><script>alert(1)</script></svg>`))
+	require.ErrorIs(t, err, imaging.ErrUnsupportedFormat)
+
+	_, err = svc.Upload(ctx, 7, io.MultiReader(bytes.NewReader(testPNG(t, 8, 8)), bytes.NewReader(make([]byte, MaxAvatarBytes))))
+	require.ErrorIs(t, err, ErrAvatarTooLarge)
+
+	// a tiny file that claims huge dimensions is refused before decoding
+	huge := image.NewPaletted(image.Rect(0, 0, 5000, 5000), color.Palette{color.Black})
+	var buf bytes.Buffer
+	require.NoError(t, png.Encode(&buf, huge))
+	_, err = svc.Upload(ctx, 7, &buf)
+	require.ErrorIs(t, err, imaging.ErrTooManyPixels)
+
+	repo.AssertNotCalled(t, "UpdateAvatar", mock.Anything, mock.Anything)
+}
@@ -0,0 +1,173 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/url"
+	"time"
+
+	"github.com/Atmosfr/user-service/internal/models"
+	"github.com/Atmosfr/user-service/internal/repository"
+)
+
+var (
+	ErrConsentRequired      = errors.New("the current terms of service and privacy policy must be accepted")
+	ErrDocumentNotCurrent   = errors.New("only the current version of a document can be accepted")
+	ErrInvalidLegalDocument = errors.New("kind must be terms_of_service or privacy_policy, with a version and an absolute url")
+)
+
+var documentKinds = map[string]bool{
+	models.DocumentTermsOfService: true,
+	models.DocumentPrivacyPolicy:  true,
+}
+
+// ConsentStatus is what a user agreed to so far.
+type ConsentStatus struct {
+	// Pending are the mandatory versions the user still has to accept.
+	Pending   []*models.LegalDocument `json:"pending"`
+	Marketing bool                    `json:"marketing"`
+	History   []*models.Consent       `json:"history"`
+}
+
+// ConsentService records which versions of the legal documents users accepted
+// and whether they want marketing email.
+type ConsentService interface {
+	// Publish adds a document version. It takes effect at PublishedAt, or
+	// right away when that is zero.
+	Publish(ctx context.Context, doc *models.LegalDocument) error
+	ListDocuments(ctx context.Context) ([]*models.LegalDocument, error)
+	// Current returns the version of each document in force.
+	Current(ctx context.Context) ([]*models.LegalDocument, error)
+	// Pending returns the mandatory versions the user has not accepted yet.
+	Pending(ctx context.Context, userID int64) ([]*models.LegalDocument, error)
+	// CheckAccepted fails with ErrConsentRequired unless versions, keyed by
+	// document kind, names the current version of every document.
+	CheckAccepted(ctx context.Context, versions map[string]string) error
+	// Accept records that the user accepted versions, keyed by document kind.
+	// Only current versions can be accepted.
+	Accept(ctx context.Context, userID int64, versions map[string]string, ip string) error
+	SetMarketing(ctx context.Context, userID int64, granted bool, ip string) error
+	Status(ctx context.Context, userID int64) (*ConsentStatus, error)
+}
+
+type consentService struct {
+	repo repository.ConsentRepository
+	now  func() time.Time
+}
+
+func (s *consentService) Publish(ctx context.Context, doc *models.LegalDocument) error {
+	u, err := url.Parse(doc.URL)
+	if !documentKinds[doc.Kind] || doc.Version == "" || len(doc.Version) > 32 || err != nil || !u.IsAbs() {
+		return ErrInvalidLegalDocument
+	}
+	if doc.PublishedAt.IsZero() {
+		doc.PublishedAt = s.now()
+	}
+	if err := s.repo.CreateDocument(ctx, doc); err != nil {
+		return err
+	}
+	slog.Info("legal document published", "kind", doc.Kind, "version", doc.Version, "mandatory", doc.Mandatory, "published_at", doc.PublishedAt)
+	return nil
+}
+
+func (s *consentService) ListDocuments(ctx context.Context) ([]*models.LegalDocument, error) {
+	return s.repo.ListDocuments(ctx)
+}
+
+func (s *consentService) Current(ctx context.Context) ([]*models.LegalDocument, error) {
+	return s.repo.CurrentDocuments(ctx, s.now())
+}
+
+func (s *consentService) Pending(ctx context.Context, userID int64) ([]*models.LegalDocument, error) {
+	return s.repo.PendingDocuments(ctx, userID, s.now())
+}
+
+// resolve looks up the documents versions names, all of which must be
+// current, next to every current document.
+func (s *consentService) resolve(ctx context.Context, versions map[string]string) (accepted, current []*models.LegalDocument, err error) {
+	current, err = s.Current(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	byKind := make(map[string]*models.LegalDocument, len(current))
+	for _, doc := range current {
+		byKind[doc.Kind] = doc
+	}
+	for kind, version := range versions {
+		doc, ok := byKind[kind]
+		if !ok || doc.Version != version {
+			return nil, nil, ErrDocumentNotCurrent
+		}
+		accepted = append(accepted, doc)
+	}
+	return accepted, current, nil
+}
+
+func (s *consentService) CheckAccepted(ctx context.Context, versions map[string]string) error {
+	accepted, current, err := s.resolve(ctx, versions)
+	if errors.Is(err, ErrDocumentNotCurrent) {
+		return ErrConsentRequired
+	}
+	if err != nil {
+		return err
+	}
+	if len(accepted) < len(current) {
+		return ErrConsentRequired
+	}
+	return nil
+}
+
+func (s *consentService) Accept(ctx context.Context, userID int64, versions map[string]string, ip string) error {
+	accepted, _, err := s.resolve(ctx, versions)
+	if err != nil {
+		return err
+	}
+	consents := make([]*models.Consent, 0, len(accepted))
+	for _, doc := range accepted {
+		consents = append(consents, &models.Consent{UserID: userID, Kind: doc.Kind, DocumentID: &doc.ID, Version: doc.Version, Granted: true, IPAddress: ip})
+	}
+	if len(consents) == 0 {
+		return nil
+	}
+	if err := s.repo.Record(ctx, consents); err != nil {
+		return err
+	}
+	for _, c := range consents {
+		slog.Info("legal document accepted", "user_id", userID, "kind", c.Kind, "version", c.Version)
+	}
+	return nil
+}
+
+func (s *consentService) SetMarketing(ctx context.Context, userID int64, granted bool, ip string) error {
+	err := s.repo.Record(ctx, []*models.Consent{{UserID: userID, Kind: models.ConsentMarketing, Granted: granted, IPAddress: ip}})
+	if err != nil {
+		return err
+	}
+	slog.Info("marketing consent changed", "user_id", userID, "granted", granted)
+	return nil
+}
+
+func (s *consentService) Status(ctx context.Context, userID int64) (*ConsentStatus, error) {
+	pending, err := s.Pending(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	history, err := s.repo.History(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	status := &ConsentStatus{Pending: pending, History: history}
+	// the history is newest first, the latest marketing choice counts
+	for _, c := range history {
+		if c.Kind == models.ConsentMarketing {
+			status.Marketing = c.Granted
+			break
+		}
+	}
+	return status, nil
+}
+
+func NewConsentService(repo repository.ConsentRepository) ConsentService {
+	return &consentService{repo: repo, now: time.Now}
+}
@@ -0,0 +1,186 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/Atmosfr/user-service/internal/auth"
+	"github.com/Atmosfr/user-service/internal/models"
+	"github.com/Atmosfr/user-service/internal/repository"
+	"github.com/Atmosfr/user-service/internal/testutil"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+type mockConsentRepo struct {
+	mock.Mock
+}
+
+func (m *mockConsentRepo) CreateDocument(ctx context.Context, doc *models.LegalDocument) error {
+	return m.Called(ctx, doc).Error(0)
+}
+
+func (m *mockConsentRepo) ListDocuments(ctx context.Context) ([]*models.LegalDocument, error) {
+	args := m.Called(ctx)
+	docs, _ := args.Get(0).([]*models.LegalDocument)
+	return docs, args.Error(1)
+}
+
+func (m *mockConsentRepo) CurrentDocuments(ctx context.Context, now time.Time) ([]*models.LegalDocument, error) {
+	args := m.Called(ctx, now)
+	docs, _ := args.Get(0).([]*models.LegalDocument)
+	return docs, args.Error(1)
+}
+
+func (m *mockConsentRepo) PendingDocuments(ctx context.Context, userID int64, now time.Time) ([]*models.LegalDocument, error) {
+	args := m.Called(ctx, userID, now)
+	docs, _ := args.Get(0).([]*models.LegalDocument)
+	return docs, args.Error(1)
+}
+
+func (m *mockConsentRepo) Record(ctx context.Context, consents []*models.Consent) error {
+	return m.Called(ctx, consents).Error(0)
+}
+
+func (m *mockConsentRepo) History(ctx context.Context, userID int64) ([]*models.Consent, error) {
+	args := m.Called(ctx, userID)
+	consents, _ := args.Get(0).([]*models.Consent)
+	return consents, args.Error(1)
+}
+
+var currentDocuments = []*models.LegalDocument{
+	{ID: 3, Kind: models.DocumentPrivacyPolicy, Version: "2026-09", Mandatory: true},
+	{ID: 4, Kind: models.DocumentTermsOfService, Version: "2026-10", Mandatory: true},
+}
+
+func newTestConsentService(repo *mockConsentRepo, now time.Time) ConsentService {
+	svc := NewConsentService(repo).(*consentService)
+	svc.now = func() time.Time { return now }
+	return svc
+}
+
+func TestConsentService_Publish(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2026, 10, 21, 12, 0, 0, 0, time.UTC)
+	repo := new(mockConsentRepo)
+	svc := newTestConsentService(repo, now)
+
+	for _, doc := range []*models.LegalDocument{
+		{Kind: "cookie_policy", Version: "1", URL: "https://example.com/cookies"},
+		{Kind: models.DocumentTermsOfService, URL: "https://example.com/terms"},
+		{Kind: models.DocumentTermsOfService, Version: "1", URL: "/terms"},
+	} {
+		require.ErrorIs(t, svc.Publish(ctx, doc), ErrInvalidLegalDocument)
+	}
+
+	repo.On("CreateDocument", mock.Anything, mock.MatchedBy(func(d *models.LegalDocument) bool { return d.PublishedAt.Equal(now) })).Return(nil).Once()
+	require.NoError(t, svc.Publish(ctx, &models.LegalDocument{Kind: models.DocumentTermsOfService, Version: "2026-11", URL: "https://example.com/terms", Mandatory: true}))
+	repo.AssertExpectations(t)
+}
+
+func TestConsentService_Accept(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2026, 10, 21, 12, 0, 0, 0, time.UTC)
+	repo := new(mockConsentRepo)
+	repo.On("CurrentDocuments", mock.Anything, now).Return(currentDocuments, nil)
+	svc := newTestConsentService(repo, now)
+
+	require.NoError(t, svc.CheckAccepted(ctx, map[string]string{"terms_of_service": "2026-10", "privacy_policy": "2026-09"}))
+	require.ErrorIs(t, svc.CheckAccepted(ctx, map[string]string{"terms_of_service": "2026-10"}), ErrConsentRequired)
+	require.ErrorIs(t, svc.CheckAccepted(ctx, map[string]string{"terms_of_service": "2026-01", "privacy_policy": "2026-09"}), ErrConsentRequired)
+	require.ErrorIs(t, svc.CheckAccepted(ctx, nil), ErrConsentRequired)
+
+	require.ErrorIs(t, svc.Accept(ctx, 7, map[string]string{"terms_of_service": "2026-01"}, "10.0.0.1"), ErrDocumentNotCurrent)
+	repo.On("Record", mock.Anything, []*models.Consent{
+		{UserID: 7, Kind: models.DocumentTermsOfService, DocumentID: &currentDocuments[1].ID, Version: "2026-10", Granted: true, IPAddress: "10.0.0.1"},
+	}).Return(nil).Once()
+	require.NoError(t, svc.Accept(ctx, 7, map[string]string{"terms_of_service": "2026-10"}, "10.0.0.1"))
+	repo.AssertExpectations(t)
+}
+
+func TestConsentService_Status(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2026, 10, 21, 12, 0, 0, 0, time.UTC)
+	repo := new(mockConsentRepo)
+	repo.On("PendingDocuments", mock.Anything, int64(7), now).Return(currentDocuments[1:], nil)
+	repo.On("History", mock.Anything, int64(7)).Return([]*models.Consent{
+		{ID: 3, Kind: models.ConsentMarketing, Granted: false},
+		{ID: 2, Kind: models.DocumentPrivacyPolicy, Granted: true},
+		{ID: 1, Kind: models.ConsentMarketing, Granted: true},
+	}, nil)
+	svc := newTestConsentService(repo, now)
+
+	status, err := svc.Status(ctx, 7)
+	require.NoError(t, err)
+	require.Len(t, status.Pending, 1)
+	// the later withdrawal wins
+	require.False(t, status.Marketing)
+	require.Len(t, status.History, 3)
+}
+
+func TestUserService_RegisterRequiresConsent(t *testing.T) {
+	ctx := context.Background()
+	auth.JwtSecret = []byte("secret")
+	now := time.Now()
+	consents := new(mockConsentRepo)
+	consents.On("CurrentDocuments", mock.Anything, mock.Anything).Return(currentDocuments, nil)
+	consentSvc := NewConsentService(consents).(*consentService)
+	consentSvc.now = func() time.Time { return now }
+
+	repo := new(testutil.MockUserRepo)
+	repo.On("FindByEmail", mock.Anything, "bob@example.com").Return((*models.User)(nil), repository.ErrUserNotFound)
+	repo.On("Create", mock.Anything, mock.AnythingOfType("*models.User")).Return(nil).Run(func(args mock.Arguments) {
+		args.Get(1).(*models.User).ID = 7
+	})
+	svc := NewUserService(repo, WithConsents(consentSvc))
+
+	_, err := svc.Register(ctx, "bob@example.com", "StrongPass!12", "bob", RegistrationConsent{Accepted: map[string]string{"terms_of_service": "2026-10"}})
+	require.ErrorIs(t, err, ErrConsentRequired)
+	repo.AssertNotCalled(t, "Create", mock.Anything, mock.Anything)
+
+	consents.On("Record", mock.Anything, mock.MatchedBy(func(c []*models.Consent) bool {
+		return len(c) == 2 && c[0].UserID == 7 && c[0].IPAddress == "10.0.0.1"
+	})).Return(nil).Once()
+	consents.On("Record", mock.Anything, []*models.Consent{{UserID: 7, Kind: models.ConsentMarketing, Granted: true, IPAddress: "10.0.0.1"}}).Return(nil).Once()
+	_, err = svc.Register(ctx, "bob@example.com", "StrongPass!12", "bob", RegistrationConsent{
+		Accepted:  map[string]string{"terms_of_service": "2026-10", "privacy_policy": "2026-09"},
+		Marketing: true,
+		IP:        "10.0.0.1",
+	})
+	require.NoError(t, err)
+	consents.AssertExpectations(t)
+}
+
+func TestInvitationService_RegisterRequiresConsent(t *testing.T) {
+	ctx := context.Background()
+	auth.JwtSecret = []byte("secret")
+	consents := new(mockConsentRepo)
+	consents.On("CurrentDocuments", mock.Anything, mock.Anything).Return(currentDocuments, nil)
+
+	repo := new(mockInvitationRepo)
+	repo.On("Find", mock.Anything, int64(5)).Return(pendingInvitation(), nil)
+	repo.On("AcceptNewUser", mock.Anything, pendingInvitation(), mock.AnythingOfType("*models.User")).Return(nil)
+	users := new(testutil.MockUserRepo)
+	users.On("FindByEmail", mock.Anything, "bob@example.com").Return(nil, repository.ErrUserNotFound)
+	svc := NewInvitationService(repo, new(mockOrganizationRepo), users, &recordingMailer{}, invitationSecret,
+		WithInvitationConsents(newTestConsentService(consents, invitationNow))).(*invitationService)
+	svc.now = func() time.Time { return invitationNow }
+	token := svc.token(pendingInvitation())
+
+	_, err := svc.Register(ctx, token, "StrongPass!12", "bob", RegistrationConsent{Accepted: map[string]string{"terms_of_service": "2026-10"}})
+	require.ErrorIs(t, err, ErrConsentRequired)
+	repo.AssertNotCalled(t, "AcceptNewUser", mock.Anything, mock.Anything, mock.Anything)
+
+	// AcceptNewUser gives the account id 20
+	consents.On("Record", mock.Anything, mock.MatchedBy(func(c []*models.Consent) bool {
+		return len(c) == 2 && c[0].UserID == 20 && c[0].IPAddress == "10.0.0.1"
+	})).Return(nil).Once()
+	consents.On("Record", mock.Anything, []*models.Consent{{UserID: 20, Kind: models.ConsentMarketing, Granted: false, IPAddress: "10.0.0.1"}}).Return(nil).Once()
+	_, err = svc.Register(ctx, token, "StrongPass!12", "bob", RegistrationConsent{
+		Accepted: map[string]string{"terms_of_service": "2026-10", "privacy_policy": "2026-09"},
+		IP:       "10.0.0.1",
+	})
+	require.NoError(t, err)
+	consents.AssertExpectations(t)
+}
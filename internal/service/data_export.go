@@ -0,0 +1,326 @@
+package service
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"strconv"
+	"time"
+
+	"github.com/Atmosfr/user-service/internal/blob"
+	"github.com/Atmosfr/user-service/internal/mail"
+	"github.com/Atmosfr/user-service/internal/models"
+	"github.com/Atmosfr/user-service/internal/repository"
+)
+
+const (
+	// DefaultDataExportTTL is how long a finished export can be downloaded.
+	DefaultDataExportTTL = 7 * 24 * time.Hour
+	// DataExportKeyPrefix starts the blob keys of all exports.
+	DataExportKeyPrefix = "exports/"
+	// dataExportStaleAfter is when a running export is assumed abandoned by a
+	// crashed worker and built again.
+	dataExportStaleAfter = 15 * time.Minute
+)
+
+var ErrInvalidExportLink = errors.New("download link is invalid or has expired")
+
+// DataExportService answers subject access requests. Exports are built in the
+// background and the user is emailed a link to download the archive.
+type DataExportService interface {
+	// Request queues an export of the user's data. It fails with
+	// repository.ErrExportInProgress while another one is being prepared.
+	Request(ctx context.Context, user *models.User) (*models.DataExport, error)
+	Latest(ctx context.Context, userID int64) (*models.DataExport, error)
+	// Open returns the archive a download link points to. Callers close it.
+	Open(ctx context.Context, token string) (io.ReadCloser, *models.DataExport, error)
+	// Process builds the next queued export and reports whether there was one.
+	Process(ctx context.Context) (bool, error)
+	// Run builds queued exports and deletes expired ones every interval until
+	// ctx is done.
+	Run(ctx context.Context, interval time.Duration)
+}
+
+type dataExportService struct {
+	exports     repository.DataExportRepository
+	users       repository.UserRepository
+	tokens      repository.TokenRepository
+	states      repository.AccountStateRepository
+	audit       repository.AuditRepository
+	settings    SettingsService
+	store       blob.Store
+	mailer      mail.Mailer
+	secret      []byte
+	ttl         time.Duration
+	downloadURL string
+	now         func() time.Time
+}
+
+type DataExportOption func(*dataExportService)
+
+func WithDataExportTTL(ttl time.Duration) DataExportOption {
+	return func(s *dataExportService) {
+		s.ttl = ttl
+	}
+}
+
+// WithDataExportURL sets where download links point to, the token is added as
+// the "token" query parameter.
+func WithDataExportURL(downloadURL string) DataExportOption {
+	return func(s *dataExportService) {
+		s.downloadURL = downloadURL
+	}
+}
+
+const dataExportPurpose = "data-export"
+
+func (s *dataExportService) Request(ctx context.Context, user *models.User) (*models.DataExport, error) {
+	export := &models.DataExport{UserID: user.ID}
+	if err := s.exports.Create(ctx, export); err != nil {
+		return nil, err
+	}
+	slog.Info("data export requested", "user_id", user.ID, "export_id", export.ID)
+	return export, nil
+}
+
+func (s *dataExportService) Latest(ctx context.Context, userID int64) (*models.DataExport, error) {
+	return s.exports.Latest(ctx, userID)
+}
+
+func (s *dataExportService) Open(ctx context.Context, token string) (io.ReadCloser, *models.DataExport, error) {
+	id, exp, ok := parseLinkToken(token)
+	if !ok || s.now().Unix() >= exp {
+		return nil, nil, ErrInvalidExportLink
+	}
+	export, err := s.exports.Find(ctx, id)
+	if errors.Is(err, repository.ErrExportNotFound) {
+		return nil, nil, ErrInvalidExportLink
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	// the key is random per export, so a link only ever opens its own archive
+	if export.Status != models.DataExportReady || !checkLinkToken(s.secret, dataExportPurpose, token, export.BlobKey) {
+		return nil, nil, ErrInvalidExportLink
+	}
+
+	body, _, err := s.store.Get(ctx, export.BlobKey)
+	if errors.Is(err, blob.ErrNotFound) {
+		return nil, nil, ErrInvalidExportLink
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	return body, export, nil
+}
+
+func (s *dataExportService) Process(ctx context.Context) (bool, error) {
+	export, err := s.exports.Claim(ctx, s.now().Add(-dataExportStaleAfter))
+	if errors.Is(err, repository.ErrExportNotFound) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	user, err := s.build(ctx, export)
+	if err != nil {
+		slog.Error("data export failed", "user_id", export.UserID, "export_id", export.ID, "err", err)
+		if export.BlobKey != "" {
+			s.deleteArchive(ctx, export.BlobKey)
+		}
+		if err := s.exports.Fail(ctx, export.ID); err != nil {
+			return true, err
+		}
+		return true, nil
+	}
+
+	// the archive is ready either way, a lost email only costs the user a new request
+	token := signedLinkToken(s.secret, dataExportPurpose, export.ID, *export.ExpiresAt, export.BlobKey)
+	err = s.mailer.Send(ctx, mail.Message{
+		To:      user.Email,
+		Subject: "Your data export is ready",
+		Body: fmt.Sprintf("The copy of your data you asked for is ready:\n%s\n\nThe link expires on %s.\n",
+			linkWithToken(s.downloadURL, token), export.ExpiresAt.UTC().Format(time.RFC1123)),
+	})
+	if err != nil {
+		slog.Error("failed to send data export email", "user_id", user.ID, "export_id", export.ID, "err", err)
+	}
+
+	slog.Info("data export ready", "user_id", user.ID, "export_id", export.ID, "size", export.Size)
+	return true, nil
+}
+
+// build stores the archive of export and marks it ready. It returns the
+// exported user.
+func (s *dataExportService) build(ctx context.Context, export *models.DataExport) (*models.User, error) {
+	user, err := s.users.FindByID(ctx, export.UserID)
+	if err != nil {
+		return nil, err
+	}
+	data, err := s.archive(ctx, user)
+	if err != nil {
+		return nil, err
+	}
+
+	key := make([]byte, 16)
+	rand.Read(key)
+	export.BlobKey = DataExportKeyPrefix + strconv.FormatInt(user.ID, 10) + "/" + hex.EncodeToString(key) + ".zip"
+	export.Size = int64(len(data))
+	if err := s.store.Put(ctx, export.BlobKey, bytes.NewReader(data), export.Size, "application/zip"); err != nil {
+		export.BlobKey = ""
+		return nil, err
+	}
+	expiresAt := s.now().Add(s.ttl)
+	export.ExpiresAt = &expiresAt
+	if err := s.exports.Complete(ctx, export); err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+// activity returns all audit events of the user, newest first, as the user
+// sees them under GET /me/activity.
+func (s *dataExportService) activity(ctx context.Context, userID int64) ([]ActivityEvent, error) {
+	activity := []ActivityEvent{}
+	filter := repository.AuditFilter{UserID: &userID}
+	var beforeID int64
+	for {
+		events, err := s.audit.List(ctx, filter, beforeID, MaxAuditPageSize)
+		if err != nil {
+			return nil, err
+		}
+		for _, event := range events {
+			activity = append(activity, UserActivity(userID, event))
+			beforeID = event.ID
+		}
+		if len(events) < MaxAuditPageSize {
+			return activity, nil
+		}
+	}
+}
+
+// archive collects the user's data into a ZIP of JSON files.
+func (s *dataExportService) archive(ctx context.Context, user *models.User) ([]byte, error) {
+	// sign-in sessions are stateless tokens, personal access tokens are the
+	// only sessions stored
+	tokens, err := s.tokens.ListByUser(ctx, user.ID)
+	if err != nil {
+		return nil, err
+	}
+	history, err := s.states.History(ctx, user.ID)
+	if err != nil {
+		return nil, err
+	}
+	activity, err := s.activity(ctx, user.ID)
+	if err != nil {
+		return nil, err
+	}
+	values, err := s.settings.Get(ctx, user.ID)
+	if err != nil {
+		return nil, err
+	}
+	// the access request covers the private metadata users cannot see otherwise
+	metadata := map[string]json.RawMessage{"public": user.PublicMetadata, "private": user.PrivateMetadata}
+
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	files := []struct {
+		name string
+		v    any
+	}{
+		{"user.json", user},
+		{"sessions.json", tokens},
+		{"state_history.json", history},
+		{"audit_events.json", activity},
+		{"settings.json", values},
+		{"metadata.json", metadata},
+	}
+	for _, f := range files {
+		w, err := zw.CreateHeader(&zip.FileHeader{Name: f.name, Method: zip.Deflate, Modified: s.now()})
+		if err != nil {
+			return nil, err
+		}
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(f.v); err != nil {
+			return nil, err
+		}
+	}
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// deleteArchive removes a stored archive, failures only leave garbage behind.
+func (s *dataExportService) deleteArchive(ctx context.Context, key string) {
+	if err := s.store.Delete(ctx, key); err != nil && !errors.Is(err, blob.ErrNotFound) {
+		slog.Warn("failed to delete data export", "key", key, "err", err)
+	}
+}
+
+func (s *dataExportService) expire(ctx context.Context) error {
+	keys, err := s.exports.Expire(ctx, s.now())
+	if err != nil {
+		return err
+	}
+	for _, key := range keys {
+		s.deleteArchive(ctx, key)
+	}
+	return nil
+}
+
+func (s *dataExportService) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := s.expire(ctx); err != nil {
+				slog.Error("failed to expire data exports", "err", err)
+			}
+			for {
+				processed, err := s.Process(ctx)
+				if err != nil {
+					slog.Error("failed to process data export", "err", err)
+				}
+				if !processed || err != nil {
+					break
+				}
+			}
+		}
+	}
+}
+
+func NewDataExportService(exports repository.DataExportRepository, users repository.UserRepository, tokens repository.TokenRepository,
+	states repository.AccountStateRepository, audit repository.AuditRepository, settings SettingsService, store blob.Store, mailer mail.Mailer, secret []byte, opts ...DataExportOption) DataExportService {
+	s := &dataExportService{
+		exports:     exports,
+		users:       users,
+		tokens:      tokens,
+		states:      states,
+		audit:       audit,
+		settings:    settings,
+		store:       store,
+		mailer:      mailer,
+		secret:      secret,
+		ttl:         DefaultDataExportTTL,
+		downloadURL: "http://localhost:8080/exports/download",
+		now:         time.Now,
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
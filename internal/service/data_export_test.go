@@ -0,0 +1,275 @@
+package service
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/Atmosfr/user-service/internal/blob"
+	"github.com/Atmosfr/user-service/internal/models"
+	"github.com/Atmosfr/user-service/internal/repository"
+	"github.com/Atmosfr/user-service/internal/testutil"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+type mockDataExportRepo struct {
+	mock.Mock
+}
+
+func (m *mockDataExportRepo) Create(ctx context.Context, export *models.DataExport) error {
+	return m.Called(ctx, export).Error(0)
+}
+
+func (m *mockDataExportRepo) Find(ctx context.Context, id int64) (*models.DataExport, error) {
+	args := m.Called(ctx, id)
+	export, _ := args.Get(0).(*models.DataExport)
+	return export, args.Error(1)
+}
+
+func (m *mockDataExportRepo) Latest(ctx context.Context, userID int64) (*models.DataExport, error) {
+	args := m.Called(ctx, userID)
+	export, _ := args.Get(0).(*models.DataExport)
+	return export, args.Error(1)
+}
+
+func (m *mockDataExportRepo) Claim(ctx context.Context, staleBefore time.Time) (*models.DataExport, error) {
+	args := m.Called(ctx, staleBefore)
+	export, _ := args.Get(0).(*models.DataExport)
+	return export, args.Error(1)
+}
+
+func (m *mockDataExportRepo) Complete(ctx context.Context, export *models.DataExport) error {
+	return m.Called(ctx, export).Error(0)
+}
+
+func (m *mockDataExportRepo) Fail(ctx context.Context, id int64) error {
+	return m.Called(ctx, id).Error(0)
+}
+
+func (m *mockDataExportRepo) Expire(ctx context.Context, now time.Time) ([]string, error) {
+	args := m.Called(ctx, now)
+	keys, _ := args.Get(0).([]string)
+	return keys, args.Error(1)
+}
+
+var (
+	dataExportSecret = []byte("export-secret")
+	dataExportNow    = time.Date(2026, 10, 20, 12, 0, 0, 0, time.UTC)
+)
+
+func TestDataExportService_Request(t *testing.T) {
+	tests := []struct {
+		name    string
+		err     error
+		wantErr error
+	}{
+		{name: "success"},
+		{name: "export in progress", err: repository.ErrExportInProgress, wantErr: repository.ErrExportInProgress},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			exports := new(mockDataExportRepo)
+			exports.On("Create", mock.Anything, &models.DataExport{UserID: 7}).Return(tt.err)
+			svc := NewDataExportService(exports, new(testutil.MockUserRepo), new(mockTokenRepo), new(mockAccountStateRepo), new(mockAuditRepo),
+				NewSettingsService(new(mockSettingsRepo), DefaultSettings), blob.NewFilesystemStore(t.TempDir()), &recordingMailer{}, dataExportSecret)
+
+			export, err := svc.Request(context.Background(), &models.User{ID: 7})
+			require.ErrorIs(t, err, tt.wantErr)
+			if tt.wantErr == nil {
+				require.Equal(t, int64(7), export.UserID)
+			}
+			exports.AssertExpectations(t)
+		})
+	}
+}
+
+func TestDataExportService_Process(t *testing.T) {
+	tests := []struct {
+		name          string
+		claimed       *models.DataExport
+		completeErr   error
+		wantProcessed bool
+		wantSent      int
+	}{
+		{
+			name:          "builds and mails the archive",
+			claimed:       &models.DataExport{ID: 5, UserID: 7, Status: models.DataExportRunning},
+			wantProcessed: true,
+			wantSent:      1,
+		},
+		{
+			name: "nothing to do",
+		},
+		{
+			name:          "failure marks the export failed",
+			claimed:       &models.DataExport{ID: 6, UserID: 7, Status: models.DataExportRunning},
+			completeErr:   errors.New("connection reset"),
+			wantProcessed: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			exports := new(mockDataExportRepo)
+			if tt.claimed != nil {
+				exports.On("Claim", mock.Anything, dataExportNow.Add(-dataExportStaleAfter)).Return(tt.claimed, nil)
+				exports.On("Complete", mock.Anything, tt.claimed).Return(tt.completeErr).Run(func(args mock.Arguments) {
+					args.Get(1).(*models.DataExport).Status = models.DataExportReady
+				})
+			} else {
+				exports.On("Claim", mock.Anything, mock.Anything).Return(nil, repository.ErrExportNotFound)
+			}
+			if tt.completeErr != nil {
+				exports.On("Fail", mock.Anything, tt.claimed.ID).Return(nil)
+			}
+
+			users := new(testutil.MockUserRepo)
+			users.On("FindByID", mock.Anything, int64(7)).Return(&models.User{
+				ID: 7, Email: "bob@example.com", PasswordHash: "hash",
+				PublicMetadata: json.RawMessage(`{"profile":{"team":"blue"}}`), PrivateMetadata: json.RawMessage(`{"crm":{"id":"A1"}}`),
+			}, nil)
+			tokens := new(mockTokenRepo)
+			tokens.On("ListByUser", mock.Anything, int64(7)).Return([]*models.PersonalAccessToken{{ID: 3, UserID: 7, Name: "ci", Prefix: "pat_ab"}}, nil)
+			states := new(mockAccountStateRepo)
+			states.On("History", mock.Anything, int64(7)).Return([]*models.AccountStateChange{{ID: 1, UserID: 7, From: "active", To: "suspended", Reason: "spam"}}, nil)
+			userID, adminID := int64(7), int64(1)
+			audit := new(mockAuditRepo)
+			audit.On("List", mock.Anything, repository.AuditFilter{UserID: &userID}, int64(0), MaxAuditPageSize).Return([]*models.AuditEvent{
+				{ID: 9, Action: models.AuditStateChanged, ActorID: &adminID, TargetID: &userID, IP: "198.51.100.9"},
+				{ID: 4, Action: models.AuditLoginSucceeded, ActorID: &userID, TargetID: &userID, IP: "192.0.2.1"},
+			}, nil)
+			settingsRepo := new(mockSettingsRepo)
+			settingsRepo.On("List", mock.Anything, []int64{7}).Return(map[int64]map[string]json.RawMessage{7: {"theme": json.RawMessage(`"dark"`)}}, nil)
+			store := blob.NewFilesystemStore(t.TempDir())
+			mailer := &recordingMailer{}
+			svc := NewDataExportService(exports, users, tokens, states, audit, NewSettingsService(settingsRepo, DefaultSettings), store, mailer,
+				dataExportSecret, WithDataExportURL("https://app.example.com/exports/download")).(*dataExportService)
+			svc.now = func() time.Time { return dataExportNow }
+
+			processed, err := svc.Process(ctx)
+			require.NoError(t, err)
+			require.Equal(t, tt.wantProcessed, processed)
+			require.Len(t, mailer.sent, tt.wantSent)
+			exports.AssertExpectations(t)
+			if tt.wantSent == 0 {
+				return
+			}
+
+			export := tt.claimed
+			require.Equal(t, dataExportNow.Add(DefaultDataExportTTL), *export.ExpiresAt)
+			require.Equal(t, "bob@example.com", mailer.sent[0].To)
+			require.Contains(t, mailer.sent[0].Body, "https://app.example.com/exports/download?token=")
+			require.Equal(t, signedLinkToken(dataExportSecret, dataExportPurpose, 5, *export.ExpiresAt, export.BlobKey), tokenFromMail(t, mailer.sent[0]))
+
+			body, _, err := store.Get(ctx, export.BlobKey)
+			require.NoError(t, err)
+			data, err := io.ReadAll(body)
+			body.Close()
+			require.NoError(t, err)
+			require.Equal(t, export.Size, int64(len(data)))
+
+			zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+			require.NoError(t, err)
+			files := map[string]string{}
+			for _, file := range zr.File {
+				r, err := file.Open()
+				require.NoError(t, err)
+				content, err := io.ReadAll(r)
+				r.Close()
+				require.NoError(t, err)
+				files[file.Name] = string(content)
+			}
+			require.Len(t, files, 6)
+			require.NotContains(t, files["user.json"], "hash")
+			require.Contains(t, files["sessions.json"], `"pat_ab"`)
+			require.Contains(t, files["state_history.json"], `"suspended"`)
+			require.Contains(t, files["audit_events.json"], `"login.succeeded"`)
+			require.Contains(t, files["audit_events.json"], `"192.0.2.1"`)
+			// the administrator's request details are not the user's data
+			require.NotContains(t, files["audit_events.json"], "198.51.100.9")
+			require.Contains(t, files["settings.json"], `"theme": "dark"`)
+			require.JSONEq(t, `{"public":{"profile":{"team":"blue"}},"private":{"crm":{"id":"A1"}}}`, files["metadata.json"])
+		})
+	}
+}
+
+func TestDataExportService_Open(t *testing.T) {
+	key := DataExportKeyPrefix + "7/abc.zip"
+	expiresAt := dataExportNow.Add(DefaultDataExportTTL)
+	valid := signedLinkToken(dataExportSecret, dataExportPurpose, 5, expiresAt, key)
+	ready := func(status, key string) *models.DataExport {
+		return &models.DataExport{ID: 5, UserID: 7, Status: status, BlobKey: key, ExpiresAt: &expiresAt, Size: 3}
+	}
+
+	tests := []struct {
+		name    string
+		token   string
+		now     time.Time
+		export  *models.DataExport
+		stored  bool
+		wantErr error
+	}{
+		{name: "success", token: valid, now: dataExportNow, export: ready(models.DataExportReady, key), stored: true},
+		{name: "expired", token: valid, now: expiresAt, export: ready(models.DataExportReady, key), stored: true, wantErr: ErrInvalidExportLink},
+		// another export's key does not match the link
+		{name: "other archive", token: valid, now: dataExportNow, export: ready(models.DataExportReady, DataExportKeyPrefix+"7/other.zip"), stored: true, wantErr: ErrInvalidExportLink},
+		{name: "not ready", token: valid, now: dataExportNow, export: ready(models.DataExportRunning, key), stored: true, wantErr: ErrInvalidExportLink},
+		{name: "archive gone", token: valid, now: dataExportNow, export: ready(models.DataExportReady, key), wantErr: ErrInvalidExportLink},
+		{name: "unknown export", token: valid, now: dataExportNow, wantErr: ErrInvalidExportLink},
+		{name: "garbage", token: "garbage", now: dataExportNow, wantErr: ErrInvalidExportLink},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			exports := new(mockDataExportRepo)
+			if tt.export != nil {
+				exports.On("Find", mock.Anything, int64(5)).Return(tt.export, nil)
+			} else {
+				exports.On("Find", mock.Anything, int64(5)).Return(nil, repository.ErrExportNotFound)
+			}
+			store := blob.NewFilesystemStore(t.TempDir())
+			if tt.stored {
+				require.NoError(t, store.Put(ctx, key, bytes.NewReader([]byte("zip")), 3, "application/zip"))
+			}
+			svc := NewDataExportService(exports, new(testutil.MockUserRepo), new(mockTokenRepo), new(mockAccountStateRepo), new(mockAuditRepo),
+				NewSettingsService(new(mockSettingsRepo), DefaultSettings), store, &recordingMailer{}, dataExportSecret).(*dataExportService)
+			svc.now = func() time.Time { return tt.now }
+
+			body, opened, err := svc.Open(ctx, tt.token)
+			require.ErrorIs(t, err, tt.wantErr)
+			if tt.wantErr != nil {
+				return
+			}
+			defer body.Close()
+			require.Equal(t, int64(5), opened.ID)
+			data, err := io.ReadAll(body)
+			require.NoError(t, err)
+			require.Equal(t, "zip", string(data))
+		})
+	}
+}
+
+func TestDataExportService_Expire(t *testing.T) {
+	ctx := context.Background()
+	exports := new(mockDataExportRepo)
+	store := blob.NewFilesystemStore(t.TempDir())
+	key := DataExportKeyPrefix + "7/abc.zip"
+	require.NoError(t, store.Put(ctx, key, bytes.NewReader([]byte("zip")), 3, "application/zip"))
+	exports.On("Expire", mock.Anything, dataExportNow).Return([]string{key}, nil)
+	svc := NewDataExportService(exports, new(testutil.MockUserRepo), new(mockTokenRepo), new(mockAccountStateRepo), new(mockAuditRepo),
+		NewSettingsService(new(mockSettingsRepo), DefaultSettings), store, &recordingMailer{}, dataExportSecret).(*dataExportService)
+	svc.now = func() time.Time { return dataExportNow }
+
+	require.NoError(t, svc.expire(ctx))
+	_, _, err := store.Get(ctx, key)
+	require.ErrorIs(t, err, blob.ErrNotFound)
+}
@@ -0,0 +1,263 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+
+	"github.com/Atmosfr/user-service/internal/models"
+	"github.com/Atmosfr/user-service/internal/repository"
+	"github.com/Atmosfr/user-service/internal/validation"
+)
+
+// Ownership of a domain is proved by publishing
+// "user-service-verification=<token>" as a TXT record of
+// "_user-service-challenge.<domain>".
+const (
+	DomainChallengePrefix = "_user-service-challenge."
+	DomainChallengeValue  = "user-service-verification="
+)
+
+var (
+	ErrDomainVerificationFailed = errors.New("the verification TXT record was not found")
+	ErrSSORequired              = errors.New("your organization requires signing in with single sign-on")
+)
+
+// TXTResolver looks up DNS TXT records. net.DefaultResolver implements it.
+type TXTResolver interface {
+	LookupTXT(ctx context.Context, name string) ([]string, error)
+}
+
+// DomainPolicy tells whether password sign-in is allowed for an address.
+type DomainPolicy interface {
+	SSORequired(ctx context.Context, email string) (bool, error)
+}
+
+// DomainUpdate changes the settings that are set, nil fields are left unchanged.
+type DomainUpdate struct {
+	AutoJoin    *bool
+	SSOEnforced *bool
+}
+
+// DomainService manages the email domains organizations claim. Auto-join and
+// SSO enforcement only apply once the domain is verified through DNS.
+type DomainService interface {
+	DomainPolicy
+
+	Claim(ctx context.Context, actorID, orgID int64, domain string) (*models.OrganizationDomain, error)
+	List(ctx context.Context, actorID, orgID int64) ([]*models.OrganizationDomain, error)
+	Verify(ctx context.Context, actorID, orgID, id int64) (*models.OrganizationDomain, error)
+	Update(ctx context.Context, actorID, orgID, id int64, update DomainUpdate) (*models.OrganizationDomain, error)
+	Delete(ctx context.Context, actorID, orgID, id int64) error
+
+	// JoinByDomain adds a user with a verified address to the organization that
+	// verified its domain with auto-join enabled. Others are left alone.
+	JoinByDomain(ctx context.Context, user *models.User) error
+}
+
+type domainService struct {
+	repo     repository.DomainRepository
+	orgs     repository.OrganizationRepository
+	resolver TXTResolver
+}
+
+// withRecord fills in the TXT record an unverified domain still needs.
+func withRecord(d *models.OrganizationDomain) *models.OrganizationDomain {
+	d.TXTRecordName, d.TXTRecordValue = "", ""
+	if !d.IsVerified() {
+		d.TXTRecordName = DomainChallengePrefix + d.Domain
+		d.TXTRecordValue = DomainChallengeValue + d.VerificationToken
+	}
+	return d
+}
+
+func (s *domainService) authorize(ctx context.Context, actorID, orgID int64) error {
+	actor, err := organizationActor(ctx, s.orgs, orgID, actorID)
+	if err != nil {
+		return err
+	}
+	if !actor.CanManageMembers() {
+		return ErrOrganizationAdminOnly
+	}
+	return nil
+}
+
+func (s *domainService) Claim(ctx context.Context, actorID, orgID int64, domain string) (*models.OrganizationDomain, error) {
+	domain = strings.ToLower(strings.TrimSpace(domain))
+	if err := validation.ValidateDomain(domain); err != nil {
+		return nil, err
+	}
+	if err := s.authorize(ctx, actorID, orgID); err != nil {
+		return nil, err
+	}
+
+	token := make([]byte, 16)
+	if _, err := rand.Read(token); err != nil {
+		return nil, err
+	}
+	d := &models.OrganizationDomain{OrganizationID: orgID, Domain: domain, VerificationToken: hex.EncodeToString(token)}
+	if err := s.repo.Create(ctx, d); err != nil {
+		return nil, err
+	}
+
+	slog.Info("domain claimed", "organization_id", orgID, "domain", domain, "actor_id", actorID)
+	return withRecord(d), nil
+}
+
+func (s *domainService) List(ctx context.Context, actorID, orgID int64) ([]*models.OrganizationDomain, error) {
+	if err := s.authorize(ctx, actorID, orgID); err != nil {
+		return nil, err
+	}
+	domains, err := s.repo.List(ctx, orgID)
+	if err != nil {
+		return nil, err
+	}
+	for _, d := range domains {
+		withRecord(d)
+	}
+	return domains, nil
+}
+
+func (s *domainService) find(ctx context.Context, actorID, orgID, id int64) (*models.OrganizationDomain, error) {
+	if err := s.authorize(ctx, actorID, orgID); err != nil {
+		return nil, err
+	}
+	return s.repo.Find(ctx, orgID, id)
+}
+
+func (s *domainService) Verify(ctx context.Context, actorID, orgID, id int64) (*models.OrganizationDomain, error) {
+	d, err := s.find(ctx, actorID, orgID, id)
+	if err != nil {
+		return nil, err
+	}
+	if d.IsVerified() {
+		return withRecord(d), nil
+	}
+
+	records, err := s.resolver.LookupTXT(ctx, DomainChallengePrefix+d.Domain)
+	if err != nil {
+		slog.Warn("domain verification lookup failed", "organization_id", orgID, "domain", d.Domain, "err", err)
+		return nil, fmt.Errorf("%w: %v", ErrDomainVerificationFailed, err)
+	}
+	want := DomainChallengeValue + d.VerificationToken
+	found := false
+	for _, record := range records {
+		if strings.TrimSpace(record) == want {
+			found = true
+			break
+		}
+	}
+	if !found {
+		return nil, ErrDomainVerificationFailed
+	}
+
+	if err := s.repo.MarkVerified(ctx, d); err != nil {
+		return nil, err
+	}
+	slog.Info("domain verified", "organization_id", orgID, "domain", d.Domain, "actor_id", actorID)
+	return withRecord(d), nil
+}
+
+func (s *domainService) Update(ctx context.Context, actorID, orgID, id int64, update DomainUpdate) (*models.OrganizationDomain, error) {
+	d, err := s.find(ctx, actorID, orgID, id)
+	if err != nil {
+		return nil, err
+	}
+	if update.AutoJoin != nil {
+		d.AutoJoin = *update.AutoJoin
+	}
+	if update.SSOEnforced != nil {
+		d.SSOEnforced = *update.SSOEnforced
+	}
+	if err := s.repo.UpdateSettings(ctx, d); err != nil {
+		return nil, err
+	}
+
+	slog.Info("domain settings updated", "organization_id", orgID, "domain", d.Domain,
+		"auto_join", d.AutoJoin, "sso_enforced", d.SSOEnforced, "actor_id", actorID)
+	return withRecord(d), nil
+}
+
+func (s *domainService) Delete(ctx context.Context, actorID, orgID, id int64) error {
+	if err := s.authorize(ctx, actorID, orgID); err != nil {
+		return err
+	}
+	if err := s.repo.Delete(ctx, orgID, id); err != nil {
+		return err
+	}
+	slog.Info("domain removed", "organization_id", orgID, "domain_id", id, "actor_id", actorID)
+	return nil
+}
+
+// verified returns the verified claim for the domain of email, or nil.
+func (s *domainService) verified(ctx context.Context, email string) (*models.OrganizationDomain, error) {
+	domain := EmailDomain(email)
+	if domain == "" {
+		return nil, nil
+	}
+	d, err := s.repo.FindVerified(ctx, domain)
+	if errors.Is(err, repository.ErrDomainNotFound) {
+		return nil, nil
+	}
+	return d, err
+}
+
+func (s *domainService) SSORequired(ctx context.Context, email string) (bool, error) {
+	d, err := s.verified(ctx, email)
+	if err != nil || d == nil {
+		return false, err
+	}
+	return d.SSOEnforced, nil
+}
+
+func (s *domainService) JoinByDomain(ctx context.Context, user *models.User) error {
+	if user.EmailVerifiedAt == nil {
+		return nil
+	}
+	d, err := s.verified(ctx, user.Email)
+	if err != nil || d == nil || !d.AutoJoin {
+		return err
+	}
+
+	err = s.orgs.AddMember(ctx, &models.OrganizationMember{OrganizationID: d.OrganizationID, UserID: user.ID, Role: models.OrgRoleMember})
+	if errors.Is(err, repository.ErrMemberAlreadyExists) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	slog.Info("user joined organization by domain", "organization_id", d.OrganizationID, "domain", d.Domain, "user_id", user.ID)
+	return nil
+}
+
+func NewDomainService(repo repository.DomainRepository, orgs repository.OrganizationRepository, resolver TXTResolver) DomainService {
+	return &domainService{repo: repo, orgs: orgs, resolver: resolver}
+}
+
+type ssoEnforcingAuthenticator struct {
+	next   Authenticator
+	policy DomainPolicy
+}
+
+func (a *ssoEnforcingAuthenticator) Authenticate(ctx context.Context, email, password string) (*models.User, error) {
+	required, err := a.policy.SSORequired(ctx, email)
+	if err != nil {
+		return nil, err
+	}
+	if required {
+		slog.Warn("password login refused for sso domain", "domain", EmailDomain(email))
+		return nil, ErrSSORequired
+	}
+	return a.next.Authenticate(ctx, email, password)
+}
+
+// NewSSOEnforcingAuthenticator refuses password logins for addresses whose
+// domain enforces single sign-on. Wrap the local authenticator with it, not
+// authenticators that are the organization's sign-on, such as LDAP.
+func NewSSOEnforcingAuthenticator(next Authenticator, policy DomainPolicy) Authenticator {
+	return &ssoEnforcingAuthenticator{next: next, policy: policy}
+}
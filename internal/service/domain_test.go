@@ -0,0 +1,212 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/Atmosfr/user-service/internal/models"
+	"github.com/Atmosfr/user-service/internal/repository"
+	"github.com/Atmosfr/user-service/internal/validation"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+type mockDomainRepo struct {
+	mock.Mock
+}
+
+func (m *mockDomainRepo) Create(ctx context.Context, d *models.OrganizationDomain) error {
+	return m.Called(ctx, d).Error(0)
+}
+
+func (m *mockDomainRepo) List(ctx context.Context, orgID int64) ([]*models.OrganizationDomain, error) {
+	args := m.Called(ctx, orgID)
+	domains, _ := args.Get(0).([]*models.OrganizationDomain)
+	return domains, args.Error(1)
+}
+
+func (m *mockDomainRepo) Find(ctx context.Context, orgID, id int64) (*models.OrganizationDomain, error) {
+	args := m.Called(ctx, orgID, id)
+	d, _ := args.Get(0).(*models.OrganizationDomain)
+	return d, args.Error(1)
+}
+
+func (m *mockDomainRepo) MarkVerified(ctx context.Context, d *models.OrganizationDomain) error {
+	args := m.Called(ctx, d)
+	if args.Error(0) == nil {
+		now := time.Now()
+		d.VerifiedAt = &now
+	}
+	return args.Error(0)
+}
+
+func (m *mockDomainRepo) UpdateSettings(ctx context.Context, d *models.OrganizationDomain) error {
+	return m.Called(ctx, d).Error(0)
+}
+
+func (m *mockDomainRepo) Delete(ctx context.Context, orgID, id int64) error {
+	return m.Called(ctx, orgID, id).Error(0)
+}
+
+func (m *mockDomainRepo) FindVerified(ctx context.Context, domain string) (*models.OrganizationDomain, error) {
+	args := m.Called(ctx, domain)
+	d, _ := args.Get(0).(*models.OrganizationDomain)
+	return d, args.Error(1)
+}
+
+// fakeResolver serves TXT records from a map, names without records fail like NXDOMAIN.
+type fakeResolver map[string][]string
+
+func (r fakeResolver) LookupTXT(ctx context.Context, name string) ([]string, error) {
+	records, ok := r[name]
+	if !ok {
+		return nil, errors.New("no such host")
+	}
+	return records, nil
+}
+
+func boolPtr(b bool) *bool {
+	return &b
+}
+
+func TestDomainService_Claim(t *testing.T) {
+	repo := new(mockDomainRepo)
+	orgs := new(mockOrganizationRepo)
+	memberships(orgs, map[int64]string{1: models.OrgRoleAdmin, 3: models.OrgRoleMember})
+	repo.On("Create", mock.Anything, mock.MatchedBy(func(d *models.OrganizationDomain) bool {
+		return d.OrganizationID == 1 && d.Domain == "acme.com" && len(d.VerificationToken) == 32
+	})).Return(nil)
+	svc := NewDomainService(repo, orgs, fakeResolver{})
+
+	d, err := svc.Claim(context.Background(), 1, 1, " Acme.com ")
+	require.NoError(t, err)
+	require.Equal(t, "_user-service-challenge.acme.com", d.TXTRecordName)
+	require.Equal(t, "user-service-verification="+d.VerificationToken, d.TXTRecordValue)
+
+	_, err = svc.Claim(context.Background(), 1, 1, "not a domain")
+	require.ErrorIs(t, err, validation.ErrInvalidDomain)
+
+	_, err = svc.Claim(context.Background(), 3, 1, "acme.com")
+	require.ErrorIs(t, err, ErrOrganizationAdminOnly)
+
+	_, err = svc.Claim(context.Background(), 99, 1, "acme.com")
+	require.ErrorIs(t, err, repository.ErrOrganizationNotFound)
+	repo.AssertNumberOfCalls(t, "Create", 1)
+}
+
+func TestDomainService_Verify(t *testing.T) {
+	tests := []struct {
+		name      string
+		records   fakeResolver
+		markErr   error
+		expectErr error
+	}{
+		{
+			name:    "record published",
+			records: fakeResolver{"_user-service-challenge.acme.com": {"v=spf1 -all", "user-service-verification=tok"}},
+		},
+		{
+			name:      "record missing",
+			records:   fakeResolver{},
+			expectErr: ErrDomainVerificationFailed,
+		},
+		{
+			name:      "other token",
+			records:   fakeResolver{"_user-service-challenge.acme.com": {"user-service-verification=other"}},
+			expectErr: ErrDomainVerificationFailed,
+		},
+		{
+			name:      "verified by another organization",
+			records:   fakeResolver{"_user-service-challenge.acme.com": {"user-service-verification=tok"}},
+			markErr:   repository.ErrDomainVerifiedElsewhere,
+			expectErr: repository.ErrDomainVerifiedElsewhere,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := new(mockDomainRepo)
+			orgs := new(mockOrganizationRepo)
+			memberships(orgs, map[int64]string{1: models.OrgRoleOwner})
+			repo.On("Find", mock.Anything, int64(1), int64(4)).
+				Return(&models.OrganizationDomain{ID: 4, OrganizationID: 1, Domain: "acme.com", VerificationToken: "tok"}, nil)
+			repo.On("MarkVerified", mock.Anything, mock.Anything).Return(tt.markErr)
+			svc := NewDomainService(repo, orgs, tt.records)
+
+			d, err := svc.Verify(context.Background(), 1, 1, 4)
+			if tt.expectErr != nil {
+				require.ErrorIs(t, err, tt.expectErr)
+				return
+			}
+			require.NoError(t, err)
+			require.True(t, d.IsVerified())
+			require.Empty(t, d.TXTRecordName)
+		})
+	}
+}
+
+func TestDomainService_JoinByDomain(t *testing.T) {
+	verifiedAt := time.Now()
+	acme := &models.OrganizationDomain{ID: 4, OrganizationID: 1, Domain: "acme.com", VerifiedAt: &verifiedAt, AutoJoin: true}
+	manual := &models.OrganizationDomain{ID: 5, OrganizationID: 2, Domain: "manual.com", VerifiedAt: &verifiedAt}
+
+	tests := []struct {
+		name     string
+		user     *models.User
+		addErr   error
+		wantJoin bool
+	}{
+		{name: "verified address joins", user: &models.User{ID: 10, Email: "bob@Acme.com", EmailVerifiedAt: &verifiedAt}, wantJoin: true},
+		{name: "already a member", user: &models.User{ID: 10, Email: "bob@acme.com", EmailVerifiedAt: &verifiedAt}, addErr: repository.ErrMemberAlreadyExists, wantJoin: true},
+		{name: "unverified address", user: &models.User{ID: 10, Email: "bob@acme.com"}},
+		{name: "auto-join disabled", user: &models.User{ID: 10, Email: "bob@manual.com", EmailVerifiedAt: &verifiedAt}},
+		{name: "unclaimed domain", user: &models.User{ID: 10, Email: "bob@gmail.com", EmailVerifiedAt: &verifiedAt}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := new(mockDomainRepo)
+			orgs := new(mockOrganizationRepo)
+			repo.On("FindVerified", mock.Anything, "acme.com").Return(acme, nil)
+			repo.On("FindVerified", mock.Anything, "manual.com").Return(manual, nil)
+			repo.On("FindVerified", mock.Anything, mock.Anything).Return(nil, repository.ErrDomainNotFound)
+			orgs.On("AddMember", mock.Anything, &models.OrganizationMember{OrganizationID: 1, UserID: 10, Role: models.OrgRoleMember}).Return(tt.addErr)
+			svc := NewDomainService(repo, orgs, fakeResolver{})
+
+			require.NoError(t, svc.JoinByDomain(context.Background(), tt.user))
+			if tt.wantJoin {
+				orgs.AssertNumberOfCalls(t, "AddMember", 1)
+			} else {
+				orgs.AssertNotCalled(t, "AddMember", mock.Anything, mock.Anything)
+			}
+		})
+	}
+}
+
+func TestSSOEnforcingAuthenticator(t *testing.T) {
+	verifiedAt := time.Now()
+	repo := new(mockDomainRepo)
+	repo.On("FindVerified", mock.Anything, "acme.com").
+		Return(&models.OrganizationDomain{OrganizationID: 1, Domain: "acme.com", VerifiedAt: &verifiedAt, SSOEnforced: true}, nil)
+	repo.On("FindVerified", mock.Anything, mock.Anything).Return(nil, repository.ErrDomainNotFound)
+	policy := NewDomainService(repo, new(mockOrganizationRepo), fakeResolver{})
+
+	local := &stubAuthenticator{name: "local"}
+	ldap := &stubAuthenticator{name: "ldap"}
+	authn := NewDomainAuthenticator(NewSSOEnforcingAuthenticator(local, policy), map[string]Authenticator{"corp.example": ldap})
+
+	_, err := authn.Authenticate(context.Background(), "bob@acme.com", "secret")
+	require.ErrorIs(t, err, ErrSSORequired)
+	require.Zero(t, local.calls)
+
+	user, err := authn.Authenticate(context.Background(), "bob@gmail.com", "secret")
+	require.NoError(t, err)
+	require.Equal(t, "local", user.Username)
+
+	// directory sign-in is not affected
+	_, err = authn.Authenticate(context.Background(), "alice@corp.example", "secret")
+	require.NoError(t, err)
+	require.Equal(t, 1, ldap.calls)
+}
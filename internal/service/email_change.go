@@ -0,0 +1,265 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/Atmosfr/user-service/internal/mail"
+	"github.com/Atmosfr/user-service/internal/models"
+	"github.com/Atmosfr/user-service/internal/repository"
+	"github.com/Atmosfr/user-service/internal/validation"
+)
+
+const (
+	DefaultEmailChangeTTL       = 24 * time.Hour
+	DefaultEmailChangeRevertTTL = 7 * 24 * time.Hour
+)
+
+var (
+	ErrInvalidEmailChangeLink = errors.New("email change link is invalid or has expired")
+	ErrEmailUnchanged         = errors.New("new email is the current email")
+	ErrEmailChangeNotSent     = errors.New("the confirmation email could not be sent, try again later")
+)
+
+// EmailChangeService moves users to a new address once they confirm it. The
+// old address is told about the change and can cancel or revert it, so a
+// stolen session alone cannot take over the account.
+type EmailChangeService interface {
+	// Request checks the user's password and emails a confirmation link to
+	// newEmail and a revert link to the current address. It replaces any
+	// pending change of the user.
+	Request(ctx context.Context, user *models.User, newEmail, password string) (*models.EmailChange, error)
+	Confirm(ctx context.Context, token string) (*models.User, error)
+	// Revert cancels a pending change or, while it is revertible, undoes a
+	// confirmed one.
+	Revert(ctx context.Context, token string) (*models.EmailChange, error)
+}
+
+type emailChangeService struct {
+	changes       repository.EmailChangeRepository
+	users         repository.UserRepository
+	authenticator Authenticator
+	domains       DomainService
+	mailer        mail.Mailer
+	secret        []byte
+	ttl           time.Duration
+	revertTTL     time.Duration
+	confirmURL    string
+	revertURL     string
+	now           func() time.Time
+}
+
+type EmailChangeOption func(*emailChangeService)
+
+func WithEmailChangeTTL(ttl time.Duration) EmailChangeOption {
+	return func(s *emailChangeService) {
+		s.ttl = ttl
+	}
+}
+
+// WithEmailChangeRevertTTL sets how long after a request the old address can
+// still revert the change.
+func WithEmailChangeRevertTTL(ttl time.Duration) EmailChangeOption {
+	return func(s *emailChangeService) {
+		s.revertTTL = ttl
+	}
+}
+
+// WithEmailChangeURLs sets the pages confirmation and revert links point to,
+// the token is added as the "token" query parameter.
+func WithEmailChangeURLs(confirmURL, revertURL string) EmailChangeOption {
+	return func(s *emailChangeService) {
+		s.confirmURL = confirmURL
+		s.revertURL = revertURL
+	}
+}
+
+const (
+	emailChangeConfirmPurpose = "email-change-confirm"
+	emailChangeRevertPurpose  = "email-change-revert"
+)
+
+func (s *emailChangeService) Request(ctx context.Context, user *models.User, newEmail, password string) (*models.EmailChange, error) {
+	if err := validation.ValidateEmail(newEmail); err != nil {
+		return nil, err
+	}
+	if strings.EqualFold(newEmail, user.Email) {
+		return nil, ErrEmailUnchanged
+	}
+
+	// authenticating by the current address keeps directory and SSO rules in force
+	authenticated, err := s.authenticator.Authenticate(ctx, user.Email, password)
+	if err != nil {
+		if errors.Is(err, repository.ErrUserNotFound) {
+			return nil, repository.ErrInvalidPassword
+		}
+		return nil, err
+	}
+	if authenticated.ID != user.ID {
+		return nil, repository.ErrInvalidPassword
+	}
+
+	required, err := s.domains.SSORequired(ctx, newEmail)
+	if err != nil {
+		return nil, err
+	}
+	if required {
+		return nil, ErrSSORequired
+	}
+	if _, err := s.users.FindByEmail(ctx, newEmail); err == nil {
+		return nil, repository.ErrEmailAlreadyExists
+	} else if !errors.Is(err, repository.ErrUserNotFound) {
+		return nil, err
+	}
+
+	now := s.now()
+	change := &models.EmailChange{
+		UserID:          user.ID,
+		OldEmail:        user.Email,
+		NewEmail:        newEmail,
+		ExpiresAt:       now.Add(s.ttl),
+		RevertibleUntil: now.Add(s.revertTTL),
+	}
+	if err := s.changes.Create(ctx, change); err != nil {
+		return nil, err
+	}
+
+	confirm := signedLinkToken(s.secret, emailChangeConfirmPurpose, change.ID, change.ExpiresAt, strings.ToLower(change.NewEmail))
+	err = s.mailer.Send(ctx, mail.Message{
+		To:      change.NewEmail,
+		Subject: "Confirm your new email address",
+		Body: fmt.Sprintf("Confirm that %s should replace %s as the address of your account:\n%s\n\nThe link expires on %s.\n",
+			change.NewEmail, change.OldEmail, linkWithToken(s.confirmURL, confirm), change.ExpiresAt.UTC().Format(time.RFC1123)),
+	})
+	if err != nil {
+		slog.Error("failed to send email change confirmation", "user_id", user.ID, "err", err)
+		if err := s.changes.Cancel(ctx, change.ID); err != nil {
+			slog.Error("failed to cancel unsent email change", "change_id", change.ID, "err", err)
+		}
+		return nil, ErrEmailChangeNotSent
+	}
+
+	revert := signedLinkToken(s.secret, emailChangeRevertPurpose, change.ID, change.RevertibleUntil, strings.ToLower(change.OldEmail))
+	err = s.mailer.Send(ctx, mail.Message{
+		To:      change.OldEmail,
+		Subject: "Your email address is being changed",
+		Body: fmt.Sprintf("Someone asked to change the address of your account from %s to %s.\n\nIf this was not you, cancel the change and keep your address:\n%s\n\nThe link works until %s.\n",
+			change.OldEmail, change.NewEmail, linkWithToken(s.revertURL, revert), change.RevertibleUntil.UTC().Format(time.RFC1123)),
+	})
+	if err != nil {
+		// without the notice the old address could not undo the change
+		slog.Error("failed to send email change notice", "user_id", user.ID, "err", err)
+		if err := s.changes.Cancel(ctx, change.ID); err != nil {
+			slog.Error("failed to cancel unsent email change", "change_id", change.ID, "err", err)
+		}
+		return nil, ErrEmailChangeNotSent
+	}
+
+	slog.Info("email change requested", "user_id", user.ID, "change_id", change.ID)
+	return change, nil
+}
+
+// open returns the change token was signed for, the signature covers the
+// address the link was sent to and the expiry it carries.
+func (s *emailChangeService) open(ctx context.Context, purpose, token string) (*models.EmailChange, error) {
+	id, exp, ok := parseLinkToken(token)
+	if !ok || s.now().Unix() >= exp {
+		return nil, ErrInvalidEmailChangeLink
+	}
+	change, err := s.changes.Find(ctx, id)
+	if errors.Is(err, repository.ErrEmailChangeNotFound) {
+		return nil, ErrInvalidEmailChangeLink
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	bound, wantExp := change.NewEmail, change.ExpiresAt
+	if purpose == emailChangeRevertPurpose {
+		bound, wantExp = change.OldEmail, change.RevertibleUntil
+	}
+	if wantExp.Unix() != exp || !checkLinkToken(s.secret, purpose, token, strings.ToLower(bound)) {
+		return nil, ErrInvalidEmailChangeLink
+	}
+	return change, nil
+}
+
+func (s *emailChangeService) Confirm(ctx context.Context, token string) (*models.User, error) {
+	change, err := s.open(ctx, emailChangeConfirmPurpose, token)
+	if err != nil {
+		return nil, err
+	}
+	if change.Status != models.EmailChangePending {
+		return nil, ErrInvalidEmailChangeLink
+	}
+
+	if err := s.changes.Confirm(ctx, change); err != nil {
+		if errors.Is(err, repository.ErrEmailChangeNotFound) {
+			return nil, ErrInvalidEmailChangeLink
+		}
+		return nil, err
+	}
+	slog.Info("email changed", "user_id", change.UserID, "change_id", change.ID)
+
+	user, err := s.users.FindByID(ctx, change.UserID)
+	if err != nil {
+		return nil, err
+	}
+	user.PasswordHash = ""
+
+	// the new address is verified, it may belong to a domain with auto-join
+	if err := s.domains.JoinByDomain(ctx, user); err != nil {
+		slog.Error("failed to join organization by domain", "user_id", user.ID, "err", err)
+	}
+	return user, nil
+}
+
+func (s *emailChangeService) Revert(ctx context.Context, token string) (*models.EmailChange, error) {
+	change, err := s.open(ctx, emailChangeRevertPurpose, token)
+	if err != nil {
+		return nil, err
+	}
+
+	switch change.Status {
+	case models.EmailChangePending:
+		err = s.changes.Cancel(ctx, change.ID)
+		change.Status = models.EmailChangeCancelled
+	case models.EmailChangeConfirmed:
+		err = s.changes.Revert(ctx, change)
+	default:
+		return nil, ErrInvalidEmailChangeLink
+	}
+	if errors.Is(err, repository.ErrEmailChangeNotFound) {
+		return nil, ErrInvalidEmailChangeLink
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	slog.Info("email change undone", "user_id", change.UserID, "change_id", change.ID, "status", change.Status)
+	return change, nil
+}
+
+func NewEmailChangeService(changes repository.EmailChangeRepository, users repository.UserRepository, authenticator Authenticator, domains DomainService, mailer mail.Mailer, secret []byte, opts ...EmailChangeOption) EmailChangeService {
+	s := &emailChangeService{
+		changes:       changes,
+		users:         users,
+		authenticator: authenticator,
+		domains:       domains,
+		mailer:        mailer,
+		secret:        secret,
+		ttl:           DefaultEmailChangeTTL,
+		revertTTL:     DefaultEmailChangeRevertTTL,
+		confirmURL:    "http://localhost:8080/email/change/confirm",
+		revertURL:     "http://localhost:8080/email/change/revert",
+		now:           time.Now,
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
@@ -0,0 +1,336 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/Atmosfr/user-service/internal/models"
+	"github.com/Atmosfr/user-service/internal/repository"
+	"github.com/Atmosfr/user-service/internal/testutil"
+	"github.com/Atmosfr/user-service/internal/validation"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/bcrypt"
+)
+
+type mockEmailChangeRepo struct {
+	mock.Mock
+}
+
+func (m *mockEmailChangeRepo) Create(ctx context.Context, change *models.EmailChange) error {
+	return m.Called(ctx, change).Error(0)
+}
+
+func (m *mockEmailChangeRepo) Find(ctx context.Context, id int64) (*models.EmailChange, error) {
+	args := m.Called(ctx, id)
+	change, _ := args.Get(0).(*models.EmailChange)
+	return change, args.Error(1)
+}
+
+func (m *mockEmailChangeRepo) Confirm(ctx context.Context, change *models.EmailChange) error {
+	return m.Called(ctx, change).Error(0)
+}
+
+func (m *mockEmailChangeRepo) Cancel(ctx context.Context, id int64) error {
+	return m.Called(ctx, id).Error(0)
+}
+
+func (m *mockEmailChangeRepo) Revert(ctx context.Context, change *models.EmailChange) error {
+	return m.Called(ctx, change).Error(0)
+}
+
+var (
+	emailChangeSecret = []byte("change-secret")
+	emailChangeNow    = time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC)
+)
+
+// storedEmailChange returns change 5 of bob to bob@new.example as Request
+// stored it at emailChangeNow.
+func storedEmailChange(status string) *models.EmailChange {
+	return &models.EmailChange{
+		ID:              5,
+		UserID:          10,
+		OldEmail:        "bob@example.com",
+		NewEmail:        "bob@new.example",
+		Status:          status,
+		ExpiresAt:       emailChangeNow.Add(DefaultEmailChangeTTL),
+		RevertibleUntil: emailChangeNow.Add(DefaultEmailChangeRevertTTL),
+	}
+}
+
+func emailChangeLinks() (confirm, revert string) {
+	change := storedEmailChange(models.EmailChangePending)
+	return signedLinkToken(emailChangeSecret, emailChangeConfirmPurpose, 5, change.ExpiresAt, change.NewEmail),
+		signedLinkToken(emailChangeSecret, emailChangeRevertPurpose, 5, change.RevertibleUntil, change.OldEmail)
+}
+
+// noDomains has no verified domain at all.
+func noDomains() DomainService {
+	domains := new(mockDomainRepo)
+	domains.On("FindVerified", mock.Anything, mock.Anything).Return(nil, repository.ErrDomainNotFound)
+	return NewDomainService(domains, new(mockOrganizationRepo), fakeResolver{})
+}
+
+func TestEmailChangeService_Request(t *testing.T) {
+	hash, err := bcrypt.GenerateFromPassword([]byte("StrongPass!12"), bcrypt.MinCost)
+	require.NoError(t, err)
+	bob := &models.User{ID: 10, Email: "bob@example.com", PasswordHash: string(hash)}
+	confirm, revert := emailChangeLinks()
+
+	tests := []struct {
+		name      string
+		newEmail  string
+		password  string
+		mailErr   error
+		setupMock func(changes *mockEmailChangeRepo)
+		wantErr   error
+		wantSent  int
+	}{
+		{
+			name:     "notifies both addresses",
+			newEmail: "bob@new.example",
+			password: "StrongPass!12",
+			setupMock: func(changes *mockEmailChangeRepo) {
+				changes.On("Create", mock.Anything, mock.AnythingOfType("*models.EmailChange")).Return(nil).Run(func(args mock.Arguments) {
+					change := args.Get(1).(*models.EmailChange)
+					change.ID = 5
+					change.Status = models.EmailChangePending
+				})
+			},
+			wantSent: 2,
+		},
+		{
+			name:     "unsent mail cancels the change",
+			newEmail: "bob@new.example",
+			password: "StrongPass!12",
+			mailErr:  errors.New("connection refused"),
+			setupMock: func(changes *mockEmailChangeRepo) {
+				changes.On("Create", mock.Anything, mock.Anything).Return(nil).Run(func(args mock.Arguments) {
+					args.Get(1).(*models.EmailChange).ID = 5
+				})
+				changes.On("Cancel", mock.Anything, int64(5)).Return(nil)
+			},
+			wantErr:  ErrEmailChangeNotSent,
+			wantSent: 1,
+		},
+		{name: "wrong password", newEmail: "bob@new.example", password: "WrongPass!12", setupMock: func(changes *mockEmailChangeRepo) {}, wantErr: repository.ErrInvalidPassword},
+		{name: "same address", newEmail: "BOB@example.com", password: "StrongPass!12", setupMock: func(changes *mockEmailChangeRepo) {}, wantErr: ErrEmailUnchanged},
+		{name: "invalid address", newEmail: "bob", password: "StrongPass!12", setupMock: func(changes *mockEmailChangeRepo) {}, wantErr: validation.ErrInvalidEmail},
+		{name: "address taken", newEmail: "alice@example.com", password: "StrongPass!12", setupMock: func(changes *mockEmailChangeRepo) {}, wantErr: repository.ErrEmailAlreadyExists},
+		{name: "sso domain", newEmail: "bob@acme.com", password: "StrongPass!12", setupMock: func(changes *mockEmailChangeRepo) {}, wantErr: ErrSSORequired},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			users := new(testutil.MockUserRepo)
+			users.On("FindByEmail", mock.Anything, "bob@example.com").Return(bob, nil)
+			users.On("FindByEmail", mock.Anything, "alice@example.com").Return(&models.User{ID: 11, Email: "alice@example.com"}, nil)
+			users.On("FindByEmail", mock.Anything, mock.Anything).Return(nil, repository.ErrUserNotFound)
+			domains := new(mockDomainRepo)
+			verifiedAt := emailChangeNow.Add(-time.Hour)
+			domains.On("FindVerified", mock.Anything, "acme.com").
+				Return(&models.OrganizationDomain{OrganizationID: 1, Domain: "acme.com", VerifiedAt: &verifiedAt, SSOEnforced: true}, nil)
+			domains.On("FindVerified", mock.Anything, mock.Anything).Return(nil, repository.ErrDomainNotFound)
+			changes := new(mockEmailChangeRepo)
+			mailer := &recordingMailer{err: tt.mailErr}
+			svc := NewEmailChangeService(changes, users, NewLocalAuthenticator(users), NewDomainService(domains, new(mockOrganizationRepo), fakeResolver{}),
+				mailer, emailChangeSecret, WithEmailChangeURLs("https://app.example.com/email/confirm", "https://app.example.com/email/revert")).(*emailChangeService)
+			svc.now = func() time.Time { return emailChangeNow }
+			tt.setupMock(changes)
+
+			change, err := svc.Request(context.Background(), bob, tt.newEmail, tt.password)
+			require.ErrorIs(t, err, tt.wantErr)
+			require.Len(t, mailer.sent, tt.wantSent)
+			changes.AssertExpectations(t)
+			if tt.wantSent == 0 {
+				changes.AssertNotCalled(t, "Create", mock.Anything, mock.Anything)
+			}
+			if tt.wantErr != nil {
+				require.Nil(t, change)
+				return
+			}
+
+			require.Equal(t, "bob@example.com", change.OldEmail)
+			require.Equal(t, emailChangeNow.Add(DefaultEmailChangeTTL), change.ExpiresAt)
+			require.Equal(t, "bob@new.example", mailer.sent[0].To)
+			require.Contains(t, mailer.sent[0].Body, "https://app.example.com/email/confirm?token=")
+			require.Equal(t, confirm, tokenFromMail(t, mailer.sent[0]))
+			require.Equal(t, "bob@example.com", mailer.sent[1].To)
+			require.Contains(t, mailer.sent[1].Body, "https://app.example.com/email/revert?token=")
+			require.Equal(t, revert, tokenFromMail(t, mailer.sent[1]))
+		})
+	}
+}
+
+func TestEmailChangeService_Confirm(t *testing.T) {
+	confirm, revert := emailChangeLinks()
+
+	tests := []struct {
+		name      string
+		token     string
+		now       time.Time
+		change    *models.EmailChange
+		setupMock func(changes *mockEmailChangeRepo, users *testutil.MockUserRepo)
+		wantErr   error
+	}{
+		{
+			name:   "success",
+			token:  confirm,
+			now:    emailChangeNow,
+			change: storedEmailChange(models.EmailChangePending),
+			setupMock: func(changes *mockEmailChangeRepo, users *testutil.MockUserRepo) {
+				changes.On("Confirm", mock.Anything, storedEmailChange(models.EmailChangePending)).Return(nil)
+				users.On("FindByID", mock.Anything, int64(10)).Return(&models.User{ID: 10, Email: "bob@new.example", PasswordHash: "hash"}, nil)
+			},
+		},
+		{
+			// links only work for the flow they were sent for
+			name:      "revert link",
+			token:     revert,
+			now:       emailChangeNow,
+			change:    storedEmailChange(models.EmailChangePending),
+			setupMock: func(changes *mockEmailChangeRepo, users *testutil.MockUserRepo) {},
+			wantErr:   ErrInvalidEmailChangeLink,
+		},
+		{
+			name:   "address taken meanwhile",
+			token:  confirm,
+			now:    emailChangeNow,
+			change: storedEmailChange(models.EmailChangePending),
+			setupMock: func(changes *mockEmailChangeRepo, users *testutil.MockUserRepo) {
+				changes.On("Confirm", mock.Anything, mock.Anything).Return(repository.ErrEmailAlreadyExists)
+			},
+			wantErr: repository.ErrEmailAlreadyExists,
+		},
+		{
+			name:      "already confirmed",
+			token:     confirm,
+			now:       emailChangeNow,
+			change:    storedEmailChange(models.EmailChangeConfirmed),
+			setupMock: func(changes *mockEmailChangeRepo, users *testutil.MockUserRepo) {},
+			wantErr:   ErrInvalidEmailChangeLink,
+		},
+		{
+			// the confirmation link expires before the revert link
+			name:      "expired",
+			token:     confirm,
+			now:       emailChangeNow.Add(DefaultEmailChangeTTL),
+			setupMock: func(changes *mockEmailChangeRepo, users *testutil.MockUserRepo) {},
+			wantErr:   ErrInvalidEmailChangeLink,
+		},
+		{
+			name:  "unknown change",
+			token: confirm,
+			now:   emailChangeNow,
+			setupMock: func(changes *mockEmailChangeRepo, users *testutil.MockUserRepo) {
+				changes.On("Find", mock.Anything, int64(5)).Return(nil, repository.ErrEmailChangeNotFound)
+			},
+			wantErr: ErrInvalidEmailChangeLink,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			changes := new(mockEmailChangeRepo)
+			users := new(testutil.MockUserRepo)
+			if tt.change != nil {
+				changes.On("Find", mock.Anything, int64(5)).Return(tt.change, nil)
+			}
+			svc := NewEmailChangeService(changes, users, NewLocalAuthenticator(users), noDomains(), &recordingMailer{}, emailChangeSecret).(*emailChangeService)
+			svc.now = func() time.Time { return tt.now }
+			tt.setupMock(changes, users)
+
+			user, err := svc.Confirm(context.Background(), tt.token)
+			require.ErrorIs(t, err, tt.wantErr)
+			changes.AssertExpectations(t)
+			users.AssertExpectations(t)
+			if tt.wantErr != nil {
+				return
+			}
+			require.Equal(t, "bob@new.example", user.Email)
+			require.Empty(t, user.PasswordHash)
+		})
+	}
+}
+
+func TestEmailChangeService_Revert(t *testing.T) {
+	confirm, revert := emailChangeLinks()
+
+	tests := []struct {
+		name       string
+		token      string
+		now        time.Time
+		change     *models.EmailChange
+		setupMock  func(changes *mockEmailChangeRepo)
+		wantErr    error
+		wantStatus string
+	}{
+		{
+			name:   "pending change is cancelled",
+			token:  revert,
+			now:    emailChangeNow,
+			change: storedEmailChange(models.EmailChangePending),
+			setupMock: func(changes *mockEmailChangeRepo) {
+				changes.On("Cancel", mock.Anything, int64(5)).Return(nil)
+			},
+			wantStatus: models.EmailChangeCancelled,
+		},
+		{
+			name:   "confirmed change is reverted",
+			token:  revert,
+			now:    emailChangeNow.Add(DefaultEmailChangeTTL),
+			change: storedEmailChange(models.EmailChangeConfirmed),
+			setupMock: func(changes *mockEmailChangeRepo) {
+				changes.On("Revert", mock.Anything, mock.MatchedBy(func(c *models.EmailChange) bool { return c.ID == 5 })).Return(nil)
+			},
+			wantStatus: models.EmailChangeConfirmed,
+		},
+		{
+			name:      "confirm link",
+			token:     confirm,
+			now:       emailChangeNow,
+			change:    storedEmailChange(models.EmailChangePending),
+			setupMock: func(changes *mockEmailChangeRepo) {},
+			wantErr:   ErrInvalidEmailChangeLink,
+		},
+		{
+			name:      "already cancelled",
+			token:     revert,
+			now:       emailChangeNow,
+			change:    storedEmailChange(models.EmailChangeCancelled),
+			setupMock: func(changes *mockEmailChangeRepo) {},
+			wantErr:   ErrInvalidEmailChangeLink,
+		},
+		{
+			name:      "expired",
+			token:     revert,
+			now:       emailChangeNow.Add(DefaultEmailChangeRevertTTL),
+			change:    storedEmailChange(models.EmailChangeConfirmed),
+			setupMock: func(changes *mockEmailChangeRepo) {},
+			wantErr:   ErrInvalidEmailChangeLink,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			changes := new(mockEmailChangeRepo)
+			users := new(testutil.MockUserRepo)
+			changes.On("Find", mock.Anything, int64(5)).Return(tt.change, nil)
+			svc := NewEmailChangeService(changes, users, NewLocalAuthenticator(users), noDomains(), &recordingMailer{}, emailChangeSecret).(*emailChangeService)
+			svc.now = func() time.Time { return tt.now }
+			tt.setupMock(changes)
+
+			change, err := svc.Revert(context.Background(), tt.token)
+			require.ErrorIs(t, err, tt.wantErr)
+			if tt.wantErr != nil {
+				changes.AssertNotCalled(t, "Cancel", mock.Anything, mock.Anything)
+				changes.AssertNotCalled(t, "Revert", mock.Anything, mock.Anything)
+				return
+			}
+			require.Equal(t, tt.wantStatus, change.Status)
+			changes.AssertExpectations(t)
+		})
+	}
+}
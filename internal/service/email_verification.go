@@ -0,0 +1,131 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/Atmosfr/user-service/internal/mail"
+	"github.com/Atmosfr/user-service/internal/models"
+	"github.com/Atmosfr/user-service/internal/repository"
+)
+
+const DefaultEmailVerificationTTL = 48 * time.Hour
+
+var (
+	ErrInvalidVerificationLink = errors.New("verification link is invalid or has expired")
+	ErrEmailAlreadyVerified    = errors.New("email address is already verified")
+	ErrVerificationNotSent     = errors.New("the verification email could not be sent, try again later")
+)
+
+// EmailVerificationService proves users own their address. Links are signed
+// for the address they were sent to, so they stop working once it changes.
+type EmailVerificationService interface {
+	Send(ctx context.Context, user *models.User) error
+	// Confirm verifies the address and joins the user to the organization that
+	// verified its domain, if that organization allows it.
+	Confirm(ctx context.Context, token string) (*models.User, error)
+}
+
+type emailVerificationService struct {
+	users     repository.UserRepository
+	domains   DomainService
+	mailer    mail.Mailer
+	secret    []byte
+	ttl       time.Duration
+	verifyURL string
+	now       func() time.Time
+}
+
+type EmailVerificationOption func(*emailVerificationService)
+
+func WithEmailVerificationTTL(ttl time.Duration) EmailVerificationOption {
+	return func(s *emailVerificationService) {
+		s.ttl = ttl
+	}
+}
+
+// WithEmailVerificationURL sets the page verification links point to, the token
+// is added as the "token" query parameter.
+func WithEmailVerificationURL(verifyURL string) EmailVerificationOption {
+	return func(s *emailVerificationService) {
+		s.verifyURL = verifyURL
+	}
+}
+
+const emailVerificationPurpose = "email-verification"
+
+func (s *emailVerificationService) Send(ctx context.Context, user *models.User) error {
+	if user.EmailVerifiedAt != nil {
+		return ErrEmailAlreadyVerified
+	}
+
+	expiresAt := s.now().Add(s.ttl)
+	token := signedLinkToken(s.secret, emailVerificationPurpose, user.ID, expiresAt, strings.ToLower(user.Email))
+	err := s.mailer.Send(ctx, mail.Message{
+		To:      user.Email,
+		Subject: "Verify your email address",
+		Body: fmt.Sprintf("Confirm that %s is your address:\n%s\n\nThe link expires on %s.\n",
+			user.Email, linkWithToken(s.verifyURL, token), expiresAt.UTC().Format(time.RFC1123)),
+	})
+	if err != nil {
+		slog.Error("failed to send verification email", "user_id", user.ID, "err", err)
+		return ErrVerificationNotSent
+	}
+
+	slog.Info("verification email sent", "user_id", user.ID)
+	return nil
+}
+
+func (s *emailVerificationService) Confirm(ctx context.Context, token string) (*models.User, error) {
+	id, exp, ok := parseLinkToken(token)
+	if !ok || s.now().Unix() >= exp {
+		return nil, ErrInvalidVerificationLink
+	}
+	user, err := s.users.FindByID(ctx, id)
+	if errors.Is(err, repository.ErrUserNotFound) {
+		return nil, ErrInvalidVerificationLink
+	}
+	if err != nil {
+		return nil, err
+	}
+	if !checkLinkToken(s.secret, emailVerificationPurpose, token, strings.ToLower(user.Email)) {
+		return nil, ErrInvalidVerificationLink
+	}
+
+	verifiedAt, err := s.users.MarkEmailVerified(ctx, user.ID, user.Email)
+	if errors.Is(err, repository.ErrUserNotFound) {
+		return nil, ErrInvalidVerificationLink
+	}
+	if err != nil {
+		return nil, err
+	}
+	user.EmailVerifiedAt = &verifiedAt
+	user.PasswordHash = ""
+	slog.Info("email verified", "user_id", user.ID)
+
+	// the address stays verified even if joining fails, an admin can still add the user
+	if err := s.domains.JoinByDomain(ctx, user); err != nil {
+		slog.Error("failed to join organization by domain", "user_id", user.ID, "err", err)
+	}
+	return user, nil
+}
+
+func NewEmailVerificationService(users repository.UserRepository, domains DomainService, mailer mail.Mailer, secret []byte, opts ...EmailVerificationOption) EmailVerificationService {
+	s := &emailVerificationService{
+		users:     users,
+		domains:   domains,
+		mailer:    mailer,
+		secret:    secret,
+		ttl:       DefaultEmailVerificationTTL,
+		verifyURL: "http://localhost:8080/email/verify",
+		now:       time.Now,
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
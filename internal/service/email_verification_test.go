@@ -0,0 +1,173 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Atmosfr/user-service/internal/models"
+	"github.com/Atmosfr/user-service/internal/repository"
+	"github.com/Atmosfr/user-service/internal/testutil"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+var (
+	verificationSecret = []byte("verify-secret")
+	verificationNow    = time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC)
+)
+
+// acmeDomains has acme.com verified for organization 1 with auto-join on.
+func acmeDomains() *mockDomainRepo {
+	domains := new(mockDomainRepo)
+	verifiedAt := verificationNow.Add(-time.Hour)
+	domains.On("FindVerified", mock.Anything, "acme.com").
+		Return(&models.OrganizationDomain{OrganizationID: 1, Domain: "acme.com", VerifiedAt: &verifiedAt, AutoJoin: true}, nil)
+	domains.On("FindVerified", mock.Anything, mock.Anything).Return(nil, repository.ErrDomainNotFound)
+	return domains
+}
+
+func TestEmailVerificationService_Send(t *testing.T) {
+	verifiedAt := verificationNow
+
+	tests := []struct {
+		name     string
+		user     *models.User
+		mailErr  error
+		wantErr  error
+		wantSent int
+	}{
+		{
+			name:     "success",
+			user:     &models.User{ID: 10, Email: "Bob@acme.com"},
+			wantSent: 1,
+		},
+		{
+			name:    "already verified",
+			user:    &models.User{ID: 10, Email: "bob@acme.com", EmailVerifiedAt: &verifiedAt},
+			wantErr: ErrEmailAlreadyVerified,
+		},
+		{
+			name:     "mail failure",
+			user:     &models.User{ID: 10, Email: "bob@acme.com"},
+			mailErr:  errors.New("connection refused"),
+			wantErr:  ErrVerificationNotSent,
+			wantSent: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mailer := &recordingMailer{err: tt.mailErr}
+			svc := NewEmailVerificationService(new(testutil.MockUserRepo), NewDomainService(acmeDomains(), new(mockOrganizationRepo), fakeResolver{}),
+				mailer, verificationSecret, WithEmailVerificationURL("https://app.example.com/verify")).(*emailVerificationService)
+			svc.now = func() time.Time { return verificationNow }
+
+			err := svc.Send(context.Background(), tt.user)
+			require.ErrorIs(t, err, tt.wantErr)
+			require.Len(t, mailer.sent, tt.wantSent)
+			if tt.wantErr == nil {
+				require.Equal(t, tt.user.Email, mailer.sent[0].To)
+				require.Contains(t, mailer.sent[0].Body, "https://app.example.com/verify?token=")
+				want := signedLinkToken(verificationSecret, emailVerificationPurpose, 10, verificationNow.Add(DefaultEmailVerificationTTL), "bob@acme.com")
+				require.Equal(t, want, tokenFromMail(t, mailer.sent[0]))
+			}
+		})
+	}
+}
+
+func TestEmailVerificationService_Confirm(t *testing.T) {
+	expiresAt := verificationNow.Add(DefaultEmailVerificationTTL)
+	valid := signedLinkToken(verificationSecret, emailVerificationPurpose, 10, expiresAt, "bob@acme.com")
+
+	tests := []struct {
+		name      string
+		token     string
+		now       time.Time
+		setupMock func(users *testutil.MockUserRepo, orgs *mockOrganizationRepo)
+		wantErr   error
+	}{
+		{
+			name:  "success",
+			token: valid,
+			now:   verificationNow,
+			setupMock: func(users *testutil.MockUserRepo, orgs *mockOrganizationRepo) {
+				users.On("FindByID", mock.Anything, int64(10)).Return(&models.User{ID: 10, Email: "bob@acme.com", PasswordHash: "hash"}, nil)
+				users.On("MarkEmailVerified", mock.Anything, int64(10), "bob@acme.com").Return(verificationNow, nil)
+				orgs.On("AddMember", mock.Anything, &models.OrganizationMember{OrganizationID: 1, UserID: 10, Role: models.OrgRoleMember}).Return(nil)
+			},
+		},
+		{
+			name:  "tampered id",
+			token: strings.Replace(valid, "10.", "11.", 1),
+			now:   verificationNow,
+			setupMock: func(users *testutil.MockUserRepo, orgs *mockOrganizationRepo) {
+				users.On("FindByID", mock.Anything, int64(11)).Return(nil, repository.ErrUserNotFound)
+			},
+			wantErr: ErrInvalidVerificationLink,
+		},
+		{
+			name:      "garbage",
+			token:     "garbage",
+			now:       verificationNow,
+			setupMock: func(users *testutil.MockUserRepo, orgs *mockOrganizationRepo) {},
+			wantErr:   ErrInvalidVerificationLink,
+		},
+		{
+			name:      "forged expiry",
+			token:     "10.1.sig",
+			now:       verificationNow,
+			setupMock: func(users *testutil.MockUserRepo, orgs *mockOrganizationRepo) {},
+			wantErr:   ErrInvalidVerificationLink,
+		},
+		{
+			name:      "expired",
+			token:     valid,
+			now:       expiresAt,
+			setupMock: func(users *testutil.MockUserRepo, orgs *mockOrganizationRepo) {},
+			wantErr:   ErrInvalidVerificationLink,
+		},
+		{
+			name:  "address changed since sending",
+			token: signedLinkToken(verificationSecret, emailVerificationPurpose, 10, expiresAt, "bob@gmail.com"),
+			now:   verificationNow,
+			setupMock: func(users *testutil.MockUserRepo, orgs *mockOrganizationRepo) {
+				users.On("FindByID", mock.Anything, int64(10)).Return(&models.User{ID: 10, Email: "bob@acme.com"}, nil)
+			},
+			wantErr: ErrInvalidVerificationLink,
+		},
+		{
+			name:  "address changed while confirming",
+			token: valid,
+			now:   verificationNow,
+			setupMock: func(users *testutil.MockUserRepo, orgs *mockOrganizationRepo) {
+				users.On("FindByID", mock.Anything, int64(10)).Return(&models.User{ID: 10, Email: "bob@acme.com"}, nil)
+				users.On("MarkEmailVerified", mock.Anything, int64(10), "bob@acme.com").Return(time.Time{}, repository.ErrUserNotFound)
+			},
+			wantErr: ErrInvalidVerificationLink,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			users := new(testutil.MockUserRepo)
+			orgs := new(mockOrganizationRepo)
+			svc := NewEmailVerificationService(users, NewDomainService(acmeDomains(), orgs, fakeResolver{}), &recordingMailer{}, verificationSecret).(*emailVerificationService)
+			svc.now = func() time.Time { return tt.now }
+			tt.setupMock(users, orgs)
+
+			user, err := svc.Confirm(context.Background(), tt.token)
+			require.ErrorIs(t, err, tt.wantErr)
+			users.AssertExpectations(t)
+			orgs.AssertExpectations(t)
+			if tt.wantErr != nil {
+				orgs.AssertNotCalled(t, "AddMember", mock.Anything, mock.Anything)
+				return
+			}
+			require.Equal(t, verificationNow, *user.EmailVerifiedAt)
+			require.Empty(t, user.PasswordHash)
+		})
+	}
+}
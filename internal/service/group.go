@@ -0,0 +1,178 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"strings"
+
+	"github.com/Atmosfr/user-service/internal/models"
+	"github.com/Atmosfr/user-service/internal/repository"
+	"github.com/Atmosfr/user-service/internal/validation"
+)
+
+var ErrGroupAdminOnly = errors.New("only organization owners and admins can manage groups")
+
+// GroupUpdate changes the fields that are set. Move re-parents the group to
+// ParentID, a nil ParentID making it a root group.
+type GroupUpdate struct {
+	Name        *string
+	Description *string
+	Move        bool
+	ParentID    *int64
+}
+
+// GroupService manages the group hierarchy of an organization. Every member can
+// read groups, owners and admins manage them.
+type GroupService interface {
+	Create(ctx context.Context, actorID, orgID int64, name, description string, parentID *int64) (*models.Group, error)
+	List(ctx context.Context, userID, orgID int64) ([]*models.Group, error)
+	Get(ctx context.Context, userID, orgID, id int64) (*models.Group, error)
+	Update(ctx context.Context, actorID, orgID, id int64, update GroupUpdate) (*models.Group, error)
+	// Delete refuses groups that still have subgroups.
+	Delete(ctx context.Context, actorID, orgID, id int64) error
+
+	// ListMembers returns direct members, with effective set also those inherited from subgroups.
+	ListMembers(ctx context.Context, userID, orgID, groupID int64, effective bool) ([]*models.GroupMember, error)
+	AddMember(ctx context.Context, actorID, orgID, groupID, userID int64) error
+	RemoveMember(ctx context.Context, actorID, orgID, groupID, userID int64) error
+
+	// EffectiveGroups returns the groups userID belongs to directly or through a
+	// subgroup. It does not check access and is meant for authorization.
+	EffectiveGroups(ctx context.Context, orgID, userID int64) ([]*models.Group, error)
+}
+
+type groupService struct {
+	repo repository.GroupRepository
+	orgs repository.OrganizationRepository
+}
+
+// manager returns ErrGroupAdminOnly unless actorID may change the organization's groups.
+func (s *groupService) manager(ctx context.Context, orgID, actorID int64) error {
+	actor, err := organizationActor(ctx, s.orgs, orgID, actorID)
+	if err != nil {
+		return err
+	}
+	if !actor.CanManageMembers() {
+		return ErrGroupAdminOnly
+	}
+	return nil
+}
+
+func (s *groupService) Create(ctx context.Context, actorID, orgID int64, name, description string, parentID *int64) (*models.Group, error) {
+	name = strings.TrimSpace(name)
+	if err := validation.ValidateGroup(name, description); err != nil {
+		return nil, err
+	}
+	if err := s.manager(ctx, orgID, actorID); err != nil {
+		return nil, err
+	}
+
+	group := &models.Group{OrganizationID: orgID, ParentID: parentID, Name: name, Description: description}
+	if err := s.repo.Create(ctx, group); err != nil {
+		return nil, err
+	}
+
+	slog.Info("group created", "organization_id", orgID, "group_id", group.ID, "actor_id", actorID)
+	return group, nil
+}
+
+func (s *groupService) List(ctx context.Context, userID, orgID int64) ([]*models.Group, error) {
+	if _, err := organizationActor(ctx, s.orgs, orgID, userID); err != nil {
+		return nil, err
+	}
+	return s.repo.List(ctx, orgID)
+}
+
+func (s *groupService) Get(ctx context.Context, userID, orgID, id int64) (*models.Group, error) {
+	if _, err := organizationActor(ctx, s.orgs, orgID, userID); err != nil {
+		return nil, err
+	}
+	return s.repo.Find(ctx, orgID, id)
+}
+
+func (s *groupService) Update(ctx context.Context, actorID, orgID, id int64, update GroupUpdate) (*models.Group, error) {
+	if err := s.manager(ctx, orgID, actorID); err != nil {
+		return nil, err
+	}
+	group, err := s.repo.Find(ctx, orgID, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if update.Name != nil {
+		group.Name = strings.TrimSpace(*update.Name)
+	}
+	if update.Description != nil {
+		group.Description = *update.Description
+	}
+	if err := validation.ValidateGroup(group.Name, group.Description); err != nil {
+		return nil, err
+	}
+
+	// moving first means a rejected move leaves the group untouched
+	if update.Move {
+		if err := s.repo.Move(ctx, orgID, id, update.ParentID); err != nil {
+			return nil, err
+		}
+		group.ParentID = update.ParentID
+		slog.Info("group moved", "organization_id", orgID, "group_id", id, "parent_id", update.ParentID, "actor_id", actorID)
+	}
+	if update.Name != nil || update.Description != nil {
+		if err := s.repo.Update(ctx, group); err != nil {
+			return nil, err
+		}
+	}
+	return group, nil
+}
+
+func (s *groupService) Delete(ctx context.Context, actorID, orgID, id int64) error {
+	if err := s.manager(ctx, orgID, actorID); err != nil {
+		return err
+	}
+	if err := s.repo.Delete(ctx, orgID, id); err != nil {
+		return err
+	}
+
+	slog.Info("group deleted", "organization_id", orgID, "group_id", id, "actor_id", actorID)
+	return nil
+}
+
+func (s *groupService) ListMembers(ctx context.Context, userID, orgID, groupID int64, effective bool) ([]*models.GroupMember, error) {
+	if _, err := organizationActor(ctx, s.orgs, orgID, userID); err != nil {
+		return nil, err
+	}
+	return s.repo.ListMembers(ctx, orgID, groupID, effective)
+}
+
+func (s *groupService) AddMember(ctx context.Context, actorID, orgID, groupID, userID int64) error {
+	if err := s.manager(ctx, orgID, actorID); err != nil {
+		return err
+	}
+	if err := s.repo.AddMember(ctx, orgID, groupID, userID); err != nil {
+		return err
+	}
+
+	slog.Info("group member added", "organization_id", orgID, "group_id", groupID, "user_id", userID, "actor_id", actorID)
+	return nil
+}
+
+func (s *groupService) RemoveMember(ctx context.Context, actorID, orgID, groupID, userID int64) error {
+	if err := s.manager(ctx, orgID, actorID); err != nil {
+		return err
+	}
+	if err := s.repo.RemoveMember(ctx, orgID, groupID, userID); err != nil {
+		return err
+	}
+
+	slog.Info("group member removed", "organization_id", orgID, "group_id", groupID, "user_id", userID, "actor_id", actorID)
+	return nil
+}
+
+func (s *groupService) EffectiveGroups(ctx context.Context, orgID, userID int64) ([]*models.Group, error) {
+	return s.repo.EffectiveGroups(ctx, orgID, userID)
+}
+
+func NewGroupService(repo repository.GroupRepository, orgs repository.OrganizationRepository) GroupService {
+	return &groupService{repo: repo, orgs: orgs}
+}
@@ -0,0 +1,170 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"github.com/Atmosfr/user-service/internal/models"
+	"github.com/Atmosfr/user-service/internal/repository"
+	"github.com/Atmosfr/user-service/internal/validation"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+type mockGroupRepo struct {
+	mock.Mock
+}
+
+func (m *mockGroupRepo) Create(ctx context.Context, group *models.Group) error {
+	return m.Called(ctx, group).Error(0)
+}
+
+func (m *mockGroupRepo) List(ctx context.Context, orgID int64) ([]*models.Group, error) {
+	args := m.Called(ctx, orgID)
+	return args.Get(0).([]*models.Group), args.Error(1)
+}
+
+func (m *mockGroupRepo) Find(ctx context.Context, orgID, id int64) (*models.Group, error) {
+	args := m.Called(ctx, orgID, id)
+	group, _ := args.Get(0).(*models.Group)
+	return group, args.Error(1)
+}
+
+func (m *mockGroupRepo) Update(ctx context.Context, group *models.Group) error {
+	return m.Called(ctx, group).Error(0)
+}
+
+func (m *mockGroupRepo) Move(ctx context.Context, orgID, id int64, parentID *int64) error {
+	return m.Called(ctx, orgID, id, parentID).Error(0)
+}
+
+func (m *mockGroupRepo) Delete(ctx context.Context, orgID, id int64) error {
+	return m.Called(ctx, orgID, id).Error(0)
+}
+
+func (m *mockGroupRepo) ListMembers(ctx context.Context, orgID, groupID int64, effective bool) ([]*models.GroupMember, error) {
+	args := m.Called(ctx, orgID, groupID, effective)
+	members, _ := args.Get(0).([]*models.GroupMember)
+	return members, args.Error(1)
+}
+
+func (m *mockGroupRepo) AddMember(ctx context.Context, orgID, groupID, userID int64) error {
+	return m.Called(ctx, orgID, groupID, userID).Error(0)
+}
+
+func (m *mockGroupRepo) RemoveMember(ctx context.Context, orgID, groupID, userID int64) error {
+	return m.Called(ctx, orgID, groupID, userID).Error(0)
+}
+
+func (m *mockGroupRepo) EffectiveGroups(ctx context.Context, orgID, userID int64) ([]*models.Group, error) {
+	args := m.Called(ctx, orgID, userID)
+	groups, _ := args.Get(0).([]*models.Group)
+	return groups, args.Error(1)
+}
+
+func int64Ptr(v int64) *int64 { return &v }
+
+func TestGroupService_Create(t *testing.T) {
+	tests := []struct {
+		name      string
+		actorID   int64
+		groupName string
+		expectErr error
+	}{
+		{name: "admin creates", actorID: 1, groupName: "  Platform "},
+		{name: "owner creates", actorID: 2, groupName: "Platform"},
+		{name: "member cannot create", actorID: 3, groupName: "Platform", expectErr: ErrGroupAdminOnly},
+		{name: "non-member sees no organization", actorID: 99, groupName: "Platform", expectErr: repository.ErrOrganizationNotFound},
+		{name: "invalid name", actorID: 1, groupName: " ", expectErr: validation.ErrInvalidGroupName},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			orgs := new(mockOrganizationRepo)
+			memberships(orgs, map[int64]string{1: models.OrgRoleAdmin, 2: models.OrgRoleOwner, 3: models.OrgRoleMember})
+			repo := new(mockGroupRepo)
+			repo.On("Create", mock.Anything, mock.MatchedBy(func(g *models.Group) bool {
+				return g.OrganizationID == 1 && g.Name == "Platform" && *g.ParentID == 5
+			})).Return(nil)
+
+			group, err := NewGroupService(repo, orgs).Create(context.Background(), tt.actorID, 1, tt.groupName, "", int64Ptr(5))
+			if tt.expectErr != nil {
+				require.ErrorIs(t, err, tt.expectErr)
+				repo.AssertNotCalled(t, "Create", mock.Anything, mock.Anything)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, "Platform", group.Name)
+		})
+	}
+}
+
+func TestGroupService_Update(t *testing.T) {
+	name := "SRE"
+	tests := []struct {
+		name      string
+		update    GroupUpdate
+		moveErr   error
+		expectErr error
+		move      bool
+		rename    bool
+	}{
+		{name: "rename only", update: GroupUpdate{Name: &name}, rename: true},
+		{name: "move to root", update: GroupUpdate{Move: true}, move: true},
+		{name: "move and rename", update: GroupUpdate{Name: &name, Move: true, ParentID: int64Ptr(2)}, move: true, rename: true},
+		{name: "cycle leaves group untouched", update: GroupUpdate{Name: &name, Move: true, ParentID: int64Ptr(4)}, moveErr: repository.ErrGroupCycle, expectErr: repository.ErrGroupCycle, move: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			orgs := new(mockOrganizationRepo)
+			memberships(orgs, map[int64]string{1: models.OrgRoleAdmin})
+			repo := new(mockGroupRepo)
+			repo.On("Find", mock.Anything, int64(1), int64(3)).Return(&models.Group{ID: 3, OrganizationID: 1, ParentID: int64Ptr(2), Name: "Ops"}, nil)
+			repo.On("Move", mock.Anything, int64(1), int64(3), tt.update.ParentID).Return(tt.moveErr)
+			repo.On("Update", mock.Anything, mock.Anything).Return(nil)
+
+			group, err := NewGroupService(repo, orgs).Update(context.Background(), 1, 1, 3, tt.update)
+			if tt.move {
+				repo.AssertCalled(t, "Move", mock.Anything, int64(1), int64(3), tt.update.ParentID)
+			} else {
+				repo.AssertNotCalled(t, "Move", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+			}
+			if !tt.rename {
+				repo.AssertNotCalled(t, "Update", mock.Anything, mock.Anything)
+			}
+			if tt.expectErr != nil {
+				require.ErrorIs(t, err, tt.expectErr)
+				return
+			}
+			require.NoError(t, err)
+			if tt.move {
+				require.Equal(t, tt.update.ParentID, group.ParentID)
+			}
+			if tt.rename {
+				require.Equal(t, "SRE", group.Name)
+			}
+		})
+	}
+}
+
+func TestGroupService_Members(t *testing.T) {
+	orgs := new(mockOrganizationRepo)
+	memberships(orgs, map[int64]string{1: models.OrgRoleAdmin, 3: models.OrgRoleMember})
+	repo := new(mockGroupRepo)
+	repo.On("ListMembers", mock.Anything, int64(1), int64(2), true).Return([]*models.GroupMember{{GroupID: 4, UserID: 3}}, nil)
+	repo.On("AddMember", mock.Anything, int64(1), int64(2), int64(3)).Return(nil)
+	svc := NewGroupService(repo, orgs)
+	ctx := context.Background()
+
+	members, err := svc.ListMembers(ctx, 3, 1, 2, true)
+	require.NoError(t, err)
+	require.Len(t, members, 1)
+
+	_, err = svc.ListMembers(ctx, 99, 1, 2, true)
+	require.ErrorIs(t, err, repository.ErrOrganizationNotFound)
+
+	require.ErrorIs(t, svc.AddMember(ctx, 3, 1, 2, 3), ErrGroupAdminOnly)
+	require.NoError(t, svc.AddMember(ctx, 1, 1, 2, 3))
+	repo.AssertNumberOfCalls(t, "AddMember", 1)
+}
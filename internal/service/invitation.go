@@ -0,0 +1,399 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/Atmosfr/user-service/internal/auth"
+	"github.com/Atmosfr/user-service/internal/mail"
+	"github.com/Atmosfr/user-service/internal/models"
+	"github.com/Atmosfr/user-service/internal/repository"
+	"github.com/Atmosfr/user-service/internal/validation"
+	"golang.org/x/crypto/bcrypt"
+)
+
+const DefaultInvitationTTL = 7 * 24 * time.Hour
+
+var (
+	ErrInvalidInvitation       = errors.New("invitation link is invalid or has expired")
+	ErrInvitationEmailMismatch = errors.New("invitation was sent to a different email address")
+	ErrInvitationNeedsLogin    = errors.New("an account with this email already exists, sign in to accept the invitation")
+	ErrInvitationNotSent       = errors.New("invitation was saved but the email could not be sent, try resending it")
+)
+
+// InvitationPreview is what the holder of an invitation link may see before
+// accepting. AccountExists tells clients whether to offer sign-in or registration.
+type InvitationPreview struct {
+	*models.Invitation
+	AccountExists bool `json:"account_exists"`
+}
+
+// InvitationService invites people to organizations by email. Links carry the
+// invitation ID and expiry signed with HMAC, so resending (which moves the
+// expiry) or revoking invalidates links sent earlier.
+type InvitationService interface {
+	Invite(ctx context.Context, actorID, orgID int64, email, role string) (*models.Invitation, error)
+	ListPending(ctx context.Context, actorID, orgID int64) ([]*models.Invitation, error)
+	Revoke(ctx context.Context, actorID, orgID, id int64) error
+	// Resend extends the expiry and emails a fresh link.
+	Resend(ctx context.Context, actorID, orgID, id int64) (*models.Invitation, error)
+
+	Preview(ctx context.Context, token string) (*InvitationPreview, error)
+	// Accept joins an existing user, whose email must be the invited address.
+	Accept(ctx context.Context, user *models.User, token string) (*models.Invitation, error)
+	// Register creates an account for the invited address, which counts as
+	// verified, and joins it. The returned token has the organization active.
+	Register(ctx context.Context, token, password, username string, consent RegistrationConsent) (*LoginResponse, error)
+}
+
+type invitationService struct {
+	repo      repository.InvitationRepository
+	orgs      repository.OrganizationRepository
+	users     repository.UserRepository
+	mailer    mail.Mailer
+	secret    []byte
+	ttl       time.Duration
+	acceptURL string
+	domains   DomainService
+	consents  ConsentService
+	audit     AuditLog
+	now       func() time.Time
+}
+
+type InvitationOption func(*invitationService)
+
+func WithInvitationTTL(ttl time.Duration) InvitationOption {
+	return func(s *invitationService) {
+		s.ttl = ttl
+	}
+}
+
+// WithInvitationURL sets the page invitation links point to, the token is added
+// as the "token" query parameter.
+func WithInvitationURL(acceptURL string) InvitationOption {
+	return func(s *invitationService) {
+		s.acceptURL = acceptURL
+	}
+}
+
+// WithInvitationDomains applies the organizations' domain settings to accounts
+// registered from invitations: SSO-enforced domains cannot register with a
+// password and verified addresses join by domain.
+func WithInvitationDomains(domains DomainService) InvitationOption {
+	return func(s *invitationService) {
+		s.domains = domains
+	}
+}
+
+// WithInvitationConsents requires accounts registered from invitations to
+// accept the current legal documents and records their choices.
+func WithInvitationConsents(consents ConsentService) InvitationOption {
+	return func(s *invitationService) {
+		s.consents = consents
+	}
+}
+
+// WithInvitationAuditLog records accounts registered from invitations.
+func WithInvitationAuditLog(audit AuditLog) InvitationOption {
+	return func(s *invitationService) {
+		s.audit = audit
+	}
+}
+
+const invitationPurpose = "invitation"
+
+func (s *invitationService) token(inv *models.Invitation) string {
+	return signedLinkToken(s.secret, invitationPurpose, inv.ID, inv.ExpiresAt, "")
+}
+
+// open verifies token and returns its invitation if it can still be accepted.
+func (s *invitationService) open(ctx context.Context, token string) (*models.Invitation, error) {
+	id, exp, ok := parseLinkToken(token)
+	if !ok || !checkLinkToken(s.secret, invitationPurpose, token, "") {
+		return nil, ErrInvalidInvitation
+	}
+
+	inv, err := s.repo.Find(ctx, id)
+	if errors.Is(err, repository.ErrInvitationNotFound) {
+		return nil, ErrInvalidInvitation
+	}
+	if err != nil {
+		return nil, err
+	}
+	if inv.ExpiresAt.Unix() != exp || !inv.IsOpen(s.now()) {
+		return nil, ErrInvalidInvitation
+	}
+	return inv, nil
+}
+
+func (s *invitationService) link(inv *models.Invitation) string {
+	return linkWithToken(s.acceptURL, s.token(inv))
+}
+
+func (s *invitationService) send(ctx context.Context, actorID int64, inv *models.Invitation) error {
+	inviter := "A member"
+	if actor, err := s.users.FindByID(ctx, actorID); err == nil {
+		inviter = actor.Username
+	}
+
+	err := s.mailer.Send(ctx, mail.Message{
+		To:      inv.Email,
+		Subject: fmt.Sprintf("You have been invited to join %s", inv.OrganizationName),
+		Body: fmt.Sprintf("%s invited you to join %s as %s.\n\nAccept the invitation:\n%s\n\nThe link expires on %s.\n",
+			inviter, inv.OrganizationName, inv.Role, s.link(inv), inv.ExpiresAt.UTC().Format(time.RFC1123)),
+	})
+	if err != nil {
+		slog.Error("failed to send invitation", "invitation_id", inv.ID, "err", err)
+		return ErrInvitationNotSent
+	}
+	return nil
+}
+
+// authorize checks that actorID may invite to orgID with role.
+func (s *invitationService) authorize(ctx context.Context, actorID, orgID int64, role string) error {
+	actor, err := organizationActor(ctx, s.orgs, orgID, actorID)
+	if err != nil {
+		return err
+	}
+	if !actor.CanManageMembers() {
+		return ErrOrganizationAdminOnly
+	}
+	if role == models.OrgRoleOwner && actor.Role != models.OrgRoleOwner {
+		return ErrOrganizationOwnerOnly
+	}
+	return nil
+}
+
+func (s *invitationService) Invite(ctx context.Context, actorID, orgID int64, email, role string) (*models.Invitation, error) {
+	email = strings.TrimSpace(email)
+	if err := validation.ValidateEmail(email); err != nil {
+		return nil, err
+	}
+	if !models.IsValidOrgRole(role) {
+		return nil, ErrInvalidOrganizationRole
+	}
+	if err := s.authorize(ctx, actorID, orgID, role); err != nil {
+		return nil, err
+	}
+
+	if user, err := s.users.FindByEmail(ctx, email); err == nil {
+		if _, err := s.orgs.FindMembership(ctx, orgID, user.ID); err == nil {
+			return nil, repository.ErrMemberAlreadyExists
+		}
+	}
+
+	inv := &models.Invitation{
+		OrganizationID: orgID,
+		Email:          email,
+		Role:           role,
+		InvitedBy:      &actorID,
+		ExpiresAt:      s.now().Add(s.ttl).Truncate(time.Second),
+	}
+	if err := s.repo.Create(ctx, inv); err != nil {
+		return nil, err
+	}
+
+	slog.Info("invitation created", "organization_id", orgID, "invitation_id", inv.ID, "role", role, "actor_id", actorID)
+	return inv, s.send(ctx, actorID, inv)
+}
+
+func (s *invitationService) ListPending(ctx context.Context, actorID, orgID int64) ([]*models.Invitation, error) {
+	if err := s.authorize(ctx, actorID, orgID, models.OrgRoleMember); err != nil {
+		return nil, err
+	}
+	return s.repo.ListPending(ctx, orgID)
+}
+
+// find returns an invitation of orgID that an admin of orgID asked for.
+func (s *invitationService) find(ctx context.Context, orgID, id int64) (*models.Invitation, error) {
+	inv, err := s.repo.Find(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if inv.OrganizationID != orgID {
+		return nil, repository.ErrInvitationNotFound
+	}
+	return inv, nil
+}
+
+func (s *invitationService) Revoke(ctx context.Context, actorID, orgID, id int64) error {
+	if err := s.authorize(ctx, actorID, orgID, models.OrgRoleMember); err != nil {
+		return err
+	}
+	inv, err := s.find(ctx, orgID, id)
+	if err != nil {
+		return err
+	}
+	// admins must not be able to take back an owner invitation sent by an owner either
+	if err := s.authorize(ctx, actorID, orgID, inv.Role); err != nil {
+		return err
+	}
+	if err := s.repo.Revoke(ctx, orgID, id); err != nil {
+		return err
+	}
+
+	slog.Info("invitation revoked", "organization_id", orgID, "invitation_id", id, "actor_id", actorID)
+	return nil
+}
+
+func (s *invitationService) Resend(ctx context.Context, actorID, orgID, id int64) (*models.Invitation, error) {
+	if err := s.authorize(ctx, actorID, orgID, models.OrgRoleMember); err != nil {
+		return nil, err
+	}
+	inv, err := s.find(ctx, orgID, id)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.authorize(ctx, actorID, orgID, inv.Role); err != nil {
+		return nil, err
+	}
+
+	expiresAt := s.now().Add(s.ttl).Truncate(time.Second)
+	if err := s.repo.Renew(ctx, orgID, id, expiresAt); err != nil {
+		return nil, err
+	}
+	inv.ExpiresAt = expiresAt
+	inv.Status = models.InvitationPending
+
+	slog.Info("invitation resent", "organization_id", orgID, "invitation_id", id, "actor_id", actorID)
+	return inv, s.send(ctx, actorID, inv)
+}
+
+func (s *invitationService) Preview(ctx context.Context, token string) (*InvitationPreview, error) {
+	inv, err := s.open(ctx, token)
+	if err != nil {
+		return nil, err
+	}
+	_, err = s.users.FindByEmail(ctx, inv.Email)
+	if err != nil && !errors.Is(err, repository.ErrUserNotFound) {
+		return nil, err
+	}
+	return &InvitationPreview{Invitation: inv, AccountExists: err == nil}, nil
+}
+
+func (s *invitationService) Accept(ctx context.Context, user *models.User, token string) (*models.Invitation, error) {
+	inv, err := s.open(ctx, token)
+	if err != nil {
+		return nil, err
+	}
+
+	invited, err := s.users.FindByEmail(ctx, inv.Email)
+	if errors.Is(err, repository.ErrUserNotFound) {
+		return nil, ErrInvitationEmailMismatch
+	}
+	if err != nil {
+		return nil, err
+	}
+	if invited.ID != user.ID {
+		return nil, ErrInvitationEmailMismatch
+	}
+
+	if err := s.repo.Accept(ctx, inv, user.ID); err != nil {
+		if errors.Is(err, repository.ErrInvitationNotFound) {
+			return nil, ErrInvalidInvitation
+		}
+		return nil, err
+	}
+
+	slog.Info("invitation accepted", "organization_id", inv.OrganizationID, "invitation_id", inv.ID, "user_id", user.ID)
+	return inv, nil
+}
+
+func (s *invitationService) Register(ctx context.Context, token, password, username string, consent RegistrationConsent) (*LoginResponse, error) {
+	inv, err := s.open(ctx, token)
+	if err != nil {
+		return nil, err
+	}
+	if err := validation.ValidateRegister(inv.Email, password, username); err != nil {
+		return nil, err
+	}
+	if s.consents != nil {
+		if err := s.consents.CheckAccepted(ctx, consent.Accepted); err != nil {
+			return nil, err
+		}
+	}
+	if _, err := s.users.FindByEmail(ctx, inv.Email); err == nil {
+		return nil, ErrInvitationNeedsLogin
+	}
+	if s.domains != nil {
+		required, err := s.domains.SSORequired(ctx, inv.Email)
+		if err != nil {
+			return nil, err
+		}
+		if required {
+			return nil, ErrSSORequired
+		}
+	}
+
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return nil, err
+	}
+	// following the emailed link proves the address
+	verifiedAt := s.now()
+	user := &models.User{
+		Email:           inv.Email,
+		PasswordHash:    string(hash),
+		Username:        username,
+		IsActive:        true,
+		State:           models.AccountActive,
+		Role:            "user",
+		EmailVerifiedAt: &verifiedAt,
+		CreatedAt:       verifiedAt,
+		UpdatedAt:       verifiedAt,
+	}
+	if err := s.repo.AcceptNewUser(ctx, inv, user); err != nil {
+		if errors.Is(err, repository.ErrInvitationNotFound) {
+			return nil, ErrInvalidInvitation
+		}
+		return nil, err
+	}
+	if s.audit != nil {
+		s.audit.Record(ctx, &models.AuditEvent{Action: models.AuditUserRegistered, ActorID: &user.ID, TargetID: &user.ID})
+	}
+	if s.consents != nil {
+		// without the record the consent gate asks the user again, so the account stays
+		if err := s.consents.Accept(ctx, user.ID, consent.Accepted, consent.IP); err != nil {
+			slog.Error("failed to record consent on registration", "user_id", user.ID, "err", err)
+		}
+		if err := s.consents.SetMarketing(ctx, user.ID, consent.Marketing, consent.IP); err != nil {
+			slog.Error("failed to record marketing consent on registration", "user_id", user.ID, "err", err)
+		}
+	}
+	if s.domains != nil {
+		if err := s.domains.JoinByDomain(ctx, user); err != nil {
+			slog.Error("failed to join organization by domain", "user_id", user.ID, "err", err)
+		}
+	}
+
+	accessToken, err := auth.GenerateToken(user, AccessTokenDuration, auth.JwtSecret, auth.WithOrganization(inv.OrganizationID, inv.Role))
+	if err != nil {
+		slog.Error("failed to generate token", "err", err)
+		return nil, err
+	}
+	user.PasswordHash = ""
+
+	slog.Info("user registered from invitation", "organization_id", inv.OrganizationID, "invitation_id", inv.ID, "user_id", user.ID)
+	return &LoginResponse{User: user, Token: accessToken}, nil
+}
+
+func NewInvitationService(repo repository.InvitationRepository, orgs repository.OrganizationRepository, users repository.UserRepository, mailer mail.Mailer, secret []byte, opts ...InvitationOption) InvitationService {
+	s := &invitationService{
+		repo:      repo,
+		orgs:      orgs,
+		users:     users,
+		mailer:    mailer,
+		secret:    secret,
+		ttl:       DefaultInvitationTTL,
+		acceptURL: "http://localhost:8080/invitations/accept",
+		now:       time.Now,
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
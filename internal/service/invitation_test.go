@@ -0,0 +1,503 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Atmosfr/user-service/internal/auth"
+	"github.com/Atmosfr/user-service/internal/mail"
+	"github.com/Atmosfr/user-service/internal/models"
+	"github.com/Atmosfr/user-service/internal/repository"
+	"github.com/Atmosfr/user-service/internal/testutil"
+	"github.com/Atmosfr/user-service/internal/validation"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+type mockInvitationRepo struct {
+	mock.Mock
+}
+
+func (m *mockInvitationRepo) Create(ctx context.Context, inv *models.Invitation) error {
+	return m.Called(ctx, inv).Error(0)
+}
+
+func (m *mockInvitationRepo) Find(ctx context.Context, id int64) (*models.Invitation, error) {
+	args := m.Called(ctx, id)
+	inv, _ := args.Get(0).(*models.Invitation)
+	return inv, args.Error(1)
+}
+
+func (m *mockInvitationRepo) ListPending(ctx context.Context, orgID int64) ([]*models.Invitation, error) {
+	args := m.Called(ctx, orgID)
+	invitations, _ := args.Get(0).([]*models.Invitation)
+	return invitations, args.Error(1)
+}
+
+func (m *mockInvitationRepo) Revoke(ctx context.Context, orgID, id int64) error {
+	return m.Called(ctx, orgID, id).Error(0)
+}
+
+func (m *mockInvitationRepo) Renew(ctx context.Context, orgID, id int64, expiresAt time.Time) error {
+	return m.Called(ctx, orgID, id, expiresAt).Error(0)
+}
+
+func (m *mockInvitationRepo) Accept(ctx context.Context, inv *models.Invitation, userID int64) error {
+	return m.Called(ctx, inv, userID).Error(0)
+}
+
+func (m *mockInvitationRepo) AcceptNewUser(ctx context.Context, inv *models.Invitation, user *models.User) error {
+	args := m.Called(ctx, inv, user)
+	user.ID = 20
+	return args.Error(0)
+}
+
+type recordingMailer struct {
+	sent []mail.Message
+	err  error
+}
+
+func (m *recordingMailer) Send(ctx context.Context, msg mail.Message) error {
+	m.sent = append(m.sent, msg)
+	return m.err
+}
+
+// tokenFromMail extracts the token of the acceptance link in msg.
+func tokenFromMail(t *testing.T, msg mail.Message) string {
+	t.Helper()
+	for _, line := range strings.Split(msg.Body, "\n") {
+		if u, err := url.Parse(line); err == nil && u.Query().Has("token") {
+			return u.Query().Get("token")
+		}
+	}
+	t.Fatalf("no link in %q", msg.Body)
+	return ""
+}
+
+var (
+	invitationSecret = []byte("invite-secret")
+	invitationNow    = time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC)
+)
+
+// pendingInvitation returns invitation 5 of Acme to bob@example.com as Invite
+// created it at invitationNow.
+func pendingInvitation() *models.Invitation {
+	return &models.Invitation{
+		ID:               5,
+		OrganizationID:   1,
+		OrganizationName: "Acme",
+		Email:            "bob@example.com",
+		Role:             models.OrgRoleMember,
+		Status:           models.InvitationPending,
+		ExpiresAt:        invitationNow.Add(DefaultInvitationTTL),
+	}
+}
+
+func TestInvitationService_Invite(t *testing.T) {
+	tests := []struct {
+		name      string
+		actorID   int64
+		email     string
+		role      string
+		mailErr   error
+		setupMock func(repo *mockInvitationRepo, users *testutil.MockUserRepo)
+		wantErr   error
+		wantSent  int
+	}{
+		{
+			name:    "success",
+			actorID: 1,
+			email:   " bob@example.com ",
+			role:    models.OrgRoleMember,
+			setupMock: func(repo *mockInvitationRepo, users *testutil.MockUserRepo) {
+				users.On("FindByEmail", mock.Anything, "bob@example.com").Return(nil, repository.ErrUserNotFound)
+				repo.On("Create", mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+					inv := args.Get(1).(*models.Invitation)
+					inv.ID = 5
+					inv.Status = models.InvitationPending
+					inv.OrganizationName = "Acme"
+				}).Return(nil)
+			},
+			wantSent: 1,
+		},
+		{
+			name:    "existing member",
+			actorID: 1,
+			email:   "member@example.com",
+			role:    models.OrgRoleMember,
+			setupMock: func(repo *mockInvitationRepo, users *testutil.MockUserRepo) {
+				users.On("FindByEmail", mock.Anything, "member@example.com").Return(&models.User{ID: 3}, nil)
+			},
+			wantErr: repository.ErrMemberAlreadyExists,
+		},
+		{
+			name:    "mail failure",
+			actorID: 1,
+			email:   "bob@example.com",
+			role:    models.OrgRoleMember,
+			mailErr: errors.New("connection refused"),
+			setupMock: func(repo *mockInvitationRepo, users *testutil.MockUserRepo) {
+				users.On("FindByEmail", mock.Anything, "bob@example.com").Return(nil, repository.ErrUserNotFound)
+				repo.On("Create", mock.Anything, mock.Anything).Return(nil)
+			},
+			wantErr:  ErrInvitationNotSent,
+			wantSent: 1,
+		},
+		{
+			name:      "member cannot invite",
+			actorID:   3,
+			email:     "carol@example.com",
+			role:      models.OrgRoleMember,
+			setupMock: func(repo *mockInvitationRepo, users *testutil.MockUserRepo) {},
+			wantErr:   ErrOrganizationAdminOnly,
+		},
+		{
+			name:      "admin cannot invite owner",
+			actorID:   1,
+			email:     "carol@example.com",
+			role:      models.OrgRoleOwner,
+			setupMock: func(repo *mockInvitationRepo, users *testutil.MockUserRepo) {},
+			wantErr:   ErrOrganizationOwnerOnly,
+		},
+		{
+			name:      "invalid email",
+			actorID:   1,
+			email:     "carol",
+			role:      models.OrgRoleMember,
+			setupMock: func(repo *mockInvitationRepo, users *testutil.MockUserRepo) {},
+			wantErr:   validation.ErrInvalidEmail,
+		},
+		{
+			name:      "invalid role",
+			actorID:   1,
+			email:     "carol@example.com",
+			role:      "root",
+			setupMock: func(repo *mockInvitationRepo, users *testutil.MockUserRepo) {},
+			wantErr:   ErrInvalidOrganizationRole,
+		},
+		{
+			name:      "non-member",
+			actorID:   99,
+			email:     "carol@example.com",
+			role:      models.OrgRoleMember,
+			setupMock: func(repo *mockInvitationRepo, users *testutil.MockUserRepo) {},
+			wantErr:   repository.ErrOrganizationNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := new(mockInvitationRepo)
+			orgs := new(mockOrganizationRepo)
+			users := new(testutil.MockUserRepo)
+			mailer := &recordingMailer{err: tt.mailErr}
+			memberships(orgs, map[int64]string{1: models.OrgRoleAdmin, 2: models.OrgRoleOwner, 3: models.OrgRoleMember})
+			users.On("FindByID", mock.Anything, mock.Anything).Return(&models.User{Username: "alice"}, nil)
+			svc := NewInvitationService(repo, orgs, users, mailer, invitationSecret,
+				WithInvitationURL("https://app.example.com/join?source=email")).(*invitationService)
+			svc.now = func() time.Time { return invitationNow }
+			tt.setupMock(repo, users)
+
+			inv, err := svc.Invite(context.Background(), tt.actorID, 1, tt.email, tt.role)
+			require.ErrorIs(t, err, tt.wantErr)
+			require.Len(t, mailer.sent, tt.wantSent)
+			repo.AssertExpectations(t)
+			if tt.wantSent == 0 {
+				require.Nil(t, inv)
+				repo.AssertNotCalled(t, "Create", mock.Anything, mock.Anything)
+				return
+			}
+
+			require.NotNil(t, inv)
+			require.Equal(t, "bob@example.com", inv.Email)
+			require.Equal(t, tt.actorID, *inv.InvitedBy)
+			require.Equal(t, invitationNow.Add(DefaultInvitationTTL), inv.ExpiresAt)
+			require.Equal(t, "bob@example.com", mailer.sent[0].To)
+			if tt.wantErr == nil {
+				require.Contains(t, mailer.sent[0].Body, "alice invited you to join Acme as member")
+				require.Contains(t, mailer.sent[0].Body, "https://app.example.com/join?")
+				require.Equal(t, svc.token(inv), tokenFromMail(t, mailer.sent[0]))
+				require.True(t, strings.HasPrefix(tokenFromMail(t, mailer.sent[0]), "5."))
+			}
+		})
+	}
+}
+
+func TestInvitationService_Preview(t *testing.T) {
+	valid := signedLinkToken(invitationSecret, invitationPurpose, 5, invitationNow.Add(DefaultInvitationTTL), "")
+
+	tests := []struct {
+		name          string
+		token         string
+		now           time.Time
+		setupMock     func(repo *mockInvitationRepo, users *testutil.MockUserRepo)
+		wantErr       error
+		accountExists bool
+	}{
+		{
+			name:  "new account",
+			token: valid,
+			now:   invitationNow,
+			setupMock: func(repo *mockInvitationRepo, users *testutil.MockUserRepo) {
+				repo.On("Find", mock.Anything, int64(5)).Return(pendingInvitation(), nil)
+				users.On("FindByEmail", mock.Anything, "bob@example.com").Return(nil, repository.ErrUserNotFound)
+			},
+		},
+		{
+			name:  "existing account",
+			token: valid,
+			now:   invitationNow,
+			setupMock: func(repo *mockInvitationRepo, users *testutil.MockUserRepo) {
+				repo.On("Find", mock.Anything, int64(5)).Return(pendingInvitation(), nil)
+				users.On("FindByEmail", mock.Anything, "bob@example.com").Return(&models.User{ID: 10}, nil)
+			},
+			accountExists: true,
+		},
+		{
+			name:      "tampered id",
+			token:     strings.Replace(valid, "5.", "6.", 1),
+			now:       invitationNow,
+			setupMock: func(repo *mockInvitationRepo, users *testutil.MockUserRepo) {},
+			wantErr:   ErrInvalidInvitation,
+		},
+		{
+			name:      "garbage",
+			token:     "garbage",
+			now:       invitationNow,
+			setupMock: func(repo *mockInvitationRepo, users *testutil.MockUserRepo) {},
+			wantErr:   ErrInvalidInvitation,
+		},
+		{
+			name:      "signed by another secret",
+			token:     signedLinkToken([]byte("other-secret"), invitationPurpose, 5, invitationNow.Add(DefaultInvitationTTL), ""),
+			now:       invitationNow,
+			setupMock: func(repo *mockInvitationRepo, users *testutil.MockUserRepo) {},
+			wantErr:   ErrInvalidInvitation,
+		},
+		{
+			name:  "unknown invitation",
+			token: signedLinkToken(invitationSecret, invitationPurpose, 6, invitationNow.Add(DefaultInvitationTTL), ""),
+			now:   invitationNow,
+			setupMock: func(repo *mockInvitationRepo, users *testutil.MockUserRepo) {
+				repo.On("Find", mock.Anything, int64(6)).Return(nil, repository.ErrInvitationNotFound)
+			},
+			wantErr: ErrInvalidInvitation,
+		},
+		{
+			// a resend moves the expiry, so links sent before stop working
+			name:  "superseded link",
+			token: signedLinkToken(invitationSecret, invitationPurpose, 5, invitationNow.Add(DefaultInvitationTTL-time.Hour), ""),
+			now:   invitationNow,
+			setupMock: func(repo *mockInvitationRepo, users *testutil.MockUserRepo) {
+				repo.On("Find", mock.Anything, int64(5)).Return(pendingInvitation(), nil)
+			},
+			wantErr: ErrInvalidInvitation,
+		},
+		{
+			name:  "expired",
+			token: valid,
+			now:   invitationNow.Add(DefaultInvitationTTL),
+			setupMock: func(repo *mockInvitationRepo, users *testutil.MockUserRepo) {
+				repo.On("Find", mock.Anything, int64(5)).Return(pendingInvitation(), nil)
+			},
+			wantErr: ErrInvalidInvitation,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := new(mockInvitationRepo)
+			users := new(testutil.MockUserRepo)
+			svc := NewInvitationService(repo, new(mockOrganizationRepo), users, &recordingMailer{}, invitationSecret).(*invitationService)
+			svc.now = func() time.Time { return tt.now }
+			tt.setupMock(repo, users)
+
+			preview, err := svc.Preview(context.Background(), tt.token)
+			require.ErrorIs(t, err, tt.wantErr)
+			if tt.wantErr == nil {
+				require.Equal(t, "Acme", preview.OrganizationName)
+				require.Equal(t, tt.accountExists, preview.AccountExists)
+			}
+			repo.AssertExpectations(t)
+		})
+	}
+}
+
+func TestInvitationService_Resend(t *testing.T) {
+	repo := new(mockInvitationRepo)
+	orgs := new(mockOrganizationRepo)
+	users := new(testutil.MockUserRepo)
+	mailer := &recordingMailer{}
+	memberships(orgs, map[int64]string{1: models.OrgRoleAdmin})
+	users.On("FindByID", mock.Anything, int64(1)).Return(&models.User{Username: "alice"}, nil)
+	users.On("FindByEmail", mock.Anything, "bob@example.com").Return(nil, repository.ErrUserNotFound)
+	inv := pendingInvitation()
+	repo.On("Find", mock.Anything, int64(5)).Return(inv, nil)
+	now := invitationNow.Add(time.Hour)
+	repo.On("Renew", mock.Anything, int64(1), int64(5), now.Add(DefaultInvitationTTL)).Return(nil)
+	svc := NewInvitationService(repo, orgs, users, mailer, invitationSecret).(*invitationService)
+	svc.now = func() time.Time { return now }
+	first := svc.token(inv)
+	ctx := context.Background()
+
+	resent, err := svc.Resend(ctx, 1, 1, 5)
+	require.NoError(t, err)
+	require.Equal(t, now.Add(DefaultInvitationTTL), resent.ExpiresAt)
+	require.Len(t, mailer.sent, 1)
+
+	// the new expiry is part of the link, so the first link stops working
+	_, err = svc.Preview(ctx, first)
+	require.ErrorIs(t, err, ErrInvalidInvitation)
+	_, err = svc.Preview(ctx, tokenFromMail(t, mailer.sent[0]))
+	require.NoError(t, err)
+	repo.AssertExpectations(t)
+}
+
+func TestInvitationService_Accept(t *testing.T) {
+	bob := &models.User{ID: 10, Email: "bob@example.com"}
+	eve := &models.User{ID: 11, Email: "eve@example.com"}
+
+	tests := []struct {
+		name      string
+		user      *models.User
+		setupMock func(repo *mockInvitationRepo, users *testutil.MockUserRepo)
+		wantErr   error
+	}{
+		{
+			name: "invited user",
+			user: bob,
+			setupMock: func(repo *mockInvitationRepo, users *testutil.MockUserRepo) {
+				users.On("FindByEmail", mock.Anything, "bob@example.com").Return(bob, nil)
+				repo.On("Accept", mock.Anything, pendingInvitation(), int64(10)).Return(nil)
+			},
+		},
+		{
+			name: "other user",
+			user: eve,
+			setupMock: func(repo *mockInvitationRepo, users *testutil.MockUserRepo) {
+				users.On("FindByEmail", mock.Anything, "bob@example.com").Return(bob, nil)
+			},
+			wantErr: ErrInvitationEmailMismatch,
+		},
+		{
+			name: "invited address has no account",
+			user: eve,
+			setupMock: func(repo *mockInvitationRepo, users *testutil.MockUserRepo) {
+				users.On("FindByEmail", mock.Anything, "bob@example.com").Return(nil, repository.ErrUserNotFound)
+			},
+			wantErr: ErrInvitationEmailMismatch,
+		},
+		{
+			name: "accepted concurrently",
+			user: bob,
+			setupMock: func(repo *mockInvitationRepo, users *testutil.MockUserRepo) {
+				users.On("FindByEmail", mock.Anything, "bob@example.com").Return(bob, nil)
+				repo.On("Accept", mock.Anything, pendingInvitation(), int64(10)).Return(repository.ErrInvitationNotFound)
+			},
+			wantErr: ErrInvalidInvitation,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := new(mockInvitationRepo)
+			users := new(testutil.MockUserRepo)
+			repo.On("Find", mock.Anything, int64(5)).Return(pendingInvitation(), nil)
+			svc := NewInvitationService(repo, new(mockOrganizationRepo), users, &recordingMailer{}, invitationSecret).(*invitationService)
+			svc.now = func() time.Time { return invitationNow }
+			tt.setupMock(repo, users)
+
+			inv, err := svc.Accept(context.Background(), tt.user, svc.token(pendingInvitation()))
+			require.ErrorIs(t, err, tt.wantErr)
+			if tt.wantErr == nil {
+				require.Equal(t, pendingInvitation(), inv)
+			} else if errors.Is(tt.wantErr, ErrInvitationEmailMismatch) {
+				repo.AssertNotCalled(t, "Accept", mock.Anything, mock.Anything, mock.Anything)
+			}
+			repo.AssertExpectations(t)
+		})
+	}
+}
+
+func TestInvitationService_Register(t *testing.T) {
+	auth.JwtSecret = []byte("secret")
+
+	tests := []struct {
+		name      string
+		password  string
+		setupMock func(repo *mockInvitationRepo, users *testutil.MockUserRepo)
+		wantErr   error
+	}{
+		{
+			name:     "success",
+			password: "password123",
+			setupMock: func(repo *mockInvitationRepo, users *testutil.MockUserRepo) {
+				users.On("FindByEmail", mock.Anything, "bob@example.com").Return(nil, repository.ErrUserNotFound)
+				repo.On("AcceptNewUser", mock.Anything, pendingInvitation(), mock.MatchedBy(func(u *models.User) bool {
+					return u.Email == "bob@example.com" && u.Username == "bob" && u.EmailVerifiedAt != nil && u.PasswordHash != "password123"
+				})).Return(nil)
+			},
+		},
+		{
+			name:      "short password",
+			password:  "short",
+			setupMock: func(repo *mockInvitationRepo, users *testutil.MockUserRepo) {},
+			wantErr:   validation.ErrPasswordTooShort,
+		},
+		{
+			name:     "account exists",
+			password: "password123",
+			setupMock: func(repo *mockInvitationRepo, users *testutil.MockUserRepo) {
+				users.On("FindByEmail", mock.Anything, "bob@example.com").Return(&models.User{ID: 10}, nil)
+			},
+			wantErr: ErrInvitationNeedsLogin,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := new(mockInvitationRepo)
+			users := new(testutil.MockUserRepo)
+			repo.On("Find", mock.Anything, int64(5)).Return(pendingInvitation(), nil)
+			audit := new(recordingAuditLog)
+			svc := NewInvitationService(repo, new(mockOrganizationRepo), users, &recordingMailer{}, invitationSecret,
+				WithInvitationAuditLog(audit)).(*invitationService)
+			svc.now = func() time.Time { return invitationNow }
+			tt.setupMock(repo, users)
+
+			resp, err := svc.Register(context.Background(), svc.token(pendingInvitation()), tt.password, "bob", RegistrationConsent{})
+			require.ErrorIs(t, err, tt.wantErr)
+			repo.AssertExpectations(t)
+			if tt.wantErr != nil {
+				repo.AssertNotCalled(t, "AcceptNewUser", mock.Anything, mock.Anything, mock.Anything)
+				require.Empty(t, audit.events)
+				return
+			}
+
+			userID := int64(20)
+			require.Equal(t, []*models.AuditEvent{{Action: models.AuditUserRegistered, ActorID: &userID, TargetID: &userID}}, audit.events)
+			require.Empty(t, resp.User.PasswordHash)
+			claims, err := auth.ParseToken(resp.Token, auth.JwtSecret)
+			require.NoError(t, err)
+			require.Equal(t, int64(20), claims.UserID)
+			require.Equal(t, int64(1), claims.OrgID)
+			require.Equal(t, models.OrgRoleMember, claims.OrgRole)
+		})
+	}
+}
+
+func TestInvitationService_RevokeOtherOrganization(t *testing.T) {
+	repo := new(mockInvitationRepo)
+	orgs := new(mockOrganizationRepo)
+	memberships(orgs, map[int64]string{1: models.OrgRoleAdmin})
+	repo.On("Find", mock.Anything, int64(9)).Return(&models.Invitation{ID: 9, OrganizationID: 2}, nil)
+	svc := NewInvitationService(repo, orgs, new(testutil.MockUserRepo), &recordingMailer{}, invitationSecret)
+
+	err := svc.Revoke(context.Background(), 1, 1, 9)
+	require.ErrorIs(t, err, repository.ErrInvitationNotFound)
+	repo.AssertNotCalled(t, "Revoke", mock.Anything, mock.Anything, mock.Anything)
+}
@@ -0,0 +1,176 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"regexp"
+
+	"github.com/Atmosfr/user-service/internal/jsonschema"
+	"github.com/Atmosfr/user-service/internal/models"
+	"github.com/Atmosfr/user-service/internal/repository"
+)
+
+var (
+	ErrInvalidNamespaceName  = errors.New("namespace name must start with a lowercase letter and contain only lowercase letters, digits and underscores")
+	ErrInvalidVisibility     = errors.New(`visibility must be "public" or "private"`)
+	ErrPrivateTokenClaims    = errors.New("only public namespaces can be added to token claims")
+	ErrNamespaceVisibility   = errors.New("namespace has a different visibility")
+	ErrInvalidMetadataFilter = errors.New("metadata filter must name a visibility, a namespace and optionally keys within it")
+)
+
+var namespaceName = regexp.MustCompile(`^[a-z][a-z0-9_]{0,62}$`)
+
+// MetadataService keeps the public and private metadata of users. Every
+// top-level key is a namespace registered by an administrator with a JSON
+// Schema its values must match.
+type MetadataService interface {
+	// RegisterNamespace creates or replaces a namespace. Values already stored
+	// are not checked against a new schema, and the visibility of an existing
+	// namespace cannot change.
+	RegisterNamespace(ctx context.Context, ns *models.MetadataNamespace) error
+	ListNamespaces(ctx context.Context) ([]*models.MetadataNamespace, error)
+	DeleteNamespace(ctx context.Context, name string) error
+
+	UserMetadata(ctx context.Context, userID int64) (*models.User, error)
+	// SetUserMetadata validates value against the namespace's schema and stores
+	// it, a nil value removes the namespace from the user.
+	SetUserMetadata(ctx context.Context, userID int64, visibility, namespace string, value json.RawMessage) (*models.User, error)
+
+	// TokenClaims returns the user's public metadata of the namespaces flagged
+	// for token claims, nil if there is none.
+	TokenClaims(ctx context.Context, user *models.User) (map[string]json.RawMessage, error)
+}
+
+// MetadataClaims is the part of MetadataService token issuers depend on.
+type MetadataClaims interface {
+	TokenClaims(ctx context.Context, user *models.User) (map[string]json.RawMessage, error)
+}
+
+type metadataService struct {
+	repo  repository.MetadataRepository
+	users repository.UserRepository
+}
+
+func validVisibility(visibility string) bool {
+	return visibility == models.MetadataPublic || visibility == models.MetadataPrivate
+}
+
+func (s *metadataService) RegisterNamespace(ctx context.Context, ns *models.MetadataNamespace) error {
+	if !namespaceName.MatchString(ns.Name) {
+		return ErrInvalidNamespaceName
+	}
+	if !validVisibility(ns.Visibility) {
+		return ErrInvalidVisibility
+	}
+	if ns.InTokenClaims && ns.Visibility != models.MetadataPublic {
+		return ErrPrivateTokenClaims
+	}
+	if _, err := jsonschema.Compile(ns.Schema); err != nil {
+		return err
+	}
+
+	existing, err := s.repo.FindNamespace(ctx, ns.Name)
+	if err == nil && existing.Visibility != ns.Visibility {
+		// the values live in the other column, moving them is not supported
+		return ErrNamespaceVisibility
+	}
+	if err != nil && !errors.Is(err, repository.ErrNamespaceNotFound) {
+		return err
+	}
+
+	if err := s.repo.UpsertNamespace(ctx, ns); err != nil {
+		return err
+	}
+	slog.Info("metadata namespace registered", "namespace", ns.Name, "visibility", ns.Visibility, "in_token_claims", ns.InTokenClaims)
+	return nil
+}
+
+func (s *metadataService) ListNamespaces(ctx context.Context) ([]*models.MetadataNamespace, error) {
+	return s.repo.ListNamespaces(ctx)
+}
+
+func (s *metadataService) DeleteNamespace(ctx context.Context, name string) error {
+	if err := s.repo.DeleteNamespace(ctx, name); err != nil {
+		return err
+	}
+	slog.Info("metadata namespace deleted", "namespace", name)
+	return nil
+}
+
+func (s *metadataService) UserMetadata(ctx context.Context, userID int64) (*models.User, error) {
+	user, err := s.users.FindByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	user.PasswordHash = ""
+	return user, nil
+}
+
+func (s *metadataService) SetUserMetadata(ctx context.Context, userID int64, visibility, namespace string, value json.RawMessage) (*models.User, error) {
+	if !validVisibility(visibility) {
+		return nil, ErrInvalidVisibility
+	}
+	ns, err := s.repo.FindNamespace(ctx, namespace)
+	if err != nil {
+		return nil, err
+	}
+	if ns.Visibility != visibility {
+		return nil, ErrNamespaceVisibility
+	}
+
+	if value != nil {
+		// compiled on registration, so only a schema edited in the database fails here
+		schema, err := jsonschema.Compile(ns.Schema)
+		if err != nil {
+			return nil, err
+		}
+		if err := schema.Validate(value); err != nil {
+			return nil, err
+		}
+	}
+
+	user, err := s.repo.SetUserMetadata(ctx, userID, visibility, namespace, value)
+	if err != nil {
+		return nil, err
+	}
+	user.PasswordHash = ""
+
+	slog.Info("user metadata updated", "user_id", userID, "visibility", visibility, "namespace", namespace, "removed", value == nil)
+	return user, nil
+}
+
+func (s *metadataService) TokenClaims(ctx context.Context, user *models.User) (map[string]json.RawMessage, error) {
+	if len(user.PublicMetadata) == 0 {
+		return nil, nil
+	}
+	var metadata map[string]json.RawMessage
+	if err := json.Unmarshal(user.PublicMetadata, &metadata); err != nil {
+		return nil, err
+	}
+	if len(metadata) == 0 {
+		return nil, nil
+	}
+
+	namespaces, err := s.repo.ListNamespaces(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var claims map[string]json.RawMessage
+	for _, ns := range namespaces {
+		value, ok := metadata[ns.Name]
+		if !ok || !ns.InTokenClaims || ns.Visibility != models.MetadataPublic {
+			continue
+		}
+		if claims == nil {
+			claims = map[string]json.RawMessage{}
+		}
+		claims[ns.Name] = value
+	}
+	return claims, nil
+}
+
+func NewMetadataService(repo repository.MetadataRepository, users repository.UserRepository) MetadataService {
+	return &metadataService{repo: repo, users: users}
+}
@@ -0,0 +1,181 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/Atmosfr/user-service/internal/auth"
+	"github.com/Atmosfr/user-service/internal/jsonschema"
+	"github.com/Atmosfr/user-service/internal/models"
+	"github.com/Atmosfr/user-service/internal/repository"
+	"github.com/Atmosfr/user-service/internal/testutil"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/bcrypt"
+)
+
+type mockMetadataRepo struct {
+	mock.Mock
+}
+
+func (m *mockMetadataRepo) UpsertNamespace(ctx context.Context, ns *models.MetadataNamespace) error {
+	return m.Called(ctx, ns).Error(0)
+}
+
+func (m *mockMetadataRepo) ListNamespaces(ctx context.Context) ([]*models.MetadataNamespace, error) {
+	args := m.Called(ctx)
+	namespaces, _ := args.Get(0).([]*models.MetadataNamespace)
+	return namespaces, args.Error(1)
+}
+
+func (m *mockMetadataRepo) FindNamespace(ctx context.Context, name string) (*models.MetadataNamespace, error) {
+	args := m.Called(ctx, name)
+	ns, _ := args.Get(0).(*models.MetadataNamespace)
+	return ns, args.Error(1)
+}
+
+func (m *mockMetadataRepo) DeleteNamespace(ctx context.Context, name string) error {
+	return m.Called(ctx, name).Error(0)
+}
+
+func (m *mockMetadataRepo) SetUserMetadata(ctx context.Context, userID int64, visibility, namespace string, value json.RawMessage) (*models.User, error) {
+	args := m.Called(ctx, userID, visibility, namespace, value)
+	user, _ := args.Get(0).(*models.User)
+	return user, args.Error(1)
+}
+
+var billingNamespace = &models.MetadataNamespace{
+	Name:          "billing",
+	Visibility:    models.MetadataPublic,
+	Schema:        json.RawMessage(`{"type":"object","properties":{"plan":{"enum":["free","pro"]}},"required":["plan"],"additionalProperties":false}`),
+	InTokenClaims: true,
+}
+
+func TestMetadataService_RegisterNamespace(t *testing.T) {
+	ctx := context.Background()
+	repo := new(mockMetadataRepo)
+	svc := NewMetadataService(repo, new(testutil.MockUserRepo))
+
+	repo.On("FindNamespace", mock.Anything, "billing").Return(nil, repository.ErrNamespaceNotFound).Once()
+	repo.On("UpsertNamespace", mock.Anything, billingNamespace).Return(nil).Once()
+	require.NoError(t, svc.RegisterNamespace(ctx, billingNamespace))
+
+	tests := []struct {
+		name    string
+		ns      models.MetadataNamespace
+		wantErr error
+	}{
+		{"invalid name", models.MetadataNamespace{Name: "Billing", Visibility: "public", Schema: json.RawMessage(`{}`)}, ErrInvalidNamespaceName},
+		{"invalid visibility", models.MetadataNamespace{Name: "crm", Visibility: "internal", Schema: json.RawMessage(`{}`)}, ErrInvalidVisibility},
+		{"private claims", models.MetadataNamespace{Name: "crm", Visibility: "private", Schema: json.RawMessage(`{}`), InTokenClaims: true}, ErrPrivateTokenClaims},
+		{"invalid schema", models.MetadataNamespace{Name: "crm", Visibility: "private", Schema: json.RawMessage(`{"$ref":"#/x"}`)}, jsonschema.ErrInvalidSchema},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.ErrorIs(t, svc.RegisterNamespace(ctx, &tt.ns), tt.wantErr)
+		})
+	}
+
+	// the values of an existing namespace stay in their column
+	repo.On("FindNamespace", mock.Anything, "billing").Return(billingNamespace, nil).Once()
+	moved := *billingNamespace
+	moved.Visibility, moved.InTokenClaims = models.MetadataPrivate, false
+	require.ErrorIs(t, svc.RegisterNamespace(ctx, &moved), ErrNamespaceVisibility)
+	repo.AssertExpectations(t)
+}
+
+func TestMetadataService_SetUserMetadata(t *testing.T) {
+	ctx := context.Background()
+	repo := new(mockMetadataRepo)
+	svc := NewMetadataService(repo, new(testutil.MockUserRepo))
+	repo.On("FindNamespace", mock.Anything, "billing").Return(billingNamespace, nil)
+	repo.On("FindNamespace", mock.Anything, "crm").Return(nil, repository.ErrNamespaceNotFound)
+
+	value := json.RawMessage(`{"plan":"pro"}`)
+	repo.On("SetUserMetadata", mock.Anything, int64(7), "public", "billing", value).
+		Return(&models.User{ID: 7, PasswordHash: "hash", PublicMetadata: json.RawMessage(`{"billing":{"plan":"pro"}}`)}, nil).Once()
+	user, err := svc.SetUserMetadata(ctx, 7, "public", "billing", value)
+	require.NoError(t, err)
+	require.Empty(t, user.PasswordHash)
+	require.JSONEq(t, `{"billing":{"plan":"pro"}}`, string(user.PublicMetadata))
+
+	var invalid *jsonschema.ValidationError
+	_, err = svc.SetUserMetadata(ctx, 7, "public", "billing", json.RawMessage(`{"plan":"gold"}`))
+	require.ErrorAs(t, err, &invalid)
+	require.Equal(t, "/plan", invalid.Path)
+
+	_, err = svc.SetUserMetadata(ctx, 7, "private", "billing", value)
+	require.ErrorIs(t, err, ErrNamespaceVisibility)
+	_, err = svc.SetUserMetadata(ctx, 7, "public", "crm", value)
+	require.ErrorIs(t, err, repository.ErrNamespaceNotFound)
+
+	// removing a value skips validation
+	repo.On("SetUserMetadata", mock.Anything, int64(7), "public", "billing", json.RawMessage(nil)).Return(&models.User{ID: 7}, nil).Once()
+	_, err = svc.SetUserMetadata(ctx, 7, "public", "billing", nil)
+	require.NoError(t, err)
+	repo.AssertExpectations(t)
+}
+
+func TestMetadataService_TokenClaims(t *testing.T) {
+	ctx := context.Background()
+	repo := new(mockMetadataRepo)
+	svc := NewMetadataService(repo, new(testutil.MockUserRepo))
+	repo.On("ListNamespaces", mock.Anything).Return([]*models.MetadataNamespace{
+		billingNamespace,
+		{Name: "prefs", Visibility: models.MetadataPublic},
+	}, nil)
+
+	claims, err := svc.TokenClaims(ctx, &models.User{ID: 7, PublicMetadata: json.RawMessage(`{"billing":{"plan":"pro"},"prefs":{"theme":"dark"}}`)})
+	require.NoError(t, err)
+	require.Equal(t, map[string]json.RawMessage{"billing": json.RawMessage(`{"plan":"pro"}`)}, claims)
+
+	claims, err = svc.TokenClaims(ctx, &models.User{ID: 8, PublicMetadata: json.RawMessage(`{}`)})
+	require.NoError(t, err)
+	require.Nil(t, claims)
+}
+
+func TestUserService_LoginAddsMetadataClaims(t *testing.T) {
+	ctx := context.Background()
+	auth.JwtSecret = []byte("test-secret")
+	hash, err := bcrypt.GenerateFromPassword([]byte("StrongPass!12"), bcrypt.MinCost)
+	require.NoError(t, err)
+
+	users := new(testutil.MockUserRepo)
+	users.On("FindByEmail", mock.Anything, "bob@example.com").Return(&models.User{
+		ID: 7, Email: "bob@example.com", PasswordHash: string(hash), Role: "user",
+		PublicMetadata: json.RawMessage(`{"billing":{"plan":"pro"}}`),
+	}, nil)
+	metadata := new(mockMetadataRepo)
+	metadata.On("ListNamespaces", mock.Anything).Return([]*models.MetadataNamespace{billingNamespace}, nil)
+	svc := NewUserService(users, WithMetadataClaims(NewMetadataService(metadata, users)))
+
+	resp, err := svc.Login(ctx, "bob@example.com", "StrongPass!12")
+	require.NoError(t, err)
+	claims, err := auth.ParseToken(resp.Token, auth.JwtSecret)
+	require.NoError(t, err)
+	require.JSONEq(t, `{"plan":"pro"}`, string(claims.Metadata["billing"]))
+}
+
+func TestUserService_RegisterAddsMetadataClaims(t *testing.T) {
+	ctx := context.Background()
+	auth.JwtSecret = []byte("test-secret")
+
+	users := new(testutil.MockUserRepo)
+	users.On("FindByEmail", mock.Anything, "bob@example.com").Return(nil, repository.ErrUserNotFound)
+	// metadata set while creating the account, e.g. by a database default
+	users.On("Create", mock.Anything, mock.AnythingOfType("*models.User")).Return(nil).Run(func(args mock.Arguments) {
+		user := args.Get(1).(*models.User)
+		user.ID = 7
+		user.PublicMetadata = json.RawMessage(`{"billing":{"plan":"free"}}`)
+	})
+	metadata := new(mockMetadataRepo)
+	metadata.On("ListNamespaces", mock.Anything).Return([]*models.MetadataNamespace{billingNamespace}, nil)
+	svc := NewUserService(users, WithMetadataClaims(NewMetadataService(metadata, users)))
+
+	resp, err := svc.Register(ctx, "bob@example.com", "StrongPass!12", "bob", RegistrationConsent{})
+	require.NoError(t, err)
+	claims, err := auth.ParseToken(resp.Token, auth.JwtSecret)
+	require.NoError(t, err)
+	require.JSONEq(t, `{"plan":"free"}`, string(claims.Metadata["billing"]))
+}
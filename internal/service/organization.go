@@ -0,0 +1,242 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"strconv"
+	"strings"
+
+	"github.com/Atmosfr/user-service/internal/auth"
+	"github.com/Atmosfr/user-service/internal/models"
+	"github.com/Atmosfr/user-service/internal/repository"
+	"github.com/Atmosfr/user-service/internal/validation"
+)
+
+var (
+	ErrInvalidOrganizationRole  = errors.New("role must be owner, admin or member")
+	ErrOrganizationAdminOnly    = errors.New("only organization owners and admins can manage members")
+	ErrOrganizationOwnerOnly    = errors.New("only organization owners can grant or revoke the owner role")
+	ErrCannotRemoveHigherMember = errors.New("admins cannot remove owners")
+)
+
+type OrganizationService interface {
+	Create(ctx context.Context, userID int64, name, slug string) (*models.Organization, error)
+	ListForUser(ctx context.Context, userID int64) ([]*models.Organization, error)
+	Get(ctx context.Context, userID, orgID int64) (*models.Organization, error)
+
+	Membership(ctx context.Context, orgID, userID int64) (*models.OrganizationMember, error)
+	ListMembers(ctx context.Context, userID, orgID int64) ([]*models.OrganizationMember, error)
+	AddMember(ctx context.Context, actorID, orgID int64, email, role string) (*models.OrganizationMember, error)
+	UpdateMemberRole(ctx context.Context, actorID, orgID, userID int64, role string) error
+	// RemoveMember lets admins remove members and any member leave.
+	RemoveMember(ctx context.Context, actorID, orgID, userID int64) error
+
+	// Switch issues a new access token with orgID as the active organization.
+	// orgID 0 issues a token without one.
+	Switch(ctx context.Context, user *models.User, orgID int64) (*LoginResponse, error)
+}
+
+type organizationService struct {
+	repo   repository.OrganizationRepository
+	users  repository.UserRepository
+	claims MetadataClaims
+	audit  AuditLog
+}
+
+type OrganizationOption func(*organizationService)
+
+// WithOrganizationAuditLog records changes to members' roles, removals included.
+func WithOrganizationAuditLog(audit AuditLog) OrganizationOption {
+	return func(s *organizationService) {
+		s.audit = audit
+	}
+}
+
+// WithOrganizationMetadataClaims keeps the metadata claim in tokens issued by Switch.
+func WithOrganizationMetadataClaims(claims MetadataClaims) OrganizationOption {
+	return func(s *organizationService) {
+		s.claims = claims
+	}
+}
+
+func (s *organizationService) Create(ctx context.Context, userID int64, name, slug string) (*models.Organization, error) {
+	name = strings.TrimSpace(name)
+	if err := validation.ValidateOrganization(name, slug); err != nil {
+		return nil, err
+	}
+
+	org := &models.Organization{Name: name, Slug: slug}
+	if err := s.repo.Create(ctx, org, userID); err != nil {
+		return nil, err
+	}
+
+	slog.Info("organization created", "organization_id", org.ID, "slug", org.Slug, "user_id", userID)
+	return org, nil
+}
+
+func (s *organizationService) ListForUser(ctx context.Context, userID int64) ([]*models.Organization, error) {
+	return s.repo.ListForUser(ctx, userID)
+}
+
+func (s *organizationService) Get(ctx context.Context, userID, orgID int64) (*models.Organization, error) {
+	return s.repo.FindForMember(ctx, orgID, userID)
+}
+
+func (s *organizationService) Membership(ctx context.Context, orgID, userID int64) (*models.OrganizationMember, error) {
+	return s.repo.FindMembership(ctx, orgID, userID)
+}
+
+// organizationActor returns the acting user's membership. Non-members get
+// ErrOrganizationNotFound so the organization's existence is not revealed.
+func organizationActor(ctx context.Context, orgs repository.OrganizationRepository, orgID, userID int64) (*models.OrganizationMember, error) {
+	member, err := orgs.FindMembership(ctx, orgID, userID)
+	if errors.Is(err, repository.ErrMemberNotFound) {
+		return nil, repository.ErrOrganizationNotFound
+	}
+	return member, err
+}
+
+func (s *organizationService) actor(ctx context.Context, orgID, userID int64) (*models.OrganizationMember, error) {
+	return organizationActor(ctx, s.repo, orgID, userID)
+}
+
+func (s *organizationService) ListMembers(ctx context.Context, userID, orgID int64) ([]*models.OrganizationMember, error) {
+	if _, err := s.actor(ctx, orgID, userID); err != nil {
+		return nil, err
+	}
+	return s.repo.ListMembers(ctx, orgID)
+}
+
+func (s *organizationService) AddMember(ctx context.Context, actorID, orgID int64, email, role string) (*models.OrganizationMember, error) {
+	if !models.IsValidOrgRole(role) {
+		return nil, ErrInvalidOrganizationRole
+	}
+	actor, err := s.actor(ctx, orgID, actorID)
+	if err != nil {
+		return nil, err
+	}
+	if !actor.CanManageMembers() {
+		return nil, ErrOrganizationAdminOnly
+	}
+	if role == models.OrgRoleOwner && actor.Role != models.OrgRoleOwner {
+		return nil, ErrOrganizationOwnerOnly
+	}
+
+	user, err := s.users.FindByEmail(ctx, email)
+	if err != nil {
+		return nil, err
+	}
+
+	member := &models.OrganizationMember{
+		OrganizationID: orgID,
+		UserID:         user.ID,
+		Email:          user.Email,
+		Username:       user.Username,
+		Role:           role,
+	}
+	if err := s.repo.AddMember(ctx, member); err != nil {
+		return nil, err
+	}
+
+	slog.Info("organization member added", "organization_id", orgID, "user_id", user.ID, "role", role, "actor_id", actorID)
+	return member, nil
+}
+
+func (s *organizationService) UpdateMemberRole(ctx context.Context, actorID, orgID, userID int64, role string) error {
+	if !models.IsValidOrgRole(role) {
+		return ErrInvalidOrganizationRole
+	}
+	actor, err := s.actor(ctx, orgID, actorID)
+	if err != nil {
+		return err
+	}
+	if !actor.CanManageMembers() {
+		return ErrOrganizationAdminOnly
+	}
+
+	target, err := s.repo.FindMembership(ctx, orgID, userID)
+	if err != nil {
+		return err
+	}
+	if (role == models.OrgRoleOwner || target.Role == models.OrgRoleOwner) && actor.Role != models.OrgRoleOwner {
+		return ErrOrganizationOwnerOnly
+	}
+
+	if err := s.repo.UpdateMemberRole(ctx, orgID, userID, role); err != nil {
+		return err
+	}
+	s.recordRoleChange(ctx, actorID, orgID, userID, target.Role, role)
+
+	slog.Info("organization member role changed", "organization_id", orgID, "user_id", userID, "role", role, "actor_id", actorID)
+	return nil
+}
+
+// recordRoleChange audits that userID went from one role in orgID to another,
+// an empty to means the user left the organization.
+func (s *organizationService) recordRoleChange(ctx context.Context, actorID, orgID, userID int64, from, to string) {
+	if s.audit == nil {
+		return
+	}
+	s.audit.Record(ctx, &models.AuditEvent{
+		Action:   models.AuditRoleChanged,
+		ActorID:  &actorID,
+		TargetID: &userID,
+		Details:  map[string]string{"organization_id": strconv.FormatInt(orgID, 10), "from": from, "to": to},
+	})
+}
+
+func (s *organizationService) RemoveMember(ctx context.Context, actorID, orgID, userID int64) error {
+	actor, err := s.actor(ctx, orgID, actorID)
+	if err != nil {
+		return err
+	}
+
+	removed := actor
+	if actorID != userID {
+		if !actor.CanManageMembers() {
+			return ErrOrganizationAdminOnly
+		}
+		if removed, err = s.repo.FindMembership(ctx, orgID, userID); err != nil {
+			return err
+		}
+		if removed.Role == models.OrgRoleOwner && actor.Role != models.OrgRoleOwner {
+			return ErrCannotRemoveHigherMember
+		}
+	}
+
+	if err := s.repo.RemoveMember(ctx, orgID, userID); err != nil {
+		return err
+	}
+	s.recordRoleChange(ctx, actorID, orgID, userID, removed.Role, "")
+
+	slog.Info("organization member removed", "organization_id", orgID, "user_id", userID, "actor_id", actorID)
+	return nil
+}
+
+func (s *organizationService) Switch(ctx context.Context, user *models.User, orgID int64) (*LoginResponse, error) {
+	opts := metadataTokenOptions(ctx, s.claims, user)
+	if orgID != 0 {
+		member, err := s.actor(ctx, orgID, user.ID)
+		if err != nil {
+			return nil, err
+		}
+		opts = append(opts, auth.WithOrganization(orgID, member.Role))
+	}
+
+	token, err := auth.GenerateToken(user, AccessTokenDuration, auth.JwtSecret, opts...)
+	if err != nil {
+		slog.Error("failed to generate token", "err", err)
+		return nil, err
+	}
+
+	return &LoginResponse{User: user, Token: token}, nil
+}
+
+func NewOrganizationService(repo repository.OrganizationRepository, users repository.UserRepository, opts ...OrganizationOption) OrganizationService {
+	s := &organizationService{repo: repo, users: users}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
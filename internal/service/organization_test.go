@@ -0,0 +1,228 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/Atmosfr/user-service/internal/auth"
+	"github.com/Atmosfr/user-service/internal/models"
+	"github.com/Atmosfr/user-service/internal/repository"
+	"github.com/Atmosfr/user-service/internal/testutil"
+	"github.com/Atmosfr/user-service/internal/validation"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+type mockOrganizationRepo struct {
+	mock.Mock
+}
+
+func (m *mockOrganizationRepo) Create(ctx context.Context, org *models.Organization, ownerID int64) error {
+	return m.Called(ctx, org, ownerID).Error(0)
+}
+
+func (m *mockOrganizationRepo) ListForUser(ctx context.Context, userID int64) ([]*models.Organization, error) {
+	args := m.Called(ctx, userID)
+	return args.Get(0).([]*models.Organization), args.Error(1)
+}
+
+func (m *mockOrganizationRepo) FindForMember(ctx context.Context, orgID, userID int64) (*models.Organization, error) {
+	args := m.Called(ctx, orgID, userID)
+	org, _ := args.Get(0).(*models.Organization)
+	return org, args.Error(1)
+}
+
+func (m *mockOrganizationRepo) FindMembership(ctx context.Context, orgID, userID int64) (*models.OrganizationMember, error) {
+	args := m.Called(ctx, orgID, userID)
+	member, _ := args.Get(0).(*models.OrganizationMember)
+	return member, args.Error(1)
+}
+
+func (m *mockOrganizationRepo) ListMembers(ctx context.Context, orgID int64) ([]*models.OrganizationMember, error) {
+	args := m.Called(ctx, orgID)
+	return args.Get(0).([]*models.OrganizationMember), args.Error(1)
+}
+
+func (m *mockOrganizationRepo) AddMember(ctx context.Context, member *models.OrganizationMember) error {
+	return m.Called(ctx, member).Error(0)
+}
+
+func (m *mockOrganizationRepo) UpdateMemberRole(ctx context.Context, orgID, userID int64, role string) error {
+	return m.Called(ctx, orgID, userID, role).Error(0)
+}
+
+func (m *mockOrganizationRepo) RemoveMember(ctx context.Context, orgID, userID int64) error {
+	return m.Called(ctx, orgID, userID).Error(0)
+}
+
+// memberships registers FindMembership results for org 1 by user id.
+func memberships(repo *mockOrganizationRepo, roles map[int64]string) {
+	for userID, role := range roles {
+		repo.On("FindMembership", mock.Anything, int64(1), userID).
+			Return(&models.OrganizationMember{OrganizationID: 1, UserID: userID, Role: role}, nil)
+	}
+	repo.On("FindMembership", mock.Anything, mock.Anything, mock.Anything).Return(nil, repository.ErrMemberNotFound)
+}
+
+func TestOrganizationService_Create(t *testing.T) {
+	repo := new(mockOrganizationRepo)
+	repo.On("Create", mock.Anything, mock.MatchedBy(func(org *models.Organization) bool {
+		return org.Name == "Acme" && org.Slug == "acme"
+	}), int64(7)).Return(nil)
+	svc := NewOrganizationService(repo, new(testutil.MockUserRepo))
+
+	org, err := svc.Create(context.Background(), 7, "  Acme ", "acme")
+	require.NoError(t, err)
+	require.Equal(t, "Acme", org.Name)
+
+	_, err = svc.Create(context.Background(), 7, "Acme", "Not A Slug")
+	require.ErrorIs(t, err, validation.ErrInvalidOrganizationSlug)
+	repo.AssertNumberOfCalls(t, "Create", 1)
+}
+
+func TestOrganizationService_AddMember(t *testing.T) {
+	tests := []struct {
+		name      string
+		actorID   int64
+		role      string
+		expectErr error
+	}{
+		{name: "admin adds member", actorID: 1, role: models.OrgRoleMember},
+		{name: "owner adds owner", actorID: 2, role: models.OrgRoleOwner},
+		{name: "admin cannot add owner", actorID: 1, role: models.OrgRoleOwner, expectErr: ErrOrganizationOwnerOnly},
+		{name: "member cannot add", actorID: 3, role: models.OrgRoleMember, expectErr: ErrOrganizationAdminOnly},
+		{name: "non-member sees no organization", actorID: 99, role: models.OrgRoleMember, expectErr: repository.ErrOrganizationNotFound},
+		{name: "invalid role", actorID: 2, role: "superuser", expectErr: ErrInvalidOrganizationRole},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := new(mockOrganizationRepo)
+			memberships(repo, map[int64]string{1: models.OrgRoleAdmin, 2: models.OrgRoleOwner, 3: models.OrgRoleMember})
+			repo.On("AddMember", mock.Anything, mock.Anything).Return(nil)
+			users := new(testutil.MockUserRepo)
+			users.On("FindByEmail", mock.Anything, "new@example.com").Return(&models.User{ID: 10, Email: "new@example.com", Username: "new"}, nil)
+
+			member, err := NewOrganizationService(repo, users).AddMember(context.Background(), tt.actorID, 1, "new@example.com", tt.role)
+			if tt.expectErr != nil {
+				require.ErrorIs(t, err, tt.expectErr)
+				repo.AssertNotCalled(t, "AddMember", mock.Anything, mock.Anything)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, int64(10), member.UserID)
+			require.Equal(t, tt.role, member.Role)
+		})
+	}
+}
+
+func TestOrganizationService_UpdateMemberRole(t *testing.T) {
+	tests := []struct {
+		name      string
+		actorID   int64
+		targetID  int64
+		role      string
+		expectErr error
+		wantFrom  string
+	}{
+		{name: "admin promotes member", actorID: 1, targetID: 3, role: models.OrgRoleAdmin, wantFrom: models.OrgRoleMember},
+		{name: "admin cannot demote owner", actorID: 1, targetID: 2, role: models.OrgRoleMember, expectErr: ErrOrganizationOwnerOnly},
+		{name: "owner demotes owner", actorID: 2, targetID: 4, role: models.OrgRoleAdmin, wantFrom: models.OrgRoleOwner},
+		{name: "owner promotes to owner", actorID: 2, targetID: 1, role: models.OrgRoleOwner, wantFrom: models.OrgRoleAdmin},
+		{name: "member cannot change roles", actorID: 3, targetID: 3, role: models.OrgRoleAdmin, expectErr: ErrOrganizationAdminOnly},
+		{name: "unknown target", actorID: 2, targetID: 50, role: models.OrgRoleAdmin, expectErr: repository.ErrMemberNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := new(mockOrganizationRepo)
+			memberships(repo, map[int64]string{1: models.OrgRoleAdmin, 2: models.OrgRoleOwner, 3: models.OrgRoleMember, 4: models.OrgRoleOwner})
+			repo.On("UpdateMemberRole", mock.Anything, int64(1), tt.targetID, tt.role).Return(nil)
+			audit := new(recordingAuditLog)
+
+			err := NewOrganizationService(repo, new(testutil.MockUserRepo), WithOrganizationAuditLog(audit)).
+				UpdateMemberRole(context.Background(), tt.actorID, 1, tt.targetID, tt.role)
+			if tt.expectErr != nil {
+				require.ErrorIs(t, err, tt.expectErr)
+				repo.AssertNotCalled(t, "UpdateMemberRole", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+				require.Empty(t, audit.events)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, []*models.AuditEvent{{
+				Action:   models.AuditRoleChanged,
+				ActorID:  &tt.actorID,
+				TargetID: &tt.targetID,
+				Details:  map[string]string{"organization_id": "1", "from": tt.wantFrom, "to": tt.role},
+			}}, audit.events)
+		})
+	}
+}
+
+func TestOrganizationService_RemoveMember(t *testing.T) {
+	tests := []struct {
+		name      string
+		actorID   int64
+		targetID  int64
+		repoErr   error
+		expectErr error
+		wantFrom  string
+	}{
+		{name: "member leaves", actorID: 3, targetID: 3, wantFrom: models.OrgRoleMember},
+		{name: "admin removes member", actorID: 1, targetID: 3, wantFrom: models.OrgRoleMember},
+		{name: "owner removes admin", actorID: 2, targetID: 1, wantFrom: models.OrgRoleAdmin},
+		{name: "admin cannot remove owner", actorID: 1, targetID: 2, expectErr: ErrCannotRemoveHigherMember},
+		{name: "member cannot remove others", actorID: 3, targetID: 1, expectErr: ErrOrganizationAdminOnly},
+		{name: "last owner cannot leave", actorID: 2, targetID: 2, repoErr: repository.ErrLastOrganizationOwner, expectErr: repository.ErrLastOrganizationOwner},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := new(mockOrganizationRepo)
+			memberships(repo, map[int64]string{1: models.OrgRoleAdmin, 2: models.OrgRoleOwner, 3: models.OrgRoleMember})
+			repo.On("RemoveMember", mock.Anything, int64(1), tt.targetID).Return(tt.repoErr)
+			audit := new(recordingAuditLog)
+
+			err := NewOrganizationService(repo, new(testutil.MockUserRepo), WithOrganizationAuditLog(audit)).
+				RemoveMember(context.Background(), tt.actorID, 1, tt.targetID)
+			if tt.expectErr != nil {
+				require.ErrorIs(t, err, tt.expectErr)
+				require.Empty(t, audit.events)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, []*models.AuditEvent{{
+				Action:   models.AuditRoleChanged,
+				ActorID:  &tt.actorID,
+				TargetID: &tt.targetID,
+				Details:  map[string]string{"organization_id": "1", "from": tt.wantFrom, "to": ""},
+			}}, audit.events)
+		})
+	}
+}
+
+func TestOrganizationService_Switch(t *testing.T) {
+	auth.JwtSecret = []byte("secret")
+	user := &models.User{ID: 2, Role: "user"}
+	repo := new(mockOrganizationRepo)
+	memberships(repo, map[int64]string{2: models.OrgRoleOwner})
+	svc := NewOrganizationService(repo, new(testutil.MockUserRepo))
+
+	resp, err := svc.Switch(context.Background(), user, 1)
+	require.NoError(t, err)
+	claims, err := auth.ParseToken(resp.Token, auth.JwtSecret)
+	require.NoError(t, err)
+	require.Equal(t, int64(1), claims.OrgID)
+	require.Equal(t, models.OrgRoleOwner, claims.OrgRole)
+	require.WithinDuration(t, time.Now().Add(AccessTokenDuration), claims.ExpiresAt.Time, time.Minute)
+
+	resp, err = svc.Switch(context.Background(), user, 0)
+	require.NoError(t, err)
+	claims, err = auth.ParseToken(resp.Token, auth.JwtSecret)
+	require.NoError(t, err)
+	require.Zero(t, claims.OrgID)
+
+	_, err = svc.Switch(context.Background(), user, 5)
+	require.ErrorIs(t, err, repository.ErrOrganizationNotFound)
+}
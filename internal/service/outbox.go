@@ -0,0 +1,140 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"time"
+
+	"github.com/Atmosfr/user-service/internal/events"
+	"github.com/Atmosfr/user-service/internal/models"
+	"github.com/Atmosfr/user-service/internal/repository"
+)
+
+const (
+	outboxBatchSize = 100
+	// outboxLease is how long a claimed event is left alone. An event whose
+	// relay crashed mid publish is claimed again after it, so it may be
+	// delivered twice but never lost.
+	outboxLease = time.Minute
+	// outboxRetention is how long published events are kept for inspection.
+	outboxRetention = 7 * 24 * time.Hour
+
+	outboxMinBackoff = 5 * time.Second
+	outboxMaxBackoff = time.Hour
+)
+
+// OutboxRelay publishes the user events the database queued in the outbox.
+type OutboxRelay interface {
+	// Relay publishes one batch of due events and returns how many were
+	// published. Events the sink rejects are retried with backoff.
+	Relay(ctx context.Context) (int, error)
+	// Run relays events every interval until ctx is done.
+	Run(ctx context.Context, interval time.Duration)
+}
+
+type outboxRelay struct {
+	repo repository.OutboxRepository
+	sink events.Sink
+	now  func() time.Time
+}
+
+// outboxBackoff doubles the delay with every failed attempt.
+func outboxBackoff(attempts int) time.Duration {
+	backoff := outboxMinBackoff
+	for i := 1; i < attempts && backoff < outboxMaxBackoff; i++ {
+		backoff *= 2
+	}
+	return min(backoff, outboxMaxBackoff)
+}
+
+// outboxEnvelope wraps an outbox row. User payloads go through UserData so
+// the published data matches the documented schema.
+func outboxEnvelope(e *models.OutboxEvent) (*events.Event, error) {
+	data := e.Payload
+	switch e.Type {
+	case events.UserRegistered, events.UserUpdated, events.UserDeleted:
+		var user events.UserData
+		if err := json.Unmarshal(e.Payload, &user); err != nil {
+			return nil, err
+		}
+		b, err := json.Marshal(user)
+		if err != nil {
+			return nil, err
+		}
+		data = b
+	}
+	return &events.Event{
+		ID:         e.EventID,
+		Type:       e.Type,
+		Version:    e.SchemaVersion,
+		Source:     events.Source,
+		OccurredAt: e.CreatedAt.UTC(),
+		Data:       data,
+	}, nil
+}
+
+func (r *outboxRelay) Relay(ctx context.Context) (int, error) {
+	now := r.now()
+	claimed, err := r.repo.Claim(ctx, now, now.Add(outboxLease), outboxBatchSize)
+	if err != nil {
+		return 0, err
+	}
+
+	published := 0
+	for i, e := range claimed {
+		event, err := outboxEnvelope(e)
+		if err == nil {
+			err = r.sink.Publish(ctx, event)
+		}
+		if err != nil {
+			next := r.now().Add(outboxBackoff(e.Attempts))
+			slog.Warn("failed to publish event", "event_id", e.EventID, "type", e.Type, "attempts", e.Attempts, "err", err)
+			if err := r.repo.Retry(ctx, e.ID, next, err.Error()); err != nil {
+				return published, err
+			}
+			continue
+		}
+		if err := r.repo.MarkPublished(ctx, e.ID); err != nil {
+			// the remaining events of the batch are claimed again once their lease ends
+			slog.Error("failed to mark event published", "event_id", e.EventID, "pending", len(claimed)-i-1, "err", err)
+			return published, err
+		}
+		published++
+	}
+	return published, nil
+}
+
+func (r *outboxRelay) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	cleaned := time.Time{}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			for {
+				published, err := r.Relay(ctx)
+				if err != nil {
+					slog.Error("failed to relay events", "err", err)
+				}
+				if published < outboxBatchSize || err != nil {
+					break
+				}
+			}
+			if now := r.now(); now.Sub(cleaned) >= time.Hour {
+				if _, err := r.repo.DeletePublished(ctx, now.Add(-outboxRetention)); err != nil {
+					slog.Error("failed to delete published events", "err", err)
+				} else {
+					cleaned = now
+				}
+			}
+		}
+	}
+}
+
+func NewOutboxRelay(repo repository.OutboxRepository, sink events.Sink) OutboxRelay {
+	return &outboxRelay{repo: repo, sink: sink, now: time.Now}
+}
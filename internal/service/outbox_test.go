@@ -0,0 +1,85 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/Atmosfr/user-service/internal/events"
+	"github.com/Atmosfr/user-service/internal/models"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+type mockOutboxRepo struct {
+	mock.Mock
+}
+
+func (m *mockOutboxRepo) Claim(ctx context.Context, now, leaseUntil time.Time, limit int) ([]*models.OutboxEvent, error) {
+	args := m.Called(ctx, now, leaseUntil, limit)
+	claimed, _ := args.Get(0).([]*models.OutboxEvent)
+	return claimed, args.Error(1)
+}
+
+func (m *mockOutboxRepo) MarkPublished(ctx context.Context, id int64) error {
+	return m.Called(ctx, id).Error(0)
+}
+
+func (m *mockOutboxRepo) Retry(ctx context.Context, id int64, next time.Time, lastError string) error {
+	return m.Called(ctx, id, next, lastError).Error(0)
+}
+
+func (m *mockOutboxRepo) DeletePublished(ctx context.Context, before time.Time) (int64, error) {
+	args := m.Called(ctx, before)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+// fakeSink keeps the events it accepts and rejects the ids in fail.
+type fakeSink struct {
+	published []*events.Event
+	fail      map[string]bool
+}
+
+func (s *fakeSink) Publish(ctx context.Context, event *events.Event) error {
+	if s.fail[event.ID] {
+		return errors.New("sink unavailable")
+	}
+	s.published = append(s.published, event)
+	return nil
+}
+
+func TestOutboxRelay_Relay(t *testing.T) {
+	now := time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC)
+	repo := new(mockOutboxRepo)
+	sink := &fakeSink{fail: map[string]bool{"e2": true}}
+	relay := &outboxRelay{repo: repo, sink: sink, now: func() time.Time { return now }}
+
+	repo.On("Claim", mock.Anything, now, now.Add(outboxLease), outboxBatchSize).Return([]*models.OutboxEvent{
+		{ID: 1, EventID: "e1", Type: events.UserRegistered, SchemaVersion: 1, AggregateID: 7, Attempts: 1, CreatedAt: now,
+			Payload: json.RawMessage(`{"id": 7, "email": "ann@example.com", "avatar_url": null, "email_verified": false}`)},
+		{ID: 2, EventID: "e2", Type: events.UserDeleted, SchemaVersion: 1, AggregateID: 8, Attempts: 3, CreatedAt: now,
+			Payload: json.RawMessage(`{"id": 8}`)},
+	}, nil)
+	repo.On("MarkPublished", mock.Anything, int64(1)).Return(nil)
+	repo.On("Retry", mock.Anything, int64(2), now.Add(20*time.Second), "sink unavailable").Return(nil)
+
+	published, err := relay.Relay(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, 1, published)
+	repo.AssertExpectations(t)
+
+	require.Len(t, sink.published, 1)
+	event := sink.published[0]
+	require.Equal(t, "e1", event.ID)
+	require.Equal(t, events.Source, event.Source)
+	require.Equal(t, 1, event.Version)
+	require.JSONEq(t, `{"id": 7, "email": "ann@example.com"}`, string(event.Data))
+}
+
+func TestOutboxBackoff(t *testing.T) {
+	require.Equal(t, 5*time.Second, outboxBackoff(1))
+	require.Equal(t, 40*time.Second, outboxBackoff(4))
+	require.Equal(t, time.Hour, outboxBackoff(30))
+}
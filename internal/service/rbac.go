@@ -0,0 +1,92 @@
+package service
+
+import (
+	"context"
+
+	"github.com/Atmosfr/user-service/internal/models"
+	"github.com/Atmosfr/user-service/internal/repository"
+)
+
+// Permissions checked by route guards. Roles and their grants live in the database.
+const (
+	PermissionProfileRead          = "profile:read"
+	PermissionProfileWrite         = "profile:write"
+	PermissionUsersRead            = "users:read"
+	PermissionUsersWrite           = "users:write"
+	PermissionUsersDelete          = "users:delete"
+	PermissionRolesRead            = "roles:read"
+	PermissionServiceAccountsRead  = "service_accounts:read"
+	PermissionServiceAccountsWrite = "service_accounts:write"
+	PermissionSCIMManage           = "scim:manage"
+	PermissionMetadataManage       = "metadata:manage"
+	PermissionLegalManage          = "legal:manage"
+	PermissionAuditRead            = "audit:read"
+)
+
+type RBACService interface {
+	Permissions(ctx context.Context, role string) (models.PermissionSet, error)
+	ListRoles(ctx context.Context) ([]*models.Role, error)
+}
+
+type rbacService struct {
+	repo repository.RoleRepository
+}
+
+func (s *rbacService) Permissions(ctx context.Context, role string) (models.PermissionSet, error) {
+	if role == "" {
+		return models.PermissionSet{}, nil
+	}
+	permissions, err := s.repo.PermissionsForRole(ctx, role)
+	if err != nil {
+		return nil, err
+	}
+	return models.PermissionSet(permissions), nil
+}
+
+// ListRoles returns all roles with their effective permissions, inherited ones included.
+func (s *rbacService) ListRoles(ctx context.Context) ([]*models.Role, error) {
+	roles, err := s.repo.ListRoles(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	byName := make(map[string]*models.Role, len(roles))
+	direct := make(map[string][]string, len(roles))
+	for _, role := range roles {
+		byName[role.Name] = role
+		direct[role.Name] = role.Permissions
+	}
+
+	for _, role := range roles {
+		seen := map[string]bool{}
+		effective := []string{}
+		for name := role.Name; name != "" && !seen[name]; {
+			seen[name] = true
+			for _, p := range direct[name] {
+				if !containsString(effective, p) {
+					effective = append(effective, p)
+				}
+			}
+			parent, ok := byName[name]
+			if !ok {
+				break
+			}
+			name = parent.Parent
+		}
+		role.Permissions = effective
+	}
+	return roles, nil
+}
+
+func containsString(values []string, v string) bool {
+	for _, s := range values {
+		if s == v {
+			return true
+		}
+	}
+	return false
+}
+
+func NewRBACService(repo repository.RoleRepository) RBACService {
+	return &rbacService{repo: repo}
+}
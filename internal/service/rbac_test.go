@@ -0,0 +1,85 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"github.com/Atmosfr/user-service/internal/models"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+type mockRoleRepo struct {
+	mock.Mock
+}
+
+func (m *mockRoleRepo) PermissionsForRole(ctx context.Context, role string) ([]string, error) {
+	args := m.Called(ctx, role)
+	return args.Get(0).([]string), args.Error(1)
+}
+
+func (m *mockRoleRepo) ListRoles(ctx context.Context) ([]*models.Role, error) {
+	args := m.Called(ctx)
+	return args.Get(0).([]*models.Role), args.Error(1)
+}
+
+func TestPermissionSet_Has(t *testing.T) {
+	tests := []struct {
+		name       string
+		set        models.PermissionSet
+		permission string
+		want       bool
+	}{
+		{name: "exact match", set: models.PermissionSet{"users:read"}, permission: "users:read", want: true},
+		{name: "missing", set: models.PermissionSet{"users:read"}, permission: "users:write", want: false},
+		{name: "namespace wildcard", set: models.PermissionSet{"users:*"}, permission: "users:delete", want: true},
+		{name: "namespace wildcard does not leak", set: models.PermissionSet{"users:*"}, permission: "roles:read", want: false},
+		{name: "global wildcard", set: models.PermissionSet{"*"}, permission: "scim:manage", want: true},
+		{name: "empty set", set: nil, permission: "profile:read", want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.Equal(t, tt.want, tt.set.Has(tt.permission))
+		})
+	}
+}
+
+func TestRBACService_ListRoles(t *testing.T) {
+	repo := new(mockRoleRepo)
+	repo.On("ListRoles", mock.Anything).Return([]*models.Role{
+		{Name: "admin", Parent: "support", Permissions: []string{"*"}},
+		{Name: "support", Parent: "user", Permissions: []string{"users:read"}},
+		{Name: "user", Permissions: []string{"profile:read", "profile:write"}},
+		{Name: "loop-a", Parent: "loop-b", Permissions: []string{"a:read"}},
+		{Name: "loop-b", Parent: "loop-a", Permissions: []string{"b:read"}},
+	}, nil)
+
+	roles, err := NewRBACService(repo).ListRoles(context.Background())
+	require.NoError(t, err)
+
+	effective := map[string][]string{}
+	for _, role := range roles {
+		effective[role.Name] = role.Permissions
+	}
+	require.Equal(t, []string{"*", "users:read", "profile:read", "profile:write"}, effective["admin"])
+	require.Equal(t, []string{"users:read", "profile:read", "profile:write"}, effective["support"])
+	require.Equal(t, []string{"profile:read", "profile:write"}, effective["user"])
+	require.Equal(t, []string{"a:read", "b:read"}, effective["loop-a"])
+}
+
+func TestRBACService_Permissions(t *testing.T) {
+	repo := new(mockRoleRepo)
+	repo.On("PermissionsForRole", mock.Anything, "support").Return([]string{"profile:read", "users:read"}, nil)
+	svc := NewRBACService(repo)
+
+	permissions, err := svc.Permissions(context.Background(), "support")
+	require.NoError(t, err)
+	require.True(t, permissions.Has(PermissionUsersRead))
+	require.False(t, permissions.Has(PermissionUsersWrite))
+
+	permissions, err = svc.Permissions(context.Background(), "")
+	require.NoError(t, err)
+	require.Empty(t, permissions)
+	repo.AssertNumberOfCalls(t, "PermissionsForRole", 1)
+}
@@ -0,0 +1,437 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"strings"
+
+	"github.com/Atmosfr/user-service/internal/models"
+	"github.com/Atmosfr/user-service/internal/repository"
+	"github.com/Atmosfr/user-service/internal/scim"
+	"github.com/Atmosfr/user-service/internal/validation"
+	"golang.org/x/crypto/bcrypt"
+)
+
+const scimTokenPrefix = "scim_"
+
+type SCIMService interface {
+	CreateTenant(ctx context.Context, name string) (*models.SCIMTenant, string, error)
+	Authenticate(ctx context.Context, token string) (*models.SCIMTenant, error)
+
+	ListUsers(ctx context.Context, tenantID int64, filter string, startIndex, count int) ([]*scim.User, int, error)
+	GetUser(ctx context.Context, tenantID int64, id string) (*scim.User, error)
+	CreateUser(ctx context.Context, tenantID int64, user *scim.User) (*scim.User, error)
+	ReplaceUser(ctx context.Context, tenantID int64, id string, user *scim.User) (*scim.User, error)
+	PatchUser(ctx context.Context, tenantID int64, id string, ops []scim.PatchOperation) (*scim.User, error)
+	DeleteUser(ctx context.Context, tenantID int64, id string) error
+
+	ListGroups(ctx context.Context, tenantID int64, filter string, startIndex, count int) ([]*scim.Group, int, error)
+	GetGroup(ctx context.Context, tenantID int64, id string) (*scim.Group, error)
+	CreateGroup(ctx context.Context, tenantID int64, group *scim.Group) (*scim.Group, error)
+	ReplaceGroup(ctx context.Context, tenantID int64, id string, group *scim.Group) (*scim.Group, error)
+	PatchGroup(ctx context.Context, tenantID int64, id string, ops []scim.PatchOperation) (*scim.Group, error)
+	DeleteGroup(ctx context.Context, tenantID int64, id string) error
+}
+
+type scimService struct {
+	repo  repository.SCIMRepository
+	audit AuditLog
+}
+
+func hashSCIMToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+func (s *scimService) CreateTenant(ctx context.Context, name string) (*models.SCIMTenant, string, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return nil, "", fmt.Errorf("%w: tenant name is required", scim.ErrInvalidValue)
+	}
+
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return nil, "", err
+	}
+	token := scimTokenPrefix + base64.RawURLEncoding.EncodeToString(secret)
+
+	tenant := &models.SCIMTenant{Name: name}
+	if err := s.repo.CreateTenant(ctx, tenant, hashSCIMToken(token)); err != nil {
+		return nil, "", err
+	}
+
+	slog.Info("scim tenant created", "tenant_id", tenant.ID, "name", tenant.Name)
+	return tenant, token, nil
+}
+
+func (s *scimService) Authenticate(ctx context.Context, token string) (*models.SCIMTenant, error) {
+	if !strings.HasPrefix(token, scimTokenPrefix) {
+		return nil, repository.ErrTenantNotFound
+	}
+	return s.repo.FindTenantByTokenHash(ctx, hashSCIMToken(token))
+}
+
+func parseFilter(filter string) (scim.Expr, error) {
+	if strings.TrimSpace(filter) == "" {
+		return nil, nil
+	}
+	return scim.ParseFilter(filter)
+}
+
+func pageBounds(startIndex, count int) (offset, limit int) {
+	if startIndex < 1 {
+		startIndex = 1
+	}
+	if count < 0 {
+		count = 0
+	}
+	if count > scim.MaxCount {
+		count = scim.MaxCount
+	}
+	return startIndex - 1, count
+}
+
+func parseResourceID(id string) (int64, bool) {
+	n, err := strconv.ParseInt(id, 10, 64)
+	return n, err == nil && n > 0
+}
+
+func scimUserResource(u *models.SCIMUser) *scim.User {
+	active := u.IsActive
+	return &scim.User{
+		Schemas:    []string{scim.SchemaUser},
+		ID:         strconv.FormatInt(u.ID, 10),
+		ExternalID: u.ExternalID,
+		UserName:   u.Username,
+		Active:     &active,
+		Emails:     []scim.Email{{Value: u.Email, Type: "work", Primary: true}},
+		Meta: &scim.Meta{
+			ResourceType: "User",
+			Created:      u.CreatedAt,
+			LastModified: u.UpdatedAt,
+		},
+	}
+}
+
+// applySCIMUser copies the writable attributes of a SCIM resource onto the stored user.
+func applySCIMUser(dst *models.SCIMUser, src *scim.User) error {
+	email := src.PrimaryEmail()
+	if email == "" && strings.Contains(src.UserName, "@") {
+		email = src.UserName
+	}
+	if err := validation.ValidateSCIMUser(src.UserName, email); err != nil {
+		return fmt.Errorf("%w: %v", scim.ErrInvalidValue, err)
+	}
+
+	dst.Username = src.UserName
+	dst.Email = email
+	dst.ExternalID = src.ExternalID
+	dst.IsActive = src.Active == nil || *src.Active
+	dst.PasswordHash = ""
+
+	if src.Password != "" {
+		if len(src.Password) < 8 {
+			return fmt.Errorf("%w: %v", scim.ErrInvalidValue, validation.ErrPasswordTooShort)
+		}
+		hash, err := bcrypt.GenerateFromPassword([]byte(src.Password), bcrypt.DefaultCost)
+		if err != nil {
+			return err
+		}
+		dst.PasswordHash = string(hash)
+	}
+	return nil
+}
+
+func (s *scimService) ListUsers(ctx context.Context, tenantID int64, filter string, startIndex, count int) ([]*scim.User, int, error) {
+	expr, err := parseFilter(filter)
+	if err != nil {
+		return nil, 0, err
+	}
+	offset, limit := pageBounds(startIndex, count)
+
+	users, total, err := s.repo.ListUsers(ctx, tenantID, expr, offset, limit)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	resources := make([]*scim.User, len(users))
+	for i, u := range users {
+		resources[i] = scimUserResource(u)
+	}
+	return resources, total, nil
+}
+
+func (s *scimService) GetUser(ctx context.Context, tenantID int64, id string) (*scim.User, error) {
+	userID, ok := parseResourceID(id)
+	if !ok {
+		return nil, repository.ErrUserNotFound
+	}
+	user, err := s.repo.FindUser(ctx, tenantID, userID)
+	if err != nil {
+		return nil, err
+	}
+	return scimUserResource(user), nil
+}
+
+func (s *scimService) CreateUser(ctx context.Context, tenantID int64, resource *scim.User) (*scim.User, error) {
+	user := &models.SCIMUser{TenantID: tenantID}
+	if err := applySCIMUser(user, resource); err != nil {
+		return nil, err
+	}
+
+	if err := s.repo.CreateUser(ctx, user); err != nil {
+		return nil, err
+	}
+
+	slog.Info("scim user provisioned", "tenant_id", tenantID, "user_id", user.ID)
+	return scimUserResource(user), nil
+}
+
+func (s *scimService) ReplaceUser(ctx context.Context, tenantID int64, id string, resource *scim.User) (*scim.User, error) {
+	userID, ok := parseResourceID(id)
+	if !ok {
+		return nil, repository.ErrUserNotFound
+	}
+	user, err := s.repo.FindUser(ctx, tenantID, userID)
+	if err != nil {
+		return nil, err
+	}
+	return s.saveUser(ctx, user, resource)
+}
+
+func (s *scimService) PatchUser(ctx context.Context, tenantID int64, id string, ops []scim.PatchOperation) (*scim.User, error) {
+	userID, ok := parseResourceID(id)
+	if !ok {
+		return nil, repository.ErrUserNotFound
+	}
+	user, err := s.repo.FindUser(ctx, tenantID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	resource := scimUserResource(user)
+	if err := patchResource(resource, ops); err != nil {
+		return nil, err
+	}
+	return s.saveUser(ctx, user, resource)
+}
+
+func (s *scimService) saveUser(ctx context.Context, user *models.SCIMUser, resource *scim.User) (*scim.User, error) {
+	if err := applySCIMUser(user, resource); err != nil {
+		return nil, err
+	}
+	if err := s.repo.UpdateUser(ctx, user); err != nil {
+		return nil, err
+	}
+	if resource.Password != "" {
+		s.audit.Record(ctx, &models.AuditEvent{
+			Action:   models.AuditPasswordChanged,
+			TargetID: &user.ID,
+			Details:  map[string]string{"scim_tenant_id": strconv.FormatInt(user.TenantID, 10)},
+		})
+	}
+
+	slog.Info("scim user updated", "tenant_id", user.TenantID, "user_id", user.ID, "active", user.IsActive)
+	return scimUserResource(user), nil
+}
+
+func (s *scimService) DeleteUser(ctx context.Context, tenantID int64, id string) error {
+	userID, ok := parseResourceID(id)
+	if !ok {
+		return repository.ErrUserNotFound
+	}
+	if err := s.repo.DeleteUser(ctx, tenantID, userID); err != nil {
+		return err
+	}
+	s.audit.Record(ctx, &models.AuditEvent{
+		Action:   models.AuditUserDeleted,
+		TargetID: &userID,
+		Details:  map[string]string{"scim_tenant_id": strconv.FormatInt(tenantID, 10)},
+	})
+
+	slog.Info("scim user deprovisioned", "tenant_id", tenantID, "user_id", userID)
+	return nil
+}
+
+// patchResource round-trips a resource through its JSON form so PATCH paths
+// can address attributes by their SCIM names.
+func patchResource[T any](resource *T, ops []scim.PatchOperation) error {
+	raw, err := json.Marshal(resource)
+	if err != nil {
+		return err
+	}
+	var doc map[string]any
+	if err := json.Unmarshal(raw, &doc); err != nil {
+		return err
+	}
+
+	if err := scim.ApplyPatch(doc, ops); err != nil {
+		return err
+	}
+
+	// some identity providers send booleans as strings, e.g. "active": "False"
+	for k, v := range doc {
+		if str, ok := v.(string); ok && strings.EqualFold(k, "active") {
+			b, err := strconv.ParseBool(strings.ToLower(str))
+			if err != nil {
+				return fmt.Errorf("%w: active must be a boolean", scim.ErrInvalidValue)
+			}
+			doc[k] = b
+		}
+	}
+
+	raw, err = json.Marshal(doc)
+	if err != nil {
+		return err
+	}
+	var patched T
+	if err := json.Unmarshal(raw, &patched); err != nil {
+		return fmt.Errorf("%w: %v", scim.ErrInvalidValue, err)
+	}
+	*resource = patched
+	return nil
+}
+
+func scimGroupResource(g *models.SCIMGroup) *scim.Group {
+	members := make([]scim.Member, len(g.Members))
+	for i, m := range g.Members {
+		members[i] = scim.Member{Value: strconv.FormatInt(m.UserID, 10), Display: m.Username}
+	}
+	return &scim.Group{
+		Schemas:     []string{scim.SchemaGroup},
+		ID:          strconv.FormatInt(g.ID, 10),
+		ExternalID:  g.ExternalID,
+		DisplayName: g.DisplayName,
+		Members:     members,
+		Meta: &scim.Meta{
+			ResourceType: "Group",
+			Created:      g.CreatedAt,
+			LastModified: g.UpdatedAt,
+		},
+	}
+}
+
+func applySCIMGroup(dst *models.SCIMGroup, src *scim.Group) error {
+	name := strings.TrimSpace(src.DisplayName)
+	if name == "" || len(name) > 255 {
+		return fmt.Errorf("%w: displayName must be 1-255 characters", scim.ErrInvalidValue)
+	}
+
+	members := make([]models.SCIMGroupMember, 0, len(src.Members))
+	for _, m := range src.Members {
+		userID, ok := parseResourceID(m.Value)
+		if !ok {
+			return repository.ErrInvalidGroupMember
+		}
+		members = append(members, models.SCIMGroupMember{UserID: userID})
+	}
+
+	dst.DisplayName = name
+	dst.ExternalID = src.ExternalID
+	dst.Members = members
+	return nil
+}
+
+func (s *scimService) ListGroups(ctx context.Context, tenantID int64, filter string, startIndex, count int) ([]*scim.Group, int, error) {
+	expr, err := parseFilter(filter)
+	if err != nil {
+		return nil, 0, err
+	}
+	offset, limit := pageBounds(startIndex, count)
+
+	groups, total, err := s.repo.ListGroups(ctx, tenantID, expr, offset, limit)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	resources := make([]*scim.Group, len(groups))
+	for i, g := range groups {
+		resources[i] = scimGroupResource(g)
+	}
+	return resources, total, nil
+}
+
+func (s *scimService) GetGroup(ctx context.Context, tenantID int64, id string) (*scim.Group, error) {
+	groupID, ok := parseResourceID(id)
+	if !ok {
+		return nil, repository.ErrGroupNotFound
+	}
+	group, err := s.repo.FindGroup(ctx, tenantID, groupID)
+	if err != nil {
+		return nil, err
+	}
+	return scimGroupResource(group), nil
+}
+
+func (s *scimService) CreateGroup(ctx context.Context, tenantID int64, resource *scim.Group) (*scim.Group, error) {
+	group := &models.SCIMGroup{TenantID: tenantID}
+	if err := applySCIMGroup(group, resource); err != nil {
+		return nil, err
+	}
+	if err := s.repo.CreateGroup(ctx, group); err != nil {
+		return nil, err
+	}
+
+	slog.Info("scim group provisioned", "tenant_id", tenantID, "group_id", group.ID)
+	return s.GetGroup(ctx, tenantID, strconv.FormatInt(group.ID, 10))
+}
+
+func (s *scimService) ReplaceGroup(ctx context.Context, tenantID int64, id string, resource *scim.Group) (*scim.Group, error) {
+	groupID, ok := parseResourceID(id)
+	if !ok {
+		return nil, repository.ErrGroupNotFound
+	}
+	group, err := s.repo.FindGroup(ctx, tenantID, groupID)
+	if err != nil {
+		return nil, err
+	}
+	return s.saveGroup(ctx, group, resource)
+}
+
+func (s *scimService) PatchGroup(ctx context.Context, tenantID int64, id string, ops []scim.PatchOperation) (*scim.Group, error) {
+	groupID, ok := parseResourceID(id)
+	if !ok {
+		return nil, repository.ErrGroupNotFound
+	}
+	group, err := s.repo.FindGroup(ctx, tenantID, groupID)
+	if err != nil {
+		return nil, err
+	}
+
+	resource := scimGroupResource(group)
+	if err := patchResource(resource, ops); err != nil {
+		return nil, err
+	}
+	return s.saveGroup(ctx, group, resource)
+}
+
+func (s *scimService) saveGroup(ctx context.Context, group *models.SCIMGroup, resource *scim.Group) (*scim.Group, error) {
+	if err := applySCIMGroup(group, resource); err != nil {
+		return nil, err
+	}
+	if err := s.repo.UpdateGroup(ctx, group); err != nil {
+		return nil, err
+	}
+
+	slog.Info("scim group updated", "tenant_id", group.TenantID, "group_id", group.ID, "members", len(group.Members))
+	return s.GetGroup(ctx, group.TenantID, strconv.FormatInt(group.ID, 10))
+}
+
+func (s *scimService) DeleteGroup(ctx context.Context, tenantID int64, id string) error {
+	groupID, ok := parseResourceID(id)
+	if !ok {
+		return repository.ErrGroupNotFound
+	}
+	return s.repo.DeleteGroup(ctx, tenantID, groupID)
+}
+
+func NewSCIMService(repo repository.SCIMRepository, audit AuditLog) SCIMService {
+	return &scimService{repo: repo, audit: audit}
+}
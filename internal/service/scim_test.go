@@ -0,0 +1,295 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/Atmosfr/user-service/internal/models"
+	"github.com/Atmosfr/user-service/internal/repository"
+	"github.com/Atmosfr/user-service/internal/scim"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+type mockSCIMRepo struct {
+	mock.Mock
+}
+
+func (m *mockSCIMRepo) CreateTenant(ctx context.Context, tenant *models.SCIMTenant, tokenHash string) error {
+	args := m.Called(ctx, tenant, tokenHash)
+	return args.Error(0)
+}
+
+func (m *mockSCIMRepo) FindTenantByTokenHash(ctx context.Context, tokenHash string) (*models.SCIMTenant, error) {
+	args := m.Called(ctx, tokenHash)
+	return args.Get(0).(*models.SCIMTenant), args.Error(1)
+}
+
+func (m *mockSCIMRepo) ListUsers(ctx context.Context, tenantID int64, filter scim.Expr, offset, limit int) ([]*models.SCIMUser, int, error) {
+	args := m.Called(ctx, tenantID, filter, offset, limit)
+	return args.Get(0).([]*models.SCIMUser), args.Int(1), args.Error(2)
+}
+
+func (m *mockSCIMRepo) FindUser(ctx context.Context, tenantID, id int64) (*models.SCIMUser, error) {
+	args := m.Called(ctx, tenantID, id)
+	return args.Get(0).(*models.SCIMUser), args.Error(1)
+}
+
+func (m *mockSCIMRepo) CreateUser(ctx context.Context, user *models.SCIMUser) error {
+	args := m.Called(ctx, user)
+	return args.Error(0)
+}
+
+func (m *mockSCIMRepo) UpdateUser(ctx context.Context, user *models.SCIMUser) error {
+	args := m.Called(ctx, user)
+	return args.Error(0)
+}
+
+func (m *mockSCIMRepo) DeleteUser(ctx context.Context, tenantID, id int64) error {
+	args := m.Called(ctx, tenantID, id)
+	return args.Error(0)
+}
+
+func (m *mockSCIMRepo) ListGroups(ctx context.Context, tenantID int64, filter scim.Expr, offset, limit int) ([]*models.SCIMGroup, int, error) {
+	args := m.Called(ctx, tenantID, filter, offset, limit)
+	return args.Get(0).([]*models.SCIMGroup), args.Int(1), args.Error(2)
+}
+
+func (m *mockSCIMRepo) FindGroup(ctx context.Context, tenantID, id int64) (*models.SCIMGroup, error) {
+	args := m.Called(ctx, tenantID, id)
+	return args.Get(0).(*models.SCIMGroup), args.Error(1)
+}
+
+func (m *mockSCIMRepo) CreateGroup(ctx context.Context, group *models.SCIMGroup) error {
+	args := m.Called(ctx, group)
+	return args.Error(0)
+}
+
+func (m *mockSCIMRepo) UpdateGroup(ctx context.Context, group *models.SCIMGroup) error {
+	args := m.Called(ctx, group)
+	return args.Error(0)
+}
+
+func (m *mockSCIMRepo) DeleteGroup(ctx context.Context, tenantID, id int64) error {
+	args := m.Called(ctx, tenantID, id)
+	return args.Error(0)
+}
+
+func patchOps(t *testing.T, raw string) []scim.PatchOperation {
+	t.Helper()
+	var ops []scim.PatchOperation
+	require.NoError(t, json.Unmarshal([]byte(raw), &ops))
+	return ops
+}
+
+func TestSCIMService_CreateTenantAndAuthenticate(t *testing.T) {
+	ctx := context.Background()
+	repo := new(mockSCIMRepo)
+	svc := NewSCIMService(repo, new(recordingAuditLog))
+
+	var storedHash string
+	repo.On("CreateTenant", mock.Anything, mock.AnythingOfType("*models.SCIMTenant"), mock.AnythingOfType("string")).Return(nil).Run(func(args mock.Arguments) {
+		args.Get(1).(*models.SCIMTenant).ID = 7
+		storedHash = args.String(2)
+	})
+
+	tenant, token, err := svc.CreateTenant(ctx, "  acme  ")
+	require.NoError(t, err)
+	require.Equal(t, "acme", tenant.Name)
+	require.Contains(t, token, scimTokenPrefix)
+	require.NotContains(t, storedHash, token)
+
+	repo.On("FindTenantByTokenHash", mock.Anything, storedHash).Return(tenant, nil)
+	got, err := svc.Authenticate(ctx, token)
+	require.NoError(t, err)
+	require.Equal(t, int64(7), got.ID)
+
+	_, err = svc.Authenticate(ctx, "not-a-scim-token")
+	require.ErrorIs(t, err, repository.ErrTenantNotFound)
+
+	_, _, err = svc.CreateTenant(ctx, " ")
+	require.ErrorIs(t, err, scim.ErrInvalidValue)
+}
+
+func TestSCIMService_CreateUser(t *testing.T) {
+	ctx := context.Background()
+	inactive := false
+
+	tests := []struct {
+		name       string
+		resource   *scim.User
+		wantErr    error
+		wantEmail  string
+		wantActive bool
+	}{
+		{
+			name: "primary email is used",
+			resource: &scim.User{
+				UserName: "bjensen",
+				Emails: []scim.Email{
+					{Value: "home@example.com"},
+					{Value: "work@example.com", Primary: true},
+				},
+			},
+			wantEmail:  "work@example.com",
+			wantActive: true,
+		},
+		{
+			name:       "email falls back to userName",
+			resource:   &scim.User{UserName: "bjensen@example.com", Active: &inactive},
+			wantEmail:  "bjensen@example.com",
+			wantActive: false,
+		},
+		{
+			name:     "missing email",
+			resource: &scim.User{UserName: "bjensen"},
+			wantErr:  scim.ErrInvalidValue,
+		},
+		{
+			name:     "short password",
+			resource: &scim.User{UserName: "bjensen@example.com", Password: "short"},
+			wantErr:  scim.ErrInvalidValue,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := new(mockSCIMRepo)
+			svc := NewSCIMService(repo, new(recordingAuditLog))
+			repo.On("CreateUser", mock.Anything, mock.AnythingOfType("*models.SCIMUser")).Return(nil).Run(func(args mock.Arguments) {
+				args.Get(1).(*models.SCIMUser).ID = 42
+			})
+
+			user, err := svc.CreateUser(ctx, 3, tt.resource)
+			if tt.wantErr != nil {
+				require.ErrorIs(t, err, tt.wantErr)
+				repo.AssertNotCalled(t, "CreateUser", mock.Anything, mock.Anything)
+				return
+			}
+
+			require.NoError(t, err)
+			require.Equal(t, "42", user.ID)
+			require.Equal(t, tt.wantEmail, user.PrimaryEmail())
+			require.Equal(t, tt.wantActive, *user.Active)
+			require.Empty(t, user.Password)
+
+			stored := repo.Calls[0].Arguments.Get(1).(*models.SCIMUser)
+			require.Equal(t, int64(3), stored.TenantID)
+			require.Equal(t, tt.wantActive, stored.IsActive)
+		})
+	}
+}
+
+func TestSCIMService_PatchUser(t *testing.T) {
+	ctx := context.Background()
+
+	tests := []struct {
+		name       string
+		ops        string
+		wantErr    error
+		wantActive bool
+		wantName   string
+	}{
+		{
+			name:       "deactivate",
+			ops:        `[{"op": "replace", "path": "active", "value": false}]`,
+			wantActive: false,
+			wantName:   "bjensen",
+		},
+		{
+			name:       "string boolean from azure",
+			ops:        `[{"op": "Replace", "path": "active", "value": "False"}]`,
+			wantActive: false,
+			wantName:   "bjensen",
+		},
+		{
+			name:       "rename without path",
+			ops:        `[{"op": "replace", "value": {"userName": "babs"}}]`,
+			wantActive: true,
+			wantName:   "babs",
+		},
+		{
+			name:    "invalid filter path",
+			ops:     `[{"op": "replace", "path": "emails[type eq]", "value": "x"}]`,
+			wantErr: scim.ErrInvalidPath,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := new(mockSCIMRepo)
+			svc := NewSCIMService(repo, new(recordingAuditLog))
+			repo.On("FindUser", mock.Anything, int64(3), int64(42)).Return(&models.SCIMUser{
+				User:     models.User{ID: 42, Email: "bjensen@example.com", Username: "bjensen", IsActive: true, PasswordHash: "hash"},
+				TenantID: 3,
+			}, nil)
+			repo.On("UpdateUser", mock.Anything, mock.AnythingOfType("*models.SCIMUser")).Return(nil)
+
+			user, err := svc.PatchUser(ctx, 3, "42", patchOps(t, tt.ops))
+			if tt.wantErr != nil {
+				require.ErrorIs(t, err, tt.wantErr)
+				return
+			}
+
+			require.NoError(t, err)
+			require.Equal(t, tt.wantActive, *user.Active)
+			require.Equal(t, tt.wantName, user.UserName)
+
+			stored := repo.Calls[1].Arguments.Get(1).(*models.SCIMUser)
+			require.Equal(t, tt.wantActive, stored.IsActive)
+			require.Empty(t, stored.PasswordHash, "password must be kept when not patched")
+		})
+	}
+}
+
+func TestSCIMService_ReplaceUserAuditsPasswordChange(t *testing.T) {
+	ctx := context.Background()
+	repo := new(mockSCIMRepo)
+	audit := new(recordingAuditLog)
+	svc := NewSCIMService(repo, audit)
+	repo.On("FindUser", mock.Anything, int64(3), int64(42)).Return(&models.SCIMUser{
+		User:     models.User{ID: 42, Email: "bjensen@example.com", Username: "bjensen", IsActive: true},
+		TenantID: 3,
+	}, nil)
+	repo.On("UpdateUser", mock.Anything, mock.AnythingOfType("*models.SCIMUser")).Return(nil)
+
+	_, err := svc.ReplaceUser(ctx, 3, "42", &scim.User{UserName: "bjensen@example.com"})
+	require.NoError(t, err)
+	require.Empty(t, audit.events)
+
+	_, err = svc.ReplaceUser(ctx, 3, "42", &scim.User{UserName: "bjensen@example.com", Password: "t1meMa$heen"})
+	require.NoError(t, err)
+	require.Equal(t, []string{models.AuditPasswordChanged}, audit.actions())
+	require.Equal(t, int64(42), *audit.events[0].TargetID)
+	require.Equal(t, "3", audit.events[0].Details["scim_tenant_id"])
+}
+
+func TestSCIMService_PatchGroupMembers(t *testing.T) {
+	ctx := context.Background()
+	repo := new(mockSCIMRepo)
+	svc := NewSCIMService(repo, new(recordingAuditLog))
+
+	group := &models.SCIMGroup{
+		ID:          5,
+		TenantID:    3,
+		DisplayName: "Engineering",
+		Members:     []models.SCIMGroupMember{{UserID: 1, Username: "a"}, {UserID: 2, Username: "b"}},
+	}
+	repo.On("FindGroup", mock.Anything, int64(3), int64(5)).Return(group, nil)
+	repo.On("UpdateGroup", mock.Anything, group).Return(nil)
+
+	ops := `[
+		{"op": "add", "path": "members", "value": [{"value": "3"}]},
+		{"op": "remove", "path": "members[value eq \"1\"]"}
+	]`
+	_, err := svc.PatchGroup(ctx, 3, "5", patchOps(t, ops))
+	require.NoError(t, err)
+	require.Equal(t, []models.SCIMGroupMember{{UserID: 2}, {UserID: 3}}, group.Members)
+
+	_, err = svc.PatchGroup(ctx, 3, "5", patchOps(t, `[{"op": "add", "path": "members", "value": [{"value": "abc"}]}]`))
+	require.ErrorIs(t, err, repository.ErrInvalidGroupMember)
+
+	_, err = svc.GetGroup(ctx, 3, "not-a-number")
+	require.ErrorIs(t, err, repository.ErrGroupNotFound)
+}
@@ -0,0 +1,282 @@
+package service
+
+import (
+	"context"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/Atmosfr/user-service/internal/auth"
+	"github.com/Atmosfr/user-service/internal/models"
+	"github.com/Atmosfr/user-service/internal/repository"
+	"github.com/Atmosfr/user-service/internal/validation"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const (
+	APIKeyPrefix = "usk_"
+
+	// ClientAssertionType is the RFC 7523 client_assertion_type for JWT client assertions.
+	ClientAssertionType         = "urn:ietf:params:oauth:client-assertion-type:jwt-bearer"
+	ServiceAccountTokenDuration = time.Hour
+
+	maxAssertionLifetime = 5 * time.Minute
+	assertionLeeway      = 30 * time.Second
+	maxKeyNameLength     = 100
+)
+
+var assertionSigningMethods = []string{"RS256", "RS384", "RS512", "PS256", "PS384", "PS512", "ES256", "ES384", "ES512", "EdDSA"}
+
+var (
+	ErrInvalidKeyName   = errors.New("key name is required and must be at most 100 characters")
+	ErrInvalidPublicKey = errors.New("public_key must be a PEM encoded RSA, ECDSA or Ed25519 public key")
+	ErrKeyExpired       = errors.New("key expired")
+	ErrInvalidAssertion = errors.New("invalid client assertion")
+)
+
+type ServiceAccountTokenResponse struct {
+	AccessToken string `json:"access_token"`
+	TokenType   string `json:"token_type"`
+	ExpiresIn   int    `json:"expires_in"`
+}
+
+type ServiceAccountService interface {
+	Create(ctx context.Context, name, description string, ownerID int64) (*models.ServiceAccount, error)
+	List(ctx context.Context) ([]*models.ServiceAccount, error)
+	Get(ctx context.Context, id int64) (*models.ServiceAccount, error)
+	Delete(ctx context.Context, id int64) error
+
+	CreateAPIKey(ctx context.Context, serviceAccountID int64, name string, expiresAt *time.Time) (*models.ServiceAccountKey, string, error)
+	AddPublicKey(ctx context.Context, serviceAccountID int64, name, publicKeyPEM string, expiresAt *time.Time) (*models.ServiceAccountKey, error)
+	ListKeys(ctx context.Context, serviceAccountID int64) ([]*models.ServiceAccountKey, error)
+	RevokeKey(ctx context.Context, serviceAccountID, keyID int64) error
+
+	AuthenticateAPIKey(ctx context.Context, key string) (*models.ServiceAccount, error)
+	ExchangeAssertion(ctx context.Context, assertion, audience string) (*ServiceAccountTokenResponse, error)
+}
+
+type serviceAccountService struct {
+	repo repository.ServiceAccountRepository
+	now  func() time.Time
+}
+
+func parsePublicKey(data string) (any, error) {
+	block, _ := pem.Decode([]byte(data))
+	if block == nil || block.Type != "PUBLIC KEY" {
+		return nil, ErrInvalidPublicKey
+	}
+	key, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return nil, ErrInvalidPublicKey
+	}
+	return key, nil
+}
+
+func (s *serviceAccountService) Create(ctx context.Context, name, description string, ownerID int64) (*models.ServiceAccount, error) {
+	name = strings.TrimSpace(name)
+	description = strings.TrimSpace(description)
+	if err := validation.ValidateServiceAccount(name, description); err != nil {
+		return nil, err
+	}
+
+	sa := &models.ServiceAccount{Name: name, Description: description, OwnerID: ownerID}
+	if err := s.repo.Create(ctx, sa); err != nil {
+		return nil, err
+	}
+
+	slog.Info("service account created", "service_account_id", sa.ID, "name", name, "owner_id", ownerID)
+	return s.repo.FindByID(ctx, sa.ID)
+}
+
+func (s *serviceAccountService) List(ctx context.Context) ([]*models.ServiceAccount, error) {
+	return s.repo.List(ctx)
+}
+
+func (s *serviceAccountService) Get(ctx context.Context, id int64) (*models.ServiceAccount, error) {
+	return s.repo.FindByID(ctx, id)
+}
+
+func (s *serviceAccountService) Delete(ctx context.Context, id int64) error {
+	if err := s.repo.Delete(ctx, id); err != nil {
+		return err
+	}
+	slog.Info("service account deleted", "service_account_id", id)
+	return nil
+}
+
+func (s *serviceAccountService) validateKey(name string, expiresAt *time.Time) (string, error) {
+	name = strings.TrimSpace(name)
+	if name == "" || len(name) > maxKeyNameLength {
+		return "", ErrInvalidKeyName
+	}
+	if expiresAt != nil && !expiresAt.After(s.now()) {
+		return "", ErrInvalidTokenExpiry
+	}
+	return name, nil
+}
+
+func (s *serviceAccountService) CreateAPIKey(ctx context.Context, serviceAccountID int64, name string, expiresAt *time.Time) (*models.ServiceAccountKey, string, error) {
+	name, err := s.validateKey(name, expiresAt)
+	if err != nil {
+		return nil, "", err
+	}
+
+	secret, err := generateSecret(APIKeyPrefix)
+	if err != nil {
+		return nil, "", err
+	}
+
+	key := &models.ServiceAccountKey{
+		ServiceAccountID: serviceAccountID,
+		Type:             models.ServiceAccountKeyTypeAPIKey,
+		Name:             name,
+		Prefix:           secret[:tokenDisplayLength],
+		ExpiresAt:        expiresAt,
+	}
+	if err := s.repo.CreateKey(ctx, key, hashToken(secret)); err != nil {
+		return nil, "", err
+	}
+
+	slog.Info("service account api key created", "service_account_id", serviceAccountID, "key_id", key.ID)
+	return key, secret, nil
+}
+
+func (s *serviceAccountService) AddPublicKey(ctx context.Context, serviceAccountID int64, name, publicKeyPEM string, expiresAt *time.Time) (*models.ServiceAccountKey, error) {
+	name, err := s.validateKey(name, expiresAt)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := parsePublicKey(publicKeyPEM); err != nil {
+		return nil, err
+	}
+
+	key := &models.ServiceAccountKey{
+		ServiceAccountID: serviceAccountID,
+		Type:             models.ServiceAccountKeyTypePublicKey,
+		Name:             name,
+		PublicKey:        strings.TrimSpace(publicKeyPEM),
+		ExpiresAt:        expiresAt,
+	}
+	if err := s.repo.CreateKey(ctx, key, ""); err != nil {
+		return nil, err
+	}
+
+	slog.Info("service account public key added", "service_account_id", serviceAccountID, "key_id", key.ID)
+	return key, nil
+}
+
+func (s *serviceAccountService) ListKeys(ctx context.Context, serviceAccountID int64) ([]*models.ServiceAccountKey, error) {
+	if _, err := s.repo.FindByID(ctx, serviceAccountID); err != nil {
+		return nil, err
+	}
+	return s.repo.ListKeys(ctx, serviceAccountID)
+}
+
+func (s *serviceAccountService) RevokeKey(ctx context.Context, serviceAccountID, keyID int64) error {
+	if err := s.repo.DeleteKey(ctx, serviceAccountID, keyID); err != nil {
+		return err
+	}
+	slog.Info("service account key revoked", "service_account_id", serviceAccountID, "key_id", keyID)
+	return nil
+}
+
+func (s *serviceAccountService) AuthenticateAPIKey(ctx context.Context, secret string) (*models.ServiceAccount, error) {
+	if !hasValidChecksum(secret, APIKeyPrefix) {
+		return nil, ErrMalformedToken
+	}
+
+	key, err := s.repo.FindKeyByHash(ctx, hashToken(secret))
+	if err != nil {
+		return nil, err
+	}
+	if key.Expired(s.now()) {
+		return nil, ErrKeyExpired
+	}
+
+	sa, err := s.repo.FindByID(ctx, key.ServiceAccountID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.repo.TouchKeyLastUsed(ctx, key.ID); err != nil {
+		slog.Warn("failed to record key usage", "key_id", key.ID, "err", err)
+	}
+	return sa, nil
+}
+
+// ExchangeAssertion verifies an RFC 7523 client assertion and issues a short lived
+// access token. The assertion's iss and sub must be the service account ID, its kid
+// header the ID of a registered public key, and aud must match audience.
+func (s *serviceAccountService) ExchangeAssertion(ctx context.Context, assertion, audience string) (*ServiceAccountTokenResponse, error) {
+	claims := &jwt.RegisteredClaims{}
+	var key *models.ServiceAccountKey
+
+	_, err := jwt.ParseWithClaims(assertion, claims, func(token *jwt.Token) (interface{}, error) {
+		serviceAccountID, ok := parseResourceID(claims.Subject)
+		if !ok || claims.Issuer != claims.Subject {
+			return nil, errors.New("iss and sub must be the service account id")
+		}
+		kid, _ := token.Header["kid"].(string)
+		keyID, ok := parseResourceID(kid)
+		if !ok {
+			return nil, errors.New("missing kid header")
+		}
+
+		var err error
+		key, err = s.repo.FindKey(ctx, serviceAccountID, keyID)
+		if err != nil {
+			return nil, err
+		}
+		if key.Type != models.ServiceAccountKeyTypePublicKey {
+			return nil, errors.New("kid does not identify a public key")
+		}
+		if key.Expired(s.now()) {
+			return nil, ErrKeyExpired
+		}
+		return parsePublicKey(key.PublicKey)
+	},
+		jwt.WithValidMethods(assertionSigningMethods),
+		jwt.WithAudience(audience),
+		jwt.WithExpirationRequired(),
+		jwt.WithLeeway(assertionLeeway),
+		jwt.WithTimeFunc(s.now),
+	)
+	if err != nil {
+		slog.Warn("client assertion rejected", "err", err)
+		return nil, fmt.Errorf("%w: %v", ErrInvalidAssertion, err)
+	}
+
+	if claims.ExpiresAt.Sub(s.now()) > maxAssertionLifetime+assertionLeeway {
+		return nil, fmt.Errorf("%w: assertion lifetime exceeds %s", ErrInvalidAssertion, maxAssertionLifetime)
+	}
+
+	sa, err := s.repo.FindByID(ctx, key.ServiceAccountID)
+	if err != nil {
+		return nil, err
+	}
+
+	token, err := auth.GenerateServiceAccountToken(sa, ServiceAccountTokenDuration, auth.JwtSecret)
+	if err != nil {
+		slog.Error("failed to generate token", "err", err)
+		return nil, err
+	}
+
+	if err := s.repo.TouchKeyLastUsed(ctx, key.ID); err != nil {
+		slog.Warn("failed to record key usage", "key_id", key.ID, "err", err)
+	}
+
+	slog.Info("service account token issued", "service_account_id", sa.ID, "key_id", key.ID)
+	return &ServiceAccountTokenResponse{
+		AccessToken: token,
+		TokenType:   "Bearer",
+		ExpiresIn:   int(ServiceAccountTokenDuration.Seconds()),
+	}, nil
+}
+
+func NewServiceAccountService(repo repository.ServiceAccountRepository) ServiceAccountService {
+	return &serviceAccountService{repo: repo, now: time.Now}
+}
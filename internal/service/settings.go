@@ -0,0 +1,102 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"maps"
+	"slices"
+
+	"github.com/Atmosfr/user-service/internal/repository"
+	"github.com/Atmosfr/user-service/internal/settings"
+	"github.com/Atmosfr/user-service/internal/validation"
+)
+
+var (
+	SettingTheme                = settings.String("theme", "system", settings.OneOf("system", "light", "dark"))
+	SettingLanguage             = settings.String("language", "en", validation.ValidateLocale)
+	SettingNotificationChannels = settings.StringList("notification_channels", []string{"email"}, settings.EachOneOf("email", "sms", "push"))
+)
+
+// DefaultSettings are the settings users can change.
+var DefaultSettings = settings.NewRegistry(
+	SettingTheme,
+	SettingLanguage,
+	SettingNotificationChannels,
+)
+
+// SettingsService stores the settings users changed and resolves them against
+// the defaults of a registry, so callers always get every setting.
+type SettingsService interface {
+	Get(ctx context.Context, userID int64) (settings.Values, error)
+	// Update changes the settings in patch and leaves the others alone. A null
+	// value resets a setting to its default. Nothing is saved if any key is
+	// unknown or any value invalid.
+	Update(ctx context.Context, userID int64, patch map[string]json.RawMessage) (settings.Values, error)
+	// Resolve returns the settings of many users at once, e.g. to pick the
+	// notification channels of everyone in an organization.
+	Resolve(ctx context.Context, userIDs []int64) (map[int64]settings.Values, error)
+}
+
+type settingsService struct {
+	repo     repository.SettingsRepository
+	registry *settings.Registry
+}
+
+func (s *settingsService) Get(ctx context.Context, userID int64) (settings.Values, error) {
+	stored, err := s.repo.List(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	return s.registry.Resolve(stored[userID]), nil
+}
+
+func (s *settingsService) Update(ctx context.Context, userID int64, patch map[string]json.RawMessage) (settings.Values, error) {
+	set := make(map[string]json.RawMessage, len(patch))
+	var reset []string
+	// sorted so the first invalid key is reported consistently
+	for _, key := range slices.Sorted(maps.Keys(patch)) {
+		raw := patch[key]
+		if string(raw) == "null" {
+			if _, err := s.registry.Lookup(key); err != nil {
+				return nil, err
+			}
+			reset = append(reset, key)
+			continue
+		}
+		value, err := s.registry.Decode(key, raw)
+		if err != nil {
+			return nil, err
+		}
+		// stored re-encoded, so whitespace and formatting of the request are not kept
+		encoded, err := json.Marshal(value)
+		if err != nil {
+			return nil, err
+		}
+		set[key] = encoded
+	}
+
+	if len(set) > 0 || len(reset) > 0 {
+		if err := s.repo.Save(ctx, userID, set, reset); err != nil {
+			return nil, err
+		}
+		slog.Info("settings updated", "user_id", userID, "changed", len(set), "reset", len(reset))
+	}
+	return s.Get(ctx, userID)
+}
+
+func (s *settingsService) Resolve(ctx context.Context, userIDs []int64) (map[int64]settings.Values, error) {
+	stored, err := s.repo.List(ctx, userIDs...)
+	if err != nil {
+		return nil, err
+	}
+	resolved := make(map[int64]settings.Values, len(userIDs))
+	for _, id := range userIDs {
+		resolved[id] = s.registry.Resolve(stored[id])
+	}
+	return resolved, nil
+}
+
+func NewSettingsService(repo repository.SettingsRepository, registry *settings.Registry) SettingsService {
+	return &settingsService{repo: repo, registry: registry}
+}
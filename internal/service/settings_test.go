@@ -0,0 +1,71 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/Atmosfr/user-service/internal/settings"
+	"github.com/Atmosfr/user-service/internal/validation"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+type mockSettingsRepo struct {
+	mock.Mock
+}
+
+func (m *mockSettingsRepo) List(ctx context.Context, userIDs ...int64) (map[int64]map[string]json.RawMessage, error) {
+	args := m.Called(ctx, userIDs)
+	stored, _ := args.Get(0).(map[int64]map[string]json.RawMessage)
+	return stored, args.Error(1)
+}
+
+func (m *mockSettingsRepo) Save(ctx context.Context, userID int64, set map[string]json.RawMessage, reset []string) error {
+	return m.Called(ctx, userID, set, reset).Error(0)
+}
+
+func TestSettingsService_Update(t *testing.T) {
+	ctx := context.Background()
+	repo := new(mockSettingsRepo)
+	svc := NewSettingsService(repo, DefaultSettings)
+
+	repo.On("Save", mock.Anything, int64(7),
+		map[string]json.RawMessage{"theme": json.RawMessage(`"dark"`), "notification_channels": json.RawMessage(`["push","email"]`)},
+		[]string{"language"}).Return(nil).Once()
+	repo.On("List", mock.Anything, []int64{7}).Return(map[int64]map[string]json.RawMessage{
+		7: {"theme": json.RawMessage(`"dark"`), "notification_channels": json.RawMessage(`["push","email"]`)},
+	}, nil).Once()
+
+	values, err := svc.Update(ctx, 7, map[string]json.RawMessage{
+		"theme":                 json.RawMessage(`"dark"`),
+		"notification_channels": json.RawMessage(`[ "push", "email" ]`),
+		"language":              json.RawMessage(`null`),
+	})
+	require.NoError(t, err)
+	require.Equal(t, "dark", settings.Get(values, SettingTheme))
+	require.Equal(t, "en", settings.Get(values, SettingLanguage))
+	require.Equal(t, []string{"push", "email"}, settings.Get(values, SettingNotificationChannels))
+
+	// one bad value rejects the whole update
+	_, err = svc.Update(ctx, 7, map[string]json.RawMessage{"theme": json.RawMessage(`"light"`), "language": json.RawMessage(`"not a tag"`)})
+	require.ErrorIs(t, err, settings.ErrInvalidValue)
+	require.ErrorIs(t, err, validation.ErrInvalidLocale)
+	_, err = svc.Update(ctx, 7, map[string]json.RawMessage{"font": json.RawMessage(`null`)})
+	require.ErrorIs(t, err, settings.ErrUnknownSetting)
+	repo.AssertExpectations(t)
+}
+
+func TestSettingsService_Resolve(t *testing.T) {
+	repo := new(mockSettingsRepo)
+	svc := NewSettingsService(repo, DefaultSettings)
+	repo.On("List", mock.Anything, []int64{7, 8}).Return(map[int64]map[string]json.RawMessage{
+		8: {"notification_channels": json.RawMessage(`["sms"]`)},
+	}, nil)
+
+	resolved, err := svc.Resolve(context.Background(), []int64{7, 8})
+	require.NoError(t, err)
+	require.Equal(t, []string{"email"}, settings.Get(resolved[7], SettingNotificationChannels))
+	require.Equal(t, []string{"sms"}, settings.Get(resolved[8], SettingNotificationChannels))
+	require.Equal(t, "system", resolved[8]["theme"])
+}
@@ -0,0 +1,207 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"log/slog"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/Atmosfr/user-service/internal/models"
+	"github.com/Atmosfr/user-service/internal/repository"
+)
+
+// Personal access tokens look like "usp_" followed by 30 random base62 characters
+// and a 6 character CRC32 checksum, so secret scanners can match and verify them offline.
+// Service account API keys use the same format with their own prefix.
+const (
+	PersonalAccessTokenPrefix = "usp_"
+
+	tokenSecretLength   = 30
+	tokenChecksumLength = 6
+	tokenDisplayLength  = 12
+	maxTokenNameLength  = 100
+)
+
+const (
+	ScopeUserRead  = "user:read"
+	ScopeUserWrite = "user:write"
+	ScopeAdmin     = "admin"
+)
+
+var TokenScopes = []string{ScopeUserRead, ScopeUserWrite, ScopeAdmin}
+
+var (
+	ErrInvalidTokenName   = errors.New("token name is required and must be at most 100 characters")
+	ErrInvalidTokenScope  = errors.New("unknown token scope")
+	ErrNoTokenScopes      = errors.New("at least one scope is required")
+	ErrInvalidTokenExpiry = errors.New("token expiry must be in the future")
+	ErrTokenExpired       = errors.New("token expired")
+	ErrMalformedToken     = errors.New("malformed token")
+)
+
+const base62Alphabet = "0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz"
+
+type TokenService interface {
+	CreateToken(ctx context.Context, userID int64, name string, scopes []string, expiresAt *time.Time) (*models.PersonalAccessToken, string, error)
+	ListTokens(ctx context.Context, userID int64) ([]*models.PersonalAccessToken, error)
+	RevokeToken(ctx context.Context, userID, tokenID int64) error
+	Authenticate(ctx context.Context, token string) (*models.PersonalAccessToken, error)
+}
+
+type tokenService struct {
+	repo repository.TokenRepository
+	now  func() time.Time
+}
+
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+func randomBase62(n int) (string, error) {
+	out := make([]byte, 0, n)
+	buf := make([]byte, n*2)
+	for len(out) < n {
+		if _, err := rand.Read(buf); err != nil {
+			return "", err
+		}
+		for _, b := range buf {
+			// 248 is the largest multiple of 62 below 256, rejecting above it avoids modulo bias
+			if b < 248 && len(out) < n {
+				out = append(out, base62Alphabet[b%62])
+			}
+		}
+	}
+	return string(out), nil
+}
+
+func tokenChecksum(secret string) string {
+	sum := crc32.ChecksumIEEE([]byte(secret))
+	out := make([]byte, tokenChecksumLength)
+	for i := tokenChecksumLength - 1; i >= 0; i-- {
+		out[i] = base62Alphabet[sum%62]
+		sum /= 62
+	}
+	return string(out)
+}
+
+// generateSecret returns prefix followed by a random base62 secret and its checksum.
+func generateSecret(prefix string) (string, error) {
+	secret, err := randomBase62(tokenSecretLength)
+	if err != nil {
+		return "", err
+	}
+	return prefix + secret + tokenChecksum(secret), nil
+}
+
+func hasValidChecksum(token, prefix string) bool {
+	body, ok := strings.CutPrefix(token, prefix)
+	if !ok || len(body) != tokenSecretLength+tokenChecksumLength {
+		return false
+	}
+	secret, checksum := body[:tokenSecretLength], body[tokenSecretLength:]
+	return tokenChecksum(secret) == checksum
+}
+
+// GeneratePersonalAccessToken returns a new random token in the personal access token format.
+func GeneratePersonalAccessToken() (string, error) {
+	return generateSecret(PersonalAccessTokenPrefix)
+}
+
+// IsPersonalAccessToken reports whether token has the personal access token format and a valid checksum.
+func IsPersonalAccessToken(token string) bool {
+	return hasValidChecksum(token, PersonalAccessTokenPrefix)
+}
+
+func normalizeScopes(scopes []string) ([]string, error) {
+	if len(scopes) == 0 {
+		return nil, ErrNoTokenScopes
+	}
+	normalized := make([]string, 0, len(scopes))
+	for _, scope := range scopes {
+		scope = strings.ToLower(strings.TrimSpace(scope))
+		if !slices.Contains(TokenScopes, scope) {
+			return nil, fmt.Errorf("%w: %q", ErrInvalidTokenScope, scope)
+		}
+		if !slices.Contains(normalized, scope) {
+			normalized = append(normalized, scope)
+		}
+	}
+	slices.Sort(normalized)
+	return normalized, nil
+}
+
+func (s *tokenService) CreateToken(ctx context.Context, userID int64, name string, scopes []string, expiresAt *time.Time) (*models.PersonalAccessToken, string, error) {
+	name = strings.TrimSpace(name)
+	if name == "" || len(name) > maxTokenNameLength {
+		return nil, "", ErrInvalidTokenName
+	}
+	scopes, err := normalizeScopes(scopes)
+	if err != nil {
+		return nil, "", err
+	}
+	if expiresAt != nil && !expiresAt.After(s.now()) {
+		return nil, "", ErrInvalidTokenExpiry
+	}
+
+	secret, err := GeneratePersonalAccessToken()
+	if err != nil {
+		return nil, "", err
+	}
+
+	token := &models.PersonalAccessToken{
+		UserID:    userID,
+		Name:      name,
+		Prefix:    secret[:tokenDisplayLength],
+		Scopes:    scopes,
+		ExpiresAt: expiresAt,
+	}
+	if err := s.repo.Create(ctx, token, hashToken(secret)); err != nil {
+		return nil, "", err
+	}
+
+	slog.Info("personal access token created", "user_id", userID, "token_id", token.ID, "scopes", scopes)
+	return token, secret, nil
+}
+
+func (s *tokenService) ListTokens(ctx context.Context, userID int64) ([]*models.PersonalAccessToken, error) {
+	return s.repo.ListByUser(ctx, userID)
+}
+
+func (s *tokenService) RevokeToken(ctx context.Context, userID, tokenID int64) error {
+	if err := s.repo.Delete(ctx, userID, tokenID); err != nil {
+		return err
+	}
+	slog.Info("personal access token revoked", "user_id", userID, "token_id", tokenID)
+	return nil
+}
+
+func (s *tokenService) Authenticate(ctx context.Context, secret string) (*models.PersonalAccessToken, error) {
+	if !IsPersonalAccessToken(secret) {
+		return nil, ErrMalformedToken
+	}
+
+	token, err := s.repo.FindByHash(ctx, hashToken(secret))
+	if err != nil {
+		return nil, err
+	}
+	if token.Expired(s.now()) {
+		return nil, ErrTokenExpired
+	}
+
+	if err := s.repo.TouchLastUsed(ctx, token.ID); err != nil {
+		slog.Warn("failed to record token usage", "token_id", token.ID, "err", err)
+	}
+	return token, nil
+}
+
+func NewTokenService(repo repository.TokenRepository) TokenService {
+	return &tokenService{repo: repo, now: time.Now}
+}
@@ -0,0 +1,236 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Atmosfr/user-service/internal/models"
+	"github.com/Atmosfr/user-service/internal/repository"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+type mockTokenRepo struct {
+	mock.Mock
+}
+
+func (m *mockTokenRepo) Create(ctx context.Context, token *models.PersonalAccessToken, tokenHash string) error {
+	return m.Called(ctx, token, tokenHash).Error(0)
+}
+
+func (m *mockTokenRepo) ListByUser(ctx context.Context, userID int64) ([]*models.PersonalAccessToken, error) {
+	args := m.Called(ctx, userID)
+	return args.Get(0).([]*models.PersonalAccessToken), args.Error(1)
+}
+
+func (m *mockTokenRepo) FindByHash(ctx context.Context, tokenHash string) (*models.PersonalAccessToken, error) {
+	args := m.Called(ctx, tokenHash)
+	return args.Get(0).(*models.PersonalAccessToken), args.Error(1)
+}
+
+func (m *mockTokenRepo) Delete(ctx context.Context, userID, id int64) error {
+	return m.Called(ctx, userID, id).Error(0)
+}
+
+func (m *mockTokenRepo) TouchLastUsed(ctx context.Context, id int64) error {
+	return m.Called(ctx, id).Error(0)
+}
+
+func TestPersonalAccessTokenFormat(t *testing.T) {
+	token, err := GeneratePersonalAccessToken()
+	require.NoError(t, err)
+	require.True(t, strings.HasPrefix(token, PersonalAccessTokenPrefix))
+	require.Len(t, token, len(PersonalAccessTokenPrefix)+tokenSecretLength+tokenChecksumLength)
+	require.True(t, IsPersonalAccessToken(token))
+
+	other, err := GeneratePersonalAccessToken()
+	require.NoError(t, err)
+	require.NotEqual(t, token, other)
+
+	// flipping a single character must break the checksum
+	last := token[len(PersonalAccessTokenPrefix)]
+	flipped := byte('A')
+	if last == 'A' {
+		flipped = 'B'
+	}
+	tampered := PersonalAccessTokenPrefix + string(flipped) + token[len(PersonalAccessTokenPrefix)+1:]
+	require.False(t, IsPersonalAccessToken(tampered))
+
+	require.False(t, IsPersonalAccessToken("scim_"+token[len(PersonalAccessTokenPrefix):]))
+	require.False(t, IsPersonalAccessToken(token[:len(token)-1]))
+}
+
+func TestTokenService_CreateToken(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC)
+	future := now.Add(30 * 24 * time.Hour)
+	past := now.Add(-time.Minute)
+
+	tests := []struct {
+		name       string
+		tokenName  string
+		scopes     []string
+		expiresAt  *time.Time
+		setupMock  func(repo *mockTokenRepo)
+		wantErr    error
+		wantScopes []string
+	}{
+		{
+			name:      "valid token with expiry",
+			tokenName: "  ci deploy  ",
+			scopes:    []string{"user:write", "USER:READ", "user:read"},
+			expiresAt: &future,
+			setupMock: func(repo *mockTokenRepo) {
+				repo.On("Create", mock.Anything, mock.MatchedBy(func(tok *models.PersonalAccessToken) bool {
+					return tok.UserID == 7 && tok.Name == "ci deploy" && strings.HasPrefix(tok.Prefix, PersonalAccessTokenPrefix)
+				}), mock.MatchedBy(func(hash string) bool { return len(hash) == 64 })).Return(nil)
+			},
+			wantScopes: []string{"user:read", "user:write"},
+		},
+		{
+			name:       "no expiry is allowed",
+			tokenName:  "laptop",
+			scopes:     []string{"user:read"},
+			setupMock:  func(repo *mockTokenRepo) { repo.On("Create", mock.Anything, mock.Anything, mock.Anything).Return(nil) },
+			wantScopes: []string{"user:read"},
+		},
+		{
+			name:      "empty name",
+			tokenName: " ",
+			scopes:    []string{"user:read"},
+			setupMock: func(repo *mockTokenRepo) {},
+			wantErr:   ErrInvalidTokenName,
+		},
+		{
+			name:      "unknown scope",
+			tokenName: "laptop",
+			scopes:    []string{"repo"},
+			setupMock: func(repo *mockTokenRepo) {},
+			wantErr:   ErrInvalidTokenScope,
+		},
+		{
+			name:      "no scopes",
+			tokenName: "laptop",
+			setupMock: func(repo *mockTokenRepo) {},
+			wantErr:   ErrNoTokenScopes,
+		},
+		{
+			name:      "expiry in the past",
+			tokenName: "laptop",
+			scopes:    []string{"user:read"},
+			expiresAt: &past,
+			setupMock: func(repo *mockTokenRepo) {},
+			wantErr:   ErrInvalidTokenExpiry,
+		},
+		{
+			name:      "duplicate name",
+			tokenName: "laptop",
+			scopes:    []string{"user:read"},
+			setupMock: func(repo *mockTokenRepo) {
+				repo.On("Create", mock.Anything, mock.Anything, mock.Anything).Return(repository.ErrTokenNameAlreadyExists)
+			},
+			wantErr: repository.ErrTokenNameAlreadyExists,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := new(mockTokenRepo)
+			tt.setupMock(repo)
+			svc := &tokenService{repo: repo, now: func() time.Time { return now }}
+
+			token, secret, err := svc.CreateToken(ctx, 7, tt.tokenName, tt.scopes, tt.expiresAt)
+			if tt.wantErr != nil {
+				require.ErrorIs(t, err, tt.wantErr)
+				require.Empty(t, secret)
+				return
+			}
+
+			require.NoError(t, err)
+			require.True(t, IsPersonalAccessToken(secret))
+			require.True(t, strings.HasPrefix(secret, token.Prefix))
+			require.Equal(t, tt.wantScopes, token.Scopes)
+			require.Equal(t, tt.expiresAt, token.ExpiresAt)
+
+			// only the hash of the secret is handed to the repository
+			repo.AssertCalled(t, "Create", mock.Anything, token, hashToken(secret))
+		})
+	}
+}
+
+func TestTokenService_Authenticate(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC)
+	expired := now.Add(-time.Second)
+	valid := now.Add(time.Hour)
+
+	secret, err := GeneratePersonalAccessToken()
+	require.NoError(t, err)
+
+	tests := []struct {
+		name      string
+		secret    string
+		setupMock func(repo *mockTokenRepo)
+		wantErr   error
+	}{
+		{
+			name:   "valid token records usage",
+			secret: secret,
+			setupMock: func(repo *mockTokenRepo) {
+				repo.On("FindByHash", mock.Anything, hashToken(secret)).Return(&models.PersonalAccessToken{ID: 1, UserID: 7, ExpiresAt: &valid}, nil)
+				repo.On("TouchLastUsed", mock.Anything, int64(1)).Return(nil)
+			},
+		},
+		{
+			name:   "usage tracking failure does not reject the token",
+			secret: secret,
+			setupMock: func(repo *mockTokenRepo) {
+				repo.On("FindByHash", mock.Anything, hashToken(secret)).Return(&models.PersonalAccessToken{ID: 1, UserID: 7}, nil)
+				repo.On("TouchLastUsed", mock.Anything, int64(1)).Return(errors.New("db down"))
+			},
+		},
+		{
+			name:   "expired token",
+			secret: secret,
+			setupMock: func(repo *mockTokenRepo) {
+				repo.On("FindByHash", mock.Anything, hashToken(secret)).Return(&models.PersonalAccessToken{ID: 1, UserID: 7, ExpiresAt: &expired}, nil)
+			},
+			wantErr: ErrTokenExpired,
+		},
+		{
+			name:   "revoked token",
+			secret: secret,
+			setupMock: func(repo *mockTokenRepo) {
+				repo.On("FindByHash", mock.Anything, hashToken(secret)).Return((*models.PersonalAccessToken)(nil), repository.ErrTokenNotFound)
+			},
+			wantErr: repository.ErrTokenNotFound,
+		},
+		{
+			name:      "bad checksum is rejected without a lookup",
+			secret:    secret[:len(secret)-1] + "!",
+			setupMock: func(repo *mockTokenRepo) {},
+			wantErr:   ErrMalformedToken,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := new(mockTokenRepo)
+			tt.setupMock(repo)
+			svc := &tokenService{repo: repo, now: func() time.Time { return now }}
+
+			token, err := svc.Authenticate(ctx, tt.secret)
+			if tt.wantErr != nil {
+				require.ErrorIs(t, err, tt.wantErr)
+				repo.AssertNotCalled(t, "TouchLastUsed", mock.Anything, mock.Anything)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, int64(7), token.UserID)
+			repo.AssertExpectations(t)
+		})
+	}
+}
@@ -2,7 +2,9 @@ package service
 
 import (
 	"context"
+	"errors"
 	"log/slog"
+	"strings"
 	"time"
 
 	"github.com/Atmosfr/user-service/internal/auth"
@@ -15,12 +17,149 @@ import (
 const AccessTokenDuration = time.Hour * 24
 
 type UserService interface {
-	Register(ctx context.Context, email, password, username string) (*LoginResponse, error)
+	Register(ctx context.Context, email, password, username string, consent RegistrationConsent) (*LoginResponse, error)
 	Login(ctx context.Context, email, password string) (*LoginResponse, error)
+	// UpdateProfile applies patch to the user. With ifUnmodified set the update
+	// fails with repository.ErrUserModified if the user changed since then.
+	UpdateProfile(ctx context.Context, userID int64, patch ProfilePatch, ifUnmodified *time.Time) (*models.User, error)
+}
+
+// RegistrationConsent is what a new user agreed to when signing up.
+type RegistrationConsent struct {
+	// Accepted maps document kinds to the accepted version.
+	Accepted  map[string]string
+	Marketing bool
+	IP        string
+}
+
+// ProfilePatch holds the profile fields to change; nil fields are left as they
+// are and empty strings clear optional fields.
+type ProfilePatch struct {
+	Username    *string
+	DisplayName *string
+	Locale      *string
+	Timezone    *string
+	AvatarURL   *string
 }
 
 type userService struct {
-	repo repository.UserRepository
+	repo          repository.UserRepository
+	authenticator Authenticator
+	domainPolicy  DomainPolicy
+	verification  EmailVerificationService
+	claims        MetadataClaims
+	consents      ConsentService
+	audit         AuditLog
+	avatars       AvatarService
+}
+
+type UserServiceOption func(*userService)
+
+// WithAuthenticator replaces the default bcrypt check against the users table.
+func WithAuthenticator(authn Authenticator) UserServiceOption {
+	return func(u *userService) {
+		u.authenticator = authn
+	}
+}
+
+// WithDomainPolicy refuses registration with a password for addresses whose
+// domain enforces single sign-on.
+func WithDomainPolicy(policy DomainPolicy) UserServiceOption {
+	return func(u *userService) {
+		u.domainPolicy = policy
+	}
+}
+
+// WithEmailVerification emails new users a link to verify their address.
+func WithEmailVerification(verification EmailVerificationService) UserServiceOption {
+	return func(u *userService) {
+		u.verification = verification
+	}
+}
+
+// WithMetadataClaims adds the user's metadata claims to tokens issued on
+// registration and login.
+func WithMetadataClaims(claims MetadataClaims) UserServiceOption {
+	return func(u *userService) {
+		u.claims = claims
+	}
+}
+
+// WithConsents requires new users to accept the current legal documents and
+// records their choices.
+func WithConsents(consents ConsentService) UserServiceOption {
+	return func(u *userService) {
+		u.consents = consents
+	}
+}
+
+// WithAuditLog records registrations and sign-in attempts.
+func WithAuditLog(audit AuditLog) UserServiceOption {
+	return func(u *userService) {
+		u.audit = audit
+	}
+}
+
+// WithAvatars deletes the files of an uploaded avatar once a profile update
+// replaces it with an external URL.
+func WithAvatars(avatars AvatarService) UserServiceOption {
+	return func(u *userService) {
+		u.avatars = avatars
+	}
+}
+
+// loginFailureReason names why a sign-in failed in the audit log.
+func loginFailureReason(err error) string {
+	switch {
+	case errors.Is(err, repository.ErrUserNotFound):
+		return "unknown_user"
+	case errors.Is(err, repository.ErrInvalidPassword), errors.Is(err, repository.ErrInvalidCredentials):
+		return "invalid_password"
+	case errors.Is(err, ErrSSORequired):
+		return "sso_required"
+	case errors.Is(err, ErrAccountSuspended):
+		return "account_suspended"
+	case errors.Is(err, ErrAccountBanned):
+		return "account_banned"
+	case errors.Is(err, ErrAccountPendingVerification):
+		return "account_pending_verification"
+	case errors.Is(err, ErrAccountDeleted):
+		return "account_deleted"
+	}
+	return "error"
+}
+
+// recordLoginFailure audits a failed sign-in. The target is the account of
+// email if there is one, the address itself is not recorded.
+func (u *userService) recordLoginFailure(ctx context.Context, email string, user *models.User, err error) {
+	if u.audit == nil {
+		return
+	}
+	event := &models.AuditEvent{Action: models.AuditLoginFailed, Details: map[string]string{"reason": loginFailureReason(err)}}
+	if user == nil && !errors.Is(err, repository.ErrUserNotFound) {
+		user, _ = u.repo.FindByEmail(ctx, email)
+	}
+	if user != nil {
+		event.TargetID = &user.ID
+	}
+	u.audit.Record(ctx, event)
+}
+
+// metadataTokenOptions returns the metadata claim for user. Tokens are still
+// issued without it if the metadata cannot be read.
+func metadataTokenOptions(ctx context.Context, claims MetadataClaims, user *models.User) []auth.TokenOption {
+	if claims == nil {
+		return nil
+	}
+	metadata, err := claims.TokenClaims(ctx, user)
+	if err != nil {
+		slog.Error("failed to read metadata claims", "user_id", user.ID, "err", err)
+		return nil
+	}
+	if metadata == nil {
+		return nil
+	}
+	return []auth.TokenOption{auth.WithMetadata(metadata)}
 }
 
 type LoginResponse struct {
@@ -28,12 +167,29 @@ type LoginResponse struct {
 	Token string       `json:"token"`
 }
 
-func (u *userService) Register(ctx context.Context, email, password, username string) (*LoginResponse, error) {
+func (u *userService) Register(ctx context.Context, email, password, username string, consent RegistrationConsent) (*LoginResponse, error) {
 	if err := validation.ValidateRegister(email, password, username); err != nil {
-		slog.Warn("registration validation failed", "email", email, "err", err)
+		slog.Warn("registration validation failed", "err", err)
 		return nil, err
 	}
 
+	if u.consents != nil {
+		if err := u.consents.CheckAccepted(ctx, consent.Accepted); err != nil {
+			return nil, err
+		}
+	}
+
+	if u.domainPolicy != nil {
+		required, err := u.domainPolicy.SSORequired(ctx, email)
+		if err != nil {
+			return nil, err
+		}
+		if required {
+			slog.Warn("password registration refused for sso domain", "domain", EmailDomain(email))
+			return nil, ErrSSORequired
+		}
+	}
+
 	if _, err := u.repo.FindByEmail(ctx, email); err == nil {
 		return nil, repository.ErrEmailAlreadyExists
 	}
@@ -50,6 +206,7 @@ func (u *userService) Register(ctx context.Context, email, password, username st
 		PasswordHash: passwordHash,
 		Username:     username,
 		IsActive:     true,
+		State:        models.AccountActive,
 		Role:         "user",
 		CreatedAt:    time.Now(),
 		UpdatedAt:    time.Now(),
@@ -60,7 +217,24 @@ func (u *userService) Register(ctx context.Context, email, password, username st
 		return nil, err
 	}
 
-	token, err := auth.GenerateToken(user, AccessTokenDuration, auth.JwtSecret)
+	if u.consents != nil {
+		// without the record the consent gate asks the user again, so the account stays
+		if err := u.consents.Accept(ctx, user.ID, consent.Accepted, consent.IP); err != nil {
+			slog.Error("failed to record consent on registration", "user_id", user.ID, "err", err)
+		}
+		if err := u.consents.SetMarketing(ctx, user.ID, consent.Marketing, consent.IP); err != nil {
+			slog.Error("failed to record marketing consent on registration", "user_id", user.ID, "err", err)
+		}
+	}
+
+	if u.verification != nil {
+		// the account is usable without a verified address, the user can ask for another link
+		if err := u.verification.Send(ctx, user); err != nil {
+			slog.Warn("verification email not sent on registration", "user_id", user.ID, "err", err)
+		}
+	}
+
+	token, err := auth.GenerateToken(user, AccessTokenDuration, auth.JwtSecret, metadataTokenOptions(ctx, u.claims, user)...)
 	if err != nil {
 		slog.Error("failed to generate token", "err", err)
 		return nil, err
@@ -68,7 +242,10 @@ func (u *userService) Register(ctx context.Context, email, password, username st
 
 	user.PasswordHash = ""
 
-	slog.Info("user registered", "email", email)
+	if u.audit != nil {
+		u.audit.Record(ctx, &models.AuditEvent{Action: models.AuditUserRegistered, ActorID: &user.ID, TargetID: &user.ID})
+	}
+	slog.Info("user registered", "user_id", user.ID)
 	return &LoginResponse{
 		User:  user,
 		Token: token,
@@ -77,22 +254,28 @@ func (u *userService) Register(ctx context.Context, email, password, username st
 
 func (u *userService) Login(ctx context.Context, email, password string) (*LoginResponse, error) {
 	if err := validation.ValidateLogin(email, password); err != nil {
-		slog.Warn("login validation failed", "email", email, "err", err)
+		slog.Warn("login validation failed", "err", err)
 		return nil, err
 	}
 
-	user, err := u.repo.FindByEmail(ctx, email)
+	user, err := u.authenticator.Authenticate(ctx, email, password)
 	if err != nil {
-		slog.Warn("user not found", "email", email)
+		u.recordLoginFailure(ctx, email, nil, err)
 		return nil, err
 	}
-
-	if err := bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(password)); err != nil {
-		slog.Warn("wrong password for user", "email", email)
-		return nil, repository.ErrInvalidPassword
+	if err := CheckAccountState(user, time.Now()); err != nil {
+		slog.Warn("login refused", "user_id", user.ID, "state", user.State)
+		u.recordLoginFailure(ctx, email, user, err)
+		if errors.Is(err, ErrAccountPendingVerification) && u.verification != nil {
+			// the link is the only way out of the state, so send a fresh one
+			if err := u.verification.Send(ctx, user); err != nil {
+				slog.Warn("verification email not sent on login", "user_id", user.ID, "err", err)
+			}
+		}
+		return nil, err
 	}
 
-	token, err := auth.GenerateToken(user, AccessTokenDuration, auth.JwtSecret)
+	token, err := auth.GenerateToken(user, AccessTokenDuration, auth.JwtSecret, metadataTokenOptions(ctx, u.claims, user)...)
 	if err != nil {
 		slog.Error("failed to generate token", "err", err)
 		return nil, err
@@ -100,7 +283,10 @@ func (u *userService) Login(ctx context.Context, email, password string) (*Login
 
 	user.PasswordHash = ""
 
-	slog.Info("login successful", "email", email)
+	if u.audit != nil {
+		u.audit.Record(ctx, &models.AuditEvent{Action: models.AuditLoginSucceeded, ActorID: &user.ID, TargetID: &user.ID})
+	}
+	slog.Info("login successful", "user_id", user.ID)
 
 	return &LoginResponse{
 		User:  user,
@@ -108,6 +294,54 @@ func (u *userService) Login(ctx context.Context, email, password string) (*Login
 	}, nil
 }
 
-func NewUserService(repo repository.UserRepository) UserService {
-	return &userService{repo: repo}
+func (u *userService) UpdateProfile(ctx context.Context, userID int64, patch ProfilePatch, ifUnmodified *time.Time) (*models.User, error) {
+	user, err := u.repo.FindByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if ifUnmodified != nil && !user.UpdatedAt.Equal(*ifUnmodified) {
+		return nil, repository.ErrUserModified
+	}
+
+	if patch.Username != nil {
+		user.Username = *patch.Username
+	}
+	if patch.DisplayName != nil {
+		user.DisplayName = strings.TrimSpace(*patch.DisplayName)
+	}
+	if patch.Locale != nil {
+		user.Locale = *patch.Locale
+	}
+	if patch.Timezone != nil {
+		user.Timezone = *patch.Timezone
+	}
+	previousAvatarKey := user.AvatarKey
+	if patch.AvatarURL != nil {
+		// an explicit URL replaces any uploaded avatar
+		user.AvatarURL = *patch.AvatarURL
+		user.AvatarURLs = nil
+		user.AvatarKey = ""
+	}
+	if err := validation.ValidateProfile(user.Username, user.DisplayName, user.Locale, user.Timezone, user.AvatarURL); err != nil {
+		return nil, err
+	}
+
+	if err := u.repo.Update(ctx, user, ifUnmodified); err != nil {
+		return nil, err
+	}
+	if previousAvatarKey != "" && user.AvatarKey == "" && u.avatars != nil {
+		u.avatars.DeleteFiles(ctx, previousAvatarKey)
+	}
+	user.PasswordHash = ""
+
+	slog.Info("profile updated", "user_id", user.ID)
+	return user, nil
+}
+
+func NewUserService(repo repository.UserRepository, opts ...UserServiceOption) UserService {
+	u := &userService{repo: repo, authenticator: NewLocalAuthenticator(repo)}
+	for _, opt := range opts {
+		opt(u)
+	}
+	return u
 }
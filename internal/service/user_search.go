@@ -0,0 +1,140 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/Atmosfr/user-service/internal/models"
+)
+
+const (
+	// MinSearchQueryLength is the shortest query that has a trigram to look up.
+	MinSearchQueryLength = 3
+	DefaultSearchLimit   = 20
+	MaxSearchLimit       = 100
+)
+
+var ErrSearchQueryTooShort = errors.New("search query must be at least 3 characters")
+
+// TextRange is a half-open range of characters in a string, counted in
+// Unicode code points.
+type TextRange struct {
+	Start int `json:"start"`
+	End   int `json:"end"`
+}
+
+// UserSearchResult is a user found by Search with the fragments of its email
+// and username that matched the query.
+type UserSearchResult struct {
+	User       *models.User
+	Score      float64
+	Highlights map[string][]TextRange
+}
+
+func (s *adminUserService) Search(ctx context.Context, query string, limit int) ([]*UserSearchResult, error) {
+	query = strings.TrimSpace(query)
+	if utf8.RuneCountInString(query) < MinSearchQueryLength {
+		return nil, ErrSearchQueryTooShort
+	}
+	if limit <= 0 {
+		limit = DefaultSearchLimit
+	}
+	limit = min(limit, MaxSearchLimit)
+
+	matches, err := s.users.Search(ctx, query, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	trigrams := queryTrigrams(query)
+	results := make([]*UserSearchResult, 0, len(matches))
+	for _, match := range matches {
+		match.User.PasswordHash = ""
+		results = append(results, &UserSearchResult{
+			User:  match.User,
+			Score: match.Score,
+			Highlights: map[string][]TextRange{
+				"email":    highlight(match.User.Email, trigrams),
+				"username": highlight(match.User.Username, trigrams),
+			},
+		})
+	}
+	return results, nil
+}
+
+// wordTrigrams calls fn with every trigram of word the way pg_trgm builds
+// them: lower case, padded with two spaces in front and one behind. pos is the
+// index in word of the trigram's last character, which is -1 or -2 for the
+// leading trigrams and len(word) for the trailing one.
+func wordTrigrams(word []rune, fn func(trigram string, pos int)) {
+	padded := append([]rune{' ', ' '}, word...)
+	padded = append(padded, ' ')
+	for i := 0; i+3 <= len(padded); i++ {
+		fn(string(padded[i:i+3]), i)
+	}
+}
+
+// splitWords returns the runs of letters and digits in s with the index of
+// their first character, as pg_trgm does.
+func splitWords(s string) (words [][]rune, starts []int) {
+	var word []rune
+	i := 0
+	for _, r := range s {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			if word == nil {
+				starts = append(starts, i)
+			}
+			word = append(word, unicode.ToLower(r))
+		} else if word != nil {
+			words = append(words, word)
+			word = nil
+		}
+		i++
+	}
+	if word != nil {
+		words = append(words, word)
+	}
+	return words, starts
+}
+
+func queryTrigrams(query string) map[string]bool {
+	trigrams := make(map[string]bool)
+	words, _ := splitWords(query)
+	for _, word := range words {
+		wordTrigrams(word, func(trigram string, _ int) { trigrams[trigram] = true })
+	}
+	return trigrams
+}
+
+// highlight returns the characters of s covered by a trigram the query shares,
+// merged into ranges.
+func highlight(s string, trigrams map[string]bool) []TextRange {
+	words, starts := splitWords(s)
+	marked := make([]bool, utf8.RuneCountInString(s))
+	for i, word := range words {
+		wordTrigrams(word, func(trigram string, pos int) {
+			if !trigrams[trigram] {
+				return
+			}
+			for j := max(pos-2, 0); j <= min(pos, len(word)-1); j++ {
+				marked[starts[i]+j] = true
+			}
+		})
+	}
+
+	ranges := []TextRange{}
+	for i := 0; i < len(marked); i++ {
+		if !marked[i] {
+			continue
+		}
+		start := i
+		for i < len(marked) && marked[i] {
+			i++
+		}
+		ranges = append(ranges, TextRange{Start: start, End: i})
+	}
+	return ranges
+}
@@ -3,37 +3,21 @@ package service
 import (
 	"context"
 	"errors"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/Atmosfr/user-service/internal/auth"
+	"github.com/Atmosfr/user-service/internal/blob"
 	"github.com/Atmosfr/user-service/internal/models"
 	"github.com/Atmosfr/user-service/internal/repository"
+	"github.com/Atmosfr/user-service/internal/testutil"
 	"github.com/Atmosfr/user-service/internal/validation"
 	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/require"
 	"golang.org/x/crypto/bcrypt"
 )
 
-type mockUserRepo struct {
-	mock.Mock
-}
-
-func (m *mockUserRepo) FindByEmail(ctx context.Context, email string) (*models.User, error) {
-	args := m.Called(ctx, email)
-	return args.Get(0).(*models.User), args.Error(1)
-}
-
-func (m *mockUserRepo) Create(ctx context.Context, user *models.User) error {
-	args := m.Called(ctx, user)
-	err := args.Error(0)
-	return err
-}
-
-func (m *mockUserRepo) FindByID(ctx context.Context, id int64) (*models.User, error) {
-	args := m.Called(ctx, id)
-	return args.Get(0).(*models.User), args.Error(1)
-}
-
 func TestUserService_Register(t *testing.T) {
 	ctx := context.Background()
 
@@ -42,7 +26,7 @@ func TestUserService_Register(t *testing.T) {
 		email      string
 		password   string
 		username   string
-		setupMock  func(repo *mockUserRepo)
+		setupMock  func(repo *testutil.MockUserRepo)
 		wantErr    error
 		wantUserID int64
 	}{
@@ -51,7 +35,7 @@ func TestUserService_Register(t *testing.T) {
 			email:    "new@example.com",
 			password: "StrongPass!12",
 			username: "newuser",
-			setupMock: func(repo *mockUserRepo) {
+			setupMock: func(repo *testutil.MockUserRepo) {
 				repo.On("FindByEmail", mock.Anything, "new@example.com").Return((*models.User)(nil), repository.ErrUserNotFound)
 				repo.On("Create", mock.Anything, mock.AnythingOfType("*models.User")).Return(nil).Run(func(args mock.Arguments) {
 					user := args.Get(1).(*models.User)
@@ -66,7 +50,7 @@ func TestUserService_Register(t *testing.T) {
 			email:    "existing@example.com",
 			password: "StrongPass!12",
 			username: "newuser",
-			setupMock: func(repo *mockUserRepo) {
+			setupMock: func(repo *testutil.MockUserRepo) {
 				repo.On("FindByEmail", mock.Anything, "existing@example.com").Return(&models.User{ID: 999, Email: "existing@example.com"}, nil)
 			},
 			wantErr:    repository.ErrEmailAlreadyExists,
@@ -77,7 +61,7 @@ func TestUserService_Register(t *testing.T) {
 			email:    "invalid-email",
 			password: "StrongPass!12",
 			username: "newuser",
-			setupMock: func(repo *mockUserRepo) {
+			setupMock: func(repo *testutil.MockUserRepo) {
 				// No repository calls expected
 			},
 			wantErr:    validation.ErrInvalidEmail,
@@ -88,7 +72,7 @@ func TestUserService_Register(t *testing.T) {
 			email:    "new@example.com",
 			password: "short",
 			username: "newuser",
-			setupMock: func(repo *mockUserRepo) {
+			setupMock: func(repo *testutil.MockUserRepo) {
 				// No repository calls expected
 			},
 			wantErr:    validation.ErrPasswordTooShort,
@@ -99,7 +83,7 @@ func TestUserService_Register(t *testing.T) {
 			email:    "",
 			password: "StrongPass!12",
 			username: "newuser",
-			setupMock: func(repo *mockUserRepo) {
+			setupMock: func(repo *testutil.MockUserRepo) {
 				// No repository calls expected
 			},
 			wantErr:    validation.ErrInvalidEmail,
@@ -110,7 +94,7 @@ func TestUserService_Register(t *testing.T) {
 			email:    "new@example.com",
 			password: "StrongPass!12",
 			username: "user!",
-			setupMock: func(repo *mockUserRepo) {
+			setupMock: func(repo *testutil.MockUserRepo) {
 				// No repository calls expected
 			},
 			wantErr:    validation.ErrInvalidUsername,
@@ -121,7 +105,7 @@ func TestUserService_Register(t *testing.T) {
 			email:    "new@example.com",
 			password: "StrongPass!12",
 			username: "",
-			setupMock: func(repo *mockUserRepo) {
+			setupMock: func(repo *testutil.MockUserRepo) {
 				// No repository calls expected
 			},
 			wantErr:    validation.ErrInvalidUsername,
@@ -132,7 +116,7 @@ func TestUserService_Register(t *testing.T) {
 			email:    "new@example.com",
 			password: "StrongPass!12",
 			username: "newuser",
-			setupMock: func(repo *mockUserRepo) {
+			setupMock: func(repo *testutil.MockUserRepo) {
 				repo.On("FindByEmail", mock.Anything, "new@example.com").Return((*models.User)(nil), repository.ErrUserNotFound)
 				repo.On("Create", mock.Anything, mock.AnythingOfType("*models.User")).Return(errors.New("database error")).Times(1)
 			},
@@ -142,13 +126,13 @@ func TestUserService_Register(t *testing.T) {
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			repo := new(mockUserRepo)
+			repo := new(testutil.MockUserRepo)
 			svc := NewUserService(repo)
 			auth.JwtSecret = []byte("secret")
 
 			tt.setupMock(repo)
 
-			logResponse, err := svc.Register(ctx, tt.email, tt.password, tt.username)
+			logResponse, err := svc.Register(ctx, tt.email, tt.password, tt.username, RegistrationConsent{})
 
 			if tt.wantErr != nil {
 				require.Error(t, err)
@@ -180,7 +164,7 @@ func TestUserService_Login(t *testing.T) {
 		role       string
 		email      string
 		password   string
-		setupMock  func(repo *mockUserRepo)
+		setupMock  func(repo *testutil.MockUserRepo)
 		wantErr    error
 		wantUserID int64
 	}{
@@ -190,7 +174,7 @@ func TestUserService_Login(t *testing.T) {
 			role:     "user",
 			email:    "existing@example.com",
 			password: "StrongPass!12",
-			setupMock: func(repo *mockUserRepo) {
+			setupMock: func(repo *testutil.MockUserRepo) {
 				hashed, _ := bcrypt.GenerateFromPassword([]byte("StrongPass!12"), bcrypt.DefaultCost)
 				user := &models.User{
 					ID:           1,
@@ -208,7 +192,7 @@ func TestUserService_Login(t *testing.T) {
 			name:     "invalid password",
 			email:    "existing@example.com",
 			password: "StrongPass!122",
-			setupMock: func(repo *mockUserRepo) {
+			setupMock: func(repo *testutil.MockUserRepo) {
 				hashed, _ := bcrypt.GenerateFromPassword([]byte("StrongPass!12"), bcrypt.DefaultCost)
 				user := &models.User{
 					ID:           1,
@@ -225,7 +209,7 @@ func TestUserService_Login(t *testing.T) {
 			name:     "user not found",
 			email:    "ex@example.com",
 			password: "StrongPass!12",
-			setupMock: func(repo *mockUserRepo) {
+			setupMock: func(repo *testutil.MockUserRepo) {
 				repo.On("FindByEmail", mock.Anything, "ex@example.com").Return((*models.User)(nil), repository.ErrUserNotFound)
 			},
 			wantErr: repository.ErrUserNotFound,
@@ -234,7 +218,7 @@ func TestUserService_Login(t *testing.T) {
 			name:     "invalid email format",
 			email:    "invalid",
 			password: "StrongPass!12",
-			setupMock: func(repo *mockUserRepo) {
+			setupMock: func(repo *testutil.MockUserRepo) {
 				// No repository calls expected
 			},
 			wantErr: validation.ErrInvalidEmail,
@@ -243,7 +227,7 @@ func TestUserService_Login(t *testing.T) {
 			name:     "empty email",
 			email:    "",
 			password: "StrongPass!12",
-			setupMock: func(repo *mockUserRepo) {
+			setupMock: func(repo *testutil.MockUserRepo) {
 				// No repository calls expected
 			},
 			wantErr: validation.ErrInvalidEmail,
@@ -252,7 +236,7 @@ func TestUserService_Login(t *testing.T) {
 			name:     "empty password",
 			email:    "ex@example.com",
 			password: "",
-			setupMock: func(repo *mockUserRepo) {
+			setupMock: func(repo *testutil.MockUserRepo) {
 				// No repository calls expected
 			},
 			wantErr: validation.ErrInvalidCredentials,
@@ -261,7 +245,7 @@ func TestUserService_Login(t *testing.T) {
 			name:     "short password",
 			email:    "ex@example.com",
 			password: "g$12",
-			setupMock: func(repo *mockUserRepo) {
+			setupMock: func(repo *testutil.MockUserRepo) {
 				// No repository calls expected
 			},
 			wantErr: validation.ErrPasswordTooShort,
@@ -270,7 +254,7 @@ func TestUserService_Login(t *testing.T) {
 			name:     "empty jwt secret",
 			email:    "existing@example.com",
 			password: "StrongPass!12",
-			setupMock: func(repo *mockUserRepo) {
+			setupMock: func(repo *testutil.MockUserRepo) {
 				auth.JwtSecret = []byte("")
 				hashed, _ := bcrypt.GenerateFromPassword([]byte("StrongPass!12"), bcrypt.DefaultCost)
 				user := &models.User{
@@ -287,7 +271,7 @@ func TestUserService_Login(t *testing.T) {
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			repo := new(mockUserRepo)
+			repo := new(testutil.MockUserRepo)
 			svc := NewUserService(repo)
 			auth.JwtSecret = []byte("secret")
 
@@ -317,3 +301,173 @@ func TestUserService_Login(t *testing.T) {
 		})
 	}
 }
+
+func TestUserService_LoginAudit(t *testing.T) {
+	ctx := context.Background()
+	repo := new(testutil.MockUserRepo)
+	audit := new(recordingAuditLog)
+	svc := NewUserService(repo, WithAuditLog(audit))
+	auth.JwtSecret = []byte("secret")
+	hashed, _ := bcrypt.GenerateFromPassword([]byte("StrongPass!12"), bcrypt.MinCost)
+	repo.On("FindByEmail", mock.Anything, "bob@example.com").Return(&models.User{ID: 4, Email: "bob@example.com", PasswordHash: string(hashed)}, nil)
+	repo.On("FindByEmail", mock.Anything, "nobody@example.com").Return((*models.User)(nil), repository.ErrUserNotFound)
+
+	_, err := svc.Login(ctx, "bob@example.com", "StrongPass!12")
+	require.NoError(t, err)
+	_, err = svc.Login(ctx, "bob@example.com", "WrongPass!123")
+	require.ErrorIs(t, err, repository.ErrInvalidPassword)
+	_, err = svc.Login(ctx, "nobody@example.com", "StrongPass!12")
+	require.ErrorIs(t, err, repository.ErrUserNotFound)
+
+	require.Equal(t, []string{models.AuditLoginSucceeded, models.AuditLoginFailed, models.AuditLoginFailed}, audit.actions())
+	require.Equal(t, int64(4), *audit.events[0].ActorID)
+	require.Equal(t, int64(4), *audit.events[1].TargetID)
+	require.Equal(t, "invalid_password", audit.events[1].Details["reason"])
+	// attempts on unknown addresses have no target and do not record the address
+	require.Nil(t, audit.events[2].TargetID)
+	require.Equal(t, map[string]string{"reason": "unknown_user"}, audit.events[2].Details)
+}
+
+func TestUserService_RegisterWithDomains(t *testing.T) {
+	ctx := context.Background()
+	auth.JwtSecret = []byte("secret")
+	verifiedAt := time.Now()
+	domains := new(mockDomainRepo)
+	domains.On("FindVerified", mock.Anything, "acme.com").
+		Return(&models.OrganizationDomain{OrganizationID: 1, Domain: "acme.com", VerifiedAt: &verifiedAt, SSOEnforced: true}, nil)
+	domains.On("FindVerified", mock.Anything, mock.Anything).Return(nil, repository.ErrDomainNotFound)
+	domainSvc := NewDomainService(domains, new(mockOrganizationRepo), fakeResolver{})
+
+	repo := new(testutil.MockUserRepo)
+	repo.On("FindByEmail", mock.Anything, "bob@example.com").Return((*models.User)(nil), repository.ErrUserNotFound)
+	repo.On("Create", mock.Anything, mock.AnythingOfType("*models.User")).Return(nil).Run(func(args mock.Arguments) {
+		args.Get(1).(*models.User).ID = 1
+	})
+	mailer := &recordingMailer{}
+	svc := NewUserService(repo,
+		WithDomainPolicy(domainSvc),
+		WithEmailVerification(NewEmailVerificationService(repo, domainSvc, mailer, []byte("verify-secret"))),
+	)
+
+	_, err := svc.Register(ctx, "bob@acme.com", "StrongPass!12", "bob", RegistrationConsent{})
+	require.ErrorIs(t, err, ErrSSORequired)
+	repo.AssertNotCalled(t, "Create", mock.Anything, mock.Anything)
+
+	_, err = svc.Register(ctx, "bob@example.com", "StrongPass!12", "bob", RegistrationConsent{})
+	require.NoError(t, err)
+	require.Len(t, mailer.sent, 1)
+	require.Equal(t, "bob@example.com", mailer.sent[0].To)
+
+	// a failed verification email does not fail the registration
+	mailer.err = errors.New("connection refused")
+	_, err = svc.Register(ctx, "bob@example.com", "StrongPass!12", "bob", RegistrationConsent{})
+	require.NoError(t, err)
+}
+
+func TestUserService_UpdateProfile(t *testing.T) {
+	ctx := context.Background()
+	version := time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC)
+	stale := version.Add(-time.Minute)
+	str := func(s string) *string { return &s }
+
+	tests := []struct {
+		name         string
+		patch        ProfilePatch
+		ifUnmodified *time.Time
+		setupMock    func(repo *testutil.MockUserRepo)
+		wantErr      error
+		want         models.User
+	}{
+		{
+			name:         "sets and clears fields",
+			patch:        ProfilePatch{DisplayName: str("  Alice Liddell "), Timezone: str("Europe/London"), Locale: str("")},
+			ifUnmodified: &version,
+			setupMock: func(repo *testutil.MockUserRepo) {
+				repo.On("Update", mock.Anything, mock.AnythingOfType("*models.User"), &version).Return(nil)
+			},
+			want: models.User{Username: "alice", DisplayName: "Alice Liddell", Timezone: "Europe/London"},
+		},
+		{
+			name:         "stale version",
+			patch:        ProfilePatch{DisplayName: str("Alice")},
+			ifUnmodified: &stale,
+			setupMock:    func(repo *testutil.MockUserRepo) {},
+			wantErr:      repository.ErrUserModified,
+		},
+		{
+			name:      "invalid timezone",
+			patch:     ProfilePatch{Timezone: str("Europe/Atlantis")},
+			setupMock: func(repo *testutil.MockUserRepo) {},
+			wantErr:   validation.ErrInvalidTimezone,
+		},
+		{
+			name:      "username cleared",
+			patch:     ProfilePatch{Username: str("")},
+			setupMock: func(repo *testutil.MockUserRepo) {},
+			wantErr:   validation.ErrInvalidUsername,
+		},
+		{
+			name:  "username taken",
+			patch: ProfilePatch{Username: str("bob")},
+			setupMock: func(repo *testutil.MockUserRepo) {
+				repo.On("Update", mock.Anything, mock.AnythingOfType("*models.User"), (*time.Time)(nil)).Return(repository.ErrUsernameAlreadyExists)
+			},
+			wantErr: repository.ErrUsernameAlreadyExists,
+		},
+		{
+			name:         "modified concurrently",
+			patch:        ProfilePatch{Locale: str("de-DE")},
+			ifUnmodified: &version,
+			setupMock: func(repo *testutil.MockUserRepo) {
+				repo.On("Update", mock.Anything, mock.AnythingOfType("*models.User"), &version).Return(repository.ErrUserModified)
+			},
+			wantErr: repository.ErrUserModified,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := new(testutil.MockUserRepo)
+			repo.On("FindByID", mock.Anything, int64(1)).Return(&models.User{
+				ID: 1, Username: "alice", PasswordHash: "hash", Locale: "en-GB", UpdatedAt: version,
+			}, nil)
+			tt.setupMock(repo)
+			svc := NewUserService(repo)
+
+			user, err := svc.UpdateProfile(ctx, 1, tt.patch, tt.ifUnmodified)
+			if tt.wantErr != nil {
+				require.ErrorIs(t, err, tt.wantErr)
+				return
+			}
+
+			require.NoError(t, err)
+			require.Empty(t, user.PasswordHash)
+			require.Equal(t, tt.want.Username, user.Username)
+			require.Equal(t, tt.want.DisplayName, user.DisplayName)
+			require.Equal(t, tt.want.Locale, user.Locale)
+			require.Equal(t, tt.want.Timezone, user.Timezone)
+			repo.AssertExpectations(t)
+		})
+	}
+}
+
+func TestUserService_UpdateProfileReplacesUploadedAvatar(t *testing.T) {
+	ctx := context.Background()
+	store := blob.NewFilesystemStore(t.TempDir())
+	require.NoError(t, store.Put(ctx, "avatars/1/abc/64.jpg", strings.NewReader("jpeg"), 4, "image/jpeg"))
+	repo := new(testutil.MockUserRepo)
+	repo.On("FindByID", mock.Anything, int64(1)).Return(&models.User{
+		ID: 1, Username: "alice", AvatarKey: "avatars/1/abc", AvatarURL: "https://cdn.example.com/avatars/1/abc/64.jpg",
+		AvatarURLs: map[string]string{"64": "https://cdn.example.com/avatars/1/abc/64.jpg"},
+	}, nil)
+	repo.On("Update", mock.Anything, mock.MatchedBy(func(u *models.User) bool {
+		return u.AvatarKey == "" && u.AvatarURLs == nil && u.AvatarURL == "https://gravatar.example/alice.png"
+	}), (*time.Time)(nil)).Return(nil)
+	svc := NewUserService(repo, WithAvatars(NewAvatarService(repo, store, "https://cdn.example.com", WithAvatarSizes(64))))
+
+	avatarURL := "https://gravatar.example/alice.png"
+	_, err := svc.UpdateProfile(ctx, 1, ProfilePatch{AvatarURL: &avatarURL}, nil)
+	require.NoError(t, err)
+	_, _, err = store.Get(ctx, "avatars/1/abc/64.jpg")
+	require.ErrorIs(t, err, blob.ErrNotFound)
+	repo.AssertExpectations(t)
+}
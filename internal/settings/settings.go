@@ -0,0 +1,187 @@
+// Package settings defines typed per-user settings with defaults declared in
+// code. Only the values a user changed are stored; a Registry resolves them
+// into a complete document.
+package settings
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
+)
+
+var (
+	ErrUnknownSetting = errors.New("unknown setting")
+	ErrInvalidValue   = errors.New("invalid setting value")
+)
+
+// Definition is a registered setting, implemented by *Setting.
+type Definition interface {
+	Key() string
+	// Type names the JSON type of the value: string, boolean, integer or string_list.
+	Type() string
+	Default() any
+	// Decode parses and validates a value.
+	Decode(raw json.RawMessage) (any, error)
+}
+
+// Setting is a key whose values have type T.
+type Setting[T any] struct {
+	key      string
+	typ      string
+	def      T
+	validate []func(T) error
+}
+
+func (s *Setting[T]) Key() string  { return s.key }
+func (s *Setting[T]) Type() string { return s.typ }
+func (s *Setting[T]) Default() any { return s.def }
+
+func (s *Setting[T]) Decode(raw json.RawMessage) (any, error) {
+	// null clears a setting, it is never a value
+	if bytes.Equal(bytes.TrimSpace(raw), []byte("null")) {
+		return nil, fmt.Errorf("%w: %s must be a %s", ErrInvalidValue, s.key, s.typ)
+	}
+	var v T
+	if err := json.Unmarshal(raw, &v); err != nil {
+		return nil, fmt.Errorf("%w: %s must be a %s", ErrInvalidValue, s.key, s.typ)
+	}
+	for _, validate := range s.validate {
+		if err := validate(v); err != nil {
+			return nil, fmt.Errorf("%w: %s: %w", ErrInvalidValue, s.key, err)
+		}
+	}
+	return v, nil
+}
+
+func String(key, def string, validate ...func(string) error) *Setting[string] {
+	return &Setting[string]{key: key, typ: "string", def: def, validate: validate}
+}
+
+func Bool(key string, def bool) *Setting[bool] {
+	return &Setting[bool]{key: key, typ: "boolean", def: def}
+}
+
+func Int(key string, def int, validate ...func(int) error) *Setting[int] {
+	return &Setting[int]{key: key, typ: "integer", def: def, validate: validate}
+}
+
+func StringList(key string, def []string, validate ...func([]string) error) *Setting[[]string] {
+	return &Setting[[]string]{key: key, typ: "string_list", def: def, validate: validate}
+}
+
+// OneOf accepts the given values only.
+func OneOf(values ...string) func(string) error {
+	return func(v string) error {
+		if !slices.Contains(values, v) {
+			return fmt.Errorf("must be one of %s", strings.Join(values, ", "))
+		}
+		return nil
+	}
+}
+
+// EachOneOf accepts lists of distinct items out of the given values.
+func EachOneOf(values ...string) func([]string) error {
+	return func(list []string) error {
+		for i, v := range list {
+			if !slices.Contains(values, v) {
+				return fmt.Errorf("items must be one of %s", strings.Join(values, ", "))
+			}
+			if slices.Contains(list[:i], v) {
+				return fmt.Errorf("%s is listed twice", v)
+			}
+		}
+		return nil
+	}
+}
+
+// Between accepts integers from lo to hi inclusive.
+func Between(lo, hi int) func(int) error {
+	return func(v int) error {
+		if v < lo || v > hi {
+			return fmt.Errorf("must be between %d and %d", lo, hi)
+		}
+		return nil
+	}
+}
+
+// Values is a resolved settings document keyed by setting key.
+type Values map[string]any
+
+// Get returns the value of s, its default if v does not hold one.
+func Get[T any](v Values, s *Setting[T]) T {
+	if value, ok := v[s.key].(T); ok {
+		return value
+	}
+	return s.def
+}
+
+// Registry is the set of settings users can change.
+type Registry struct {
+	defs map[string]Definition
+	keys []string
+}
+
+// NewRegistry panics on duplicate keys, registries are declared in code.
+func NewRegistry(defs ...Definition) *Registry {
+	r := &Registry{defs: make(map[string]Definition, len(defs))}
+	for _, def := range defs {
+		if _, ok := r.defs[def.Key()]; ok {
+			panic("settings: duplicate key " + def.Key())
+		}
+		r.defs[def.Key()] = def
+		r.keys = append(r.keys, def.Key())
+	}
+	slices.Sort(r.keys)
+	return r
+}
+
+// Definitions returns the registered settings ordered by key.
+func (r *Registry) Definitions() []Definition {
+	defs := make([]Definition, 0, len(r.keys))
+	for _, key := range r.keys {
+		defs = append(defs, r.defs[key])
+	}
+	return defs
+}
+
+// Lookup returns the setting registered as key.
+func (r *Registry) Lookup(key string) (Definition, error) {
+	def, ok := r.defs[key]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownSetting, key)
+	}
+	return def, nil
+}
+
+// Decode validates a value submitted for key.
+func (r *Registry) Decode(key string, raw json.RawMessage) (any, error) {
+	def, err := r.Lookup(key)
+	if err != nil {
+		return nil, err
+	}
+	return def.Decode(raw)
+}
+
+// Resolve merges stored overrides into the defaults. Overrides of keys that
+// are no longer registered or no longer valid fall back to the default.
+func (r *Registry) Resolve(overrides map[string]json.RawMessage) Values {
+	values := make(Values, len(r.defs))
+	for key, def := range r.defs {
+		values[key] = def.Default()
+		raw, ok := overrides[key]
+		if !ok {
+			continue
+		}
+		v, err := def.Decode(raw)
+		if err != nil {
+			slog.Warn("ignoring stored setting", "key", key, "err", err)
+			continue
+		}
+		values[key] = v
+	}
+	return values
+}
@@ -0,0 +1,76 @@
+package settings
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+var (
+	theme    = String("theme", "system", OneOf("system", "light", "dark"))
+	digest   = Bool("weekly_digest", true)
+	pageSize = Int("page_size", 25, Between(10, 100))
+	channels = StringList("channels", []string{"email"}, EachOneOf("email", "sms"))
+	registry = NewRegistry(theme, digest, pageSize, channels)
+)
+
+func TestDecode(t *testing.T) {
+	tests := []struct {
+		key     string
+		raw     string
+		want    any
+		wantErr error
+	}{
+		{key: "theme", raw: `"dark"`, want: "dark"},
+		{key: "theme", raw: `"neon"`, wantErr: ErrInvalidValue},
+		{key: "theme", raw: `1`, wantErr: ErrInvalidValue},
+		{key: "theme", raw: `null`, wantErr: ErrInvalidValue},
+		{key: "weekly_digest", raw: `false`, want: false},
+		{key: "weekly_digest", raw: `"false"`, wantErr: ErrInvalidValue},
+		{key: "page_size", raw: `50`, want: 50},
+		{key: "page_size", raw: `50.5`, wantErr: ErrInvalidValue},
+		{key: "page_size", raw: `500`, wantErr: ErrInvalidValue},
+		{key: "channels", raw: `["sms","email"]`, want: []string{"sms", "email"}},
+		{key: "channels", raw: `[]`, want: []string{}},
+		{key: "channels", raw: `["email","email"]`, wantErr: ErrInvalidValue},
+		{key: "channels", raw: `["fax"]`, wantErr: ErrInvalidValue},
+		{key: "font", raw: `"serif"`, wantErr: ErrUnknownSetting},
+	}
+	for _, tt := range tests {
+		t.Run(tt.key+" "+tt.raw, func(t *testing.T) {
+			got, err := registry.Decode(tt.key, json.RawMessage(tt.raw))
+			if tt.wantErr != nil {
+				require.ErrorIs(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestResolve(t *testing.T) {
+	values := registry.Resolve(map[string]json.RawMessage{
+		"theme":     json.RawMessage(`"dark"`),
+		"page_size": json.RawMessage(`5000`),  // no longer valid
+		"removed":   json.RawMessage(`"old"`), // no longer registered
+	})
+
+	require.Equal(t, Values{
+		"theme":         "dark",
+		"weekly_digest": true,
+		"page_size":     25,
+		"channels":      []string{"email"},
+	}, values)
+	require.Equal(t, "dark", Get(values, theme))
+	require.Equal(t, 25, Get(values, pageSize))
+	require.Equal(t, "system", Get(Values{}, theme))
+}
+
+func TestNewRegistry_DuplicateKey(t *testing.T) {
+	require.Panics(t, func() { NewRegistry(theme, String("theme", "light")) })
+	_, err := registry.Lookup("font")
+	require.True(t, errors.Is(err, ErrUnknownSetting))
+}
@@ -0,0 +1,69 @@
+// Package testutil holds test doubles shared by the tests of several packages.
+package testutil
+
+import (
+	"context"
+	"time"
+
+	"github.com/Atmosfr/user-service/internal/models"
+	"github.com/Atmosfr/user-service/internal/repository"
+	"github.com/stretchr/testify/mock"
+)
+
+// MockUserRepo is a repository.UserRepository for tests.
+type MockUserRepo struct {
+	mock.Mock
+}
+
+var _ repository.UserRepository = (*MockUserRepo)(nil)
+
+func (m *MockUserRepo) Create(ctx context.Context, user *models.User) error {
+	return m.Called(ctx, user).Error(0)
+}
+
+func (m *MockUserRepo) FindByEmail(ctx context.Context, email string) (*models.User, error) {
+	args := m.Called(ctx, email)
+	user, _ := args.Get(0).(*models.User)
+	return user, args.Error(1)
+}
+
+func (m *MockUserRepo) FindByID(ctx context.Context, id int64) (*models.User, error) {
+	args := m.Called(ctx, id)
+	user, _ := args.Get(0).(*models.User)
+	return user, args.Error(1)
+}
+
+func (m *MockUserRepo) UpdateRole(ctx context.Context, id int64, role string) error {
+	return m.Called(ctx, id, role).Error(0)
+}
+
+func (m *MockUserRepo) Update(ctx context.Context, user *models.User, ifUnmodified *time.Time) error {
+	return m.Called(ctx, user, ifUnmodified).Error(0)
+}
+
+func (m *MockUserRepo) UpdateAvatar(ctx context.Context, user *models.User) (string, error) {
+	args := m.Called(ctx, user)
+	return args.String(0), args.Error(1)
+}
+
+func (m *MockUserRepo) MarkEmailVerified(ctx context.Context, id int64, email string) (time.Time, error) {
+	args := m.Called(ctx, id, email)
+	verifiedAt, _ := args.Get(0).(time.Time)
+	return verifiedAt, args.Error(1)
+}
+
+func (m *MockUserRepo) List(ctx context.Context, filter repository.UserFilter, after *repository.UserCursor, limit int) ([]*models.User, error) {
+	args := m.Called(ctx, filter, after, limit)
+	users, _ := args.Get(0).([]*models.User)
+	return users, args.Error(1)
+}
+
+func (m *MockUserRepo) Delete(ctx context.Context, id int64) error {
+	return m.Called(ctx, id).Error(0)
+}
+
+func (m *MockUserRepo) Search(ctx context.Context, query string, limit int) ([]*repository.UserMatch, error) {
+	args := m.Called(ctx, query, limit)
+	matches, _ := args.Get(0).([]*repository.UserMatch)
+	return matches, args.Error(1)
+}
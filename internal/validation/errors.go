@@ -3,8 +3,25 @@ package validation
 import "errors"
 
 var (
-	ErrInvalidEmail     = errors.New("invalid email format")
-	ErrPasswordTooShort = errors.New("password must be at least 8 characters")
-	ErrInvalidUsername  = errors.New("username must be 3-30 characters, alphanumeric")
-	ErrInvalidCredentials = errors.New("invalid credentials")
+	ErrInvalidEmail        = errors.New("invalid email format")
+	ErrPasswordTooShort    = errors.New("password must be at least 8 characters")
+	ErrInvalidUsername     = errors.New("username must be 3-30 characters, alphanumeric")
+	ErrInvalidCredentials  = errors.New("invalid credentials")
+	ErrInvalidSCIMUserName = errors.New("userName must be 1-255 characters")
+
+	ErrInvalidServiceAccountName        = errors.New("service account name must be 3-63 characters of lowercase letters, digits or hyphens")
+	ErrInvalidServiceAccountDescription = errors.New("service account description must be at most 1000 characters")
+
+	ErrInvalidOrganizationName = errors.New("organization name must be 1-100 characters")
+	ErrInvalidOrganizationSlug = errors.New("organization slug must be 2-63 characters of lowercase letters, digits or hyphens")
+
+	ErrInvalidGroupName        = errors.New("group name must be 1-100 characters")
+	ErrInvalidGroupDescription = errors.New("group description must be at most 1000 characters")
+
+	ErrInvalidDomain = errors.New("domain must be a lowercase fully qualified domain name")
+
+	ErrInvalidDisplayName = errors.New("display name must be at most 100 characters without control characters")
+	ErrInvalidLocale      = errors.New("locale must be a BCP 47 language tag")
+	ErrInvalidTimezone    = errors.New("timezone must be an IANA time zone name")
+	ErrInvalidAvatarURL   = errors.New("avatar url must be an http or https url of at most 2048 characters")
 )
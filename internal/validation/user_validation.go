@@ -3,6 +3,10 @@ package validation
 import (
 	"errors"
 	"regexp"
+	"strings"
+	_ "time/tzdata" // timezone names must not depend on the host's zoneinfo
+	"unicode"
+	"unicode/utf8"
 
 	"github.com/go-playground/locales/en"
 	ut "github.com/go-playground/universal-translator"
@@ -155,3 +159,111 @@ func ValidateLogin(email, password string) error {
 
 	return err
 }
+
+// ValidateEmail checks a single address, e.g. of an invitation.
+func ValidateEmail(email string) error {
+	if err := validate.Var(email, "required,email,max=255"); err != nil {
+		return ErrInvalidEmail
+	}
+	return nil
+}
+
+type SCIMUserRequest struct {
+	UserName string `validate:"required,max=255"`
+	Email    string `validate:"required,email,max=255"`
+}
+
+func ValidateSCIMUser(userName, email string) error {
+	err := validate.Struct(&SCIMUserRequest{UserName: userName, Email: email})
+	if err == nil {
+		return nil
+	}
+
+	var errs validator.ValidationErrors
+	if errors.As(err, &errs) {
+		for _, e := range errs {
+			if e.Field() == "UserName" {
+				return ErrInvalidSCIMUserName
+			}
+			return ErrInvalidEmail
+		}
+	}
+
+	return err
+}
+
+var serviceAccountNamePattern = regexp.MustCompile(`^[a-z0-9][a-z0-9-]{2,62}$`)
+
+func ValidateServiceAccount(name, description string) error {
+	if !serviceAccountNamePattern.MatchString(name) {
+		return ErrInvalidServiceAccountName
+	}
+	if len(description) > 1000 {
+		return ErrInvalidServiceAccountDescription
+	}
+	return nil
+}
+
+var organizationSlugPattern = regexp.MustCompile(`^[a-z0-9][a-z0-9-]{1,62}$`)
+
+func ValidateOrganization(name, slug string) error {
+	if n := utf8.RuneCountInString(strings.TrimSpace(name)); n == 0 || n > 100 {
+		return ErrInvalidOrganizationName
+	}
+	if !organizationSlugPattern.MatchString(slug) {
+		return ErrInvalidOrganizationSlug
+	}
+	return nil
+}
+
+func ValidateGroup(name, description string) error {
+	if n := utf8.RuneCountInString(strings.TrimSpace(name)); n == 0 || n > 100 {
+		return ErrInvalidGroupName
+	}
+	if utf8.RuneCountInString(description) > 1000 {
+		return ErrInvalidGroupDescription
+	}
+	return nil
+}
+
+// ValidateDomain accepts registrable names like "acme.com", without a trailing dot.
+func ValidateDomain(domain string) error {
+	if domain != strings.ToLower(domain) || strings.HasSuffix(domain, ".") {
+		return ErrInvalidDomain
+	}
+	if err := validate.Var(domain, "required,fqdn,max=253"); err != nil {
+		return ErrInvalidDomain
+	}
+	return nil
+}
+
+// ValidateLocale checks a BCP 47 language tag such as "en" or "pt-BR".
+func ValidateLocale(locale string) error {
+	if validate.Var(locale, "required,max=35,bcp47_language_tag") != nil {
+		return ErrInvalidLocale
+	}
+	return nil
+}
+
+// ValidateProfile checks the editable profile of a user. Everything but the
+// username is optional and may be empty.
+func ValidateProfile(username, displayName, locale, timezone, avatarURL string) error {
+	if err := validate.Var(username, "required,username,min=3,max=30"); err != nil {
+		return ErrInvalidUsername
+	}
+	if utf8.RuneCountInString(displayName) > 100 || strings.IndexFunc(displayName, unicode.IsControl) >= 0 {
+		return ErrInvalidDisplayName
+	}
+	if locale != "" {
+		if err := ValidateLocale(locale); err != nil {
+			return err
+		}
+	}
+	if timezone != "" && validate.Var(timezone, "max=64,timezone") != nil {
+		return ErrInvalidTimezone
+	}
+	if avatarURL != "" && validate.Var(avatarURL, "max=2048,http_url") != nil {
+		return ErrInvalidAvatarURL
+	}
+	return nil
+}
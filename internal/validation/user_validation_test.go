@@ -183,3 +183,177 @@ func TestValidateLogin(t *testing.T) {
 		})
 	}
 }
+
+func TestValidateServiceAccount(t *testing.T) {
+	tests := []struct {
+		name        string
+		saName      string
+		description string
+		expectErr   error
+	}{
+		{name: "valid", saName: "ci-deployer", description: "deploys main", expectErr: nil},
+		{name: "digits allowed", saName: "cron2", expectErr: nil},
+		{name: "too short", saName: "ci", expectErr: ErrInvalidServiceAccountName},
+		{name: "uppercase", saName: "CI-Deployer", expectErr: ErrInvalidServiceAccountName},
+		{name: "leading hyphen", saName: "-deployer", expectErr: ErrInvalidServiceAccountName},
+		{name: "too long", saName: strings.Repeat("a", 64), expectErr: ErrInvalidServiceAccountName},
+		{name: "description too long", saName: "ci-deployer", description: strings.Repeat("a", 1001), expectErr: ErrInvalidServiceAccountDescription},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			err := ValidateServiceAccount(tt.saName, tt.description)
+			if !errors.Is(err, tt.expectErr) {
+				t.Errorf("expected error: %v, got: %v", tt.expectErr, err)
+			}
+		})
+	}
+}
+
+func TestValidateOrganization(t *testing.T) {
+	tests := []struct {
+		name      string
+		orgName   string
+		slug      string
+		expectErr error
+	}{
+		{name: "valid", orgName: "Acme Corp", slug: "acme", expectErr: nil},
+		{name: "unicode name", orgName: "Überwald GmbH", slug: "uberwald-2", expectErr: nil},
+		{name: "blank name", orgName: "   ", slug: "acme", expectErr: ErrInvalidOrganizationName},
+		{name: "name too long", orgName: strings.Repeat("a", 101), slug: "acme", expectErr: ErrInvalidOrganizationName},
+		{name: "slug too short", orgName: "Acme", slug: "a", expectErr: ErrInvalidOrganizationSlug},
+		{name: "slug uppercase", orgName: "Acme", slug: "Acme", expectErr: ErrInvalidOrganizationSlug},
+		{name: "slug leading hyphen", orgName: "Acme", slug: "-acme", expectErr: ErrInvalidOrganizationSlug},
+		{name: "slug too long", orgName: "Acme", slug: strings.Repeat("a", 64), expectErr: ErrInvalidOrganizationSlug},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			err := ValidateOrganization(tt.orgName, tt.slug)
+			if !errors.Is(err, tt.expectErr) {
+				t.Errorf("expected error: %v, got: %v", tt.expectErr, err)
+			}
+		})
+	}
+}
+
+func TestValidateGroup(t *testing.T) {
+	tests := []struct {
+		name        string
+		groupName   string
+		description string
+		expectErr   error
+	}{
+		{name: "valid", groupName: "Platform", description: "Platform engineering", expectErr: nil},
+		{name: "empty description", groupName: "SRE", description: "", expectErr: nil},
+		{name: "blank name", groupName: " ", description: "", expectErr: ErrInvalidGroupName},
+		{name: "name too long", groupName: strings.Repeat("a", 101), description: "", expectErr: ErrInvalidGroupName},
+		{name: "description too long", groupName: "SRE", description: strings.Repeat("a", 1001), expectErr: ErrInvalidGroupDescription},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			err := ValidateGroup(tt.groupName, tt.description)
+			if !errors.Is(err, tt.expectErr) {
+				t.Errorf("expected error: %v, got: %v", tt.expectErr, err)
+			}
+		})
+	}
+}
+
+func TestValidateEmail(t *testing.T) {
+	tests := []struct {
+		name      string
+		email     string
+		expectErr error
+	}{
+		{name: "valid", email: "bob@example.com", expectErr: nil},
+		{name: "empty", email: "", expectErr: ErrInvalidEmail},
+		{name: "no domain", email: "bob@", expectErr: ErrInvalidEmail},
+		{name: "too long", email: strings.Repeat("a", 250) + "@example.com", expectErr: ErrInvalidEmail},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			err := ValidateEmail(tt.email)
+			if !errors.Is(err, tt.expectErr) {
+				t.Errorf("expected error: %v, got: %v", tt.expectErr, err)
+			}
+		})
+	}
+}
+
+func TestValidateDomain(t *testing.T) {
+	tests := []struct {
+		name      string
+		domain    string
+		expectErr error
+	}{
+		{name: "valid", domain: "acme.com", expectErr: nil},
+		{name: "subdomain", domain: "eu.acme.co.uk", expectErr: nil},
+		{name: "empty", domain: "", expectErr: ErrInvalidDomain},
+		{name: "single label", domain: "localhost", expectErr: ErrInvalidDomain},
+		{name: "uppercase", domain: "Acme.com", expectErr: ErrInvalidDomain},
+		{name: "trailing dot", domain: "acme.com.", expectErr: ErrInvalidDomain},
+		{name: "email", domain: "bob@acme.com", expectErr: ErrInvalidDomain},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			err := ValidateDomain(tt.domain)
+			if !errors.Is(err, tt.expectErr) {
+				t.Errorf("expected error: %v, got: %v", tt.expectErr, err)
+			}
+		})
+	}
+}
+
+func TestValidateProfile(t *testing.T) {
+	tests := []struct {
+		name        string
+		username    string
+		displayName string
+		locale      string
+		timezone    string
+		avatarURL   string
+		expectErr   error
+	}{
+		{name: "valid", username: "alice", displayName: "Alice Liddell", locale: "en-GB", timezone: "Europe/London", avatarURL: "https://cdn.example.com/a.png", expectErr: nil},
+		{name: "only username", username: "alice", expectErr: nil},
+		{name: "invalid username", username: "al", expectErr: ErrInvalidUsername},
+		{name: "long display name", username: "alice", displayName: strings.Repeat("é", 101), expectErr: ErrInvalidDisplayName},
+		{name: "control character in display name", username: "alice", displayName: "Alice\nLiddell", expectErr: ErrInvalidDisplayName},
+		{name: "invalid locale", username: "alice", locale: "english please", expectErr: ErrInvalidLocale},
+		{name: "invalid timezone", username: "alice", timezone: "Mars/Olympus_Mons", expectErr: ErrInvalidTimezone},
+		{name: "local timezone", username: "alice", timezone: "Local", expectErr: ErrInvalidTimezone},
+		{name: "relative avatar url", username: "alice", avatarURL: "/avatars/a.png", expectErr: ErrInvalidAvatarURL},
+		{name: "non-http avatar url", username: "alice", avatarURL: "javascript:alert(1)", expectErr: ErrInvalidAvatarURL},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			err := ValidateProfile(tt.username, tt.displayName, tt.locale, tt.timezone, tt.avatarURL)
+			if !errors.Is(err, tt.expectErr) {
+				t.Errorf("expected error: %v, got: %v", tt.expectErr, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,36 @@
+-- +goose Up
+CREATE TABLE scim_tenants (
+    id SERIAL PRIMARY KEY,
+    name VARCHAR(255) NOT NULL UNIQUE,
+    token_hash VARCHAR(64) NOT NULL UNIQUE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+ALTER TABLE users ADD COLUMN scim_tenant_id INTEGER REFERENCES scim_tenants(id) ON DELETE SET NULL;
+ALTER TABLE users ADD COLUMN external_id VARCHAR(255);
+
+CREATE UNIQUE INDEX users_scim_external_id_key ON users (scim_tenant_id, external_id) WHERE external_id IS NOT NULL;
+
+CREATE TABLE scim_groups (
+    id SERIAL PRIMARY KEY,
+    tenant_id INTEGER NOT NULL REFERENCES scim_tenants(id) ON DELETE CASCADE,
+    display_name VARCHAR(255) NOT NULL,
+    external_id VARCHAR(255),
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    UNIQUE (tenant_id, display_name)
+);
+
+CREATE TABLE scim_group_members (
+    group_id INTEGER NOT NULL REFERENCES scim_groups(id) ON DELETE CASCADE,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    PRIMARY KEY (group_id, user_id)
+);
+
+-- +goose Down
+DROP TABLE IF EXISTS scim_group_members;
+DROP TABLE IF EXISTS scim_groups;
+DROP INDEX IF EXISTS users_scim_external_id_key;
+ALTER TABLE users DROP COLUMN IF EXISTS external_id;
+ALTER TABLE users DROP COLUMN IF EXISTS scim_tenant_id;
+DROP TABLE IF EXISTS scim_tenants;
@@ -0,0 +1,30 @@
+-- +goose Up
+CREATE TABLE service_accounts (
+    id SERIAL PRIMARY KEY,
+    name VARCHAR(63) NOT NULL UNIQUE,
+    description TEXT NOT NULL DEFAULT '',
+    owner_id INTEGER NOT NULL REFERENCES users(id) ON DELETE RESTRICT,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+CREATE INDEX service_accounts_owner_id_idx ON service_accounts (owner_id);
+
+CREATE TABLE service_account_keys (
+    id SERIAL PRIMARY KEY,
+    service_account_id INTEGER NOT NULL REFERENCES service_accounts(id) ON DELETE CASCADE,
+    type VARCHAR(16) NOT NULL CHECK (type IN ('api_key', 'public_key')),
+    name VARCHAR(100) NOT NULL,
+    key_prefix VARCHAR(16),
+    key_hash VARCHAR(64) UNIQUE,
+    public_key TEXT,
+    expires_at TIMESTAMP WITH TIME ZONE,
+    last_used_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    UNIQUE (service_account_id, name),
+    CHECK ((type = 'api_key' AND key_hash IS NOT NULL) OR (type = 'public_key' AND public_key IS NOT NULL))
+);
+
+-- +goose Down
+DROP TABLE IF EXISTS service_account_keys;
+DROP TABLE IF EXISTS service_accounts;
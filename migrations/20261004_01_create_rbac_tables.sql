@@ -0,0 +1,59 @@
+-- +goose Up
+CREATE TABLE roles (
+    id SERIAL PRIMARY KEY,
+    name VARCHAR(50) NOT NULL UNIQUE,
+    description TEXT NOT NULL DEFAULT '',
+    parent_id INTEGER REFERENCES roles(id) ON DELETE SET NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    CHECK (parent_id <> id)
+);
+
+CREATE TABLE permissions (
+    id SERIAL PRIMARY KEY,
+    name VARCHAR(100) NOT NULL UNIQUE,
+    description TEXT NOT NULL DEFAULT ''
+);
+
+CREATE TABLE role_permissions (
+    role_id INTEGER NOT NULL REFERENCES roles(id) ON DELETE CASCADE,
+    permission_id INTEGER NOT NULL REFERENCES permissions(id) ON DELETE CASCADE,
+    PRIMARY KEY (role_id, permission_id)
+);
+
+INSERT INTO permissions (name, description) VALUES
+    ('*', 'Every permission'),
+    ('profile:read', 'Read your own profile'),
+    ('profile:write', 'Update your own profile'),
+    ('users:read', 'Read any user'),
+    ('users:write', 'Update any user'),
+    ('users:delete', 'Delete any user'),
+    ('roles:read', 'List roles and their permissions'),
+    ('service_accounts:read', 'List service accounts and their keys'),
+    ('service_accounts:write', 'Create and delete service accounts and keys'),
+    ('scim:manage', 'Create SCIM provisioning tenants');
+
+INSERT INTO roles (name, description) VALUES ('user', 'Default role for people');
+INSERT INTO roles (name, description, parent_id)
+    SELECT 'support', 'Read access to users for support staff', id FROM roles WHERE name = 'user';
+INSERT INTO roles (name, description, parent_id)
+    SELECT 'admin', 'Full access', id FROM roles WHERE name = 'support';
+
+INSERT INTO role_permissions (role_id, permission_id)
+    SELECT r.id, p.id FROM roles r, permissions p
+    WHERE (r.name = 'user' AND p.name IN ('profile:read', 'profile:write'))
+       OR (r.name = 'support' AND p.name IN ('users:read', 'roles:read', 'service_accounts:read'))
+       OR (r.name = 'admin' AND p.name = '*');
+
+-- roles already assigned to users become real roles without permissions
+UPDATE users SET role = 'user' WHERE role IS NULL;
+INSERT INTO roles (name) SELECT DISTINCT role FROM users ON CONFLICT (name) DO NOTHING;
+
+ALTER TABLE users ALTER COLUMN role SET NOT NULL;
+ALTER TABLE users ADD CONSTRAINT users_role_fkey FOREIGN KEY (role) REFERENCES roles(name) ON UPDATE CASCADE;
+
+-- +goose Down
+ALTER TABLE users DROP CONSTRAINT IF EXISTS users_role_fkey;
+ALTER TABLE users ALTER COLUMN role DROP NOT NULL;
+DROP TABLE IF EXISTS role_permissions;
+DROP TABLE IF EXISTS permissions;
+DROP TABLE IF EXISTS roles;
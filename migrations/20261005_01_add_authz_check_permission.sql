@@ -0,0 +1,6 @@
+-- +goose Up
+INSERT INTO permissions (name, description) VALUES
+    ('authz:check', 'Ask for authorization decisions on behalf of other principals');
+
+-- +goose Down
+DELETE FROM permissions WHERE name = 'authz:check';
@@ -0,0 +1,45 @@
+-- +goose Up
+CREATE TABLE org_groups (
+    id SERIAL PRIMARY KEY,
+    organization_id INTEGER NOT NULL REFERENCES organizations(id) ON DELETE CASCADE,
+    parent_id INTEGER,
+    name VARCHAR(100) NOT NULL,
+    description TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    UNIQUE (organization_id, name),
+    UNIQUE (organization_id, id),
+    -- parents must belong to the same organization
+    CONSTRAINT org_groups_parent_fkey FOREIGN KEY (organization_id, parent_id)
+        REFERENCES org_groups (organization_id, id) ON DELETE RESTRICT
+);
+
+-- every ancestor/descendant pair, including each group paired with itself at depth 0
+CREATE TABLE org_group_closure (
+    ancestor_id INTEGER NOT NULL REFERENCES org_groups(id) ON DELETE CASCADE,
+    descendant_id INTEGER NOT NULL REFERENCES org_groups(id) ON DELETE CASCADE,
+    depth INTEGER NOT NULL,
+    PRIMARY KEY (ancestor_id, descendant_id)
+);
+
+CREATE INDEX org_group_closure_descendant_id_idx ON org_group_closure (descendant_id);
+
+CREATE TABLE org_group_members (
+    organization_id INTEGER NOT NULL,
+    group_id INTEGER NOT NULL,
+    user_id INTEGER NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    PRIMARY KEY (group_id, user_id),
+    CONSTRAINT org_group_members_group_fkey FOREIGN KEY (organization_id, group_id)
+        REFERENCES org_groups (organization_id, id) ON DELETE CASCADE,
+    -- leaving the organization removes the user from its groups
+    CONSTRAINT org_group_members_member_fkey FOREIGN KEY (organization_id, user_id)
+        REFERENCES organization_members (organization_id, user_id) ON DELETE CASCADE
+);
+
+CREATE INDEX org_group_members_user_id_idx ON org_group_members (organization_id, user_id);
+
+-- +goose Down
+DROP TABLE IF EXISTS org_group_members;
+DROP TABLE IF EXISTS org_group_closure;
+DROP TABLE IF EXISTS org_groups;
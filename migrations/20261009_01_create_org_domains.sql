@@ -0,0 +1,19 @@
+-- +goose Up
+CREATE TABLE org_domains (
+    id SERIAL PRIMARY KEY,
+    organization_id INTEGER NOT NULL REFERENCES organizations(id) ON DELETE CASCADE,
+    domain VARCHAR(253) NOT NULL,
+    verification_token VARCHAR(64) NOT NULL,
+    verified_at TIMESTAMP WITH TIME ZONE,
+    auto_join BOOLEAN NOT NULL DEFAULT FALSE,
+    sso_enforced BOOLEAN NOT NULL DEFAULT FALSE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    UNIQUE (organization_id, domain)
+);
+
+-- several organizations may claim a domain, only one can prove it
+CREATE UNIQUE INDEX org_domains_verified_key ON org_domains (domain) WHERE verified_at IS NOT NULL;
+
+-- +goose Down
+DROP TABLE IF EXISTS org_domains;
@@ -0,0 +1,76 @@
+-- +goose Up
+-- Tenant-scoped transactions assume this role and set app.current_tenant to the
+-- organization they act for. The policies below only apply to it; the table
+-- owner the service connects as bypasses them for cross-organization lookups.
+-- +goose StatementBegin
+DO $$
+BEGIN
+    IF NOT EXISTS (SELECT 1 FROM pg_roles WHERE rolname = 'user_service_tenant') THEN
+        CREATE ROLE user_service_tenant NOLOGIN;
+    END IF;
+END
+$$;
+-- +goose StatementEnd
+
+GRANT user_service_tenant TO CURRENT_USER;
+GRANT SELECT, INSERT, UPDATE, DELETE ON ALL TABLES IN SCHEMA public TO user_service_tenant;
+GRANT USAGE, SELECT ON ALL SEQUENCES IN SCHEMA public TO user_service_tenant;
+ALTER DEFAULT PRIVILEGES IN SCHEMA public GRANT SELECT, INSERT, UPDATE, DELETE ON TABLES TO user_service_tenant;
+ALTER DEFAULT PRIVILEGES IN SCHEMA public GRANT USAGE, SELECT ON SEQUENCES TO user_service_tenant;
+
+-- NULL when no tenant is set, which matches no rows
+-- +goose StatementBegin
+CREATE FUNCTION app_current_tenant() RETURNS INTEGER
+LANGUAGE sql STABLE AS $$
+    SELECT NULLIF(current_setting('app.current_tenant', true), '')::integer
+$$;
+-- +goose StatementEnd
+
+ALTER TABLE organizations ENABLE ROW LEVEL SECURITY;
+CREATE POLICY tenant_isolation ON organizations TO user_service_tenant
+    USING (id = app_current_tenant());
+
+ALTER TABLE organization_members ENABLE ROW LEVEL SECURITY;
+CREATE POLICY tenant_isolation ON organization_members TO user_service_tenant
+    USING (organization_id = app_current_tenant());
+
+ALTER TABLE org_groups ENABLE ROW LEVEL SECURITY;
+CREATE POLICY tenant_isolation ON org_groups TO user_service_tenant
+    USING (organization_id = app_current_tenant());
+
+-- closure rows have no organization column, they follow the visibility of their groups
+ALTER TABLE org_group_closure ENABLE ROW LEVEL SECURITY;
+CREATE POLICY tenant_isolation ON org_group_closure TO user_service_tenant
+    USING (EXISTS (SELECT 1 FROM org_groups g WHERE g.id = descendant_id)
+       AND EXISTS (SELECT 1 FROM org_groups g WHERE g.id = ancestor_id));
+
+ALTER TABLE org_group_members ENABLE ROW LEVEL SECURITY;
+CREATE POLICY tenant_isolation ON org_group_members TO user_service_tenant
+    USING (organization_id = app_current_tenant());
+
+ALTER TABLE org_invitations ENABLE ROW LEVEL SECURITY;
+CREATE POLICY tenant_isolation ON org_invitations TO user_service_tenant
+    USING (organization_id = app_current_tenant());
+
+ALTER TABLE org_domains ENABLE ROW LEVEL SECURITY;
+CREATE POLICY tenant_isolation ON org_domains TO user_service_tenant
+    USING (organization_id = app_current_tenant());
+
+-- +goose Down
+DROP POLICY IF EXISTS tenant_isolation ON org_domains;
+ALTER TABLE org_domains DISABLE ROW LEVEL SECURITY;
+DROP POLICY IF EXISTS tenant_isolation ON org_invitations;
+ALTER TABLE org_invitations DISABLE ROW LEVEL SECURITY;
+DROP POLICY IF EXISTS tenant_isolation ON org_group_members;
+ALTER TABLE org_group_members DISABLE ROW LEVEL SECURITY;
+DROP POLICY IF EXISTS tenant_isolation ON org_group_closure;
+ALTER TABLE org_group_closure DISABLE ROW LEVEL SECURITY;
+DROP POLICY IF EXISTS tenant_isolation ON org_groups;
+ALTER TABLE org_groups DISABLE ROW LEVEL SECURITY;
+DROP POLICY IF EXISTS tenant_isolation ON organization_members;
+ALTER TABLE organization_members DISABLE ROW LEVEL SECURITY;
+DROP POLICY IF EXISTS tenant_isolation ON organizations;
+ALTER TABLE organizations DISABLE ROW LEVEL SECURITY;
+DROP FUNCTION IF EXISTS app_current_tenant();
+DROP OWNED BY user_service_tenant;
+DROP ROLE IF EXISTS user_service_tenant;
@@ -0,0 +1,20 @@
+-- +goose Up
+CREATE TABLE email_changes (
+    id SERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    old_email VARCHAR(255) NOT NULL,
+    new_email VARCHAR(255) NOT NULL,
+    status VARCHAR(20) NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'confirmed', 'cancelled', 'reverted')),
+    expires_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    -- the old address can undo a confirmed change until then
+    revertible_until TIMESTAMP WITH TIME ZONE NOT NULL,
+    confirmed_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+-- a new request replaces the open one
+CREATE UNIQUE INDEX email_changes_pending_key ON email_changes (user_id) WHERE status = 'pending';
+
+-- +goose Down
+DROP TABLE IF EXISTS email_changes;
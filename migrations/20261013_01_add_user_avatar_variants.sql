@@ -0,0 +1,8 @@
+-- +goose Up
+-- avatar_key is the blob key prefix of an uploaded avatar, avatar_urls maps sizes to URLs
+ALTER TABLE users ADD COLUMN avatar_key VARCHAR(255) NOT NULL DEFAULT '';
+ALTER TABLE users ADD COLUMN avatar_urls JSONB NOT NULL DEFAULT '{}';
+
+-- +goose Down
+ALTER TABLE users DROP COLUMN IF EXISTS avatar_urls;
+ALTER TABLE users DROP COLUMN IF EXISTS avatar_key;
@@ -0,0 +1,29 @@
+-- +goose Up
+-- each top-level key of the metadata columns is a namespace registered in metadata_namespaces
+ALTER TABLE users ADD COLUMN public_metadata JSONB NOT NULL DEFAULT '{}';
+ALTER TABLE users ADD COLUMN private_metadata JSONB NOT NULL DEFAULT '{}';
+
+-- jsonb_ops rather than jsonb_path_ops so key existence (?) is indexed as well as @> and @?
+CREATE INDEX users_public_metadata_idx ON users USING GIN (public_metadata);
+CREATE INDEX users_private_metadata_idx ON users USING GIN (private_metadata);
+
+CREATE TABLE metadata_namespaces (
+    name VARCHAR(63) PRIMARY KEY,
+    visibility VARCHAR(10) NOT NULL CHECK (visibility IN ('public', 'private')),
+    schema JSONB NOT NULL,
+    -- private metadata never leaves the server, so only public namespaces can be token claims
+    in_token_claims BOOLEAN NOT NULL DEFAULT FALSE CHECK (NOT in_token_claims OR visibility = 'public'),
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+INSERT INTO permissions (name, description) VALUES
+    ('metadata:manage', 'Register metadata namespaces and write user metadata');
+
+-- +goose Down
+DELETE FROM permissions WHERE name = 'metadata:manage';
+DROP TABLE IF EXISTS metadata_namespaces;
+DROP INDEX IF EXISTS users_private_metadata_idx;
+DROP INDEX IF EXISTS users_public_metadata_idx;
+ALTER TABLE users DROP COLUMN IF EXISTS private_metadata;
+ALTER TABLE users DROP COLUMN IF EXISTS public_metadata;
@@ -0,0 +1,12 @@
+-- +goose Up
+-- only values a user changed are stored, everything else resolves to the defaults registered in code
+CREATE TABLE user_settings (
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    key VARCHAR(100) NOT NULL,
+    value JSONB NOT NULL,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    PRIMARY KEY (user_id, key)
+);
+
+-- +goose Down
+DROP TABLE IF EXISTS user_settings;
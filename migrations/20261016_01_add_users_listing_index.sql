@@ -0,0 +1,8 @@
+-- +goose Up
+-- admin listings page through users newest first, id breaks ties between equal timestamps
+CREATE INDEX users_created_at_id_idx ON users (created_at DESC, id DESC);
+CREATE INDEX users_email_domain_idx ON users (LOWER(SPLIT_PART(email, '@', 2)));
+
+-- +goose Down
+DROP INDEX IF EXISTS users_email_domain_idx;
+DROP INDEX IF EXISTS users_created_at_id_idx;
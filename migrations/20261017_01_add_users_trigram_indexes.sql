@@ -0,0 +1,9 @@
+-- +goose Up
+CREATE EXTENSION IF NOT EXISTS pg_trgm;
+
+CREATE INDEX users_email_trgm_idx ON users USING GIN (LOWER(email) gin_trgm_ops);
+CREATE INDEX users_username_trgm_idx ON users USING GIN (LOWER(username) gin_trgm_ops);
+
+-- +goose Down
+DROP INDEX IF EXISTS users_username_trgm_idx;
+DROP INDEX IF EXISTS users_email_trgm_idx;
@@ -0,0 +1,46 @@
+-- +goose Up
+ALTER TABLE users
+    ADD COLUMN state VARCHAR(32) NOT NULL DEFAULT 'active'
+        CHECK (state IN ('active', 'suspended', 'banned', 'pending_verification', 'deleted')),
+    ADD COLUMN state_reason VARCHAR(64) NOT NULL DEFAULT '',
+    -- the end of a suspension, NULL for indefinite ones
+    ADD COLUMN state_expires_at TIMESTAMPTZ,
+    ADD CONSTRAINT users_state_expires_at_check CHECK (state_expires_at IS NULL OR state = 'suspended');
+
+-- accounts deactivated before states existed stay locked
+UPDATE users SET state = 'suspended', state_reason = 'other' WHERE is_active IS FALSE;
+
+-- is_active is kept for SCIM and existing clients and now follows state
+ALTER TABLE users DROP COLUMN is_active;
+ALTER TABLE users ADD COLUMN is_active BOOLEAN GENERATED ALWAYS AS (state = 'active') STORED;
+
+CREATE INDEX users_suspension_expiry_idx ON users (state_expires_at) WHERE state = 'suspended' AND state_expires_at IS NOT NULL;
+
+CREATE TABLE user_state_history (
+    id BIGSERIAL PRIMARY KEY,
+    user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    from_state VARCHAR(32) NOT NULL,
+    to_state VARCHAR(32) NOT NULL,
+    reason VARCHAR(64) NOT NULL,
+    note TEXT NOT NULL DEFAULT '',
+    expires_at TIMESTAMPTZ,
+    -- NULL for changes the service made itself, such as expired suspensions
+    actor_id BIGINT REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX user_state_history_user_id_idx ON user_state_history (user_id, created_at DESC);
+
+-- +goose Down
+DROP TABLE IF EXISTS user_state_history;
+DROP INDEX IF EXISTS users_suspension_expiry_idx;
+
+ALTER TABLE users DROP COLUMN is_active;
+ALTER TABLE users ADD COLUMN is_active BOOLEAN DEFAULT TRUE;
+UPDATE users SET is_active = (state = 'active');
+
+ALTER TABLE users
+    DROP CONSTRAINT users_state_expires_at_check,
+    DROP COLUMN state_expires_at,
+    DROP COLUMN state_reason,
+    DROP COLUMN state;
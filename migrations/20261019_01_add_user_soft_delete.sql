@@ -0,0 +1,15 @@
+-- +goose Up
+-- deleted accounts keep their row, purged ones are anonymized in place
+ALTER TABLE users
+    ADD COLUMN deleted_at TIMESTAMPTZ,
+    ADD COLUMN purged_at TIMESTAMPTZ,
+    ADD CONSTRAINT users_deleted_at_check CHECK ((state = 'deleted') = (deleted_at IS NOT NULL));
+
+CREATE INDEX users_pending_purge_idx ON users (deleted_at) WHERE deleted_at IS NOT NULL AND purged_at IS NULL;
+
+-- +goose Down
+DROP INDEX IF EXISTS users_pending_purge_idx;
+ALTER TABLE users
+    DROP CONSTRAINT users_deleted_at_check,
+    DROP COLUMN purged_at,
+    DROP COLUMN deleted_at;
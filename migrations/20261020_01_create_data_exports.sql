@@ -0,0 +1,20 @@
+-- +goose Up
+CREATE TABLE data_exports (
+    id SERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    status VARCHAR(20) NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'running', 'ready', 'failed', 'expired')),
+    -- set once the archive is stored, the download link expires with it
+    blob_key VARCHAR(255) NOT NULL DEFAULT '',
+    size BIGINT NOT NULL DEFAULT 0,
+    expires_at TIMESTAMP WITH TIME ZONE,
+    started_at TIMESTAMP WITH TIME ZONE,
+    completed_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+-- one export per user is built at a time
+CREATE UNIQUE INDEX data_exports_in_flight_key ON data_exports (user_id) WHERE status IN ('pending', 'running');
+CREATE INDEX data_exports_user_id_idx ON data_exports (user_id, created_at DESC);
+
+-- +goose Down
+DROP TABLE IF EXISTS data_exports;
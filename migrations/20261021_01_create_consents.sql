@@ -0,0 +1,37 @@
+-- +goose Up
+-- a version takes effect at published_at; mandatory versions make users accept them again
+CREATE TABLE legal_documents (
+    id SERIAL PRIMARY KEY,
+    kind VARCHAR(32) NOT NULL CHECK (kind IN ('terms_of_service', 'privacy_policy')),
+    version VARCHAR(32) NOT NULL,
+    url VARCHAR(2048) NOT NULL,
+    mandatory BOOLEAN NOT NULL DEFAULT TRUE,
+    published_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    UNIQUE (kind, version)
+);
+
+CREATE INDEX legal_documents_kind_published_at_idx ON legal_documents (kind, published_at DESC);
+
+-- append-only: marketing is granted and withdrawn by new rows, the latest one counts
+CREATE TABLE user_consents (
+    id BIGSERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    kind VARCHAR(32) NOT NULL CHECK (kind IN ('terms_of_service', 'privacy_policy', 'marketing')),
+    document_id INTEGER REFERENCES legal_documents(id) ON DELETE RESTRICT,
+    granted BOOLEAN NOT NULL,
+    ip_address VARCHAR(45) NOT NULL DEFAULT '',
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    CHECK ((kind = 'marketing') = (document_id IS NULL)),
+    CHECK (granted OR kind = 'marketing')
+);
+
+CREATE INDEX user_consents_user_id_idx ON user_consents (user_id, kind, created_at DESC);
+
+INSERT INTO permissions (name, description) VALUES
+    ('legal:manage', 'Publish versions of the terms of service and privacy policy');
+
+-- +goose Down
+DELETE FROM permissions WHERE name = 'legal:manage';
+DROP TABLE IF EXISTS user_consents;
+DROP TABLE IF EXISTS legal_documents;
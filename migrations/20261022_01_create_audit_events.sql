@@ -0,0 +1,48 @@
+-- +goose Up
+-- append-only: every event stores the hash of its predecessor, so edits made
+-- around the triggers break the chain. Users are not referenced by foreign
+-- keys, events outlive the accounts they are about.
+CREATE TABLE audit_events (
+    id BIGSERIAL PRIMARY KEY,
+    action VARCHAR(64) NOT NULL,
+    actor_id INTEGER,
+    target_id INTEGER,
+    ip_address VARCHAR(45) NOT NULL DEFAULT '',
+    user_agent VARCHAR(512) NOT NULL DEFAULT '',
+    request_id VARCHAR(128) NOT NULL DEFAULT '',
+    details JSONB NOT NULL DEFAULT '{}',
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    prev_hash VARCHAR(64) NOT NULL DEFAULT '',
+    hash VARCHAR(64) NOT NULL,
+    CONSTRAINT audit_events_prev_hash_key UNIQUE (prev_hash)
+);
+
+CREATE INDEX audit_events_actor_id_idx ON audit_events (actor_id, id DESC);
+CREATE INDEX audit_events_target_id_idx ON audit_events (target_id, id DESC);
+CREATE INDEX audit_events_action_idx ON audit_events (action, id DESC);
+CREATE INDEX audit_events_created_at_idx ON audit_events (created_at);
+
+-- +goose StatementBegin
+CREATE FUNCTION audit_events_append_only() RETURNS trigger
+LANGUAGE plpgsql AS $$
+BEGIN
+    RAISE EXCEPTION 'audit_events is append-only';
+END
+$$;
+-- +goose StatementEnd
+
+CREATE TRIGGER audit_events_no_change BEFORE UPDATE OR DELETE ON audit_events
+    FOR EACH ROW EXECUTE FUNCTION audit_events_append_only();
+CREATE TRIGGER audit_events_no_truncate BEFORE TRUNCATE ON audit_events
+    FOR EACH STATEMENT EXECUTE FUNCTION audit_events_append_only();
+
+-- the tenant role gets write access to new tables by default
+REVOKE UPDATE, DELETE, TRUNCATE ON audit_events FROM user_service_tenant;
+
+INSERT INTO permissions (name, description) VALUES
+    ('audit:read', 'Read the audit log and verify its hash chain');
+
+-- +goose Down
+DELETE FROM permissions WHERE name = 'audit:read';
+DROP TABLE IF EXISTS audit_events;
+DROP FUNCTION IF EXISTS audit_events_append_only();
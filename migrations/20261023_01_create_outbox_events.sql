@@ -0,0 +1,83 @@
+-- +goose Up
+-- Transactional outbox of user lifecycle events. Rows are written by triggers
+-- on users, so every path that changes a user, SCIM and the purge job
+-- included, enqueues its event in the same transaction. A relay publishes
+-- them and retries until the sink accepts them.
+CREATE TABLE outbox_events (
+    id BIGSERIAL PRIMARY KEY,
+    event_id UUID NOT NULL DEFAULT gen_random_uuid(),
+    event_type VARCHAR(64) NOT NULL,
+    schema_version INTEGER NOT NULL,
+    -- events of one user are published in order
+    aggregate_id INTEGER NOT NULL,
+    payload JSONB NOT NULL,
+    attempts INTEGER NOT NULL DEFAULT 0,
+    next_attempt_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    last_error TEXT NOT NULL DEFAULT '',
+    published_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX outbox_events_pending_idx ON outbox_events (next_attempt_at, id) WHERE published_at IS NULL;
+CREATE INDEX outbox_events_aggregate_pending_idx ON outbox_events (aggregate_id, id) WHERE published_at IS NULL;
+CREATE INDEX outbox_events_published_at_idx ON outbox_events (published_at) WHERE published_at IS NOT NULL;
+
+-- version 1 of the user payload, keep in sync with events.UserData and bump
+-- schema_version below on incompatible changes
+-- +goose StatementBegin
+CREATE FUNCTION user_event_payload(u users) RETURNS JSONB
+LANGUAGE sql STABLE AS $$
+    SELECT jsonb_build_object(
+        'id', u.id,
+        'email', u.email,
+        'username', u.username,
+        'display_name', u.display_name,
+        'locale', u.locale,
+        'timezone', u.timezone,
+        'avatar_url', u.avatar_url,
+        'role', u.role,
+        'state', u.state,
+        'email_verified', u.email_verified_at IS NOT NULL,
+        'created_at', u.created_at,
+        'updated_at', u.updated_at
+    )
+$$;
+-- +goose StatementEnd
+
+-- Soft deletion is an update to the deleted state, user.deleted follows when
+-- the account is purged or removed.
+-- +goose StatementBegin
+CREATE FUNCTION enqueue_user_event() RETURNS trigger
+LANGUAGE plpgsql AS $$
+BEGIN
+    IF TG_OP = 'INSERT' THEN
+        INSERT INTO outbox_events (event_type, schema_version, aggregate_id, payload)
+        VALUES ('user.registered', 1, NEW.id, user_event_payload(NEW));
+    ELSIF TG_OP = 'DELETE' THEN
+        IF OLD.purged_at IS NULL THEN
+            INSERT INTO outbox_events (event_type, schema_version, aggregate_id, payload)
+            VALUES ('user.deleted', 1, OLD.id, jsonb_build_object('id', OLD.id));
+        END IF;
+    ELSIF NEW.purged_at IS NOT NULL THEN
+        IF OLD.purged_at IS NULL THEN
+            INSERT INTO outbox_events (event_type, schema_version, aggregate_id, payload)
+            VALUES ('user.deleted', 1, NEW.id, jsonb_build_object('id', NEW.id));
+        END IF;
+    -- password and metadata changes only touch fields the payload leaves out
+    ELSIF (user_event_payload(NEW) - 'updated_at') IS DISTINCT FROM (user_event_payload(OLD) - 'updated_at') THEN
+        INSERT INTO outbox_events (event_type, schema_version, aggregate_id, payload)
+        VALUES ('user.updated', 1, NEW.id, user_event_payload(NEW));
+    END IF;
+    RETURN NULL;
+END
+$$;
+-- +goose StatementEnd
+
+CREATE TRIGGER users_outbox AFTER INSERT OR UPDATE OR DELETE ON users
+    FOR EACH ROW EXECUTE FUNCTION enqueue_user_event();
+
+-- +goose Down
+DROP TRIGGER IF EXISTS users_outbox ON users;
+DROP FUNCTION IF EXISTS enqueue_user_event();
+DROP FUNCTION IF EXISTS user_event_payload(users);
+DROP TABLE IF EXISTS outbox_events;
@@ -0,0 +1,274 @@
+// Package authzclient asks the user service for authorization decisions.
+//
+//	client := authzclient.New("http://user-service:8080", apiKey)
+//	decision, err := client.Check(ctx, authzclient.Check{
+//		Subject:  authzclient.Subject{Token: bearerToken},
+//		Action:   "users:read",
+//		Resource: map[string]any{"type": "user", "id": 42},
+//	})
+//
+// Decisions are cached in memory for as long as the service's Cache-Control
+// header allows.
+package authzclient
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// MaxBatchSize is the number of checks the service accepts per request.
+// CheckMany splits larger batches.
+const MaxBatchSize = 100
+
+const maxCacheEntries = 10000
+
+type Subject struct {
+	UserID     int64          `json:"user_id,omitempty"`
+	Token      string         `json:"token,omitempty"`
+	Attributes map[string]any `json:"attributes,omitempty"`
+}
+
+type Check struct {
+	Subject  Subject        `json:"subject"`
+	Action   string         `json:"action"`
+	Resource map[string]any `json:"resource,omitempty"`
+	Context  map[string]any `json:"context,omitempty"`
+}
+
+type Decision struct {
+	Allowed bool   `json:"allowed"`
+	Reason  string `json:"reason"`
+	Policy  string `json:"policy,omitempty"`
+}
+
+// Error is returned for non-200 responses.
+type Error struct {
+	StatusCode int
+	Message    string
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("authz: %d: %s", e.StatusCode, e.Message)
+}
+
+type Client struct {
+	baseURL    string
+	credential string
+	httpClient *http.Client
+	cache      *decisionCache
+	now        func() time.Time
+}
+
+type Option func(*Client)
+
+func WithHTTPClient(httpClient *http.Client) Option {
+	return func(c *Client) {
+		c.httpClient = httpClient
+	}
+}
+
+// WithoutCache disables the decision cache.
+func WithoutCache() Option {
+	return func(c *Client) {
+		c.cache = nil
+	}
+}
+
+// New creates a client. credential is the caller's own bearer credential, usually
+// a service account API key or access token.
+func New(baseURL, credential string, opts ...Option) *Client {
+	c := &Client{
+		baseURL:    strings.TrimSuffix(baseURL, "/"),
+		credential: credential,
+		httpClient: &http.Client{Timeout: 5 * time.Second},
+		cache:      &decisionCache{entries: map[string]cacheEntry{}},
+		now:        time.Now,
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+func (c *Client) Check(ctx context.Context, check Check) (*Decision, error) {
+	key, err := cacheKey(check)
+	if err != nil {
+		return nil, err
+	}
+	if d, ok := c.cache.get(key, c.now()); ok {
+		return d, nil
+	}
+
+	var decision Decision
+	maxAge, err := c.post(ctx, "/authz/check", check, &decision)
+	if err != nil {
+		return nil, err
+	}
+	c.cache.put(key, &decision, c.now(), maxAge)
+	return &decision, nil
+}
+
+// CheckMany returns one decision per check, in order. Only checks missing from
+// the cache are sent.
+func (c *Client) CheckMany(ctx context.Context, checks []Check) ([]*Decision, error) {
+	decisions := make([]*Decision, len(checks))
+	keys := make([]string, len(checks))
+	var pending []int
+
+	now := c.now()
+	for i, check := range checks {
+		key, err := cacheKey(check)
+		if err != nil {
+			return nil, err
+		}
+		keys[i] = key
+		if d, ok := c.cache.get(key, now); ok {
+			decisions[i] = d
+			continue
+		}
+		pending = append(pending, i)
+	}
+
+	for start := 0; start < len(pending); start += MaxBatchSize {
+		batch := pending[start:min(start+MaxBatchSize, len(pending))]
+		req := struct {
+			Checks []Check `json:"checks"`
+		}{Checks: make([]Check, len(batch))}
+		for j, i := range batch {
+			req.Checks[j] = checks[i]
+		}
+
+		var resp struct {
+			Decisions []*Decision `json:"decisions"`
+		}
+		maxAge, err := c.post(ctx, "/authz/check-many", req, &resp)
+		if err != nil {
+			return nil, err
+		}
+		if len(resp.Decisions) != len(batch) {
+			return nil, fmt.Errorf("authz: expected %d decisions, got %d", len(batch), len(resp.Decisions))
+		}
+
+		now := c.now()
+		for j, i := range batch {
+			decisions[i] = resp.Decisions[j]
+			c.cache.put(keys[i], resp.Decisions[j], now, maxAge)
+		}
+	}
+	return decisions, nil
+}
+
+// post sends body to path and decodes the response into out. It returns how long
+// the response may be cached.
+func (c *Client) post(ctx context.Context, path string, body, out any) (time.Duration, error) {
+	payload, err := json.Marshal(body)
+	if err != nil {
+		return 0, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.baseURL+path, bytes.NewReader(payload))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+c.credential)
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		var errBody struct {
+			Error string `json:"error"`
+		}
+		json.NewDecoder(resp.Body).Decode(&errBody)
+		if errBody.Error == "" {
+			errBody.Error = http.StatusText(resp.StatusCode)
+		}
+		return 0, &Error{StatusCode: resp.StatusCode, Message: errBody.Error}
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return 0, fmt.Errorf("authz: decoding response: %w", err)
+	}
+	return maxAge(resp.Header.Get("Cache-Control")), nil
+}
+
+// maxAge returns the max-age of a Cache-Control header, zero if the response must not be cached.
+func maxAge(header string) time.Duration {
+	var age time.Duration
+	for _, directive := range strings.Split(header, ",") {
+		directive = strings.TrimSpace(strings.ToLower(directive))
+		switch {
+		case directive == "no-store", directive == "no-cache":
+			return 0
+		case strings.HasPrefix(directive, "max-age="):
+			seconds, err := strconv.Atoi(strings.TrimPrefix(directive, "max-age="))
+			if err == nil && seconds > 0 {
+				age = time.Duration(seconds) * time.Second
+			}
+		}
+	}
+	return age
+}
+
+// cacheKey is the check's JSON encoding; encoding/json sorts map keys, so equal
+// checks produce equal keys.
+func cacheKey(check Check) (string, error) {
+	b, err := json.Marshal(check)
+	return string(b), err
+}
+
+type cacheEntry struct {
+	decision Decision
+	expires  time.Time
+}
+
+type decisionCache struct {
+	mu      sync.Mutex
+	entries map[string]cacheEntry
+}
+
+func (c *decisionCache) get(key string, now time.Time) (*Decision, bool) {
+	if c == nil {
+		return nil, false
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry, ok := c.entries[key]
+	if !ok || !now.Before(entry.expires) {
+		return nil, false
+	}
+	d := entry.decision
+	return &d, true
+}
+
+func (c *decisionCache) put(key string, d *Decision, now time.Time, ttl time.Duration) {
+	if c == nil || ttl <= 0 {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if len(c.entries) >= maxCacheEntries {
+		for k, entry := range c.entries {
+			if !now.Before(entry.expires) {
+				delete(c.entries, k)
+			}
+		}
+		if len(c.entries) >= maxCacheEntries {
+			// still full of live entries: start over rather than grow without bound
+			c.entries = map[string]cacheEntry{}
+		}
+	}
+	c.entries[key] = cacheEntry{decision: *d, expires: now.Add(ttl)}
+}
@@ -0,0 +1,155 @@
+package authzclient
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Atmosfr/user-service/internal/handlers"
+	"github.com/Atmosfr/user-service/internal/service"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeAuthz allows every action starting with "read" and counts the checks it evaluates.
+type fakeAuthz struct {
+	mu      sync.Mutex
+	checked int
+	batches int
+}
+
+func (f *fakeAuthz) decide(check service.AuthzCheck) *service.AuthzDecision {
+	f.checked++
+	allowed := len(check.Action) >= 4 && check.Action[:4] == "read"
+	return &service.AuthzDecision{Allowed: allowed, Reason: "fake"}
+}
+
+func (f *fakeAuthz) Check(ctx context.Context, check service.AuthzCheck) (*service.AuthzDecision, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if check.Action == "" {
+		return nil, service.ErrMissingAuthzAction
+	}
+	return f.decide(check), nil
+}
+
+func (f *fakeAuthz) CheckMany(ctx context.Context, checks []service.AuthzCheck) ([]*service.AuthzDecision, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if len(checks) > service.MaxAuthzBatchSize {
+		return nil, service.ErrAuthzBatchTooLarge
+	}
+	f.batches++
+	decisions := make([]*service.AuthzDecision, len(checks))
+	for i, check := range checks {
+		decisions[i] = f.decide(check)
+	}
+	return decisions, nil
+}
+
+func newTestServer(t *testing.T, maxAge time.Duration) (*fakeAuthz, *httptest.Server) {
+	t.Helper()
+	fake := &fakeAuthz{}
+	mux := http.NewServeMux()
+	mux.Handle("POST /authz/check", handlers.CheckAuthzHandler(fake, maxAge))
+	mux.Handle("POST /authz/check-many", handlers.CheckManyAuthzHandler(fake, maxAge))
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer usk_caller" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		mux.ServeHTTP(w, r)
+	}))
+	t.Cleanup(srv.Close)
+	return fake, srv
+}
+
+func TestClient_CheckCachesDecisions(t *testing.T) {
+	fake, srv := newTestServer(t, time.Minute)
+	client := New(srv.URL, "usk_caller")
+	now := time.Now()
+	client.now = func() time.Time { return now }
+
+	check := Check{Subject: Subject{UserID: 1}, Action: "read:users", Resource: map[string]any{"type": "user", "id": 2}}
+	for range 3 {
+		decision, err := client.Check(context.Background(), check)
+		require.NoError(t, err)
+		require.True(t, decision.Allowed)
+	}
+	require.Equal(t, 1, fake.checked)
+
+	// different check, different cache entry
+	decision, err := client.Check(context.Background(), Check{Subject: Subject{UserID: 1}, Action: "delete:users"})
+	require.NoError(t, err)
+	require.False(t, decision.Allowed)
+	require.Equal(t, 2, fake.checked)
+
+	now = now.Add(time.Minute)
+	_, err = client.Check(context.Background(), check)
+	require.NoError(t, err)
+	require.Equal(t, 3, fake.checked, "expired entries are refreshed")
+}
+
+func TestClient_NoStoreIsNotCached(t *testing.T) {
+	fake, srv := newTestServer(t, 0)
+	client := New(srv.URL, "usk_caller")
+
+	check := Check{Subject: Subject{UserID: 1}, Action: "read:users"}
+	for range 2 {
+		_, err := client.Check(context.Background(), check)
+		require.NoError(t, err)
+	}
+	require.Equal(t, 2, fake.checked)
+}
+
+func TestClient_CheckManySendsOnlyMissesInBatches(t *testing.T) {
+	fake, srv := newTestServer(t, time.Minute)
+	client := New(srv.URL, "usk_caller")
+
+	_, err := client.Check(context.Background(), Check{Subject: Subject{Token: "t"}, Action: "read:0"})
+	require.NoError(t, err)
+
+	checks := make([]Check, 150)
+	for i := range checks {
+		checks[i] = Check{Subject: Subject{Token: "t"}, Action: fmt.Sprintf("read:%d", i)}
+	}
+	checks[0] = Check{Subject: Subject{Token: "t"}, Action: "read:0"}
+	checks[149] = Check{Subject: Subject{Token: "t"}, Action: "write"}
+
+	decisions, err := client.CheckMany(context.Background(), checks)
+	require.NoError(t, err)
+	require.Len(t, decisions, 150)
+	require.True(t, decisions[0].Allowed)
+	require.False(t, decisions[149].Allowed)
+	require.Equal(t, 1+149, fake.checked)
+	require.Equal(t, 2, fake.batches)
+
+	_, err = client.CheckMany(context.Background(), checks)
+	require.NoError(t, err)
+	require.Equal(t, 150, fake.checked, "second round is served from the cache")
+}
+
+func TestClient_Errors(t *testing.T) {
+	_, srv := newTestServer(t, time.Minute)
+
+	_, err := New(srv.URL, "usk_caller").Check(context.Background(), Check{Subject: Subject{UserID: 1}})
+	var apiErr *Error
+	require.ErrorAs(t, err, &apiErr)
+	require.Equal(t, http.StatusBadRequest, apiErr.StatusCode)
+	require.Equal(t, "action is required", apiErr.Message)
+
+	_, err = New(srv.URL, "usk_wrong").Check(context.Background(), Check{Subject: Subject{UserID: 1}, Action: "read"})
+	require.ErrorAs(t, err, &apiErr)
+	require.Equal(t, http.StatusUnauthorized, apiErr.StatusCode)
+	require.Equal(t, "Unauthorized", apiErr.Message)
+}
+
+func TestMaxAge(t *testing.T) {
+	require.Equal(t, 30*time.Second, maxAge("private, max-age=30"))
+	require.Equal(t, time.Duration(0), maxAge("no-store"))
+	require.Equal(t, time.Duration(0), maxAge("max-age=30, no-cache"))
+	require.Equal(t, time.Duration(0), maxAge(""))
+}